@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"github.com/dalemusser/strataforge/internal/app/bootstrap"
 	"github.com/dalemusser/waffle/app"
@@ -21,7 +22,14 @@ import (
 // The bootstrap.Hooks value wires this application into WAFFLE's lifecycle.
 // app.Run executes the lifecycle in the correct order, blocking until the
 // service shuts down. Any error is considered fatal and terminates the process.
+//
+// `strataforge new feature <name>` is handled before WAFFLE starts: it
+// scaffolds a feature package and exits without loading configuration.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "new" {
+		os.Exit(runNew(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	if err := app.Run(context.Background(), bootstrap.Hooks); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dalemusser/strataforge/internal/app/system/scaffold"
+)
+
+const newUsage = `usage: strataforge new feature <name>
+
+Generates internal/app/features/<name> (a handler stub, a test file, and
+the template registration) in the current directory, which must be the
+repository root. Existing files are never overwritten.
+`
+
+// runNew handles `strataforge new ...`. args are the arguments after "new".
+func runNew(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 || args[0] != "feature" {
+		fmt.Fprint(stderr, newUsage)
+		return 2
+	}
+	name := args[1]
+
+	files, err := scaffold.Feature(".", name)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for _, f := range files {
+		fmt.Fprintln(stdout, "created", f.Path)
+	}
+
+	wiring, err := scaffold.Wiring(".", name)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "\nMount it in internal/app/bootstrap/routes.go:\n\n%s", wiring)
+	return 0
+}
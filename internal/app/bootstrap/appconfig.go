@@ -39,6 +39,10 @@ type AppConfig struct {
 	SessionDomain string        // Cookie domain (blank means current host)
 	SessionMaxAge time.Duration // Maximum session cookie lifetime (default: 24h)
 
+	// Remember-me configuration
+	RememberMeEnabled bool          // Offer "Remember me" on the password login form (default: false)
+	RememberMeMaxAge  time.Duration // Lifetime of a remember-me token (default: 720h)
+
 	// Idle logout configuration
 	IdleLogoutEnabled bool          // Enable automatic logout after idle time
 	IdleLogoutTimeout time.Duration // Duration of inactivity before logout (default: 30m)
@@ -50,6 +54,67 @@ type AppConfig struct {
 	RateLimitLoginWindow   time.Duration // Time window for counting failed attempts (default: 15m)
 	RateLimitLoginLockout  time.Duration // Lockout duration after exceeding limit (default: 15m)
 
+	RateLimitLoginIPAttempts int    // Max failed login attempts per client IP before lockout (default: 0, disabled)
+	RateLimitLoginStore      string // "mongo" (default) or "memory"
+
+	RateLimitRequestsPerMinute int // Sustained requests per minute per client IP (default: 0, disabled)
+	RateLimitBurst             int // Burst size per client IP (default: 60)
+
+	// Response compression configuration
+	CompressionMinSize int // Smallest response body in bytes that is compressed (default: 1024)
+
+	// Maintenance mode configuration
+	MaintenanceMode       bool          // Start with maintenance mode on (default: false)
+	MaintenanceAllowIPs   string        // Comma-separated IPs/CIDRs allowed through during maintenance
+	MaintenanceRetryAfter time.Duration // Retry-After hint for maintenance responses (default: 0, omitted)
+
+	// JSON error bodies
+	ProblemDetails  bool   // Send RFC 7807 problem+json error bodies to JSON clients (default: false)
+	ProblemTypeBase string // URI prefix for the problem type member (default: "", about:blank)
+
+	// In-memory background job queue
+	JobQueueWorkers int           // Background jobs that run at once (default: 4)
+	JobQueueSize    int           // Jobs that may wait before Enqueue refuses more (default: 256)
+	JobQueueTimeout time.Duration // Default deadline for each job (default: 1m)
+
+	// Feature flags
+	FeatureFlags    string // Comma-separated flags enabled for everyone
+	FeatureRollouts string // Comma-separated flag:percent entries for gradual rollouts
+
+	// Client IP detection
+	TrustedProxies string // Comma-separated proxy IPs/CIDRs whose forwarding headers are trusted (default: loopback)
+
+	// Host header validation
+	AllowedHosts string // Comma-separated allowed Host values, "*.domain" for subdomains (empty: allow any)
+
+	// HTTPS enforcement
+	ForceHTTPS          bool // Redirect HTTP to HTTPS and send HSTS (max-age etc. from the core hsts_* settings)
+	TrustForwardedProto bool // Believe X-Forwarded-Proto from the proxy when deciding whether a request is secure
+
+	// Request size limits
+	MaxRequestBodyBytes   int64 // Largest accepted request body (0 disables)
+	MaxRequestHeaderBytes int64 // Largest accepted request line plus headers (0 disables)
+
+	// Access log sampling (successful requests only; errors are always logged)
+	AccessLogSamplePercent      int // Percent of 2xx/3xx requests logged
+	AccessLogAssetSamplePercent int // Percent of 2xx/3xx /assets/ requests logged
+
+	// Access log bodies (debugging only)
+	AccessLogBodies       bool // Record redacted request and response bodies
+	AccessLogBodyMaxBytes int  // Bytes of each body recorded
+
+	// Request metrics
+	MetricsEnabled  bool   // Record Prometheus request metrics and serve them (default: false)
+	MetricsPath     string // Path the metrics are served at (default: /metrics)
+	MetricsAllowIPs string // Comma-separated IPs/CIDRs allowed to fetch the metrics (default: loopback)
+
+	// Profiling
+	PprofEnabled bool   // Mount the admin-only pprof endpoints
+	PprofPrefix  string // Path the pprof endpoints are mounted at
+
+	// Frontend build
+	AssetManifest string // Path of the build's manifest.json; empty in development
+
 	// CSRF protection configuration
 	CSRFKey string // Secret key for CSRF token signing (32 bytes, must be strong in production)
 
@@ -72,6 +137,7 @@ type AppConfig struct {
 	StorageCFKeyPath   string // Path to CloudFront private key file
 
 	// Email/SMTP configuration
+	MailBackend  string // "smtp" (default) or "log" to write emails to the log without a mail server
 	MailSMTPHost string // SMTP server host (e.g., localhost for Mailpit, email-smtp.us-east-1.amazonaws.com for SES)
 	MailSMTPPort int    // SMTP server port (e.g., 1025 for Mailpit, 587 for SES)
 	MailSMTPUser string // SMTP username (empty for Mailpit, SES SMTP credentials for AWS)
@@ -87,12 +153,20 @@ type AppConfig struct {
 
 	// Audit logging configuration
 	// Values: "all" (MongoDB + zap), "db" (MongoDB only), "log" (zap only), "off" (disabled)
-	AuditLogAuth  string // Authentication events (login, logout, password, verification)
-	AuditLogAdmin string // Admin actions (user CRUD, settings changes)
+	AuditLogAuth   string // Authentication events (login, logout, password, verification)
+	AuditLogAdmin  string // Admin actions (user CRUD, settings changes)
+	AuditLogAccess string // Access checks (role checks passed, 401/403 denials)
+	AuditLogFile   string // JSON file for audit lines, apart from the app log ("" = app log)
 
 	// Google OAuth configuration
 	GoogleClientID     string // Google OAuth2 client ID
 	GoogleClientSecret string // Google OAuth2 client secret
+	GoogleSignupRole   string // Role for accounts created on first Google sign-in (empty: existing users only)
+
+	// CAPTCHA configuration
+	CaptchaProvider  string // "recaptcha", "hcaptcha", or "" for none
+	CaptchaSiteKey   string // Site key rendered in the widget
+	CaptchaSecretKey string // Secret key for verifying responses
 
 	// Admin seeding configuration
 	SeedAdminEmail string // Email of the admin user to create on startup (if set)
@@ -30,6 +30,10 @@ var appConfigKeys = []config.AppKey{
 	{Name: "session_domain", Default: "", Desc: "Session cookie domain (blank means current host)"},
 	{Name: "session_max_age", Default: "24h", Desc: "Session cookie max age (e.g., 24h, 720h, 30m)"},
 
+	// Remember-me configuration
+	{Name: "remember_me_enabled", Default: false, Desc: "Offer 'Remember me' on the password login form"},
+	{Name: "remember_me_max_age", Default: "720h", Desc: "How long a remembered browser stays logged in"},
+
 	// Idle logout configuration
 	{Name: "idle_logout_enabled", Default: false, Desc: "Enable automatic logout after idle time"},
 	{Name: "idle_logout_timeout", Default: "30m", Desc: "Idle timeout duration before logout"},
@@ -40,6 +44,65 @@ var appConfigKeys = []config.AppKey{
 	{Name: "rate_limit_login_attempts", Default: 5, Desc: "Max failed login attempts before lockout"},
 	{Name: "rate_limit_login_window", Default: "15m", Desc: "Time window for counting failed attempts"},
 	{Name: "rate_limit_login_lockout", Default: "15m", Desc: "Lockout duration after exceeding limit"},
+	{Name: "rate_limit_login_ip_attempts", Default: 0, Desc: "Max failed login attempts from one client IP before it is locked out (0 disables)"},
+	{Name: "rate_limit_login_store", Default: "mongo", Desc: "Where login failures are counted: 'mongo' (shared by all instances) or 'memory' (this instance only)"},
+	{Name: "rate_limit_requests_per_minute", Default: 0, Desc: "Sustained requests per minute allowed per client IP (0 disables)"},
+	{Name: "rate_limit_burst", Default: 60, Desc: "Requests a client IP may make in a burst before throttling"},
+
+	// Response compression configuration (enable_compression and compression_level are WAFFLE core settings)
+	{Name: "compression_min_size", Default: 1024, Desc: "Smallest response body in bytes that is compressed"},
+
+	// Maintenance mode configuration
+	{Name: "maintenance_mode", Default: false, Desc: "Start in maintenance mode (all requests get a 503 page)"},
+	{Name: "maintenance_allow_ips", Default: "", Desc: "Comma-separated IPs or CIDRs allowed through during maintenance"},
+	{Name: "maintenance_retry_after", Default: "0s", Desc: "Retry-After hint sent during maintenance (0 to omit)"},
+
+	// JSON error bodies
+	{Name: "problem_details", Default: false, Desc: "Send RFC 7807 application/problem+json error bodies to JSON clients"},
+	{Name: "problem_type_base", Default: "", Desc: "URI prefix for the problem type member (empty uses about:blank)"},
+
+	// In-memory background job queue
+	{Name: "job_queue_workers", Default: 4, Desc: "Background jobs that run at once"},
+	{Name: "job_queue_size", Default: 256, Desc: "Background jobs that may wait for a worker before new ones are refused"},
+	{Name: "job_queue_timeout", Default: "1m", Desc: "Default deadline for each background job"},
+
+	// Feature flags
+	{Name: "feature_flags", Default: "", Desc: "Comma-separated feature flags enabled for everyone"},
+	{Name: "feature_rollouts", Default: "", Desc: "Comma-separated flag:percent entries enabled for a share of users"},
+
+	// Client IP detection
+	{Name: "trusted_proxies", Default: "127.0.0.1,::1", Desc: "Comma-separated proxy IPs or CIDRs whose X-Forwarded-For headers are trusted"},
+
+	// Host header validation
+	{Name: "allowed_hosts", Default: "", Desc: "Comma-separated host names (*.domain for subdomains) the server answers for; empty allows any"},
+
+	// HTTPS enforcement
+	{Name: "force_https", Default: false, Desc: "Redirect plain-HTTP requests to HTTPS and send HSTS on secure responses"},
+	{Name: "trust_forwarded_proto", Default: false, Desc: "Treat X-Forwarded-Proto: https as a secure request (only behind a TLS-terminating proxy)"},
+
+	// Request size limits
+	{Name: "max_request_body_bytes", Default: 64 << 20, Desc: "Largest request body in bytes; bigger requests get a 413 (0 disables)"},
+	{Name: "max_request_header_bytes", Default: 64 << 10, Desc: "Largest total size in bytes of the request line and headers; bigger requests get a 431 (0 disables)"},
+
+	// Access log sampling
+	{Name: "access_log_sample_percent", Default: 100, Desc: "Percent (0-100) of successful requests written to the access log; 4xx and 5xx are always logged"},
+	{Name: "access_log_asset_sample_percent", Default: 100, Desc: "Percent (0-100) of successful /assets/ requests written to the access log"},
+
+	// Access log bodies
+	{Name: "access_log_bodies", Default: false, Desc: "Record request and response bodies, with secrets redacted, on access log lines (debugging only)"},
+	{Name: "access_log_body_max_bytes", Default: 4096, Desc: "Bytes of each body recorded when access_log_bodies is on"},
+
+	// Request metrics
+	{Name: "metrics_enabled", Default: false, Desc: "Record Prometheus request metrics and serve them at metrics_path"},
+	{Name: "metrics_path", Default: "/metrics", Desc: "Path the Prometheus metrics are served at"},
+	{Name: "metrics_allow_ips", Default: "127.0.0.1,::1", Desc: "Comma-separated IPs or CIDRs allowed to fetch metrics_path"},
+
+	// Profiling
+	{Name: "pprof_enabled", Default: false, Desc: "Serve the admin-only net/http/pprof endpoints under pprof_prefix"},
+	{Name: "pprof_prefix", Default: "/debug/pprof", Desc: "Path the profiling endpoints are mounted at"},
+
+	// Frontend build
+	{Name: "asset_manifest", Default: "", Desc: "Path of the frontend build's manifest.json, for the asset template function (empty links names as given)"},
 
 	{Name: "csrf_key", Default: "dev-only-csrf-key-please-change-0123456789", Desc: "CSRF token signing key (32+ chars in production)"},
 
@@ -60,6 +123,7 @@ var appConfigKeys = []config.AppKey{
 	{Name: "storage_cf_key_path", Default: "", Desc: "Path to CloudFront private key file"},
 
 	// Email/SMTP configuration
+	{Name: "mail_backend", Default: "smtp", Desc: "Email backend: smtp, or log to write emails to the log instead of sending"},
 	{Name: "mail_smtp_host", Default: "localhost", Desc: "SMTP server host"},
 	{Name: "mail_smtp_port", Default: 1025, Desc: "SMTP server port"},
 	{Name: "mail_smtp_user", Default: "", Desc: "SMTP username"},
@@ -76,10 +140,18 @@ var appConfigKeys = []config.AppKey{
 	// Audit logging settings
 	{Name: "audit_log_auth", Default: "all", Desc: "Auth event logging: 'all' (db+log), 'db', 'log', or 'off'"},
 	{Name: "audit_log_admin", Default: "all", Desc: "Admin event logging: 'all' (db+log), 'db', 'log', or 'off'"},
+	{Name: "audit_log_access", Default: "log", Desc: "Access check logging (role checks passed, 401/403 denials): 'all' (db+log), 'db', 'log', or 'off'"},
+	{Name: "audit_log_file", Default: "", Desc: "File the audit log is written to as JSON, apart from the app log; empty writes it to the app log"},
 
 	// Google OAuth configuration
 	{Name: "google_client_id", Default: "", Desc: "Google OAuth2 client ID"},
 	{Name: "google_client_secret", Default: "", Desc: "Google OAuth2 client secret"},
+	{Name: "google_signup_role", Default: "", Desc: "Role given to accounts created on first Google sign-in (empty: only existing users may sign in)"},
+
+	// CAPTCHA configuration
+	{Name: "captcha_provider", Default: "", Desc: "CAPTCHA on the login and invitation forms: 'recaptcha', 'hcaptcha', or empty for none"},
+	{Name: "captcha_site_key", Default: "", Desc: "CAPTCHA site key, shown in the page"},
+	{Name: "captcha_secret_key", Default: "", Desc: "CAPTCHA secret key, used to verify responses"},
 
 	// Admin seeding configuration
 	{Name: "seed_admin_email", Default: "", Desc: "Email of admin user to create on startup"},
@@ -99,7 +171,13 @@ var appConfigKeys = []config.AppKey{
 //   - Reading environment variables (WAFFLE_* for core, STRATAFORGE_* for app)
 //   - Parsing command-line flags
 //   - Merging with precedence: flags > env > files > defaults
+//
+// Before that, applyProfile overlays the APP_ENV profile
+// (config.<env>.toml and the like) between the files and the environment.
 func LoadConfig(logger *zap.Logger) (*config.CoreConfig, AppConfig, error) {
+	if err := applyProfile(logger); err != nil {
+		return nil, AppConfig{}, err
+	}
 	coreCfg, appValues, err := config.LoadWithAppConfig(logger, EnvVarPrefix, appConfigKeys)
 	if err != nil {
 		return nil, AppConfig{}, err
@@ -115,6 +193,10 @@ func LoadConfig(logger *zap.Logger) (*config.CoreConfig, AppConfig, error) {
 		SessionDomain:    appValues.String("session_domain"),
 		SessionMaxAge:    appValues.Duration("session_max_age", 24*time.Hour),
 
+		// Remember-me
+		RememberMeEnabled: appValues.Bool("remember_me_enabled"),
+		RememberMeMaxAge:  appValues.Duration("remember_me_max_age", 720*time.Hour),
+
 		// Idle logout
 		IdleLogoutEnabled: appValues.Bool("idle_logout_enabled"),
 		IdleLogoutTimeout: appValues.Duration("idle_logout_timeout", 30*time.Minute),
@@ -125,6 +207,56 @@ func LoadConfig(logger *zap.Logger) (*config.CoreConfig, AppConfig, error) {
 		RateLimitLoginAttempts: appValues.Int("rate_limit_login_attempts"),
 		RateLimitLoginWindow:   appValues.Duration("rate_limit_login_window", 15*time.Minute),
 		RateLimitLoginLockout:  appValues.Duration("rate_limit_login_lockout", 15*time.Minute),
+		RateLimitLoginIPAttempts: appValues.Int("rate_limit_login_ip_attempts"),
+		RateLimitLoginStore:      appValues.String("rate_limit_login_store"),
+		RateLimitRequestsPerMinute: appValues.Int("rate_limit_requests_per_minute"),
+		RateLimitBurst:             appValues.Int("rate_limit_burst"),
+
+		// Response compression
+		CompressionMinSize: appValues.Int("compression_min_size"),
+
+		// Maintenance mode
+		MaintenanceMode:       appValues.Bool("maintenance_mode"),
+		MaintenanceAllowIPs:   appValues.String("maintenance_allow_ips"),
+		MaintenanceRetryAfter: appValues.Duration("maintenance_retry_after", 0),
+
+		// JSON error bodies
+		ProblemDetails:  appValues.Bool("problem_details"),
+		ProblemTypeBase: appValues.String("problem_type_base"),
+
+		// In-memory background job queue
+		JobQueueWorkers: appValues.Int("job_queue_workers"),
+		JobQueueSize:    appValues.Int("job_queue_size"),
+		JobQueueTimeout: appValues.Duration("job_queue_timeout", time.Minute),
+
+		// Feature flags
+		FeatureFlags:    appValues.String("feature_flags"),
+		FeatureRollouts: appValues.String("feature_rollouts"),
+
+		TrustedProxies: appValues.String("trusted_proxies"),
+
+		AllowedHosts: appValues.String("allowed_hosts"),
+
+		ForceHTTPS:          appValues.Bool("force_https"),
+		TrustForwardedProto: appValues.Bool("trust_forwarded_proto"),
+
+		MaxRequestBodyBytes:   int64(appValues.Int("max_request_body_bytes")),
+		MaxRequestHeaderBytes: int64(appValues.Int("max_request_header_bytes")),
+
+		AccessLogSamplePercent:      appValues.Int("access_log_sample_percent"),
+		AccessLogAssetSamplePercent: appValues.Int("access_log_asset_sample_percent"),
+
+		AccessLogBodies:       appValues.Bool("access_log_bodies"),
+		AccessLogBodyMaxBytes: appValues.Int("access_log_body_max_bytes"),
+
+		MetricsEnabled:  appValues.Bool("metrics_enabled"),
+		MetricsPath:     appValues.String("metrics_path"),
+		MetricsAllowIPs: appValues.String("metrics_allow_ips"),
+
+		PprofEnabled: appValues.Bool("pprof_enabled"),
+		PprofPrefix:  appValues.String("pprof_prefix"),
+
+		AssetManifest: appValues.String("asset_manifest"),
 
 		CSRFKey: appValues.String("csrf_key"),
 		APIKey:           appValues.String("api_key"),
@@ -143,6 +275,7 @@ func LoadConfig(logger *zap.Logger) (*config.CoreConfig, AppConfig, error) {
 		StorageCFKeyPath:   appValues.String("storage_cf_key_path"),
 
 		// Email/SMTP
+		MailBackend:  appValues.String("mail_backend"),
 		MailSMTPHost: appValues.String("mail_smtp_host"),
 		MailSMTPPort: appValues.Int("mail_smtp_port"),
 		MailSMTPUser: appValues.String("mail_smtp_user"),
@@ -159,10 +292,18 @@ func LoadConfig(logger *zap.Logger) (*config.CoreConfig, AppConfig, error) {
 		// Audit logging
 		AuditLogAuth:  appValues.String("audit_log_auth"),
 		AuditLogAdmin: appValues.String("audit_log_admin"),
+		AuditLogAccess: appValues.String("audit_log_access"),
+		AuditLogFile:   appValues.String("audit_log_file"),
 
 		// Google OAuth
 		GoogleClientID:     appValues.String("google_client_id"),
 		GoogleClientSecret: appValues.String("google_client_secret"),
+		GoogleSignupRole:   appValues.String("google_signup_role"),
+
+		// CAPTCHA
+		CaptchaProvider:  appValues.String("captcha_provider"),
+		CaptchaSiteKey:   appValues.String("captcha_site_key"),
+		CaptchaSecretKey: appValues.String("captcha_secret_key"),
 
 		// Admin seeding
 		SeedAdminEmail: appValues.String("seed_admin_email"),
@@ -183,5 +324,12 @@ func ValidateConfig(coreCfg *config.CoreConfig, appCfg AppConfig, logger *zap.Lo
 		return fmt.Errorf("invalid MongoDB URI: %w", err)
 	}
 
+	switch appCfg.RateLimitLoginStore {
+	case "", "mongo", "memory":
+	default:
+		logger.Error("invalid rate_limit_login_store", zap.String("value", appCfg.RateLimitLoginStore))
+		return fmt.Errorf("invalid rate_limit_login_store %q: want \"mongo\" or \"memory\"", appCfg.RateLimitLoginStore)
+	}
+
 	return nil
 }
@@ -89,6 +89,7 @@ func ConnectDB(ctx context.Context, coreCfg *config.CoreConfig, appCfg AppConfig
 
 	// Initialize email mailer
 	mail := mailer.New(mailer.Config{
+		Backend:  appCfg.MailBackend,
 		Host:     appCfg.MailSMTPHost,
 		Port:     appCfg.MailSMTPPort,
 		User:     appCfg.MailSMTPUser,
@@ -97,6 +98,7 @@ func ConnectDB(ctx context.Context, coreCfg *config.CoreConfig, appCfg AppConfig
 		FromName: appCfg.MailFromName,
 	}, logger)
 	logger.Info("initialized email mailer",
+		zap.String("backend", appCfg.MailBackend),
 		zap.String("host", appCfg.MailSMTPHost),
 		zap.Int("port", appCfg.MailSMTPPort),
 	)
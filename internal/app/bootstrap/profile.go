@@ -0,0 +1,130 @@
+// internal/app/bootstrap/profile.go
+package bootstrap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	sysconfig "github.com/dalemusser/strataforge/internal/app/system/config"
+	"github.com/dalemusser/waffle/config"
+	"go.uber.org/zap"
+)
+
+// configExts are the config file types WAFFLE reads from the working
+// directory, in the order it merges them.
+var configExts = []string{"yaml", "yml", "json", "toml"}
+
+// applyProfile overlays the per-environment profile selected by APP_ENV
+// (sysconfig.ProfileEnvVar) on the config files WAFFLE loads: with
+// APP_ENV=staging, config.staging.toml next to config.toml (and likewise for
+// the other formats). At least one profile file must exist.
+//
+// WAFFLE has no hook between its file and environment layers, so each
+// profile setting is handed to it as the environment variable it would read
+// for that key, unless that variable is already set. Precedence is then
+// flags > environment > profile > config files > defaults. Entries in .env,
+// which WAFFLE loads afterwards without overriding the environment, lose to
+// the profile. Keys that are not WAFFLE or app settings are logged as
+// warnings and ignored.
+func applyProfile(logger *zap.Logger) error {
+	env := os.Getenv(sysconfig.ProfileEnvVar)
+	if env == "" {
+		return nil
+	}
+
+	values := map[string]any{}
+	var files []string
+	for _, ext := range configExts {
+		path, profile, err := sysconfig.ReadProfile("config."+ext, env)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		files = append(files, path)
+		for key, value := range profile {
+			values[strings.ToLower(key)] = value
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("config: %s=%s but there is no config.%s.* profile", sysconfig.ProfileEnvVar, env, env)
+	}
+
+	known := knownConfigKeys()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] {
+			logger.Warn("config: unknown key in profile", zap.Strings("files", files), zap.String("key", key))
+			continue
+		}
+		name := EnvVarPrefix + "_" + strings.ToUpper(key)
+		if _, set := os.LookupEnv(name); set {
+			continue
+		}
+		value, err := envValue(values[key])
+		if err != nil {
+			return fmt.Errorf("config: profile key %s: %w", key, err)
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	logger.Info("Loaded config profile", zap.String("profile", env), zap.Strings("files", files))
+	return nil
+}
+
+// envValue renders a profile value the way the environment variable for
+// its key would spell it. Lists become JSON arrays, which WAFFLE accepts
+// for its list settings.
+func envValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool, int, int64, float64:
+		return fmt.Sprint(v), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// knownConfigKeys returns the keys WAFFLE's core config and appConfigKeys
+// define. Core keys are read from config.CoreConfig's mapstructure tags, as
+// WAFFLE decodes it.
+func knownConfigKeys() map[string]bool {
+	known := make(map[string]bool, len(appConfigKeys))
+	for _, key := range appConfigKeys {
+		known[key.Name] = true
+	}
+	collectMapstructureKeys(reflect.TypeOf(config.CoreConfig{}), known)
+	return known
+}
+
+// collectMapstructureKeys adds the mapstructure key of each field of struct
+// type t to into, descending into squashed structs.
+func collectMapstructureKeys(t reflect.Type, into map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("mapstructure"), ",")
+		if strings.Contains(opts, "squash") && sf.Type.Kind() == reflect.Struct {
+			collectMapstructureKeys(sf.Type, into)
+			continue
+		}
+		if name != "" && name != "-" {
+			into[name] = true
+		}
+	}
+}
@@ -3,49 +3,75 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	activityfeature "github.com/dalemusser/strataforge/internal/app/features/activity"
 	announcementsfeature "github.com/dalemusser/strataforge/internal/app/features/announcements"
 	apikeysfeature "github.com/dalemusser/strataforge/internal/app/features/apikeys"
 	auditlogfeature "github.com/dalemusser/strataforge/internal/app/features/auditlog"
-	authgooglefeature "github.com/dalemusser/strataforge/internal/app/features/authgoogle"
+	compressfeature "github.com/dalemusser/strataforge/internal/app/features/compress"
+	corsfeature "github.com/dalemusser/strataforge/internal/app/features/cors"
+	csrffeature "github.com/dalemusser/strataforge/internal/app/features/csrf"
 	dashboardfeature "github.com/dalemusser/strataforge/internal/app/features/dashboard"
+	debugfeature "github.com/dalemusser/strataforge/internal/app/features/debug"
 	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	etagfeature "github.com/dalemusser/strataforge/internal/app/features/etag"
 	filesfeature "github.com/dalemusser/strataforge/internal/app/features/files"
+	"github.com/dalemusser/strataforge/internal/app/features/flash"
 	healthfeature "github.com/dalemusser/strataforge/internal/app/features/health"
 	heartbeatfeature "github.com/dalemusser/strataforge/internal/app/features/heartbeat"
 	homefeature "github.com/dalemusser/strataforge/internal/app/features/home"
 	invitationsfeature "github.com/dalemusser/strataforge/internal/app/features/invitations"
 	jobsfeature "github.com/dalemusser/strataforge/internal/app/features/jobs"
 	ledgerfeature "github.com/dalemusser/strataforge/internal/app/features/ledger"
+	limitsfeature "github.com/dalemusser/strataforge/internal/app/features/limits"
 	loginfeature "github.com/dalemusser/strataforge/internal/app/features/login"
 	logoutfeature "github.com/dalemusser/strataforge/internal/app/features/logout"
+	metricsfeature "github.com/dalemusser/strataforge/internal/app/features/metrics"
+	oauthfeature "github.com/dalemusser/strataforge/internal/app/features/oauth"
+	otelfeature "github.com/dalemusser/strataforge/internal/app/features/otel"
 	pagesfeature "github.com/dalemusser/strataforge/internal/app/features/pages"
 	profilefeature "github.com/dalemusser/strataforge/internal/app/features/profile"
+	ratelimitfeature "github.com/dalemusser/strataforge/internal/app/features/ratelimit"
+	rememberfeature "github.com/dalemusser/strataforge/internal/app/features/remember"
+	securityfeature "github.com/dalemusser/strataforge/internal/app/features/security"
+	sessionfeature "github.com/dalemusser/strataforge/internal/app/features/session"
 	settingsfeature "github.com/dalemusser/strataforge/internal/app/features/settings"
+	staticfeature "github.com/dalemusser/strataforge/internal/app/features/static"
 	statsfeature "github.com/dalemusser/strataforge/internal/app/features/stats"
 	statusfeature "github.com/dalemusser/strataforge/internal/app/features/status"
 	systemusersfeature "github.com/dalemusser/strataforge/internal/app/features/systemusers"
+	timeoutfeature "github.com/dalemusser/strataforge/internal/app/features/timeout"
 	appresources "github.com/dalemusser/strataforge/internal/app/resources"
 	"github.com/dalemusser/strataforge/internal/app/store/activity"
 	announcementstore "github.com/dalemusser/strataforge/internal/app/store/announcement"
 	"github.com/dalemusser/strataforge/internal/app/store/audit"
 	"github.com/dalemusser/strataforge/internal/app/store/oauthstate"
 	"github.com/dalemusser/strataforge/internal/app/store/ratelimit"
+	rememberstore "github.com/dalemusser/strataforge/internal/app/store/remember"
 	"github.com/dalemusser/strataforge/internal/app/store/sessions"
 	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
-	"github.com/dalemusser/strataforge/internal/app/system/auth"
 	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/captcha"
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+	"github.com/dalemusser/strataforge/internal/app/system/flags"
+	"github.com/dalemusser/strataforge/internal/app/system/lockout"
+	"github.com/dalemusser/strataforge/internal/app/system/logging"
+	"github.com/dalemusser/strataforge/internal/app/system/templatefuncs"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
 	"github.com/dalemusser/waffle/config"
 	"github.com/dalemusser/waffle/middleware"
 	"github.com/dalemusser/waffle/pantry/fileserver"
 	"github.com/dalemusser/waffle/pantry/templates"
 	"github.com/go-chi/chi/v5"
-	chimw "github.com/go-chi/chi/v5/middleware"
-	"github.com/gorilla/csrf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"go.uber.org/zap"
 )
 
@@ -91,8 +117,42 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	// This ensures role changes, disabled accounts, and profile updates take effect immediately.
 	sessionMgr.SetUserFetcher(userstore.NewFetcher(deps.MongoDatabase, logger))
 
+	// Embedded assets, fingerprinted by content hash for the assetURL template helper.
+	// The frontend build's manifest, when configured, backs the asset helper.
+	staticOpts := []staticfeature.Option{staticfeature.WithLogger(logger)}
+	if appCfg.AssetManifest != "" {
+		manifest, err := staticfeature.LoadManifest(os.DirFS(filepath.Dir(appCfg.AssetManifest)), filepath.Base(appCfg.AssetManifest))
+		if err != nil {
+			logger.Error("asset manifest load failed", zap.String("path", appCfg.AssetManifest), zap.Error(err))
+			return nil, err
+		}
+		staticOpts = append(staticOpts, staticfeature.WithManifest(manifest))
+	}
+	staticAssets := staticfeature.NewHandler(appresources.Assets(), "/assets", staticOpts...)
+
+	// Feature flags: feature_flags are on for everyone, feature_rollouts for a
+	// stable share of signed-in users. Gate routes with flags.Require(featureFlags,
+	// "key", errorsHandler.NotFoundHandler()).
+	rollouts, err := flags.ParseRollout(appCfg.FeatureRollouts)
+	if err != nil {
+		return nil, err
+	}
+	featureFlags := flags.Any(flags.ParseStatic(appCfg.FeatureFlags), rollouts)
+
+	// Register template helpers before the engine parses templates. Names must be
+	// unique across features; a clash fails startup instead of replacing a helper.
+	if err := errors.Join(
+		templatefuncs.RegisterMap(flags.FuncMap(featureFlags)),
+		templatefuncs.RegisterMap(csrffeature.FuncMap()),
+		templatefuncs.RegisterMap(staticAssets.FuncMap()),
+	); err != nil {
+		logger.Error("template function registration failed", zap.Error(err))
+		return nil, err
+	}
+	templatefuncs.Install()
+
 	// Initialize and boot the template engine once at startup.
-	// Dev mode enables template reloading for faster iteration.
+	// In dev, error pages re-parse the templates from disk on every render instead.
 	eng := templates.New(coreCfg.Env == "dev")
 	if err := eng.Boot(logger); err != nil {
 		logger.Error("template engine boot failed", zap.Error(err))
@@ -103,6 +163,9 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	// Initialize viewdata with storage and database for settings loading.
 	viewdata.Init(deps.FileStorage, deps.MongoDatabase)
 
+	// Initialize flash messages, signed with the session key.
+	flash.Init([]byte(appCfg.SessionKey), secure)
+
 	// Set up announcement loader for viewdata.
 	// This allows BaseVM to include active announcements for banner display.
 	annStore := announcementstore.New(deps.MongoDatabase)
@@ -125,16 +188,67 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 		return result
 	})
 
+	// Reverse proxies whose X-Forwarded-For / X-Real-IP headers are believed when
+	// finding the client IP for logs, the maintenance allowlist, and rate limiting.
+	trustedProxies := strings.Split(appCfg.TrustedProxies, ",")
+
 	// Create error logger for handlers.
-	errLog := errorsfeature.NewErrorLogger(logger)
+	// Credentials and tokens are redacted from logged fields and query strings,
+	// and from bodies in the access log when access_log_bodies is on, where any
+	// key containing one of these (new_password, csrf_token) is redacted.
+	redactKeys := []string{"authorization", "cookie", "password", "token", "secret", "api_key"}
+	errLog := errorsfeature.NewErrorLogger(logger,
+		errorsfeature.WithRedactKeys(redactKeys...),
+		errorsfeature.WithClientIP(trustedProxies...),
+	)
+
+	// Prometheus registry for request and error metrics (nil unless metrics_enabled).
+	var metricsReg *prometheus.Registry
+	if appCfg.MetricsEnabled {
+		metricsReg = prometheus.NewRegistry()
+		metricsReg.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+	}
+
+	// Error page handler, shared by the panic recovery middleware and the error routes below.
+	errorsOpts := []errorsfeature.Option{
+		errorsfeature.WithErrorLogger(errLog),
+		errorsfeature.WithEngine(eng),
+		errorsfeature.WithReload(coreCfg.Env == "dev"),
+		errorsfeature.WithMaintenanceAllowlist(strings.Split(appCfg.MaintenanceAllowIPs, ",")...),
+		errorsfeature.WithMaintenanceRetryAfter(appCfg.MaintenanceRetryAfter),
+		errorsfeature.WithTrustedProxies(trustedProxies...),
+		errorsfeature.WithProblemDetails(appCfg.ProblemDetails),
+		errorsfeature.WithProblemTypeBase(appCfg.ProblemTypeBase),
+		// Error details on the 500 page, never outside development.
+		errorsfeature.WithDebug(coreCfg.Env == "dev"),
+	}
+	if metricsReg != nil {
+		errorsOpts = append(errorsOpts, errorsfeature.WithMetrics(metricsReg))
+	}
+	errorsHandler := errorsfeature.NewHandler(errorsOpts...)
+	errorsHandler.SetMaintenance(appCfg.MaintenanceMode)
+	sessionMgr.SetUnauthorizedHandler(errorsHandler.Unauthorized)
+	sessionMgr.SetForbiddenHandler(errorsHandler.Forbidden)
 
 	// Create audit store and logger for security event tracking.
 	auditStore := audit.New(deps.MongoDatabase)
 	auditConfig := auditlog.Config{
-		Auth:  appCfg.AuditLogAuth,
-		Admin: appCfg.AuditLogAdmin,
+		Auth:           appCfg.AuditLogAuth,
+		Admin:          appCfg.AuditLogAdmin,
+		Access:         appCfg.AuditLogAccess,
+		TrustedProxies: trustedProxies,
 	}
-	auditLogger := auditlog.New(auditStore, logger, auditConfig)
+	auditZap, err := auditlog.NewZapLogger(appCfg.AuditLogFile, logger)
+	if err != nil {
+		logger.Error("audit log init failed", zap.Error(err), zap.String("path", appCfg.AuditLogFile))
+		return nil, err
+	}
+	auditLogger := auditlog.New(auditStore, auditZap, auditConfig)
+	// Audit role checks and 401/403 denials from the session manager.
+	sessionMgr.SetAccessHook(auditLogger.AccessChecked)
 
 	// Create sessions store for activity tracking.
 	sessionsStore := sessions.New(deps.MongoDatabase)
@@ -142,81 +256,241 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	// Create activity store for logging user events.
 	activityStore := activity.New(deps.MongoDatabase)
 
+	// Remember-me tokens: keep users logged in across browser restarts (nil if disabled)
+	var rememberHandler *rememberfeature.Handler
+	if appCfg.RememberMeEnabled {
+		rememberHandler = rememberfeature.NewHandler(
+			rememberstore.New(deps.MongoDatabase, appCfg.RememberMeMaxAge),
+			sessionMgr,
+			deps.MongoDatabase,
+			sessionsStore,
+			auditLogger,
+			errLog,
+			rememberfeature.CookieOptions{Domain: appCfg.SessionDomain, Secure: secure},
+			logger,
+		)
+		rememberHandler.SetTrustedProxies(trustedProxies...)
+	}
+
 	r := chi.NewRouter()
 
-	// Request timeout middleware: prevents requests from hanging indefinitely.
-	// Requests exceeding 30 seconds will be cancelled and return a 503 Service Unavailable.
-	r.Use(chimw.Timeout(30 * time.Second))
+	// Request ID middleware: reuses an incoming X-Request-ID or generates one,
+	// and echoes it on the response so error logs can be traced from a user report.
+	r.Use(errorsfeature.RequestIDMiddleware())
+
+	// Route matcher: lets the not-found handler tell "no such path" (404)
+	// from "path exists, wrong method" (405 with Allow).
+	r.Use(errorsfeature.RouteMatcherMiddleware(r))
+
+	// CSP nonces: a fresh nonce per request for inline scripts (templates use
+	// {{ cspNonce .CSPNonce }}), added to the script-src of content_security_policy and of
+	// any other policy header when the response is sent. Runs ahead of every middleware
+	// that can write an error page so those pages carry a valid nonce too.
+	r.Use(csp.Middleware())
+
+	healthPaths := []string{"/health", "/ready", "/readyz", "/livez", "/healthz"}
+
+	// Tracing middleware: one OpenTelemetry server span per request, continuing an
+	// incoming traceparent. Error log lines carry its trace_id and span_id. Spans go
+	// to the global tracer provider, a no-op until one is installed.
+	r.Use(otelfeature.Middleware(otelfeature.WithSkipPaths(healthPaths...)))
+
+	// Request metrics: a duration histogram labelled by method, route pattern, and status,
+	// and an in-flight gauge, served at metrics_path. Health probes and scrapes are not counted.
+	if metricsReg != nil {
+		r.Use(metricsfeature.Middleware(metricsReg,
+			metricsfeature.WithSkipPaths(healthPaths...),
+			metricsfeature.WithSkipPaths(appCfg.MetricsPath),
+		))
+	}
+
+	// Access log middleware: one structured line per request, tagged with the request ID
+	// so it can be matched against error log lines. Health probes are not logged, and
+	// successful requests can be sampled (per request ID) to trim healthy traffic.
+	// Bodies are only recorded while debugging, with access_log_bodies.
+	accessLogOpts := []logging.Option{
+		logging.WithSkipPaths(healthPaths...),
+		logging.WithTrustedProxies(trustedProxies...),
+		logging.WithSuccessSampleRate(float64(appCfg.AccessLogSamplePercent) / 100),
+		logging.WithPathSampleRate("/assets/", float64(appCfg.AccessLogAssetSamplePercent)/100),
+	}
+	if appCfg.AccessLogBodies {
+		logger.Warn("access log is recording request and response bodies; turn access_log_bodies off when done debugging",
+			zap.Int("max_bytes", appCfg.AccessLogBodyMaxBytes))
+		accessLogOpts = append(accessLogOpts, logging.WithBodies(appCfg.AccessLogBodyMaxBytes, redactKeys...))
+	}
+	r.Use(logging.Middleware(logger, accessLogOpts...))
+
+	// CORS: answers preflights from the origins in cors_allowed_origins and adds
+	// Access-Control-* headers to their requests. It runs ahead of host checks, HTTPS
+	// redirects, size limits, maintenance, rate limiting, and timeouts, so a preflight
+	// never meets those and their error responses still carry CORS headers for the
+	// calling page to read. Only active when enable_cors=true in config.
+	if coreCfg.CORS.EnableCORS {
+		r.Use(corsfeature.Middleware(corsfeature.Options{
+			AllowedOrigins:   coreCfg.CORS.CORSAllowedOrigins,
+			AllowedMethods:   coreCfg.CORS.CORSAllowedMethods,
+			AllowedHeaders:   coreCfg.CORS.CORSAllowedHeaders,
+			ExposedHeaders:   coreCfg.CORS.CORSExposedHeaders,
+			AllowCredentials: coreCfg.CORS.CORSAllowCredentials,
+			MaxAge:           time.Duration(coreCfg.CORS.CORSMaxAge) * time.Second,
+		}))
+	}
+
+	// Host header validation: requests for a host not listed in allowed_hosts get the
+	// 400 page, so forged Host headers never reach links the app builds. Empty allows any.
+	r.Use(securityfeature.AllowedHosts(errorsHandler, strings.Split(appCfg.AllowedHosts, ",")...))
+
+	// Request size limits: oversized headers get the 431 page and bodies declared larger
+	// than max_request_body_bytes the 413 page; other bodies are capped as they are read.
+	r.Use(limitsfeature.Middleware(errorsHandler, limitsfeature.Options{
+		MaxHeaderBytes: appCfg.MaxRequestHeaderBytes,
+		MaxBodyBytes:   appCfg.MaxRequestBodyBytes,
+	}))
+
+	// HTTPS enforcement: plain-HTTP requests are redirected to HTTPS and secure responses
+	// carry HSTS, using the core hsts_* settings. Health probes may stay on plain HTTP.
+	// With TLS terminated at a proxy, trust_forwarded_proto reads the original scheme.
+	if appCfg.ForceHTTPS {
+		r.Use(securityfeature.ForceHTTPS(securityfeature.Options{
+			TrustXForwardedProto: appCfg.TrustForwardedProto,
+			HSTSMaxAge:           time.Duration(coreCfg.Security.HSTSMaxAge) * time.Second,
+			IncludeSubdomains:    coreCfg.Security.HSTSIncludeSubDomains,
+			Preload:              coreCfg.Security.HSTSPreload,
+			ExemptPaths:          healthPaths,
+		}))
+	}
+
+	// Response compression: gzip/deflate for clients that accept it, wrapping everything
+	// below so error pages are compressed too. Controlled by enable_compression.
+	if coreCfg.EnableCompression {
+		r.Use(compressfeature.Middleware(
+			compressfeature.WithLevel(coreCfg.CompressionLevel),
+			compressfeature.WithMinSize(appCfg.CompressionMinSize),
+		))
+	}
+
+	// Conditional requests: successful GET pages get a strong ETag, and a matching
+	// If-None-Match is answered with 304. Runs inside compression so tags are computed
+	// over the uncompressed page. Handlers opt out with etagfeature.Skip(r). It runs
+	// outside panic recovery, whose 500 page passes through untagged.
+	r.Use(etagfeature.Middleware())
+
+	// Panic recovery middleware: catches panics from the middleware below it and from
+	// handlers, logs them, and renders the 500 page. It sits inside ETags, compression,
+	// size limits, access logging, metrics, and tracing so that page is compressed and the
+	// request is still logged, counted, and traced with status 500. A panic in one of
+	// those outer layers is not caught here; net/http recovers it and drops the connection.
+	r.Use(errorsHandler.Recover)
+
+	// Maintenance mode middleware: while maintenance is on, every request except those
+	// from allow-listed IPs gets the 503 maintenance page. Toggle at runtime with
+	// errorsHandler.SetMaintenance.
+	r.Use(errorsHandler.Maintenance)
+
+	// Per-IP request throttling: clients over the limit get the 429 page with a Retry-After
+	// header. Only active when rate_limit_requests_per_minute > 0.
+	if appCfg.RateLimitRequestsPerMinute > 0 {
+		r.Use(ratelimitfeature.Middleware(float64(appCfg.RateLimitRequestsPerMinute)/60, appCfg.RateLimitBurst, nil,
+			ratelimitfeature.WithErrorHandler(errorsHandler),
+			ratelimitfeature.WithLogger(logger),
+			ratelimitfeature.WithTrustedProxies(trustedProxies...),
+		))
+	}
 
-	// CORS middleware: must be early in the chain to handle preflight requests.
-	// Only active when enable_cors=true in config.
-	r.Use(middleware.CORSFromConfig(coreCfg))
+	// Request timeout middleware: prevents requests from hanging indefinitely.
+	// Requests exceeding 30 seconds have their context cancelled; if the handler has not
+	// started responding by then, the client gets the 504 Gateway Timeout page.
+	// WebSocket and Server-Sent Events routes must be listed with WithExemptPaths.
+	r.Use(timeoutfeature.Middleware(30*time.Second, timeoutfeature.WithErrorHandler(errorsHandler)))
 
 	// Security headers middleware: adds X-Frame-Options, X-Content-Type-Options, etc.
 	// Enabled by default with secure values. Configure via enable_security_headers and related options.
 	r.Use(middleware.SecurityHeadersFromConfig(coreCfg))
 
+	// Remember-me middleware: restores the session from a remember-me cookie when
+	// there is none. Must run before LoadSessionUser so the user is loaded this request.
+	if rememberHandler != nil {
+		r.Use(rememberHandler.Middleware)
+	}
+
 	// Global auth middleware: loads SessionUser into context if logged in.
 	// This makes the current user available to all handlers via auth.CurrentUser(r).
 	r.Use(sessionMgr.LoadSessionUser)
 
+	// Request-scoped logger: handlers call logging.FromContext(r.Context()) to get a logger
+	// already tagged with request_id, user_id, and route. Error log lines pick up the same fields,
+	// and the access log line gets the user_id too. Routes that authenticate later (bearer
+	// tokens, say) add it with logging.UserMiddleware.
+	r.Use(logging.ContextMiddleware(logger, func(r *http.Request) string {
+		if u, ok := auth.CurrentUser(r); ok {
+			return u.ID
+		}
+		return ""
+	}))
+
+	// Feature session middleware: loads general-purpose session values (flash messages,
+	// preferences) into the request context and saves them if a handler changes them.
+	r.Use(sessionfeature.Middleware(sessionfeature.NewCookieStore([]byte(appCfg.SessionKey), nil, sessionfeature.CookieOptions{
+		Domain: appCfg.SessionDomain,
+		MaxAge: appCfg.SessionMaxAge,
+		Secure: secure,
+	}), logger))
+
 	// CSRF protection middleware: protects POST/PUT/DELETE requests from cross-site request forgery.
 	// The CSRF token must be included in forms as a hidden field or in the X-CSRF-Token header.
-	csrfOpts := []csrf.Option{
-		csrf.Secure(secure),
-		csrf.Path("/"),
-		csrf.CookieName("csrf_token"),
-		csrf.FieldName("csrf_token"),
-		csrf.SameSite(csrf.SameSiteLaxMode),
-		csrf.ErrorHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			logger.Warn("CSRF validation failed",
-				zap.String("path", r.URL.Path),
-				zap.String("method", r.Method),
-				zap.String("reason", csrf.FailureReason(r).Error()),
-			)
-			if r.Header.Get("HX-Request") == "true" {
-				w.Header().Set("HX-Redirect", "/login")
-				w.WriteHeader(http.StatusForbidden)
-				return
-			}
-			http.Error(w, "CSRF token invalid or missing", http.StatusForbidden)
-		})),
+	// Failures are rendered with the 403 error page.
+	csrfOpts := []csrffeature.Option{
+		csrffeature.WithSecure(secure),
+		csrffeature.WithErrorHandler(errorsHandler),
+		csrffeature.WithLogger(logger),
 	}
-	// In dev mode, trust localhost origins for CSRF validation.
 	// gorilla/csrf validates the Origin header's Host against TrustedOrigins (not the full URL).
-	trustedOrigins := []string{
-		"localhost:8080",
-		"localhost:3000",
-		"127.0.0.1:8080",
-		"127.0.0.1:3000",
+	// The CORS allowlist is trusted in every mode, so its credentialed requests (which
+	// still need X-CSRF-Token) are not refused for their origin.
+	if coreCfg.CORS.EnableCORS {
+		csrfOpts = append(csrfOpts, csrffeature.WithTrustedOrigins(corsfeature.Hosts(coreCfg.CORS.CORSAllowedOrigins)...))
 	}
+	// In dev mode, also trust localhost origins.
 	if !secure {
-		csrfOpts = append(csrfOpts, csrf.TrustedOrigins(trustedOrigins))
+		csrfOpts = append(csrfOpts, csrffeature.WithTrustedOrigins(
+			"localhost:8080",
+			"localhost:3000",
+			"127.0.0.1:8080",
+			"127.0.0.1:3000",
+		))
 	}
 	// Set CSRF cookie domain to match session domain when configured.
 	// This ensures CSRF cookie is shared across subdomains (if any).
 	if appCfg.SessionDomain != "" {
-		csrfOpts = append(csrfOpts, csrf.Domain(appCfg.SessionDomain))
+		csrfOpts = append(csrfOpts, csrffeature.WithDomain(appCfg.SessionDomain))
 	}
-	csrfMiddleware := csrf.Protect([]byte(appCfg.CSRFKey), csrfOpts...)
-	r.Use(csrfMiddleware)
+	r.Use(csrffeature.Middleware([]byte(appCfg.CSRFKey), csrfOpts...))
 
 	// Health check endpoints for load balancers and orchestrators
 	// Provides:
 	//   /health      - full health check with service status
 	//   /health/ready, /health/live - sub-routes
 	//   /ready, /readyz - Kubernetes readiness probes (root level)
-	//   /livez       - Kubernetes liveness probe (root level)
+	//   /livez, /healthz - Kubernetes liveness probes (root level)
 	healthHandler := healthfeature.NewHandler(deps.MongoClient, logger)
 	r.Mount("/health", healthfeature.Routes(healthHandler))
 	healthfeature.MountRootEndpoints(r, healthHandler)
 
+	// Prometheus scrape endpoint (registered only when metrics are enabled), limited
+	// to the scrapers listed in metrics_allow_ips.
+	if metricsReg != nil {
+		r.With(securityfeature.AllowedIPs(errorsHandler, trustedProxies, strings.Split(appCfg.MetricsAllowIPs, ",")...)).
+			Method(http.MethodGet, appCfg.MetricsPath, metricsfeature.Handler(metricsReg))
+	}
+
 	// Static assets with pre-compressed file support (gzip/brotli)
 	// /static/* serves files from disk (static directory)
 	r.Handle("/static/*", fileserver.Handler("/static", "static"))
 
-	// /assets/* serves embedded assets (bundled into the binary)
-	r.Handle("/assets/*", appresources.AssetsHandler("/assets"))
+	// /assets/* serves embedded assets (bundled into the binary).
+	// Fingerprinted URLs from assetURL are cached as immutable; unknown paths get the 404 page.
+	r.Handle("/assets/*", staticfeature.Routes(staticAssets, errorsHandler.NotFoundHandler()))
 
 	// Uploaded files (local storage only)
 	// When using local storage, serve files from the configured path
@@ -236,6 +510,14 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	r.Mount("/privacy", pagesHandler.PrivacyRouter())
 	r.Mount("/pages", pagesfeature.EditRoutes(pagesHandler, sessionMgr))
 
+	// CAPTCHA on the public auth forms (no-op unless a provider is configured)
+	captchaVerifier, err := captcha.NewVerifier(appCfg.CaptchaProvider, appCfg.CaptchaSecretKey)
+	if err != nil {
+		logger.Error("captcha setup failed", zap.Error(err))
+		return nil, err
+	}
+	captchaWidget := captcha.Widget{Provider: appCfg.CaptchaProvider, SiteKey: appCfg.CaptchaSiteKey}
+
 	// User Invitations (public accept route)
 	invitationsHandler := invitationsfeature.NewHandler(
 		deps.MongoDatabase,
@@ -248,6 +530,9 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 		7*24*time.Hour, // 7 days expiry
 		logger,
 	)
+	invitationsHandler.SetCaptcha(captchaVerifier, captchaWidget)
+	invitationsHandler.SetTrustedProxies(trustedProxies...)
+	invitationsHandler.SetJobQueue(jobQueue)
 	r.Mount("/invite", invitationsfeature.AcceptRoutes(invitationsHandler))
 
 	// Authentication
@@ -255,15 +540,20 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	// Trust login is only enabled in dev mode for security - it allows passwordless login
 	trustLoginEnabled := coreCfg.Env == "dev"
 
-	// Rate limiting for login attempts (nil if disabled)
-	var rateLimitStore *ratelimit.Store
+	// Lockout after repeated failed logins (nil if disabled)
+	var loginLockout *lockout.Lockout
 	if appCfg.RateLimitEnabled {
-		rateLimitStore = ratelimit.New(
-			deps.MongoDatabase,
-			appCfg.RateLimitLoginAttempts,
-			appCfg.RateLimitLoginWindow,
-			appCfg.RateLimitLoginLockout,
-		)
+		// rate_limit_login_store is checked in ValidateConfig.
+		var lockoutStore lockout.Store = ratelimit.NewLockoutStore(deps.MongoDatabase)
+		if appCfg.RateLimitLoginStore == "memory" {
+			lockoutStore = lockout.NewMemoryStore()
+		}
+		loginLockout = lockout.New(lockoutStore, lockout.Config{
+			MaxAttempts:   appCfg.RateLimitLoginAttempts,
+			IPMaxAttempts: appCfg.RateLimitLoginIPAttempts,
+			Window:        appCfg.RateLimitLoginWindow,
+			Duration:      appCfg.RateLimitLoginLockout,
+		})
 	}
 
 	loginHandler := loginfeature.NewHandler(
@@ -274,16 +564,25 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 		auditLogger,
 		sessionsStore,
 		activityStore,
-		rateLimitStore,
+		loginLockout,
 		appCfg.BaseURL,
 		appCfg.EmailVerifyExpiry,
 		googleEnabled,
 		trustLoginEnabled,
 		logger,
 	)
+	loginHandler.SetCaptcha(captchaVerifier, captchaWidget)
+	loginHandler.SetErrorHandler(errorsHandler)
+	loginHandler.SetTrustedProxies(trustedProxies...)
+	if rememberHandler != nil {
+		loginHandler.SetRemember(rememberHandler)
+	}
 	r.Mount("/login", loginfeature.Routes(loginHandler))
 
 	logoutHandler := logoutfeature.NewHandler(sessionMgr, auditLogger, sessionsStore, logger)
+	if rememberHandler != nil {
+		logoutHandler.SetRemember(rememberHandler)
+	}
 	r.Mount("/logout", logoutfeature.Routes(logoutHandler, sessionMgr))
 
 	// Heartbeat API for activity tracking
@@ -291,22 +590,25 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	heartbeatHandler.SetIdleLogoutConfig(appCfg.IdleLogoutEnabled, appCfg.IdleLogoutTimeout, appCfg.IdleLogoutWarning)
 	r.Mount("/api/heartbeat", heartbeatfeature.Routes(heartbeatHandler, sessionMgr))
 
-	// Google OAuth (only mount if configured)
+	// Google OAuth (only mount if configured). State is checked against both a
+	// single-use record and a browser-bound cookie, and the code exchange uses PKCE.
 	if googleEnabled {
-		oauthStateStore := oauthstate.New(deps.MongoDatabase)
-		googleHandler := authgooglefeature.NewHandler(
-			deps.MongoDatabase,
+		googleHandler := oauthfeature.NewHandler(
+			oauthfeature.Google(appCfg.GoogleClientID, appCfg.GoogleClientSecret, appCfg.BaseURL+"/auth/google/callback"),
+			userstore.New(deps.MongoDatabase),
 			sessionMgr,
 			errLog,
 			auditLogger,
 			sessionsStore,
-			oauthStateStore,
-			appCfg.GoogleClientID,
-			appCfg.GoogleClientSecret,
-			appCfg.BaseURL,
+			oauthstate.New(deps.MongoDatabase),
+			[]byte(appCfg.SessionKey),
+			secure,
 			logger,
 		)
-		r.Mount("/auth/google", authgooglefeature.Routes(googleHandler))
+		googleHandler.SetErrorHandler(errorsHandler)
+		googleHandler.SetSignupRole(appCfg.GoogleSignupRole)
+		googleHandler.SetTrustedProxies(trustedProxies...)
+		r.Mount("/auth/google", oauthfeature.Routes(googleHandler))
 		logger.Info("Google OAuth enabled", zap.String("redirect_url", appCfg.BaseURL+"/auth/google/callback"))
 	}
 
@@ -318,7 +620,6 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	})
 
 	// Error pages
-	errorsHandler := errorsfeature.NewHandler()
 	r.Get("/forbidden", errorsHandler.Forbidden)
 	r.Get("/unauthorized", errorsHandler.Unauthorized)
 	r.Get("/troubleshooting", errorsHandler.Troubleshooting)
@@ -345,8 +646,14 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	sessionsHandler := dashboardfeature.NewSessionsHandler(deps.MongoDatabase, sessionsStore, logger)
 	r.Mount("/dashboard/sessions", dashboardfeature.SessionsRoutes(sessionsHandler, sessionMgr))
 
+	// Profiling endpoints (admin only, registered only when enabled)
+	if appCfg.PprofEnabled {
+		r.Mount(appCfg.PprofPrefix, debugfeature.Routes(sessionMgr, debugfeature.WithErrorHandler(errorsHandler)))
+	}
+
 	// System user management (admin only)
 	sysUsersHandler := systemusersfeature.NewHandler(deps.MongoDatabase, deps.Mailer, errLog, auditLogger, logger)
+	sysUsersHandler.SetJobQueue(jobQueue)
 	r.Mount("/system-users", systemusersfeature.Routes(sysUsersHandler, sessionMgr))
 
 	// Audit log (admin only)
@@ -376,46 +683,57 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 
 	// System status page (admin only)
 	statusAppCfg := statusfeature.AppConfig{
-		MongoURI:           appCfg.MongoURI,
-		MongoDatabase:      appCfg.MongoDatabase,
-		MongoMaxPoolSize:   appCfg.MongoMaxPoolSize,
-		MongoMinPoolSize:   appCfg.MongoMinPoolSize,
-		SessionKey:         appCfg.SessionKey,
-		SessionName:        appCfg.SessionName,
-		SessionDomain:      appCfg.SessionDomain,
-		SessionMaxAge:      appCfg.SessionMaxAge,
-		IdleLogoutEnabled:      appCfg.IdleLogoutEnabled,
-		IdleLogoutTimeout:      appCfg.IdleLogoutTimeout,
-		IdleLogoutWarning:      appCfg.IdleLogoutWarning,
-		RateLimitEnabled:       appCfg.RateLimitEnabled,
-		RateLimitLoginAttempts: appCfg.RateLimitLoginAttempts,
-		RateLimitLoginWindow:   appCfg.RateLimitLoginWindow,
-		RateLimitLoginLockout:  appCfg.RateLimitLoginLockout,
-		CSRFKey:                appCfg.CSRFKey,
-		APIKey:                 appCfg.APIKey,
-		StorageType:        appCfg.StorageType,
-		StorageLocalPath:   appCfg.StorageLocalPath,
-		StorageLocalURL:    appCfg.StorageLocalURL,
-		StorageS3Region:    appCfg.StorageS3Region,
-		StorageS3Bucket:    appCfg.StorageS3Bucket,
-		StorageS3Prefix:    appCfg.StorageS3Prefix,
-		StorageCFURL:       appCfg.StorageCFURL,
-		StorageCFKeyPairID: appCfg.StorageCFKeyPairID,
-		StorageCFKeyPath:   appCfg.StorageCFKeyPath,
-		MailSMTPHost:       appCfg.MailSMTPHost,
-		MailSMTPPort:       appCfg.MailSMTPPort,
-		MailSMTPUser:       appCfg.MailSMTPUser,
-		MailSMTPPass:       appCfg.MailSMTPPass,
-		MailFrom:           appCfg.MailFrom,
-		MailFromName:       appCfg.MailFromName,
-		BaseURL:            appCfg.BaseURL,
-		EmailVerifyExpiry:  appCfg.EmailVerifyExpiry,
-		AuditLogAuth:       appCfg.AuditLogAuth,
-		AuditLogAdmin:      appCfg.AuditLogAdmin,
-		GoogleClientID:     appCfg.GoogleClientID,
-		GoogleClientSecret: appCfg.GoogleClientSecret,
-		SeedAdminEmail:     appCfg.SeedAdminEmail,
-		SeedAdminName:      appCfg.SeedAdminName,
+		MongoURI:                 appCfg.MongoURI,
+		MongoDatabase:            appCfg.MongoDatabase,
+		MongoMaxPoolSize:         appCfg.MongoMaxPoolSize,
+		MongoMinPoolSize:         appCfg.MongoMinPoolSize,
+		SessionKey:               appCfg.SessionKey,
+		SessionName:              appCfg.SessionName,
+		SessionDomain:            appCfg.SessionDomain,
+		SessionMaxAge:            appCfg.SessionMaxAge,
+		RememberMeEnabled:        appCfg.RememberMeEnabled,
+		RememberMeMaxAge:         appCfg.RememberMeMaxAge,
+		IdleLogoutEnabled:        appCfg.IdleLogoutEnabled,
+		IdleLogoutTimeout:        appCfg.IdleLogoutTimeout,
+		IdleLogoutWarning:        appCfg.IdleLogoutWarning,
+		RateLimitEnabled:         appCfg.RateLimitEnabled,
+		RateLimitLoginAttempts:   appCfg.RateLimitLoginAttempts,
+		RateLimitLoginWindow:     appCfg.RateLimitLoginWindow,
+		RateLimitLoginLockout:    appCfg.RateLimitLoginLockout,
+		RateLimitLoginIPAttempts: appCfg.RateLimitLoginIPAttempts,
+		RateLimitLoginStore:      appCfg.RateLimitLoginStore,
+		CSRFKey:                  appCfg.CSRFKey,
+		APIKey:                   appCfg.APIKey,
+		StorageType:              appCfg.StorageType,
+		StorageLocalPath:         appCfg.StorageLocalPath,
+		StorageLocalURL:          appCfg.StorageLocalURL,
+		StorageS3Region:          appCfg.StorageS3Region,
+		StorageS3Bucket:          appCfg.StorageS3Bucket,
+		StorageS3Prefix:          appCfg.StorageS3Prefix,
+		StorageCFURL:             appCfg.StorageCFURL,
+		StorageCFKeyPairID:       appCfg.StorageCFKeyPairID,
+		StorageCFKeyPath:         appCfg.StorageCFKeyPath,
+		MailBackend:              appCfg.MailBackend,
+		MailSMTPHost:             appCfg.MailSMTPHost,
+		MailSMTPPort:             appCfg.MailSMTPPort,
+		MailSMTPUser:             appCfg.MailSMTPUser,
+		MailSMTPPass:             appCfg.MailSMTPPass,
+		MailFrom:                 appCfg.MailFrom,
+		MailFromName:             appCfg.MailFromName,
+		BaseURL:                  appCfg.BaseURL,
+		EmailVerifyExpiry:        appCfg.EmailVerifyExpiry,
+		AuditLogAuth:             appCfg.AuditLogAuth,
+		AuditLogAdmin:            appCfg.AuditLogAdmin,
+		AuditLogAccess:           appCfg.AuditLogAccess,
+		AuditLogFile:             appCfg.AuditLogFile,
+		GoogleClientID:           appCfg.GoogleClientID,
+		GoogleClientSecret:       appCfg.GoogleClientSecret,
+		GoogleSignupRole:         appCfg.GoogleSignupRole,
+		CaptchaProvider:          appCfg.CaptchaProvider,
+		CaptchaSiteKey:           appCfg.CaptchaSiteKey,
+		CaptchaSecretKey:         appCfg.CaptchaSecretKey,
+		SeedAdminEmail:           appCfg.SeedAdminEmail,
+		SeedAdminName:            appCfg.SeedAdminName,
 	}
 	statusHandler := statusfeature.NewHandler(deps.MongoClient, appCfg.BaseURL, coreCfg, statusAppCfg, logger)
 	r.Mount("/admin/status", statusfeature.Routes(statusHandler, sessionMgr))
@@ -448,8 +766,29 @@ func BuildHandler(coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, log
 	statsHandler := statsfeature.NewHandler(deps.MongoDatabase, errLog, logger)
 	r.Mount("/stats", statsfeature.Routes(statsHandler, sessionMgr))
 
-	// 404 catch-all for unmatched routes
-	r.NotFound(errorsHandler.NotFound)
+	// Unmatched requests: 405 with Allow when the path exists for other
+	// methods, 404 otherwise
+	r.NotFound(errorsHandler.DispatchHandler().ServeHTTP)
+	r.MethodNotAllowed(errorsHandler.DispatchHandler().ServeHTTP)
+
+	// "Did you mean" hints on 404 pages in development (shown only with WithDebug).
+	if coreCfg.Env == "dev" {
+		var patterns []string
+		_ = chi.Walk(r, func(_ string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			patterns = append(patterns, route)
+			return nil
+		})
+		errorsHandler.SetRoutes(patterns)
+	}
+
+	// Run the lifecycle start hooks registered in Startup and above, now that
+	// everything is built and before requests are served.
+	if appLifecycle != nil {
+		if err := appLifecycle.Start(context.Background()); err != nil {
+			logger.Error("lifecycle start failed", zap.Error(err))
+			return nil, err
+		}
+	}
 
 	return r, nil
 }
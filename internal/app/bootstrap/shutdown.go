@@ -17,7 +17,10 @@ import (
 // The context provided has a timeout (default 10 seconds) and should be
 // respected—if cleanup takes too long, the context will be cancelled.
 //
-// Common uses for Shutdown:
+// Cleanup is done by appLifecycle's stop hooks, which run in the reverse of
+// the order they were registered in Startup and BuildHandler: the job queue
+// drains, then the task runner stops, and MongoDB disconnects last. Register
+// a stop hook there rather than adding cleanup here. Common uses:
 //   - Close database connections
 //   - Flush pending writes to external services
 //   - Stop background workers gracefully
@@ -25,32 +28,11 @@ import (
 //   - Release file handles or network connections
 //
 // If an error is returned, it will be logged but won't prevent the process
-// from exiting. However, returning nil on success helps ensure clean shutdown
-// behavior and accurate logging.
+// from exiting. Every stop hook runs even if an earlier one fails; their
+// errors are returned together.
 func Shutdown(ctx context.Context, coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, logger *zap.Logger) error {
-	var firstErr error
-
-	// Stop background task runner with context timeout
-	if taskRunner != nil {
-		logger.Info("stopping background task runner")
-		if err := taskRunner.Stop(ctx); err != nil {
-			logger.Warn("background task runner did not stop cleanly", zap.Error(err))
-			if firstErr == nil {
-				firstErr = err
-			}
-		}
-	}
-
-	// Disconnect MongoDB client
-	if deps.MongoClient != nil {
-		logger.Info("disconnecting MongoDB client")
-		if err := deps.MongoClient.Disconnect(ctx); err != nil {
-			logger.Error("MongoDB disconnect failed", zap.Error(err))
-			if firstErr == nil {
-				firstErr = err
-			}
-		}
+	if appLifecycle == nil {
+		return nil
 	}
-
-	return firstErr
+	return appLifecycle.Stop(ctx)
 }
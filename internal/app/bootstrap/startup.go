@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/dalemusser/strataforge/internal/app/resources"
+	"github.com/dalemusser/strataforge/internal/app/system/jobqueue"
+	"github.com/dalemusser/strataforge/internal/app/system/lifecycle"
 	"github.com/dalemusser/strataforge/internal/app/system/tasks"
 	"github.com/dalemusser/strataforge/internal/domain/models"
 	"github.com/dalemusser/waffle/config"
@@ -34,7 +36,8 @@ import (
 //   - Warm caches with frequently accessed data
 //   - Initialize in-memory lookup tables
 //   - Validate external service connectivity
-//   - Set up background workers or scheduled tasks
+//   - Set up background workers or scheduled tasks, registering their start
+//     and stop with appLifecycle
 //   - Perform health checks on dependencies
 //
 // Returning a non-nil error will abort startup and prevent the server from
@@ -45,6 +48,13 @@ import (
 func Startup(ctx context.Context, coreCfg *config.CoreConfig, appCfg AppConfig, deps DBDeps, logger *zap.Logger) error {
 	resources.LoadSharedTemplates()
 
+	// Resources with start and stop work register hooks here and in BuildHandler.
+	// Stop hooks run in reverse, so MongoDB, registered first, disconnects last.
+	appLifecycle = lifecycle.New(lifecycle.WithLogger(logger))
+	if deps.MongoClient != nil {
+		appLifecycle.OnStop("mongodb", deps.MongoClient.Disconnect)
+	}
+
 	// Note: Indexes are created in EnsureSchema via indexes.EnsureAll().
 	// Store-level EnsureIndexes() calls are not needed here.
 
@@ -56,17 +66,39 @@ func Startup(ctx context.Context, coreCfg *config.CoreConfig, appCfg AppConfig,
 		}
 	}
 
-	// Start background task runner
-	startTaskRunner(deps.MongoDatabase, logger)
+	// Background task runner, started with the other lifecycle hooks
+	registerTaskRunner(deps.MongoDatabase, logger)
+
+	// Start the in-memory queue for work handlers hand off after responding.
+	// Its jobs may still need the database, so it drains first.
+	jobQueue = jobqueue.New(jobqueue.Config{
+		Workers:    appCfg.JobQueueWorkers,
+		QueueSize:  appCfg.JobQueueSize,
+		JobTimeout: appCfg.JobQueueTimeout,
+	}, logger)
+	appLifecycle.OnStop("job queue", func(ctx context.Context) error {
+		logger.Info("draining background job queue", zap.Int("queued", jobQueue.Len()))
+		return jobQueue.Stop(ctx)
+	})
 
 	return nil
 }
 
+// appLifecycle holds the app's start and stop hooks. BuildHandler runs the
+// start hooks once the routes are built; Shutdown runs the stop hooks.
+var appLifecycle *lifecycle.Lifecycle
+
+// jobQueue is the global in-memory job queue. BuildHandler hands it to the
+// features that send email after responding; it is drained during graceful
+// shutdown.
+var jobQueue *jobqueue.Queue
+
 // taskRunner is the global task runner instance, used for graceful shutdown.
 var taskRunner *tasks.Runner
 
-// startTaskRunner initializes and starts the background task runner.
-func startTaskRunner(db *mongo.Database, logger *zap.Logger) {
+// registerTaskRunner initializes the background task runner and registers
+// its start and stop with appLifecycle.
+func registerTaskRunner(db *mongo.Database, logger *zap.Logger) {
 	taskRunner = tasks.New(logger)
 
 	// Register cleanup jobs
@@ -79,8 +111,11 @@ func startTaskRunner(db *mongo.Database, logger *zap.Logger) {
 	// Close sessions inactive for 30 minutes (checked every 5 minutes)
 	taskRunner.Register(tasks.InactiveSessionCleanupJob(db, logger, 30*time.Minute))
 
-	// Start running jobs
-	taskRunner.Start()
+	appLifecycle.OnStart("task runner", func(context.Context) error {
+		taskRunner.Start()
+		return nil
+	})
+	appLifecycle.OnStop("task runner", taskRunner.Stop)
 }
 
 // ensureAdminUser ensures an admin user exists with the given login_id.
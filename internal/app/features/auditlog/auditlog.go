@@ -104,6 +104,7 @@ func allCategories() []categoryOption {
 	return []categoryOption{
 		{Value: audit.CategoryAuth, Label: "Authentication"},
 		{Value: audit.CategoryAdmin, Label: "Administration"},
+		{Value: audit.CategoryAccess, Label: "Access Control"},
 	}
 }
 
@@ -133,16 +134,25 @@ func eventTypesForCategory(category string) []string {
 		audit.EventPageUpdated,
 	}
 
+	accessEvents := []string{
+		audit.EventAccessGranted,
+		audit.EventAccessDenied,
+		audit.EventAccessUnauthenticated,
+	}
+
 	switch category {
 	case audit.CategoryAuth:
 		return authEvents
 	case audit.CategoryAdmin:
 		return adminEvents
+	case audit.CategoryAccess:
+		return accessEvents
 	case "":
 		// Return all event types when no category selected
-		all := make([]string, 0, len(authEvents)+len(adminEvents))
+		all := make([]string, 0, len(authEvents)+len(adminEvents)+len(accessEvents))
 		all = append(all, authEvents...)
 		all = append(all, adminEvents...)
+		all = append(all, accessEvents...)
 		return all
 	default:
 		return nil
@@ -1,292 +0,0 @@
-package authgoogle
-
-import (
-	"net/http"
-	"net/http/httptest"
-	"testing"
-	"time"
-
-	"github.com/dalemusser/strataforge/internal/app/store/oauthstate"
-	"github.com/dalemusser/strataforge/internal/app/store/sessions"
-	"github.com/dalemusser/strataforge/internal/app/system/auth"
-	"github.com/dalemusser/strataforge/internal/testutil"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.uber.org/zap"
-)
-
-func newTestHandler(t *testing.T) (*Handler, *mongo.Database, *oauthstate.Store) {
-	t.Helper()
-	db := testutil.SetupTestDB(t)
-	logger := zap.NewNop()
-
-	sessionsStore := sessions.New(db)
-	oauthStateStore := oauthstate.New(db)
-
-	sessionMgr, err := auth.NewSessionManager(
-		"test-session-key-for-testing-1234567890",
-		"test-session",
-		"",
-		24*time.Hour,
-		false,
-		logger,
-	)
-	if err != nil {
-		t.Fatalf("failed to create session manager: %v", err)
-	}
-
-	handler := NewHandler(
-		db,
-		sessionMgr,
-		nil, // errLog
-		nil, // auditLogger
-		sessionsStore,
-		oauthStateStore,
-		"test-client-id",
-		"test-client-secret",
-		"http://localhost:8080",
-		logger,
-	)
-
-	return handler, db, oauthStateStore
-}
-
-func TestNewHandler(t *testing.T) {
-	h, _, _ := newTestHandler(t)
-	if h == nil {
-		t.Fatal("NewHandler() returned nil")
-	}
-}
-
-func TestRoutes(t *testing.T) {
-	h, _, _ := newTestHandler(t)
-	router := Routes(h)
-	if router == nil {
-		t.Fatal("Routes() returned nil")
-	}
-}
-
-func TestStartAuth_RedirectsToGoogle(t *testing.T) {
-	h, _, _ := newTestHandler(t)
-
-	req := httptest.NewRequest(http.MethodGet, "/auth/google", nil)
-	rec := httptest.NewRecorder()
-
-	h.startAuth(rec, req)
-
-	// Should redirect (either to Google or error page)
-	if rec.Code != http.StatusTemporaryRedirect && rec.Code != http.StatusSeeOther {
-		t.Errorf("status = %d, want redirect (307 or 303)", rec.Code)
-	}
-
-	location := rec.Header().Get("Location")
-	if location == "" {
-		t.Error("Location header should be set")
-	}
-
-	// If successful, should redirect to Google OAuth URL
-	// If error, should redirect to login with error
-	if rec.Code == http.StatusTemporaryRedirect {
-		// Should contain Google OAuth URL
-		if !contains(location, "accounts.google.com") && !contains(location, "oauth") {
-			t.Errorf("Location = %q, should contain Google OAuth URL or oauth", location)
-		}
-	}
-}
-
-func TestStartAuth_StoresState(t *testing.T) {
-	h, _, oauthStore := newTestHandler(t)
-	ctx, cancel := testutil.TestContext()
-	defer cancel()
-
-	req := httptest.NewRequest(http.MethodGet, "/auth/google", nil)
-	rec := httptest.NewRecorder()
-
-	h.startAuth(rec, req)
-
-	// If the redirect is to Google, a state should have been created
-	if rec.Code == http.StatusTemporaryRedirect {
-		location := rec.Header().Get("Location")
-		// Extract state from URL
-		// The state parameter should be in the URL
-		if contains(location, "state=") {
-			// State was generated and stored
-			// Note: We can't easily verify the exact state without parsing the URL,
-			// but the test passing means state generation worked
-		}
-	}
-
-	_ = ctx
-	_ = oauthStore
-}
-
-func TestCallback_InvalidState(t *testing.T) {
-	h, _, _ := newTestHandler(t)
-
-	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=invalid-state&code=test-code", nil)
-	rec := httptest.NewRecorder()
-
-	h.handleCallback(rec, req)
-
-	// Should redirect to login with error
-	if rec.Code != http.StatusSeeOther {
-		t.Errorf("status = %d, want %d", rec.Code, http.StatusSeeOther)
-	}
-
-	location := rec.Header().Get("Location")
-	if !contains(location, "invalid_state") {
-		t.Errorf("Location = %q, want to contain 'invalid_state'", location)
-	}
-}
-
-func TestCallback_OAuthError(t *testing.T) {
-	h, _, _ := newTestHandler(t)
-
-	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?error=access_denied", nil)
-	rec := httptest.NewRecorder()
-
-	h.handleCallback(rec, req)
-
-	// Should redirect to login with error
-	if rec.Code != http.StatusSeeOther {
-		t.Errorf("status = %d, want %d", rec.Code, http.StatusSeeOther)
-	}
-
-	location := rec.Header().Get("Location")
-	if !contains(location, "access_denied") {
-		t.Errorf("Location = %q, want to contain 'access_denied'", location)
-	}
-}
-
-func TestCallback_NoCode(t *testing.T) {
-	h, _, oauthStore := newTestHandler(t)
-	ctx, cancel := testutil.TestContext()
-	defer cancel()
-
-	// Create a valid state first
-	state := "test-valid-state-token"
-	err := oauthStore.Create(ctx, state)
-	if err != nil {
-		t.Fatalf("failed to create state: %v", err)
-	}
-
-	// Request with valid state but no code
-	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?state="+state, nil)
-	rec := httptest.NewRecorder()
-
-	h.handleCallback(rec, req)
-
-	// Should redirect with error (token exchange will fail without code)
-	if rec.Code != http.StatusSeeOther {
-		t.Errorf("status = %d, want %d", rec.Code, http.StatusSeeOther)
-	}
-}
-
-func TestGenerateState(t *testing.T) {
-	// Test that generateState produces unique values
-	state1, err1 := generateState()
-	if err1 != nil {
-		t.Fatalf("generateState() error: %v", err1)
-	}
-
-	state2, err2 := generateState()
-	if err2 != nil {
-		t.Fatalf("generateState() error: %v", err2)
-	}
-
-	if state1 == state2 {
-		t.Error("generateState() should produce unique values")
-	}
-
-	// Should be base64 URL encoded (44 chars for 32 bytes)
-	if len(state1) != 44 {
-		t.Errorf("len(state) = %d, want 44", len(state1))
-	}
-}
-
-func TestGetClientIP(t *testing.T) {
-	tests := []struct {
-		name           string
-		xForwardedFor  string
-		xRealIP        string
-		remoteAddr     string
-		expectedResult string
-	}{
-		{
-			name:           "X-Forwarded-For",
-			xForwardedFor:  "192.168.1.1",
-			expectedResult: "192.168.1.1",
-		},
-		{
-			name:           "X-Real-IP",
-			xRealIP:        "192.168.1.2",
-			expectedResult: "192.168.1.2",
-		},
-		{
-			name:           "RemoteAddr",
-			remoteAddr:     "192.168.1.3:12345",
-			expectedResult: "192.168.1.3:12345",
-		},
-		{
-			name:           "X-Forwarded-For takes precedence",
-			xForwardedFor:  "192.168.1.1",
-			xRealIP:        "192.168.1.2",
-			remoteAddr:     "192.168.1.3:12345",
-			expectedResult: "192.168.1.1",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, "/", nil)
-			if tt.xForwardedFor != "" {
-				req.Header.Set("X-Forwarded-For", tt.xForwardedFor)
-			}
-			if tt.xRealIP != "" {
-				req.Header.Set("X-Real-IP", tt.xRealIP)
-			}
-			if tt.remoteAddr != "" {
-				req.RemoteAddr = tt.remoteAddr
-			}
-
-			got := getClientIP(req)
-			if got != tt.expectedResult {
-				t.Errorf("getClientIP() = %q, want %q", got, tt.expectedResult)
-			}
-		})
-	}
-}
-
-func TestGoogleUserInfo(t *testing.T) {
-	// Test that GoogleUserInfo struct works as expected
-	info := GoogleUserInfo{
-		ID:            "123",
-		Email:         "test@example.com",
-		VerifiedEmail: true,
-		Name:          "Test User",
-		Picture:       "https://example.com/photo.jpg",
-	}
-
-	if info.ID != "123" {
-		t.Errorf("ID = %q, want %q", info.ID, "123")
-	}
-	if info.Email != "test@example.com" {
-		t.Errorf("Email = %q, want %q", info.Email, "test@example.com")
-	}
-	if !info.VerifiedEmail {
-		t.Error("VerifiedEmail should be true")
-	}
-}
-
-// contains is a helper function to check if a string contains a substring
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsImpl(s, substr))
-}
-
-func containsImpl(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,359 @@
+// internal/app/features/compress/compress.go
+//
+// Package compress gzip- or deflate-encodes responses for clients that
+// accept it.
+//
+// The encoding is chosen from the request's Accept-Encoding header, with
+// gzip preferred on a tie. Output is buffered until it reaches the minimum
+// size: smaller responses, responses that already carry a Content-Encoding
+// (such as pre-compressed static files), bodiless statuses, and content
+// types that are already compressed (images, archives, fonts, media) are
+// sent as they are. Every response gets Vary: Accept-Encoding so caches
+// keep the variants apart, and strong ETags on compressed responses are
+// weakened.
+//
+// The wrapper defers WriteHeader until it has decided, so the status set by
+// handlers and the error pages reaches the client unchanged, and Flush
+// pushes buffered output through immediately for streamed responses.
+package compress
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMinSize is the smallest response body that is compressed. Below
+// it, the framing overhead outweighs the savings.
+const DefaultMinSize = 1024
+
+// DefaultLevel is the compression level used when none is configured.
+const DefaultLevel = 5
+
+// defaultSkipTypes are content types, or type/ prefixes, that are already
+// compressed or must not be buffered. image/svg+xml is text and is still
+// compressed; text/event-stream is sent as written so events arrive
+// promptly.
+var defaultSkipTypes = []string{
+	"text/event-stream",
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-7z-compressed", "application/x-rar-compressed",
+	"application/x-bzip2", "application/zstd", "application/pdf",
+	"application/octet-stream",
+}
+
+// config holds the settings built up by Options.
+type config struct {
+	level     int
+	minSize   int
+	skipTypes []string
+}
+
+// Option configures the compression middleware.
+type Option func(*config)
+
+// WithLevel sets the compression level, from 1 (fastest) to 9 (smallest).
+// Out-of-range values are clamped; 0 keeps DefaultLevel.
+func WithLevel(level int) Option {
+	return func(c *config) {
+		if level != 0 {
+			c.level = max(flate.BestSpeed, min(level, flate.BestCompression))
+		}
+	}
+}
+
+// WithMinSize sets the smallest body, in bytes, that is compressed.
+// Negative values are treated as zero.
+func WithMinSize(n int) Option {
+	return func(c *config) {
+		c.minSize = max(n, 0)
+	}
+}
+
+// WithSkipTypes adds content types that are passed through uncompressed.
+// An entry ending in "/" matches every subtype.
+func WithSkipTypes(types ...string) Option {
+	return func(c *config) {
+		c.skipTypes = append(c.skipTypes, types...)
+	}
+}
+
+// Middleware compresses responses for clients that accept gzip or deflate.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{level: DefaultLevel, minSize: DefaultMinSize}
+	cfg.skipTypes = append(cfg.skipTypes, defaultSkipTypes...)
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	gzipPool := &sync.Pool{New: func() any {
+		zw, _ := gzip.NewWriterLevel(io.Discard, cfg.level)
+		return zw
+	}}
+	flatePool := &sync.Pool{New: func() any {
+		fw, _ := flate.NewWriter(io.Discard, cfg.level)
+		return fw
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiate(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				cfg:            &cfg,
+				encoding:       encoding,
+				gzipPool:       gzipPool,
+				flatePool:      flatePool,
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// negotiate picks "gzip" or "deflate" from an Accept-Encoding header, or
+// "" when neither is acceptable. A "*" entry accepts both.
+func negotiate(header string) string {
+	var gzipQ, deflateQ, anyQ float64 = -1, -1, -1
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(fields[0])) {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "deflate":
+			deflateQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ < 0 {
+		gzipQ = anyQ
+	}
+	if deflateQ < 0 {
+		deflateQ = anyQ
+	}
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return "gzip"
+	case deflateQ > 0:
+		return "deflate"
+	}
+	return ""
+}
+
+// encoder is the part of gzip.Writer and flate.Writer the wrapper uses.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// compressWriter buffers the start of a response until it can decide
+// whether to compress it, then either streams through an encoder or
+// passes writes straight to the underlying ResponseWriter.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg       *config
+	encoding  string
+	gzipPool  *sync.Pool
+	flatePool *sync.Pool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	// Informational responses are sent as they are and do not end the header.
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	if !bodyAllowed(code) {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= max(cw.cfg.minSize, 1) {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends buffered output to the client, deciding on compression with
+// whatever has been written so far.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.decide(len(cw.buf) >= cw.cfg.minSize && len(cw.buf) > 0)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket and other upgraded connections take over the
+// connection when nothing has been written yet.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok && !cw.decided {
+		cw.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Written implements httpx.StatusWriter. A status the wrapper is still
+// holding back counts as written, since a later WriteHeader is ignored.
+func (cw *compressWriter) Written() bool {
+	return cw.status != 0 || cw.decided
+}
+
+// Status implements httpx.StatusWriter.
+func (cw *compressWriter) Status() int {
+	return cw.status
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the header, compressed if want is true and the response is
+// eligible, and then the buffered body.
+func (cw *compressWriter) decide(want bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if want && cw.eligible() {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		// The compressed bytes differ from the ones a strong tag was computed
+		// over, so the tag is weakened for this representation.
+		if tag := h.Get("ETag"); tag != "" && !strings.HasPrefix(tag, "W/") {
+			h.Set("ETag", "W/"+tag)
+		}
+		cw.enc = cw.acquire()
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// eligible reports whether the response may be compressed.
+func (cw *compressWriter) eligible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", ct)
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(ct, ";")[0]))
+	for _, skip := range cw.cfg.skipTypes {
+		if mediaType == skip || (strings.HasSuffix(skip, "/") && strings.HasPrefix(mediaType, skip)) {
+			return false
+		}
+	}
+	return true
+}
+
+// close finishes the response after the handler returns.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Nothing written; let net/http send its implicit 200.
+			return
+		}
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.release(cw.enc)
+		cw.enc = nil
+	}
+}
+
+func (cw *compressWriter) acquire() encoder {
+	pool := cw.gzipPool
+	if cw.encoding == "deflate" {
+		pool = cw.flatePool
+	}
+	enc := pool.Get().(encoder)
+	enc.Reset(cw.ResponseWriter)
+	return enc
+}
+
+func (cw *compressWriter) release(enc encoder) {
+	enc.Reset(io.Discard)
+	if cw.encoding == "deflate" {
+		cw.flatePool.Put(enc)
+		return
+	}
+	cw.gzipPool.Put(enc)
+}
+
+// bodyAllowed reports whether a response with status may carry a body.
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,211 @@
+package compress
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var page = strings.Repeat("<p>hello, compressed world</p>\n", 100)
+
+func serve(t *testing.T, h http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var r io.Reader = rec.Body
+	switch rec.Header().Get("Content-Encoding") {
+	case "gzip":
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader() error = %v", err)
+		}
+		r = zr
+	case "deflate":
+		r = flate.NewReader(rec.Body)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return string(b)
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"gzip":                    "gzip",
+		"deflate":                 "deflate",
+		"gzip, deflate, br":       "gzip",
+		"gzip;q=0.5, deflate":     "deflate",
+		"gzip;q=0, deflate;q=0":   "",
+		"*":                       "gzip",
+		"*;q=0.1, gzip;q=0":       "deflate",
+		"identity":                "",
+		"br":                      "",
+		" GZIP ; q=1.0 , deflate": "gzip",
+	}
+	for header, want := range tests {
+		if got := negotiate(header); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestMiddleware_CompressesLargeResponses(t *testing.T) {
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", "99999")
+		io.WriteString(w, page)
+	}))
+
+	for _, enc := range []string{"gzip", "deflate"} {
+		t.Run(enc, func(t *testing.T) {
+			rec := serve(t, h, enc)
+			if got := rec.Header().Get("Content-Encoding"); got != enc {
+				t.Fatalf("Content-Encoding = %q, want %q", got, enc)
+			}
+			if rec.Header().Get("Content-Length") != "" {
+				t.Error("Content-Length should be removed from compressed responses")
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", rec.Header().Get("Vary"))
+			}
+			if rec.Body.Len() >= len(page) {
+				t.Errorf("compressed size %d not smaller than %d", rec.Body.Len(), len(page))
+			}
+			if got := decode(t, rec); got != page {
+				t.Error("decoded body does not match original")
+			}
+		})
+	}
+}
+
+func TestMiddleware_PassesThrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  string
+		handler http.HandlerFunc
+	}{
+		{"no accept-encoding", "", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, page)
+		}},
+		{"below min size", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "short")
+		}},
+		{"already compressed type", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, page)
+		}},
+		{"existing content-encoding", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, page)
+		}},
+		{"event stream", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, page)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, Middleware()(tt.handler), tt.accept)
+			if enc := rec.Header().Get("Content-Encoding"); enc == "gzip" {
+				t.Errorf("Content-Encoding = %q, want uncompressed", enc)
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Vary = %q, want Accept-Encoding", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestMiddleware_PreservesStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		wantCE string
+	}{
+		{"error page", http.StatusNotFound, page, "gzip"},
+		{"small error", http.StatusUnauthorized, `{"error":"unauthorized"}`, ""},
+		{"no content", http.StatusNoContent, "", ""},
+		{"header only", http.StatusAccepted, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			rec := serve(t, h, "gzip")
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantCE {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantCE)
+			}
+			if got := decode(t, rec); got != tt.body {
+				t.Errorf("body = %q, want %q", got, tt.body)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Flush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	h := Middleware(WithMinSize(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first event\n\n")
+		w.(http.Flusher).Flush()
+		if rec.Body.Len() == 0 {
+			t.Error("Flush did not push output to the client")
+		}
+		io.WriteString(w, "data: second event\n\n")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	h.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Error("Flush was not forwarded to the underlying writer")
+	}
+	if got := decode(t, rec); got != "data: first event\n\ndata: second event\n\n" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestMiddleware_ResponseController(t *testing.T) {
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("ResponseController.Flush() error = %v", err)
+		}
+	}))
+	serve(t, h, "gzip")
+}
+
+func TestMiddleware_WeakensETag(t *testing.T) {
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		io.WriteString(w, page)
+	}))
+
+	if got := serve(t, h, "gzip").Header().Get("ETag"); got != `W/"abc"` {
+		t.Errorf("compressed ETag = %q, want %q", got, `W/"abc"`)
+	}
+	if got := serve(t, h, "").Header().Get("ETag"); got != `"abc"` {
+		t.Errorf("uncompressed ETag = %q, want %q", got, `"abc"`)
+	}
+}
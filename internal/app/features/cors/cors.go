@@ -0,0 +1,233 @@
+// internal/app/features/cors/cors.go
+//
+// Package cors lets browser clients on other origins, such as a single-page
+// app on app.example.com, call the app's endpoints.
+//
+// Middleware answers CORS preflight requests (OPTIONS with
+// Access-Control-Request-Method) itself and adds Access-Control-* headers to
+// the responses of actual requests, but only for origins on the allowlist,
+// which it echoes back. Requests from any other origin, and requests without
+// an Origin header, pass through untouched and without CORS headers, so the
+// browser withholds the response from the calling page.
+//
+// Credentialed requests (cookies) still go through CSRF protection. Pass the
+// allowlist's hosts (Hosts) to the CSRF middleware's trusted origins too, or
+// it rejects the cross-origin requests for their Origin header.
+//
+// API-key routes, which need no cookies, use the permissive apicors policy
+// instead.
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default methods and headers, used when Options leaves them empty.
+var (
+	DefaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	DefaultHeaders = []string{"Accept", "Content-Type"}
+)
+
+// Options configures Middleware.
+type Options struct {
+	// AllowedOrigins are the origins ("https://app.example.com", scheme and
+	// host with any port) allowed to call the app. Matching ignores case.
+	// "*" allows every origin; it cannot be combined with AllowCredentials.
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods a preflight may ask for (default
+	// DefaultMethods).
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers a preflight may ask for
+	// (default DefaultHeaders). Matching ignores case; "*" allows any header
+	// on requests without credentials.
+	AllowedHeaders []string
+
+	// ExposedHeaders are response headers, beyond the CORS-safelisted ones,
+	// that the calling page may read.
+	ExposedHeaders []string
+
+	// AllowCredentials lets the browser send cookies and read the response
+	// of credentialed requests.
+	AllowCredentials bool
+
+	// MaxAge is how long the browser may cache a preflight answer. Zero
+	// leaves it to the browser's default.
+	MaxAge time.Duration
+}
+
+// Middleware returns CORS middleware for opts. It panics if opts allows
+// every origin ("*") together with credentials, which browsers refuse and
+// which would hand any site the user's session.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	p := newPolicy(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if !p.allowsOrigin(origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !preflight {
+				p.setOrigin(h, origin)
+				if p.exposed != "" {
+					h.Set("Access-Control-Expose-Headers", p.exposed)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// A preflight is answered here; the handler never sees it. One
+			// asking for a method or header that is not allowed gets no
+			// CORS headers, and the browser does not send the request.
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			method := r.Header.Get("Access-Control-Request-Method")
+			requested := requestedHeaders(r)
+			if p.allowsMethod(method) && p.allowsHeaders(requested) {
+				p.setOrigin(h, origin)
+				h.Set("Access-Control-Allow-Methods", method)
+				if len(requested) > 0 {
+					h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+				}
+				if p.maxAge != "" {
+					h.Set("Access-Control-Max-Age", p.maxAge)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// Hosts returns the host[:port] of each allowed origin, the form the CSRF
+// middleware's trusted origins take. "*" and entries that are not origins
+// are left out.
+func Hosts(origins []string) []string {
+	var hosts []string
+	for _, origin := range origins {
+		u, err := url.Parse(strings.TrimSpace(origin))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			continue
+		}
+		hosts = append(hosts, strings.ToLower(u.Host))
+	}
+	return hosts
+}
+
+// policy is Options normalized for matching.
+type policy struct {
+	anyOrigin   bool
+	origins     map[string]struct{}
+	methods     []string
+	anyHeader   bool
+	headers     []string // lowercased
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+func newPolicy(opts Options) policy {
+	p := policy{
+		origins:     make(map[string]struct{}, len(opts.AllowedOrigins)),
+		methods:     opts.AllowedMethods,
+		credentials: opts.AllowCredentials,
+		exposed:     strings.Join(opts.ExposedHeaders, ", "),
+	}
+	for _, o := range opts.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		switch o {
+		case "":
+		case "*":
+			p.anyOrigin = true
+		default:
+			p.origins[strings.TrimSuffix(o, "/")] = struct{}{}
+		}
+	}
+	if p.anyOrigin && p.credentials {
+		panic(`cors: AllowedOrigins "*" cannot be combined with AllowCredentials`)
+	}
+	if len(p.methods) == 0 {
+		p.methods = DefaultMethods
+	}
+	headers := opts.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultHeaders
+	}
+	for _, h := range headers {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "*" && !p.credentials {
+			p.anyHeader = true
+		}
+		p.headers = append(p.headers, h)
+	}
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return p
+}
+
+func (p policy) allowsOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	_, ok := p.origins[strings.ToLower(origin)]
+	return ok
+}
+
+// setOrigin sets the allow-origin header: "*" for an open policy, the
+// echoed origin otherwise.
+func (p policy) setOrigin(h http.Header, origin string) {
+	if p.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// allowsMethod matches case-sensitively, as methods are.
+func (p policy) allowsMethod(method string) bool {
+	return slices.Contains(p.methods, method)
+}
+
+func (p policy) allowsHeaders(requested []string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, h := range requested {
+		if !slices.Contains(p.headers, strings.ToLower(h)) {
+			return false
+		}
+	}
+	return true
+}
+
+// requestedHeaders splits a preflight's Access-Control-Request-Headers.
+func requestedHeaders(r *http.Request) []string {
+	var out []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				out = append(out, h)
+			}
+		}
+	}
+	return out
+}
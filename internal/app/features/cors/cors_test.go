@@ -0,0 +1,167 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// reached answers 200 and records that the handler ran.
+func reached(ran *bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ran = true
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestMiddleware_Preflight(t *testing.T) {
+	mw := Middleware(Options{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+		AllowedHeaders:   []string{"Content-Type", "X-CSRF-Token"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	tests := []struct {
+		name, origin, method, headers string
+		wantOrigin, wantHeaders       string
+		wantHandler                   bool
+	}{
+		{"allowed", "https://app.example.com", "DELETE", "content-type, x-csrf-token", "https://app.example.com", "content-type, x-csrf-token", false},
+		{"origin case", "HTTPS://App.Example.com", "POST", "", "HTTPS://App.Example.com", "", false},
+		{"method not allowed", "https://app.example.com", "PUT", "", "", "", false},
+		{"header not allowed", "https://app.example.com", "POST", "Authorization", "", "", false},
+		{"origin not allowed", "https://evil.example", "POST", "", "", "", true},
+		{"other scheme", "http://app.example.com", "POST", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/api/items", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			var ran bool
+			rec := httptest.NewRecorder()
+			mw(reached(&ran)).ServeHTTP(rec, req)
+
+			if ran != tt.wantHandler {
+				t.Errorf("handler ran = %v, want %v", ran, tt.wantHandler)
+			}
+			if !tt.wantHandler && rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+				t.Errorf("Allow-Headers = %q, want %q", got, tt.wantHeaders)
+			}
+			if tt.wantOrigin == "" {
+				for _, name := range []string{"Access-Control-Allow-Methods", "Access-Control-Allow-Credentials", "Access-Control-Max-Age"} {
+					if h.Get(name) != "" {
+						t.Errorf("%s = %q on a refused preflight", name, h.Get(name))
+					}
+				}
+				return
+			}
+			if got := h.Get("Access-Control-Allow-Methods"); got != tt.method {
+				t.Errorf("Allow-Methods = %q, want %q", got, tt.method)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != "true" {
+				t.Errorf("Allow-Credentials = %q, want true", got)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Max-Age = %q, want 600", got)
+			}
+		})
+	}
+}
+
+func TestMiddleware_ActualRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		origin      string
+		wantOrigin  string
+		wantCreds   string
+		wantExposed string
+	}{
+		{"credentialed", Options{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true, ExposedHeaders: []string{"X-Request-ID"}},
+			"https://app.example.com", "https://app.example.com", "true", "X-Request-ID"},
+		{"without credentials", Options{AllowedOrigins: []string{"https://app.example.com/"}},
+			"https://app.example.com", "https://app.example.com", "", ""},
+		{"any origin", Options{AllowedOrigins: []string{"*"}},
+			"https://anything.example", "*", "", ""},
+		{"origin not allowed", Options{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true},
+			"https://app.example.com.evil.example", "", "", ""},
+		{"no origin header", Options{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true},
+			"", "", "", ""},
+		{"empty allowlist", Options{},
+			"https://app.example.com", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/items", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			var ran bool
+			rec := httptest.NewRecorder()
+			Middleware(tt.opts)(reached(&ran)).ServeHTTP(rec, req)
+
+			if !ran || rec.Code != http.StatusOK {
+				t.Fatalf("handler ran = %v, status %d; want the request passed through", ran, rec.Code)
+			}
+			h := rec.Header()
+			if got := h.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+			if got := h.Get("Access-Control-Expose-Headers"); got != tt.wantExposed {
+				t.Errorf("Expose-Headers = %q, want %q", got, tt.wantExposed)
+			}
+			if tt.origin != "" && h.Get("Vary") != "Origin" {
+				t.Errorf("Vary = %q, want Origin", h.Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestMiddleware_AnyHeader(t *testing.T) {
+	mw := Middleware(Options{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "X-Anything")
+	rec := httptest.NewRecorder()
+	mw(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "X-Anything" {
+		t.Errorf("Allow-Headers = %q, want X-Anything", got)
+	}
+}
+
+func TestMiddleware_WildcardWithCredentialsPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error(`Middleware with "*" and credentials did not panic`)
+		}
+	}()
+	Middleware(Options{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+}
+
+func TestHosts(t *testing.T) {
+	got := Hosts([]string{"https://App.example.com", "http://localhost:3000", "*", "app.example.com", ""})
+	want := []string{"app.example.com", "localhost:3000"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Hosts() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+// internal/app/features/csrf/csrf.go
+//
+// Package csrf protects state-changing requests from cross-site request
+// forgery.
+//
+// It wraps gorilla/csrf, which uses the double-submit-cookie pattern: a
+// random token is kept in a signed, HttpOnly cookie, and every unsafe request
+// (POST, PUT, PATCH, DELETE) must echo a masked copy of it in the csrf_token
+// form field or the X-CSRF-Token header. Safe methods (GET, HEAD, OPTIONS,
+// TRACE) pass through and are issued a token. Over HTTPS the Origin/Referer
+// header is also checked against the request host and any trusted origins.
+//
+// Templates embed the token with the csrfField function:
+//
+//	<form method="post">
+//	    {{ csrfField .CSRFToken }}
+//	</form>
+package csrf
+
+import (
+	"html/template"
+	"net/http"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/gorilla/csrf"
+	"go.uber.org/zap"
+)
+
+const (
+	// CookieName is the cookie holding the signed CSRF token.
+	CookieName = "csrf_token"
+	// FieldName is the form field that must carry the token on unsafe requests.
+	FieldName = "csrf_token"
+	// HeaderName is the request header accepted in place of the form field.
+	HeaderName = "X-CSRF-Token"
+)
+
+// config holds the settings built up by Options.
+type config struct {
+	secure         bool
+	domain         string
+	trustedOrigins []string
+	errors         *errorsfeature.Handler
+	logger         *zap.Logger
+}
+
+// Option configures the CSRF middleware.
+type Option func(*config)
+
+// WithSecure sets the Secure flag on the CSRF cookie. Enable it in production.
+func WithSecure(secure bool) Option {
+	return func(c *config) {
+		c.secure = secure
+	}
+}
+
+// WithDomain sets the CSRF cookie domain. Empty means the current host.
+func WithDomain(domain string) Option {
+	return func(c *config) {
+		c.domain = domain
+	}
+}
+
+// WithTrustedOrigins allows cross-origin requests from the given hosts
+// (host[:port], not full URLs), e.g. a dev server on "localhost:3000".
+func WithTrustedOrigins(origins ...string) Option {
+	return func(c *config) {
+		c.trustedOrigins = append(c.trustedOrigins, origins...)
+	}
+}
+
+// WithErrorHandler renders validation failures with h.Forbidden. Without it,
+// failures get a plain-text 403.
+func WithErrorHandler(h *errorsfeature.Handler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// WithLogger logs each validation failure as a warning.
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// Middleware returns CSRF protection middleware keyed by secret, which should
+// be 32 or more random bytes and stable across restarts and instances.
+func Middleware(secret []byte, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	csrfOpts := []csrf.Option{
+		csrf.Secure(cfg.secure),
+		csrf.Path("/"),
+		csrf.CookieName(CookieName),
+		csrf.FieldName(FieldName),
+		csrf.RequestHeader(HeaderName),
+		csrf.SameSite(csrf.SameSiteLaxMode),
+		csrf.ErrorHandler(failureHandler(cfg)),
+	}
+	if len(cfg.trustedOrigins) > 0 {
+		csrfOpts = append(csrfOpts, csrf.TrustedOrigins(cfg.trustedOrigins))
+	}
+	if cfg.domain != "" {
+		csrfOpts = append(csrfOpts, csrf.Domain(cfg.domain))
+	}
+	return csrf.Protect(secret, csrfOpts...)
+}
+
+// failureHandler responds to requests that fail CSRF validation.
+// HTMX requests are redirected to the login page, since an expired session
+// is the usual cause; everyone else gets the 403 page.
+func failureHandler(cfg config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg.logger.Warn("CSRF validation failed",
+			zap.String("path", r.URL.Path),
+			zap.String("method", r.Method),
+			zap.String("reason", csrf.FailureReason(r).Error()),
+		)
+		if r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("HX-Redirect", "/login")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if cfg.errors != nil {
+			cfg.errors.Forbidden(w, r)
+			return
+		}
+		http.Error(w, "CSRF token invalid or missing", http.StatusForbidden)
+	})
+}
+
+// Token returns the masked CSRF token for the request, for embedding in a
+// form or sending in the X-CSRF-Token header. It is "" outside the middleware.
+func Token(r *http.Request) string {
+	return csrf.Token(r)
+}
+
+// FuncMap returns template helpers for CSRF protection:
+//
+//	csrfField TOKEN - renders the hidden csrf_token input
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"csrfField": Field,
+	}
+}
+
+// Field renders a hidden form input carrying token.
+func Field(token string) template.HTML {
+	return template.HTML(`<input type="hidden" name="` + FieldName + `" value="` + template.HTMLEscapeString(token) + `">`)
+}
@@ -0,0 +1,125 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+var testSecret = []byte("test-csrf-secret-0123456789abcdef")
+
+// protected wraps a handler that echoes the CSRF token for the request.
+func protected(opts ...Option) http.Handler {
+	return Middleware(testSecret, opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(Token(r)))
+	}))
+}
+
+// fetchToken performs a GET and returns the issued token and cookie.
+func fetchToken(t *testing.T, h http.Handler) (string, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "https://example.com/form", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CookieName {
+			return rec.Body.String(), c
+		}
+	}
+	t.Fatal("expected a CSRF cookie on GET")
+	return "", nil
+}
+
+func post(h http.Handler, token string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	form := url.Values{}
+	if token != "" {
+		form.Set(FieldName, token)
+	}
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/form", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", "https://example.com/form")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_AcceptsValidToken(t *testing.T) {
+	h := protected()
+	token, cookie := fetchToken(t, h)
+
+	if rec := post(h, token, cookie); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMiddleware_RejectsMissingToken(t *testing.T) {
+	h := protected()
+	_, cookie := fetchToken(t, h)
+
+	if rec := post(h, "", cookie); rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestMiddleware_RejectsTokenWithoutCookie(t *testing.T) {
+	h := protected()
+	token, _ := fetchToken(t, h)
+
+	if rec := post(h, token, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestMiddleware_UsesErrorHandler(t *testing.T) {
+	h := protected(WithErrorHandler(errorsfeature.NewHandler()))
+	_, cookie := fetchToken(t, h)
+
+	form := url.Values{}
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/form", strings.NewReader(form.Encode()))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Referer", "https://example.com/form")
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if !strings.Contains(rec.Body.String(), `"error":"forbidden"`) {
+		t.Errorf("body = %q, want errors handler JSON", rec.Body.String())
+	}
+}
+
+func TestMiddleware_HTMXRedirectsToLogin(t *testing.T) {
+	h := protected()
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/form", nil)
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set("Referer", "https://example.com/form")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if got := rec.Header().Get("HX-Redirect"); got != "/login" {
+		t.Errorf("HX-Redirect = %q, want %q", got, "/login")
+	}
+}
+
+func TestField_EscapesToken(t *testing.T) {
+	got := string(Field(`a"b`))
+	want := `<input type="hidden" name="csrf_token" value="a&#34;b">`
+	if got != want {
+		t.Errorf("Field() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+// internal/app/features/debug/debug.go
+//
+// Package debug serves the net/http/pprof profiling endpoints to admins,
+// for on-demand CPU, heap, goroutine, and trace profiles in production.
+//
+// Routes are mounted under a configurable prefix (pprof_prefix) and only
+// when pprof_enabled is on; otherwise they are not registered at all and
+// the prefix answers 404 like any unknown path. Every route requires the
+// admin role through the session manager's RequireRole, which sends
+// signed-in non-admins to the errors handler's 403 page. Without a session
+// manager nobody can prove they are an admin, so every request gets the
+// 403 page.
+//
+// Profiles that run for a while (profile?seconds=N, trace?seconds=N) must
+// finish within the server's write timeout and the request timeout middleware.
+package debug
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// config holds the settings built up by Options.
+type config struct {
+	errors *errorsfeature.Handler
+}
+
+// Option configures the debug routes.
+type Option func(*config)
+
+// WithErrorHandler renders the 403 page through h when no session manager
+// is available. Without it a plain-text 403 is sent.
+func WithErrorHandler(h *errorsfeature.Handler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// Routes returns the pprof endpoints, restricted to admins. Mount it at the
+// configured prefix:
+//
+//	r.Mount("/debug/pprof", debug.Routes(sessionMgr, debug.WithErrorHandler(errorsHandler)))
+func Routes(sm *auth.SessionManager, opts ...Option) chi.Router {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	r := chi.NewRouter()
+	if sm != nil {
+		r.Use(sm.RequireRole("admin"))
+	} else {
+		r.Use(forbidAll(cfg.errors))
+	}
+
+	r.Get("/", pprof.Index)
+	r.Get("/cmdline", pprof.Cmdline)
+	r.Get("/profile", pprof.Profile)
+	r.Get("/symbol", pprof.Symbol)
+	r.Post("/symbol", pprof.Symbol)
+	r.Get("/trace", pprof.Trace)
+	// Named profiles: heap, goroutine, allocs, block, mutex, threadcreate.
+	// pprof.Index only finds them under /debug/pprof/, so they are routed
+	// here explicitly to work under any prefix.
+	r.Get("/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(chi.URLParam(r, "profile")).ServeHTTP(w, r)
+	})
+
+	return r
+}
+
+// forbidAll answers every request with 403, through h when it is set.
+func forbidAll(h *errorsfeature.Handler) func(http.Handler) http.Handler {
+	return func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if h != nil {
+				h.Forbidden(w, r)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package debug
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/testutil"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// newRouter mounts the debug routes at a custom prefix, as routes.go does.
+func newRouter(t *testing.T, sm *auth.SessionManager) http.Handler {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Mount("/_debug/pprof", Routes(sm, WithErrorHandler(errorsfeature.NewHandler())))
+	return r
+}
+
+func newSessionManager(t *testing.T) *auth.SessionManager {
+	t.Helper()
+	sm, err := auth.NewSessionManager("test-session-key-0123456789abcdef0123", "test-session", "", time.Hour, false, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewSessionManager() error = %v", err)
+	}
+	sm.SetForbiddenHandler(errorsfeature.NewHandler().Forbidden)
+	return sm
+}
+
+func TestRoutes_AdminCanProfile(t *testing.T) {
+	h := newRouter(t, newSessionManager(t))
+
+	for _, path := range []string{"/_debug/pprof/", "/_debug/pprof/heap?debug=1", "/_debug/pprof/goroutine?debug=1", "/_debug/pprof/cmdline"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, testutil.NewAuthenticatedRequest(http.MethodGet, path, testutil.AdminUser()))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s as admin: status = %d, want 200", path, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, testutil.NewAuthenticatedRequest(http.MethodGet, "/_debug/pprof/heap?debug=1", testutil.AdminUser()))
+	if !strings.Contains(rec.Body.String(), "heap profile") {
+		t.Errorf("heap profile under a custom prefix served %q", rec.Body.String()[:min(80, rec.Body.Len())])
+	}
+}
+
+func TestRoutes_NonAdminForbidden(t *testing.T) {
+	h := newRouter(t, newSessionManager(t))
+	user := testutil.AdminUser()
+	user.Role = "user"
+
+	req := testutil.NewAuthenticatedRequest(http.MethodGet, "/_debug/pprof/heap", user)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestRoutes_AnonymousRejected(t *testing.T) {
+	h := newRouter(t, newSessionManager(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/_debug/pprof/", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestRoutes_NoSessionManagerForbidsAll(t *testing.T) {
+	h := newRouter(t, nil)
+
+	req := testutil.NewAuthenticatedRequest(http.MethodGet, "/_debug/pprof/", testutil.AdminUser())
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/testutil"
+)
+
+func TestError_SetsStatus(t *testing.T) {
+	h := NewHandler()
+
+	tests := []struct {
+		status int
+		want   int
+	}{
+		{http.StatusNotFound, http.StatusNotFound},
+		{http.StatusConflict, http.StatusConflict},
+		{http.StatusBadGateway, http.StatusBadGateway},
+		{http.StatusOK, http.StatusInternalServerError},
+		{1000, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+
+			h.Error(rec, req, tt.status)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_UnmappedStatusUsesGenericPage(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/upstream", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.Error(rec, req, http.StatusBadGateway)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if !strings.Contains(rec.Body.String(), "Bad Gateway") {
+		t.Error("expected status text in generic error page")
+	}
+}
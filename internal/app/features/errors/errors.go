@@ -2,57 +2,443 @@
 package errors
 
 import (
+	stderrors "errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/dalemusser/strataforge/internal/app/system/jsonutil"
+	"github.com/dalemusser/strataforge/internal/app/system/render"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
+	"github.com/dalemusser/waffle/pantry/requestid"
 	"github.com/dalemusser/waffle/pantry/templates"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 )
 
-// ErrorLogger wraps the zap logger for error logging.
-type ErrorLogger struct {
-	logger *zap.Logger
+// defaultTemplates maps status codes to the built-in error page templates.
+var defaultTemplates = map[int]string{
+	http.StatusBadRequest:          "errors/bad_request",
+	http.StatusUnauthorized:        "errors/unauthorized",
+	http.StatusForbidden:           "errors/forbidden",
+	http.StatusNotFound:            "errors/not_found",
+	http.StatusMethodNotAllowed:    genericTemplate,
+	http.StatusTooManyRequests:     "errors/too_many_requests",
+	http.StatusInternalServerError: "errors/internal",
+	http.StatusServiceUnavailable:  "errors/maintenance",
 }
 
-// NewErrorLogger creates a new ErrorLogger.
-func NewErrorLogger(logger *zap.Logger) *ErrorLogger {
-	return &ErrorLogger{logger: logger}
+// defaultMessages holds the built-in message for each status with its own
+// page. Other statuses use http.StatusText.
+var defaultMessages = map[int]string{
+	http.StatusBadRequest:          "Bad Request",
+	http.StatusUnauthorized:        "Unauthorized",
+	http.StatusForbidden:           "Access Denied",
+	http.StatusNotFound:            "Page Not Found",
+	http.StatusMethodNotAllowed:    "Method Not Allowed",
+	http.StatusTooManyRequests:     "Too Many Requests",
+	http.StatusInternalServerError: "Server Error",
+	http.StatusServiceUnavailable:  "Down for Maintenance",
 }
 
-// Log logs an error with the given message and error.
-func (e *ErrorLogger) Log(r *http.Request, msg string, err error) {
-	e.logger.Error(msg,
-		zap.Error(err),
-		zap.String("path", r.URL.Path),
-		zap.String("method", r.Method),
-	)
+// genericTemplate renders any status without a page of its own.
+const genericTemplate = "errors/error"
+
+// defaultSecurityHeaders are set on every error response unless overridden
+// with WithSecurityHeaders.
+var defaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
 }
 
-// LogWithFields logs an error with additional fields.
-func (e *ErrorLogger) LogWithFields(r *http.Request, msg string, err error, fields ...zap.Field) {
-	allFields := append([]zap.Field{
-		zap.Error(err),
-		zap.String("path", r.URL.Path),
-		zap.String("method", r.Method),
-	}, fields...)
-	e.logger.Error(msg, allFields...)
+// defaultCacheControl is the Cache-Control sent with every error response
+// unless overridden for its status with WithCacheControl, so caching proxies
+// never serve one user's transient error to others.
+const defaultCacheControl = "no-store"
+
+// errorVM is the view model passed to error page templates.
+// Status, Message, and Description are used by the generic errors/error page;
+// the status-specific pages only rely on the embedded BaseVM.
+type errorVM struct {
+	viewdata.BaseVM
+	Status      int
+	Message     string
+	Description string
+	Details     map[string]string // field -> validation message (400 and 409 only)
+	RetryAfter  int               // seconds until the client may retry (429 and 503)
+	IncidentID  string            // request ID shown as "Reference" so support can find the logs (5xx only)
+	DebugError  string            // error message, shown on the 500 page only with WithDebug
+	DebugStack  string            // stack trace, shown on the 500 page only with WithDebug
+	Suggestion  string            // closest known route, shown on the 404 page only with WithDebug
+
+	problem ProblemDetails // caller-supplied problem details members (Problem only)
 }
 
 // Handler provides error page handlers.
-type Handler struct{}
+type Handler struct {
+	templates     map[int]string // status code -> custom template name
+	errLog        *ErrorLogger
+	engine        *templates.Engine
+	reload        bool // re-parse templates on every error page; development only
+	renderer      *render.Renderer
+	onRenderError func(w http.ResponseWriter, r *http.Request, err error)
+	onPanic       func(w http.ResponseWriter, r *http.Request, err error)
+	messages      map[string]map[int]string // locale -> status -> message
+	defaultLocale string
+
+	maintenance           atomic.Bool
+	maintenanceAllow      []netip.Prefix
+	maintenanceRetryAfter time.Duration
+	trustedProxies        []netip.Prefix
+
+	metrics *prometheus.CounterVec // nil unless WithMetrics is used
+
+	securityHeaders map[string]string // header -> value; "" removes the header
+	cacheControl    map[int]string    // status -> Cache-Control overriding defaultCacheControl; "" omits it
+
+	wwwAuthenticate string // WWW-Authenticate challenge for 401 responses; "" omits it
+
+	debug     bool // show error details on 500 pages; development only
+	plainText bool // write the status text instead of rendering templates
+
+	problemDetails  bool   // write RFC 7807 bodies to JSON clients
+	problemTypeBase string // URI prefix for the problem type member; "" means about:blank
+
+	routes atomic.Pointer[routeSet] // patterns for "did you mean" hints on 404 pages (debug only)
+}
+
+// RenderError is reported when an error page template fails to render.
+// Use errors.As to inspect the status and template that failed.
+type RenderError struct {
+	Status   int
+	Template string
+	Err      error
+}
+
+func (e *RenderError) Error() string {
+	return fmt.Sprintf("render error page %q (status %d): %v", e.Template, e.Status, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithTemplate renders the named template for the given status code instead
+// of the built-in page. Registering the same status more than once is allowed;
+// the last registration wins.
+func WithTemplate(status int, name string) Option {
+	return func(h *Handler) {
+		h.templates[status] = name
+	}
+}
+
+// WithErrorLogger sets the ErrorLogger used to record error responses and
+// recovered panics.
+func WithErrorLogger(errLog *ErrorLogger) Option {
+	return func(h *Handler) {
+		h.errLog = errLog
+	}
+}
+
+// WithEngine renders error pages with eng, through a render.Renderer, instead
+// of the package-level templates.Render helper. This lets the handler see
+// render failures and report them as a *RenderError to the OnRenderError
+// callback.
+func WithEngine(eng *templates.Engine) Option {
+	return func(h *Handler) {
+		h.engine = eng
+	}
+}
+
+// WithReload re-parses the templates from disk for every error page while
+// on, so edits to them show without a restart (see render.WithReload). A
+// template that fails to parse shows as a 500 page with the parse error.
+// It applies only with WithEngine, and is for development only.
+func WithReload(on bool) Option {
+	return func(h *Handler) {
+		h.reload = on
+	}
+}
+
+// WithOnRenderError sets the callback invoked with a *RenderError when an
+// error page fails to render. Only headers have been set on w when it is called.
+// The default logs the failure and writes the status text as plain text.
+// Render failures are only detected when an engine is set via WithEngine.
+func WithOnRenderError(fn func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(h *Handler) {
+		h.onRenderError = fn
+	}
+}
+
+// WithOnPanic sets the response Recover writes for a panic caught before
+// the handler sent anything, in place of the 500 page; err carries the panic
+// value, which has already been logged with its stack. Panics after the
+// response has started always abort the connection.
+func WithOnPanic(fn func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(h *Handler) {
+		h.onPanic = fn
+	}
+}
+
+// WithSecurityHeaders sets extra response headers on every error response,
+// such as a Content-Security-Policy, which is given the request's CSP nonce
+// when there is one. Entries are merged over the defaults
+// (X-Content-Type-Options: nosniff); an empty value drops that header.
+func WithSecurityHeaders(headers map[string]string) Option {
+	return func(h *Handler) {
+		for name, value := range headers {
+			h.securityHeaders[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+}
+
+// WithCacheControl sets the Cache-Control header sent with error responses
+// for status instead of the default "no-store", for example
+// WithCacheControl(http.StatusNotFound, "public, max-age=60"). An empty
+// value sends no Cache-Control header for that status.
+func WithCacheControl(status int, value string) Option {
+	return func(h *Handler) {
+		h.cacheControl[status] = value
+	}
+}
+
+// WithWWWAuthenticate sets the WWW-Authenticate challenge sent with 401
+// responses from Unauthorized, e.g. `Bearer realm="api"`, so API clients
+// know how to authenticate. Empty, the default, omits the header.
+func WithWWWAuthenticate(challenge string) Option {
+	return func(h *Handler) {
+		h.wwwAuthenticate = challenge
+	}
+}
+
+// WithDebug shows the error message and stack trace on the 500 page
+// rendered by InternalErrorWithError and Recover, and enables the 404
+// page's route suggestions (SetRoutes). It exposes internals to
+// whoever sees the page, so enable it only in development; by default the
+// page stays generic and the details go only to the log.
+func WithDebug(enabled bool) Option {
+	return func(h *Handler) {
+		h.debug = enabled
+	}
+}
+
+// WithPlainText makes every error method write the status text as
+// text/plain instead of rendering an HTML page, for applications without
+// templates such as pure JSON APIs. Clients that prefer JSON still get an
+// ErrorResponse.
+func WithPlainText() Option {
+	return func(h *Handler) {
+		h.plainText = true
+	}
+}
 
 // NewHandler creates a new error Handler.
-func NewHandler() *Handler {
-	return &Handler{}
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{
+		templates: make(map[int]string),
+		errLog:    NewErrorLogger(zap.NewNop()),
+
+		securityHeaders: make(map[string]string, len(defaultSecurityHeaders)),
+		cacheControl:    make(map[int]string),
+	}
+	for name, value := range defaultSecurityHeaders {
+		h.securityHeaders[name] = value
+	}
+	h.onRenderError = h.plainTextFallback
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.engine != nil {
+		h.renderer = render.New(h.engine, render.WithReload(h.reload), render.WithDeadlineCheck(false))
+	}
+	return h
+}
+
+// templateFor returns the template to render for status, preferring a
+// registered template over the built-in one and falling back to the generic
+// error page.
+func (h *Handler) templateFor(status int) string {
+	if name, ok := h.templates[status]; ok {
+		return name
+	}
+	if name, ok := defaultTemplates[status]; ok {
+		return name
+	}
+	return genericTemplate
+}
+
+// messageFor returns the built-in message for status.
+func messageFor(status int) string {
+	if msg, ok := defaultMessages[status]; ok {
+		return msg
+	}
+	if text := http.StatusText(status); text != "" {
+		return text
+	}
+	return "Error"
+}
+
+// render writes the error response described by vm. Clients that prefer JSON
+// receive an ErrorResponse body (ProblemDetails with WithProblemDetails);
+// everyone else gets the HTML error page.
+// The localized message, BaseVM, and page title are filled in here.
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, vm errorVM) {
+	// A handler that already started its response cannot be given an error
+	// page; log the status it wanted and leave the response as is.
+	if httpx.Written(w) {
+		h.errLog.LogStatus(r, vm.Status, "error response after response started; not written", nil)
+		return
+	}
+	if vm.Status >= 500 {
+		r, vm.IncidentID = withIncidentID(r)
+		if w.Header().Get(requestid.DefaultHeader) == "" {
+			w.Header().Set(requestid.DefaultHeader, vm.IncidentID)
+		}
+	}
+	h.errLog.LogStatus(r, vm.Status, "error response", nil)
+	h.countError(vm.Status)
+	vm.Message = h.localizedMessage(r, vm.Status, vm.Message)
+	h.setSecurityHeaders(w, r)
+	h.setCacheControl(w, vm.Status)
+
+	if wantsJSON(r) {
+		if h.problemDetails {
+			h.writeProblem(w, r, vm)
+			return
+		}
+		resp := newErrorResponse(vm.Status)
+		resp.Details = vm.Details
+		resp.IncidentID = vm.IncidentID
+		jsonutil.JSON(w, vm.Status, resp)
+		return
+	}
+	if h.plainText {
+		text := http.StatusText(vm.Status)
+		if text == "" {
+			text = vm.Message
+		}
+		http.Error(w, text, vm.Status)
+		return
+	}
+
+	vm.BaseVM = viewdata.New(r)
+	vm.Title = vm.Message
+	name := h.templateFor(vm.Status)
+
+	if h.renderer == nil {
+		w.WriteHeader(vm.Status)
+		templates.Render(w, r, name, vm)
+		return
+	}
+
+	// A failed render leaves the response untouched for the OnRenderError callback,
+	// except a reload parse error or a passed deadline, whose response has
+	// already been written.
+	if err := h.renderer.RenderStatus(w, r, vm.Status, name, vm); err != nil {
+		if stderrors.Is(err, render.ErrReload) {
+			h.errLog.Log(r, "error page templates failed to parse", err)
+			return
+		}
+		if stderrors.Is(err, render.ErrDeadlineExceeded) {
+			return
+		}
+		h.onRenderError(w, r, &RenderError{Status: vm.Status, Template: name, Err: err})
+	}
+}
+
+// setCacheControl writes the Cache-Control header for status. It must run
+// before WriteHeader.
+func (h *Handler) setCacheControl(w http.ResponseWriter, status int) {
+	value, ok := h.cacheControl[status]
+	if !ok {
+		value = defaultCacheControl
+	}
+	if value == "" {
+		w.Header().Del("Cache-Control")
+		return
+	}
+	w.Header().Set("Cache-Control", value)
+}
+
+// withIncidentID returns the request ID that identifies this failure to
+// support. Requests that passed through RequestIDMiddleware already have
+// one; otherwise a short ID is generated and stored in the returned
+// request's context so the error log records the same value.
+func withIncidentID(r *http.Request) (*http.Request, string) {
+	if id := RequestID(r); id != "" {
+		return r, id
+	}
+	id := requestid.GenerateShort()
+	return r.WithContext(requestid.Set(r.Context(), id)), id
+}
+
+// setSecurityHeaders writes the configured security headers. It must run
+// before WriteHeader for them to take effect. A Content-Security-Policy gets
+// the request's nonce (see csp.Middleware), so the error page's inline
+// scripts run under it.
+func (h *Handler) setSecurityHeaders(w http.ResponseWriter, r *http.Request) {
+	for name, value := range h.securityHeaders {
+		if value == "" {
+			continue
+		}
+		if name == csp.HeaderName || name == csp.ReportOnlyHeaderName {
+			value = csp.AddNonce(value, csp.Nonce(r.Context()))
+		}
+		w.Header().Set(name, value)
+	}
+}
+
+// plainTextFallback is the default OnRenderError callback. It logs the
+// failure and writes the original status with its status text.
+func (h *Handler) plainTextFallback(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	var renderErr *RenderError
+	if stderrors.As(err, &renderErr) {
+		status = renderErr.Status
+	}
+	h.errLog.Log(r, "error page render failed", err)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// Error renders the error page for an arbitrary status, using the template
+// registered for it, its built-in page, or the generic error page. Statuses
+// outside 400-599 are treated as 500. Use the named methods when a status
+// needs extra data, such as the Allow header for 405 or Retry-After for 429.
+func (h *Handler) Error(w http.ResponseWriter, r *http.Request, status int) {
+	if status < 400 || status > 599 {
+		status = http.StatusInternalServerError
+	}
+	h.render(w, r, errorVM{Status: status, Message: messageFor(status)})
+}
+
+// BadRequest renders the 400 bad request page.
+func (h *Handler) BadRequest(w http.ResponseWriter, r *http.Request) {
+	h.BadRequestWithDetails(w, r, nil)
+}
+
+// BadRequestWithDetails renders the 400 bad request page with field-level
+// validation errors. The details map field names to messages and is included
+// in the JSON body when the client prefers JSON.
+func (h *Handler) BadRequestWithDetails(w http.ResponseWriter, r *http.Request, details map[string]string) {
+	h.render(w, r, errorVM{
+		Status:  http.StatusBadRequest,
+		Message: messageFor(http.StatusBadRequest),
+		Details: details,
+	})
 }
 
 // Forbidden renders the 403 forbidden page.
 func (h *Handler) Forbidden(w http.ResponseWriter, r *http.Request) {
-	vm := viewdata.New(r)
-	vm.Title = "Access Denied"
-
-	w.WriteHeader(http.StatusForbidden)
-	templates.Render(w, r, "errors/forbidden", vm)
+	h.Error(w, r, http.StatusForbidden)
 }
 
 // Troubleshooting renders the "Having Trouble?" self-service troubleshooting page.
@@ -63,29 +449,123 @@ func (h *Handler) Troubleshooting(w http.ResponseWriter, r *http.Request) {
 	templates.Render(w, r, "errors/troubleshooting", vm)
 }
 
-// Unauthorized renders the 401 unauthorized page.
+// Unauthorized renders the 401 unauthorized page, with the WWW-Authenticate
+// challenge when one is configured.
 func (h *Handler) Unauthorized(w http.ResponseWriter, r *http.Request) {
-	vm := viewdata.New(r)
-	vm.Title = "Unauthorized"
-
-	w.WriteHeader(http.StatusUnauthorized)
-	templates.Render(w, r, "errors/unauthorized", vm)
+	if h.wwwAuthenticate != "" {
+		w.Header().Set("WWW-Authenticate", h.wwwAuthenticate)
+	}
+	h.Error(w, r, http.StatusUnauthorized)
 }
 
-// NotFound renders the 404 not found page.
+// NotFound renders the 404 not found page. With WithDebug and known routes
+// (SetRoutes), the page suggests the closest route as "Did you mean".
 func (h *Handler) NotFound(w http.ResponseWriter, r *http.Request) {
-	vm := viewdata.New(r)
-	vm.Title = "Not Found"
+	h.render(w, r, errorVM{
+		Status:     http.StatusNotFound,
+		Message:    messageFor(http.StatusNotFound),
+		Suggestion: h.suggestRoute(r.URL.Path),
+	})
+}
 
-	w.WriteHeader(http.StatusNotFound)
-	templates.Render(w, r, "errors/not_found", vm)
+// MethodNotAllowed renders the 405 method not allowed page.
+// The allowed methods are written to the Allow header; when none are given,
+// any Allow header already set on the response is left in place.
+func (h *Handler) MethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+	}
+	h.render(w, r, errorVM{
+		Status:      http.StatusMethodNotAllowed,
+		Message:     messageFor(http.StatusMethodNotAllowed),
+		Description: "This page doesn't support the " + r.Method + " method.",
+	})
 }
 
-// InternalError renders the 500 internal server error page.
-func (h *Handler) InternalError(w http.ResponseWriter, r *http.Request) {
-	vm := viewdata.New(r)
-	vm.Title = "Server Error"
+// Conflict renders the 409 conflict page, for a request that clashes with
+// another still in progress, such as a retry sharing its Idempotency-Key.
+func (h *Handler) Conflict(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, errorVM{
+		Status:      http.StatusConflict,
+		Message:     messageFor(http.StatusConflict),
+		Description: "This request conflicts with another that is still being processed. Please try again shortly.",
+	})
+}
+
+// ConflictWithDetails renders the 409 conflict page for a change that clashes
+// with data already stored, such as a signup whose email is taken. The details
+// map field names to messages, as for BadRequestWithDetails. Handlers can
+// use dberrors.UniqueViolation to spot the store errors that call for this.
+func (h *Handler) ConflictWithDetails(w http.ResponseWriter, r *http.Request, details map[string]string) {
+	h.render(w, r, errorVM{
+		Status:      http.StatusConflict,
+		Message:     messageFor(http.StatusConflict),
+		Description: "This conflicts with something that already exists. Please change it and try again.",
+		Details:     details,
+	})
+}
+
+// RequestEntityTooLarge renders the 413 page for a request body larger than
+// the server accepts.
+func (h *Handler) RequestEntityTooLarge(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, errorVM{
+		Status:      http.StatusRequestEntityTooLarge,
+		Message:     messageFor(http.StatusRequestEntityTooLarge),
+		Description: "The request is larger than this server accepts.",
+	})
+}
+
+// TooManyRequests renders the 429 too many requests page. A positive
+// retryAfter is written as a Retry-After header in whole seconds (rounded up)
+// and passed to the template; zero omits the header.
+func (h *Handler) TooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := retryAfterSeconds(retryAfter)
+	if seconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	h.render(w, r, errorVM{
+		Status:     http.StatusTooManyRequests,
+		Message:    messageFor(http.StatusTooManyRequests),
+		RetryAfter: seconds,
+	})
+}
 
-	w.WriteHeader(http.StatusInternalServerError)
-	templates.Render(w, r, "errors/internal", vm)
+// retryAfterSeconds converts d to whole seconds for a Retry-After header,
+// rounding up so clients never retry early. Non-positive durations return 0.
+func retryAfterSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// InternalErrorWithError logs err together with the caller's stack trace and
+// renders the 500 internal server error page. Use InternalError when there is
+// no error value to record.
+func (h *Handler) InternalErrorWithError(w http.ResponseWriter, r *http.Request, err error) {
+	r, _ = withIncidentID(r)
+	stack := captureStack(1)
+	h.errLog.LogWithFields(r, "internal server error", err,
+		zap.String("stack", stack),
+	)
+	h.internalError(w, r, err, stack)
+}
+
+// internalError renders the 500 page, with err and stack on it when debug
+// is enabled.
+func (h *Handler) internalError(w http.ResponseWriter, r *http.Request, err error, stack string) {
+	vm := errorVM{Status: http.StatusInternalServerError, Message: messageFor(http.StatusInternalServerError)}
+	if h.debug {
+		if err != nil {
+			vm.DebugError = err.Error()
+		}
+		vm.DebugStack = stack
+	}
+	h.render(w, r, vm)
+}
+
+// InternalError renders the 500 internal server error page. The page and
+// the error log line carry the same incident ID (see withIncidentID).
+func (h *Handler) InternalError(w http.ResponseWriter, r *http.Request) {
+	h.Error(w, r, http.StatusInternalServerError)
 }
@@ -1,12 +1,19 @@
 package errors
 
 import (
+	"encoding/json"
+	stderrors "errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/dalemusser/strataforge/internal/testutil"
+	"github.com/dalemusser/waffle/pantry/templates"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestNewHandler(t *testing.T) {
@@ -97,3 +104,448 @@ func TestErrorLogger_LogWithFields(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	errLog.LogWithFields(req, "test error", nil, zap.String("extra", "field"))
 }
+
+func TestWithTemplate_OverridesBuiltIn(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler(WithTemplate(http.StatusNotFound, "errors/forbidden"))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if !strings.Contains(rec.Body.String(), "permission to access this page") {
+		t.Error("expected custom template to be rendered")
+	}
+}
+
+func TestWithTemplate_LastRegistrationWins(t *testing.T) {
+	h := NewHandler(
+		WithTemplate(http.StatusNotFound, "custom/first"),
+		WithTemplate(http.StatusNotFound, "custom/second"),
+	)
+
+	if got := h.templateFor(http.StatusNotFound); got != "custom/second" {
+		t.Errorf("templateFor(404) = %q, want %q", got, "custom/second")
+	}
+}
+
+func TestTemplateFor_FallsBackToBuiltIn(t *testing.T) {
+	h := NewHandler(WithTemplate(http.StatusNotFound, "custom/not_found"))
+
+	if got := h.templateFor(http.StatusInternalServerError); got != "errors/internal" {
+		t.Errorf("templateFor(500) = %q, want %q", got, "errors/internal")
+	}
+}
+
+func TestMethodNotAllowed_Returns405WithAllowHeader(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/submit", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.MethodNotAllowed(rec, req, http.MethodPost, http.MethodPut)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if got := rec.Header().Get("Allow"); got != "POST, PUT" {
+		t.Errorf("Allow = %q, want %q", got, "POST, PUT")
+	}
+	if !strings.Contains(rec.Body.String(), "405") {
+		t.Error("expected rendered page to contain the status code")
+	}
+}
+
+func TestMethodNotAllowed_KeepsExistingAllowHeader(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/submit", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Allow", "POST")
+
+	h.MethodNotAllowed(rec, req)
+
+	if got := rec.Header().Get("Allow"); got != "POST" {
+		t.Errorf("Allow = %q, want %q", got, "POST")
+	}
+}
+
+func TestBadRequest_Returns400(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/form", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.BadRequest(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestBadRequestWithDetails_RendersDetails(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/form", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.BadRequestWithDetails(rec, req, map[string]string{"email": "is required"})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(rec.Body.String(), "is required") {
+		t.Error("expected validation detail in rendered page")
+	}
+}
+
+func TestConflictWithDetails_RendersDetails(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.ConflictWithDetails(rec, req, map[string]string{"email": "is already taken"})
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if !strings.Contains(rec.Body.String(), "is already taken") {
+		t.Error("expected conflict detail in rendered page")
+	}
+}
+
+func TestInternalErrorWithError_LogsErrorAndStack(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	h := NewHandler(WithErrorLogger(NewErrorLogger(zap.New(core))))
+
+	req := httptest.NewRequest(http.MethodGet, "/error", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.InternalErrorWithError(rec, req, stderrors.New("database unavailable"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	entries := logs.FilterMessage("internal server error").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["error"] != "database unavailable" {
+		t.Errorf("error field = %v, want %q", fields["error"], "database unavailable")
+	}
+	stack, _ := fields["stack"].(string)
+	if !strings.Contains(stack, "TestInternalErrorWithError_LogsErrorAndStack") {
+		t.Errorf("expected stack to include the caller, got:\n%s", stack)
+	}
+}
+
+func TestInternalError_ShowsRequestIDAsReference(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/error", nil)
+	req.Header.Set("X-Request-ID", "ab12cd")
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.InternalError(rec, req)
+
+	if body := rec.Body.String(); !strings.Contains(body, "Reference:") || !strings.Contains(body, "ab12cd") {
+		t.Errorf("500 page does not show the request ID as its reference:\n%s", body)
+	}
+}
+
+func TestInternalErrorWithError_GeneratesIncidentIDForPageAndLog(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	h := NewHandler(WithErrorLogger(NewErrorLogger(zap.New(core))))
+
+	req := httptest.NewRequest(http.MethodGet, "/error", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.InternalErrorWithError(rec, req, stderrors.New("database unavailable"))
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.IncidentID == "" {
+		t.Fatal("incident_id is empty")
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != resp.IncidentID {
+		t.Errorf("X-Request-ID = %q, want %q", got, resp.IncidentID)
+	}
+	for _, msg := range []string{"internal server error", "error response"} {
+		entries := logs.FilterMessage(msg).All()
+		if len(entries) != 1 {
+			t.Fatalf("expected 1 %q log entry, got %d", msg, len(entries))
+		}
+		if got := entries[0].ContextMap()["request_id"]; got != resp.IncidentID {
+			t.Errorf("%q request_id = %v, want %q", msg, got, resp.IncidentID)
+		}
+	}
+}
+
+func TestNotFound_HasNoIncidentID(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Request-ID", "ab12cd")
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if strings.Contains(rec.Body.String(), "incident_id") {
+		t.Errorf("404 body = %s, want no incident_id", rec.Body.String())
+	}
+}
+
+func TestInternalErrorWithError_DebugShowsDetails(t *testing.T) {
+	testutil.MustBootTemplates(t)
+
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"default", nil, false},
+		{"debug off", []Option{WithDebug(false)}, false},
+		{"debug on", []Option{WithDebug(true)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.opts...)
+			req := testutil.WithCSRFToken(httptest.NewRequest(http.MethodGet, "/error", nil))
+			rec := httptest.NewRecorder()
+
+			h.InternalErrorWithError(rec, req, stderrors.New("database unavailable"))
+
+			body := rec.Body.String()
+			if got := strings.Contains(body, "database unavailable"); got != tt.want {
+				t.Errorf("page shows the error = %v, want %v", got, tt.want)
+			}
+			if got := strings.Contains(body, "TestInternalErrorWithError_DebugShowsDetails"); got != tt.want {
+				t.Errorf("page shows the stack = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_OnRenderErrorReceivesRenderError(t *testing.T) {
+	// An engine that was never booted has no templates, so every render fails.
+	eng := templates.New(false)
+
+	var got error
+	h := NewHandler(
+		WithEngine(eng),
+		WithOnRenderError(func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+			w.WriteHeader(http.StatusTeapot)
+		}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	var renderErr *RenderError
+	if !stderrors.As(got, &renderErr) {
+		t.Fatalf("expected *RenderError, got %v", got)
+	}
+	if renderErr.Status != http.StatusNotFound || renderErr.Template != "errors/not_found" {
+		t.Errorf("RenderError = %+v, want status 404 and template errors/not_found", renderErr)
+	}
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want callback's %d", rec.Code, http.StatusTeapot)
+	}
+}
+
+func TestRender_DefaultFallbackWritesPlainText(t *testing.T) {
+	h := NewHandler(WithEngine(templates.New(false)))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if !strings.Contains(rec.Body.String(), "Not Found") {
+		t.Errorf("body = %q, want status text", rec.Body.String())
+	}
+}
+
+func TestWithReload_RendersFreshTemplates(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	// The never-booted engine has no templates; only a reload can find the page.
+	h := NewHandler(WithEngine(templates.New(false)), WithReload(true))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Type"), "text/html") || rec.Body.Len() == 0 {
+		t.Errorf("expected the rendered 404 page, got %q", rec.Body.String())
+	}
+}
+
+func TestWithReload_ParseErrorShowsPage(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	templates.Register(templates.Set{
+		Name:     "errors_reload_broken",
+		FS:       fstest.MapFS{"templates/broken.gohtml": {Data: []byte(`{{ define "broken" }}{{ if }}{{ end }}`)}},
+		Patterns: []string{"templates/*.gohtml"},
+	})
+	t.Cleanup(func() {
+		sets := templates.All()
+		templates.Reset()
+		for _, s := range sets {
+			if s.Name != "errors_reload_broken" {
+				templates.Register(s)
+			}
+		}
+	})
+
+	called := false
+	h := NewHandler(
+		WithEngine(templates.New(false)),
+		WithReload(true),
+		WithOnRenderError(func(w http.ResponseWriter, r *http.Request, err error) { called = true }),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if called {
+		t.Error("OnRenderError called for a reload parse error")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(rec.Body.String(), "broken.gohtml") {
+		t.Errorf("body = %q, want the parse error", rec.Body.String())
+	}
+}
+
+func TestTooManyRequests_SetsRetryAfter(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.TooManyRequests(rec, req, 29500*time.Millisecond)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want %q", got, "30")
+	}
+	if !strings.Contains(rec.Body.String(), "30 seconds") {
+		t.Error("expected retry duration in rendered page")
+	}
+}
+
+func TestTooManyRequests_ZeroOmitsRetryAfter(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.TooManyRequests(rec, req, 0)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if _, ok := rec.Header()["Retry-After"]; ok {
+		t.Error("expected no Retry-After header")
+	}
+}
+
+func TestErrorLogger_NilLoggerIsSafe(t *testing.T) {
+	errLog := NewErrorLogger(nil, WithSampling(1, 1, time.Second))
+
+	// Should not panic
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	errLog.Log(req, "test error", nil)
+	errLog.LogWithFields(req, "test error", nil, zap.String("extra", "field"))
+}
+
+func TestWithPlainText_WritesStatusTextWithoutTemplates(t *testing.T) {
+	h := NewHandler(WithPlainText())
+
+	tests := []struct {
+		name   string
+		call   func(http.ResponseWriter, *http.Request)
+		status int
+	}{
+		{"NotFound", h.NotFound, http.StatusNotFound},
+		{"Forbidden", h.Forbidden, http.StatusForbidden},
+		{"BadRequest", h.BadRequest, http.StatusBadRequest},
+		{"InternalError", h.InternalError, http.StatusInternalServerError},
+		{"TooManyRequests", func(w http.ResponseWriter, r *http.Request) { h.TooManyRequests(w, r, time.Second) }, http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.call(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+				t.Errorf("Content-Type = %q, want text/plain", ct)
+			}
+			if got, want := strings.TrimSpace(rec.Body.String()), http.StatusText(tt.status); got != want {
+				t.Errorf("body = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWithPlainText_JSONStillWins(t *testing.T) {
+	h := NewHandler(WithPlainText())
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+}
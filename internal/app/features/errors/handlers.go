@@ -0,0 +1,80 @@
+// internal/app/features/errors/handlers.go
+package errors
+
+import (
+	"net/http"
+	"time"
+)
+
+// The methods below adapt the error pages to http.Handler values so they can
+// be mounted directly on a router, for example:
+//
+//	r.NotFound(h.NotFoundHandler().ServeHTTP)
+//	mux.Handle("/forbidden", h.ForbiddenHandler())
+
+// BadRequestHandler returns the 400 bad request page as an http.Handler.
+func (h *Handler) BadRequestHandler() http.Handler {
+	return http.HandlerFunc(h.BadRequest)
+}
+
+// UnauthorizedHandler returns the 401 unauthorized page as an http.Handler.
+func (h *Handler) UnauthorizedHandler() http.Handler {
+	return http.HandlerFunc(h.Unauthorized)
+}
+
+// ForbiddenHandler returns the 403 forbidden page as an http.Handler.
+func (h *Handler) ForbiddenHandler() http.Handler {
+	return http.HandlerFunc(h.Forbidden)
+}
+
+// NotFoundHandler returns the 404 not found page as an http.Handler.
+func (h *Handler) NotFoundHandler() http.Handler {
+	return http.HandlerFunc(h.NotFound)
+}
+
+// MethodNotAllowedHandler returns the 405 method not allowed page as an
+// http.Handler that advertises the given methods in the Allow header.
+func (h *Handler) MethodNotAllowedHandler(allowed ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.MethodNotAllowed(w, r, allowed...)
+	})
+}
+
+// DispatchHandler returns Dispatch as an http.Handler, for use as both the
+// router's not-found and method-not-allowed handler.
+func (h *Handler) DispatchHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.Dispatch(w, r, nil)
+	})
+}
+
+// ConflictHandler returns the 409 conflict page as an http.Handler.
+func (h *Handler) ConflictHandler() http.Handler {
+	return http.HandlerFunc(h.Conflict)
+}
+
+// RequestEntityTooLargeHandler returns the 413 request entity too large page
+// as an http.Handler.
+func (h *Handler) RequestEntityTooLargeHandler() http.Handler {
+	return http.HandlerFunc(h.RequestEntityTooLarge)
+}
+
+// TooManyRequestsHandler returns the 429 too many requests page as an
+// http.Handler that sends the given Retry-After hint.
+func (h *Handler) TooManyRequestsHandler(retryAfter time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.TooManyRequests(w, r, retryAfter)
+	})
+}
+
+// InternalErrorHandler returns the 500 internal server error page as an
+// http.Handler.
+func (h *Handler) InternalErrorHandler() http.Handler {
+	return http.HandlerFunc(h.InternalError)
+}
+
+// ServiceUnavailableHandler returns the 503 maintenance page as an
+// http.Handler.
+func (h *Handler) ServiceUnavailableHandler() http.Handler {
+	return http.HandlerFunc(h.ServiceUnavailable)
+}
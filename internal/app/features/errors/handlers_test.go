@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHandlerAdapters(t *testing.T) {
+	h := NewHandler()
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		want    int
+	}{
+		{"BadRequestHandler", h.BadRequestHandler(), http.StatusBadRequest},
+		{"UnauthorizedHandler", h.UnauthorizedHandler(), http.StatusUnauthorized},
+		{"ForbiddenHandler", h.ForbiddenHandler(), http.StatusForbidden},
+		{"NotFoundHandler", h.NotFoundHandler(), http.StatusNotFound},
+		{"MethodNotAllowedHandler", h.MethodNotAllowedHandler(http.MethodGet), http.StatusMethodNotAllowed},
+		{"ConflictHandler", h.ConflictHandler(), http.StatusConflict},
+		{"RequestEntityTooLargeHandler", h.RequestEntityTooLargeHandler(), http.StatusRequestEntityTooLarge},
+		{"TooManyRequestsHandler", h.TooManyRequestsHandler(time.Second), http.StatusTooManyRequests},
+		{"InternalErrorHandler", h.InternalErrorHandler(), http.StatusInternalServerError},
+		{"ServiceUnavailableHandler", h.ServiceUnavailableHandler(), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+
+			tt.handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotFoundHandler_MountsOnChi(t *testing.T) {
+	h := NewHandler()
+	r := chi.NewRouter()
+	r.NotFound(h.NotFoundHandler().ServeHTTP)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,140 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+	"github.com/dalemusser/strataforge/internal/testutil"
+)
+
+func TestSecurityHeaders_DefaultNosniff(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, want %q", got, "nosniff")
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got != "" {
+		t.Errorf("Content-Security-Policy = %q, want none by default", got)
+	}
+}
+
+func TestWithSecurityHeaders_ExtendsAndOverrides(t *testing.T) {
+	h := NewHandler(WithSecurityHeaders(map[string]string{
+		"content-security-policy": "default-src 'self'",
+		"X-Content-Type-Options":  "",
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if got := rec.Header().Get("Content-Security-Policy"); got != "default-src 'self'" {
+		t.Errorf("Content-Security-Policy = %q, want %q", got, "default-src 'self'")
+	}
+	if _, ok := rec.Header()["X-Content-Type-Options"]; ok {
+		t.Error("expected X-Content-Type-Options to be dropped")
+	}
+}
+
+func TestWithSecurityHeaders_CSPNonce(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler(WithSecurityHeaders(map[string]string{
+		"Content-Security-Policy": "default-src 'self'; script-src 'self'",
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req = req.WithContext(csp.WithNonce(req.Context(), "n0nce"))
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if got, want := rec.Header().Get("Content-Security-Policy"), "default-src 'self'; script-src 'self' 'nonce-n0nce'"; got != want {
+		t.Errorf("Content-Security-Policy = %q, want %q", got, want)
+	}
+	if !strings.Contains(rec.Body.String(), `<script nonce="n0nce">`) {
+		t.Error("expected the error page's inline scripts to carry the nonce")
+	}
+}
+
+func TestUnauthorized_WWWAuthenticate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "unset omits header", want: ""},
+		{name: "configured challenge", opts: []Option{WithWWWAuthenticate(`Bearer realm="api"`)}, want: `Bearer realm="api"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.opts...)
+			req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+
+			h.Unauthorized(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.want {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheControl_DefaultNoStore(t *testing.T) {
+	h := NewHandler()
+
+	for _, status := range []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Cache-Control", "public, max-age=3600") // set by the failing handler
+
+		h.Error(rec, req, status)
+
+		if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("status %d: Cache-Control = %q, want %q", status, got, "no-store")
+		}
+	}
+}
+
+func TestWithCacheControl_OverridesPerStatus(t *testing.T) {
+	h := NewHandler(
+		WithCacheControl(http.StatusNotFound, "public, max-age=60"),
+		WithCacheControl(http.StatusGone, ""),
+	)
+
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusNotFound, "public, max-age=60"},
+		{http.StatusGone, ""},
+		{http.StatusInternalServerError, "no-store"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+
+		h.Error(rec, req, tt.status)
+
+		if got := rec.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("status %d: Cache-Control = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
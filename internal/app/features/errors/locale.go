@@ -0,0 +1,115 @@
+// internal/app/features/errors/locale.go
+package errors
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dalemusser/strataforge/internal/app/system/i18n"
+)
+
+// localeKey is the context key for a locale chosen by middleware.
+type localeKey struct{}
+
+// ContextWithLocale returns a copy of ctx carrying locale. The error Handler
+// prefers this over the Accept-Language header when picking message text.
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// localeFromContext returns the locale stored by ContextWithLocale, or else
+// the one chosen by the i18n middleware, if any.
+func localeFromContext(ctx context.Context) string {
+	if locale, _ := ctx.Value(localeKey{}).(string); locale != "" {
+		return locale
+	}
+	return i18n.Locale(ctx)
+}
+
+// WithLocalizedMessages registers error page messages per locale and status
+// code, e.g. messages["es"][404] = "Página no encontrada". The locale is taken
+// from the request context (see ContextWithLocale) or the Accept-Language
+// header; when it isn't registered, or has no message for the status,
+// defaultLocale is used. Statuses with no message in either keep the
+// built-in English text.
+func WithLocalizedMessages(defaultLocale string, messages map[string]map[int]string) Option {
+	return func(h *Handler) {
+		h.defaultLocale = strings.ToLower(defaultLocale)
+		h.messages = make(map[string]map[int]string, len(messages))
+		for locale, byStatus := range messages {
+			h.messages[strings.ToLower(locale)] = byStatus
+		}
+	}
+}
+
+// localizedMessage returns the message for status in the request's locale,
+// falling back to the default locale and finally to fallback.
+func (h *Handler) localizedMessage(r *http.Request, status int, fallback string) string {
+	if len(h.messages) == 0 {
+		return fallback
+	}
+	if msg, ok := h.messages[h.requestLocale(r)][status]; ok {
+		return msg
+	}
+	if msg, ok := h.messages[h.defaultLocale][status]; ok {
+		return msg
+	}
+	return fallback
+}
+
+// requestLocale picks the best registered locale for the request.
+func (h *Handler) requestLocale(r *http.Request) string {
+	if locale := h.matchLocale(localeFromContext(r.Context())); locale != "" {
+		return locale
+	}
+	for _, tag := range acceptedLanguages(r.Header.Get("Accept-Language")) {
+		if locale := h.matchLocale(tag); locale != "" {
+			return locale
+		}
+	}
+	return h.defaultLocale
+}
+
+// matchLocale returns the registered locale matching tag exactly, or its
+// base language (es-MX -> es). Returns "" when neither is registered.
+func (h *Handler) matchLocale(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return ""
+	}
+	if _, ok := h.messages[tag]; ok {
+		return tag
+	}
+	if base, _, found := strings.Cut(tag, "-"); found {
+		if _, ok := h.messages[base]; ok {
+			return base
+		}
+	}
+	return ""
+}
+
+// acceptedLanguages returns the language tags from an Accept-Language header,
+// ordered by descending quality. Tags with q=0 and wildcards are dropped.
+func acceptedLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, q := parseMediaRange(part)
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{tag, q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
@@ -0,0 +1,92 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/system/i18n"
+	"github.com/dalemusser/strataforge/internal/testutil"
+)
+
+var testMessages = map[string]map[int]string{
+	"en": {http.StatusNotFound: "Page Not Found", http.StatusForbidden: "Access Denied"},
+	"es": {http.StatusNotFound: "Página no encontrada"},
+}
+
+func TestAcceptedLanguages(t *testing.T) {
+	got := acceptedLanguages("en;q=0.5, es-MX, fr;q=0, *;q=0.1")
+	want := []string{"es-mx", "en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("acceptedLanguages = %v, want %v", got, want)
+	}
+}
+
+func TestLocalizedMessage(t *testing.T) {
+	h := NewHandler(WithLocalizedMessages("en", testMessages))
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		status         int
+		want           string
+	}{
+		{"exact locale", "es", http.StatusNotFound, "Página no encontrada"},
+		{"regional falls back to base", "es-MX,en;q=0.5", http.StatusNotFound, "Página no encontrada"},
+		{"unknown locale uses default", "de", http.StatusNotFound, "Page Not Found"},
+		{"missing status uses default locale", "es", http.StatusForbidden, "Access Denied"},
+		{"missing everywhere uses built-in", "es", http.StatusInternalServerError, "built-in"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			if got := h.localizedMessage(req, tt.status, "built-in"); got != tt.want {
+				t.Errorf("localizedMessage = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizedMessage_ContextLocaleWins(t *testing.T) {
+	h := NewHandler(WithLocalizedMessages("en", testMessages))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en")
+	req = req.WithContext(ContextWithLocale(req.Context(), "es"))
+
+	if got := h.localizedMessage(req, http.StatusNotFound, "built-in"); got != "Página no encontrada" {
+		t.Errorf("localizedMessage = %q, want Spanish message", got)
+	}
+}
+
+func TestNotFound_RendersLocalizedMessage(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler(WithLocalizedMessages("en", testMessages))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept-Language", "es")
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if !strings.Contains(rec.Body.String(), "Página no encontrada") {
+		t.Error("expected localized message in rendered page")
+	}
+}
+
+func TestLocalizedMessage_UsesI18nLocale(t *testing.T) {
+	h := NewHandler(WithLocalizedMessages("en", testMessages))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en")
+	req = req.WithContext(i18n.WithLocale(req.Context(), "es"))
+
+	if got := h.localizedMessage(req, http.StatusNotFound, "built-in"); got != "Página no encontrada" {
+		t.Errorf("localizedMessage = %q, want the i18n locale's message", got)
+	}
+}
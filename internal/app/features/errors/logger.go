@@ -0,0 +1,317 @@
+// internal/app/features/errors/logger.go
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/logging"
+	"github.com/dalemusser/strataforge/internal/app/system/network"
+	"github.com/dalemusser/waffle/pantry/requestid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorLogger wraps the zap logger for error logging.
+type ErrorLogger struct {
+	logger      *zap.Logger
+	levelMapper func(status int) zapcore.Level
+	redactKeys  map[string]struct{} // lowercased field / query keys to redact
+	reporter    func(r *http.Request, msg string, err error)
+
+	logClientIP    bool
+	trustedProxies []netip.Prefix
+
+	keepDisconnects bool // log client disconnects at the mapped level
+	keepCanceled    bool // log errors on canceled requests at the mapped level
+}
+
+// redactedValue replaces the value of any redacted field or query parameter.
+const redactedValue = "[REDACTED]"
+
+// LoggerOption configures an ErrorLogger.
+type LoggerOption func(*ErrorLogger)
+
+// WithLevelMapper sets the function that picks the log level for an HTTP
+// status. The default is DefaultLevelMapper.
+func WithLevelMapper(fn func(status int) zapcore.Level) LoggerOption {
+	return func(e *ErrorLogger) {
+		e.levelMapper = fn
+	}
+}
+
+// WithSampling limits repeated log lines: within each interval, the first
+// `first` entries with a given message are logged, then only every
+// `thereafter`-th one. This keeps a single failing endpoint from flooding
+// the logs during an incident.
+func WithSampling(first, thereafter int, interval time.Duration) LoggerOption {
+	return func(e *ErrorLogger) {
+		e.logger = e.logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, interval, first, thereafter)
+		}))
+	}
+}
+
+// WithRedactKeys replaces the value of any log field or query-string parameter
+// whose key matches one of keys (case-insensitively) with "[REDACTED]" before
+// it is written. Calling it more than once adds to the set of keys.
+func WithRedactKeys(keys ...string) LoggerOption {
+	return func(e *ErrorLogger) {
+		if e.redactKeys == nil {
+			e.redactKeys = make(map[string]struct{}, len(keys))
+		}
+		for _, k := range keys {
+			e.redactKeys[strings.ToLower(k)] = struct{}{}
+		}
+	}
+}
+
+// WithReporter sets a function, such as a Sentry client, that is called in
+// addition to the log write whenever Log or LogWithFields receives a non-nil
+// error. It runs synchronously on the request goroutine; a panic inside it is
+// recovered and logged so it cannot take down the request.
+func WithReporter(fn func(r *http.Request, msg string, err error)) LoggerOption {
+	return func(e *ErrorLogger) {
+		e.reporter = fn
+	}
+}
+
+// WithClientIP records the client IP on every log line as client_ip.
+// Forwarding headers are only believed from peers within trustedProxies
+// (IPs or CIDR ranges); see network.ClientIP.
+func WithClientIP(trustedProxies ...string) LoggerOption {
+	return func(e *ErrorLogger) {
+		e.logClientIP = true
+		e.trustedProxies = network.ParsePrefixes(trustedProxies)
+	}
+}
+
+// WithDowngradeDisconnects controls whether errors caused by the client
+// going away (broken pipe, connection reset; see IsClientDisconnect) are
+// logged at Debug and kept from the reporter. It is on by default, since
+// such errors are not actionable; pass false to log them like any other
+// error.
+func WithDowngradeDisconnects(enabled bool) LoggerOption {
+	return func(e *ErrorLogger) {
+		e.keepDisconnects = !enabled
+	}
+}
+
+// WithDowngradeCanceled controls whether errors logged for a request whose
+// context was canceled, typically because the user navigated away, are
+// logged at Debug and kept from the reporter. It is on by default, since the
+// failure was caused by the cancellation; pass false to see them at their
+// mapped level. Deadline expiry is not a cancellation and is logged as usual,
+// including a context canceled with context.DeadlineExceeded as its cause,
+// as the timeout middleware does.
+func WithDowngradeCanceled(enabled bool) LoggerOption {
+	return func(e *ErrorLogger) {
+		e.keepCanceled = !enabled
+	}
+}
+
+// IsClientDisconnect reports whether err means the client closed the
+// connection before the response was written: a broken pipe or a
+// connection reset.
+func IsClientDisconnect(err error) bool {
+	return stderrors.Is(err, syscall.EPIPE) || stderrors.Is(err, syscall.ECONNRESET)
+}
+
+// DefaultLevelMapper logs 4xx client errors at Warn, 5xx server errors at
+// Error, and everything else at Info.
+func DefaultLevelMapper(status int) zapcore.Level {
+	switch {
+	case status >= 500:
+		return zapcore.ErrorLevel
+	case status >= 400:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// NewErrorLogger creates a new ErrorLogger.
+// A nil logger is replaced with zap.NewNop(), so the returned ErrorLogger is
+// always safe to use even when the caller has no logger wired up.
+func NewErrorLogger(logger *zap.Logger, opts ...LoggerOption) *ErrorLogger {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	e := &ErrorLogger{
+		logger:      logger,
+		levelMapper: DefaultLevelMapper,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Log logs an error with the given message and error.
+// It is recorded as a 500-class failure.
+func (e *ErrorLogger) Log(r *http.Request, msg string, err error) {
+	e.LogStatus(r, http.StatusInternalServerError, msg, err)
+	e.report(r, msg, err)
+}
+
+// LogWithFields logs an error with additional fields.
+// It is recorded as a 500-class failure.
+func (e *ErrorLogger) LogWithFields(r *http.Request, msg string, err error, fields ...zap.Field) {
+	e.LogStatus(r, http.StatusInternalServerError, msg, err, fields...)
+	e.report(r, msg, err)
+}
+
+// LogStatus logs an error for a response with the given HTTP status.
+// The level is chosen by the configured level mapper, except that client
+// disconnects and canceled requests are logged at Debug (see
+// WithDowngradeDisconnects and WithDowngradeCanceled); canceled requests
+// are marked with canceled=true.
+func (e *ErrorLogger) LogStatus(r *http.Request, status int, msg string, err error, fields ...zap.Field) {
+	allFields := append(e.requestFields(r, err), zap.Int("status", status))
+	allFields = append(allFields, fields...)
+	level := e.levelMapper(status)
+	if e.downgraded(r, err) {
+		level = zapcore.DebugLevel
+		if e.canceled(r, err) {
+			allFields = append(allFields, zap.Bool("canceled", true))
+		}
+	}
+	e.logger.Log(level, msg, e.redact(allFields)...)
+}
+
+// downgraded reports whether err is a client disconnect, or r was
+// canceled, so the error should be logged at Debug rather than its mapped
+// level.
+func (e *ErrorLogger) downgraded(r *http.Request, err error) bool {
+	return (!e.keepDisconnects && IsClientDisconnect(err)) || e.canceled(r, err)
+}
+
+// canceled reports whether r's context was canceled, or err is a
+// cancellation, and WithDowngradeCanceled has not been turned off. A
+// context ended by a deadline, or canceled with one as its cause, is not a
+// cancellation even when err is context.Canceled, since that is what
+// operations on such a context return.
+func (e *ErrorLogger) canceled(r *http.Request, err error) bool {
+	if e.keepCanceled {
+		return false
+	}
+	if r != nil && r.Context().Err() != nil {
+		return stderrors.Is(context.Cause(r.Context()), context.Canceled)
+	}
+	return stderrors.Is(err, context.Canceled)
+}
+
+// report passes err to the configured reporter, if any, recovering from
+// panics raised by the reporter.
+func (e *ErrorLogger) report(r *http.Request, msg string, err error) {
+	if e.reporter == nil || err == nil || e.downgraded(r, err) {
+		return
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			e.logger.Error("error reporter panicked",
+				zap.Any("panic", rec),
+				zap.String("path", r.URL.Path),
+			)
+		}
+	}()
+	e.reporter(r, msg, err)
+}
+
+// requestFields returns the fields recorded on every error log line. When
+// the request carries a context logger (logging.ContextMiddleware), its
+// request-scoped fields, such as request_id and user_id, are used so error
+// lines match the handler's own log lines; the ErrorLogger's core, level
+// mapping, sampling, and redaction still apply. A span in the context (see
+// the otel feature) adds trace_id and span_id.
+func (e *ErrorLogger) requestFields(r *http.Request, err error) []zap.Field {
+	fields := []zap.Field{
+		zap.Error(err),
+		zap.String("path", r.URL.Path),
+		zap.String("method", r.Method),
+	}
+	if r.URL.RawQuery != "" {
+		fields = append(fields, zap.String("query", e.redactQuery(r.URL.RawQuery)))
+	}
+	if pattern := routePattern(r); pattern != "" {
+		fields = append(fields, zap.String("route", pattern))
+	}
+	if scoped := logging.ContextFields(r.Context()); scoped != nil {
+		fields = append(fields, scoped...)
+	} else if id := RequestID(r); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", sc.TraceID().String()),
+			zap.String("span_id", sc.SpanID().String()),
+		)
+	}
+	if e.logClientIP {
+		fields = append(fields, zap.String("client_ip", network.ClientIP(r, e.trustedProxies)))
+	}
+	return fields
+}
+
+// isRedacted reports whether key should have its value redacted.
+func (e *ErrorLogger) isRedacted(key string) bool {
+	_, ok := e.redactKeys[strings.ToLower(key)]
+	return ok
+}
+
+// redact replaces the values of fields with redacted keys.
+func (e *ErrorLogger) redact(fields []zap.Field) []zap.Field {
+	if len(e.redactKeys) == 0 {
+		return fields
+	}
+	for i, f := range fields {
+		if e.isRedacted(f.Key) {
+			fields[i] = zap.String(f.Key, redactedValue)
+		}
+	}
+	return fields
+}
+
+// redactQuery returns rawQuery with the values of redacted parameters replaced.
+// An unparseable query is dropped entirely rather than risk logging secrets.
+func (e *ErrorLogger) redactQuery(rawQuery string) string {
+	if len(e.redactKeys) == 0 {
+		return rawQuery
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redactedValue
+	}
+	for key := range values {
+		if e.isRedacted(key) {
+			values[key] = []string{redactedValue}
+		}
+	}
+	return values.Encode()
+}
+
+// RequestID returns the correlation ID for the request. It prefers the ID
+// stored in the context by the request ID middleware and falls back to the
+// incoming X-Request-ID header. Returns "" when neither is present.
+func RequestID(r *http.Request) string {
+	if id := requestid.FromRequest(r); id != "" {
+		return id
+	}
+	return r.Header.Get(requestid.DefaultHeader)
+}
+
+// RequestIDMiddleware ensures every request carries a request ID. An incoming
+// X-Request-ID header is reused; otherwise a short random ID is generated.
+// The ID is stored in the request context and echoed back on the response.
+func RequestIDMiddleware() func(http.Handler) http.Handler {
+	cfg := requestid.DefaultConfig()
+	cfg.Generator = requestid.GenerateShort
+	return requestid.Middleware(cfg)
+}
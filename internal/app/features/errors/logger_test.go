@@ -0,0 +1,488 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestErrorLogger_IncludesRequestIDFromHeader(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-ID", "abc123")
+	errLog.Log(req, "test error", nil)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()["request_id"]; got != "abc123" {
+		t.Errorf("request_id = %v, want %q", got, "abc123")
+	}
+}
+
+func TestErrorLogger_IncludesTraceIDs(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req = req.WithContext(trace.ContextWithSpanContext(req.Context(), sc))
+	errLog.Log(req, "test error", nil)
+
+	fields := logs.All()[0].ContextMap()
+	if fields["trace_id"] != traceID.String() || fields["span_id"] != spanID.String() {
+		t.Errorf("trace_id, span_id = %v, %v", fields["trace_id"], fields["span_id"])
+	}
+
+	// Without a span, no trace fields are added.
+	errLog.Log(httptest.NewRequest(http.MethodGet, "/test", nil), "test error", nil)
+	if _, ok := logs.All()[1].ContextMap()["trace_id"]; ok {
+		t.Error("trace_id logged for a request without a span")
+	}
+}
+
+func TestRequestIDMiddleware_GeneratesAndEchoes(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r)
+		errLog.Log(r, "inside handler", nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	rec := httptest.NewRecorder()
+	RequestIDMiddleware()(next).ServeHTTP(rec, req)
+
+	if seen == "" {
+		t.Fatal("expected a generated request ID")
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != seen {
+		t.Errorf("X-Request-ID header = %q, want %q", got, seen)
+	}
+	if got := logs.All()[0].ContextMap()["request_id"]; got != seen {
+		t.Errorf("logged request_id = %v, want %q", got, seen)
+	}
+}
+
+func TestRequestIDMiddleware_ReusesIncomingHeader(t *testing.T) {
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-ID", "from-proxy")
+	rec := httptest.NewRecorder()
+	RequestIDMiddleware()(next).ServeHTTP(rec, req)
+
+	if seen != "from-proxy" {
+		t.Errorf("RequestID = %q, want %q", seen, "from-proxy")
+	}
+}
+
+func TestDefaultLevelMapper(t *testing.T) {
+	tests := []struct {
+		status int
+		want   zapcore.Level
+	}{
+		{http.StatusOK, zapcore.InfoLevel},
+		{http.StatusFound, zapcore.InfoLevel},
+		{http.StatusBadRequest, zapcore.WarnLevel},
+		{http.StatusNotFound, zapcore.WarnLevel},
+		{http.StatusInternalServerError, zapcore.ErrorLevel},
+		{http.StatusServiceUnavailable, zapcore.ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultLevelMapper(tt.status); got != tt.want {
+			t.Errorf("DefaultLevelMapper(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestErrorLogger_LogStatusUsesMappedLevel(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	errLog.LogStatus(req, http.StatusNotFound, "not found", nil)
+	errLog.Log(req, "boom", nil)
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 log entries, got %d", len(entries))
+	}
+	if entries[0].Level != zapcore.WarnLevel {
+		t.Errorf("404 level = %v, want %v", entries[0].Level, zapcore.WarnLevel)
+	}
+	if entries[1].Level != zapcore.ErrorLevel {
+		t.Errorf("Log level = %v, want %v", entries[1].Level, zapcore.ErrorLevel)
+	}
+}
+
+func TestErrorLogger_WithLevelMapper(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core), WithLevelMapper(func(int) zapcore.Level {
+		return zapcore.DebugLevel
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	errLog.Log(req, "quiet", nil)
+
+	if got := logs.All()[0].Level; got != zapcore.DebugLevel {
+		t.Errorf("level = %v, want %v", got, zapcore.DebugLevel)
+	}
+}
+
+func TestErrorLogger_WithSampling(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core), WithSampling(2, 5, time.Minute))
+
+	req := httptest.NewRequest(http.MethodGet, "/broken", nil)
+	for i := 0; i < 12; i++ {
+		errLog.Log(req, "same failure", nil)
+	}
+	errLog.Log(req, "different failure", nil)
+
+	// First 2 logged, then every 5th: occurrences 1, 2, 7, 12.
+	if got := logs.FilterMessage("same failure").Len(); got != 4 {
+		t.Errorf("sampled entries = %d, want 4", got)
+	}
+	if got := logs.FilterMessage("different failure").Len(); got != 1 {
+		t.Errorf("distinct message entries = %d, want 1", got)
+	}
+}
+
+func TestErrorLogger_NoSamplingByDefault(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	req := httptest.NewRequest(http.MethodGet, "/broken", nil)
+	for i := 0; i < 12; i++ {
+		errLog.Log(req, "same failure", nil)
+	}
+
+	if got := logs.Len(); got != 12 {
+		t.Errorf("entries = %d, want 12", got)
+	}
+}
+
+func TestErrorLogger_WithRedactKeys_Fields(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core), WithRedactKeys("authorization", "password"))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	errLog.LogWithFields(req, "test error", nil,
+		zap.String("Authorization", "Bearer secret"),
+		zap.String("PASSWORD", "hunter2"),
+		zap.String("user", "alice"),
+	)
+
+	fields := logs.All()[0].ContextMap()
+	if fields["Authorization"] != "[REDACTED]" {
+		t.Errorf("Authorization = %v, want [REDACTED]", fields["Authorization"])
+	}
+	if fields["PASSWORD"] != "[REDACTED]" {
+		t.Errorf("PASSWORD = %v, want [REDACTED]", fields["PASSWORD"])
+	}
+	if fields["user"] != "alice" {
+		t.Errorf("user = %v, want alice", fields["user"])
+	}
+}
+
+func TestErrorLogger_WithRedactKeys_Query(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core), WithRedactKeys("token"))
+
+	req := httptest.NewRequest(http.MethodGet, "/reset?Token=abc123&page=2", nil)
+	errLog.Log(req, "test error", nil)
+
+	query, _ := logs.All()[0].ContextMap()["query"].(string)
+	if strings.Contains(query, "abc123") {
+		t.Errorf("query %q leaks the token", query)
+	}
+	if !strings.Contains(query, "page=2") {
+		t.Errorf("query %q dropped unredacted parameters", query)
+	}
+}
+
+func TestErrorLogger_ReporterCalledWithError(t *testing.T) {
+	var gotMsg string
+	var gotErr error
+	errLog := NewErrorLogger(zap.NewNop(), WithReporter(func(r *http.Request, msg string, err error) {
+		gotMsg, gotErr = msg, err
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	boom := stderrors.New("boom")
+	errLog.LogWithFields(req, "db failed", boom, zap.String("extra", "x"))
+
+	if gotMsg != "db failed" || gotErr != boom {
+		t.Errorf("reporter got (%q, %v), want (%q, %v)", gotMsg, gotErr, "db failed", boom)
+	}
+}
+
+func TestErrorLogger_ReporterSkippedWithoutError(t *testing.T) {
+	called := false
+	errLog := NewErrorLogger(zap.NewNop(), WithReporter(func(r *http.Request, msg string, err error) {
+		called = true
+	}))
+
+	errLog.Log(httptest.NewRequest(http.MethodGet, "/test", nil), "no error", nil)
+
+	if called {
+		t.Error("expected reporter not to be called for a nil error")
+	}
+}
+
+func TestErrorLogger_ReporterPanicRecovered(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core), WithReporter(func(r *http.Request, msg string, err error) {
+		panic("reporter exploded")
+	}))
+
+	// Should not panic
+	errLog.Log(httptest.NewRequest(http.MethodGet, "/test", nil), "test error", stderrors.New("boom"))
+
+	if logs.FilterMessage("error reporter panicked").Len() != 1 {
+		t.Error("expected the reporter panic to be logged")
+	}
+}
+
+func TestErrorLogger_WithClientIP(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core), WithClientIP("10.0.0.0/8"))
+
+	trusted := httptest.NewRequest(http.MethodGet, "/", nil)
+	trusted.RemoteAddr = "10.0.0.1:1234"
+	trusted.Header.Set("X-Forwarded-For", "203.0.113.7")
+	errLog.Log(trusted, "test error", nil)
+
+	spoofed := httptest.NewRequest(http.MethodGet, "/", nil)
+	spoofed.RemoteAddr = "198.51.100.1:1234"
+	spoofed.Header.Set("X-Forwarded-For", "203.0.113.7")
+	errLog.Log(spoofed, "test error", nil)
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["client_ip"]; got != "203.0.113.7" {
+		t.Errorf("trusted client_ip = %v, want %q", got, "203.0.113.7")
+	}
+	if got := entries[1].ContextMap()["client_ip"]; got != "198.51.100.1" {
+		t.Errorf("spoofed client_ip = %v, want %q", got, "198.51.100.1")
+	}
+}
+
+func TestErrorLogger_OmitsClientIPByDefault(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	errLog.Log(httptest.NewRequest(http.MethodGet, "/", nil), "test error", nil)
+
+	if _, ok := logs.All()[0].ContextMap()["client_ip"]; ok {
+		t.Error("client_ip logged without WithClientIP")
+	}
+}
+
+func TestErrorLogger_UsesContextLoggerFields(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Request-ID", "header-id")
+	req = req.WithContext(logging.NewContext(req.Context(), zap.NewNop(),
+		zap.String("request_id", "ctx-id"),
+		zap.String("user_id", "user-7"),
+	))
+	errLog.Log(req, "test error", nil)
+
+	entry := logs.All()[0]
+	fields := entry.ContextMap()
+	if fields["request_id"] != "ctx-id" || fields["user_id"] != "user-7" {
+		t.Errorf("fields = %v, want request_id and user_id from the context logger", fields)
+	}
+	var requestIDs int
+	for _, f := range entry.Context {
+		if f.Key == "request_id" {
+			requestIDs++
+		}
+	}
+	if requestIDs != 1 {
+		t.Errorf("request_id appears %d times, want 1", requestIDs)
+	}
+}
+
+func TestErrorLogger_UserIDAfterAuth(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errLog.Log(r, "test error", nil)
+	})
+	h = logging.UserMiddleware(func(r *http.Request) string { return r.Header.Get("X-Test-User") })(h)
+	h = logging.ContextMiddleware(zap.NewNop(), nil)(h)
+
+	for _, user := range []string{"user-7", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("X-Test-User", user)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["user_id"]; got != "user-7" {
+		t.Errorf("authenticated user_id = %v, want %q", got, "user-7")
+	}
+	if _, ok := entries[1].ContextMap()["user_id"]; ok {
+		t.Error("anonymous request logged a user_id")
+	}
+}
+
+func TestIsClientDisconnect(t *testing.T) {
+	write := &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	if !IsClientDisconnect(write) {
+		t.Error("broken pipe should be a client disconnect")
+	}
+	if !IsClientDisconnect(reset) {
+		t.Error("connection reset should be a client disconnect")
+	}
+	if IsClientDisconnect(stderrors.New("db timeout")) || IsClientDisconnect(nil) {
+		t.Error("other errors should not be client disconnects")
+	}
+}
+
+func TestErrorLogger_DowngradesDisconnects(t *testing.T) {
+	brokenPipe := &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}
+
+	tests := []struct {
+		name         string
+		opts         []LoggerOption
+		wantLevel    zapcore.Level
+		wantReported bool
+	}{
+		{"default", nil, zapcore.DebugLevel, false},
+		{"disabled", []LoggerOption{WithDowngradeDisconnects(false)}, zapcore.ErrorLevel, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			reported := false
+			opts := append([]LoggerOption{WithReporter(func(*http.Request, string, error) { reported = true })}, tt.opts...)
+			errLog := NewErrorLogger(zap.New(core), opts...)
+
+			errLog.Log(httptest.NewRequest(http.MethodGet, "/export", nil), "failed to write export", brokenPipe)
+
+			entries := logs.All()
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 log entry, got %d", len(entries))
+			}
+			if entries[0].Level != tt.wantLevel {
+				t.Errorf("level = %v, want %v", entries[0].Level, tt.wantLevel)
+			}
+			if reported != tt.wantReported {
+				t.Errorf("reported = %v, want %v", reported, tt.wantReported)
+			}
+		})
+	}
+}
+
+func TestErrorLogger_DowngradesCanceled(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []LoggerOption
+		cancel       bool
+		wantLevel    zapcore.Level
+		wantReported bool
+	}{
+		{"canceled", nil, true, zapcore.DebugLevel, false},
+		{"not canceled", nil, false, zapcore.ErrorLevel, true},
+		{"disabled", []LoggerOption{WithDowngradeCanceled(false)}, true, zapcore.ErrorLevel, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.DebugLevel)
+			reported := false
+			opts := append([]LoggerOption{WithReporter(func(*http.Request, string, error) { reported = true })}, tt.opts...)
+			errLog := NewErrorLogger(zap.New(core), opts...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			req := httptest.NewRequest(http.MethodGet, "/report", nil).WithContext(ctx)
+			errLog.Log(req, "failed to load report", stderrors.New("query failed"))
+
+			entries := logs.All()
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 log entry, got %d", len(entries))
+			}
+			if entries[0].Level != tt.wantLevel {
+				t.Errorf("level = %v, want %v", entries[0].Level, tt.wantLevel)
+			}
+			_, marked := entries[0].ContextMap()["canceled"]
+			if want := tt.wantLevel == zapcore.DebugLevel; marked != want {
+				t.Errorf("canceled field present = %v, want %v", marked, want)
+			}
+			if reported != tt.wantReported {
+				t.Errorf("reported = %v, want %v", reported, tt.wantReported)
+			}
+		})
+	}
+}
+
+func TestErrorLogger_TimeoutIsNotCanceled(t *testing.T) {
+	// The timeout middleware cancels the request context with
+	// DeadlineExceeded as the cause; queries on it fail with context.Canceled.
+	core, logs := observer.New(zap.DebugLevel)
+	reported := false
+	errLog := NewErrorLogger(zap.New(core), WithReporter(func(*http.Request, string, error) { reported = true }))
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(context.DeadlineExceeded)
+	req := httptest.NewRequest(http.MethodGet, "/report", nil).WithContext(ctx)
+	errLog.Log(req, "failed to load report", fmt.Errorf("find: %w", context.Canceled))
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].Level != zapcore.ErrorLevel {
+		t.Fatalf("expected one Error entry, got %v", entries)
+	}
+	if _, marked := entries[0].ContextMap()["canceled"]; marked {
+		t.Error("timed-out request marked canceled")
+	}
+	if !reported {
+		t.Error("timed-out request was not reported")
+	}
+}
+
+func TestErrorLogger_DowngradesCanceledError(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	errLog.Log(httptest.NewRequest(http.MethodGet, "/report", nil), "query aborted", fmt.Errorf("find: %w", context.Canceled))
+
+	if entries := logs.All(); len(entries) != 1 || entries[0].Level != zapcore.DebugLevel {
+		t.Errorf("expected one Debug entry, got %v", entries)
+	}
+}
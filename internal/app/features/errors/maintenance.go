@@ -0,0 +1,100 @@
+// internal/app/features/errors/maintenance.go
+package errors
+
+import (
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/network"
+)
+
+// WithMaintenanceAllowlist lets requests from the given IPs or CIDR ranges
+// (e.g. "10.0.0.5", "192.168.0.0/16") through while maintenance mode is on.
+// Entries that cannot be parsed are ignored.
+func WithMaintenanceAllowlist(entries ...string) Option {
+	return func(h *Handler) {
+		h.maintenanceAllow = append(h.maintenanceAllow, network.ParsePrefixes(entries)...)
+	}
+}
+
+// WithTrustedProxies sets the reverse proxies (IPs or CIDR ranges) whose
+// X-Forwarded-For and X-Real-IP headers are believed when finding the client
+// IP for the maintenance allowlist. Without it, the connecting peer's
+// address is used.
+func WithTrustedProxies(entries ...string) Option {
+	return func(h *Handler) {
+		h.trustedProxies = network.ParsePrefixes(entries)
+	}
+}
+
+// WithMaintenanceRetryAfter sets the Retry-After hint sent with maintenance
+// responses from the Maintenance middleware. Zero omits the header.
+func WithMaintenanceRetryAfter(d time.Duration) Option {
+	return func(h *Handler) {
+		h.maintenanceRetryAfter = d
+	}
+}
+
+// SetMaintenance turns maintenance mode on or off. It is safe to call while
+// requests are being served.
+func (h *Handler) SetMaintenance(on bool) {
+	h.maintenance.Store(on)
+}
+
+// InMaintenance reports whether maintenance mode is on.
+func (h *Handler) InMaintenance() bool {
+	return h.maintenance.Load()
+}
+
+// Maintenance is middleware that answers every request with the 503
+// maintenance page while maintenance mode is on. Requests from allow-listed
+// IPs are passed through so operators can verify the app before reopening it.
+func (h *Handler) Maintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.InMaintenance() || h.maintenanceAllowed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.ServiceUnavailableWithRetryAfter(w, r, h.maintenanceRetryAfter)
+	})
+}
+
+// maintenanceAllowed reports whether the client IP is on the allowlist.
+func (h *Handler) maintenanceAllowed(r *http.Request) bool {
+	if len(h.maintenanceAllow) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(network.ClientIP(r, h.trustedProxies))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range h.maintenanceAllow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceUnavailable renders the 503 maintenance page.
+func (h *Handler) ServiceUnavailable(w http.ResponseWriter, r *http.Request) {
+	h.ServiceUnavailableWithRetryAfter(w, r, 0)
+}
+
+// ServiceUnavailableWithRetryAfter renders the 503 maintenance page. A
+// positive retryAfter is written as a Retry-After header in whole seconds
+// (rounded up) and passed to the template; zero omits the header.
+func (h *Handler) ServiceUnavailableWithRetryAfter(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := retryAfterSeconds(retryAfter)
+	if seconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	h.render(w, r, errorVM{
+		Status:     http.StatusServiceUnavailable,
+		Message:    messageFor(http.StatusServiceUnavailable),
+		RetryAfter: seconds,
+	})
+}
@@ -0,0 +1,127 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/testutil"
+)
+
+func TestServiceUnavailable_Returns503(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = testutil.WithCSRFToken(req)
+	rec := httptest.NewRecorder()
+
+	h.ServiceUnavailableWithRetryAfter(rec, req, 2*time.Minute)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "120" {
+		t.Errorf("Retry-After = %q, want %q", got, "120")
+	}
+	if !strings.Contains(rec.Body.String(), "Down for Maintenance") {
+		t.Error("expected maintenance page to be rendered")
+	}
+}
+
+func TestServiceUnavailable_OmitsRetryAfter(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.ServiceUnavailable(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if _, ok := rec.Header()["Retry-After"]; ok {
+		t.Error("expected no Retry-After header")
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	h := NewHandler(
+		WithMaintenanceAllowlist("203.0.113.7", "10.0.0.0/8", "::1", "not-an-ip"),
+		WithMaintenanceRetryAfter(time.Minute),
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := h.Maintenance(next)
+
+	tests := []struct {
+		name       string
+		on         bool
+		remoteAddr string
+		want       int
+	}{
+		{name: "off passes through", on: false, remoteAddr: "198.51.100.1:1234", want: http.StatusOK},
+		{name: "on blocks unlisted IP", on: true, remoteAddr: "198.51.100.1:1234", want: http.StatusServiceUnavailable},
+		{name: "on allows listed IP", on: true, remoteAddr: "203.0.113.7:1234", want: http.StatusOK},
+		{name: "on allows CIDR member", on: true, remoteAddr: "10.1.2.3:1234", want: http.StatusOK},
+		{name: "on allows IPv6", on: true, remoteAddr: "[::1]:1234", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.SetMaintenance(tt.on)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", "application/json")
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+
+			mw.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "60" {
+				t.Errorf("Retry-After = %q, want %q", rec.Header().Get("Retry-After"), "60")
+			}
+		})
+	}
+}
+
+func TestMaintenance_TrustedProxies(t *testing.T) {
+	h := NewHandler(
+		WithMaintenanceAllowlist("203.0.113.7"),
+		WithTrustedProxies("10.0.0.1"),
+	)
+	h.SetMaintenance(true)
+	mw := h.Maintenance(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		want       int
+	}{
+		{name: "forwarded by trusted proxy", remoteAddr: "10.0.0.1:1234", want: http.StatusOK},
+		{name: "spoofed by untrusted peer", remoteAddr: "198.51.100.1:1234", want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", "application/json")
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+
+			mw.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
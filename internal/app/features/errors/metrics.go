@@ -0,0 +1,49 @@
+// internal/app/features/errors/metrics.go
+package errors
+
+import (
+	stderrors "errors"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithMetrics registers a strataforge_errors_total counter, labelled by
+// status code, with reg and increments it for every error response. If an
+// identical counter is already registered with reg, it is reused. Without
+// this option no counter is created.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(h *Handler) {
+		counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "strataforge_errors_total",
+			Help: "Number of error responses served, by HTTP status code.",
+		}, []string{"status"})
+
+		if err := reg.Register(counter); err != nil {
+			var are prometheus.AlreadyRegisteredError
+			if !stderrors.As(err, &are) {
+				panic(err)
+			}
+			existing, ok := are.ExistingCollector.(*prometheus.CounterVec)
+			if !ok {
+				panic(err)
+			}
+			counter = existing
+		}
+		h.metrics = counter
+	}
+}
+
+// Metrics returns the error counter registered by WithMetrics, or nil when
+// metrics are disabled.
+func (h *Handler) Metrics() *prometheus.CounterVec {
+	return h.metrics
+}
+
+// countError increments the error counter for status when metrics are enabled.
+func (h *Handler) countError(status int) {
+	if h.metrics == nil {
+		return
+	}
+	h.metrics.WithLabelValues(strconv.Itoa(status)).Inc()
+}
@@ -0,0 +1,50 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWithMetrics_CountsByStatus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	h := NewHandler(WithMetrics(reg))
+
+	for _, fn := range []http.HandlerFunc{h.NotFound, h.NotFound, h.InternalError} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/json")
+		fn(httptest.NewRecorder(), req)
+	}
+
+	if got := promtest.ToFloat64(h.Metrics().WithLabelValues("404")); got != 2 {
+		t.Errorf("404 count = %v, want 2", got)
+	}
+	if got := promtest.ToFloat64(h.Metrics().WithLabelValues("500")); got != 1 {
+		t.Errorf("500 count = %v, want 1", got)
+	}
+}
+
+func TestWithMetrics_ReusesRegisteredCounter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	first := NewHandler(WithMetrics(reg))
+	second := NewHandler(WithMetrics(reg))
+
+	if first.Metrics() != second.Metrics() {
+		t.Error("expected handlers sharing a registerer to share the counter")
+	}
+}
+
+func TestMetrics_NilWithoutOption(t *testing.T) {
+	h := NewHandler()
+	if h.Metrics() != nil {
+		t.Error("expected no counter without WithMetrics")
+	}
+
+	// Should not panic
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	h.NotFound(httptest.NewRecorder(), req)
+}
@@ -0,0 +1,84 @@
+// internal/app/features/errors/negotiate.go
+package errors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorResponse is the JSON body written when a client prefers JSON over HTML.
+// Details carries field-level errors for 400 and 409 responses.
+//
+// Example:
+//
+//	{"error":"not_found","status":404}
+//	{"error":"bad_request","status":400,"details":{"email":"is required"}}
+type ErrorResponse struct {
+	Error   string            `json:"error"`
+	Status  int               `json:"status"`
+	Details map[string]string `json:"details,omitempty"`
+
+	// IncidentID is the request ID of a 5xx response, to quote to support.
+	IncidentID string `json:"incident_id,omitempty"`
+}
+
+// newErrorResponse builds the JSON body for the given status code.
+func newErrorResponse(status int) ErrorResponse {
+	return ErrorResponse{
+		Error:  errorCode(status),
+		Status: status,
+	}
+}
+
+// errorCode converts a status code into a snake_case identifier,
+// e.g. 404 -> "not_found", 500 -> "internal_server_error".
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	text = strings.ToLower(text)
+	text = strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+	return text
+}
+
+// wantsJSON reports whether the request's Accept header prefers
+// application/json (or application/problem+json) over text/html. A missing Accept header, a wildcard,
+// or a tie all resolve to HTML so existing pages keep rendering.
+func wantsJSON(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	var jsonQ, htmlQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, q := parseMediaRange(part)
+		switch mediaType {
+		case "application/json", ProblemContentType:
+			jsonQ = max(jsonQ, q)
+		case "text/html", "application/xhtml+xml", "*/*":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+	return jsonQ > htmlQ
+}
+
+// parseMediaRange splits a single Accept entry into its media type and
+// quality value. Entries without a valid q parameter default to 1.
+func parseMediaRange(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+	q := 1.0
+	for _, param := range fields[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.TrimSpace(key) != "q" {
+			continue
+		}
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			q = parsed
+		}
+	}
+	return mediaType, q
+}
@@ -0,0 +1,157 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWantsJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{"no header", "", false},
+		{"json", "application/json", true},
+		{"html", "text/html", false},
+		{"wildcard", "*/*", false},
+		{"browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false},
+		{"json preferred by q", "text/html;q=0.5, application/json", true},
+		{"html preferred by q", "application/json;q=0.5, text/html", false},
+		{"json with wildcard fallback", "application/json, */*;q=0.1", true},
+		{"tie resolves to html", "application/json, text/html", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			if got := wantsJSON(req); got != tt.want {
+				t.Errorf("wantsJSON(%q) = %v, want %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusUnauthorized, "unauthorized"},
+		{http.StatusForbidden, "forbidden"},
+		{http.StatusNotFound, "not_found"},
+		{http.StatusInternalServerError, "internal_server_error"},
+		{999, "error"},
+	}
+
+	for _, tt := range tests {
+		if got := errorCode(tt.status); got != tt.want {
+			t.Errorf("errorCode(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestNotFound_JSON(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/missing", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.NotFound(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", ct, "application/json")
+	}
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "not_found" || resp.Status != http.StatusNotFound {
+		t.Errorf("response = %+v, want {Error:not_found Status:404}", resp)
+	}
+}
+
+func TestInternalError_JSON(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/broken", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.InternalError(rec, req)
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "internal_server_error" || resp.Status != http.StatusInternalServerError {
+		t.Errorf("response = %+v, want {Error:internal_server_error Status:500}", resp)
+	}
+}
+
+func TestBadRequestWithDetails_JSON(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.BadRequestWithDetails(rec, req, map[string]string{"email": "is required"})
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "bad_request" || resp.Status != http.StatusBadRequest {
+		t.Errorf("response = %+v, want {Error:bad_request Status:400}", resp)
+	}
+	if resp.Details["email"] != "is required" {
+		t.Errorf("details = %v, want email: is required", resp.Details)
+	}
+}
+
+func TestConflictWithDetails_JSON(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.ConflictWithDetails(rec, req, map[string]string{"email": "is already taken"})
+
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "conflict" || resp.Status != http.StatusConflict {
+		t.Errorf("response = %+v, want {Error:conflict Status:409}", resp)
+	}
+	if resp.Details["email"] != "is already taken" {
+		t.Errorf("details = %v, want email: is already taken", resp.Details)
+	}
+}
+
+func TestBadRequest_JSONOmitsDetails(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.BadRequest(rec, req)
+
+	if strings.Contains(rec.Body.String(), "details") {
+		t.Errorf("expected no details key, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,120 @@
+// internal/app/features/errors/problem.go
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of ProblemDetails bodies.
+const ProblemContentType = "application/problem+json"
+
+// ProblemDetails is the RFC 7807 body written instead of ErrorResponse to
+// clients that prefer JSON, when the Handler is created with
+// WithProblemDetails. Details and IncidentID are extension members carrying
+// the same data as in ErrorResponse.
+//
+// Example:
+//
+//	{"type":"about:blank","title":"Not Found","status":404,"instance":"/api/widgets/7"}
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Details    map[string]string `json:"details,omitempty"`
+	IncidentID string            `json:"incident_id,omitempty"`
+}
+
+// WithProblemDetails makes JSON error responses RFC 7807 problem details
+// (ProblemDetails, as application/problem+json) instead of ErrorResponse.
+// HTML error pages are unchanged.
+func WithProblemDetails(enabled bool) Option {
+	return func(h *Handler) {
+		h.problemDetails = enabled
+	}
+}
+
+// WithProblemTypeBase sets the URI prefix for the type member of problem
+// details: the error code is appended, as in
+// "https://example.com/problems/not_found". Empty, the default, uses
+// "about:blank", which RFC 7807 defines as "no more than the status code".
+func WithProblemTypeBase(base string) Option {
+	return func(h *Handler) {
+		h.problemTypeBase = base
+	}
+}
+
+// Problem writes an error response described by p, for handlers that want
+// to tell the client more than the status. p.Status selects the status
+// (outside 400-599 it is treated as 500); p.Detail is shown as the page's
+// description, and with WithProblemDetails the given Type, Title, Detail,
+// and Instance replace the defaults in the JSON body. Without
+// WithProblemDetails, JSON clients get the usual ErrorResponse.
+//
+//	h.Problem(w, r, errors.ProblemDetails{
+//		Status:   http.StatusNotFound,
+//		Detail:   "No widget has ID 7.",
+//		Instance: "/api/widgets/7",
+//	})
+func (h *Handler) Problem(w http.ResponseWriter, r *http.Request, p ProblemDetails) {
+	status := p.Status
+	if status < 400 || status > 599 {
+		status = http.StatusInternalServerError
+	}
+	h.render(w, r, errorVM{
+		Status:      status,
+		Message:     messageFor(status),
+		Description: p.Detail,
+		Details:     p.Details,
+		problem:     p,
+	})
+}
+
+// writeProblem writes vm as application/problem+json, filling in whatever
+// the caller of Problem left empty.
+func (h *Handler) writeProblem(w http.ResponseWriter, r *http.Request, vm errorVM) {
+	p := vm.problem
+	p.Status = vm.Status
+	if p.Type == "" {
+		p.Type = h.problemType(vm.Status)
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(vm.Status)
+		if p.Title == "" {
+			p.Title = vm.Message
+		}
+	}
+	if p.Detail == "" {
+		p.Detail = vm.Description
+	}
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.Details == nil {
+		p.Details = vm.Details
+	}
+	p.IncidentID = vm.IncidentID
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		// Only strings and a string map: cannot happen, but never write half a body.
+		h.errLog.Log(r, "problem details encode failed", err)
+		http.Error(w, http.StatusText(vm.Status), vm.Status)
+		return
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(vm.Status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// problemType returns the type URI for status.
+func (h *Handler) problemType(status int) string {
+	if h.problemTypeBase == "" {
+		return "about:blank"
+	}
+	return strings.TrimSuffix(h.problemTypeBase, "/") + "/" + errorCode(status)
+}
@@ -0,0 +1,153 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestProblemDetails_RoundTrip(t *testing.T) {
+	in := ProblemDetails{
+		Type:       "https://example.com/problems/bad_request",
+		Title:      "Bad Request",
+		Status:     http.StatusBadRequest,
+		Detail:     "The form has errors.",
+		Instance:   "/api/users",
+		Details:    map[string]string{"email": "is required"},
+		IncidentID: "abc123",
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var out ProblemDetails
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	// Optional members are omitted; the required ones are always present.
+	b, _ = json.Marshal(ProblemDetails{Type: "about:blank", Title: "Not Found", Status: 404})
+	if got, want := string(b), `{"type":"about:blank","title":"Not Found","status":404}`; got != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
+
+// decodeProblem checks rec is a problem+json response and decodes it.
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) ProblemDetails {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("Content-Type = %q, want %q", ct, ProblemContentType)
+	}
+	var p ProblemDetails
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return p
+}
+
+func TestNotFound_ProblemDetails(t *testing.T) {
+	h := NewHandler(WithProblemDetails(true))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/widgets/7?x=1", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	rec := httptest.NewRecorder()
+	h.NotFound(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	want := ProblemDetails{Type: "about:blank", Title: "Not Found", Status: 404, Instance: "/api/widgets/7"}
+	if got := decodeProblem(t, rec); !reflect.DeepEqual(got, want) {
+		t.Errorf("problem = %+v, want %+v", got, want)
+	}
+}
+
+func TestBadRequestWithDetails_ProblemDetails(t *testing.T) {
+	h := NewHandler(WithProblemDetails(true), WithProblemTypeBase("https://example.com/problems/"))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.BadRequestWithDetails(rec, req, map[string]string{"email": "is required"})
+
+	p := decodeProblem(t, rec)
+	if p.Type != "https://example.com/problems/bad_request" || p.Status != http.StatusBadRequest {
+		t.Errorf("problem = %+v, want type .../bad_request and status 400", p)
+	}
+	if p.Details["email"] != "is required" {
+		t.Errorf("details = %v, want email: is required", p.Details)
+	}
+}
+
+func TestInternalError_ProblemDetails_IncidentID(t *testing.T) {
+	h := NewHandler(WithProblemDetails(true))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/broken", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.InternalError(rec, req)
+
+	p := decodeProblem(t, rec)
+	if p.IncidentID == "" || p.IncidentID != rec.Header().Get("X-Request-ID") {
+		t.Errorf("incident_id = %q, want the X-Request-ID %q", p.IncidentID, rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestProblem_EnrichesDetailAndInstance(t *testing.T) {
+	h := NewHandler(WithProblemDetails(true))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/widgets/7", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.Problem(rec, req, ProblemDetails{
+		Status:   http.StatusConflict,
+		Detail:   "Widget 7 is in use.",
+		Instance: "/incidents/42",
+	})
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	want := ProblemDetails{Type: "about:blank", Title: "Conflict", Status: 409, Detail: "Widget 7 is in use.", Instance: "/incidents/42"}
+	if got := decodeProblem(t, rec); !reflect.DeepEqual(got, want) {
+		t.Errorf("problem = %+v, want %+v", got, want)
+	}
+}
+
+func TestProblem_WithoutProblemDetails(t *testing.T) {
+	h := NewHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/widgets/7", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.Problem(rec, req, ProblemDetails{Status: http.StatusNotFound, Detail: "No widget has ID 7."})
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error != "not_found" || resp.Status != http.StatusNotFound {
+		t.Errorf("response = %+v, want {Error:not_found Status:404}", resp)
+	}
+}
+
+func TestProblemDetails_HTMLUnchanged(t *testing.T) {
+	h := NewHandler(WithProblemDetails(true), WithPlainText())
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	h.NotFound(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct == ProblemContentType {
+		t.Errorf("Content-Type = %q for an HTML client", ct)
+	}
+}
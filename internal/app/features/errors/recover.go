@@ -0,0 +1,66 @@
+// internal/app/features/errors/recover.go
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"go.uber.org/zap"
+)
+
+// Recover is middleware that recovers from panics in downstream handlers,
+// logs the panic value and stack trace through the error logger, and renders
+// the 500 page, showing the panic and stack there only when WithDebug is on.
+// WithOnPanic replaces the 500 page with another response.
+//
+// A response is only written if the downstream handler has not already sent
+// headers. Once it has, a clean 500 is impossible, and finishing the partial
+// response would pass it off as complete, so the panic is logged and
+// http.ErrAbortHandler is panicked instead: net/http then drops the
+// connection, and the client sees a truncated response rather than a
+// corrupt one. An http.ErrAbortHandler from the handler itself is
+// re-panicked the same way.
+func (h *Handler) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := httpx.Wrap(w)
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			// Give the log line and the 500 page the same incident ID.
+			r, _ := withIncidentID(r)
+
+			err, ok := rec.(error)
+			if !ok {
+				err = fmt.Errorf("panic: %v", rec)
+			}
+			stack := debug.Stack()
+			h.errLog.LogWithFields(r, "panic recovered", err,
+				zap.ByteString("stack", stack),
+			)
+
+			if ww.Written() {
+				h.errLog.LogWithFields(r, "panic occurred after headers written; aborting connection", nil,
+					zap.Int("status_already_sent", ww.Status()),
+					zap.Int("bytes_already_sent", ww.BytesWritten()),
+				)
+				panic(http.ErrAbortHandler)
+			}
+			if h.onPanic != nil {
+				h.onPanic(ww, r, err)
+				return
+			}
+			h.internalError(ww, r, err, string(stack))
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,211 @@
+package errors
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/dalemusser/strataforge/internal/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRecover_PanicRendersInternalError(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	h := NewHandler(WithErrorLogger(NewErrorLogger(zap.New(core))))
+
+	panicky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Recover(panicky).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	entries := logs.FilterMessage("panic recovered").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 panic log entry, got %d", len(entries))
+	}
+	if _, ok := entries[0].ContextMap()["stack"]; !ok {
+		t.Error("expected stack field in panic log entry")
+	}
+}
+
+func TestRecover_PanicAfterWriteAborts(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	h := NewHandler(WithErrorLogger(NewErrorLogger(zap.New(core))))
+
+	panicky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	rec := httptest.NewRecorder()
+
+	func() {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+			}
+		}()
+		h.Recover(panicky).ServeHTTP(rec, req)
+	}()
+
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "partial" {
+		t.Errorf("response = %d %q, want the partial response left as is", rec.Code, rec.Body.String())
+	}
+	if logs.FilterMessage("panic recovered").Len() != 1 {
+		t.Error("expected panic to be logged")
+	}
+	entries := logs.FilterMessage("panic occurred after headers written; aborting connection").All()
+	if len(entries) != 1 || entries[0].ContextMap()["bytes_already_sent"] != int64(len("partial")) {
+		t.Errorf("abort log entries = %v, want one with bytes_already_sent", entries)
+	}
+}
+
+// TestRecover_OverTheWire checks what a client actually receives for panics
+// before and after the response has started.
+func TestRecover_OverTheWire(t *testing.T) {
+	h := NewHandler(WithPlainText())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/before", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/after", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		http.NewResponseController(w).Flush()
+		panic("boom")
+	})
+	srv := httptest.NewUnstartedServer(h.Recover(mux))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/before")
+	if err != nil {
+		t.Fatalf("GET /before: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("GET /before status = %d, want %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+
+	resp, err = http.Get(srv.URL + "/after")
+	if err != nil {
+		t.Fatalf("GET /after: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, err := io.ReadAll(resp.Body); err == nil {
+		t.Errorf("GET /after read %q cleanly, want the connection aborted mid-body", body)
+	}
+}
+
+func TestRecover_OnPanic(t *testing.T) {
+	var got error
+	h := NewHandler(WithOnPanic(func(w http.ResponseWriter, r *http.Request, err error) {
+		got = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	panicky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	rec := httptest.NewRecorder()
+
+	h.Recover(panicky).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the callback's %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got == nil || got.Error() != "panic: boom" {
+		t.Errorf("callback err = %v, want the panic value", got)
+	}
+}
+
+func TestRecover_NoPanicPassesThrough(t *testing.T) {
+	h := NewHandler()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	h.Recover(ok).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func TestRecover_RepanicsAbortHandler(t *testing.T) {
+	h := NewHandler()
+
+	aborting := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", rec)
+		}
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	h.Recover(aborting).ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestRecover_DebugShowsPanic(t *testing.T) {
+	testutil.MustBootTemplates(t)
+	h := NewHandler(WithDebug(true))
+
+	panicky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	req := testutil.WithCSRFToken(httptest.NewRequest(http.MethodGet, "/panic", nil))
+	rec := httptest.NewRecorder()
+
+	h.Recover(panicky).ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); !strings.Contains(body, "panic: boom") || !strings.Contains(body, "goroutine") {
+		t.Errorf("debug 500 page does not show the panic and stack:\n%s", body)
+	}
+}
+
+func TestRender_SkipsStartedResponse(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	h := NewHandler(WithErrorLogger(NewErrorLogger(zap.New(core))))
+
+	handler := httpx.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		h.NotFound(w, r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/started", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Body.String(); got != "partial" {
+		t.Errorf("body = %q, want %q", got, "partial")
+	}
+	if logs.FilterMessage("error response after response started; not written").Len() != 1 {
+		t.Error("expected the skipped error response to be logged")
+	}
+}
@@ -0,0 +1,127 @@
+// internal/app/features/errors/route.go
+package errors
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// routeContextKey is the type of RouteContextKey.
+type routeContextKey struct{}
+
+// RouteContextKey is the context key under which the matched route pattern
+// (e.g. "/users/{id}") is stored. Prefer WithRoute over setting it directly.
+var RouteContextKey = routeContextKey{}
+
+// WithRoute returns a copy of ctx carrying the matched route pattern.
+// ErrorLogger adds it to every log line as the "route" field.
+func WithRoute(ctx context.Context, pattern string) context.Context {
+	return context.WithValue(ctx, RouteContextKey, pattern)
+}
+
+// RouteFromContext returns the route pattern stored by WithRoute, or "".
+func RouteFromContext(ctx context.Context) string {
+	pattern, _ := ctx.Value(RouteContextKey).(string)
+	return pattern
+}
+
+// routePattern returns the route pattern for r. A pattern stored with
+// WithRoute wins; otherwise the pattern chi matched is used, if any.
+func routePattern(r *http.Request) string {
+	if pattern := RouteFromContext(r.Context()); pattern != "" {
+		return pattern
+	}
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// RouteMatcher reports whether a router has a route for method and path.
+// *chi.Mux satisfies it.
+type RouteMatcher interface {
+	Match(rctx *chi.Context, method, path string) bool
+}
+
+// routeMatcherContextKey is the context key for the RouteMatcher stored by
+// RouteMatcherMiddleware.
+type routeMatcherContextKey struct{}
+
+// WithRouteMatcher returns a copy of ctx carrying m, which Dispatch uses to
+// find the methods a path accepts.
+func WithRouteMatcher(ctx context.Context, m RouteMatcher) context.Context {
+	return context.WithValue(ctx, routeMatcherContextKey{}, m)
+}
+
+// RouteMatcherFromContext returns the RouteMatcher stored by
+// WithRouteMatcher, or nil.
+func RouteMatcherFromContext(ctx context.Context) RouteMatcher {
+	m, _ := ctx.Value(routeMatcherContextKey{}).(RouteMatcher)
+	return m
+}
+
+// RouteMatcherMiddleware stores m in every request's context. Install it on
+// the root router with the router itself, so routes mounted anywhere below
+// are found:
+//
+//	r := chi.NewRouter()
+//	r.Use(errors.RouteMatcherMiddleware(r))
+func RouteMatcherMiddleware(m RouteMatcher) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithRouteMatcher(r.Context(), m)))
+		})
+	}
+}
+
+// probeMethods are the methods AllowedMethods tries, in Allow header order.
+var probeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// AllowedMethods returns the methods the RouteMatcher in r's context has
+// routes for at r's path, or nil when there is no matcher or no route.
+func AllowedMethods(r *http.Request) []string {
+	m := RouteMatcherFromContext(r.Context())
+	if m == nil {
+		return nil
+	}
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+	var allowed []string
+	for _, method := range probeMethods {
+		if m.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// Dispatch answers a request no route handled: 405 with an Allow header
+// when the path has routes for other methods, 404 otherwise. allowedMethods
+// are the methods the path accepts, if the caller knows them; when empty
+// they are looked up with AllowedMethods. Register it for both cases:
+//
+//	r.NotFound(h.DispatchHandler().ServeHTTP)
+//	r.MethodNotAllowed(h.DispatchHandler().ServeHTTP)
+func (h *Handler) Dispatch(w http.ResponseWriter, r *http.Request, allowedMethods []string) {
+	if len(allowedMethods) == 0 {
+		allowedMethods = AllowedMethods(r)
+	}
+	if len(allowedMethods) == 0 || slices.Contains(allowedMethods, r.Method) {
+		h.NotFound(w, r)
+		return
+	}
+	h.MethodNotAllowed(w, r, allowedMethods...)
+}
@@ -0,0 +1,127 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestErrorLogger_IncludesRouteFromContext(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req = req.WithContext(WithRoute(req.Context(), "/users/{id}"))
+	errLog.Log(req, "test error", nil)
+
+	if got := logs.All()[0].ContextMap()["route"]; got != "/users/{id}" {
+		t.Errorf("route = %v, want %q", got, "/users/{id}")
+	}
+}
+
+func TestErrorLogger_IncludesChiRoutePattern(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	r := chi.NewRouter()
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		errLog.Log(r, "test error", nil)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	if got := logs.All()[0].ContextMap()["route"]; got != "/users/{id}" {
+		t.Errorf("route = %v, want %q", got, "/users/{id}")
+	}
+}
+
+func TestErrorLogger_OmitsRouteWhenUnknown(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	errLog := NewErrorLogger(zap.New(core))
+
+	errLog.Log(httptest.NewRequest(http.MethodGet, "/test", nil), "test error", nil)
+
+	if _, ok := logs.All()[0].ContextMap()["route"]; ok {
+		t.Error("expected no route field")
+	}
+}
+
+// newDispatchRouter builds a router with a mounted subrouter and Dispatch
+// as its not-found and method-not-allowed handler.
+func newDispatchRouter() http.Handler {
+	h := NewHandler()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Use(RouteMatcherMiddleware(r))
+	r.Get("/items", ok)
+	r.Post("/items", ok)
+	sub := chi.NewRouter()
+	sub.Delete("/{id}", ok)
+	r.Mount("/admin", sub)
+	r.NotFound(h.DispatchHandler().ServeHTTP)
+	r.MethodNotAllowed(h.DispatchHandler().ServeHTTP)
+	return r
+}
+
+func TestDispatch(t *testing.T) {
+	router := newDispatchRouter()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantAllow  string
+	}{
+		{"wrong method", http.MethodDelete, "/items", http.StatusMethodNotAllowed, "GET, POST"},
+		{"wrong method in mounted router", http.MethodGet, "/admin/7", http.StatusMethodNotAllowed, "DELETE"},
+		{"unknown path", http.MethodGet, "/nope", http.StatusNotFound, ""},
+		{"unknown path in mounted router", http.MethodGet, "/admin/7/extra", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestDispatch_UsesGivenMethods(t *testing.T) {
+	h := NewHandler()
+	req := httptest.NewRequest(http.MethodPut, "/anything", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Dispatch(rec, req, []string{http.MethodGet})
+
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET" {
+		t.Errorf("status = %d, Allow = %q; want 405 and %q", rec.Code, rec.Header().Get("Allow"), "GET")
+	}
+}
+
+func TestDispatch_NoMatcherIsNotFound(t *testing.T) {
+	h := NewHandler()
+	req := httptest.NewRequest(http.MethodPut, "/anything", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	h.Dispatch(rec, req, nil)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
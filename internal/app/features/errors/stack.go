@@ -0,0 +1,29 @@
+// internal/app/features/errors/stack.go
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth limits how many frames captureStack records.
+const maxStackDepth = 32
+
+// captureStack returns a formatted stack trace of the caller, skipping the
+// given number of frames above captureStack itself.
+func captureStack(skip int) string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
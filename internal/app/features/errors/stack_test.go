@@ -0,0 +1,17 @@
+package errors
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaptureStack_IncludesCaller(t *testing.T) {
+	stack := captureStack(0)
+
+	if !strings.Contains(stack, "TestCaptureStack_IncludesCaller") {
+		t.Errorf("expected stack to include the calling test, got:\n%s", stack)
+	}
+	if strings.Contains(stack, "captureStack") {
+		t.Errorf("expected captureStack itself to be skipped, got:\n%s", stack)
+	}
+}
@@ -0,0 +1,90 @@
+// internal/app/features/errors/suggest.go
+package errors
+
+import (
+	"sort"
+	"strings"
+)
+
+// routeSet holds the route patterns used for "did you mean" hints.
+type routeSet struct {
+	patterns []string
+}
+
+// WithRoutes sets the route patterns the 404 page suggests from when debug
+// is on. Routes usually are not known until the router is built; use
+// SetRoutes then.
+func WithRoutes(patterns ...string) Option {
+	return func(h *Handler) {
+		h.SetRoutes(patterns)
+	}
+}
+
+// SetRoutes replaces the route patterns the 404 page suggests from. Trailing
+// "/*" wildcards are dropped and duplicates removed. It is safe to call while
+// requests are being served.
+//
+// Suggestions are only shown with WithDebug, since they reveal the route map.
+func (h *Handler) SetRoutes(patterns []string) {
+	seen := make(map[string]struct{}, len(patterns))
+	set := &routeSet{}
+	for _, p := range patterns {
+		p = strings.TrimSuffix(p, "/*")
+		if p == "" || p == "*" {
+			continue
+		}
+		if _, dup := seen[p]; dup {
+			continue
+		}
+		seen[p] = struct{}{}
+		set.patterns = append(set.patterns, p)
+	}
+	sort.Strings(set.patterns)
+	h.routes.Store(set)
+}
+
+// suggestRoute returns the known route pattern closest to path by edit
+// distance, or "" when debug is off, no routes are known, or nothing is
+// close enough to be a plausible typo.
+func (h *Handler) suggestRoute(path string) string {
+	if !h.debug {
+		return ""
+	}
+	set := h.routes.Load()
+	if set == nil {
+		return ""
+	}
+
+	path = strings.ToLower(path)
+	// Allow roughly one typo per three characters, and always at least two.
+	limit := max(2, len(path)/3)
+	best, bestDist := "", limit+1
+	for _, p := range set.patterns {
+		d := levenshtein(path, strings.ToLower(p))
+		if d > 0 && d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b, counting bytes.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,85 @@
+package errors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/testutil"
+)
+
+func TestSuggestRoute(t *testing.T) {
+	h := NewHandler(WithDebug(true), WithRoutes("/users", "/users/{id}", "/settings", "/assets/*", "/"))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/uesrs", "/users"},
+		{"/setings", "/settings"},
+		{"/Settings/", "/settings"},
+		{"/asset", "/assets"},
+		{"/users", ""}, // exact match: nothing to suggest
+		{"/completely/unrelated/path", ""},
+	}
+	for _, tt := range tests {
+		if got := h.suggestRoute(tt.path); got != tt.want {
+			t.Errorf("suggestRoute(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestRoute_OffWithoutDebug(t *testing.T) {
+	h := NewHandler(WithRoutes("/users"))
+	if got := h.suggestRoute("/uesrs"); got != "" {
+		t.Errorf("suggestRoute without debug = %q, want none", got)
+	}
+}
+
+func TestNotFound_ShowsSuggestion(t *testing.T) {
+	testutil.MustBootTemplates(t)
+
+	tests := []struct {
+		name  string
+		debug bool
+		want  bool
+	}{
+		{"debug off", false, false},
+		{"debug on", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(WithDebug(tt.debug))
+			h.SetRoutes([]string{"/settings"})
+			req := testutil.WithCSRFToken(httptest.NewRequest(http.MethodGet, "/setings", nil))
+			rec := httptest.NewRecorder()
+
+			h.NotFound(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+			}
+			if got := strings.Contains(rec.Body.String(), "Did you mean"); got != tt.want {
+				t.Errorf("page shows a suggestion = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"/users", "/uesrs", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
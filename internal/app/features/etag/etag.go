@@ -0,0 +1,205 @@
+// internal/app/features/etag/etag.go
+//
+// Package etag adds strong ETags to rendered pages and answers conditional
+// requests with 304 Not Modified.
+//
+// Middleware buffers successful GET and HEAD responses, hashes the body into
+// an ETag, and sends 304 with no body when the request's If-None-Match
+// already names that tag. Only 200 responses are tagged: error pages and
+// other statuses are written straight through, so the error handlers keep
+// full control of their responses.
+//
+// A handler opts out by calling Skip before writing (for example, for
+// per-request pages where a tag would never match), by setting its own
+// ETag header, or by sending Cache-Control: no-store. Responses that grow
+// past the buffer limit or are flushed part way through are streamed
+// without a tag. Upgrade requests (WebSocket) are passed straight through.
+package etag
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxSize is the largest body that is buffered and tagged.
+const DefaultMaxSize = 1 << 20
+
+// config holds the settings built up by Options.
+type config struct {
+	maxSize int
+}
+
+// Option configures the ETag middleware.
+type Option func(*config)
+
+// WithMaxSize sets the largest body, in bytes, that is buffered and tagged.
+func WithMaxSize(n int) Option {
+	return func(c *config) {
+		c.maxSize = n
+	}
+}
+
+// ctxKey is the context key for the per-request state Skip updates.
+type ctxKey struct{}
+
+// state is shared between the middleware and Skip.
+type state struct {
+	skip bool
+}
+
+// Skip opts the current request out of ETag handling. Call it before
+// writing the response; it has no effect outside Middleware.
+func Skip(r *http.Request) {
+	if st, ok := r.Context().Value(ctxKey{}).(*state); ok {
+		st.skip = true
+	}
+}
+
+// Middleware tags successful GET and HEAD responses with a strong ETag and
+// answers matching If-None-Match requests with 304.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{maxSize: DefaultMaxSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			st := &state{}
+			r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, st))
+			ew := &etagWriter{ResponseWriter: w, cfg: &cfg, st: st}
+			next.ServeHTTP(ew, r)
+			ew.finish(r)
+		})
+	}
+}
+
+// etagWriter buffers a 200 response until the handler returns, or passes
+// the response through untouched once it is ineligible.
+type etagWriter struct {
+	http.ResponseWriter
+	cfg *config
+	st  *state
+
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.passthrough {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if ew.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+	ew.status = code
+	if code != http.StatusOK || !ew.taggable() {
+		ew.startPassthrough()
+	}
+}
+
+func (ew *etagWriter) Write(p []byte) (int, error) {
+	if ew.status == 0 {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if !ew.passthrough && (!ew.taggable() || ew.buf.Len()+len(p) > ew.cfg.maxSize) {
+		ew.startPassthrough()
+	}
+	if ew.passthrough {
+		return ew.ResponseWriter.Write(p)
+	}
+	return ew.buf.Write(p)
+}
+
+// Flush streams the response without a tag from this point on.
+func (ew *etagWriter) Flush() {
+	if !ew.passthrough {
+		if ew.status == 0 {
+			ew.status = http.StatusOK
+		}
+		ew.startPassthrough()
+	}
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController.
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// taggable reports whether the response may still be tagged.
+func (ew *etagWriter) taggable() bool {
+	h := ew.Header()
+	return !ew.st.skip &&
+		h.Get("ETag") == "" &&
+		!strings.Contains(strings.ToLower(h.Get("Cache-Control")), "no-store")
+}
+
+// startPassthrough sends the header and anything buffered, untagged.
+func (ew *etagWriter) startPassthrough() {
+	ew.passthrough = true
+	ew.ResponseWriter.WriteHeader(ew.status)
+	if ew.buf.Len() > 0 {
+		ew.ResponseWriter.Write(ew.buf.Bytes())
+		ew.buf.Reset()
+	}
+}
+
+// finish tags the buffered response and sends either it or a 304.
+func (ew *etagWriter) finish(r *http.Request) {
+	if ew.passthrough || ew.status == 0 {
+		return
+	}
+	if !ew.taggable() {
+		ew.startPassthrough()
+		return
+	}
+
+	sum := sha256.Sum256(ew.buf.Bytes())
+	tag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	h := ew.Header()
+	h.Set("ETag", tag)
+
+	if matches(r.Header.Get("If-None-Match"), tag) {
+		for _, k := range []string{"Content-Type", "Content-Length", "Content-Encoding"} {
+			h.Del(k)
+		}
+		ew.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+	ew.ResponseWriter.WriteHeader(http.StatusOK)
+	ew.ResponseWriter.Write(ew.buf.Bytes())
+}
+
+// matches reports whether an If-None-Match header names tag, using the weak
+// comparison RFC 9110 specifies for If-None-Match, so a W/ prefix added by
+// compression still matches.
+func matches(ifNoneMatch, tag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package etag
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func page(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, body)
+	}
+}
+
+func get(h http.Handler, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_200Then304(t *testing.T) {
+	h := Middleware()(page("<h1>Dashboard</h1>"))
+
+	first := get(h, "")
+	if first.Code != http.StatusOK {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusOK)
+	}
+	tag := first.Header().Get("ETag")
+	if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		t.Fatalf("ETag = %q, want a strong quoted tag", tag)
+	}
+	if first.Body.String() != "<h1>Dashboard</h1>" {
+		t.Errorf("first body = %q", first.Body.String())
+	}
+
+	second := get(h, tag)
+	if second.Code != http.StatusNotModified {
+		t.Fatalf("second status = %d, want %d", second.Code, http.StatusNotModified)
+	}
+	if second.Body.Len() != 0 {
+		t.Errorf("304 body = %q, want empty", second.Body.String())
+	}
+	if second.Header().Get("ETag") != tag {
+		t.Errorf("304 ETag = %q, want %q", second.Header().Get("ETag"), tag)
+	}
+	if second.Header().Get("Content-Type") != "" {
+		t.Error("304 should not carry Content-Type")
+	}
+
+	// A weak form of the tag, as sent back after a compressed response, still matches.
+	if rec := get(h, "W/"+tag); rec.Code != http.StatusNotModified {
+		t.Errorf("weak If-None-Match status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	changed := get(Middleware()(page("<h1>Dashboard v2</h1>")), tag)
+	if changed.Code != http.StatusOK || changed.Header().Get("ETag") == tag {
+		t.Errorf("changed page status = %d, ETag = %q; want 200 with a new tag", changed.Code, changed.Header().Get("ETag"))
+	}
+}
+
+func TestMiddleware_LeavesErrorStatusesAlone(t *testing.T) {
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, "not found page")
+	}))
+
+	first := get(h, "")
+	if first.Code != http.StatusNotFound || first.Header().Get("ETag") != "" {
+		t.Errorf("status = %d, ETag = %q; want 404 without a tag", first.Code, first.Header().Get("ETag"))
+	}
+	if rec := get(h, "*"); rec.Code != http.StatusNotFound {
+		t.Errorf("If-None-Match: * status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestMiddleware_OptOuts(t *testing.T) {
+	tests := map[string]http.HandlerFunc{
+		"skip": func(w http.ResponseWriter, r *http.Request) {
+			Skip(r)
+			io.WriteString(w, "per-request page")
+		},
+		"no-store": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "private, no-store")
+			io.WriteString(w, "account page")
+		},
+		"flushed": func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "streamed")
+			w.(http.Flusher).Flush()
+			io.WriteString(w, " page")
+		},
+		"too large": func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, strings.Repeat("x", 64))
+		},
+	}
+	for name, handler := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := get(Middleware(WithMaxSize(32))(handler), "*")
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if rec.Header().Get("ETag") != "" {
+				t.Errorf("ETag = %q, want none", rec.Header().Get("ETag"))
+			}
+			if rec.Body.Len() == 0 {
+				t.Error("body should be passed through")
+			}
+		})
+	}
+}
+
+func TestMiddleware_HandlerETagIsKept(t *testing.T) {
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, "asset")
+	}))
+	rec := get(h, `"v1"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"v1"` {
+		t.Errorf("status = %d, ETag = %q; want the handler's own response", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestMiddleware_IgnoresUnsafeMethods(t *testing.T) {
+	h := Middleware()(page("saved"))
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("If-None-Match", "*")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != "" {
+		t.Errorf("POST status = %d, ETag = %q; want 200 without a tag", rec.Code, rec.Header().Get("ETag"))
+	}
+}
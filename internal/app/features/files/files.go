@@ -3,6 +3,7 @@ package files
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"github.com/dalemusser/strataforge/internal/app/store/folder"
 	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
 	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/formutil"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
 	"github.com/dalemusser/waffle/pantry/storage"
 	"github.com/dalemusser/waffle/pantry/templates"
@@ -787,12 +789,17 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	actor, _ := auth.CurrentUser(r)
 
-	// Parse multipart form
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+	// Parse multipart form; Close removes any temporary files
+	form, err := formutil.Parse(r, maxUploadSize)
+	if err != nil {
 		h.errLog.Log(r, "failed to parse multipart form", err)
+		msg := "Invalid upload. Please try again."
+		if errors.Is(err, formutil.ErrTooLarge) {
+			msg = "File too large (max 32MB)"
+		}
 		vm := FileUploadVM{
 			BaseVM:  viewdata.New(r),
-			Error:   "File too large (max 32MB)",
+			Error:   msg,
 			MaxSize: "32 MB",
 		}
 		vm.Title = "Upload File"
@@ -800,9 +807,10 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request) {
 		templates.Render(w, r, "files/file_upload", vm)
 		return
 	}
+	defer form.Close()
 
 	// Get folder ID
-	folderIDStr := r.FormValue("folder_id")
+	folderIDStr := form.String("folder_id")
 	var folderID *primitive.ObjectID
 	if folderIDStr != "" {
 		id, err := primitive.ObjectIDFromHex(folderIDStr)
@@ -812,7 +820,7 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get uploaded file
-	uploadedFile, header, err := r.FormFile("file")
+	uploadedFile, header, err := form.File("file")
 	if err != nil {
 		vm := FileUploadVM{
 			BaseVM:     viewdata.New(r),
@@ -827,7 +835,7 @@ func (h *Handler) upload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer uploadedFile.Close()
 
-	description := strings.TrimSpace(r.FormValue("description"))
+	description := form.String("description")
 
 	// Generate storage path: files/YYYY/MM/uuid-filename
 	now := time.Now().UTC()
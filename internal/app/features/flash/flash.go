@@ -0,0 +1,161 @@
+// internal/app/features/flash/flash.go
+//
+// Package flash provides one-time messages for post/redirect/get flows.
+//
+// A handler calls Set before redirecting; the next page calls Get to read the
+// messages, which also clears them. When the session middleware is installed,
+// messages are kept in the session; otherwise they travel in a signed cookie
+// of their own. Either way they survive the redirect.
+//
+// Usage:
+//
+//	flash.Set(w, r, flash.LevelSuccess, "Saved!")
+//	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+//
+//	// on the next request
+//	vm.Flash = flash.Get(w, r)
+package flash
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/dalemusser/strataforge/internal/app/features/session"
+	"github.com/dalemusser/strataforge/internal/app/system/securecookie"
+)
+
+// CookieName is the name of the cookie that carries pending flash messages.
+const CookieName = "strataforge-flash"
+
+// sessionKey is the session value that holds pending flash messages as JSON.
+const sessionKey = "_flash"
+
+// Level is the severity of a flash message. Templates use it to pick a style.
+type Level string
+
+const (
+	LevelSuccess Level = "success"
+	LevelInfo    Level = "info"
+	LevelWarning Level = "warning"
+	LevelError   Level = "error"
+)
+
+// Message is a single flash message.
+type Message struct {
+	Level Level  `json:"level"`
+	Text  string `json:"text"`
+}
+
+// codec signs and verifies the flash cookie; set by Init.
+var codec *securecookie.Codec
+
+// secureCookie sets the Secure flag on the flash cookie; set by Init.
+var secureCookie bool
+
+// Init configures the signing key for flash cookies. secure sets the
+// cookie's Secure flag and should be true when serving over HTTPS.
+// Call it once at startup; until then, or if hashKey is empty, requests
+// without a session get no flash messages.
+func Init(hashKey []byte, secure bool) {
+	codec, _ = securecookie.New([]securecookie.Key{{Hash: hashKey}})
+	secureCookie = secure
+}
+
+// Set queues a flash message to be shown on the next page that calls Get.
+// Multiple calls, in the same request or across requests, accumulate until
+// read. Cookies are limited to about 4KB, so keep messages short.
+func Set(w http.ResponseWriter, r *http.Request, level Level, text string) {
+	if sess := session.FromRequest(r); sess != nil {
+		msgs := append(fromSession(sess), Message{Level: level, Text: text})
+		if raw, err := json.Marshal(msgs); err == nil {
+			sess.Set(sessionKey, string(raw))
+		}
+		return
+	}
+	if codec == nil {
+		return
+	}
+	msgs := append(pending(w, r), Message{Level: level, Text: text})
+	encoded, err := codec.Encode(CookieName, msgs)
+	if err != nil {
+		return
+	}
+	writeCookie(w, encoded, 0)
+}
+
+// Get returns any pending flash messages and clears them. It returns nil
+// when there are none or the cookie fails verification.
+func Get(w http.ResponseWriter, r *http.Request) []Message {
+	if sess := session.FromRequest(r); sess != nil {
+		msgs := fromSession(sess)
+		sess.Delete(sessionKey)
+		return msgs
+	}
+	if codec == nil {
+		return nil
+	}
+	msgs := pending(w, r)
+	if _, err := r.Cookie(CookieName); err == nil || len(msgs) > 0 {
+		writeCookie(w, "", -1)
+	}
+	return msgs
+}
+
+// pending returns the messages already queued: any set earlier in this
+// response take precedence over those carried in by the request cookie.
+func pending(w http.ResponseWriter, r *http.Request) []Message {
+	resp := http.Response{Header: w.Header()}
+	for _, c := range resp.Cookies() {
+		if c.Name == CookieName && c.MaxAge >= 0 {
+			return decode(c.Value)
+		}
+	}
+	if c, err := r.Cookie(CookieName); err == nil {
+		return decode(c.Value)
+	}
+	return nil
+}
+
+// fromSession returns the messages queued in sess.
+func fromSession(sess *session.Session) []Message {
+	raw := sess.GetString(sessionKey)
+	if raw == "" {
+		return nil
+	}
+	var msgs []Message
+	if err := json.Unmarshal([]byte(raw), &msgs); err != nil {
+		return nil
+	}
+	return msgs
+}
+
+// decode verifies and decodes a flash cookie value.
+func decode(value string) []Message {
+	var msgs []Message
+	if err := codec.Decode(CookieName, value, &msgs); err != nil {
+		return nil
+	}
+	return msgs
+}
+
+// writeCookie replaces any flash cookie already set on w with one holding
+// value. A negative maxAge deletes the cookie.
+func writeCookie(w http.ResponseWriter, value string, maxAge int) {
+	var kept []string
+	for _, line := range w.Header().Values("Set-Cookie") {
+		if !strings.HasPrefix(line, CookieName+"=") {
+			kept = append(kept, line)
+		}
+	}
+	w.Header()["Set-Cookie"] = kept
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   secureCookie,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,106 @@
+package flash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/features/session"
+)
+
+func init() {
+	Init([]byte("test-flash-key-0123456789abcdef0123"), false)
+}
+
+// carryCookies returns a request to the next page carrying the cookies set on rec.
+func carryCookies(rec *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/next", nil)
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			req.AddCookie(c)
+		}
+	}
+	return req
+}
+
+func TestSetThenGet(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Set(rec, httptest.NewRequest(http.MethodPost, "/save", nil), LevelSuccess, "Saved!")
+
+	next := httptest.NewRecorder()
+	msgs := Get(next, carryCookies(rec))
+
+	if len(msgs) != 1 || msgs[0].Level != LevelSuccess || msgs[0].Text != "Saved!" {
+		t.Fatalf("Get() = %+v, want one success message", msgs)
+	}
+
+	cleared := false
+	for _, c := range next.Result().Cookies() {
+		if c.Name == CookieName && c.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("expected Get to clear the flash cookie")
+	}
+}
+
+func TestSet_Accumulates(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/save", nil)
+	http.SetCookie(rec, &http.Cookie{Name: "other", Value: "kept"})
+	Set(rec, req, LevelSuccess, "Saved!")
+	Set(rec, req, LevelWarning, "Check your email")
+
+	if n := len(rec.Header().Values("Set-Cookie")); n != 2 {
+		t.Errorf("Set-Cookie headers = %d, want 2 (other + flash)", n)
+	}
+
+	msgs := Get(httptest.NewRecorder(), carryCookies(rec))
+	if len(msgs) != 2 || msgs[1].Level != LevelWarning {
+		t.Errorf("Get() = %+v, want two messages in order", msgs)
+	}
+}
+
+func TestGet_RejectsTamperedCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/next", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: "forged"})
+
+	if msgs := Get(httptest.NewRecorder(), req); msgs != nil {
+		t.Errorf("Get() = %+v, want nil for a tampered cookie", msgs)
+	}
+}
+
+func TestGet_NoCookie(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if msgs := Get(rec, httptest.NewRequest(http.MethodGet, "/", nil)); msgs != nil {
+		t.Errorf("Get() = %+v, want nil", msgs)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("expected no cookie to be written")
+	}
+}
+
+func TestSetThenGet_UsesSession(t *testing.T) {
+	sess := session.New()
+	req := httptest.NewRequest(http.MethodPost, "/save", nil)
+	req = req.WithContext(session.WithSession(req.Context(), sess))
+
+	rec := httptest.NewRecorder()
+	Set(rec, req, LevelSuccess, "Saved!")
+
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("expected no flash cookie when a session is present")
+	}
+	if !sess.Modified() {
+		t.Error("expected the session to be marked modified")
+	}
+
+	msgs := Get(httptest.NewRecorder(), req)
+	if len(msgs) != 1 || msgs[0].Text != "Saved!" {
+		t.Fatalf("Get() = %+v, want one message", msgs)
+	}
+	if again := Get(httptest.NewRecorder(), req); again != nil {
+		t.Errorf("second Get() = %+v, want nil", again)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -13,26 +15,104 @@ import (
 	"go.uber.org/zap"
 )
 
+// checkTimeout bounds how long all checks may take for one request.
+const checkTimeout = 5 * time.Second
+
+// CheckFunc reports whether a dependency is healthy. It should return
+// promptly once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// namedCheck is a registered CheckFunc.
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
 // Handler provides health check endpoints.
 type Handler struct {
-	mongoClient *mongo.Client
-	logger      *zap.Logger
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	checks []namedCheck
+
+	draining atomic.Bool
 }
 
-// NewHandler creates a new health check Handler.
+// NewHandler creates a new health check Handler. When mongoClient is non-nil
+// a "mongodb" check that pings the primary is registered.
 func NewHandler(mongoClient *mongo.Client, logger *zap.Logger) *Handler {
-	return &Handler{
-		mongoClient: mongoClient,
-		logger:      logger,
+	h := &Handler{logger: logger}
+	if mongoClient != nil {
+		h.AddCheck("mongodb", func(ctx context.Context) error {
+			return mongoClient.Ping(ctx, readpref.Primary())
+		})
+	}
+	return h
+}
+
+// AddCheck registers a dependency check run by Check and Ready. Checks run
+// in registration order; adding a name twice replaces the earlier check.
+func (h *Handler) AddCheck(name string, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, c := range h.checks {
+		if c.name == name {
+			h.checks[i].fn = fn
+			return
+		}
+	}
+	h.checks = append(h.checks, namedCheck{name: name, fn: fn})
+}
+
+// Drain makes Ready answer 503 from now on, while Live keeps answering 200,
+// so load balancers stop routing new requests here before the server shuts
+// down. It cannot be undone; call it once shutdown has begun. See
+// server.WithDrain for wiring it to SIGTERM. The main server, which WAFFLE
+// runs, never calls it: WAFFLE shuts down as soon as the signal arrives, so
+// deployments use a preStop delay instead (see docs/deployment.md).
+func (h *Handler) Drain() {
+	if h.draining.CompareAndSwap(false, true) {
+		h.logger.Info("draining: readiness probe now failing")
 	}
 }
 
+// Draining reports whether Drain has been called.
+func (h *Handler) Draining() bool {
+	return h.draining.Load()
+}
+
 // Response represents the health check response.
 type Response struct {
 	Status   string            `json:"status"`
 	Services map[string]string `json:"services,omitempty"`
 }
 
+// ReadyResponse is the body returned by Ready when a check fails.
+type ReadyResponse struct {
+	Status string   `json:"status"`
+	Failed []string `json:"failed,omitempty"`
+}
+
+// runChecks runs every registered check and returns the names of those that
+// failed, logging each failure.
+func (h *Handler) runChecks(ctx context.Context, msg string) (failed []string, ran []string) {
+	h.mu.RLock()
+	checks := append([]namedCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	for _, c := range checks {
+		ran = append(ran, c.name)
+		if err := c.fn(ctx); err != nil {
+			failed = append(failed, c.name)
+			h.logger.Warn(msg, zap.String("check", c.name), zap.Error(err))
+		}
+	}
+	return failed, ran
+}
+
 // Routes returns a chi.Router with health check routes mounted.
 // Provides /health (full check), /health/ready, and /health/live.
 func Routes(h *Handler) http.Handler {
@@ -46,30 +126,29 @@ func Routes(h *Handler) http.Handler {
 // MountRootEndpoints adds /ready and /livez endpoints directly on the root router.
 // This is the standard convention for Kubernetes probes:
 //   - /ready (or /readyz) - readiness probe
-//   - /livez - liveness probe
+//   - /livez (or /healthz) - liveness probe
 func MountRootEndpoints(r chi.Router, h *Handler) {
 	r.Get("/ready", h.Ready)
 	r.Get("/readyz", h.Ready)
 	r.Get("/livez", h.Live)
+	r.Get("/healthz", h.Live)
 }
 
-// Check performs a full health check including database connectivity.
+// Check performs a full health check, reporting the status of every
+// registered check.
 func (h *Handler) Check(w http.ResponseWriter, r *http.Request) {
 	resp := Response{
 		Status:   "ok",
 		Services: make(map[string]string),
 	}
 
-	// Check MongoDB
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-
-	if err := h.mongoClient.Ping(ctx, readpref.Primary()); err != nil {
+	failed, ran := h.runChecks(r.Context(), "health check failed")
+	for _, name := range ran {
+		resp.Services[name] = "ok"
+	}
+	for _, name := range failed {
 		resp.Status = "degraded"
-		resp.Services["mongodb"] = "unavailable"
-		h.logger.Warn("health check: mongodb ping failed", zap.Error(err))
-	} else {
-		resp.Services["mongodb"] = "ok"
+		resp.Services[name] = "unavailable"
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -79,20 +158,24 @@ func (h *Handler) Check(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-// Ready checks if the service is ready to accept requests.
+// Ready checks if the service is ready to accept requests by running every
+// registered check. It returns 503 with the names of failed checks, or
+// 503 {"status":"draining"} without running them once Drain has been called.
 // Used by Kubernetes readiness probes.
 func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-
-	if err := h.mongoClient.Ping(ctx, readpref.Primary()); err != nil {
-		h.logger.Warn("readiness check failed", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json")
+	if h.Draining() {
 		w.WriteHeader(http.StatusServiceUnavailable)
-		w.Write([]byte(`{"status":"not ready"}`))
+		json.NewEncoder(w).Encode(ReadyResponse{Status: "draining"})
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	failed, _ := h.runChecks(r.Context(), "readiness check failed")
+	if len(failed) > 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(ReadyResponse{Status: "not ready", Failed: failed})
+		return
+	}
 	w.Write([]byte(`{"status":"ready"}`))
 }
 
@@ -1,7 +1,9 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -164,3 +166,104 @@ func TestResponse_JSON(t *testing.T) {
 		t.Errorf("cache status = %q, want %q", decoded.Services["cache"], "degraded")
 	}
 }
+
+func TestHandler_ReadyReportsFailedChecks(t *testing.T) {
+	h := NewHandler(nil, zap.NewNop())
+	h.AddCheck("cache", func(ctx context.Context) error { return nil })
+	h.AddCheck("queue", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+
+	h.Ready(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Ready() status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	var resp ReadyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Failed) != 1 || resp.Failed[0] != "queue" {
+		t.Errorf("failed = %v, want [queue]", resp.Failed)
+	}
+}
+
+func TestHandler_ReadyWithPassingChecks(t *testing.T) {
+	h := NewHandler(nil, zap.NewNop())
+	h.AddCheck("cache", func(ctx context.Context) error { return nil })
+
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	rec := httptest.NewRecorder()
+
+	h.Ready(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Ready() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); body != `{"status":"ready"}` {
+		t.Errorf("Ready() body = %q, want %q", body, `{"status":"ready"}`)
+	}
+}
+
+func TestHandler_AddCheckReplacesByName(t *testing.T) {
+	h := NewHandler(nil, zap.NewNop())
+	h.AddCheck("cache", func(ctx context.Context) error { return errors.New("down") })
+	h.AddCheck("cache", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	h.Check(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Check() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMountRootEndpoints_Healthz(t *testing.T) {
+	r := chi.NewRouter()
+	MountRootEndpoints(r, NewHandler(nil, zap.NewNop()))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestHandler_Drain(t *testing.T) {
+	h := NewHandler(nil, zap.NewNop())
+	ran := false
+	h.AddCheck("cache", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	h.Drain()
+	if !h.Draining() {
+		t.Fatal("Draining() = false after Drain()")
+	}
+
+	rec := httptest.NewRecorder()
+	h.Ready(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Ready() status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	var resp ReadyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "draining" {
+		t.Errorf("Ready() status field = %q, want %q", resp.Status, "draining")
+	}
+	if ran {
+		t.Error("checks ran while draining")
+	}
+
+	rec = httptest.NewRecorder()
+	h.Live(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Live() status while draining = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
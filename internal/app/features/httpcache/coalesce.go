@@ -0,0 +1,63 @@
+// internal/app/features/httpcache/coalesce.go
+package httpcache
+
+import (
+	"context"
+	"sync"
+)
+
+// flights tracks the misses being filled, so concurrent requests for the
+// same response wait for one handler run instead of each starting their own.
+type flights struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is one handler run that other requests are waiting on.
+type flight struct {
+	done    chan struct{}
+	resp    *Response // set before done is closed; nil if it cannot be shared
+	waiters int       // requests waiting, for tests
+}
+
+func newFlights() *flights {
+	return &flights{calls: make(map[string]*flight)}
+}
+
+// join returns the flight for id, and true if the caller is its leader and
+// must run the handler and then call finish.
+func (fs *flights) join(id string) (*flight, bool) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if f, ok := fs.calls[id]; ok {
+		f.waiters++
+		return f, false
+	}
+	f := &flight{done: make(chan struct{})}
+	fs.calls[id] = f
+	return f, true
+}
+
+// finish records the leader's response and releases the waiters. Requests
+// arriving afterwards start a new flight; by then the cache has the
+// response if it could be stored.
+func (fs *flights) finish(id string, f *flight, resp *Response) {
+	fs.mu.Lock()
+	delete(fs.calls, id)
+	fs.mu.Unlock()
+
+	f.resp = resp
+	close(f.done)
+}
+
+// wait blocks until the leader finishes and returns its response, or nil
+// when it could not be shared. It returns false if ctx ends first.
+func (f *flight) wait(ctx context.Context) (*Response, bool) {
+	select {
+	case <-f.done:
+		return f.resp, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
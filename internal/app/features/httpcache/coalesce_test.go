@@ -0,0 +1,167 @@
+package httpcache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// herd sends one request to h, waits until its handler has started, then
+// sends n more for the same URL and releases the handler once they are all
+// waiting on it. It returns every response, first request first.
+func herd(t *testing.T, c *Cache, h http.Handler, started, release chan struct{}, n int) []*httptest.ResponseRecorder {
+	t.Helper()
+	recs := make([]*httptest.ResponseRecorder, n+1)
+	var wg sync.WaitGroup
+	send := func(i int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { _ = recover() }() // a panicking leader
+			recs[i] = httptest.NewRecorder()
+			h.ServeHTTP(recs[i], httptest.NewRequest(http.MethodGet, "/reports", nil))
+		}()
+	}
+
+	send(0)
+	<-started
+	for i := 1; i <= n; i++ {
+		send(i)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for c.waiting() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests waiting, want %d", c.waiting(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	return recs
+}
+
+// waiting returns the number of requests waiting on any flight.
+func (c *Cache) waiting() int {
+	c.flights.mu.Lock()
+	defer c.flights.mu.Unlock()
+	n := 0
+	for _, f := range c.flights.calls {
+		n += f.waiters
+	}
+	return n
+}
+
+// blocking returns a handler that signals started on its first run and
+// then waits for release before answering with status.
+func blocking(calls *atomic.Int32, started, release chan struct{}, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("report"))
+	})
+}
+
+func TestWithCoalescing_SharesOneRun(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	c := New(time.Minute, WithCoalescing())
+	h := c.Middleware(blocking(&calls, started, release, http.StatusOK))
+
+	recs := herd(t, c, h, started, release, 5)
+
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+	for i, rec := range recs {
+		if rec.Code != http.StatusOK || rec.Body.String() != "report" {
+			t.Errorf("response %d = %d %q, want the shared page", i, rec.Code, rec.Body.String())
+		}
+	}
+	if recs[0].Header().Get(HeaderCache) != "MISS" || recs[1].Header().Get(HeaderCache) != "HIT" {
+		t.Errorf("X-Cache = %q for the leader and %q for a waiter, want MISS and HIT",
+			recs[0].Header().Get(HeaderCache), recs[1].Header().Get(HeaderCache))
+	}
+
+	// The shared run filled the cache.
+	if rec := get(h, "/reports"); rec.Header().Get(HeaderCache) != "HIT" || calls.Load() != 1 {
+		t.Errorf("later request X-Cache = %q after %d runs, want a HIT from the stored page", rec.Header().Get(HeaderCache), calls.Load())
+	}
+}
+
+func TestWithCoalescing_FailedLeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(calls *atomic.Int32, started, release chan struct{}) http.Handler
+	}{
+		{"error status", func(calls *atomic.Int32, started, release chan struct{}) http.Handler {
+			return blocking(calls, started, release, http.StatusInternalServerError)
+		}},
+		{"panic", func(calls *atomic.Int32, started, release chan struct{}) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					close(started)
+					<-release
+					panic("render failed")
+				}
+				_, _ = w.Write([]byte("report"))
+			})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			started, release := make(chan struct{}), make(chan struct{})
+			c := New(time.Minute, WithCoalescing())
+			h := c.Middleware(tt.handler(&calls, started, release))
+
+			recs := herd(t, c, h, started, release, 3)
+
+			if calls.Load() != 4 {
+				t.Errorf("handler ran %d times, want each waiter to run it after the leader failed", calls.Load())
+			}
+			for i, rec := range recs[1:] {
+				if rec.Header().Get(HeaderCache) != "MISS" {
+					t.Errorf("waiter %d X-Cache = %q, want its own MISS", i, rec.Header().Get(HeaderCache))
+				}
+			}
+		})
+	}
+}
+
+func TestWithCoalescing_VariantsRunSeparately(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c := New(time.Minute, WithCoalescing(), WithVary("Accept-Language"))
+	h := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+
+	var wg sync.WaitGroup
+	for _, lang := range []string{"en", "fr"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			req.Header.Set("Accept-Language", lang)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for calls.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("handler ran %d times, want both variants to run at once", calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+}
@@ -0,0 +1,491 @@
+// internal/app/features/httpcache/httpcache.go
+//
+// Package httpcache caches whole responses to GET requests, so expensive
+// pages that rarely change are rendered once per TTL instead of once per
+// request.
+//
+// A Cache stores the status, headers, and body of each 2xx GET response
+// under its method and URL (see Key), and answers later requests for the
+// same URL from the store until the TTL runs out. Requests that differ in
+// the headers named with WithVary (for example Accept-Language) get their
+// own copy. Hits carry X-Cache: HIT and an Age header; misses carry
+// X-Cache: MISS.
+//
+// Cached responses are shared between clients, so by default the Cache
+// only serves and stores requests with no signed-in user and no
+// Authorization header; WithPerUser caches signed-in pages per user
+// instead. A handler keeps a response out of the cache by sending
+// Cache-Control: no-store or private, or by setting a cookie. Pages that
+// embed per-session values, such as CSRF tokens in forms, should not be
+// cached. Nor is a page that carries the request's CSP nonce (any page with
+// a nonced inline script), since every hit would reuse the nonce; the nonce
+// is left out of stored policy headers so hits are sent with their own.
+//
+// WithCoalescing stops a thundering herd when an entry expires: concurrent
+// misses for the same response wait for one of them to run the handler and
+// are served its result, instead of all rendering the same page. If that
+// response cannot be cached, each waiter runs the handler itself.
+//
+// After the data behind a page changes, Invalidate purges every cached
+// copy of it. Responses live in a Store; the default MemoryStore keeps them
+// in process, and multi-instance deployments can supply a shared Store
+// with WithStore.
+//
+// Usage:
+//
+//	reports := httpcache.New(5*time.Minute, httpcache.WithVary("Accept-Language"))
+//	r.With(reports.Middleware).Get("/reports/summary", h.Summary)
+//	...
+//	_ = reports.Invalidate(ctx, httpcache.Key(http.MethodGet, "/reports/summary"))
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+	"go.uber.org/zap"
+)
+
+// HeaderCache reports whether a response came from the cache.
+const HeaderCache = "X-Cache"
+
+// DefaultMaxSize is the largest body that is stored.
+const DefaultMaxSize = 1 << 20
+
+// Response is one cached response.
+type Response struct {
+	Status   int
+	Header   http.Header // headers the handler set
+	Body     []byte
+	StoredAt time.Time
+	Expires  time.Time
+}
+
+// Entry holds the cached variants of one method and URL, by the values of
+// the Cache's vary headers.
+type Entry struct {
+	Variants map[string]*Response
+}
+
+// Store holds cached entries.
+//
+// Get returns the entry for key, or (nil, nil) when there is none. Set
+// replaces the entry for key, to expire after ttl. Delete removes it.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, key string, e *Entry, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is an in-process Store. Expired entries are swept lazily
+// during Set, so it needs no background goroutine.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	entry   *Entry
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*memoryEntry),
+		now:     time.Now,
+	}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !s.now().Before(e.expires) {
+		return nil, nil
+	}
+	return e.entry, nil
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(_ context.Context, key string, e *Entry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	s.entries[key] = &memoryEntry{entry: e, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Len returns the number of entries currently held.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweep drops expired entries, at most once a minute. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Cache caches responses for the routes it wraps. Create it with New.
+type Cache struct {
+	ttl     time.Duration
+	store   Store
+	vary    []string
+	perUser bool
+	maxSize int
+	flights *flights // nil unless WithCoalescing
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// Option configures a Cache.
+type Option func(*Cache)
+
+// WithStore keeps responses in store instead of a new MemoryStore.
+func WithStore(store Store) Option {
+	return func(c *Cache) {
+		c.store = store
+	}
+}
+
+// WithVary keeps a separate copy of each response per combination of the
+// named request headers' values.
+func WithVary(headers ...string) Option {
+	return func(c *Cache) {
+		for _, h := range headers {
+			c.vary = append(c.vary, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// WithPerUser caches requests from signed-in users too, keeping a separate
+// copy per user.
+func WithPerUser() Option {
+	return func(c *Cache) {
+		c.perUser = true
+	}
+}
+
+// WithMaxSize sets the largest body, in bytes, that is stored. Larger
+// responses are sent normally and not cached.
+func WithMaxSize(n int) Option {
+	return func(c *Cache) {
+		c.maxSize = n
+	}
+}
+
+// WithCoalescing makes concurrent misses for the same response (same key
+// and vary values) share one run of the handler. The first runs it and
+// fills the cache; the rest wait for it and are served the stored response.
+// If that run's response is not cacheable, or the handler panics, the
+// waiters each run the handler on their own rather than share the failure.
+// Coalescing is per process; it does not span instances sharing a Store.
+func WithCoalescing() Option {
+	return func(c *Cache) {
+		c.flights = newFlights()
+	}
+}
+
+// WithLogger logs store failures as warnings.
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *Cache) {
+		c.logger = logger
+	}
+}
+
+// New creates a Cache that keeps responses for ttl.
+func New(ttl time.Duration, opts ...Option) *Cache {
+	c := &Cache{
+		ttl:     ttl,
+		maxSize: DefaultMaxSize,
+		logger:  zap.NewNop(),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	sort.Strings(c.vary)
+	if c.store == nil {
+		c.store = NewMemoryStore()
+	}
+	return c
+}
+
+// Key returns the cache key for a request: its method and request URI,
+// including the query string. Pass it to Invalidate.
+func Key(method, requestURI string) string {
+	return method + " " + requestURI
+}
+
+// Invalidate removes every cached copy of the response stored under key.
+func (c *Cache) Invalidate(ctx context.Context, key string) error {
+	return c.store.Delete(ctx, key)
+}
+
+// Middleware serves cached responses and stores new ones. If the store
+// fails, the request is handled normally.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		variant, ok := c.variant(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := Key(r.Method, r.URL.RequestURI())
+
+		entry, err := c.store.Get(r.Context(), key)
+		if err != nil {
+			c.logger.Warn("response cache store failed", zap.Error(err), zap.String("key", key))
+		}
+		now := c.now()
+		if entry != nil {
+			if resp, ok := entry.Variants[variant]; ok && now.Before(resp.Expires) {
+				c.serve(w, resp, now)
+				return
+			}
+		}
+
+		if c.flights == nil {
+			c.fill(w, r, next, key, variant, entry, now)
+			return
+		}
+
+		id := key + "\n" + variant
+		f, leader := c.flights.join(id)
+		if leader {
+			var resp *Response
+			// Deferred so a panicking handler still releases the waiters.
+			defer func() { c.flights.finish(id, f, resp) }()
+			resp = c.fill(w, r, next, key, variant, entry, now)
+			return
+		}
+		resp, ok := f.wait(r.Context())
+		if !ok {
+			return
+		}
+		if resp != nil {
+			c.serve(w, resp, c.now())
+			return
+		}
+		c.fill(w, r, next, key, variant, entry, c.now())
+	})
+}
+
+// fill handles a miss: it runs next and stores the response if it may be
+// shared. It returns the stored response, or nil if it was not cacheable.
+func (c *Cache) fill(w http.ResponseWriter, r *http.Request, next http.Handler, key, variant string, entry *Entry, now time.Time) *Response {
+	// Headers set by earlier middleware (request IDs and the like)
+	// belong to this request and are not stored.
+	before := w.Header().Clone()
+	w.Header().Set(HeaderCache, "MISS")
+	rec := &recorder{ResponseWriter: w, maxSize: c.maxSize}
+	next.ServeHTTP(rec, r)
+
+	status := rec.statusCode()
+	if rec.overflow || status < 200 || status >= 300 || !cacheable(w.Header()) {
+		return nil
+	}
+	// A page carrying this request's CSP nonce cannot be shared: every hit
+	// would reuse it. The policy headers are stored without it, so each hit
+	// gets its own.
+	nonce := csp.Nonce(r.Context())
+	if nonce != "" && bytes.Contains(rec.body.Bytes(), []byte(nonce)) {
+		return nil
+	}
+	after := w.Header().Clone()
+	for _, name := range []string{csp.HeaderName, csp.ReportOnlyHeaderName} {
+		for i, v := range after[name] {
+			after[name][i] = csp.RemoveNonce(v, nonce)
+		}
+	}
+
+	resp := &Response{
+		Status:   status,
+		Header:   added(before, after),
+		Body:     rec.body.Bytes(),
+		StoredAt: now,
+		Expires:  now.Add(c.ttl),
+	}
+	c.save(r.Context(), key, variant, entry, resp)
+	return resp
+}
+
+// variant returns the key of the request's copy within its entry, and
+// false when the request must not use the cache.
+func (c *Cache) variant(r *http.Request) (string, bool) {
+	if r.Method != http.MethodGet || r.Header.Get("Upgrade") != "" {
+		return "", false
+	}
+	if r.Header.Get("Authorization") != "" {
+		return "", false
+	}
+
+	var b strings.Builder
+	if u, ok := auth.CurrentUser(r); ok && u != nil {
+		if !c.perUser {
+			return "", false
+		}
+		b.WriteString("user=" + u.ID + "\n")
+	}
+	for _, name := range c.vary {
+		b.WriteString(name + "=" + strings.Join(r.Header.Values(name), ",") + "\n")
+	}
+	return b.String(), true
+}
+
+// save adds resp to entry, dropping variants that have expired, and
+// writes the entry back. A concurrent miss for another variant of the same
+// URL may overwrite it; the cost is one more miss.
+func (c *Cache) save(ctx context.Context, key, variant string, entry *Entry, resp *Response) {
+	variants := map[string]*Response{variant: resp}
+	latest := resp.Expires
+	if entry != nil {
+		for v, old := range entry.Variants {
+			if v == variant || !resp.StoredAt.Before(old.Expires) {
+				continue
+			}
+			variants[v] = old
+			if old.Expires.After(latest) {
+				latest = old.Expires
+			}
+		}
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if err := c.store.Set(ctx, key, &Entry{Variants: variants}, latest.Sub(resp.StoredAt)); err != nil {
+		c.logger.Warn("response cache store failed", zap.Error(err), zap.String("key", key))
+	}
+}
+
+// serve writes a cached response.
+func (c *Cache) serve(w http.ResponseWriter, resp *Response, now time.Time) {
+	h := w.Header()
+	for name, values := range resp.Header {
+		h[name] = append([]string(nil), values...)
+	}
+	h.Set(HeaderCache, "HIT")
+	h.Set("Age", strconv.Itoa(int(now.Sub(resp.StoredAt).Seconds())))
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// cacheable reports whether the handler allowed the response to be shared.
+func cacheable(h http.Header) bool {
+	if len(h.Values("Set-Cookie")) > 0 {
+		return false
+	}
+	cc := strings.ToLower(strings.Join(h.Values("Cache-Control"), ","))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
+
+// added returns the headers in after that differ from before, leaving out
+// HeaderCache.
+func added(before, after http.Header) http.Header {
+	out := make(http.Header)
+	for name, values := range after {
+		if name == HeaderCache || equal(before[name], values) {
+			continue
+		}
+		out[name] = append([]string(nil), values...)
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// recorder passes a response through while keeping a copy of its status
+// and body, up to maxSize. A flushed response is streamed and not kept.
+type recorder struct {
+	http.ResponseWriter
+	maxSize  int
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 && status >= 200 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	if !r.overflow {
+		if r.body.Len()+len(b) > r.maxSize {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush streams the response; it will not be cached.
+func (r *recorder) Flush() {
+	r.overflow = true
+	r.body.Reset()
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// statusCode returns the status written, or 200 if the handler wrote nothing.
+func (r *recorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,264 @@
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+)
+
+// counting returns a handler that records how often it ran and answers
+// with the run number.
+func counting(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strconv.Itoa(int(n))))
+	})
+}
+
+func get(h http.Handler, path string, mod ...func(*http.Request) *http.Request) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, m := range mod {
+		req = m(req)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_CachesGet(t *testing.T) {
+	var calls atomic.Int32
+	c := New(time.Minute)
+	h := c.Middleware(counting(&calls))
+
+	first := get(h, "/reports")
+	second := get(h, "/reports")
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls.Load())
+	}
+	if first.Header().Get(HeaderCache) != "MISS" || second.Header().Get(HeaderCache) != "HIT" {
+		t.Errorf("X-Cache = %q then %q, want MISS then HIT", first.Header().Get(HeaderCache), second.Header().Get(HeaderCache))
+	}
+	if second.Body.String() != "1" || second.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("hit = %q %v, want the first response", second.Body.String(), second.Header())
+	}
+	if second.Header().Get("Age") == "" {
+		t.Error("hit has no Age header")
+	}
+
+	// The query string is part of the key
+	get(h, "/reports?page=2")
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2 after a new query", calls.Load())
+	}
+}
+
+func TestMiddleware_Expires(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Now()
+	c := New(time.Minute)
+	c.now = func() time.Time { return now }
+	h := c.Middleware(counting(&calls))
+
+	get(h, "/reports")
+	now = now.Add(2 * time.Minute)
+	if rec := get(h, "/reports"); rec.Body.String() != "2" {
+		t.Errorf("body after TTL = %q, want a fresh render", rec.Body.String())
+	}
+}
+
+func TestMiddleware_Vary(t *testing.T) {
+	var calls atomic.Int32
+	h := New(time.Minute, WithVary("accept-language")).Middleware(counting(&calls))
+	lang := func(v string) func(*http.Request) *http.Request {
+		return func(r *http.Request) *http.Request {
+			r.Header.Set("Accept-Language", v)
+			return r
+		}
+	}
+
+	get(h, "/", lang("en"))
+	get(h, "/", lang("fr"))
+	if rec := get(h, "/", lang("en")); rec.Body.String() != "1" {
+		t.Errorf("en body = %q, want the cached en copy", rec.Body.String())
+	}
+	if rec := get(h, "/", lang("fr")); rec.Body.String() != "2" {
+		t.Errorf("fr body = %q, want the cached fr copy", rec.Body.String())
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+}
+
+func TestMiddleware_NotCached(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		handler func(w http.ResponseWriter, r *http.Request)
+		mod     func(*http.Request) *http.Request
+	}{
+		{"post", http.MethodPost, nil, nil},
+		{"error status", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}, nil},
+		{"no-store", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+		}, nil},
+		{"private", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		}, nil},
+		{"sets cookie", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: "flash", Value: "x"})
+		}, nil},
+		{"too large", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(make([]byte, 32))
+		}, nil},
+		{"flushed", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("a"))
+			_ = http.NewResponseController(w).Flush()
+		}, nil},
+		{"signed in", http.MethodGet, nil, func(r *http.Request) *http.Request {
+			return auth.WithTestUser(r, &auth.SessionUser{ID: "u1", Role: "admin"})
+		}},
+		{"authorization", http.MethodGet, nil, func(r *http.Request) *http.Request {
+			r.Header.Set("Authorization", "Bearer x")
+			return r
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			h := New(time.Minute, WithMaxSize(16)).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if tt.handler != nil {
+					tt.handler(w, r)
+				}
+			}))
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(tt.method, "/page", nil)
+				if tt.mod != nil {
+					req = tt.mod(req)
+				}
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			if calls.Load() != 2 {
+				t.Errorf("handler ran %d times, want 2 (not cached)", calls.Load())
+			}
+		})
+	}
+}
+
+func TestMiddleware_PerUser(t *testing.T) {
+	var calls atomic.Int32
+	h := New(time.Minute, WithPerUser()).Middleware(counting(&calls))
+	as := func(id string) func(*http.Request) *http.Request {
+		return func(r *http.Request) *http.Request {
+			return auth.WithTestUser(r, &auth.SessionUser{ID: id, Role: "user"})
+		}
+	}
+
+	get(h, "/dashboard", as("u1"))
+	if rec := get(h, "/dashboard", as("u2")); rec.Body.String() != "2" {
+		t.Errorf("u2 body = %q, want its own render", rec.Body.String())
+	}
+	if rec := get(h, "/dashboard", as("u1")); rec.Body.String() != "1" {
+		t.Errorf("u1 body = %q, want its cached copy", rec.Body.String())
+	}
+}
+
+func TestMiddleware_KeepsRequestHeaders(t *testing.T) {
+	var calls atomic.Int32
+	inner := New(time.Minute).Middleware(counting(&calls))
+	n := 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Header().Set("X-Request-ID", strconv.Itoa(n))
+		inner.ServeHTTP(w, r)
+	})
+
+	get(h, "/")
+	if rec := get(h, "/"); rec.Header().Get("X-Request-ID") != "2" {
+		t.Errorf("X-Request-ID = %q, want this request's, not the cached one", rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestMiddleware_CSPNonce(t *testing.T) {
+	var calls atomic.Int32
+	cache := New(time.Minute)
+	// The policy is set outside the cache, as security headers are; the
+	// handler embeds the nonce only on /nonced.
+	h := csp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(csp.HeaderName, "script-src 'self'")
+		cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			body := "<p>static</p>"
+			if r.URL.Path == "/nonced" {
+				body = `<script nonce="` + csp.Nonce(r.Context()) + `">run()</script>`
+			}
+			_, _ = w.Write([]byte(body))
+		})).ServeHTTP(w, r)
+	}))
+
+	get(h, "/nonced")
+	second := get(h, "/nonced")
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times for a nonced page, want 2 (not cached)", calls.Load())
+	}
+	if second.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("X-Cache = %q for a nonced page, want MISS", second.Header().Get(HeaderCache))
+	}
+
+	first := get(h, "/plain")
+	hit := get(h, "/plain")
+	if hit.Header().Get(HeaderCache) != "HIT" {
+		t.Fatalf("X-Cache = %q for a page without the nonce, want HIT", hit.Header().Get(HeaderCache))
+	}
+	policy, firstPolicy := hit.Header().Get(csp.HeaderName), first.Header().Get(csp.HeaderName)
+	if policy == firstPolicy || strings.Count(policy, "'nonce-") != 1 {
+		t.Errorf("hit policy = %q (first %q), want only the hit's own nonce", policy, firstPolicy)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	var calls atomic.Int32
+	c := New(time.Minute, WithVary("Accept-Language"))
+	h := c.Middleware(counting(&calls))
+
+	get(h, "/reports")
+	get(h, "/reports", func(r *http.Request) *http.Request {
+		r.Header.Set("Accept-Language", "fr")
+		return r
+	})
+	if err := c.Invalidate(context.Background(), Key(http.MethodGet, "/reports")); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if rec := get(h, "/reports"); rec.Header().Get(HeaderCache) != "MISS" {
+		t.Errorf("X-Cache after Invalidate = %q, want MISS", rec.Header().Get(HeaderCache))
+	}
+}
+
+func TestMemoryStore_Sweeps(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = s.Set(ctx, "a", &Entry{}, time.Second)
+	now = now.Add(2 * time.Minute)
+	if e, _ := s.Get(ctx, "a"); e != nil {
+		t.Error("Get() returned an expired entry")
+	}
+	_ = s.Set(ctx, "b", &Entry{}, time.Minute)
+	if s.Len() != 1 {
+		t.Errorf("Len() = %d, want 1 after sweep", s.Len())
+	}
+}
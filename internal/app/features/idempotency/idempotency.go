@@ -0,0 +1,389 @@
+// internal/app/features/idempotency/idempotency.go
+//
+// Package idempotency makes retried POST and PATCH requests safe by
+// replaying the first response instead of running the handler again.
+//
+// A client opts in by sending an Idempotency-Key header. The first request
+// with a key runs normally and its response is stored for the TTL; a later
+// request with the same key and the same body gets that response back, with
+// an Idempotent-Replayed: true header, and the handler does not run. Keys
+// are scoped to the signed-in user plus the method and path, so two users
+// (or two endpoints) never share a response.
+//
+// While the first request is still running, retries with its key get 409
+// Conflict. Reusing a key with a different body gets 422 Unprocessable
+// Entity. Responses with a 5xx status are not stored, so the client can
+// retry a failed attempt with the same key.
+//
+// Requests without the header, other methods, and requests with no user
+// pass straight through. Responses live in a Store; the default MemoryStore
+// keeps them in process, and multi-instance deployments can supply a shared
+// Store with WithStore.
+//
+// Usage:
+//
+//	r.With(idempotency.Middleware(24*time.Hour,
+//		idempotency.WithErrorHandler(errorsHandler),
+//	)).Post("/payments", h.Create)
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+	"go.uber.org/zap"
+)
+
+// HeaderKey is the request header carrying the client's idempotency key.
+const HeaderKey = "Idempotency-Key"
+
+// HeaderReplayed is set to "true" on replayed responses.
+const HeaderReplayed = "Idempotent-Replayed"
+
+// MaxKeyLength is the longest accepted Idempotency-Key; longer keys get 400.
+const MaxKeyLength = 255
+
+// ErrInProgress is returned by Store.Start when another request holds the key.
+var ErrInProgress = errors.New("idempotency: request in progress")
+
+// Response is a stored response, replayed for requests that reuse its key.
+type Response struct {
+	Fingerprint string // SHA-256 of the request body that produced it
+	Status      int
+	Header      http.Header
+	Body        []byte
+	Nonce       string // CSP nonce of the request that produced it, if any
+}
+
+// Store holds in-flight markers and completed responses.
+//
+// Start claims key for a new request and returns (nil, nil); when a
+// completed response is stored for key it returns that instead, and when
+// another request has claimed key and not finished it returns
+// ErrInProgress. Finish stores the response for key, replacing the claim;
+// Abort drops the claim so the key can be used again. Claims and responses
+// expire after ttl.
+//
+// Implementations must be safe for concurrent use, and Start must claim
+// atomically.
+type Store interface {
+	Start(ctx context.Context, key string, ttl time.Duration) (*Response, error)
+	Finish(ctx context.Context, key string, resp *Response, ttl time.Duration) error
+	Abort(ctx context.Context, key string) error
+}
+
+// MemoryStore is an in-process Store. Expired entries are swept lazily
+// during Start, so it needs no background goroutine.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// entry is a claim (resp is nil) or a completed response.
+type entry struct {
+	resp    *Response
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Start implements Store.
+func (s *MemoryStore) Start(_ context.Context, key string, ttl time.Duration) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		if e.resp == nil {
+			return nil, ErrInProgress
+		}
+		return e.resp, nil
+	}
+	s.entries[key] = &entry{expires: now.Add(ttl)}
+	return nil, nil
+}
+
+// Finish implements Store.
+func (s *MemoryStore) Finish(_ context.Context, key string, resp *Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &entry{resp: resp, expires: s.now().Add(ttl)}
+	return nil
+}
+
+// Abort implements Store.
+func (s *MemoryStore) Abort(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Len returns the number of claims and responses currently held.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweep drops expired entries, at most once a minute. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// config holds the settings built up by Options.
+type config struct {
+	store  Store
+	errors *errorsfeature.Handler
+	logger *zap.Logger
+	userFn func(*http.Request) string
+}
+
+// Option configures the idempotency middleware.
+type Option func(*config)
+
+// WithStore keeps responses in store instead of a new MemoryStore.
+func WithStore(store Store) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// WithErrorHandler renders the 400, 409, 413, and 422 responses through h.
+// Without it, they are plain text.
+func WithErrorHandler(h *errorsfeature.Handler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// WithLogger logs store failures as warnings.
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithUserFunc sets how the caller is identified for scoping keys, for
+// example by API client. It returns "" for requests that should not be
+// deduplicated. The default is the signed-in user's ID.
+func WithUserFunc(fn func(*http.Request) string) Option {
+	return func(c *config) {
+		c.userFn = fn
+	}
+}
+
+// sessionUser identifies the caller by the signed-in user's ID.
+func sessionUser(r *http.Request) string {
+	if u, ok := auth.CurrentUser(r); ok && u != nil {
+		return u.ID
+	}
+	return ""
+}
+
+// Middleware deduplicates POST and PATCH requests that carry an
+// Idempotency-Key, keeping each response for ttl. If the store fails, the
+// request runs without deduplication rather than failing.
+func Middleware(ttl time.Duration, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{logger: zap.NewNop(), userFn: sessionUser}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryStore()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idemKey := r.Header.Get(HeaderKey)
+			if idemKey == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			user := cfg.userFn(r)
+			if user == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(idemKey) > MaxKeyLength {
+				cfg.reject(w, r, http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					cfg.reject(w, r, http.StatusRequestEntityTooLarge)
+					return
+				}
+				cfg.reject(w, r, http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := hash(body)
+			key := hash([]byte(user + "\x00" + r.Method + " " + r.URL.Path + "\x00" + idemKey))
+
+			stored, err := cfg.store.Start(r.Context(), key, ttl)
+			switch {
+			case errors.Is(err, ErrInProgress):
+				cfg.reject(w, r, http.StatusConflict)
+				return
+			case err != nil:
+				cfg.logger.Warn("idempotency store failed", zap.Error(err), zap.String("path", r.URL.Path))
+				next.ServeHTTP(w, r)
+				return
+			case stored != nil:
+				if stored.Fingerprint != fingerprint {
+					cfg.reject(w, r, http.StatusUnprocessableEntity)
+					return
+				}
+				replay(w, r, stored)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w}
+			finished := false
+			// Drop the claim if the handler panics or fails, so a retry runs again.
+			defer func() {
+				if !finished {
+					if err := cfg.store.Abort(context.WithoutCancel(r.Context()), key); err != nil {
+						cfg.logger.Warn("idempotency store failed", zap.Error(err), zap.String("path", r.URL.Path))
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			status := rec.statusCode()
+			if status >= 500 {
+				return
+			}
+			header := w.Header().Clone()
+			header.Del("Set-Cookie") // cookies belong to the original exchange
+			nonce := csp.Nonce(r.Context())
+			for _, name := range []string{csp.HeaderName, csp.ReportOnlyHeaderName} {
+				for i, v := range header[name] {
+					header[name][i] = csp.RemoveNonce(v, nonce)
+				}
+			}
+			resp := &Response{
+				Fingerprint: fingerprint,
+				Status:      status,
+				Header:      header,
+				Body:        rec.body.Bytes(),
+				Nonce:       nonce,
+			}
+			if err := cfg.store.Finish(context.WithoutCancel(r.Context()), key, resp, ttl); err != nil {
+				cfg.logger.Warn("idempotency store failed", zap.Error(err), zap.String("path", r.URL.Path))
+				return
+			}
+			finished = true
+		})
+	}
+}
+
+// reject answers with status through the error handler, or in plain text.
+func (c *config) reject(w http.ResponseWriter, r *http.Request, status int) {
+	if c.errors == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	switch status {
+	case http.StatusBadRequest:
+		c.errors.BadRequest(w, r)
+	case http.StatusConflict:
+		c.errors.Conflict(w, r)
+	case http.StatusRequestEntityTooLarge:
+		c.errors.RequestEntityTooLarge(w, r)
+	default:
+		c.errors.Error(w, r, status)
+	}
+}
+
+// replay writes a stored response. Its policy headers were stored without
+// the first request's CSP nonce, and the body's copies of that nonce are
+// swapped for this request's, so the replay's inline scripts still run and
+// no nonce is sent twice.
+func replay(w http.ResponseWriter, r *http.Request, resp *Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	body := resp.Body
+	if nonce := csp.Nonce(r.Context()); resp.Nonce != "" && nonce != "" {
+		body = bytes.ReplaceAll(body, []byte(resp.Nonce), []byte(nonce))
+	}
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(body)
+}
+
+// hash returns the hex SHA-256 of b.
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// recorder passes a response through while keeping a copy of its status
+// and body.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// statusCode returns the status written, or 200 if the handler wrote nothing.
+func (r *recorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
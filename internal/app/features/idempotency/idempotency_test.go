@@ -0,0 +1,240 @@
+package idempotency
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+)
+
+// counting returns a handler that records how often it ran and answers
+// 201 with the run number and a cookie.
+func counting(calls *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		_, _ = io.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "flash", Value: "saved"})
+		w.Header().Set("Location", "/payments/1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte{'0' + byte(n)})
+	})
+}
+
+func newRequest(method, path, key, body, userID string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Accept", "application/json")
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	if userID != "" {
+		req = auth.WithTestUser(req, &auth.SessionUser{ID: userID, Role: "user"})
+	}
+	return req
+}
+
+func TestMiddleware_ReplaysFirstResponse(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(time.Hour, WithErrorHandler(errorsfeature.NewHandler()))(counting(&calls))
+
+	first := httptest.NewRecorder()
+	h.ServeHTTP(first, newRequest(http.MethodPost, "/payments", "k1", `{"amount":5}`, "u1"))
+	second := httptest.NewRecorder()
+	h.ServeHTTP(second, newRequest(http.MethodPost, "/payments", "k1", `{"amount":5}`, "u1"))
+
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls.Load())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != "1" {
+		t.Errorf("replay = %d %q, want 201 %q", second.Code, second.Body.String(), "1")
+	}
+	if second.Header().Get("Location") != "/payments/1" || second.Header().Get(HeaderReplayed) != "true" {
+		t.Errorf("replay headers = %v", second.Header())
+	}
+	if second.Header().Get("Set-Cookie") != "" {
+		t.Errorf("replay sent Set-Cookie %q, want none", second.Header().Get("Set-Cookie"))
+	}
+	if first.Header().Get(HeaderReplayed) != "" {
+		t.Error("first response is marked as replayed")
+	}
+}
+
+func TestMiddleware_ReplayGetsItsOwnNonce(t *testing.T) {
+	var calls atomic.Int32
+	idem := Middleware(time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_, _ = w.Write([]byte(`<script nonce="` + csp.Nonce(r.Context()) + `">done()</script>`))
+	}))
+	h := csp.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(csp.HeaderName, "script-src 'self'")
+		idem.ServeHTTP(w, r)
+	}))
+
+	var nonces []string
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRequest(http.MethodPost, "/payments", "k1", "a=1", "u1"))
+		policy := rec.Header().Get(csp.HeaderName)
+		if strings.Count(policy, "'nonce-") != 1 {
+			t.Fatalf("request %d policy = %q, want exactly one nonce", i+1, policy)
+		}
+		nonce := strings.TrimSuffix(strings.TrimPrefix(policy, "script-src 'self' 'nonce-"), "'")
+		if want := `<script nonce="` + nonce + `">done()</script>`; rec.Body.String() != want {
+			t.Errorf("request %d body = %q, want %q", i+1, rec.Body.String(), want)
+		}
+		nonces = append(nonces, nonce)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+	if nonces[0] == nonces[1] {
+		t.Error("replay reused the first request's nonce")
+	}
+}
+
+func TestMiddleware_ScopesKeys(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(time.Hour)(counting(&calls))
+
+	for _, req := range []*http.Request{
+		newRequest(http.MethodPost, "/payments", "k1", "", "u1"),
+		newRequest(http.MethodPost, "/payments", "k1", "", "u2"),  // other user
+		newRequest(http.MethodPost, "/refunds", "k1", "", "u1"),   // other path
+		newRequest(http.MethodPatch, "/payments", "k1", "", "u1"), // other method
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if calls.Load() != 4 {
+		t.Errorf("handler ran %d times, want 4", calls.Load())
+	}
+}
+
+func TestMiddleware_PassesThrough(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(time.Hour)(counting(&calls))
+
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPost, "/payments", "", "", "u1"))  // no key
+		h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPut, "/payments", "k1", "", "u1")) // PUT
+		h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPost, "/payments", "k1", "", ""))  // anonymous
+	}
+
+	if calls.Load() != 6 {
+		t.Errorf("handler ran %d times, want 6", calls.Load())
+	}
+}
+
+func TestMiddleware_ConcurrentRequestConflicts(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+	h := Middleware(time.Hour, WithErrorHandler(errorsfeature.NewHandler()))(slow)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPost, "/payments", "k1", "", "u1"))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest(http.MethodPost, "/payments", "k1", "", "u1"))
+	close(release)
+	<-done
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", rec.Code)
+	}
+}
+
+func TestMiddleware_DifferentBodyIsRejected(t *testing.T) {
+	var calls atomic.Int32
+	h := Middleware(time.Hour)(counting(&calls))
+
+	h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPost, "/payments", "k1", `{"amount":5}`, "u1"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRequest(http.MethodPost, "/payments", "k1", `{"amount":500}`, "u1"))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestMiddleware_ServerErrorsAreNotStored(t *testing.T) {
+	var calls atomic.Int32
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	store := NewMemoryStore()
+	h := Middleware(time.Hour, WithStore(store))(failing)
+
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPost, "/payments", "k1", "", "u1"))
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("handler ran %d times, want 2", calls.Load())
+	}
+	if store.Len() != 0 {
+		t.Errorf("store holds %d entries, want 0", store.Len())
+	}
+}
+
+func TestMiddleware_PanicReleasesKey(t *testing.T) {
+	store := NewMemoryStore()
+	h := Middleware(time.Hour, WithStore(store))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() { _ = recover() }()
+		h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPost, "/payments", "k1", "", "u1"))
+	}()
+
+	if store.Len() != 0 {
+		t.Errorf("store holds %d entries after a panic, want 0", store.Len())
+	}
+}
+
+func TestMiddleware_LongKeyIsBadRequest(t *testing.T) {
+	h := Middleware(time.Hour)(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, newRequest(http.MethodPost, "/payments", strings.Repeat("k", MaxKeyLength+1), "", "u1"))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+func TestMemoryStore_Expires(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	if _, err := s.Start(t.Context(), "k", time.Minute); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := s.Start(t.Context(), "k", time.Minute); err != ErrInProgress {
+		t.Fatalf("second Start() error = %v, want ErrInProgress", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if resp, err := s.Start(t.Context(), "k", time.Minute); err != nil || resp != nil {
+		t.Errorf("Start() after expiry = %v, %v; want a fresh claim", resp, err)
+	}
+}
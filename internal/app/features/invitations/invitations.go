@@ -6,8 +6,11 @@ package invitations
 //   - LoginID / loginID / login_id: The human-readable string users type to log in
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/mail"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -18,6 +21,8 @@ import (
 	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
 	"github.com/dalemusser/strataforge/internal/app/system/auth"
 	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
+	"github.com/dalemusser/strataforge/internal/app/system/captcha"
+	"github.com/dalemusser/strataforge/internal/app/system/jobqueue"
 	"github.com/dalemusser/strataforge/internal/app/system/mailer"
 	"github.com/dalemusser/strataforge/internal/app/system/network"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
@@ -41,6 +46,10 @@ type Handler struct {
 	mailer          *mailer.Mailer
 	auditLogger     *auditlog.Logger
 	baseURL         string
+	captcha         captcha.Verifier
+	captchaWidget   captcha.Widget
+	trustedProxies  []netip.Prefix
+	jobs            *jobqueue.Queue
 	logger          *zap.Logger
 }
 
@@ -70,10 +79,48 @@ func NewHandler(
 		mailer:          m,
 		auditLogger:     auditLogger,
 		baseURL:         baseURL,
+		captcha:         captcha.Noop{},
 		logger:          logger,
 	}
 }
 
+// SetCaptcha requires a solved CAPTCHA, checked with v, on the accept
+// invitation form, which shows widget. By default no CAPTCHA is asked for.
+func (h *Handler) SetCaptcha(v captcha.Verifier, widget captcha.Widget) {
+	h.captcha = v
+	h.captchaWidget = widget
+}
+
+// SetTrustedProxies sets the reverse proxies (IPs or CIDR ranges) whose
+// forwarding headers are believed when the client IP is passed to the
+// CAPTCHA provider and recorded on the new user's tracked session; see
+// network.ClientIP. By default only the direct peer is used.
+func (h *Handler) SetTrustedProxies(entries ...string) {
+	h.trustedProxies = network.ParsePrefixes(entries)
+}
+
+// SetJobQueue sends notification emails, such as the welcome email, on q
+// after the response instead of on a goroutine of their own, so they are
+// bounded by its workers and drained at shutdown.
+func (h *Handler) SetJobQueue(q *jobqueue.Queue) {
+	h.jobs = q
+}
+
+// sendLater sends email without holding up the response: on the job queue
+// when one is set, otherwise on its own goroutine. Failures are logged.
+func (h *Handler) sendLater(r *http.Request, name string, email mailer.Email) {
+	send := func(ctx context.Context) error {
+		return h.mailer.Send(ctx, email)
+	}
+	if h.jobs == nil {
+		go func() { _ = send(context.WithoutCancel(r.Context())) }()
+		return
+	}
+	if err := h.jobs.Enqueue(jobqueue.Job{Name: name, Run: send}); err != nil {
+		h.logger.Warn("could not queue email", zap.String("job", name), zap.Error(err))
+	}
+}
+
 // invitationRow represents an invitation in the list.
 type invitationRow struct {
 	ID        string
@@ -304,7 +351,7 @@ func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
 	// Send invitation email
 	if h.mailer != nil {
 		inviteURL := h.baseURL + "/invite?token=" + inv.Token
-		err = h.mailer.Send(mailer.Email{
+		err = h.mailer.Send(r.Context(), mailer.Email{
 			To:      email,
 			Subject: "You're Invited!",
 			TextBody: "You've been invited to join our platform.\n\n" +
@@ -398,7 +445,7 @@ func (h *Handler) resend(w http.ResponseWriter, r *http.Request) {
 	// Send invitation email
 	if h.mailer != nil {
 		inviteURL := h.baseURL + "/invite?token=" + newInv.Token
-		err = h.mailer.Send(mailer.Email{
+		err = h.mailer.Send(r.Context(), mailer.Email{
 			To:      inv.Email,
 			Subject: "You're Invited!",
 			TextBody: "You've been invited to join our platform.\n\n" +
@@ -427,6 +474,7 @@ type AcceptVM struct {
 	Email    string
 	FullName string
 	Error    string
+	Captcha  captcha.Widget
 }
 
 // showAccept displays the accept invitation form.
@@ -500,9 +548,10 @@ func (h *Handler) showAccept(w http.ResponseWriter, r *http.Request) {
 	}
 
 	vm := AcceptVM{
-		BaseVM: viewdata.New(r),
-		Token:  token,
-		Email:  inv.Email,
+		BaseVM:  viewdata.New(r),
+		Token:   token,
+		Email:   inv.Email,
+		Captcha: h.captchaWidget,
 	}
 	vm.Title = "Complete Your Registration"
 
@@ -535,6 +584,25 @@ func (h *Handler) handleAccept(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.captcha.Verify(r.Context(), captcha.Token(r), network.ClientIP(r, h.trustedProxies)); err != nil {
+		if !errors.Is(err, captcha.ErrFailed) {
+			h.errLog.Log(r, "captcha verification failed", err)
+		}
+		vm := AcceptVM{
+			BaseVM:   viewdata.New(r),
+			Token:    token,
+			Email:    inv.Email,
+			FullName: fullName,
+			Error:    "Please complete the CAPTCHA and try again.",
+			Captcha:  h.captchaWidget,
+		}
+		vm.Title = "Complete Your Registration"
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		templates.Render(w, r, "invitations/accept", vm)
+		return
+	}
+
 	// Validate inputs
 	if fullName == "" {
 		vm := AcceptVM{
@@ -543,6 +611,7 @@ func (h *Handler) handleAccept(w http.ResponseWriter, r *http.Request) {
 			Email:    inv.Email,
 			FullName: fullName,
 			Error:    "Full name is required",
+			Captcha:  h.captchaWidget,
 		}
 		vm.Title = "Complete Your Registration"
 		templates.Render(w, r, "invitations/accept", vm)
@@ -604,20 +673,18 @@ func (h *Handler) handleAccept(w http.ResponseWriter, r *http.Request) {
 			if siteName == "" {
 				siteName = "Strata"
 			}
-			go func() {
-				text, html := mailer.WelcomeEmail(mailer.WelcomeEmailData{
-					AppName:  siteName,
-					UserName: userName,
-					LoginURL: h.baseURL + "/login",
-					Role:     userRole,
-				})
-				_ = h.mailer.Send(mailer.Email{
-					To:       userEmail,
-					Subject:  "Welcome to " + siteName + "!",
-					TextBody: text,
-					HTMLBody: html,
-				})
-			}()
+			text, html := mailer.WelcomeEmail(mailer.WelcomeEmailData{
+				AppName:  siteName,
+				UserName: userName,
+				LoginURL: h.baseURL + "/login",
+				Role:     userRole,
+			})
+			h.sendLater(r, "welcome-email", mailer.Email{
+				To:       userEmail,
+				Subject:  "Welcome to " + siteName + "!",
+				TextBody: text,
+				HTMLBody: html,
+			})
 		}
 	}
 
@@ -653,7 +720,7 @@ func (h *Handler) createTrackedSession(w http.ResponseWriter, r *http.Request, u
 	session := sessions.Session{
 		Token:        token,
 		UserID:       userID,
-		IPAddress:    network.GetClientIP(r),
+		IPAddress:    network.ClientIP(r, h.trustedProxies),
 		UserAgent:    r.UserAgent(),
 		LoginAt:      now,
 		LastActivity: now,
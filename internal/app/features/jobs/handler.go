@@ -4,10 +4,10 @@ package jobsfeature
 import (
 	"context"
 	"net/http"
-	"strconv"
 
 	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
 	jobstore "github.com/dalemusser/strataforge/internal/app/store/jobs"
+	"github.com/dalemusser/strataforge/internal/app/system/pagination"
 	"github.com/dalemusser/strataforge/internal/app/system/timeouts"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
 	"github.com/dalemusser/waffle/pantry/templates"
@@ -82,10 +82,7 @@ func (h *Handler) ServeList(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), timeouts.Medium())
 	defer cancel()
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
+	p := pagination.Parse(r, 50)
 
 	filter := jobstore.ListFilter{
 		QueueName: r.URL.Query().Get("queue"),
@@ -94,7 +91,7 @@ func (h *Handler) ServeList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	store := jobstore.New(h.DB)
-	result, err := store.List(ctx, filter, page, 50)
+	result, err := store.List(ctx, filter, p.Page, p.PerPage)
 	if err != nil {
 		h.ErrLog.Log(r, "failed to load jobs", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
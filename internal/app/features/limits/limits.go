@@ -0,0 +1,80 @@
+// internal/app/features/limits/limits.go
+//
+// Package limits caps the size of incoming requests so a client cannot
+// exhaust memory with oversized headers or bodies.
+//
+// The header check runs before anything else: a request whose request line
+// and headers together exceed MaxHeaderBytes is answered with 431 Request
+// Header Fields Too Large. The server's own MaxHeaderBytes (1 MB by
+// default) still applies first; this limit is for going lower.
+//
+// Bodies are capped at MaxBodyBytes with http.MaxBytesReader. A request
+// that declares a larger Content-Length is answered with 413 Request
+// Entity Too Large before the handler runs. A chunked body that grows past
+// the limit makes the handler's next read fail with *http.MaxBytesError;
+// handlers that read bodies themselves should answer that with
+// errors.Handler.RequestEntityTooLarge. jsonutil.DecodeJSON and
+// formutil.Parse report it as their ErrTooLarge.
+package limits
+
+import (
+	"net/http"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+// Options sets the limits. A zero or negative value disables that limit.
+type Options struct {
+	MaxHeaderBytes int64 // request line plus header names and values
+	MaxBodyBytes   int64
+}
+
+// Middleware returns middleware that enforces opts, rendering the 413 and
+// 431 pages through h (or plain-text responses when h is nil).
+func Middleware(h *errorsfeature.Handler, opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if opts.MaxHeaderBytes <= 0 && opts.MaxBodyBytes <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.MaxHeaderBytes > 0 && headerSize(r) > opts.MaxHeaderBytes {
+				reject(w, r, h, http.StatusRequestHeaderFieldsTooLarge)
+				return
+			}
+			if opts.MaxBodyBytes > 0 && r.Body != nil && r.Body != http.NoBody {
+				if r.ContentLength > opts.MaxBodyBytes {
+					reject(w, r, h, http.StatusRequestEntityTooLarge)
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// headerSize approximates the bytes the client sent before the body: the
+// request line and each header as "Name: value\r\n".
+func headerSize(r *http.Request) int64 {
+	n := int64(len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4)
+	n += int64(len("Host: ") + len(r.Host) + 2)
+	for name, values := range r.Header {
+		for _, v := range values {
+			n += int64(len(name) + len(v) + 4)
+		}
+	}
+	return n
+}
+
+// reject answers with status through h, or in plain text when h is nil.
+func reject(w http.ResponseWriter, r *http.Request, h *errorsfeature.Handler, status int) {
+	if h == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if status == http.StatusRequestEntityTooLarge {
+		h.RequestEntityTooLarge(w, r)
+		return
+	}
+	h.Error(w, r, status)
+}
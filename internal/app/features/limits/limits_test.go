@@ -0,0 +1,120 @@
+package limits
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+// readAll is a handler that reads the whole body, answering 413 itself if
+// the limit cuts it off, and records that it ran.
+func readAll(ran *bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ran = true
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func jsonRequest(method, body string) *http.Request {
+	req := httptest.NewRequest(method, "/api/items", strings.NewReader(body))
+	req.Header.Set("Accept", "application/json")
+	return req
+}
+
+func TestMiddleware_RejectsDeclaredOversizedBody(t *testing.T) {
+	var ran bool
+	h := Middleware(errorsfeature.NewHandler(), Options{MaxBodyBytes: 16})(readAll(&ran))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, jsonRequest(http.MethodPost, strings.Repeat("a", 17)))
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+	if ran {
+		t.Error("handler ran for a body over the limit")
+	}
+	var body errorsfeature.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("body = %s (err %v), want the errors handler's 413 JSON", rec.Body.String(), err)
+	}
+}
+
+func TestMiddleware_CapsUndeclaredBody(t *testing.T) {
+	var ran bool
+	h := Middleware(nil, Options{MaxBodyBytes: 16})(readAll(&ran))
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("a", 100)))
+	req.ContentLength = -1 // chunked: size unknown up front
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413 from the capped read", rec.Code)
+	}
+}
+
+func TestMiddleware_AllowsBodyWithinLimit(t *testing.T) {
+	var ran bool
+	h := Middleware(errorsfeature.NewHandler(), Options{MaxBodyBytes: 16})(readAll(&ran))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, jsonRequest(http.MethodPost, strings.Repeat("a", 16)))
+
+	if rec.Code != http.StatusOK || !ran {
+		t.Errorf("status = %d, ran = %v; want 200 and the handler to run", rec.Code, ran)
+	}
+}
+
+func TestMiddleware_RejectsOversizedHeaders(t *testing.T) {
+	var ran bool
+	h := Middleware(errorsfeature.NewHandler(), Options{MaxHeaderBytes: 1024})(readAll(&ran))
+
+	req := jsonRequest(http.MethodGet, "")
+	req.Header.Set("Cookie", strings.Repeat("c", 2048))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("status = %d, want 431", rec.Code)
+	}
+	if ran {
+		t.Error("handler ran for headers over the limit")
+	}
+
+	ran = false
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, jsonRequest(http.MethodGet, ""))
+	if rec.Code != http.StatusOK || !ran {
+		t.Errorf("small request: status = %d, ran = %v", rec.Code, ran)
+	}
+}
+
+func TestMiddleware_ZeroDisables(t *testing.T) {
+	var ran bool
+	h := Middleware(nil, Options{})(readAll(&ran))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 1<<16)))
+	req.Header.Set("X-Big", strings.Repeat("h", 1<<16))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 with limits disabled", rec.Code)
+	}
+}
@@ -6,20 +6,25 @@ package login
 //   - LoginID / loginID / login_id: The human-readable string users type to log in
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"time"
 
 	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	rememberfeature "github.com/dalemusser/strataforge/internal/app/features/remember"
 	"github.com/dalemusser/strataforge/internal/app/store/activity"
 	"github.com/dalemusser/strataforge/internal/app/store/emailverify"
 	"github.com/dalemusser/strataforge/internal/app/store/passwordreset"
-	"github.com/dalemusser/strataforge/internal/app/store/ratelimit"
 	"github.com/dalemusser/strataforge/internal/app/store/sessions"
 	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
 	"github.com/dalemusser/strataforge/internal/app/system/auth"
 	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
 	"github.com/dalemusser/strataforge/internal/app/system/authutil"
+	"github.com/dalemusser/strataforge/internal/app/system/captcha"
+	"github.com/dalemusser/strataforge/internal/app/system/lockout"
 	"github.com/dalemusser/strataforge/internal/app/system/mailer"
 	"github.com/dalemusser/strataforge/internal/app/system/network"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
@@ -34,26 +39,32 @@ import (
 
 // Handler provides login handlers.
 type Handler struct {
-	userStore          *userstore.Store
+	userStore          userstore.UserStore
 	emailVerifyStore   *emailverify.Store
 	passwordResetStore *passwordreset.Store
 	sessionsStore      *sessions.Store
 	activityStore      *activity.Store
-	rateLimitStore     *ratelimit.Store // nil if rate limiting disabled
+	lockout            *lockout.Lockout // nil if lockout disabled
+	trustedProxies     []netip.Prefix
 	sessionMgr         *auth.SessionManager
 	errLog             *errorsfeature.ErrorLogger
+	errors             *errorsfeature.Handler // nil renders lockouts on the form
 	mailer             *mailer.Mailer
 	auditLogger        *auditlog.Logger
 	baseURL            string
 	emailVerifyExpiry  time.Duration
 	googleEnabled      bool
 	trustLoginEnabled  bool // Only enable in dev mode for security
+	hasher             authutil.PasswordHasher
+	captcha            captcha.Verifier
+	captchaWidget      captcha.Widget
+	remember           *rememberfeature.Handler // nil hides "Remember me"
 	logger             *zap.Logger
 }
 
 // NewHandler creates a new login Handler.
 // Set trustLoginEnabled to true only in development mode.
-// rateLimitStore can be nil to disable rate limiting.
+// loginLockout can be nil to disable locking out repeated failures.
 func NewHandler(
 	db *mongo.Database,
 	sessionMgr *auth.SessionManager,
@@ -62,7 +73,7 @@ func NewHandler(
 	auditLogger *auditlog.Logger,
 	sessionsStore *sessions.Store,
 	activityStore *activity.Store,
-	rateLimitStore *ratelimit.Store,
+	loginLockout *lockout.Lockout,
 	baseURL string,
 	emailVerifyExpiry time.Duration,
 	googleEnabled bool,
@@ -81,7 +92,7 @@ func NewHandler(
 		passwordResetStore: passwordreset.New(db, passwordResetExpiry),
 		sessionsStore:      sessionsStore,
 		activityStore:      activityStore,
-		rateLimitStore:     rateLimitStore,
+		lockout:            loginLockout,
 		sessionMgr:         sessionMgr,
 		errLog:             errLog,
 		mailer:             m,
@@ -90,10 +101,67 @@ func NewHandler(
 		emailVerifyExpiry:  emailVerifyExpiry,
 		googleEnabled:      googleEnabled,
 		trustLoginEnabled:  trustLoginEnabled,
+		hasher:             authutil.BcryptHasher{},
+		captcha:            captcha.Noop{},
 		logger:             logger,
 	}
 }
 
+// SetUserStore replaces the MongoDB user store, for applications that keep
+// users elsewhere and for tests (see userstore.MemoryStore).
+func (h *Handler) SetUserStore(s userstore.UserStore) {
+	h.userStore = s
+}
+
+// SetPasswordHasher replaces the bcrypt hasher used to verify and set
+// passwords. Tests use it to inject a fast fake.
+func (h *Handler) SetPasswordHasher(hasher authutil.PasswordHasher) {
+	h.hasher = hasher
+}
+
+// SetErrorHandler renders locked-out password attempts with
+// h.TooManyRequests. Without it, the password form is shown again with 429.
+func (h *Handler) SetErrorHandler(eh *errorsfeature.Handler) {
+	h.errors = eh
+}
+
+// SetTrustedProxies sets the reverse proxies (IPs or CIDR ranges) whose
+// forwarding headers are believed when failures are counted per client IP,
+// when the client IP is passed to the CAPTCHA provider, and when it is
+// recorded on the tracked session; see network.ClientIP. By default only
+// the direct peer is used.
+func (h *Handler) SetTrustedProxies(entries ...string) {
+	h.trustedProxies = network.ParsePrefixes(entries)
+}
+
+// SetRemember offers "Remember me" on the password form, issuing a
+// remember-me token through rm when it is ticked. A completed password reset
+// also revokes the user's tokens.
+func (h *Handler) SetRemember(rm *rememberfeature.Handler) {
+	h.remember = rm
+}
+
+// SetCaptcha requires a solved CAPTCHA, checked with v, on the password and
+// email login forms, which show widget. By default no CAPTCHA is asked for.
+func (h *Handler) SetCaptcha(v captcha.Verifier, widget captcha.Widget) {
+	h.captcha = v
+	h.captchaWidget = widget
+}
+
+// checkCaptcha verifies the CAPTCHA response posted with r. Failures other
+// than a wrong or missing response, such as the provider being unreachable,
+// are logged; either way the submission is refused.
+func (h *Handler) checkCaptcha(r *http.Request) bool {
+	err := h.captcha.Verify(r.Context(), captcha.Token(r), network.ClientIP(r, h.trustedProxies))
+	if err != nil && !errors.Is(err, captcha.ErrFailed) {
+		h.errLog.Log(r, "captcha verification failed", err)
+	}
+	return err == nil
+}
+
+// captchaError is shown when the CAPTCHA was not solved.
+const captchaError = "Please complete the CAPTCHA and try again."
+
 // LoginVM is the view model for the login page.
 type LoginVM struct {
 	viewdata.BaseVM
@@ -142,7 +210,7 @@ func (h *Handler) showLogin(w http.ResponseWriter, r *http.Request) {
 	vm := LoginVM{
 		BaseVM:        viewdata.New(r),
 		GoogleEnabled: h.googleEnabled,
-		ReturnURL:     query.Get(r, "return"),
+		ReturnURL:     returnFromQuery(r),
 		Error:         r.URL.Query().Get("error"),
 	}
 	vm.Title = "Login"
@@ -302,6 +370,9 @@ type PasswordLoginVM struct {
 	Error     string
 	LoginID   string
 	ReturnURL string
+	Captcha   captcha.Widget
+
+	RememberMe bool // show the "Remember me" checkbox
 }
 
 // showPasswordLogin displays the password login form.
@@ -309,7 +380,9 @@ func (h *Handler) showPasswordLogin(w http.ResponseWriter, r *http.Request) {
 	vm := PasswordLoginVM{
 		BaseVM:    viewdata.New(r),
 		LoginID:   r.URL.Query().Get("login_id"),
-		ReturnURL: query.Get(r, "return"),
+		ReturnURL:  query.Get(r, "return"),
+		Captcha:    h.captchaWidget,
+		RememberMe: h.remember != nil,
 	}
 	vm.Title = "Enter Password"
 
@@ -328,90 +401,66 @@ func (h *Handler) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 	password := r.FormValue("password")
 	returnURL := r.FormValue("return")
 
-	// Check rate limit before processing
-	if h.rateLimitStore != nil {
-		allowed, _, lockedUntil := h.rateLimitStore.CheckAllowed(r.Context(), loginID)
-		if !allowed {
-			h.auditLogger.LogAuthEvent(r, nil, "login_rate_limited", false, "rate limit exceeded for "+loginID)
-
-			errorMsg := "Too many failed login attempts. Please try again later."
-			if lockedUntil != nil {
-				remaining := time.Until(*lockedUntil)
-				if remaining > time.Minute {
-					errorMsg = fmt.Sprintf("Too many failed login attempts. Please try again in %d minute(s).", int(remaining.Minutes())+1)
-				} else {
-					errorMsg = fmt.Sprintf("Too many failed login attempts. Please try again in %d second(s).", int(remaining.Seconds())+1)
-				}
-			}
-
-			vm := PasswordLoginVM{
-				BaseVM:    viewdata.New(r),
-				Error:     errorMsg,
-				LoginID:   loginID,
-				ReturnURL: returnURL,
-			}
-			templates.Render(w, r, "login/password", vm)
-			return
+	if !h.checkCaptcha(r) {
+		h.auditLogger.LogAuthEvent(r, nil, "login_captcha_failed", false, "captcha not solved for "+loginID)
+		vm := PasswordLoginVM{
+			BaseVM:    viewdata.New(r),
+			Error:     captchaError,
+			LoginID:   loginID,
+			ReturnURL: returnURL,
 		}
+		h.renderPasswordLogin(w, r, http.StatusBadRequest, vm)
+		return
+	}
+
+	// Check for a lockout before processing
+	if wait := h.lockedOut(r, loginID); wait > 0 {
+		h.auditLogger.LogAuthEvent(r, nil, "login_rate_limited", false, "rate limit exceeded for "+loginID)
+		h.tooManyAttempts(w, r, loginID, returnURL, wait)
+		return
 	}
 
 	user, err := h.userStore.GetByLoginID(r.Context(), loginID)
 	if err != nil {
-		// Record failure for rate limiting (even though user doesn't exist)
-		if h.rateLimitStore != nil {
-			h.rateLimitStore.RecordFailure(r.Context(), loginID)
-		}
+		// Record failure for lockout (even though user doesn't exist)
 		h.auditLogger.LoginFailedUserNotFound(r.Context(), r, loginID)
+		if wait := h.recordFailure(r, loginID); wait > 0 {
+			h.tooManyAttempts(w, r, loginID, returnURL, wait)
+			return
+		}
 
 		vm := PasswordLoginVM{
 			BaseVM:  viewdata.New(r),
 			Error:   "Invalid credentials",
 			LoginID: loginID,
 		}
-		templates.Render(w, r, "login/password", vm)
+		h.renderPasswordLogin(w, r, http.StatusUnauthorized, vm)
 		return
 	}
 
 	if user.Status != "active" {
-		// Record failure for rate limiting
-		if h.rateLimitStore != nil {
-			h.rateLimitStore.RecordFailure(r.Context(), loginID)
-		}
+		// Record failure for lockout
 		h.auditLogger.LogAuthEvent(r, &user.ID, "login_failed_user_disabled", false, "user disabled")
+		if wait := h.recordFailure(r, loginID); wait > 0 {
+			h.tooManyAttempts(w, r, loginID, returnURL, wait)
+			return
+		}
 
 		vm := PasswordLoginVM{
 			BaseVM:  viewdata.New(r),
 			Error:   "Account is disabled",
 			LoginID: loginID,
 		}
-		templates.Render(w, r, "login/password", vm)
-		return
-	}
-
-	if user.PasswordHash == nil || !authutil.CheckPassword(password, *user.PasswordHash) {
-		// Record failure for rate limiting
-		if h.rateLimitStore != nil {
-			lockedOut, lockedUntil := h.rateLimitStore.RecordFailure(r.Context(), loginID)
-			if lockedOut {
-				h.auditLogger.LogAuthEvent(r, &user.ID, "login_locked_out", false, "too many failed attempts")
-				errorMsg := "Too many failed login attempts. Please try again later."
-				if lockedUntil != nil {
-					remaining := time.Until(*lockedUntil)
-					if remaining > time.Minute {
-						errorMsg = fmt.Sprintf("Too many failed login attempts. Please try again in %d minute(s).", int(remaining.Minutes())+1)
-					} else {
-						errorMsg = fmt.Sprintf("Too many failed login attempts. Please try again in %d second(s).", int(remaining.Seconds())+1)
-					}
-				}
-				vm := PasswordLoginVM{
-					BaseVM:    viewdata.New(r),
-					Error:     errorMsg,
-					LoginID:   loginID,
-					ReturnURL: returnURL,
-				}
-				templates.Render(w, r, "login/password", vm)
-				return
-			}
+		h.renderPasswordLogin(w, r, http.StatusUnauthorized, vm)
+		return
+	}
+
+	if user.PasswordHash == nil || !h.hasher.Compare(password, *user.PasswordHash) {
+		// Record failure for lockout
+		if wait := h.recordFailure(r, loginID); wait > 0 {
+			h.auditLogger.LogAuthEvent(r, &user.ID, "login_locked_out", false, "too many failed attempts")
+			h.tooManyAttempts(w, r, loginID, returnURL, wait)
+			return
 		}
 		h.auditLogger.LogAuthEvent(r, &user.ID, "login_failed_wrong_password", false, "wrong password")
 
@@ -420,13 +469,15 @@ func (h *Handler) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 			Error:   "Invalid credentials",
 			LoginID: loginID,
 		}
-		templates.Render(w, r, "login/password", vm)
+		h.renderPasswordLogin(w, r, http.StatusUnauthorized, vm)
 		return
 	}
 
-	// Clear rate limit on successful login
-	if h.rateLimitStore != nil {
-		h.rateLimitStore.ClearOnSuccess(r.Context(), loginID)
+	// Clear the account's failures on successful login
+	if h.lockout != nil {
+		if err := h.lockout.Succeed(r.Context(), loginID); err != nil {
+			h.errLog.Log(r, "failed to clear login failures", err)
+		}
 	}
 
 	// Create session
@@ -436,6 +487,13 @@ func (h *Handler) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Keep the user logged in across browser restarts if asked
+	if h.remember != nil && r.FormValue("remember") == "1" {
+		if err := h.remember.Issue(w, r, user.ID); err != nil {
+			h.errLog.Log(r, "failed to issue remember-me token", err)
+		}
+	}
+
 	h.auditLogger.LogAuthEvent(r, &user.ID, "login_success", true, "")
 
 	// Check if password change is required
@@ -450,14 +508,16 @@ func (h *Handler) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
 // EmailLoginVM is the view model for email login.
 type EmailLoginVM struct {
 	viewdata.BaseVM
-	Error string
-	Email string
+	Error   string
+	Email   string
+	Captcha captcha.Widget
 }
 
 // showEmailLogin displays the email login form.
 func (h *Handler) showEmailLogin(w http.ResponseWriter, r *http.Request) {
 	vm := EmailLoginVM{
-		BaseVM: viewdata.New(r),
+		BaseVM:  viewdata.New(r),
+		Captcha: h.captchaWidget,
 	}
 	vm.Title = "Email Login"
 
@@ -474,6 +534,21 @@ func (h *Handler) handleEmailLogin(w http.ResponseWriter, r *http.Request) {
 
 	email := r.FormValue("email")
 
+	if !h.checkCaptcha(r) {
+		h.auditLogger.LogAuthEvent(r, nil, "login_captcha_failed", false, "captcha not solved")
+		vm := EmailLoginVM{
+			BaseVM:  viewdata.New(r),
+			Error:   captchaError,
+			Email:   email,
+			Captcha: h.captchaWidget,
+		}
+		vm.Title = "Email Login"
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		templates.Render(w, r, "login/email", vm)
+		return
+	}
+
 	user, err := h.userStore.GetByEmail(r.Context(), email)
 	if err != nil {
 		// Don't reveal if email exists
@@ -504,7 +579,7 @@ func (h *Handler) handleEmailLogin(w http.ResponseWriter, r *http.Request) {
 			Code:     verification.Code,
 			MagicURL: magicURL,
 		})
-		err = h.mailer.Send(mailer.Email{
+		err = h.mailer.Send(r.Context(), mailer.Email{
 			To:       email,
 			Subject:  "Your Login Code",
 			TextBody: textBody,
@@ -721,7 +796,7 @@ func (h *Handler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
 			ResetURL:  resetURL,
 			ExpiryMin: expiryMin,
 		})
-		err = h.mailer.Send(mailer.Email{
+		err = h.mailer.Send(r.Context(), mailer.Email{
 			To:       *user.Email,
 			Subject:  "Password Reset Request",
 			TextBody: textBody,
@@ -752,12 +827,7 @@ func (h *Handler) showResetPassword(w http.ResponseWriter, r *http.Request) {
 	// Verify token is valid before showing form
 	_, err := h.passwordResetStore.VerifyToken(r.Context(), token)
 	if err != nil {
-		vm := ResetPasswordVM{
-			BaseVM: viewdata.New(r),
-			Error:  "Invalid or expired reset link. Please request a new one.",
-		}
-		vm.Title = "Reset Password"
-		templates.Render(w, r, "login/reset_password", vm)
+		h.renderResetTokenError(w, r, err)
 		return
 	}
 
@@ -785,13 +855,8 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 	// Verify token
 	reset, err := h.passwordResetStore.VerifyToken(r.Context(), token)
 	if err != nil {
-		h.auditLogger.LogAuthEvent(r, nil, "password_reset_failed", false, "invalid token")
-		vm := ResetPasswordVM{
-			BaseVM: viewdata.New(r),
-			Error:  "Invalid or expired reset link. Please request a new one.",
-		}
-		vm.Title = "Reset Password"
-		templates.Render(w, r, "login/reset_password", vm)
+		h.auditLogger.LogAuthEvent(r, nil, "password_reset_failed", false, err.Error())
+		h.renderResetTokenError(w, r, err)
 		return
 	}
 
@@ -830,7 +895,7 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Hash new password
-	hash, err := authutil.HashPassword(password)
+	hash, err := h.hasher.Hash(password)
 	if err != nil {
 		h.errLog.Log(r, "failed to hash password", err)
 		vm := ResetPasswordVM{
@@ -843,24 +908,35 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Claim the token before changing the password so two concurrent
+	// submissions cannot both use it.
+	if err := h.passwordResetStore.Consume(r.Context(), reset.ID); err != nil {
+		h.auditLogger.LogAuthEvent(r, &reset.UserID, "password_reset_failed", false, err.Error())
+		h.renderResetTokenError(w, r, err)
+		return
+	}
+
 	// Update user password
 	if err := h.userStore.UpdatePassword(r.Context(), reset.UserID, hash); err != nil {
 		h.errLog.Log(r, "failed to update password", err)
 		vm := ResetPasswordVM{
 			BaseVM: viewdata.New(r),
-			Token:  token,
-			Error:  "Failed to reset password. Please try again.",
+			Error:  "Failed to reset password. Please request a new reset link.",
 		}
 		vm.Title = "Reset Password"
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
 		templates.Render(w, r, "login/reset_password", vm)
 		return
 	}
 
-	// Mark reset token as used
-	h.passwordResetStore.MarkUsed(r.Context(), reset.ID)
-
 	h.auditLogger.LogAuthEvent(r, &reset.UserID, "password_reset_completed", true, "")
 
+	// Browsers remembered under the old password must log in again
+	if h.remember != nil {
+		h.remember.ForgetUser(r, reset.UserID)
+	}
+
 	// Send password changed confirmation email
 	if h.mailer != nil {
 		loginURL := h.baseURL + "/login"
@@ -868,7 +944,7 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 			AppName:  h.mailer.FromName(),
 			LoginURL: loginURL,
 		})
-		err = h.mailer.Send(mailer.Email{
+		err = h.mailer.Send(r.Context(), mailer.Email{
 			To:       reset.Email,
 			Subject:  "Your Password Has Been Changed",
 			TextBody: textBody,
@@ -888,6 +964,39 @@ func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
 	templates.Render(w, r, "login/reset_password", vm)
 }
 
+// renderResetTokenError renders the reset page for a token that cannot be
+// used: 410 Gone when it has expired or was already used, 400 when it is
+// unknown, and 500 when the lookup itself failed.
+func (h *Handler) renderResetTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg := resetTokenError(err)
+	if status == http.StatusInternalServerError {
+		h.errLog.Log(r, "failed to verify password reset token", err)
+	}
+
+	vm := ResetPasswordVM{
+		BaseVM: viewdata.New(r),
+		Error:  msg,
+	}
+	vm.Title = "Reset Password"
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	templates.Render(w, r, "login/reset_password", vm)
+}
+
+// resetTokenError maps a passwordreset store error to a status and message.
+func resetTokenError(err error) (int, string) {
+	switch {
+	case errors.Is(err, passwordreset.ErrTokenExpired):
+		return http.StatusGone, "This reset link has expired. Please request a new one."
+	case errors.Is(err, passwordreset.ErrTokenUsed):
+		return http.StatusGone, "This reset link has already been used. Please request a new one."
+	case errors.Is(err, passwordreset.ErrInvalidToken):
+		return http.StatusBadRequest, "Invalid reset link. Please request a new one."
+	default:
+		return http.StatusInternalServerError, "Failed to verify reset link. Please try again."
+	}
+}
+
 // createTrackedSession creates a session in both the cookie and MongoDB for tracking.
 func (h *Handler) createTrackedSession(w http.ResponseWriter, r *http.Request, userID primitive.ObjectID, role string) error {
 	// Generate token first so we can use it for both cookie and MongoDB tracking
@@ -906,7 +1015,7 @@ func (h *Handler) createTrackedSession(w http.ResponseWriter, r *http.Request, u
 	session := sessions.Session{
 		Token:        token,
 		UserID:       userID,
-		IPAddress:    network.GetClientIP(r),
+		IPAddress:    network.ClientIP(r, h.trustedProxies),
 		UserAgent:    r.UserAgent(),
 		LoginAt:      now,
 		LastActivity: now,
@@ -922,3 +1031,74 @@ func (h *Handler) createTrackedSession(w http.ResponseWriter, r *http.Request, u
 
 	return nil
 }
+
+// lockedOut returns how long password attempts for loginID from r's client
+// are refused, or 0. A lockout store that cannot be reached is logged and
+// lets the attempt through, so an outage does not lock everyone out.
+func (h *Handler) lockedOut(r *http.Request, loginID string) time.Duration {
+	if h.lockout == nil {
+		return 0
+	}
+	wait, err := h.lockout.Check(r.Context(), loginID, network.ClientIP(r, h.trustedProxies))
+	if err != nil {
+		h.errLog.Log(r, "failed to check login lockout", err)
+	}
+	return wait
+}
+
+// recordFailure counts a failed password attempt for loginID and r's
+// client, and returns how long the account or IP is now locked, or 0.
+func (h *Handler) recordFailure(r *http.Request, loginID string) time.Duration {
+	if h.lockout == nil {
+		return 0
+	}
+	wait, err := h.lockout.Fail(r.Context(), loginID, network.ClientIP(r, h.trustedProxies))
+	if err != nil {
+		h.errLog.Log(r, "failed to record login failure", err)
+	}
+	return wait
+}
+
+// tooManyAttempts answers a locked-out password attempt with 429 and a
+// Retry-After of wait: the error page when SetErrorHandler was used,
+// otherwise the password form with the time left.
+func (h *Handler) tooManyAttempts(w http.ResponseWriter, r *http.Request, loginID, returnURL string, wait time.Duration) {
+	if h.errors != nil {
+		h.errors.TooManyRequests(w, r, wait)
+		return
+	}
+
+	var errorMsg string
+	if wait > time.Minute {
+		errorMsg = fmt.Sprintf("Too many failed login attempts. Please try again in %d minute(s).", int(wait.Minutes())+1)
+	} else {
+		errorMsg = fmt.Sprintf("Too many failed login attempts. Please try again in %d second(s).", int(wait.Seconds())+1)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	vm := PasswordLoginVM{
+		BaseVM:    viewdata.New(r),
+		Error:     errorMsg,
+		LoginID:   loginID,
+		ReturnURL: returnURL,
+	}
+	h.renderPasswordLogin(w, r, http.StatusTooManyRequests, vm)
+}
+
+// renderPasswordLogin re-renders the password form with status, so failed
+// attempts are distinguishable from a successful page load.
+func (h *Handler) renderPasswordLogin(w http.ResponseWriter, r *http.Request, status int, vm PasswordLoginVM) {
+	vm.Captcha = h.captchaWidget
+	vm.RememberMe = h.remember != nil
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	templates.Render(w, r, "login/password", vm)
+}
+
+// returnFromQuery returns the post-login destination from the "return"
+// query parameter, or from "next" as set by auth.RequireLogin.
+func returnFromQuery(r *http.Request) string {
+	if ret := query.Get(r, "return"); ret != "" {
+		return ret
+	}
+	return query.Get(r, "next")
+}
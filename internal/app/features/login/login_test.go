@@ -1,6 +1,8 @@
 package login
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -8,9 +10,13 @@ import (
 	"testing"
 	"time"
 
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/store/passwordreset"
 	"github.com/dalemusser/strataforge/internal/app/store/ratelimit"
 	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
 	"github.com/dalemusser/strataforge/internal/app/system/authutil"
+	"github.com/dalemusser/strataforge/internal/app/system/captcha"
+	"github.com/dalemusser/strataforge/internal/app/system/lockout"
 	"github.com/dalemusser/strataforge/internal/testutil"
 	"go.uber.org/zap"
 )
@@ -286,6 +292,31 @@ func TestPasswordResetTokenValidation(t *testing.T) {
 	}
 }
 
+func TestResetTokenError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"expired", passwordreset.ErrTokenExpired, http.StatusGone},
+		{"used", passwordreset.ErrTokenUsed, http.StatusGone},
+		{"unknown", passwordreset.ErrInvalidToken, http.StatusBadRequest},
+		{"lookup failure", errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, msg := resetTokenError(tt.err)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if msg == "" {
+				t.Error("message should not be empty")
+			}
+		})
+	}
+}
+
 func TestFormParsing(t *testing.T) {
 	// Test form value extraction
 	form := url.Values{}
@@ -415,6 +446,101 @@ func TestNewHandler(t *testing.T) {
 	}
 }
 
+func TestHandler_PasswordLogin_CaptchaFailed(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	testutil.MustBootTemplates(t)
+
+	h := NewHandler(db, nil, nil, nil, nil, nil, nil, nil, "", 0, false, false, zap.NewNop())
+	h.SetCaptcha(rejectCaptcha{}, captcha.Widget{Provider: captcha.ProviderHCaptcha, SiteKey: "site-key"})
+
+	form := url.Values{}
+	form.Set("login_id", "testuser")
+	form.Set("password", "validpassword123")
+	req := httptest.NewRequest(http.MethodPost, "/login/password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	h.handlePasswordLogin(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(rec.Body.String(), `data-sitekey="site-key"`) {
+		t.Error("re-rendered form has no CAPTCHA widget")
+	}
+}
+
+func TestHandler_PasswordLogin_LockedOut(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	testutil.MustBootTemplates(t)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	l := lockout.New(lockout.NewMemoryStore(), lockout.Config{MaxAttempts: 1, Window: time.Minute, Duration: time.Minute})
+	if locked, _ := l.Fail(ctx, "lockeduser", ""); locked == 0 {
+		t.Fatal("lockout did not lock after one failure")
+	}
+
+	h := NewHandler(db, nil, nil, nil, nil, nil, nil, l, "", 0, false, false, zap.NewNop())
+	h.SetErrorHandler(errorsfeature.NewHandler())
+
+	form := url.Values{}
+	form.Set("login_id", "LockedUser")
+	form.Set("password", "whatever")
+	req := httptest.NewRequest(http.MethodPost, "/login/password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	h.handlePasswordLogin(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want %q", got, "60")
+	}
+}
+
+// rejectCaptcha is a captcha.Verifier that fails every response.
+type rejectCaptcha struct{}
+
+func (rejectCaptcha) Verify(context.Context, string, string) error { return captcha.ErrFailed }
+
+// ipCaptcha is a captcha.Verifier that records the client IP it was given.
+type ipCaptcha struct{ remoteIP *string }
+
+func (c ipCaptcha) Verify(_ context.Context, _, remoteIP string) error {
+	*c.remoteIP = remoteIP
+	return nil
+}
+
+func TestCheckCaptcha_ClientIPFromTrustedProxiesOnly(t *testing.T) {
+	tests := []struct {
+		name, remote, want string
+	}{
+		{"trusted proxy", "10.0.0.1:5000", "203.0.113.9"},
+		{"untrusted peer", "198.51.100.7:5000", "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := &Handler{}
+			h.SetCaptcha(ipCaptcha{&got}, captcha.Widget{})
+			h.SetTrustedProxies("10.0.0.1")
+
+			req := httptest.NewRequest(http.MethodPost, "/login/password", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			if !h.checkCaptcha(req) {
+				t.Fatal("checkCaptcha() = false, want true")
+			}
+			if got != tt.want {
+				t.Errorf("verifier got remote IP %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRoutes_TrustLoginEnabled(t *testing.T) {
 	db := testutil.SetupTestDB(t)
 	logger := zap.NewNop()
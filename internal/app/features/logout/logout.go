@@ -4,6 +4,7 @@ package logout
 import (
 	"net/http"
 
+	rememberfeature "github.com/dalemusser/strataforge/internal/app/features/remember"
 	"github.com/dalemusser/strataforge/internal/app/store/sessions"
 	"github.com/dalemusser/strataforge/internal/app/system/auth"
 	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
@@ -16,6 +17,7 @@ type Handler struct {
 	sessionMgr    *auth.SessionManager
 	auditLogger   *auditlog.Logger
 	sessionsStore *sessions.Store
+	remember      *rememberfeature.Handler // nil if remember-me is off
 	logger        *zap.Logger
 }
 
@@ -34,6 +36,12 @@ func NewHandler(
 	}
 }
 
+// SetRemember revokes the browser's remember-me token on logout, so the
+// next visit does not log the user back in.
+func (h *Handler) SetRemember(rm *rememberfeature.Handler) {
+	h.remember = rm
+}
+
 // Routes returns a chi.Router with logout routes mounted.
 func Routes(h *Handler, sessionMgr *auth.SessionManager) http.Handler {
 	r := chi.NewRouter()
@@ -58,6 +66,9 @@ func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if h.remember != nil {
+		h.remember.Forget(w, r)
+	}
 	h.sessionMgr.DestroySession(w, r)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -0,0 +1,137 @@
+// internal/app/features/metrics/metrics.go
+//
+// Package metrics exposes Prometheus request metrics.
+//
+// Middleware records a strataforge_http_request_duration_seconds histogram,
+// labelled by method, route, and status, and a
+// strataforge_http_requests_in_flight gauge. The route label is the chi
+// route pattern ("/users/{id}"), not the raw path, so the number of series
+// stays bounded however many IDs are requested; requests that match no
+// route share the label "unmatched". Handler serves a registry in the
+// Prometheus text format for scraping at /metrics.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute is the route label for requests no route matched.
+const unmatchedRoute = "unmatched"
+
+// config holds the settings built up by Options.
+type config struct {
+	buckets []float64
+	skip    map[string]struct{}
+}
+
+// Option configures the metrics middleware.
+type Option func(*config)
+
+// WithBuckets sets the histogram buckets, in seconds. The default is
+// prometheus.DefBuckets (5ms to 10s).
+func WithBuckets(buckets ...float64) Option {
+	return func(c *config) {
+		if len(buckets) > 0 {
+			c.buckets = buckets
+		}
+	}
+}
+
+// WithSkipPaths excludes exact request paths, such as health probes and
+// the metrics endpoint itself, from the metrics.
+func WithSkipPaths(paths ...string) Option {
+	return func(c *config) {
+		for _, p := range paths {
+			c.skip[p] = struct{}{}
+		}
+	}
+}
+
+// Middleware returns middleware that records request durations and the
+// number of requests in flight, registering both collectors with reg. If
+// identical collectors are already registered with reg, they are reused,
+// so several routers may share one registry. It panics if reg holds a
+// conflicting collector of the same name.
+func Middleware(reg prometheus.Registerer, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{
+		buckets: prometheus.DefBuckets,
+		skip:    make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	duration := register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "strataforge_http_request_duration_seconds",
+		Help:    "Time taken to serve HTTP requests, by method, route pattern, and status code.",
+		Buckets: cfg.buckets,
+	}, []string{"method", "route", "status"}))
+	inFlight := register(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "strataforge_http_requests_in_flight",
+		Help: "Number of HTTP requests currently being served.",
+	}))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := cfg.skip[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			inFlight.Inc()
+			defer inFlight.Dec()
+			start := time.Now()
+
+			ww := httpx.Wrap(w)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			duration.WithLabelValues(r.Method, route(r), strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// Handler serves the metrics gathered by g in the Prometheus exposition
+// format. Pass the registry given to Middleware.
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+// route returns the chi route pattern matched for r. It must be called
+// after the router has served r.
+func route(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" {
+			return p
+		}
+	}
+	return unmatchedRoute
+}
+
+// register registers c with reg, returning the collector already there if
+// an identical one was registered before.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			panic(err)
+		}
+		existing, ok := are.ExistingCollector.(C)
+		if !ok {
+			panic(err)
+		}
+		return existing
+	}
+	return c
+}
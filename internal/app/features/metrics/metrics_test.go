@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newRouter(reg prometheus.Registerer, opts ...Option) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(Middleware(reg, opts...))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	return r
+}
+
+func serve(h http.Handler, path string) {
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r := newRouter(reg)
+
+	serve(r, "/users/1")
+	serve(r, "/users/2")
+	serve(r, "/nowhere")
+
+	want := `
+# HELP strataforge_http_requests_in_flight Number of HTTP requests currently being served.
+# TYPE strataforge_http_requests_in_flight gauge
+strataforge_http_requests_in_flight 0
+`
+	if err := promtest.GatherAndCompare(reg, strings.NewReader(want), "strataforge_http_requests_in_flight"); err != nil {
+		t.Error(err)
+	}
+
+	if got := promtest.CollectAndCount(reg, "strataforge_http_request_duration_seconds"); got != 2 {
+		t.Errorf("duration series = %d, want 2 (one per route)", got)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]uint64{}
+	for _, mf := range families {
+		if mf.GetName() != "strataforge_http_request_duration_seconds" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := map[string]string{}
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			counts[labels["method"]+" "+labels["route"]+" "+labels["status"]] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	if counts["GET /users/{id} 418"] != 2 {
+		t.Errorf("GET /users/{id} 418 count = %d, want 2; got %v", counts["GET /users/{id} 418"], counts)
+	}
+	if counts["GET unmatched 404"] != 1 {
+		t.Errorf("GET unmatched 404 count = %d, want 1; got %v", counts["GET unmatched 404"], counts)
+	}
+}
+
+func TestMiddleware_SkipPaths(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	r := newRouter(reg, WithSkipPaths("/health"))
+
+	serve(r, "/health")
+
+	if got := promtest.CollectAndCount(reg, "strataforge_http_request_duration_seconds"); got != 0 {
+		t.Errorf("duration series = %d, want 0 for a skipped path", got)
+	}
+}
+
+func TestMiddleware_ReusesRegisteredCollectors(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	serve(newRouter(reg), "/users/1")
+	serve(newRouter(reg), "/users/1")
+
+	if got := promtest.CollectAndCount(reg, "strataforge_http_request_duration_seconds"); got != 1 {
+		t.Errorf("duration series = %d, want 1 shared series", got)
+	}
+}
+
+func TestHandler_ServesRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	serve(newRouter(reg), "/users/1")
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `strataforge_http_request_duration_seconds_count{method="GET",route="/users/{id}",status="418"} 1`) {
+		t.Errorf("body missing duration count:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,420 @@
+// internal/app/features/oauth/oauth.go
+//
+// Package oauth signs users in with an external OAuth2 / OpenID Connect
+// provider ("Sign in with Google").
+//
+// The start route redirects to the provider with a random state and a
+// PKCE challenge. The state is recorded server-side (single use, short
+// lived) and, together with the PKCE verifier and the return URL, in a
+// signed and encrypted cookie, so the callback accepts only the browser
+// that began the flow. The callback exchanges the code, reads the account
+// from the provider, finds the user by verified email (creating one when
+// sign-up is enabled), and establishes a session.
+//
+//	h := oauth.NewHandler(oauth.Google(id, secret, baseURL+"/auth/google/callback"), ...)
+//	r.Mount("/auth/google", oauth.Routes(h))
+package oauth
+
+// Terminology: User Identifiers
+//   - UserID / userID / user_id: The MongoDB ObjectID (_id) that uniquely identifies a user record
+//   - LoginID / loginID / login_id: The human-readable string users type to log in
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/store/sessions"
+	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
+	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/network"
+	"github.com/dalemusser/strataforge/internal/app/system/securecookie"
+	"github.com/dalemusser/strataforge/internal/domain/models"
+	"github.com/dalemusser/waffle/pantry/query"
+	"github.com/dalemusser/waffle/pantry/urlutil"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// flowTTL is how long a user has to finish signing in at the provider.
+const flowTTL = 10 * time.Minute
+
+// StateStore records issued state tokens. Verify must consume the token so
+// each can be used once. oauthstate.Store implements it.
+type StateStore interface {
+	Create(ctx context.Context, state string) error
+	Verify(ctx context.Context, state string) bool
+}
+
+// SessionTracker records signed-in sessions for the activity dashboards.
+// sessions.Store implements it.
+type SessionTracker interface {
+	Create(ctx context.Context, session sessions.Session) error
+}
+
+// flow is what the state cookie carries between start and callback.
+type flow struct {
+	State    string `json:"s"`
+	Verifier string `json:"v"`
+	Return   string `json:"r,omitempty"`
+}
+
+// Handler provides OAuth sign-in handlers for one provider.
+type Handler struct {
+	provider       Provider
+	userStore      userstore.UserStore
+	sessionMgr     *auth.SessionManager
+	errLog         *errorsfeature.ErrorLogger
+	auditLogger    *auditlog.Logger
+	sessionsStore  SessionTracker
+	stateStore     StateStore
+	codec          *securecookie.Codec
+	secure         bool
+	errors         *errorsfeature.Handler
+	signupRole     string
+	trustedProxies []netip.Prefix
+	logger         *zap.Logger
+}
+
+// NewHandler creates an OAuth Handler for provider. cookieKey (such as the
+// session key) signs and encrypts the state cookie; keys for that are
+// derived from it, so it never signs anything outside this package. secure
+// sets the cookie's Secure flag.
+func NewHandler(
+	provider Provider,
+	userStore userstore.UserStore,
+	sessionMgr *auth.SessionManager,
+	errLog *errorsfeature.ErrorLogger,
+	auditLogger *auditlog.Logger,
+	sessionsStore SessionTracker,
+	stateStore StateStore,
+	cookieKey []byte,
+	secure bool,
+	logger *zap.Logger,
+) *Handler {
+	codec, err := securecookie.New([]securecookie.Key{{
+		Hash:  deriveKey(cookieKey, "strataforge/oauth state"),
+		Block: deriveKey(cookieKey, "strataforge/oauth state encryption"),
+	}}, securecookie.WithMaxAge(flowTTL))
+	if err != nil {
+		// Derived keys are always valid sizes; this cannot happen.
+		panic(err)
+	}
+	return &Handler{
+		provider:      provider,
+		userStore:     userStore,
+		sessionMgr:    sessionMgr,
+		errLog:        errLog,
+		auditLogger:   auditLogger,
+		sessionsStore: sessionsStore,
+		stateStore:    stateStore,
+		codec:         codec,
+		secure:        secure,
+		logger:        logger,
+	}
+}
+
+// SetErrorHandler renders failed sign-ins with eh's error pages: 400 for a
+// missing or mismatched state, 403 when the provider or the app refuses the
+// account, 502 when the provider cannot be reached. Without it, failures
+// redirect to the login page with an error code.
+func (h *Handler) SetErrorHandler(eh *errorsfeature.Handler) {
+	h.errors = eh
+}
+
+// SetSignupRole creates an account with role for a verified email no user
+// has yet. By default, as for the other login methods, only existing users
+// may sign in.
+func (h *Handler) SetSignupRole(role string) {
+	h.signupRole = role
+}
+
+// SetTrustedProxies sets the reverse proxies (IPs or CIDR ranges) whose
+// forwarding headers are believed when recording the session's client IP;
+// see network.ClientIP. By default only the direct peer is used.
+func (h *Handler) SetTrustedProxies(entries ...string) {
+	h.trustedProxies = network.ParsePrefixes(entries)
+}
+
+// Routes returns a chi.Router with the start and callback routes mounted.
+func Routes(h *Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", h.start)
+	r.Get("/callback", h.callback)
+	return r
+}
+
+// cookieName is the state cookie for this provider.
+func (h *Handler) cookieName() string {
+	return "oauth_" + h.provider.Name()
+}
+
+// start redirects to the provider's consent page.
+func (h *Handler) start(w http.ResponseWriter, r *http.Request) {
+	state, err := generateState()
+	if err != nil {
+		h.errLog.Log(r, "failed to generate oauth state", err)
+		h.fail(w, r, http.StatusInternalServerError, "oauth_error")
+		return
+	}
+	f := flow{
+		State:    state,
+		Verifier: oauth2.GenerateVerifier(),
+		Return:   returnFromQuery(r),
+	}
+
+	encoded, err := h.codec.Encode(h.cookieName(), f)
+	if err != nil {
+		h.errLog.Log(r, "failed to encode oauth state cookie", err)
+		h.fail(w, r, http.StatusInternalServerError, "oauth_error")
+		return
+	}
+	if err := h.stateStore.Create(r.Context(), state); err != nil {
+		h.errLog.Log(r, "failed to store oauth state", err)
+		h.fail(w, r, http.StatusInternalServerError, "oauth_error")
+		return
+	}
+
+	h.setCookie(w, encoded, int(flowTTL/time.Second))
+	url := h.provider.Config().AuthCodeURL(state, oauth2.S256ChallengeOption(f.Verifier))
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
+
+// callback completes sign-in when the provider redirects back.
+func (h *Handler) callback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	// The state cookie is single use; clear it whatever happens next.
+	f, ok := h.readFlow(r)
+	h.setCookie(w, "", -1)
+
+	state := q.Get("state")
+	if !ok || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(f.State)) != 1 ||
+		!h.stateStore.Verify(ctx, state) {
+		h.logger.Warn("invalid oauth state", zap.String("provider", h.provider.Name()))
+		h.fail(w, r, http.StatusBadRequest, "invalid_state")
+		return
+	}
+
+	// The user declined, or the provider refused the request.
+	if errCode := q.Get("error"); errCode != "" {
+		h.logger.Info("oauth sign-in denied",
+			zap.String("provider", h.provider.Name()),
+			zap.String("error", errCode))
+		h.auditLogger.LogAuthEvent(r, nil, "login_failed_oauth_denied", false, errCode)
+		h.fail(w, r, http.StatusForbidden, "access_denied")
+		return
+	}
+
+	code := q.Get("code")
+	if code == "" {
+		h.fail(w, r, http.StatusBadRequest, "oauth_error")
+		return
+	}
+
+	cfg := h.provider.Config()
+	token, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(f.Verifier))
+	if err != nil {
+		h.errLog.LogStatus(r, http.StatusBadGateway, "oauth token exchange failed", exchangeError(err),
+			zap.String("provider", h.provider.Name()))
+		h.fail(w, r, http.StatusBadGateway, "token_exchange_failed")
+		return
+	}
+
+	info, err := h.provider.UserInfo(ctx, cfg.Client(ctx, token))
+	if err != nil {
+		h.errLog.LogStatus(r, http.StatusBadGateway, "oauth userinfo failed", err,
+			zap.String("provider", h.provider.Name()))
+		h.fail(w, r, http.StatusBadGateway, "userinfo_failed")
+		return
+	}
+	if info.Email == "" || !info.EmailVerified {
+		h.auditLogger.LogAuthEvent(r, nil, "login_failed_email_unverified", false, h.provider.Name())
+		h.fail(w, r, http.StatusForbidden, "email_unverified")
+		return
+	}
+
+	user, err := h.findOrCreateUser(r, info)
+	if errors.Is(err, userstore.ErrNotFound) {
+		h.auditLogger.LoginFailedUserNotFound(ctx, r, info.Email)
+		h.fail(w, r, http.StatusForbidden, "user_not_found")
+		return
+	}
+	if err != nil {
+		h.errLog.Log(r, "failed to find or create oauth user", err)
+		h.fail(w, r, http.StatusInternalServerError, "database_error")
+		return
+	}
+
+	if user.Status != "active" {
+		h.auditLogger.LoginFailedUserDisabled(ctx, r, user.ID, info.Email)
+		h.fail(w, r, http.StatusForbidden, "account_disabled")
+		return
+	}
+
+	if err := h.createTrackedSession(w, r, user.ID, user.Role); err != nil {
+		h.errLog.Log(r, "failed to create session", err)
+		h.fail(w, r, http.StatusInternalServerError, "session_error")
+		return
+	}
+
+	h.auditLogger.LoginSuccess(ctx, r, user.ID, h.provider.Name(), info.Email)
+	http.Redirect(w, r, urlutil.SafeReturn(f.Return, "", "/dashboard"), http.StatusSeeOther)
+}
+
+// findOrCreateUser returns the user with info's email. If there is none, it
+// creates one when sign-up is enabled and otherwise returns
+// userstore.ErrNotFound.
+func (h *Handler) findOrCreateUser(r *http.Request, info *UserInfo) (*models.User, error) {
+	user, err := h.userStore.GetByEmail(r.Context(), info.Email)
+	if !errors.Is(err, userstore.ErrNotFound) || h.signupRole == "" {
+		return user, err
+	}
+
+	name := info.Name
+	if name == "" {
+		name = info.Email
+	}
+	email := info.Email
+	created, err := h.userStore.Create(r.Context(), models.User{
+		FullName:   name,
+		LoginID:    &email,
+		Email:      &email,
+		AuthMethod: h.provider.Name(),
+		Role:       h.signupRole,
+	})
+	if errors.Is(err, userstore.ErrDuplicateLoginID) {
+		// Created by a concurrent sign-in, or the email is another user's login ID.
+		return h.userStore.GetByEmail(r.Context(), info.Email)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.auditLogger.LogAuthEvent(r, &created.ID, "user_created_oauth", true, "")
+	return &created, nil
+}
+
+// readFlow decodes the state cookie.
+func (h *Handler) readFlow(r *http.Request) (flow, bool) {
+	var f flow
+	c, err := r.Cookie(h.cookieName())
+	if err != nil {
+		return f, false
+	}
+	if err := h.codec.Decode(h.cookieName(), c.Value, &f); err != nil {
+		return f, false
+	}
+	return f, true
+}
+
+// setCookie writes the state cookie; maxAge < 0 deletes it. SameSite=Lax
+// lets it ride along on the provider's top-level redirect back.
+func (h *Handler) setCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookieName(),
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// fail renders a failed sign-in: the error page for status when an error
+// handler is set, otherwise a redirect to the login page with code.
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, status int, code string) {
+	if h.errors != nil {
+		h.errors.Error(w, r, status)
+		return
+	}
+	http.Redirect(w, r, "/login?error="+code, http.StatusSeeOther)
+}
+
+// createTrackedSession creates a session in both the cookie and MongoDB for tracking.
+func (h *Handler) createTrackedSession(w http.ResponseWriter, r *http.Request, userID primitive.ObjectID, role string) error {
+	// Generate token first so we can use it for both cookie and MongoDB tracking
+	token, err := auth.GenerateSessionToken()
+	if err != nil {
+		return err
+	}
+
+	// Create the cookie session with the generated token
+	if err := h.sessionMgr.CreateSession(w, r, userID, role, token); err != nil {
+		return err
+	}
+
+	// Store session in MongoDB for tracking
+	now := time.Now()
+	session := sessions.Session{
+		Token:        token,
+		UserID:       userID,
+		IPAddress:    network.ClientIP(r, h.trustedProxies),
+		UserAgent:    r.UserAgent(),
+		LoginAt:      now,
+		LastActivity: now,
+		ExpiresAt:    now.Add(24 * 30 * time.Hour), // 30 days
+	}
+
+	// Best effort - don't fail login if tracking fails
+	if err := h.sessionsStore.Create(r.Context(), session); err != nil {
+		h.logger.Warn("failed to track session", zap.Error(err))
+	}
+
+	return nil
+}
+
+// exchangeError describes a failed token exchange without the token
+// endpoint's response body, which may echo request parameters.
+func exchangeError(err error) error {
+	var re *oauth2.RetrieveError
+	if !errors.As(err, &re) {
+		return err
+	}
+	status := 0
+	if re.Response != nil {
+		status = re.Response.StatusCode
+	}
+	if re.ErrorCode != "" {
+		return fmt.Errorf("oauth2: token endpoint returned %d: %s", status, re.ErrorCode)
+	}
+	return fmt.Errorf("oauth2: token endpoint returned %d", status)
+}
+
+// generateState generates a random state token.
+func generateState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// deriveKey derives a 32-byte key for purpose from secret.
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// returnFromQuery reads the page to return to after sign-in, as the login
+// feature passes it.
+func returnFromQuery(r *http.Request) string {
+	if ret := query.Get(r, "return"); ret != "" {
+		return ret
+	}
+	return query.Get(r, "next")
+}
@@ -0,0 +1,349 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/store/sessions"
+	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/domain/models"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// memStates is an in-memory StateStore.
+type memStates struct {
+	mu     sync.Mutex
+	states map[string]bool
+}
+
+func (s *memStates) Create(_ context.Context, state string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state] = true
+	return nil
+}
+
+func (s *memStates) Verify(_ context.Context, state string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok := s.states[state]
+	delete(s.states, state)
+	return ok
+}
+
+type nopTracker struct{}
+
+func (nopTracker) Create(context.Context, sessions.Session) error { return nil }
+
+// fakeProvider talks to an httptest token endpoint and reports info.
+type fakeProvider struct {
+	config *oauth2.Config
+	info   UserInfo
+}
+
+func (p *fakeProvider) Name() string           { return "fake" }
+func (p *fakeProvider) Config() *oauth2.Config { return p.config }
+func (p *fakeProvider) UserInfo(context.Context, *http.Client) (*UserInfo, error) {
+	info := p.info
+	return &info, nil
+}
+
+type fixture struct {
+	h         *Handler
+	provider  *fakeProvider
+	users     *userstore.MemoryStore
+	exchanges []url.Values // token requests received
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{users: userstore.NewMemoryStore()}
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		f.exchanges = append(f.exchanges, r.PostForm)
+		if r.PostForm.Get("code") != "good-code" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant","error_description":"client_secret=leaked"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	t.Cleanup(tokenServer.Close)
+
+	f.provider = &fakeProvider{
+		config: &oauth2.Config{
+			ClientID:     "client-id",
+			ClientSecret: "client-secret",
+			RedirectURL:  "http://localhost:8080/auth/fake/callback",
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://provider.example/authorize",
+				TokenURL:  tokenServer.URL,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		info: UserInfo{Subject: "123", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"},
+	}
+
+	logger := zap.NewNop()
+	sessionMgr, err := auth.NewSessionManager("test-session-key-for-testing-1234567890", "test-session", "", 24*time.Hour, false, logger)
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+
+	f.h = NewHandler(
+		f.provider,
+		f.users,
+		sessionMgr,
+		errorsfeature.NewErrorLogger(logger),
+		nil, // auditLogger
+		nopTracker{},
+		&memStates{states: make(map[string]bool)},
+		[]byte("test-session-key-for-testing-1234567890"),
+		false,
+		logger,
+	)
+	return f
+}
+
+func (f *fixture) addUser(t *testing.T, email, status string) {
+	t.Helper()
+	if _, err := f.users.Create(context.Background(), models.User{
+		FullName: "Ada", Email: &email, LoginID: &email, AuthMethod: "fake", Role: models.RoleAdmin, Status: status,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// begin runs the start route and returns the state cookie and state.
+func (f *fixture) begin(t *testing.T, target string) (*http.Cookie, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Routes(f.h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("start status = %d, want 307", rec.Code)
+	}
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("start set %d cookies, want 1", len(cookies))
+	}
+	return cookies[0], loc.Query().Get("state")
+}
+
+func (f *fixture) finish(cookie *http.Cookie, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/callback?"+query, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	Routes(f.h).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStart_RedirectsWithStateAndPKCE(t *testing.T) {
+	f := newFixture(t)
+	rec := httptest.NewRecorder()
+	Routes(f.h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	loc, _ := url.Parse(rec.Header().Get("Location"))
+	if loc.Host != "provider.example" {
+		t.Errorf("redirect host = %q, want provider.example", loc.Host)
+	}
+	q := loc.Query()
+	if q.Get("state") == "" {
+		t.Error("redirect has no state")
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("redirect lacks an S256 PKCE challenge: %v", q)
+	}
+
+	c := rec.Result().Cookies()[0]
+	if c.Name != "oauth_fake" || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("state cookie = %+v, want HttpOnly SameSite=Lax oauth_fake", c)
+	}
+	if strings.Contains(c.Value, q.Get("state")) {
+		t.Error("state cookie value is not encrypted")
+	}
+}
+
+func TestCallback_SignsInExistingUser(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, "ada@example.com", "active")
+	cookie, state := f.begin(t, "/?return=/settings")
+
+	rec := f.finish(cookie, "state="+state+"&code=good-code")
+
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/settings" {
+		t.Fatalf("status = %d, Location = %q; want 303 to /settings", rec.Code, rec.Header().Get("Location"))
+	}
+	if len(f.exchanges) != 1 || f.exchanges[0].Get("code_verifier") == "" {
+		t.Errorf("token exchange did not send the PKCE verifier: %v", f.exchanges)
+	}
+}
+
+func TestCallback_UnknownUser(t *testing.T) {
+	t.Run("refused by default", func(t *testing.T) {
+		f := newFixture(t)
+		cookie, state := f.begin(t, "/")
+		rec := f.finish(cookie, "state="+state+"&code=good-code")
+		if !strings.Contains(rec.Header().Get("Location"), "user_not_found") {
+			t.Errorf("Location = %q, want user_not_found", rec.Header().Get("Location"))
+		}
+	})
+
+	t.Run("created with a signup role", func(t *testing.T) {
+		f := newFixture(t)
+		f.h.SetSignupRole(models.RoleDeveloper)
+		cookie, state := f.begin(t, "/")
+
+		rec := f.finish(cookie, "state="+state+"&code=good-code")
+
+		if rec.Header().Get("Location") != "/dashboard" {
+			t.Fatalf("Location = %q, want /dashboard", rec.Header().Get("Location"))
+		}
+		u, err := f.users.GetByEmail(context.Background(), "ada@example.com")
+		if err != nil {
+			t.Fatalf("user not created: %v", err)
+		}
+		if u.Role != models.RoleDeveloper || u.AuthMethod != "fake" || u.FullName != "Ada Lovelace" {
+			t.Errorf("created user = %+v", u)
+		}
+	})
+}
+
+func TestCallback_UnverifiedEmail(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, "ada@example.com", "active")
+	f.provider.info.EmailVerified = false
+	cookie, state := f.begin(t, "/")
+
+	rec := f.finish(cookie, "state="+state+"&code=good-code")
+	if !strings.Contains(rec.Header().Get("Location"), "email_unverified") {
+		t.Errorf("Location = %q, want email_unverified", rec.Header().Get("Location"))
+	}
+}
+
+func TestCallback_DisabledUser(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, "ada@example.com", "disabled")
+	cookie, state := f.begin(t, "/")
+
+	rec := f.finish(cookie, "state="+state+"&code=good-code")
+	if !strings.Contains(rec.Header().Get("Location"), "account_disabled") {
+		t.Errorf("Location = %q, want account_disabled", rec.Header().Get("Location"))
+	}
+}
+
+func TestCallback_InvalidState(t *testing.T) {
+	f := newFixture(t)
+	cookie, state := f.begin(t, "/")
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		query  string
+	}{
+		{"no cookie", nil, "state=" + state + "&code=good-code"},
+		{"mismatched state", cookie, "state=other&code=good-code"},
+		{"no state", cookie, "code=good-code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.finish(tt.cookie, tt.query)
+			if !strings.Contains(rec.Header().Get("Location"), "invalid_state") {
+				t.Errorf("Location = %q, want invalid_state", rec.Header().Get("Location"))
+			}
+		})
+	}
+	if len(f.exchanges) != 0 {
+		t.Error("code was exchanged despite an invalid state")
+	}
+}
+
+func TestCallback_StateIsSingleUse(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, "ada@example.com", "active")
+	cookie, state := f.begin(t, "/")
+
+	f.finish(cookie, "state="+state+"&code=good-code")
+	rec := f.finish(cookie, "state="+state+"&code=good-code")
+	if !strings.Contains(rec.Header().Get("Location"), "invalid_state") {
+		t.Errorf("replayed callback Location = %q, want invalid_state", rec.Header().Get("Location"))
+	}
+}
+
+func TestCallback_DenialRendersErrorPage(t *testing.T) {
+	f := newFixture(t)
+	f.h.SetErrorHandler(errorsfeature.NewHandler())
+	cookie, state := f.begin(t, "/")
+
+	rec := f.finish(cookie, "state="+state+"&error=access_denied")
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestCallback_ExchangeFailure(t *testing.T) {
+	f := newFixture(t)
+	f.h.SetErrorHandler(errorsfeature.NewHandler())
+	cookie, state := f.begin(t, "/")
+
+	rec := f.finish(cookie, "state="+state+"&code=bad-code")
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+}
+
+func TestExchangeError_OmitsResponseBody(t *testing.T) {
+	err := exchangeError(&oauth2.RetrieveError{
+		Response:  &http.Response{StatusCode: http.StatusBadRequest},
+		Body:      []byte(`{"error":"invalid_grant","error_description":"client_secret=leaked"}`),
+		ErrorCode: "invalid_grant",
+	})
+	if strings.Contains(err.Error(), "leaked") {
+		t.Errorf("exchangeError() = %q, leaks the response body", err)
+	}
+	if !strings.Contains(err.Error(), "invalid_grant") || !strings.Contains(err.Error(), "400") {
+		t.Errorf("exchangeError() = %q, want status and error code", err)
+	}
+}
+
+func TestGoogle(t *testing.T) {
+	p := Google("id", "secret", "http://localhost:8080/auth/google/callback")
+	if p.Name() != "google" {
+		t.Errorf("Name() = %q, want google", p.Name())
+	}
+	if p.Config().RedirectURL != "http://localhost:8080/auth/google/callback" {
+		t.Errorf("RedirectURL = %q", p.Config().RedirectURL)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"sub":"42","email":"ada@example.com","email_verified":true,"name":"Ada"}`))
+	}))
+	defer srv.Close()
+	p.(*googleProvider).userInfoURL = srv.URL
+
+	info, err := p.UserInfo(context.Background(), srv.Client())
+	if err != nil {
+		t.Fatalf("UserInfo() error = %v", err)
+	}
+	if info.Subject != "42" || info.Email != "ada@example.com" || !info.EmailVerified || info.Name != "Ada" {
+		t.Errorf("UserInfo() = %+v", info)
+	}
+}
@@ -0,0 +1,118 @@
+// internal/app/features/otel/otel.go
+//
+// Package otel traces HTTP requests with OpenTelemetry.
+//
+// The middleware continues the trace named by an incoming traceparent
+// header, or starts a new one, and opens a server span per request. The
+// span is stored in the request context, so handlers can add child spans
+// and errors.ErrorLogger can tag its lines with trace_id and span_id. When
+// the handler returns, the span is named after the chi route pattern
+// ("GET /users/{id}") and records the method, route, and status; 5xx
+// responses mark it as errored.
+//
+// Spans go to the global tracer provider unless WithTracerProvider is
+// given, so nothing is exported until the application installs one with
+// otel.SetTracerProvider.
+package otel
+
+import (
+	"net/http"
+
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies this instrumentation to the tracer provider.
+const tracerName = "github.com/dalemusser/strataforge/internal/app/features/otel"
+
+// config holds the settings built up by Options.
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	skip       map[string]struct{}
+}
+
+// Option configures the tracing middleware.
+type Option func(*config)
+
+// WithTracerProvider sets the provider spans are created from. The
+// default is the global provider; tests pass a no-op or recording one.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		if tp != nil {
+			c.provider = tp
+		}
+	}
+}
+
+// WithPropagator sets how the parent trace is read from request headers.
+// The default reads W3C traceparent/tracestate and baggage.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		if p != nil {
+			c.propagator = p
+		}
+	}
+}
+
+// WithSkipPaths excludes exact request paths, such as health probes, from
+// tracing.
+func WithSkipPaths(paths ...string) Option {
+	return func(c *config) {
+		for _, p := range paths {
+			c.skip[p] = struct{}{}
+		}
+	}
+}
+
+// Middleware returns middleware that starts a server span per request.
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		skip:       make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := cfg.provider.Tracer(tracerName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := cfg.skip[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := cfg.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)),
+			)
+			defer span.End()
+
+			ww := httpx.Wrap(w)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				if route := rctx.RoutePattern(); route != "" {
+					span.SetName(r.Method + " " + route)
+					span.SetAttributes(semconv.HTTPRoute(route))
+				}
+			}
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package otel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// newTracedRouter returns a router traced into a span recorder, with a
+// route that answers with the given status.
+func newTracedRouter(status int, opts ...Option) (http.Handler, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	r := chi.NewRouter()
+	r.Use(Middleware(append([]Option{WithTracerProvider(tp)}, opts...)...))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			http.Error(w, "no span in context", http.StatusTeapot)
+			return
+		}
+		w.WriteHeader(status)
+	})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	return r, rec
+}
+
+func attrs(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value)
+	for _, kv := range s.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestMiddleware_RecordsSpan(t *testing.T) {
+	h, rec := newTracedRouter(http.StatusOK)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (span must be in the handler's context)", w.Code)
+	}
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	s := spans[0]
+	if s.Name() != "GET /users/{id}" {
+		t.Errorf("span name = %q", s.Name())
+	}
+	if s.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", s.SpanKind())
+	}
+	a := attrs(s)
+	if a["http.request.method"].AsString() != "GET" {
+		t.Errorf("http.request.method = %v", a["http.request.method"])
+	}
+	if a["http.route"].AsString() != "/users/{id}" {
+		t.Errorf("http.route = %v", a["http.route"])
+	}
+	if a["http.response.status_code"].AsInt64() != 200 {
+		t.Errorf("http.response.status_code = %v", a["http.response.status_code"])
+	}
+	if s.Status().Code == codes.Error {
+		t.Error("a 200 response should not mark the span as errored")
+	}
+}
+
+func TestMiddleware_ServerErrorMarksSpan(t *testing.T) {
+	h, rec := newTracedRouter(http.StatusBadGateway)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("span status = %v, want Error", spans[0].Status())
+	}
+}
+
+func TestMiddleware_PropagatesTraceparent(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	h, rec := newTracedRouter(http.StatusOK)
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("trace id = %s, want %s", got, traceID)
+	}
+	if got := spans[0].Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span id = %s", got)
+	}
+}
+
+func TestMiddleware_SkipPaths(t *testing.T) {
+	h, rec := newTracedRouter(http.StatusOK, WithSkipPaths("/health"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if n := len(rec.Ended()); n != 0 {
+		t.Errorf("recorded %d spans for a skipped path, want 0", n)
+	}
+}
+
+func TestMiddleware_NoopProvider(t *testing.T) {
+	h := Middleware(WithTracerProvider(noop.NewTracerProvider()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/items", nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want 201", w.Code)
+	}
+}
@@ -0,0 +1,216 @@
+// internal/app/features/ratelimit/ratelimit.go
+//
+// Package ratelimit throttles clients with a per-key token bucket.
+//
+// Each key (by default the client IP) gets a bucket holding up to burst
+// tokens that refills at rate tokens per second. Every request takes one
+// token; when the bucket is empty the request is answered with 429 Too Many
+// Requests and a Retry-After header giving the time until the next token.
+//
+// Buckets live in a Store. The default MemoryStore keeps them in process,
+// which is fine for a single instance; multi-instance deployments can supply
+// a shared Store (e.g. Redis) with WithStore.
+//
+// Usage:
+//
+//	r.Use(ratelimit.Middleware(5, 20, nil,
+//		ratelimit.WithErrorHandler(errorsHandler),
+//	))
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/network"
+	"go.uber.org/zap"
+)
+
+// Store holds token buckets. Take removes one token from the bucket for key,
+// creating a full bucket if none exists. It reports whether a token was
+// available and, if not, how long until one will be.
+//
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// DefaultIdleTTL is how long MemoryStore keeps a bucket nobody has used.
+const DefaultIdleTTL = 10 * time.Minute
+
+// MemoryStore is an in-process Store. Idle buckets are swept lazily during
+// Take, so it needs no background goroutine.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	ttl       time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// bucket is the state of one key's token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryStore creates a MemoryStore that forgets buckets idle for longer
+// than ttl. A non-positive ttl uses DefaultIdleTTL.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultIdleTTL
+	}
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(_ context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	if rate <= 0 {
+		return false, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// Len returns the number of buckets currently tracked.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// sweep drops idle buckets, at most once per ttl. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > s.ttl {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// ClientIPKey returns a key function that buckets requests by client IP,
+// believing X-Forwarded-For and X-Real-IP only from trustedProxies (see
+// network.ClientIP).
+func ClientIPKey(trustedProxies []netip.Prefix) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return network.ClientIP(r, trustedProxies)
+	}
+}
+
+// config holds the settings built up by Options.
+type config struct {
+	store          Store
+	errors         *errorsfeature.Handler
+	logger         *zap.Logger
+	trustedProxies []netip.Prefix
+}
+
+// Option configures the rate limit middleware.
+type Option func(*config)
+
+// WithStore keeps buckets in store instead of a new MemoryStore.
+func WithStore(store Store) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// WithErrorHandler renders limited requests with h.TooManyRequests. Without
+// it, they get a plain-text 429.
+func WithErrorHandler(h *errorsfeature.Handler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// WithTrustedProxies sets the reverse proxies (IPs or CIDR ranges) whose
+// forwarding headers are believed by the default key function. Without it,
+// every request is keyed by the connecting peer's address. It has no effect
+// when a key function is passed to Middleware.
+func WithTrustedProxies(entries ...string) Option {
+	return func(c *config) {
+		c.trustedProxies = network.ParsePrefixes(entries)
+	}
+}
+
+// WithLogger logs store failures as warnings.
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// Middleware limits each key to rate requests per second with bursts of up
+// to burst requests. keyFn picks the bucket for a request; nil uses the
+// client IP, as ClientIPKey does with the WithTrustedProxies setting.
+// If the store fails, the request is let through rather than blocking every
+// client on an outage.
+func Middleware(rate float64, burst int, keyFn func(*http.Request) string, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if keyFn == nil {
+		keyFn = ClientIPKey(cfg.trustedProxies)
+	}
+	if cfg.store == nil {
+		cfg.store = NewMemoryStore(DefaultIdleTTL)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, retryAfter, err := cfg.store.Take(r.Context(), keyFn(r), rate, burst)
+			if err != nil {
+				cfg.logger.Warn("rate limit store failed", zap.Error(err), zap.String("path", r.URL.Path))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if cfg.errors != nil {
+				cfg.errors.TooManyRequests(w, r, retryAfter)
+				return
+			}
+			if seconds := int(math.Ceil(retryAfter.Seconds())); seconds > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			}
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore(time.Hour)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := s.Take(ctx, "a", 1, 2); !ok {
+			t.Fatalf("take %d denied, want allowed within burst", i)
+		}
+	}
+	ok, wait, _ := s.Take(ctx, "a", 1, 2)
+	if ok {
+		t.Fatal("take allowed, want denied once burst is spent")
+	}
+	if wait != time.Second {
+		t.Errorf("retryAfter = %v, want %v", wait, time.Second)
+	}
+
+	if ok, _, _ := s.Take(ctx, "b", 1, 2); !ok {
+		t.Error("other key denied, want its own bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if _, wait, _ := s.Take(ctx, "a", 1, 2); wait != 500*time.Millisecond {
+		t.Errorf("retryAfter after partial refill = %v, want %v", wait, 500*time.Millisecond)
+	}
+
+	now = now.Add(time.Second)
+	if ok, _, _ := s.Take(ctx, "a", 1, 2); !ok {
+		t.Error("take denied after refill, want allowed")
+	}
+}
+
+func TestMemoryStore_SweepsIdleBuckets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore(time.Minute)
+	s.now = func() time.Time { return now }
+
+	s.Take(context.Background(), "a", 1, 1)
+	now = now.Add(2 * time.Minute)
+	s.Take(context.Background(), "b", 1, 1)
+
+	if got := s.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+}
+
+func TestMiddleware_TrustedProxies(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := Middleware(0.5, 1, nil, WithTrustedProxies("10.0.0.0/8"))(next)
+
+	serve := func(remoteAddr, xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Clients behind the trusted proxy get their own buckets.
+	if got := serve("10.0.0.1:1234", "203.0.113.7"); got != http.StatusOK {
+		t.Fatalf("client A status = %d, want %d", got, http.StatusOK)
+	}
+	if got := serve("10.0.0.1:1234", "203.0.113.8"); got != http.StatusOK {
+		t.Fatalf("client B status = %d, want %d", got, http.StatusOK)
+	}
+
+	// An untrusted peer cannot escape its bucket by rotating the header.
+	if got := serve("198.51.100.1:1234", "1.1.1.1"); got != http.StatusOK {
+		t.Fatalf("first spoofed request status = %d, want %d", got, http.StatusOK)
+	}
+	if got := serve("198.51.100.1:1234", "2.2.2.2"); got != http.StatusTooManyRequests {
+		t.Errorf("second spoofed request status = %d, want %d", got, http.StatusTooManyRequests)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := Middleware(0.5, 1, nil, WithErrorHandler(errorsfeature.NewHandler()))(next)
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/json")
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("198.51.100.1:1234"); rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	rec := serve("198.51.100.1:5678")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want %q", got, "2")
+	}
+	if rec := serve("198.51.100.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("other client status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMiddleware_PlainTextWithoutErrorHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	keyFn := func(*http.Request) string { return "all" }
+	mw := Middleware(1, 1, keyFn)(next)
+
+	mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want %q", got, "1")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, float64, int) (bool, time.Duration, error) {
+	return false, 0, errors.New("unavailable")
+}
+
+func TestMiddleware_FailsOpen(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := Middleware(1, 1, nil, WithStore(failingStore{}))(next)
+
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,253 @@
+// internal/app/features/remember/remember.go
+//
+// Package remember keeps users logged in across browser restarts.
+//
+// When a user ticks "Remember me" at login, Issue sets a long-lived cookie
+// holding a remember-me token (see store/remember). Middleware, installed
+// before auth.LoadSessionUser, turns that cookie back into a session on a
+// request that has none, and replaces the token's validator each time.
+// A cookie whose selector is known but whose validator is stale means it was
+// copied and used elsewhere: the series is revoked and a
+// "remember_token_theft" audit event is written.
+//
+// Usage:
+//
+//	rm := remember.NewHandler(store, sessionMgr, db, sessionsStore, auditLogger, errLog, cookieOpts, logger)
+//	r.Use(rm.Middleware)
+//	r.Use(sessionMgr.LoadSessionUser)
+//	loginHandler.SetRemember(rm)
+//	logoutHandler.SetRemember(rm)
+package remember
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	rememberstore "github.com/dalemusser/strataforge/internal/app/store/remember"
+	"github.com/dalemusser/strataforge/internal/app/store/sessions"
+	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
+	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/network"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// CookieName is the default name of the remember-me cookie.
+const CookieName = "strataforge-remember"
+
+// CookieOptions configures the remember-me cookie. Its lifetime is the
+// store's MaxAge.
+type CookieOptions struct {
+	Name   string // defaults to CookieName
+	Domain string // empty means current host
+	Secure bool   // set for HTTPS production
+}
+
+// Handler issues, restores, and forgets remember-me tokens.
+type Handler struct {
+	store         *rememberstore.Store
+	sessionMgr    *auth.SessionManager
+	userStore     userstore.UserStore
+	sessionsStore *sessions.Store
+	auditLogger   *auditlog.Logger
+	errLog        *errorsfeature.ErrorLogger
+	cookie        CookieOptions
+	logger        *zap.Logger
+
+	trustedProxies []netip.Prefix
+}
+
+// NewHandler creates a remember-me Handler keeping tokens in store.
+func NewHandler(
+	store *rememberstore.Store,
+	sessionMgr *auth.SessionManager,
+	db *mongo.Database,
+	sessionsStore *sessions.Store,
+	auditLogger *auditlog.Logger,
+	errLog *errorsfeature.ErrorLogger,
+	cookie CookieOptions,
+	logger *zap.Logger,
+) *Handler {
+	if cookie.Name == "" {
+		cookie.Name = CookieName
+	}
+	return &Handler{
+		store:         store,
+		sessionMgr:    sessionMgr,
+		userStore:     userstore.New(db),
+		sessionsStore: sessionsStore,
+		auditLogger:   auditLogger,
+		errLog:        errLog,
+		cookie:        cookie,
+		logger:        logger,
+	}
+}
+
+// SetUserStore replaces the MongoDB user store used to load remembered
+// users. Give it the same store as the login handler.
+func (h *Handler) SetUserStore(s userstore.UserStore) {
+	h.userStore = s
+}
+
+// SetTrustedProxies sets the reverse proxies (IPs or CIDR ranges) whose
+// forwarding headers are believed when the client IP is recorded on tokens
+// and sessions; see network.ClientIP. By default only the direct peer is
+// used.
+func (h *Handler) SetTrustedProxies(entries ...string) {
+	h.trustedProxies = network.ParsePrefixes(entries)
+}
+
+// Middleware restores the session of a request that has no signed-in
+// session but carries a valid remember-me cookie. It must run before
+// auth.LoadSessionUser so the restored user is loaded for this request.
+// Any problem with the cookie clears it and lets the request through
+// signed out.
+func (h *Handler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(h.cookie.Name)
+		if err != nil || c.Value == "" || h.sessionMgr.IsAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.restore(w, r, c.Value)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// restore re-establishes the session for cookieValue, or clears the cookie.
+func (h *Handler) restore(w http.ResponseWriter, r *http.Request, cookieValue string) {
+	tok, err := h.store.Use(r.Context(), cookieValue)
+	switch {
+	case errors.Is(err, rememberstore.ErrTokenTheft):
+		h.auditLogger.LogAuthEvent(r, &tok.UserID, "remember_token_theft", false, "validator mismatch; series revoked")
+		h.clearCookie(w)
+		return
+	case errors.Is(err, rememberstore.ErrInvalidToken):
+		h.clearCookie(w)
+		return
+	case err != nil:
+		// Keep the cookie; the store may be back on the next request.
+		h.errLog.Log(r, "failed to check remember-me token", err)
+		return
+	}
+
+	user, err := h.userStore.GetByID(r.Context(), tok.UserID)
+	if err != nil || user.Status != "active" {
+		if err != nil && !errors.Is(err, userstore.ErrNotFound) {
+			h.errLog.Log(r, "failed to load remembered user", err)
+			return
+		}
+		// Deleted or disabled: the series is of no further use.
+		if err := h.store.Delete(r.Context(), tok.Selector); err != nil {
+			h.errLog.Log(r, "failed to delete remember-me token", err)
+		}
+		h.clearCookie(w)
+		return
+	}
+
+	if err := h.createTrackedSession(w, r, user.ID, user.Role); err != nil {
+		h.errLog.Log(r, "failed to restore remembered session", err)
+		return
+	}
+	// An empty validator means a concurrent request rotated the token and is
+	// writing the new cookie.
+	if tok.Validator != "" {
+		h.setCookie(w, tok)
+	}
+	h.auditLogger.LogAuthEvent(r, &user.ID, "login_remembered", true, "")
+}
+
+// Issue starts a remember-me series for userID and sets its cookie. Call it
+// after a successful login on which the user asked to be remembered.
+func (h *Handler) Issue(w http.ResponseWriter, r *http.Request, userID primitive.ObjectID) error {
+	tok, err := h.store.Issue(r.Context(), userID, network.ClientIP(r, h.trustedProxies), r.UserAgent())
+	if err != nil {
+		return err
+	}
+	h.setCookie(w, tok)
+	return nil
+}
+
+// Forget revokes the request's remember-me series, if any, and clears its
+// cookie. Logout calls it.
+func (h *Handler) Forget(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(h.cookie.Name); err == nil && c.Value != "" {
+		if err := h.store.DeleteByCookie(r.Context(), c.Value); err != nil {
+			h.errLog.Log(r, "failed to delete remember-me token", err)
+		}
+	}
+	h.clearCookie(w)
+}
+
+// ForgetUser revokes every remember-me series for userID, as after a
+// password reset, so a stolen cookie does not outlive the old password.
+func (h *Handler) ForgetUser(r *http.Request, userID primitive.ObjectID) {
+	if err := h.store.DeleteByUser(r.Context(), userID); err != nil {
+		h.errLog.Log(r, "failed to delete remember-me tokens", err)
+	}
+}
+
+func (h *Handler) setCookie(w http.ResponseWriter, tok *rememberstore.Token) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookie.Name,
+		Value:    tok.CookieValue(),
+		Path:     "/",
+		Domain:   h.cookie.Domain,
+		Expires:  tok.ExpiresAt,
+		MaxAge:   int(time.Until(tok.ExpiresAt).Seconds()),
+		Secure:   h.cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *Handler) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookie.Name,
+		Value:    "",
+		Path:     "/",
+		Domain:   h.cookie.Domain,
+		MaxAge:   -1,
+		Secure:   h.cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// createTrackedSession creates a session in both the cookie and MongoDB for tracking.
+func (h *Handler) createTrackedSession(w http.ResponseWriter, r *http.Request, userID primitive.ObjectID, role string) error {
+	// Generate token first so we can use it for both cookie and MongoDB tracking
+	token, err := auth.GenerateSessionToken()
+	if err != nil {
+		return err
+	}
+
+	// Create the cookie session with the generated token
+	if err := h.sessionMgr.CreateSession(w, r, userID, role, token); err != nil {
+		return err
+	}
+
+	// Store session in MongoDB for tracking
+	now := time.Now()
+	session := sessions.Session{
+		Token:        token,
+		UserID:       userID,
+		IPAddress:    network.ClientIP(r, h.trustedProxies),
+		UserAgent:    r.UserAgent(),
+		LoginAt:      now,
+		LastActivity: now,
+		ExpiresAt:    now.Add(24 * 30 * time.Hour), // 30 days
+	}
+
+	// Best effort - don't fail the request if tracking fails
+	if err := h.sessionsStore.Create(r.Context(), session); err != nil {
+		h.logger.Warn("failed to track session", zap.Error(err))
+	}
+
+	return nil
+}
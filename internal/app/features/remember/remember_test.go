@@ -0,0 +1,199 @@
+package remember
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	rememberstore "github.com/dalemusser/strataforge/internal/app/store/remember"
+	"github.com/dalemusser/strataforge/internal/app/store/sessions"
+	userstore "github.com/dalemusser/strataforge/internal/app/store/users"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func newTestHandler(t *testing.T) (*Handler, *rememberstore.Store, *auth.SessionManager, primitive.ObjectID) {
+	t.Helper()
+	db := testutil.SetupTestDB(t)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+	logger := zap.NewNop()
+
+	sessionMgr, err := auth.NewSessionManager("test-session-key-for-testing-1234567890", "test-session", "", 24*time.Hour, false, logger)
+	if err != nil {
+		t.Fatalf("failed to create session manager: %v", err)
+	}
+
+	user, err := userstore.New(db).CreateFromInput(ctx, userstore.CreateInput{
+		FullName:   "Remembered User",
+		LoginID:    "remembered",
+		AuthMethod: "password",
+		Role:       "admin",
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	store := rememberstore.New(db, time.Hour)
+	// auditLogger can be nil - it's nil-safe
+	h := NewHandler(store, sessionMgr, db, sessions.New(db), nil, errorsfeature.NewErrorLogger(logger), CookieOptions{}, logger)
+	return h, store, sessionMgr, user.ID
+}
+
+// serve runs req through the middleware and reports whether the next
+// handler saw a signed-in session.
+func serve(h *Handler, sessionMgr *auth.SessionManager, req *http.Request) (*httptest.ResponseRecorder, bool) {
+	var signedIn bool
+	next := sessionMgr.LoadSessionUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, signedIn = auth.CurrentUser(r)
+	}))
+	rec := httptest.NewRecorder()
+	h.Middleware(next).ServeHTTP(rec, req)
+	return rec, signedIn
+}
+
+func rememberCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == CookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestMiddleware_RestoresSessionAndRotates(t *testing.T) {
+	h, store, sessionMgr, userID := newTestHandler(t)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	tok, err := store.Issue(ctx, userID, "", "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: tok.CookieValue()})
+	rec, signedIn := serve(h, sessionMgr, req)
+
+	if !signedIn {
+		t.Fatal("session not restored from remember-me cookie")
+	}
+	c := rememberCookie(rec)
+	if c == nil || c.Value == tok.CookieValue() || c.MaxAge <= 0 {
+		t.Errorf("remember cookie = %+v, want a rotated value", c)
+	}
+	if c != nil && !c.HttpOnly {
+		t.Error("remember cookie is not HttpOnly")
+	}
+}
+
+func TestMiddleware_StolenCookieClearsAndRevokes(t *testing.T) {
+	h, store, sessionMgr, userID := newTestHandler(t)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	tok, err := store.Issue(ctx, userID, "", "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	stolen := tok.Selector + ":not-the-validator"
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: stolen})
+	rec, signedIn := serve(h, sessionMgr, req)
+
+	if signedIn {
+		t.Error("signed in with a mismatched validator")
+	}
+	if c := rememberCookie(rec); c == nil || c.MaxAge >= 0 {
+		t.Errorf("remember cookie = %+v, want it cleared", c)
+	}
+	// The genuine cookie no longer works either
+	if _, err := store.Use(ctx, tok.CookieValue()); err != rememberstore.ErrInvalidToken {
+		t.Errorf("Use() after theft error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestMiddleware_NoCookie(t *testing.T) {
+	h, _, sessionMgr, _ := newTestHandler(t)
+
+	rec, signedIn := serve(h, sessionMgr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if signedIn {
+		t.Error("signed in without a cookie")
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Errorf("cookies set = %v, want none", rec.Result().Cookies())
+	}
+}
+
+func TestForget(t *testing.T) {
+	h, store, _, userID := newTestHandler(t)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	tok, err := store.Issue(ctx, userID, "", "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: tok.CookieValue()})
+	rec := httptest.NewRecorder()
+	h.Forget(rec, req)
+
+	if c := rememberCookie(rec); c == nil || c.MaxAge >= 0 {
+		t.Errorf("remember cookie = %+v, want it cleared", c)
+	}
+	if _, err := store.Use(ctx, tok.CookieValue()); err != rememberstore.ErrInvalidToken {
+		t.Errorf("Use() after Forget error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestClientIP_IgnoresSpoofedForwardedFor(t *testing.T) {
+	h, store, sessionMgr, userID := newTestHandler(t)
+	h.SetTrustedProxies("10.0.0.0/8")
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	spoofed := func(req *http.Request) *http.Request {
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.Header.Set("X-Forwarded-For", "198.51.100.9")
+		return req
+	}
+
+	// Issue records the peer on the token.
+	rec := httptest.NewRecorder()
+	if err := h.Issue(rec, spoofed(httptest.NewRequest(http.MethodPost, "/login", nil)), userID); err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	c := rememberCookie(rec)
+	if c == nil {
+		t.Fatal("Issue() set no remember cookie")
+	}
+	tok, err := store.Use(ctx, c.Value)
+	if err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if tok.IPAddress != "203.0.113.7" {
+		t.Errorf("token IP = %q, want the peer 203.0.113.7", tok.IPAddress)
+	}
+
+	// A restored session records the peer too.
+	req := spoofed(httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: tok.CookieValue()})
+	if _, signedIn := serve(h, sessionMgr, req); !signedIn {
+		t.Fatal("session not restored from remember-me cookie")
+	}
+	tracked, err := h.sessionsStore.ListByUser(ctx, userID)
+	if err != nil || len(tracked) != 1 {
+		t.Fatalf("ListByUser() = %d sessions, %v; want 1", len(tracked), err)
+	}
+	if tracked[0].IPAddress != "203.0.113.7" {
+		t.Errorf("session IP = %q, want the peer 203.0.113.7", tracked[0].IPAddress)
+	}
+}
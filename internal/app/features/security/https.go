@@ -0,0 +1,104 @@
+// internal/app/features/security/https.go
+package security
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures ForceHTTPS.
+type Options struct {
+	// TrustXForwardedProto treats a request as secure when X-Forwarded-Proto
+	// says "https". Enable it only behind a proxy that terminates TLS and
+	// overwrites the header; otherwise clients can set it themselves.
+	TrustXForwardedProto bool
+
+	// HSTSMaxAge is the Strict-Transport-Security max-age sent on secure
+	// responses. Zero sends no HSTS header.
+	HSTSMaxAge time.Duration
+
+	// IncludeSubdomains adds includeSubDomains to the HSTS header.
+	IncludeSubdomains bool
+
+	// Preload adds the preload directive. Only set it once the domain has
+	// been submitted to the browsers' preload list.
+	Preload bool
+
+	// ExemptPaths are exact paths served over plain HTTP without a redirect,
+	// such as health checks probed directly by a load balancer.
+	ExemptPaths []string
+}
+
+// ForceHTTPS returns middleware that redirects plain-HTTP requests to the
+// same URL over HTTPS and sets Strict-Transport-Security on secure ones.
+// GET and HEAD requests get 301 Moved Permanently; other methods get
+// 308 Permanent Redirect so the client repeats the method and body. The
+// redirect drops any port from the Host, so HTTPS is expected on 443.
+func ForceHTTPS(opts Options) func(http.Handler) http.Handler {
+	exempt := make(map[string]struct{}, len(opts.ExemptPaths))
+	for _, p := range opts.ExemptPaths {
+		exempt[p] = struct{}{}
+	}
+	hsts := hstsValue(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isSecure(r, opts.TrustXForwardedProto) {
+				if hsts != "" {
+					w.Header().Set("Strict-Transport-Security", hsts)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := exempt[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+				if strings.Contains(host, ":") {
+					host = "[" + host + "]"
+				}
+			}
+			code := http.StatusMovedPermanently
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				code = http.StatusPermanentRedirect
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+		})
+	}
+}
+
+// isSecure reports whether the request reached the client over TLS.
+func isSecure(r *http.Request, trustProto bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if !trustProto {
+		return false
+	}
+	// A chain of proxies may append values; the first is the client's.
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// hstsValue builds the Strict-Transport-Security header, or "" when HSTS
+// is off.
+func hstsValue(opts Options) string {
+	if opts.HSTSMaxAge <= 0 {
+		return ""
+	}
+	v := "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge/time.Second), 10)
+	if opts.IncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if opts.Preload {
+		v += "; preload"
+	}
+	return v
+}
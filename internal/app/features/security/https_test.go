@@ -0,0 +1,92 @@
+package security
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestForceHTTPS_Redirects(t *testing.T) {
+	mw := ForceHTTPS(Options{HSTSMaxAge: time.Hour})
+
+	tests := []struct {
+		method, host, target string
+		wantCode             int
+		wantLocation         string
+	}{
+		{http.MethodGet, "example.com", "/reports?page=2", http.StatusMovedPermanently, "https://example.com/reports?page=2"},
+		{http.MethodHead, "example.com:8080", "/", http.StatusMovedPermanently, "https://example.com/"},
+		{http.MethodPost, "example.com", "/login", http.StatusPermanentRedirect, "https://example.com/login"},
+		{http.MethodGet, "[::1]:8080", "/", http.StatusMovedPermanently, "https://[::1]/"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		mw(ok).ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s %s%s: status = %d, want %d", tt.method, tt.host, tt.target, rec.Code, tt.wantCode)
+		}
+		if got := rec.Header().Get("Location"); got != tt.wantLocation {
+			t.Errorf("%s %s%s: Location = %q, want %q", tt.method, tt.host, tt.target, got, tt.wantLocation)
+		}
+		if rec.Header().Get("Strict-Transport-Security") != "" {
+			t.Error("HSTS must not be sent over plain HTTP")
+		}
+	}
+}
+
+func TestForceHTTPS_SecureRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		setup    func(*http.Request)
+		wantCode int
+		wantHSTS string
+	}{
+		{"direct TLS", Options{HSTSMaxAge: 365 * 24 * time.Hour, IncludeSubdomains: true},
+			func(r *http.Request) { r.TLS = &tls.ConnectionState{} },
+			http.StatusOK, "max-age=31536000; includeSubDomains"},
+		{"trusted forwarded proto", Options{TrustXForwardedProto: true, HSTSMaxAge: time.Hour, Preload: true},
+			func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "HTTPS, http") },
+			http.StatusOK, "max-age=3600; preload"},
+		{"untrusted forwarded proto", Options{HSTSMaxAge: time.Hour},
+			func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") },
+			http.StatusMovedPermanently, ""},
+		{"forwarded http", Options{TrustXForwardedProto: true, HSTSMaxAge: time.Hour},
+			func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "http") },
+			http.StatusMovedPermanently, ""},
+		{"HSTS off", Options{},
+			func(r *http.Request) { r.TLS = &tls.ConnectionState{} },
+			http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			tt.setup(req)
+			rec := httptest.NewRecorder()
+			ForceHTTPS(tt.opts)(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.wantHSTS {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.wantHSTS)
+			}
+		})
+	}
+}
+
+func TestForceHTTPS_ExemptPaths(t *testing.T) {
+	mw := ForceHTTPS(Options{ExemptPaths: []string{"/health"}})
+	for path, want := range map[string]int{"/health": http.StatusOK, "/health/db": http.StatusMovedPermanently} {
+		rec := httptest.NewRecorder()
+		mw(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// internal/app/features/security/ips.go
+package security
+
+import (
+	"net/http"
+	"net/netip"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/network"
+)
+
+// AllowedIPs returns middleware that answers 403 Forbidden, through
+// h.Forbidden (or a plain-text 403 when h is nil), unless the client IP is
+// within one of entries: IPs or CIDR ranges such as "10.0.0.5" or
+// "10.0.0.0/8". Unparseable entries are ignored, and with no valid entries
+// every request is refused, so a typo never opens the route.
+//
+// The client IP is found with network.ClientIP, believing X-Forwarded-For
+// and X-Real-IP only from trustedProxies.
+func AllowedIPs(h *errorsfeature.Handler, trustedProxies []string, entries ...string) func(http.Handler) http.Handler {
+	allow := network.ParsePrefixes(entries)
+	trusted := network.ParsePrefixes(trustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ipAllowed(network.ClientIP(r, trusted), allow) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if h != nil {
+				h.Forbidden(w, r)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+}
+
+// ipAllowed reports whether ip is within one of allow.
+func ipAllowed(ip string, allow []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
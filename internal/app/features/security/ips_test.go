@@ -0,0 +1,63 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+func TestAllowedIPs(t *testing.T) {
+	mw := AllowedIPs(nil, []string{"10.0.0.1"}, "127.0.0.1", "::1", "192.168.0.0/16", "not-an-ip")
+
+	tests := []struct {
+		name, remote, xff string
+		want              int
+	}{
+		{"loopback", "127.0.0.1:5000", "", http.StatusOK},
+		{"ipv6 loopback", "[::1]:5000", "", http.StatusOK},
+		{"in range", "192.168.4.2:5000", "", http.StatusOK},
+		{"outside", "203.0.113.9:5000", "", http.StatusForbidden},
+		{"spoofed forwarded header", "203.0.113.9:5000", "127.0.0.1", http.StatusForbidden},
+		{"through trusted proxy", "10.0.0.1:5000", "192.168.4.2", http.StatusOK},
+		{"outside through trusted proxy", "10.0.0.1:5000", "203.0.113.9", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			rec := httptest.NewRecorder()
+			mw(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowedIPs_NoEntriesRefusesAll(t *testing.T) {
+	mw := AllowedIPs(nil, nil, "", "bogus")
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:5000"
+	rec := httptest.NewRecorder()
+	mw(ok).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestAllowedIPs_ErrorPage(t *testing.T) {
+	mw := AllowedIPs(errorsfeature.NewHandler(), nil, "127.0.0.1")
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "203.0.113.9:5000"
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	mw(ok).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,83 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func status(mw func(http.Handler) http.Handler, host string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = host
+	rec := httptest.NewRecorder()
+	mw(ok).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAllowedHosts(t *testing.T) {
+	mw := AllowedHosts(nil, "example.com", "*.apps.example.org", "admin.example.com:8443", "[::1]")
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		// exact
+		{"example.com", http.StatusOK},
+		{"EXAMPLE.com.", http.StatusOK},
+		{"evil.com", http.StatusBadRequest},
+		{"example.com.evil.com", http.StatusBadRequest},
+		{"www.example.com", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+		// wildcard
+		{"a.apps.example.org", http.StatusOK},
+		{"a.b.apps.example.org", http.StatusOK},
+		{"apps.example.org", http.StatusBadRequest},
+		{"evilapps.example.org", http.StatusBadRequest},
+		// ports
+		{"example.com:8080", http.StatusOK},
+		{"a.apps.example.org:443", http.StatusOK},
+		{"admin.example.com:8443", http.StatusOK},
+		{"admin.example.com", http.StatusBadRequest},
+		{"admin.example.com:80", http.StatusBadRequest},
+		// IPv6
+		{"[::1]", http.StatusOK},
+		{"[::1]:8080", http.StatusOK},
+		{"[::2]", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got := status(mw, tt.host); got != tt.want {
+			t.Errorf("Host %q: status = %d, want %d", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedHosts_EmptyAllowsAll(t *testing.T) {
+	for _, mw := range []func(http.Handler) http.Handler{AllowedHosts(nil), AllowedHosts(nil, "", " ")} {
+		if got := status(mw, "anything.test"); got != http.StatusOK {
+			t.Errorf("status = %d, want %d", got, http.StatusOK)
+		}
+	}
+}
+
+func TestAllowedHosts_UsesErrorHandler(t *testing.T) {
+	mw := AllowedHosts(errorsfeature.NewHandler(), "example.com")
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "evil.com"
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	mw(ok).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, "application/json") {
+		t.Errorf("Content-Type = %q, want the errors handler's JSON response", ct)
+	}
+}
@@ -0,0 +1,67 @@
+// internal/app/features/session/cookie.go
+package session
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/securecookie"
+)
+
+// CookieStore keeps session values in a signed cookie. Values are visible to
+// the client unless a block key is given, but cannot be altered. Keep them
+// small: browsers cap cookies at about 4KB.
+type CookieStore struct {
+	codec *securecookie.Codec
+	err   error // set when the keys are unusable; returned by Save
+	opts  CookieOptions
+}
+
+// NewCookieStore returns a CookieStore that signs cookies with hashKey and,
+// when blockKey is non-nil (16, 24, or 32 bytes), also encrypts them. The
+// cookie's expiry is embedded in its value and checked on every request.
+// If the keys are unusable, no session is ever loaded and Save fails.
+func NewCookieStore(hashKey, blockKey []byte, opts CookieOptions) *CookieStore {
+	opts = opts.withDefaults()
+	codec, err := securecookie.New([]securecookie.Key{{Hash: hashKey, Block: blockKey}},
+		securecookie.WithMaxAge(opts.MaxAge))
+	return &CookieStore{codec: codec, err: err, opts: opts}
+}
+
+// Get decodes the session cookie on r.
+func (s *CookieStore) Get(r *http.Request) (*Session, error) {
+	if s.err != nil {
+		return nil, ErrNoSession
+	}
+	c, err := r.Cookie(s.opts.Name)
+	if err != nil {
+		return nil, ErrNoSession
+	}
+	values := make(Values)
+	if err := s.codec.Decode(s.opts.Name, c.Value, &values); err != nil {
+		return nil, ErrNoSession
+	}
+	return &Session{Values: values}, nil
+}
+
+// Save encodes the session values into the cookie.
+func (s *CookieStore) Save(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	if s.err != nil {
+		return s.err
+	}
+	encoded, err := s.codec.Encode(s.opts.Name, sess.Values)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, s.opts.cookie(encoded, int(s.opts.MaxAge/time.Second)))
+	sess.modified = false
+	return nil
+}
+
+// Destroy expires the session cookie and clears the values.
+func (s *CookieStore) Destroy(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	http.SetCookie(w, s.opts.cookie("", -1))
+	sess.Values = make(Values)
+	sess.modified = false
+	return nil
+}
@@ -0,0 +1,81 @@
+// internal/app/features/session/middleware.go
+package session
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Middleware loads the session for each request from store and stores it in
+// the request context (see FromRequest). If a handler modifies the session,
+// it is saved just before the response headers are written, or when the
+// handler returns without writing anything. Load and save failures are
+// logged; a request with no usable session gets a new, empty one.
+func Middleware(store Store, logger *zap.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sess, err := store.Get(r)
+			if err != nil {
+				if !errors.Is(err, ErrNoSession) {
+					logger.Warn("session load failed", zap.Error(err), zap.String("path", r.URL.Path))
+				}
+				sess = New()
+			}
+			r = r.WithContext(WithSession(r.Context(), sess))
+
+			sw := &saveWriter{ResponseWriter: w, save: func() {
+				if !sess.Modified() {
+					return
+				}
+				if err := store.Save(w, r, sess); err != nil {
+					logger.Error("session save failed", zap.Error(err), zap.String("path", r.URL.Path))
+				}
+			}}
+			next.ServeHTTP(sw, r)
+			sw.flush()
+		})
+	}
+}
+
+// saveWriter saves the session before the first byte of the response.
+type saveWriter struct {
+	http.ResponseWriter
+	save  func()
+	saved bool
+}
+
+func (sw *saveWriter) flush() {
+	if sw.saved {
+		return
+	}
+	sw.saved = true
+	sw.save()
+}
+
+func (sw *saveWriter) WriteHeader(status int) {
+	sw.flush()
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *saveWriter) Write(b []byte) (int, error) {
+	sw.flush()
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for streaming handlers.
+func (sw *saveWriter) Flush() {
+	sw.flush()
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (sw *saveWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,95 @@
+//go:build redis
+
+// internal/app/features/session/redis.go
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps session values in Redis under "session:<id>". The cookie
+// carries only the random session ID, so values never reach the client.
+// Build with -tags redis to include it.
+type RedisStore struct {
+	client redis.UniversalClient
+	opts   CookieOptions
+	prefix string
+}
+
+// NewRedisStore returns a RedisStore using client. Sessions expire in Redis
+// after opts.MaxAge.
+func NewRedisStore(client redis.UniversalClient, opts CookieOptions) *RedisStore {
+	return &RedisStore{client: client, opts: opts.withDefaults(), prefix: "session:"}
+}
+
+// Get loads the session named by the cookie on r.
+func (s *RedisStore) Get(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(s.opts.Name)
+	if err != nil || c.Value == "" {
+		return nil, ErrNoSession
+	}
+	raw, err := s.client.Get(r.Context(), s.prefix+c.Value).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNoSession
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make(Values)
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, ErrNoSession
+	}
+	return &Session{ID: c.Value, Values: values}, nil
+}
+
+// Save writes the session values to Redis, assigning an ID to new sessions,
+// and refreshes the cookie and expiry.
+func (s *RedisStore) Save(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	if sess.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		sess.ID = id
+	}
+	raw, err := json.Marshal(sess.Values)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(r.Context(), s.prefix+sess.ID, raw, s.opts.MaxAge).Err(); err != nil {
+		return err
+	}
+	http.SetCookie(w, s.opts.cookie(sess.ID, int(s.opts.MaxAge/time.Second)))
+	sess.modified = false
+	return nil
+}
+
+// Destroy deletes the session from Redis and expires the cookie.
+func (s *RedisStore) Destroy(w http.ResponseWriter, r *http.Request, sess *Session) error {
+	if sess.ID != "" {
+		if err := s.client.Del(r.Context(), s.prefix+sess.ID).Err(); err != nil {
+			return err
+		}
+	}
+	http.SetCookie(w, s.opts.cookie("", -1))
+	sess.ID = ""
+	sess.Values = make(Values)
+	sess.modified = false
+	return nil
+}
+
+// newID returns a random 256-bit session ID.
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
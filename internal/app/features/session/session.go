@@ -0,0 +1,177 @@
+// internal/app/features/session/session.go
+//
+// Package session provides general-purpose sessions for feature handlers.
+//
+// A Store loads and saves a Session, which carries a Values map. Two stores
+// are available:
+//
+//   - CookieStore (default): values live in a signed cookie on the client.
+//   - RedisStore (build tag "redis"): values live in Redis; the cookie holds
+//     only a random session ID.
+//
+// Middleware loads the session for each request into the context, where
+// handlers read it with FromRequest, and saves it before the response is
+// written if it changed.
+//
+// This is separate from the login session managed by auth.SessionManager,
+// which only tracks the signed-in user.
+//
+// The flash feature stores its messages in this session when Middleware is
+// installed, so flashes need no cookie of their own. CSRF protection does not
+// depend on the session: the csrf feature uses the double-submit-cookie
+// pattern with its own signed cookie.
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrNoSession is returned by stores when a request carries no valid session.
+var ErrNoSession = errors.New("session: no session")
+
+// Values holds session data. Values must be JSON-encodable; numbers come
+// back as float64 after a round trip, so prefer the typed Session getters.
+type Values map[string]any
+
+// Session is the per-request session.
+type Session struct {
+	// ID identifies server-side sessions. It is empty for cookie sessions.
+	ID string
+	// Values holds the session data. Modify it through Set and Delete so
+	// changes are noticed and saved.
+	Values Values
+	// IsNew reports whether the session was created for this request.
+	IsNew bool
+
+	modified bool
+}
+
+// New returns an empty session.
+func New() *Session {
+	return &Session{Values: make(Values), IsNew: true}
+}
+
+// Get returns the value stored under key, or nil.
+func (s *Session) Get(key string) any {
+	return s.Values[key]
+}
+
+// GetString returns the string stored under key, or "".
+func (s *Session) GetString(key string) string {
+	v, _ := s.Values[key].(string)
+	return v
+}
+
+// GetInt returns the number stored under key as an int, or 0.
+func (s *Session) GetInt(key string) int {
+	switch v := s.Values[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// GetBool returns the bool stored under key, or false.
+func (s *Session) GetBool(key string) bool {
+	v, _ := s.Values[key].(bool)
+	return v
+}
+
+// Set stores value under key and marks the session modified.
+func (s *Session) Set(key string, value any) {
+	s.Values[key] = value
+	s.modified = true
+}
+
+// Delete removes key and marks the session modified.
+func (s *Session) Delete(key string) {
+	if _, ok := s.Values[key]; !ok {
+		return
+	}
+	delete(s.Values, key)
+	s.modified = true
+}
+
+// Modified reports whether the session changed since it was loaded.
+func (s *Session) Modified() bool {
+	return s.modified
+}
+
+// Store loads, saves, and destroys sessions.
+type Store interface {
+	// Get returns the session for r. It returns ErrNoSession when r carries
+	// none or it is invalid or expired.
+	Get(r *http.Request) (*Session, error)
+	// Save persists s and writes its cookie to w. It must be called before
+	// the response headers are written.
+	Save(w http.ResponseWriter, r *http.Request, s *Session) error
+	// Destroy removes s and expires its cookie.
+	Destroy(w http.ResponseWriter, r *http.Request, s *Session) error
+}
+
+// CookieOptions configures the session cookie shared by all stores.
+type CookieOptions struct {
+	Name   string        // cookie name (default: "strataforge-data")
+	Path   string        // cookie path (default: "/")
+	Domain string        // cookie domain (default: current host)
+	MaxAge time.Duration // session lifetime (default: 24h)
+	Secure bool          // set the Secure flag; enable in production
+}
+
+// withDefaults fills in unset options.
+func (o CookieOptions) withDefaults() CookieOptions {
+	if o.Name == "" {
+		o.Name = "strataforge-data"
+	}
+	if o.Path == "" {
+		o.Path = "/"
+	}
+	if o.MaxAge <= 0 {
+		o.MaxAge = 24 * time.Hour
+	}
+	return o
+}
+
+// cookie builds the session cookie carrying value. A negative maxAge
+// expires it.
+func (o CookieOptions) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     o.Name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   maxAge,
+		Secure:   o.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ctxKey is the context key for the request's session.
+type ctxKey struct{}
+
+// FromRequest returns the session loaded by Middleware, or nil when the
+// middleware is not installed.
+func FromRequest(r *http.Request) *Session {
+	return FromContext(r.Context())
+}
+
+// FromContext returns the session stored in ctx, or nil.
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(ctxKey{}).(*Session)
+	return s
+}
+
+// WithSession returns a copy of ctx carrying s. Middleware does this for
+// every request; tests can use it to inject a session.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
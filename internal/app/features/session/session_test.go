@@ -0,0 +1,114 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var testKey = []byte("test-session-key-0123456789abcdef")
+
+func TestCookieStore_RoundTrip(t *testing.T) {
+	store := NewCookieStore(testKey, nil, CookieOptions{})
+	sess := New()
+	sess.Set("user", "ada")
+	sess.Set("count", 3)
+
+	rec := httptest.NewRecorder()
+	if err := store.Save(rec, httptest.NewRequest(http.MethodGet, "/", nil), sess); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	got, err := store.Get(req)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.GetString("user") != "ada" || got.GetInt("count") != 3 {
+		t.Errorf("values = %v, want user=ada count=3", got.Values)
+	}
+}
+
+func TestCookieStore_RejectsTampered(t *testing.T) {
+	store := NewCookieStore(testKey, nil, CookieOptions{})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "strataforge-data", Value: "forged"})
+
+	if _, err := store.Get(req); err != ErrNoSession {
+		t.Errorf("Get() error = %v, want ErrNoSession", err)
+	}
+}
+
+func TestCookieStore_InvalidKeys(t *testing.T) {
+	store := NewCookieStore(testKey, []byte("not-an-aes-key"), CookieOptions{})
+	sess := New()
+	sess.Set("user", "ada")
+
+	if err := store.Save(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), sess); err == nil {
+		t.Error("Save() with an invalid block key = nil error")
+	}
+}
+
+func TestCookieStore_Destroy(t *testing.T) {
+	store := NewCookieStore(testKey, nil, CookieOptions{})
+	sess := New()
+	sess.Set("user", "ada")
+
+	rec := httptest.NewRecorder()
+	_ = store.Destroy(rec, httptest.NewRequest(http.MethodGet, "/", nil), sess)
+
+	if len(sess.Values) != 0 {
+		t.Error("expected values to be cleared")
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("cookies = %v, want one expired cookie", cookies)
+	}
+}
+
+func TestMiddleware_SavesModifiedSession(t *testing.T) {
+	store := NewCookieStore(testKey, nil, CookieOptions{})
+	h := Middleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Set("theme", "dark")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(rec.Result().Cookies()) != 1 {
+		t.Fatal("expected the modified session to be saved")
+	}
+}
+
+func TestMiddleware_SavesWhenHandlerWritesNothing(t *testing.T) {
+	store := NewCookieStore(testKey, nil, CookieOptions{})
+	h := Middleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Set("theme", "dark")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(rec.Result().Cookies()) != 1 {
+		t.Fatal("expected the modified session to be saved")
+	}
+}
+
+func TestMiddleware_SkipsUnmodifiedSession(t *testing.T) {
+	store := NewCookieStore(testKey, nil, CookieOptions{})
+	h := Middleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = FromRequest(r).GetString("theme")
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("expected no cookie for an unmodified session")
+	}
+}
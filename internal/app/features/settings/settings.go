@@ -3,6 +3,7 @@ package settings
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
 	settingsstore "github.com/dalemusser/strataforge/internal/app/store/settings"
+	"github.com/dalemusser/strataforge/internal/app/system/formutil"
 	"github.com/dalemusser/strataforge/internal/app/system/htmlsanitize"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
 	"github.com/dalemusser/strataforge/internal/domain/models"
@@ -119,12 +121,19 @@ const MaxFooterLength = 10000
 
 // update saves the settings including logo handling.
 func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
-	// Parse multipart form for file uploads (10MB max)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Parse multipart form for file uploads (10MB max); Close removes any
+	// temporary files
+	form, err := formutil.Parse(r, 10<<20)
+	if err != nil {
 		h.errLog.Log(r, "failed to parse form", err)
+		if errors.Is(err, formutil.ErrTooLarge) {
+			h.renderSettingsWithError(w, r, "Upload is too large. Maximum size is 10 MB.")
+			return
+		}
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
+	defer form.Close()
 
 	ctx := r.Context()
 	siteName := r.FormValue("site_name")
@@ -168,8 +177,8 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check for new logo upload
-	file, header, fileErr := r.FormFile("logo")
-	hasNewLogo := fileErr == nil && header != nil && header.Size > 0
+	file, header, fileErr := form.File("logo")
+	hasNewLogo := fileErr == nil
 	if hasNewLogo {
 		defer file.Close()
 
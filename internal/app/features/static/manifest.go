@@ -0,0 +1,78 @@
+// internal/app/features/static/manifest.go
+package static
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// AssetManifest maps the logical names a frontend build knows its outputs
+// by ("app.js") to the fingerprinted files it wrote ("js/app.4f3a9c.js").
+// Load one with LoadManifest and attach it with WithManifest.
+type AssetManifest struct {
+	files   map[string]string   // logical name -> file path relative to the asset root
+	outputs map[string]struct{} // the files, for serving them as immutable
+}
+
+// manifestEntry is the object form of a manifest value, as Vite writes it.
+type manifestEntry struct {
+	File string `json:"file"`
+}
+
+// LoadManifest reads the JSON manifest at name in fsys. Two shapes are
+// understood: a flat object of names to files, as written by esbuild and
+// webpack manifest plugins,
+//
+//	{"app.js": "js/app.4f3a9c.js"}
+//
+// and Vite's object of entries, whose file member names the output:
+//
+//	{"src/app.js": {"file": "js/app.4f3a9c.js", "isEntry": true}}
+//
+// Files are relative to the asset root; a leading slash is dropped.
+func LoadManifest(fsys fs.FS, name string) (*AssetManifest, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("static: read manifest: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("static: parse manifest %s: %w", name, err)
+	}
+
+	m := &AssetManifest{files: make(map[string]string, len(raw)), outputs: make(map[string]struct{}, len(raw))}
+	for key, value := range raw {
+		var file string
+		if err := json.Unmarshal(value, &file); err != nil {
+			var entry manifestEntry
+			if err := json.Unmarshal(value, &entry); err != nil || entry.File == "" {
+				return nil, fmt.Errorf("static: manifest %s: entry %q has no file", name, key)
+			}
+			file = entry.File
+		}
+		file = strings.TrimPrefix(file, "/")
+		m.files[strings.TrimPrefix(key, "/")] = file
+		m.outputs[file] = struct{}{}
+	}
+	return m, nil
+}
+
+// Lookup returns the fingerprinted file for the logical name.
+func (m *AssetManifest) Lookup(name string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	file, ok := m.files[strings.TrimPrefix(name, "/")]
+	return file, ok
+}
+
+// isFile reports whether path is one of the manifest's output files.
+func (m *AssetManifest) isFile(path string) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m.outputs[path]
+	return ok
+}
@@ -0,0 +1,135 @@
+package static
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func manifestFS() fstest.MapFS {
+	fsys := testFS()
+	fsys["js/app.4f3a9c.js"] = &fstest.MapFile{Data: []byte("console.log('built');")}
+	fsys["manifest.json"] = &fstest.MapFile{Data: []byte(`{"app.js": "/js/app.4f3a9c.js"}`)}
+	fsys["vite.json"] = &fstest.MapFile{Data: []byte(`{"src/app.js": {"file": "js/app.4f3a9c.js", "isEntry": true, "css": ["css/app.1b2c.css"]}}`)}
+	return fsys
+}
+
+func TestLoadManifest(t *testing.T) {
+	for _, name := range []string{"manifest.json", "vite.json"} {
+		t.Run(name, func(t *testing.T) {
+			m, err := LoadManifest(manifestFS(), name)
+			if err != nil {
+				t.Fatalf("LoadManifest() error = %v", err)
+			}
+			key := "app.js"
+			if name == "vite.json" {
+				key = "src/app.js"
+			}
+			if got, ok := m.Lookup(key); !ok || got != "js/app.4f3a9c.js" {
+				t.Errorf("Lookup(%q) = %q, %v; want js/app.4f3a9c.js", key, got, ok)
+			}
+			if _, ok := m.Lookup("missing.js"); ok {
+				t.Error("Lookup(missing.js) found an entry")
+			}
+		})
+	}
+}
+
+func TestLoadManifest_Errors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"bad.json":     {Data: []byte(`[1, 2]`)},
+		"nofile.json":  {Data: []byte(`{"app.js": {"isEntry": true}}`)},
+		"number.json":  {Data: []byte(`{"app.js": 7}`)},
+		"truncated.js": {Data: []byte(`{"app.js": `)},
+	}
+	for _, name := range []string{"absent.json", "bad.json", "nofile.json", "number.json", "truncated.js"} {
+		if _, err := LoadManifest(fsys, name); err == nil {
+			t.Errorf("LoadManifest(%q) error = nil, want error", name)
+		}
+	}
+}
+
+func TestAsset(t *testing.T) {
+	m, err := LoadManifest(manifestFS(), "manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	core, logs := observer.New(zapcore.WarnLevel)
+	h := NewHandler(manifestFS(), "/assets", WithManifest(m), WithLogger(zap.New(core)))
+
+	if got, want := h.Asset("app.js"), "/assets/js/app.4f3a9c.js"; got != want {
+		t.Errorf("Asset(app.js) = %q, want %q", got, want)
+	}
+
+	// A name the manifest lacks falls back to the plain asset URL, with one warning.
+	for i := 0; i < 3; i++ {
+		if got, want := h.Asset("css/site.css"), h.AssetURL("css/site.css"); got != want {
+			t.Errorf("Asset(css/site.css) = %q, want fallback %q", got, want)
+		}
+	}
+	if logs.Len() != 1 {
+		t.Fatalf("logged %d warnings, want 1", logs.Len())
+	}
+	if got := logs.All()[0].ContextMap()["asset"]; got != "css/site.css" {
+		t.Errorf("warning asset = %v, want css/site.css", got)
+	}
+}
+
+func TestAsset_NoManifest(t *testing.T) {
+	h := NewHandler(testFS(), "/assets")
+
+	if got, want := h.Asset("js/app.js"), h.AssetURL("js/app.js"); got != want {
+		t.Errorf("Asset() without manifest = %q, want %q", got, want)
+	}
+	if got := h.Asset("app.js"); got != "/assets/app.js" {
+		t.Errorf("Asset() for unknown file = %q, want /assets/app.js", got)
+	}
+}
+
+func TestAsset_TemplateFunc(t *testing.T) {
+	m, err := LoadManifest(manifestFS(), "manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(manifestFS(), "/assets", WithManifest(m))
+	tmpl := template.Must(template.New("page").Funcs(h.FuncMap()).Parse(`<script type="module" src="{{ asset "app.js" }}"></script>`))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<script type="module" src="/assets/js/app.4f3a9c.js"></script>`; buf.String() != want {
+		t.Errorf("rendered %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRoutes_ManifestFilesImmutable(t *testing.T) {
+	m, err := LoadManifest(manifestFS(), "manifest.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(manifestFS(), "/assets", WithManifest(m))
+	srv := Routes(h, errorsfeature.NewHandler().NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, h.Asset("app.js"), nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != immutableCache {
+		t.Errorf("Cache-Control = %q, want %q", got, immutableCache)
+	}
+	if !strings.Contains(rec.Body.String(), "built") {
+		t.Errorf("body = %q, want the built file", rec.Body.String())
+	}
+}
@@ -0,0 +1,155 @@
+// internal/app/features/static/static.go
+//
+// Package static serves embedded assets with cache-busting fingerprints.
+//
+// Each file's content hash is computed once at startup. Templates link to
+// assets through the assetURL function, which appends ?v=<hash>:
+//
+//	<link rel="stylesheet" href="{{ assetURL "css/tailwind.css" }}">
+//
+// Requests whose v parameter matches the current hash are cached by browsers
+// for a year (Cache-Control: immutable), since a changed file gets a new URL.
+// Other requests must revalidate.
+//
+// Files produced by a frontend build (Vite, esbuild) that fingerprints names
+// itself are linked through its manifest (LoadManifest, WithManifest) with
+// the asset function, which takes the name the build knows the file by:
+//
+//	<script type="module" src="{{ asset "app.js" }}"></script>
+//
+// Without a manifest, as in development, asset falls back to assetURL for
+// the name as given. Manifest outputs are always served as immutable.
+package static
+
+import (
+	"bytes"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dalemusser/waffle/pantry/assets"
+	"go.uber.org/zap"
+)
+
+const (
+	immutableCache  = "public, max-age=31536000, immutable"
+	revalidateCache = "no-cache"
+)
+
+// Handler serves the files in an fs.FS under a URL prefix.
+type Handler struct {
+	fsys     fs.FS
+	prefix   string
+	hashes   map[string]string // file path -> content hash
+	manifest *AssetManifest
+	logger   *zap.Logger
+	warned   sync.Map // logical names missing from the manifest, warned about once
+}
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithManifest resolves the asset template function through m.
+func WithManifest(m *AssetManifest) Option {
+	return func(h *Handler) {
+		h.manifest = m
+	}
+}
+
+// WithLogger logs names the asset function cannot find in the manifest.
+func WithLogger(logger *zap.Logger) Option {
+	return func(h *Handler) {
+		h.logger = logger
+	}
+}
+
+// NewHandler returns a Handler for fsys mounted at prefix (e.g. "/assets").
+// It hashes every file in fsys; files that cannot be read are skipped.
+func NewHandler(fsys fs.FS, prefix string, opts ...Option) *Handler {
+	h := &Handler{
+		fsys:   fsys,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		hashes: make(map[string]string),
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	_ = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		h.hashes[path] = assets.ContentHash(fsys, path)
+		return nil
+	})
+	return h
+}
+
+// AssetURL returns the fingerprinted URL for the file at path, relative to
+// the asset root (e.g. "css/tailwind.css"). Unknown files get a plain URL.
+func (h *Handler) AssetURL(path string) string {
+	path = strings.TrimPrefix(path, "/")
+	url := h.prefix + "/" + path
+	if hash, ok := h.hashes[path]; ok {
+		url += "?v=" + hash
+	}
+	return url
+}
+
+// Asset returns the URL of the build output the manifest lists under name
+// (e.g. "app.js"). Without a manifest it is AssetURL(name). A name missing
+// from the manifest is logged, once, as a warning and also gets
+// AssetURL(name), so a stale manifest breaks one link rather than the page.
+func (h *Handler) Asset(name string) string {
+	if h.manifest == nil {
+		return h.AssetURL(name)
+	}
+	if file, ok := h.manifest.Lookup(name); ok {
+		return h.prefix + "/" + file
+	}
+	if _, seen := h.warned.LoadOrStore(name, struct{}{}); !seen {
+		h.logger.Warn("asset not in manifest; linking the name as given", zap.String("asset", name))
+	}
+	return h.AssetURL(name)
+}
+
+// FuncMap returns template helpers for linking to assets:
+//
+//	assetURL PATH - fingerprinted URL for the asset at PATH
+//	asset NAME    - URL of the build output named NAME in the manifest
+func (h *Handler) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"assetURL": h.AssetURL,
+		"asset":    h.Asset,
+	}
+}
+
+// Routes returns an http.Handler that serves the assets. Requests for
+// directories or missing files are passed to notFound, typically the errors
+// Handler's NotFoundHandler.
+func Routes(h *Handler, notFound http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, h.prefix), "/")
+		hash, ok := h.hashes[path]
+		if !ok {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+		data, err := fs.ReadFile(h.fsys, path)
+		if err != nil {
+			notFound.ServeHTTP(w, r)
+			return
+		}
+
+		if r.URL.Query().Get("v") == hash || h.manifest.isFile(path) {
+			w.Header().Set("Cache-Control", immutableCache)
+		} else {
+			w.Header().Set("Cache-Control", revalidateCache)
+		}
+		w.Header().Set("ETag", `"`+hash+`"`)
+		http.ServeContent(w, r, path, time.Time{}, bytes.NewReader(data))
+	})
+}
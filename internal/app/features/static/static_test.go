@@ -0,0 +1,92 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/site.css": {Data: []byte("body { color: black; }")},
+		"js/app.js":    {Data: []byte("console.log('hi');")},
+	}
+}
+
+func TestAssetURL(t *testing.T) {
+	h := NewHandler(testFS(), "/assets/")
+
+	got := h.AssetURL("css/site.css")
+	if !strings.HasPrefix(got, "/assets/css/site.css?v=") || len(got) != len("/assets/css/site.css?v=")+10 {
+		t.Errorf("AssetURL() = %q, want fingerprinted URL", got)
+	}
+	if got := h.AssetURL("/css/site.css"); !strings.HasPrefix(got, "/assets/css/site.css?v=") {
+		t.Errorf("AssetURL() with leading slash = %q", got)
+	}
+	if got := h.AssetURL("missing.css"); got != "/assets/missing.css" {
+		t.Errorf("AssetURL() for unknown file = %q, want %q", got, "/assets/missing.css")
+	}
+}
+
+func TestAssetURL_ChangesWithContent(t *testing.T) {
+	a := NewHandler(testFS(), "/assets")
+	changed := testFS()
+	changed["css/site.css"] = &fstest.MapFile{Data: []byte("body { color: red; }")}
+	b := NewHandler(changed, "/assets")
+
+	if a.AssetURL("css/site.css") == b.AssetURL("css/site.css") {
+		t.Error("expected the fingerprint to change with file content")
+	}
+}
+
+func TestRoutes_CacheHeaders(t *testing.T) {
+	h := NewHandler(testFS(), "/assets")
+	srv := Routes(h, errorsfeature.NewHandler().NotFoundHandler())
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"fingerprinted", h.AssetURL("css/site.css"), immutableCache},
+		{"stale fingerprint", "/assets/css/site.css?v=0000000000", revalidateCache},
+		{"no fingerprint", "/assets/css/site.css", revalidateCache},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/css") {
+				t.Errorf("Content-Type = %q, want text/css", got)
+			}
+		})
+	}
+}
+
+func TestRoutes_UnknownPathIsNotFound(t *testing.T) {
+	h := NewHandler(testFS(), "/assets")
+	srv := Routes(h, errorsfeature.NewHandler().NotFoundHandler())
+
+	for _, url := range []string{"/assets/missing.css", "/assets/css", "/assets/"} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", url, rec.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -45,16 +45,20 @@ type AppConfig struct {
 	SessionName       string
 	SessionDomain     string
 	SessionMaxAge     time.Duration
+	RememberMeEnabled bool
+	RememberMeMaxAge  time.Duration
 	IdleLogoutEnabled bool
 	IdleLogoutTimeout time.Duration
 	IdleLogoutWarning time.Duration
 	CSRFKey           string
 
 	// Rate Limiting
-	RateLimitEnabled       bool
-	RateLimitLoginAttempts int
-	RateLimitLoginWindow   time.Duration
-	RateLimitLoginLockout  time.Duration
+	RateLimitEnabled         bool
+	RateLimitLoginAttempts   int
+	RateLimitLoginWindow     time.Duration
+	RateLimitLoginLockout    time.Duration
+	RateLimitLoginIPAttempts int
+	RateLimitLoginStore      string
 
 	// API
 	APIKey string
@@ -71,6 +75,7 @@ type AppConfig struct {
 	StorageCFKeyPath   string
 
 	// Email/SMTP
+	MailBackend       string
 	MailSMTPHost      string
 	MailSMTPPort      int
 	MailSMTPUser      string
@@ -81,12 +86,20 @@ type AppConfig struct {
 	EmailVerifyExpiry time.Duration
 
 	// Audit
-	AuditLogAuth  string
-	AuditLogAdmin string
+	AuditLogAuth   string
+	AuditLogAdmin  string
+	AuditLogAccess string
+	AuditLogFile   string
 
 	// Google OAuth
 	GoogleClientID     string
 	GoogleClientSecret string
+	GoogleSignupRole   string
+
+	// CAPTCHA
+	CaptchaProvider  string
+	CaptchaSiteKey   string
+	CaptchaSecretKey string
 
 	// Admin seeding
 	SeedAdminEmail string
@@ -435,6 +448,8 @@ func (h *Handler) buildConfigGroups() []ConfigGroup {
 			{Name: "session_name", Value: h.AppCfg.SessionName},
 			{Name: "session_domain", Value: h.AppCfg.SessionDomain},
 			{Name: "session_max_age", Value: h.AppCfg.SessionMaxAge.String()},
+			{Name: "remember_me_enabled", Value: boolStr(h.AppCfg.RememberMeEnabled)},
+			{Name: "remember_me_max_age", Value: h.AppCfg.RememberMeMaxAge.String()},
 			{Name: "idle_logout_enabled", Value: boolStr(h.AppCfg.IdleLogoutEnabled)},
 			{Name: "idle_logout_timeout", Value: h.AppCfg.IdleLogoutTimeout.String()},
 			{Name: "idle_logout_warning", Value: h.AppCfg.IdleLogoutWarning.String()},
@@ -442,6 +457,8 @@ func (h *Handler) buildConfigGroups() []ConfigGroup {
 			{Name: "rate_limit_login_attempts", Value: fmt.Sprintf("%d", h.AppCfg.RateLimitLoginAttempts)},
 			{Name: "rate_limit_login_window", Value: h.AppCfg.RateLimitLoginWindow.String()},
 			{Name: "rate_limit_login_lockout", Value: h.AppCfg.RateLimitLoginLockout.String()},
+			{Name: "rate_limit_login_ip_attempts", Value: fmt.Sprintf("%d", h.AppCfg.RateLimitLoginIPAttempts)},
+			{Name: "rate_limit_login_store", Value: h.AppCfg.RateLimitLoginStore},
 			{Name: "csrf_key", Value: mask(h.AppCfg.CSRFKey)},
 			{Name: "api_key", Value: mask(h.AppCfg.APIKey)},
 		},
@@ -467,6 +484,7 @@ func (h *Handler) buildConfigGroups() []ConfigGroup {
 	groups = append(groups, ConfigGroup{
 		Name: "Email/SMTP",
 		Items: []ConfigItem{
+			{Name: "mail_backend", Value: h.AppCfg.MailBackend},
 			{Name: "mail_smtp_host", Value: h.AppCfg.MailSMTPHost},
 			{Name: "mail_smtp_port", Value: fmt.Sprintf("%d", h.AppCfg.MailSMTPPort)},
 			{Name: "mail_smtp_user", Value: h.AppCfg.MailSMTPUser},
@@ -484,6 +502,10 @@ func (h *Handler) buildConfigGroups() []ConfigGroup {
 		Items: []ConfigItem{
 			{Name: "google_client_id", Value: mask(h.AppCfg.GoogleClientID)},
 			{Name: "google_client_secret", Value: mask(h.AppCfg.GoogleClientSecret)},
+			{Name: "google_signup_role", Value: h.AppCfg.GoogleSignupRole},
+			{Name: "captcha_provider", Value: h.AppCfg.CaptchaProvider},
+			{Name: "captcha_site_key", Value: h.AppCfg.CaptchaSiteKey},
+			{Name: "captcha_secret_key", Value: mask(h.AppCfg.CaptchaSecretKey)},
 		},
 	})
 
@@ -493,6 +515,8 @@ func (h *Handler) buildConfigGroups() []ConfigGroup {
 		Items: []ConfigItem{
 			{Name: "audit_log_auth", Value: h.AppCfg.AuditLogAuth},
 			{Name: "audit_log_admin", Value: h.AppCfg.AuditLogAdmin},
+			{Name: "audit_log_access", Value: h.AppCfg.AuditLogAccess},
+			{Name: "audit_log_file", Value: h.AppCfg.AuditLogFile},
 		},
 	})
 
@@ -0,0 +1,43 @@
+package systemusers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/jobqueue"
+	"github.com/dalemusser/strataforge/internal/app/system/mailer"
+	"go.uber.org/zap"
+)
+
+// chanSender hands each message it is given to a channel.
+type chanSender chan mailer.Message
+
+func (s chanSender) Send(_ context.Context, msg mailer.Message) error {
+	s <- msg
+	return nil
+}
+
+func TestSendLater_UsesJobQueue(t *testing.T) {
+	sent := make(chanSender, 1)
+	q := jobqueue.New(jobqueue.Config{Workers: 1, QueueSize: 1, JobTimeout: time.Second}, zap.NewNop())
+	h := &Handler{mailer: mailer.NewWithSender(sent, "noreply@example.com", "Strata", zap.NewNop()), logger: zap.NewNop()}
+	h.SetJobQueue(q)
+
+	h.sendLater(httptest.NewRequest(http.MethodPost, "/system-users", nil), "welcome-email", mailer.Email{To: "a@example.com", Subject: "Welcome"})
+
+	// Stop drains the queue, so the email has been sent once it returns.
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-sent:
+		if msg.To != "a@example.com" {
+			t.Errorf("sent to %q, want a@example.com", msg.To)
+		}
+	default:
+		t.Fatal("email was not sent by the time the queue drained")
+	}
+}
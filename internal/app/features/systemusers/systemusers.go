@@ -6,6 +6,7 @@ package systemusers
 //   - LoginID / loginID / login_id: The human-readable string users type to log in
 
 import (
+	"context"
 	"html/template"
 	"net/http"
 	"strconv"
@@ -17,6 +18,7 @@ import (
 	"github.com/dalemusser/strataforge/internal/app/system/auth"
 	"github.com/dalemusser/strataforge/internal/app/system/auditlog"
 	"github.com/dalemusser/strataforge/internal/app/system/authutil"
+	"github.com/dalemusser/strataforge/internal/app/system/jobqueue"
 	"github.com/dalemusser/strataforge/internal/app/system/mailer"
 	"github.com/dalemusser/strataforge/internal/app/system/normalize"
 	"github.com/dalemusser/strataforge/internal/app/system/viewdata"
@@ -41,6 +43,7 @@ type Handler struct {
 	mailer        *mailer.Mailer
 	errLog        *errorsfeature.ErrorLogger
 	auditLogger   *auditlog.Logger
+	jobs          *jobqueue.Queue
 	logger        *zap.Logger
 }
 
@@ -62,6 +65,28 @@ func NewHandler(
 	}
 }
 
+// SetJobQueue sends notification emails (welcome, account disabled and
+// enabled) on q after the response instead of on a goroutine of their own,
+// so they are bounded by its workers and drained at shutdown.
+func (h *Handler) SetJobQueue(q *jobqueue.Queue) {
+	h.jobs = q
+}
+
+// sendLater sends email without holding up the response: on the job queue
+// when one is set, otherwise on its own goroutine. Failures are logged.
+func (h *Handler) sendLater(r *http.Request, name string, email mailer.Email) {
+	send := func(ctx context.Context) error {
+		return h.mailer.Send(ctx, email)
+	}
+	if h.jobs == nil {
+		go func() { _ = send(context.WithoutCancel(r.Context())) }()
+		return
+	}
+	if err := h.jobs.Enqueue(jobqueue.Job{Name: name, Run: send}); err != nil {
+		h.logger.Warn("could not queue email", zap.String("job", name), zap.Error(err))
+	}
+}
+
 // userRow represents a user in the list.
 type userRow struct {
 	ID       primitive.ObjectID
@@ -417,20 +442,18 @@ func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
 			if siteName == "" {
 				siteName = "Strata"
 			}
-			go func() {
-				text, html := mailer.WelcomeEmail(mailer.WelcomeEmailData{
-					AppName:  siteName,
-					UserName: userName,
-					LoginURL: "/login",
-					Role:     user.Role,
-				})
-				_ = h.mailer.Send(mailer.Email{
-					To:       userEmail,
-					Subject:  "Welcome to " + siteName,
-					TextBody: text,
-					HTMLBody: html,
-				})
-			}()
+			text, html := mailer.WelcomeEmail(mailer.WelcomeEmailData{
+				AppName:  siteName,
+				UserName: userName,
+				LoginURL: "/login",
+				Role:     user.Role,
+			})
+			h.sendLater(r, "welcome-email", mailer.Email{
+				To:       userEmail,
+				Subject:  "Welcome to " + siteName,
+				TextBody: text,
+				HTMLBody: html,
+			})
 		}
 	}
 
@@ -733,18 +756,16 @@ func (h *Handler) disable(w http.ResponseWriter, r *http.Request) {
 			if siteName == "" {
 				siteName = "Strata"
 			}
-			go func() {
-				text, html := mailer.AccountDisabledEmail(mailer.AccountDisabledEmailData{
-					AppName:  siteName,
-					UserName: userName,
-				})
-				_ = h.mailer.Send(mailer.Email{
-					To:       userEmail,
-					Subject:  "Your " + siteName + " account has been disabled",
-					TextBody: text,
-					HTMLBody: html,
-				})
-			}()
+			text, html := mailer.AccountDisabledEmail(mailer.AccountDisabledEmailData{
+				AppName:  siteName,
+				UserName: userName,
+			})
+			h.sendLater(r, "account-disabled-email", mailer.Email{
+				To:       userEmail,
+				Subject:  "Your " + siteName + " account has been disabled",
+				TextBody: text,
+				HTMLBody: html,
+			})
 		}
 	}
 
@@ -797,19 +818,17 @@ func (h *Handler) enable(w http.ResponseWriter, r *http.Request) {
 			if siteName == "" {
 				siteName = "Strata"
 			}
-			go func() {
-				text, html := mailer.AccountEnabledEmail(mailer.AccountEnabledEmailData{
-					AppName:  siteName,
-					UserName: userName,
-					LoginURL: "/login",
-				})
-				_ = h.mailer.Send(mailer.Email{
-					To:       userEmail,
-					Subject:  "Your " + siteName + " account has been enabled",
-					TextBody: text,
-					HTMLBody: html,
-				})
-			}()
+			text, html := mailer.AccountEnabledEmail(mailer.AccountEnabledEmailData{
+				AppName:  siteName,
+				UserName: userName,
+				LoginURL: "/login",
+			})
+			h.sendLater(r, "account-enabled-email", mailer.Email{
+				To:       userEmail,
+				Subject:  "Your " + siteName + " account has been enabled",
+				TextBody: text,
+				HTMLBody: html,
+			})
 		}
 	}
 
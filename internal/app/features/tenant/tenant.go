@@ -0,0 +1,185 @@
+// internal/app/features/tenant/tenant.go
+//
+// Package tenant routes requests to tenants hosted on subdomains of a base
+// domain, such as acme.app.com for the tenant "acme" under app.com.
+//
+// Middleware reads the tenant slug from the Host header, looks it up with a
+// Resolver, and stores the tenant in the request context for FromContext.
+// Hosts fall into these cases:
+//
+//	app.com, www.app.com      apex: no tenant, the request continues
+//	api.app.com               reserved subdomain: no tenant, the request continues
+//	acme.app.com              tenant "acme": resolved, or 404 if unknown
+//	a.b.app.com, -x.app.com   not a valid tenant host: 404
+//	other.example, 10.0.0.1   outside the base domain: no tenant, the request continues
+//
+// Handlers that only make sense for a tenant should check FromContext and
+// answer 404 when it reports none. Restrict which hosts are served at all
+// with security.AllowedHosts.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+// ErrUnknownTenant is returned by a Resolver when no tenant has the slug.
+var ErrUnknownTenant = errors.New("tenant: unknown tenant")
+
+// DefaultReserved lists the subdomains that are never tenant slugs. "www"
+// is always treated as the apex and need not be listed.
+var DefaultReserved = []string{"admin", "api", "app", "assets", "mail", "static", "status"}
+
+// Tenant is a resolved tenant.
+type Tenant struct {
+	ID   string
+	Slug string
+	Name string
+}
+
+// Resolver looks up the tenant for a slug. It returns ErrUnknownTenant when
+// there is none; any other error is answered with a 500.
+type Resolver interface {
+	Resolve(ctx context.Context, slug string) (*Tenant, error)
+}
+
+// ResolverFunc adapts a function to Resolver.
+type ResolverFunc func(ctx context.Context, slug string) (*Tenant, error)
+
+// Resolve calls f(ctx, slug).
+func (f ResolverFunc) Resolve(ctx context.Context, slug string) (*Tenant, error) {
+	return f(ctx, slug)
+}
+
+// config holds the settings built up by Options.
+type config struct {
+	reserved map[string]struct{}
+}
+
+// Option configures the tenant middleware.
+type Option func(*config)
+
+// WithReserved replaces DefaultReserved with slugs.
+func WithReserved(slugs ...string) Option {
+	return func(c *config) {
+		c.reserved = make(map[string]struct{}, len(slugs))
+		for _, s := range slugs {
+			c.reserved[strings.ToLower(s)] = struct{}{}
+		}
+	}
+}
+
+// contextKey is the context key for the resolved *Tenant.
+type contextKey struct{}
+
+// WithTenant returns a copy of ctx carrying t.
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant stored by Middleware, if any.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(contextKey{}).(*Tenant)
+	return t, ok && t != nil
+}
+
+// Middleware returns middleware that resolves the tenant for subdomains of
+// baseDomain (e.g. "app.com") with resolver. Unknown tenants and invalid
+// tenant hosts get h.NotFound, and resolver failures h.InternalErrorWithError;
+// with a nil h, plain-text responses are sent instead.
+func Middleware(h *errorsfeature.Handler, baseDomain string, resolver Resolver, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{}
+	WithReserved(DefaultReserved...)(&cfg)
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	baseDomain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(baseDomain)), ".")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug, ok := parseSlug(r.Host, baseDomain)
+			if !ok {
+				notFound(w, r, h)
+				return
+			}
+			if slug == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, reserved := cfg.reserved[slug]; reserved {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			t, err := resolver.Resolve(r.Context(), slug)
+			switch {
+			case errors.Is(err, ErrUnknownTenant) || (err == nil && t == nil):
+				notFound(w, r, h)
+				return
+			case err != nil:
+				if h != nil {
+					h.InternalErrorWithError(w, r, err)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), t)))
+		})
+	}
+}
+
+// parseSlug returns the tenant slug in hostport, or "" for the apex, www,
+// and hosts outside baseDomain. ok is false when hostport is a subdomain of
+// baseDomain that cannot be a tenant: nested, or not a valid DNS label.
+func parseSlug(hostport, baseDomain string) (slug string, ok bool) {
+	host := strings.ToLower(strings.TrimSpace(hostport))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	if host == baseDomain || baseDomain == "" {
+		return "", true
+	}
+	label, found := strings.CutSuffix(host, "."+baseDomain)
+	if !found {
+		return "", true
+	}
+	if label == "www" {
+		return "", true
+	}
+	if !validLabel(label) {
+		return "", false
+	}
+	return label, true
+}
+
+// validLabel reports whether s is a single DNS label: 1-63 letters, digits,
+// and hyphens, not starting or ending with a hyphen.
+func validLabel(s string) bool {
+	if len(s) == 0 || len(s) > 63 || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// notFound answers 404 through h, or in plain text when h is nil.
+func notFound(w http.ResponseWriter, r *http.Request, h *errorsfeature.Handler) {
+	if h != nil {
+		h.NotFound(w, r)
+		return
+	}
+	http.NotFound(w, r)
+}
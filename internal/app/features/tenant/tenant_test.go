@@ -0,0 +1,91 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+var tenants = ResolverFunc(func(_ context.Context, slug string) (*Tenant, error) {
+	switch slug {
+	case "acme":
+		return &Tenant{ID: "t1", Slug: "acme", Name: "Acme"}, nil
+	case "broken":
+		return nil, errors.New("database unavailable")
+	}
+	return nil, ErrUnknownTenant
+})
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		wantStatus int
+		wantTenant string // "" means no tenant in context
+	}{
+		{"tenant", "acme.app.com", http.StatusOK, "acme"},
+		{"tenant with port and case", "ACME.App.com:8443", http.StatusOK, "acme"},
+		{"apex", "app.com", http.StatusOK, ""},
+		{"www", "www.app.com", http.StatusOK, ""},
+		{"reserved", "api.app.com", http.StatusOK, ""},
+		{"other domain", "localhost:8080", http.StatusOK, ""},
+		{"unknown tenant", "nope.app.com", http.StatusNotFound, ""},
+		{"nested subdomain", "a.acme.app.com", http.StatusNotFound, ""},
+		{"invalid label", "-acme.app.com", http.StatusNotFound, ""},
+		{"resolver failure", "broken.app.com", http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tn, ok := FromContext(r.Context()); ok {
+					got = tn.Slug
+				}
+			})
+			h := Middleware(errorsfeature.NewHandler(), "app.com", tenants)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got != tt.wantTenant {
+				t.Errorf("tenant = %q, want %q", got, tt.wantTenant)
+			}
+		})
+	}
+}
+
+func TestWithReserved_ReplacesDefaults(t *testing.T) {
+	var resolved []string
+	resolver := ResolverFunc(func(_ context.Context, slug string) (*Tenant, error) {
+		resolved = append(resolved, slug)
+		return &Tenant{Slug: slug}, nil
+	})
+	h := Middleware(nil, "app.com", resolver, WithReserved("Docs"))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, host := range []string{"docs.app.com", "api.app.com"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(resolved) != 1 || resolved[0] != "api" {
+		t.Errorf("resolved = %v, want only [api]", resolved)
+	}
+}
+
+func TestFromContext_Empty(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext reported a tenant for an empty context")
+	}
+}
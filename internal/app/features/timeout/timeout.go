@@ -0,0 +1,272 @@
+// internal/app/features/timeout/timeout.go
+//
+// Package timeout bounds how long a handler may run.
+//
+// Middleware cancels each request's context after a fixed duration. If the
+// handler has not started its response by then, the client gets a
+// 504 Gateway Timeout page straight away and anything the handler writes
+// afterwards is discarded (Write returns http.ErrHandlerTimeout). If the
+// handler has already started writing, for example while streaming a file,
+// the response is left alone and the handler is expected to notice the
+// cancelled context and stop.
+//
+// Unlike http.TimeoutHandler, responses are not buffered, so downloads and
+// streamed responses reach the client as they are written. Routes that
+// serve long-lived connections, such as WebSocket and Server-Sent Events
+// endpoints, are exempted with WithExemptPaths; what the client sends
+// cannot switch the timeout off.
+//
+// The deadline is recorded on the request context, so handlers can read it
+// with httpx.Deadline and skip optional work when little time is left.
+package timeout
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+)
+
+// config holds the settings built up by Options.
+type config struct {
+	errors *errorsfeature.Handler
+	exempt []string
+}
+
+// Option configures the timeout middleware.
+type Option func(*config)
+
+// WithErrorHandler renders timeouts with h.Error. Without it, timeouts get a
+// plain-text 504.
+func WithErrorHandler(h *errorsfeature.Handler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// WithExemptPaths leaves requests for the given paths untimed. A path
+// ending in "/" exempts everything under it; any other path only itself.
+// Use it for routes that stay open for as long as the client does, such
+// as a WebSocket endpoint or an sse.Stream one.
+func WithExemptPaths(paths ...string) Option {
+	return func(c *config) {
+		c.exempt = append(c.exempt, paths...)
+	}
+}
+
+// isExempt reports whether path is one of the exempt paths.
+func (c config) isExempt(path string) bool {
+	for _, p := range c.exempt {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware cancels each request's context after d and answers with 504
+// if the handler has not responded by then. Panics in the handler are
+// re-raised on the serving goroutine so recovery middleware still sees them.
+func Middleware(d time.Duration, opts ...Option) func(http.Handler) http.Handler {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.isExempt(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The context is cancelled by the timer below rather than by its own
+			// deadline, so the timeout response is claimed before the handler can
+			// see the cancellation and race to write.
+			// The cancellation carries context.DeadlineExceeded as its cause, so
+			// the error logger can tell a timeout from a client going away.
+			ctx, cancel := context.WithCancelCause(r.Context())
+			defer cancel(nil)
+			r = r.WithContext(httpx.WithDeadline(ctx, time.Now().Add(d)))
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+				tw.finish()
+			case p := <-panicked:
+				panic(p)
+			case <-timer.C:
+				claimed := tw.timeout()
+				cancel(context.DeadlineExceeded)
+				if !claimed {
+					// The response was already under way; let the handler finish it.
+					select {
+					case <-done:
+						tw.finish()
+					case p := <-panicked:
+						panic(p)
+					}
+					return
+				}
+				if cfg.errors != nil {
+					cfg.errors.Error(w, r, http.StatusGatewayTimeout)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+			}
+		})
+	}
+}
+
+// timeoutWriter passes writes through to w until the request times out.
+// The handler gets its own header map, copied to w when the response
+// starts, so it cannot race with the timeout response. It reports the
+// status it has sent (httpx.StatusWriter), so the errors feature and
+// recovery middleware below it leave a started response alone.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	status      int
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// Flush implements http.Flusher for streaming handlers.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket and other upgraded connections take over the
+// connection, unless the request has already timed out. The timeout
+// response is not written afterwards.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := http.NewResponseController(tw.w).Hijack()
+	if err == nil && !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Written reports whether the handler has started its response.
+func (tw *timeoutWriter) Written() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.wroteHeader
+}
+
+// Status returns the status the handler sent, or 0 if it has sent none.
+func (tw *timeoutWriter) Status() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.status
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// writeHeaderLocked copies the handler's headers and sends status.
+// Informational (1xx) statuses are passed on and do not start the response.
+func (tw *timeoutWriter) writeHeaderLocked(status int) {
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	if status >= 100 && status < 200 {
+		tw.w.WriteHeader(status)
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = status
+	tw.w.WriteHeader(status)
+}
+
+// finish copies headers for a handler that returned without writing, so a
+// bare header-only response still reaches the client.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wroteHeader {
+		return
+	}
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+}
+
+// timeout marks the writer as timed out and reports whether the timeout
+// response may be written, i.e. the handler had not started its own.
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wroteHeader {
+		return false
+	}
+	tw.timedOut = true
+	return true
+}
@@ -0,0 +1,312 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/resources"
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/dalemusser/strataforge/internal/testutil"
+	"github.com/dalemusser/waffle/pantry/templates"
+	"go.uber.org/zap"
+)
+
+func TestMiddleware_FastHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+	rec := httptest.NewRecorder()
+	Middleware(time.Second)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if rec.Header().Get("X-Test") != "yes" {
+		t.Error("handler header was not copied to the response")
+	}
+	if rec.Body.String() != "created" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "created")
+	}
+}
+
+func TestMiddleware_ExposesDeadline(t *testing.T) {
+	var remaining time.Duration
+	var ok bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d time.Time
+		d, ok = httpx.Deadline(r.Context())
+		remaining = time.Until(d)
+	})
+	Middleware(time.Minute)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !ok {
+		t.Fatal("httpx.Deadline() ok = false inside the middleware")
+	}
+	if remaining <= 0 || remaining > time.Minute {
+		t.Errorf("time remaining = %v, want within (0, 1m]", remaining)
+	}
+}
+
+func TestMiddleware_HeaderOnlyResponse(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "yes")
+	})
+	rec := httptest.NewRecorder()
+	Middleware(time.Second)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Header().Get("X-Test") != "yes" {
+		t.Error("handler header was not copied to the response")
+	}
+}
+
+func TestMiddleware_SlowHandler(t *testing.T) {
+	writeErr := make(chan error, 1)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("X-Late", "yes")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("too late"))
+		writeErr <- err
+	})
+	mw := Middleware(10*time.Millisecond, WithErrorHandler(errorsfeature.NewHandler()))(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	select {
+	case err := <-writeErr:
+		if !errors.Is(err, http.ErrHandlerTimeout) {
+			t.Errorf("late Write error = %v, want http.ErrHandlerTimeout", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler did not finish")
+	}
+	if strings.Contains(rec.Body.String(), "too late") {
+		t.Error("late handler output reached the client")
+	}
+	if rec.Header().Get("X-Late") != "" {
+		t.Error("late handler header reached the client")
+	}
+}
+
+func TestMiddleware_SlowHandlerErrorPage(t *testing.T) {
+	// The error page is rendered after the request context is cancelled; it
+	// must still come out as the 504 page, written once.
+	resources.LoadSharedTemplates()
+	eng := templates.New(false)
+	if err := eng.Boot(zap.NewNop()); err != nil {
+		t.Fatalf("boot templates: %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	mw := Middleware(10*time.Millisecond, WithErrorHandler(errorsfeature.NewHandler(errorsfeature.WithEngine(eng))))(next)
+
+	req := testutil.WithCSRFToken(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<html") || !strings.Contains(body, "Gateway Timeout") {
+		t.Errorf("body = %q, want the rendered 504 page", body)
+	}
+	if strings.Contains(body, "Service Unavailable") {
+		t.Errorf("body = %q, want no 503 fallback", body)
+	}
+}
+
+func TestMiddleware_CancelCauseIsDeadline(t *testing.T) {
+	cause := make(chan error, 1)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		cause <- context.Cause(r.Context())
+	})
+	Middleware(10*time.Millisecond)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	select {
+	case err := <-cause:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("context.Cause() = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler did not finish")
+	}
+}
+
+func TestMiddleware_PlainTextWithoutErrorHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	rec := httptest.NewRecorder()
+	Middleware(10*time.Millisecond)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+}
+
+func TestMiddleware_StartedResponseIsNotReplaced(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		<-r.Context().Done()
+		w.Write([]byte(" rest"))
+	})
+	rec := httptest.NewRecorder()
+	Middleware(10*time.Millisecond)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Body.String() != "partial rest" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "partial rest")
+	}
+}
+
+func TestMiddleware_ErrorAfterPartialWrite(t *testing.T) {
+	// The errors feature must see that the handler has started its response
+	// and leave it alone, as it does without the timeout in between.
+	eh := errorsfeature.NewHandler(errorsfeature.WithPlainText())
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		eh.InternalError(w, r)
+	})
+	rec := httptest.NewRecorder()
+	httpx.Middleware(Middleware(time.Second)(next)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Body.String() != "partial" {
+		t.Errorf("body = %q, want %q", rec.Body.String(), "partial")
+	}
+}
+
+func TestMiddleware_ReportsStatus(t *testing.T) {
+	var before, after bool
+	var status int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before = httpx.Written(w)
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusAccepted)
+		after = httpx.Written(w)
+		status = w.(httpx.StatusWriter).Status()
+	})
+	Middleware(time.Second)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if before || !after {
+		t.Errorf("httpx.Written before/after WriteHeader = %v/%v, want false/true", before, after)
+	}
+	if status != http.StatusAccepted {
+		t.Errorf("Status() = %d, want %d", status, http.StatusAccepted)
+	}
+}
+
+func TestMiddleware_Hijack(t *testing.T) {
+	written := make(chan bool, 1)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() error = %v", err)
+			close(written)
+			return
+		}
+		written <- httpx.Written(w)
+		conn.Close()
+	})
+	srv := httptest.NewServer(Middleware(time.Second)(next))
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL); err == nil {
+		resp.Body.Close()
+	}
+	select {
+	case ok, hijacked := <-written:
+		if hijacked && !ok {
+			t.Error("httpx.Written = false after Hijack, want true")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler did not finish")
+	}
+}
+
+func TestMiddleware_PropagatesPanic(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want %q", p, "boom")
+		}
+	}()
+	Middleware(time.Second)(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Error("expected panic")
+}
+
+func TestMiddleware_SkipsLongLivedRequests(t *testing.T) {
+	tests := []struct {
+		name, path, header, value string
+	}{
+		{"websocket route", "/ws", "Upgrade", "websocket"},
+		{"exempt path", "/events", "", ""},
+		{"under exempt prefix", "/streams/jobs", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(30 * time.Millisecond)
+				if r.Context().Err() != nil {
+					t.Error("request context was canceled")
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			Middleware(10*time.Millisecond, WithExemptPaths("/ws", "/events", "/streams/"))(next).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestMiddleware_ClientHeadersAreTimed(t *testing.T) {
+	// Asking for an event stream or an upgrade does not opt a route out of
+	// the timeout.
+	headers := map[string]string{"Accept": "text/event-stream", "Upgrade": "websocket"}
+	for name, value := range headers {
+		for _, path := range []string{"/page", "/events/extra", "/streams"} {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			})
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(name, value)
+			rec := httptest.NewRecorder()
+			Middleware(10*time.Millisecond, WithExemptPaths("/events", "/streams/"))(next).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusGatewayTimeout {
+				t.Errorf("%s %s: status = %d, want %d", name, path, rec.Code, http.StatusGatewayTimeout)
+			}
+		}
+	}
+}
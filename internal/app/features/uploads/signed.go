@@ -0,0 +1,186 @@
+// internal/app/features/uploads/signed.go
+package uploads
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDownloadPath is where signed URLs point unless WithDownloadPath is
+// used. Mount DownloadHandler there.
+const DefaultDownloadPath = "/uploads"
+
+// WithSigningKey sets the secret signed download URLs are made with, such
+// as the app's session key. The HMAC key is derived from it, so the secret
+// itself never signs anything outside this package. Without it, SignedURL
+// returns "" and DownloadHandler refuses every request.
+func WithSigningKey(secret []byte) Option {
+	return func(c *config) {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte("strataforge/uploads download"))
+		c.signingKey = mac.Sum(nil)
+	}
+}
+
+// WithDownloadPath sets the path DownloadHandler is mounted at; signed URLs
+// are built under it. The default is DefaultDownloadPath.
+func WithDownloadPath(p string) Option {
+	return func(c *config) {
+		c.downloadPath = "/" + strings.Trim(p, "/")
+	}
+}
+
+// SignedURL returns a relative URL that downloads key through
+// DownloadHandler until expiry has passed, e.g.
+// "/uploads/uploads/3f2a...9c.pdf?expires=1767225600&sig=...". It returns ""
+// without a signing key.
+func (u *Uploader) SignedURL(key string, expiry time.Duration) string {
+	if u.cfg.signingKey == nil {
+		return ""
+	}
+	expires := strconv.FormatInt(u.now().Add(expiry).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("sig", u.sign(key, expires))
+	return u.cfg.downloadPath + "/" + key + "?" + q.Encode()
+}
+
+// DownloadHandler streams files addressed by SignedURL. A missing, wrong,
+// or expired signature gets 403 and a key with no file gets 404. Range
+// requests are supported, so interrupted downloads can resume.
+//
+// The Content-Type is sniffed from the file, as Save checked it, never
+// taken from the key, and the file is sent as an attachment, so an upload
+// is not opened as a page on the app's origin. Images and media still load
+// in <img> and <video> tags.
+//
+//	r.Get(uploads.DefaultDownloadPath+"/*", up.DownloadHandler().ServeHTTP)
+func (u *Uploader) DownloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, u.cfg.downloadPath+"/")
+		if !u.validSignature(key, r.URL.Query().Get("expires"), r.URL.Query().Get("sig")) {
+			u.fail(w, r, http.StatusForbidden, nil)
+			return
+		}
+
+		rc, err := u.store.Get(r.Context(), key)
+		switch {
+		case errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidKey):
+			u.fail(w, r, http.StatusNotFound, nil)
+			return
+		case err != nil:
+			u.fail(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		defer rc.Close()
+
+		content, cleanup, err := seekable(rc)
+		if err != nil {
+			u.fail(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		defer cleanup()
+
+		contentType, err := sniff(content)
+		if err != nil {
+			u.fail(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		// Signed URLs are for one holder; keep shared caches out of it.
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, key, time.Time{}, content)
+	})
+}
+
+// sign returns the URL signature for key and expires.
+func (u *Uploader) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, u.cfg.signingKey)
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validSignature reports whether sig signs key and expires, and expires is
+// still in the future.
+func (u *Uploader) validSignature(key, expires, sig string) bool {
+	if u.cfg.signingKey == nil || key == "" || expires == "" || sig == "" {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || u.now().Unix() > unix {
+		return false
+	}
+	want := u.sign(key, expires)
+	return hmac.Equal([]byte(sig), []byte(want))
+}
+
+// sniff returns the content type http.DetectContentType finds at the start
+// of rs and rewinds it.
+func sniff(rs io.ReadSeeker) (string, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(rs, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// seekable returns rc itself when it can seek, which ranges need, or else a
+// temporary file holding its contents. cleanup removes the temporary file.
+func seekable(rc io.ReadCloser) (rs io.ReadSeeker, cleanup func(), err error) {
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		return rs, func() {}, nil
+	}
+	f, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup = func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return f, cleanup, nil
+}
+
+// fail answers with status through the error handler, or in plain text.
+func (u *Uploader) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if u.cfg.errors == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	switch status {
+	case http.StatusForbidden:
+		u.cfg.errors.Forbidden(w, r)
+	case http.StatusNotFound:
+		u.cfg.errors.NotFound(w, r)
+	default:
+		u.cfg.errors.InternalErrorWithError(w, r, err)
+	}
+}
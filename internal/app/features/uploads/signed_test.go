@@ -0,0 +1,144 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedURL_Download(t *testing.T) {
+	store := newMemoryStore()
+	content := []byte("0123456789abcdef")
+	if err := store.Put(context.Background(), "uploads/report.txt", bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	up := New(store, WithSigningKey([]byte("app secret")))
+	handler := up.DownloadHandler()
+
+	link := up.SignedURL("uploads/report.txt", time.Hour)
+	if !strings.HasPrefix(link, DefaultDownloadPath+"/uploads/report.txt?") {
+		t.Fatalf("SignedURL = %q", link)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != string(content) {
+		t.Fatalf("download = %d %q, want 200 with the file", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, link, nil)
+	req.Header.Set("Range", "bytes=10-")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "abcdef" {
+		t.Errorf("range download = %d %q, want 206 \"abcdef\"", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDownloadHandler_ContentTypeFromContent(t *testing.T) {
+	store := newMemoryStore()
+	up := New(store, WithAllowedTypes("image/*"), WithSigningKey([]byte("app secret")))
+
+	// An allowed image named like a page is stored and served as an image.
+	rec := httptest.NewRecorder()
+	upload, ok := up.Receive(rec, uploadRequest(t, "evil.html", pngHeader), "file")
+	if !ok {
+		t.Fatalf("Receive failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.HasSuffix(upload.Key, ".png") {
+		t.Errorf("Key = %q, want the extension of the sniffed type, .png", upload.Key)
+	}
+
+	// A file stored under a page-like key is still not served as one.
+	_ = store.Put(context.Background(), "uploads/old.html", strings.NewReader("<html><script>alert(1)</script>"))
+
+	tests := []struct {
+		key, wantType string
+	}{
+		{upload.Key, "image/png"},
+		{"uploads/old.html", "text/html; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		up.DownloadHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, up.SignedURL(tt.key, time.Hour), nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d", tt.key, rec.Code, http.StatusOK)
+		}
+		if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.key, got, tt.wantType)
+		}
+		if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment") {
+			t.Errorf("%s: Content-Disposition = %q, want attachment", tt.key, got)
+		}
+	}
+}
+
+func TestDownloadHandler_Rejects(t *testing.T) {
+	store := newMemoryStore()
+	_ = store.Put(context.Background(), "uploads/a.txt", strings.NewReader("private"))
+
+	up := New(store, WithSigningKey([]byte("app secret")))
+	now := time.Unix(1_700_000_000, 0)
+	up.now = func() time.Time { return now }
+
+	valid := up.SignedURL("uploads/a.txt", time.Minute)
+	tampered := strings.Replace(valid, "/a.txt", "/b.txt", 1)
+	other := New(store, WithSigningKey([]byte("other secret"))).SignedURL("uploads/a.txt", time.Minute)
+	missing := up.SignedURL("uploads/missing.txt", time.Minute)
+
+	u, _ := url.Parse(valid)
+	unsigned := u.Path
+
+	tests := []struct {
+		name    string
+		target  string
+		advance time.Duration
+		want    int
+	}{
+		{"valid", valid, 0, http.StatusOK},
+		{"expired", valid, 2 * time.Minute, http.StatusForbidden},
+		{"different key", tampered, 0, http.StatusForbidden},
+		{"different secret", other, 0, http.StatusForbidden},
+		{"unsigned", unsigned, 0, http.StatusForbidden},
+		{"missing file", missing, 0, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up.now = func() time.Time { return now.Add(tt.advance) }
+			rec := httptest.NewRecorder()
+			up.DownloadHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedURL_NoSigningKey(t *testing.T) {
+	up := New(newMemoryStore())
+	if got := up.SignedURL("uploads/a.txt", time.Hour); got != "" {
+		t.Errorf("SignedURL without a key = %q, want empty", got)
+	}
+}
+
+func TestSeekable_SpoolsStreams(t *testing.T) {
+	rs, cleanup, err := seekable(io.NopCloser(strings.NewReader("streamed")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if _, err := rs.Seek(3, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := io.ReadAll(rs)
+	if string(rest) != "eamed" {
+		t.Errorf("read after seek = %q, want \"eamed\"", rest)
+	}
+}
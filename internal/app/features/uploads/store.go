@@ -0,0 +1,104 @@
+// internal/app/features/uploads/store.go
+package uploads
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/dalemusser/waffle/pantry/storage"
+)
+
+// ErrNotFound is returned by Store.Get when no object has the key.
+var ErrNotFound = errors.New("uploads: not found")
+
+// ErrInvalidKey is returned for keys that could escape the store's root:
+// empty, absolute, containing "..", a backslash, or characters outside
+// letters, digits, and "/._-".
+var ErrInvalidKey = errors.New("uploads: invalid key")
+
+// Store keeps uploaded files by key. Keys come from NewKey; Get and Delete
+// must still reject keys that fail CheckKey, since callers may pass keys
+// taken from requests.
+//
+// Implementations must be safe for concurrent use.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// CheckKey returns ErrInvalidKey if key is not a safe relative storage key.
+func CheckKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '/', c == '.', c == '_', c == '-':
+		default:
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// storageStore adapts a waffle storage backend to Store.
+type storageStore struct {
+	backend storage.Store
+}
+
+// FromStorage returns a Store backed by s, such as the file storage built
+// from the storage_* settings (local disk or S3 with CloudFront).
+func FromStorage(s storage.Store) Store {
+	return &storageStore{backend: s}
+}
+
+// NewLocalStore returns a Store that keeps files under dir on the local
+// filesystem, creating it if needed.
+func NewLocalStore(dir string) (Store, error) {
+	backend, err := storage.NewLocal(storage.LocalConfig{BasePath: dir})
+	if err != nil {
+		return nil, err
+	}
+	return FromStorage(backend), nil
+}
+
+// Put implements Store.
+func (s *storageStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	return s.backend.Put(ctx, key, r, nil)
+}
+
+// Get implements Store.
+func (s *storageStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := CheckKey(key); err != nil {
+		return nil, err
+	}
+	rc, err := s.backend.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return rc, err
+}
+
+// Delete implements Store. Deleting a missing key is not an error.
+func (s *storageStore) Delete(ctx context.Context, key string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	if err := s.backend.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return nil
+}
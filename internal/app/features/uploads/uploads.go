@@ -0,0 +1,373 @@
+// internal/app/features/uploads/uploads.go
+//
+// Package uploads receives user file uploads and stores them safely.
+//
+// An Uploader runs each file through the same pipeline:
+//
+//  1. The size is checked against the limit (WithMaxBytes).
+//  2. The content type is sniffed from the first bytes of the file, not
+//     taken from the client, and checked against WithAllowedTypes.
+//  3. The optional Scanner (WithScanner), such as a ClamAV client, reads
+//     the whole file and can reject it.
+//  4. The file is written to the Store under a key generated by NewKey,
+//     with an extension that follows the sniffed type. Client file names
+//     never become part of a key, so they cannot be used for path traversal
+//     or to have an image served as HTML.
+//
+// Receive does all of this for a multipart form field and answers files
+// that break the limits, or that the scanner rejects, with the errors
+// handler's 400 page. Save is the same pipeline for files the caller has
+// already opened.
+//
+// Private files are served without a public bucket through signed URLs:
+// SignedURL makes a link that expires, signed with HMAC-SHA256 under the
+// key given to WithSigningKey, and DownloadHandler checks the signature
+// before streaming the file, with range support for resumed downloads.
+//
+// Stores: FromStorage adapts the configured file storage (local disk, S3,
+// GCS, Azure), NewLocalStore keeps files in a directory, and S3Store talks to
+// any S3-compatible service directly when built with -tags s3.
+//
+// Usage:
+//
+//	up := uploads.New(uploads.FromStorage(fileStore),
+//		uploads.WithMaxBytes(10<<20),
+//		uploads.WithAllowedTypes("image/*", "application/pdf"),
+//		uploads.WithErrorHandler(errorsHandler),
+//	)
+//	upload, ok := up.Receive(w, r, "file")
+//	if !ok {
+//		return
+//	}
+package uploads
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/dalemusser/strataforge/internal/app/system/formutil"
+)
+
+// DefaultMaxBytes is the largest file accepted unless WithMaxBytes is used.
+const DefaultMaxBytes int64 = 10 << 20
+
+// DefaultKeyPrefix is the directory NewKey puts uploads under.
+const DefaultKeyPrefix = "uploads"
+
+// sniffLen is how much of a file http.DetectContentType looks at.
+const sniffLen = 512
+
+// formOverhead is room for the multipart framing and other fields on top of
+// the file size limit when Receive parses the form.
+const formOverhead = 1 << 20
+
+// Errors returned by Save. Receive answers all of them with 400.
+var (
+	ErrTooLarge       = errors.New("uploads: file too large")
+	ErrTypeNotAllowed = errors.New("uploads: file type not allowed")
+	// ErrRejected is returned, usually wrapped, by a Scanner that found a
+	// problem with the file. Any other Scanner error is a scan failure and
+	// the upload is refused with a 500.
+	ErrRejected = errors.New("uploads: file rejected by scanner")
+)
+
+// Scanner inspects a file before it is stored. Scan reads r to the end and
+// returns an error wrapping ErrRejected when the file must not be stored.
+type Scanner interface {
+	Scan(ctx context.Context, filename string, r io.Reader) error
+}
+
+// ScannerFunc adapts a function to Scanner.
+type ScannerFunc func(ctx context.Context, filename string, r io.Reader) error
+
+// Scan calls f(ctx, filename, r).
+func (f ScannerFunc) Scan(ctx context.Context, filename string, r io.Reader) error {
+	return f(ctx, filename, r)
+}
+
+// Upload describes a stored file.
+type Upload struct {
+	Key         string // storage key, from NewKey
+	Filename    string // base name the client sent; display only
+	ContentType string // sniffed from the content
+	Size        int64
+}
+
+// config holds the settings built up by Options.
+type config struct {
+	maxBytes  int64
+	allowed   []string
+	scanner   Scanner
+	keyPrefix string
+	errors    *errorsfeature.Handler
+
+	signingKey   []byte // HMAC key for signed download URLs; nil disables them
+	downloadPath string
+}
+
+// Option configures an Uploader.
+type Option func(*config)
+
+// WithMaxBytes sets the largest file accepted. The default is
+// DefaultMaxBytes.
+func WithMaxBytes(n int64) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxBytes = n
+		}
+	}
+}
+
+// WithAllowedTypes limits uploads to the given content types. A type
+// ending in "/*", like "image/*", allows every subtype. Without it, any type
+// is accepted.
+func WithAllowedTypes(types ...string) Option {
+	return func(c *config) {
+		for _, t := range types {
+			c.allowed = append(c.allowed, strings.ToLower(strings.TrimSpace(t)))
+		}
+	}
+}
+
+// WithScanner runs s on every file before it is stored.
+func WithScanner(s Scanner) Option {
+	return func(c *config) {
+		c.scanner = s
+	}
+}
+
+// WithKeyPrefix puts generated keys under prefix instead of
+// DefaultKeyPrefix.
+func WithKeyPrefix(prefix string) Option {
+	return func(c *config) {
+		c.keyPrefix = strings.Trim(prefix, "/")
+	}
+}
+
+// WithErrorHandler renders the 400, 403, 404, and 500 responses of Receive
+// and DownloadHandler through h. Without it, they are plain text.
+func WithErrorHandler(h *errorsfeature.Handler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// Uploader checks, scans, and stores uploaded files.
+type Uploader struct {
+	store Store
+	cfg   config
+	now   func() time.Time
+}
+
+// New creates an Uploader that stores files in store.
+func New(store Store, opts ...Option) *Uploader {
+	cfg := config{maxBytes: DefaultMaxBytes, keyPrefix: DefaultKeyPrefix, downloadPath: DefaultDownloadPath}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Uploader{store: store, cfg: cfg, now: time.Now}
+}
+
+// Save checks, scans, and stores file. It returns ErrTooLarge,
+// ErrTypeNotAllowed, or the scanner's ErrRejected error for files that
+// break the rules, and other errors when scanning or storing fails.
+func (u *Uploader) Save(ctx context.Context, file multipart.File, header *multipart.FileHeader) (*Upload, error) {
+	if header.Size > u.cfg.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	contentType := mediaType(http.DetectContentType(head[:n]))
+	if !u.allowedType(contentType) {
+		return nil, ErrTypeNotAllowed
+	}
+
+	filename := path.Base(strings.ReplaceAll(header.Filename, `\`, "/"))
+	if u.cfg.scanner != nil {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := u.cfg.scanner.Scan(ctx, filename, io.LimitReader(file, u.cfg.maxBytes)); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	key, err := NewKey(u.cfg.keyPrefix, contentType)
+	if err != nil {
+		return nil, err
+	}
+	counter := &countingReader{r: io.LimitReader(file, u.cfg.maxBytes+1)}
+	if err := u.store.Put(ctx, key, counter); err != nil {
+		return nil, err
+	}
+	// The header's size is the client's claim; a file that grew past the
+	// limit while being read is removed again.
+	if counter.n > u.cfg.maxBytes {
+		_ = u.store.Delete(context.WithoutCancel(ctx), key)
+		return nil, ErrTooLarge
+	}
+
+	return &Upload{
+		Key:         key,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        counter.n,
+	}, nil
+}
+
+// Receive saves the file uploaded in the multipart form field. It reports
+// false when it has already answered the request: with 400 for a missing
+// file, a malformed form, or a file Save refuses, and with 500 when
+// scanning or storing fails.
+func (u *Uploader) Receive(w http.ResponseWriter, r *http.Request, field string) (*Upload, bool) {
+	form, err := formutil.Parse(r, u.cfg.maxBytes+formOverhead)
+	if err != nil {
+		msg := "The upload could not be read."
+		if errors.Is(err, formutil.ErrTooLarge) {
+			msg = fmt.Sprintf("The file must not be larger than %d bytes.", u.cfg.maxBytes)
+		}
+		u.badRequest(w, r, field, msg)
+		return nil, false
+	}
+	defer form.Close()
+
+	file, header, err := form.File(field)
+	if err != nil {
+		u.badRequest(w, r, field, "Choose a file to upload.")
+		return nil, false
+	}
+
+	upload, err := u.Save(r.Context(), file, header)
+	switch {
+	case errors.Is(err, ErrTooLarge):
+		u.badRequest(w, r, field, fmt.Sprintf("The file must not be larger than %d bytes.", u.cfg.maxBytes))
+		return nil, false
+	case errors.Is(err, ErrTypeNotAllowed):
+		u.badRequest(w, r, field, "This type of file is not allowed.")
+		return nil, false
+	case errors.Is(err, ErrRejected):
+		u.badRequest(w, r, field, "The file was rejected.")
+		return nil, false
+	case err != nil:
+		if u.cfg.errors != nil {
+			u.cfg.errors.InternalErrorWithError(w, r, fmt.Errorf("upload %s: %w", field, err))
+		} else {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return upload, true
+}
+
+// NewKey returns a random storage key under prefix with the usual file
+// extension for contentType, e.g. "uploads/3f2a...9c.pdf" for
+// application/pdf. Types without an entry in typeExts, including every
+// type a browser would run as a page, such as text/html, get no extension.
+func NewKey(prefix, contentType string) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(b[:]) + typeExts[mediaType(contentType)]
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		key = prefix + "/" + key
+	}
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// typeExts maps the content types http.DetectContentType reports to the
+// extensions keys get for them. HTML, XML, and other markup are left out on
+// purpose, so no stored key looks like a page to a server or CDN.
+var typeExts = map[string]string{
+	"application/ogg":    ".ogg",
+	"application/pdf":    ".pdf",
+	"application/wasm":   ".wasm",
+	"application/x-gzip": ".gz",
+	"application/zip":    ".zip",
+	"audio/aiff":         ".aiff",
+	"audio/basic":        ".au",
+	"audio/midi":         ".mid",
+	"audio/mpeg":         ".mp3",
+	"audio/wave":         ".wav",
+	"font/otf":           ".otf",
+	"font/ttf":           ".ttf",
+	"font/woff":          ".woff",
+	"font/woff2":         ".woff2",
+	"image/bmp":          ".bmp",
+	"image/gif":          ".gif",
+	"image/jpeg":         ".jpg",
+	"image/png":          ".png",
+	"image/webp":         ".webp",
+	"image/x-icon":       ".ico",
+	"text/plain":         ".txt",
+	"video/avi":          ".avi",
+	"video/mp4":          ".mp4",
+	"video/webm":         ".webm",
+}
+
+// allowedType reports whether contentType passes WithAllowedTypes.
+func (u *Uploader) allowedType(contentType string) bool {
+	if len(u.cfg.allowed) == 0 {
+		return true
+	}
+	for _, t := range u.cfg.allowed {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(contentType, prefix+"/") {
+				return true
+			}
+		} else if contentType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaType strips parameters such as charset from a content type.
+func mediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	return contentType
+}
+
+// badRequest answers 400 with msg as the detail for field.
+func (u *Uploader) badRequest(w http.ResponseWriter, r *http.Request, field, msg string) {
+	if u.cfg.errors == nil {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	u.cfg.errors.BadRequestWithDetails(w, r, map[string]string{field: msg})
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,188 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/waffle/pantry/storage"
+)
+
+// pngHeader is enough of a PNG file for http.DetectContentType.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// uploadRequest builds a multipart POST with content as the "file" field.
+func uploadRequest(t *testing.T, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write(content)
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func newMemoryStore() Store {
+	return FromStorage(storage.NewMemory(storage.MemoryConfig{}))
+}
+
+func TestReceive_StoresFile(t *testing.T) {
+	store := newMemoryStore()
+	up := New(store, WithAllowedTypes("image/*"))
+
+	content := append(append([]byte(nil), pngHeader...), bytes.Repeat([]byte{0}, 100)...)
+	rec := httptest.NewRecorder()
+	upload, ok := up.Receive(rec, uploadRequest(t, "../../etc/photo.PNG", content), "file")
+	if !ok {
+		t.Fatalf("Receive failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if upload.ContentType != "image/png" {
+		t.Errorf("ContentType = %q, want image/png", upload.ContentType)
+	}
+	if upload.Filename != "photo.PNG" {
+		t.Errorf("Filename = %q, want photo.PNG", upload.Filename)
+	}
+	if upload.Size != int64(len(content)) {
+		t.Errorf("Size = %d, want %d", upload.Size, len(content))
+	}
+	if !strings.HasPrefix(upload.Key, DefaultKeyPrefix+"/") || !strings.HasSuffix(upload.Key, ".png") || strings.Contains(upload.Key, "..") {
+		t.Errorf("Key = %q, want a generated key under %s/ ending in .png", upload.Key, DefaultKeyPrefix)
+	}
+
+	rc, err := store.Get(context.Background(), upload.Key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer rc.Close()
+	got, _ := io.ReadAll(rc)
+	if !bytes.Equal(got, content) {
+		t.Error("stored content differs from the upload")
+	}
+}
+
+func TestReceive_RejectsBadFiles(t *testing.T) {
+	infected := ScannerFunc(func(_ context.Context, _ string, r io.Reader) error {
+		b, _ := io.ReadAll(r)
+		if bytes.Contains(b, []byte("EICAR")) {
+			return fmt.Errorf("eicar signature: %w", ErrRejected)
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"too large", append(append([]byte(nil), pngHeader...), bytes.Repeat([]byte{0}, 2048)...)},
+		{"type not allowed", []byte("plain text, not an image")},
+		{"rejected by scanner", append(append([]byte(nil), pngHeader...), []byte("EICAR")...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := New(newMemoryStore(),
+				WithMaxBytes(1024),
+				WithAllowedTypes("image/png"),
+				WithScanner(infected),
+			)
+			rec := httptest.NewRecorder()
+			if _, ok := up.Receive(rec, uploadRequest(t, "file.png", tt.content), "file"); ok {
+				t.Fatal("Receive accepted the file")
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestReceive_ScannerFailureIs500(t *testing.T) {
+	broken := ScannerFunc(func(context.Context, string, io.Reader) error {
+		return errors.New("clamd unreachable")
+	})
+	up := New(newMemoryStore(), WithScanner(broken))
+
+	rec := httptest.NewRecorder()
+	if _, ok := up.Receive(rec, uploadRequest(t, "a.png", pngHeader), "file"); ok {
+		t.Fatal("Receive accepted the file")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestReceive_MissingFile(t *testing.T) {
+	up := New(newMemoryStore())
+	rec := httptest.NewRecorder()
+	if _, ok := up.Receive(rec, uploadRequest(t, "a.png", pngHeader), "avatar"); ok {
+		t.Fatal("Receive accepted a missing field")
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	tests := []struct {
+		contentType string
+		wantExt     string
+	}{
+		{"application/pdf", ".pdf"},
+		{"image/jpeg", ".jpg"},
+		{"text/plain; charset=utf-8", ".txt"},
+		{"text/html; charset=utf-8", ""},
+		{"text/xml; charset=utf-8", ""},
+		{"application/octet-stream", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		key, err := NewKey("files/", tt.contentType)
+		if err != nil {
+			t.Fatalf("NewKey(%q): %v", tt.contentType, err)
+		}
+		if !strings.HasPrefix(key, "files/") || !strings.HasSuffix(key, tt.wantExt) || len(key) != len("files/")+32+len(tt.wantExt) {
+			t.Errorf("NewKey(%q) = %q, want files/<32 hex>%s", tt.contentType, key, tt.wantExt)
+		}
+	}
+}
+
+func TestCheckKey(t *testing.T) {
+	for _, key := range []string{"uploads/ab12.png", "a", "a/b-c_d.e"} {
+		if err := CheckKey(key); err != nil {
+			t.Errorf("CheckKey(%q) = %v, want nil", key, err)
+		}
+	}
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../b", "a//b", "a/", `a\b`, "a b", "./a"} {
+		if err := CheckKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("CheckKey(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestStore_GetMissingAndDelete(t *testing.T) {
+	store := newMemoryStore()
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "uploads/missing.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "uploads/missing.png"); err != nil {
+		t.Errorf("Delete missing = %v, want nil", err)
+	}
+	if _, err := store.Get(ctx, "../secret"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Get traversal = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,190 @@
+// internal/app/features/ws/conn.go
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message types, as used by ReadMessage.
+const (
+	TextMessage   = websocket.TextMessage
+	BinaryMessage = websocket.BinaryMessage
+)
+
+var (
+	// ErrClosed is returned when using a connection that has been closed.
+	ErrClosed = errors.New("ws: connection closed")
+	// ErrSlowPeer is returned by Send when the peer is not reading fast
+	// enough to keep up; the connection is closed.
+	ErrSlowPeer = errors.New("ws: send queue full")
+)
+
+// outbound is a message waiting for the writer goroutine.
+type outbound struct {
+	typ  int
+	data []byte
+}
+
+// Conn is an open WebSocket connection. Send and Close may be called from
+// any goroutine; ReadMessage, ReadJSON, and Wait must be called from one
+// goroutine at a time.
+type Conn struct {
+	ws   *websocket.Conn
+	cfg  *config
+	send chan outbound
+	done chan struct{}
+	once sync.Once
+}
+
+func newConn(ws *websocket.Conn, cfg *config) *Conn {
+	c := &Conn{
+		ws:   ws,
+		cfg:  cfg,
+		send: make(chan outbound, cfg.sendQueue),
+		done: make(chan struct{}),
+	}
+	ws.SetReadLimit(cfg.readLimit)
+	c.extendDeadline()
+	ws.SetPongHandler(func(string) error {
+		c.extendDeadline()
+		return nil
+	})
+	go c.writeLoop()
+	return c
+}
+
+// extendDeadline gives the peer two ping intervals to send something.
+func (c *Conn) extendDeadline() {
+	c.ws.SetReadDeadline(time.Now().Add(2 * c.cfg.pingInterval))
+}
+
+// ReadMessage waits for the next message from the peer. Any error closes
+// the connection; ErrClosed means Close was called.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	messageType, data, err = c.ws.ReadMessage()
+	if err != nil {
+		select {
+		case <-c.done:
+			err = ErrClosed
+		default:
+			c.shutdown()
+		}
+		return 0, nil, err
+	}
+	c.extendDeadline()
+	return messageType, data, nil
+}
+
+// ReadJSON reads the next message and decodes it into v.
+func (c *Conn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Wait reads and discards messages until the connection ends, for streams
+// where only the server sends. It returns nil when the peer closed normally
+// or Close was called.
+func (c *Conn) Wait() error {
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
+			if errors.Is(err, ErrClosed) || websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Send queues a text message. It does not block: if the peer has fallen
+// too far behind, the connection is closed and ErrSlowPeer returned.
+func (c *Conn) Send(data []byte) error {
+	return c.enqueue(outbound{typ: websocket.TextMessage, data: data})
+}
+
+// SendJSON queues v encoded as a JSON text message.
+func (c *Conn) SendJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(data)
+}
+
+func (c *Conn) enqueue(msg outbound) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.send <- msg:
+		return nil
+	default:
+		c.closeWith(websocket.CloseTryAgainLater, "too slow")
+		return ErrSlowPeer
+	}
+}
+
+// Done returns a channel that is closed when the connection ends.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close sends a normal close frame and closes the connection. Messages
+// still queued are dropped.
+func (c *Conn) Close() error {
+	return c.closeWith(websocket.CloseNormalClosure, "")
+}
+
+func (c *Conn) closeWith(code int, text string) error {
+	select {
+	case <-c.done:
+		return nil
+	default:
+	}
+	msg := websocket.FormatCloseMessage(code, text)
+	c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.cfg.writeTimeout))
+	c.shutdown()
+	return nil
+}
+
+// shutdown ends the connection without a close frame.
+func (c *Conn) shutdown() {
+	c.once.Do(func() {
+		close(c.done)
+		c.ws.Close()
+	})
+}
+
+// writeLoop is the only goroutine that writes data messages. Pings and
+// close frames go through WriteControl, which may run alongside it.
+func (c *Conn) writeLoop() {
+	ticker := time.NewTicker(c.cfg.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(c.cfg.writeTimeout))
+			if err := c.ws.WriteMessage(msg.typ, msg.data); err != nil {
+				c.shutdown()
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.cfg.writeTimeout)); err != nil {
+				c.shutdown()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// internal/app/features/ws/hub.go
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Hub broadcasts messages to a set of connections, such as every open
+// dashboard. Connections leave the hub on their own when they close.
+type Hub struct {
+	mu     sync.Mutex
+	conns  map[*Conn]struct{}
+	closed bool
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub {
+	return &Hub{conns: make(map[*Conn]struct{})}
+}
+
+// Add registers c for broadcasts until it closes. Adding to a closed hub
+// closes c.
+func (h *Hub) Add(c *Conn) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		c.closeWith(websocket.CloseGoingAway, "")
+		return
+	}
+	h.conns[c] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-c.Done()
+		h.Remove(c)
+	}()
+}
+
+// Remove stops broadcasting to c without closing it.
+func (h *Hub) Remove(c *Conn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	h.mu.Unlock()
+}
+
+// Len returns the number of connections in the hub.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.conns)
+}
+
+// Broadcast queues a text message on every connection. Peers too slow to
+// keep up are disconnected rather than holding up the others.
+func (h *Hub) Broadcast(data []byte) {
+	for _, c := range h.snapshot() {
+		c.Send(data)
+	}
+}
+
+// BroadcastJSON broadcasts v encoded as JSON.
+func (h *Hub) BroadcastJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.Broadcast(data)
+	return nil
+}
+
+// Close disconnects every connection with a going-away close frame and
+// refuses new ones. Call it during shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+
+	for _, c := range h.snapshot() {
+		c.closeWith(websocket.CloseGoingAway, "")
+	}
+}
+
+// snapshot copies the connection set so sends happen without the lock.
+func (h *Hub) snapshot() []*Conn {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns := make([]*Conn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
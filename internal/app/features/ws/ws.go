@@ -0,0 +1,192 @@
+// internal/app/features/ws/ws.go
+//
+// Package ws upgrades HTTP requests to WebSocket connections for pages that
+// receive live updates.
+//
+// An Upgrader performs the handshake and returns a *Conn. Failed handshakes
+// (a plain GET, a missing key, a cross-origin page) are answered through the
+// errors feature, normally with 400 Bad Request. Each Conn keeps itself
+// alive with pings and drops peers that stop answering, limits the size of
+// incoming messages, and queues outgoing messages for a single writer
+// goroutine so handlers and a Hub can send at the same time.
+//
+// A connection only processes pongs and close frames while something is
+// reading from it, so handlers must keep calling ReadMessage (or Wait, for
+// push-only streams) for as long as the connection is open:
+//
+//	conn, err := upgrader.Upgrade(w, r)
+//	if err != nil {
+//	    return // the error response has been sent
+//	}
+//	hub.Add(conn)
+//	conn.Wait()
+//
+// Upgraded connections are hijacked from net/http, so http.Server.Shutdown
+// does not wait for them; call Hub.Close during shutdown.
+//
+// The request timeout middleware would cancel the connection's request
+// context after its deadline; exempt WebSocket routes with
+// timeout.WithExemptPaths.
+package ws
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	"github.com/gorilla/websocket"
+)
+
+// Defaults used when the matching Option is not given.
+const (
+	DefaultReadLimit    int64 = 64 << 10
+	DefaultPingInterval       = 30 * time.Second
+	DefaultWriteTimeout       = 10 * time.Second
+	DefaultSendQueue          = 64
+)
+
+// config holds the settings built up by Options.
+type config struct {
+	errors       *errorsfeature.Handler
+	readLimit    int64
+	pingInterval time.Duration
+	writeTimeout time.Duration
+	sendQueue    int
+	checkOrigin  func(r *http.Request) bool
+}
+
+// Option configures an Upgrader.
+type Option func(*config)
+
+// WithErrorHandler renders failed handshakes with h.Error. Without it, they
+// get a plain-text response.
+func WithErrorHandler(h *errorsfeature.Handler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// WithReadLimit sets the largest incoming message, in bytes. A peer that
+// sends more is disconnected. Zero keeps DefaultReadLimit.
+func WithReadLimit(n int64) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.readLimit = n
+		}
+	}
+}
+
+// WithPingInterval sets how often the server pings the peer. A peer that
+// sends nothing, not even a pong, for two intervals is disconnected. Zero
+// keeps DefaultPingInterval.
+func WithPingInterval(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.pingInterval = d
+		}
+	}
+}
+
+// WithWriteTimeout bounds how long a single write to the peer may take.
+// Zero keeps DefaultWriteTimeout.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.writeTimeout = d
+		}
+	}
+}
+
+// WithSendQueue sets how many outgoing messages may wait for a slow peer
+// before it is disconnected. Zero keeps DefaultSendQueue.
+func WithSendQueue(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.sendQueue = n
+		}
+	}
+}
+
+// WithCheckOrigin replaces the default origin check, which accepts only
+// browsers on the same host and clients that send no Origin header.
+func WithCheckOrigin(fn func(r *http.Request) bool) Option {
+	return func(c *config) {
+		c.checkOrigin = fn
+	}
+}
+
+// Upgrader turns HTTP requests into WebSocket connections. It is safe for
+// concurrent use.
+type Upgrader struct {
+	cfg      config
+	upgrader websocket.Upgrader
+}
+
+// New returns an Upgrader configured by opts.
+func New(opts ...Option) *Upgrader {
+	cfg := config{
+		readLimit:    DefaultReadLimit,
+		pingInterval: DefaultPingInterval,
+		writeTimeout: DefaultWriteTimeout,
+		sendQueue:    DefaultSendQueue,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	u := &Upgrader{cfg: cfg}
+	u.upgrader = websocket.Upgrader{
+		HandshakeTimeout: cfg.writeTimeout,
+		CheckOrigin:      cfg.checkOrigin,
+		Error:            u.handshakeError,
+	}
+	return u
+}
+
+// Upgrade upgrades the request with the default settings and no errors
+// handler. See Upgrader.Upgrade.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	return New().Upgrade(w, r)
+}
+
+// Upgrade completes the WebSocket handshake and returns the open
+// connection. On failure the error response has already been written and
+// the handler should simply return.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	ws, err := u.upgrader.Upgrade(hijacker{w}, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(ws, &u.cfg), nil
+}
+
+// handshakeError answers a failed handshake. gorilla/websocket reports
+// malformed handshakes as 400, bad origins as 403, wrong methods as 405,
+// and unsupported versions as 426.
+func (u *Upgrader) handshakeError(w http.ResponseWriter, r *http.Request, status int, _ error) {
+	if status == http.StatusUpgradeRequired || status == http.StatusBadRequest {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+	}
+	if u.cfg.errors != nil {
+		u.cfg.errors.Error(w, r, status)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// hijacker finds the connection through middleware writers that expose
+// Unwrap but not Hijack, such as the session and ETag wrappers.
+type hijacker struct {
+	http.ResponseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController.
+func (h hijacker) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
@@ -0,0 +1,207 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+	etagfeature "github.com/dalemusser/strataforge/internal/app/features/etag"
+	timeoutfeature "github.com/dalemusser/strataforge/internal/app/features/timeout"
+	"github.com/gorilla/websocket"
+)
+
+// dial opens a client connection to srv.
+func dial(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestUpgrade_EchoThroughMiddleware(t *testing.T) {
+	u := New()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.Send(append([]byte("echo: "), data...))
+		}
+	})
+	// The ETag and timeout wrappers do not implement http.Hijacker; the
+	// WebSocket route is exempt from the timeout.
+	chain := etagfeature.Middleware()(timeoutfeature.Middleware(50*time.Millisecond, timeoutfeature.WithExemptPaths("/"))(handler))
+	srv := httptest.NewServer(chain)
+	defer srv.Close()
+
+	client := dial(t, srv)
+	time.Sleep(100 * time.Millisecond) // outlive the request timeout
+	if err := client.WriteMessage(websocket.TextMessage, []byte("hi")); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	_, got, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if string(got) != "echo: hi" {
+		t.Errorf("got %q, want %q", got, "echo: hi")
+	}
+}
+
+func TestUpgrade_FailureUsesErrorHandler(t *testing.T) {
+	u := New(WithErrorHandler(errorsfeature.NewHandler()))
+	req := httptest.NewRequest(http.MethodGet, "/live", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+
+	conn, err := u.Upgrade(rec, req)
+	if err == nil || conn != nil {
+		t.Fatal("Upgrade() of a plain GET should fail")
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, "application/json") {
+		t.Errorf("Content-Type = %q, want the errors handler's JSON response", ct)
+	}
+}
+
+func TestConn_ReadLimit(t *testing.T) {
+	readErr := make(chan error, 1)
+	u := New(WithReadLimit(16))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		_, _, err = conn.ReadMessage()
+		readErr <- err
+	}))
+	defer srv.Close()
+
+	client := dial(t, srv)
+	client.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 64)))
+
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Error("ReadMessage() of an oversized message should fail")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("server did not reject the oversized message")
+	}
+}
+
+func TestConn_KeepAlive(t *testing.T) {
+	waitErr := make(chan error, 1)
+	u := New(WithPingInterval(20 * time.Millisecond))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		waitErr <- conn.Wait()
+	}))
+	defer srv.Close()
+
+	// A client that reads answers pings and stays connected.
+	client := dial(t, srv)
+	pings := make(chan struct{}, 16)
+	client.SetPingHandler(func(data string) error {
+		pings <- struct{}{}
+		return client.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	time.Sleep(150 * time.Millisecond)
+	select {
+	case err := <-waitErr:
+		t.Fatalf("responsive client was disconnected: %v", err)
+	default:
+	}
+	if len(pings) == 0 {
+		t.Error("server sent no pings")
+	}
+
+	// A client that never reads does not answer pings and is dropped, as is
+	// the first client once it closes.
+	dial(t, srv)
+	client.Close()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-waitErr:
+		case <-time.After(time.Second):
+			t.Fatal("unresponsive client was not disconnected")
+		}
+	}
+}
+
+func TestHub_Broadcast(t *testing.T) {
+	hub := NewHub()
+	u := New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := u.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		hub.Add(conn)
+		conn.Wait()
+	}))
+	defer srv.Close()
+
+	clients := []*websocket.Conn{dial(t, srv), dial(t, srv)}
+	deadline := time.Now().Add(time.Second)
+	for hub.Len() < len(clients) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hub.Len() != len(clients) {
+		t.Fatalf("hub.Len() = %d, want %d", hub.Len(), len(clients))
+	}
+
+	if err := hub.BroadcastJSON(map[string]int{"online": 2}); err != nil {
+		t.Fatalf("BroadcastJSON() error = %v", err)
+	}
+	for i, c := range clients {
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		_, got, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("client %d ReadMessage() error = %v", i, err)
+		}
+		if string(got) != `{"online":2}` {
+			t.Errorf("client %d got %q", i, got)
+		}
+	}
+
+	clients[0].Close()
+	for hub.Len() != 1 && time.Now().Before(deadline.Add(time.Second)) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hub.Len() != 1 {
+		t.Errorf("hub.Len() after a client left = %d, want 1", hub.Len())
+	}
+
+	hub.Close()
+	clients[1].SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := clients[1].ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("after hub.Close() client error = %v, want going-away close", err)
+	}
+}
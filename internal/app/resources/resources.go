@@ -8,6 +8,8 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
+	"github.com/dalemusser/strataforge/internal/app/system/templatefuncs"
 	"github.com/dalemusser/waffle/pantry/assets"
 	"github.com/dalemusser/waffle/pantry/templates"
 )
@@ -27,16 +29,19 @@ var (
 )
 
 func init() {
-	templates.RegisterFunc("tailwindVersion", func() string { return tailwindVersion })
-	templates.RegisterFunc("tiptapVersion", func() string { return tiptapVersion })
-	templates.RegisterFunc("htmxVersion", func() string { return htmxVersion })
+	templatefuncs.MustRegister("tailwindVersion", func() string { return tailwindVersion })
+	templatefuncs.MustRegister("tiptapVersion", func() string { return tiptapVersion })
+	templatefuncs.MustRegister("htmxVersion", func() string { return htmxVersion })
+	templatefuncs.MustRegister("cspNonce", csp.Attr)
 }
 
 var registerOnce sync.Once
 
-// LoadSharedTemplates registers shared templates (layout, menu) with the waffle template engine.
+// LoadSharedTemplates registers shared templates (layout, menu) with the waffle template engine,
+// and installs the shared template functions they use.
 // This must be called before templates.Boot() in BuildHandler.
 func LoadSharedTemplates() {
+	templatefuncs.Install()
 	registerOnce.Do(func() {
 		templates.Register(templates.Set{
 			Name:     "shared",
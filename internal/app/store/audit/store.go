@@ -17,8 +17,9 @@ import (
 
 // Event categories
 const (
-	CategoryAuth  = "auth"
-	CategoryAdmin = "admin"
+	CategoryAuth   = "auth"
+	CategoryAdmin  = "admin"
+	CategoryAccess = "access"
 )
 
 // Auth event types
@@ -48,6 +49,13 @@ const (
 	EventPageUpdated     = "page_updated"
 )
 
+// Access event types, from the session manager's role and sign-in checks
+const (
+	EventAccessGranted         = "access_granted"         // passed a role check
+	EventAccessDenied          = "access_denied"          // signed in without an allowed role (403)
+	EventAccessUnauthenticated = "access_unauthenticated" // not signed in (401)
+)
+
 // Event represents an audit event.
 type Event struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
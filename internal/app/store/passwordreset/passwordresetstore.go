@@ -8,7 +8,10 @@ package passwordreset
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -18,12 +21,22 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Errors returned by VerifyToken and Consume.
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrTokenExpired = errors.New("reset token has expired")
+	ErrTokenUsed    = errors.New("reset token has already been used")
+)
+
 // Reset represents a password reset request.
+// Only the SHA-256 hash of the token is stored; Token holds the raw value
+// and is set only on the Reset returned by Create, for building the link.
 type Reset struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
 	UserID    primitive.ObjectID `bson:"user_id"`
 	Email     string             `bson:"email"`
-	Token     string             `bson:"token"`
+	Token     string             `bson:"-"`
+	TokenHash string             `bson:"token_hash"`
 	Used      bool               `bson:"used"`
 	ExpiresAt time.Time          `bson:"expires_at"`
 	CreatedAt time.Time          `bson:"created_at"`
@@ -51,7 +64,7 @@ func (s *Store) EnsureIndexes(ctx context.Context) error {
 			Options: options.Index(),
 		},
 		{
-			Keys:    bson.D{{Key: "token", Value: 1}},
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
 		{
@@ -60,6 +73,10 @@ func (s *Store) EnsureIndexes(ctx context.Context) error {
 		},
 	}
 
+	// Tokens used to be stored in plain text under a unique "token" index,
+	// which would now reject every insert once two records lack the field.
+	_, _ = s.c.Indexes().DropOne(ctx, "token_1")
+
 	_, err := s.c.Indexes().CreateMany(ctx, indexes)
 	return err
 }
@@ -84,7 +101,7 @@ func (s *Store) Create(ctx context.Context, userID primitive.ObjectID, email str
 		ID:        primitive.NewObjectID(),
 		UserID:    userID,
 		Email:     email,
-		Token:     token,
+		TokenHash: hashToken(token),
 		Used:      false,
 		ExpiresAt: now.Add(s.expiry),
 		CreatedAt: now,
@@ -94,28 +111,57 @@ func (s *Store) Create(ctx context.Context, userID primitive.ObjectID, email str
 		return nil, err
 	}
 
+	r.Token = token
 	return &r, nil
 }
 
 // VerifyToken verifies a reset token and returns the reset record if valid.
+// It returns ErrInvalidToken for an unknown token, and ErrTokenExpired or
+// ErrTokenUsed for one that matched a record that can no longer be used.
 func (s *Store) VerifyToken(ctx context.Context, token string) (*Reset, error) {
-	var r Reset
-	filter := bson.M{
-		"token":      token,
-		"used":       false,
-		"expires_at": bson.M{"$gt": time.Now()},
+	if token == "" {
+		return nil, ErrInvalidToken
 	}
+	hash := hashToken(token)
 
-	if err := s.c.FindOne(ctx, filter).Decode(&r); err != nil {
+	var r Reset
+	if err := s.c.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&r); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("invalid or expired token")
+			return nil, ErrInvalidToken
 		}
 		return nil, err
 	}
+	if subtle.ConstantTimeCompare([]byte(r.TokenHash), []byte(hash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	if r.Used {
+		return nil, ErrTokenUsed
+	}
+	if !time.Now().Before(r.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
 
 	return &r, nil
 }
 
+// Consume marks an unused reset as used and returns ErrTokenUsed if another
+// request claimed it first, so a token can set a password only once.
+func (s *Store) Consume(ctx context.Context, id primitive.ObjectID) error {
+	res, err := s.c.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "used": false},
+		bson.M{"$set": bson.M{"used": true}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount == 0 {
+		return ErrTokenUsed
+	}
+	return nil
+}
+
 // MarkUsed marks a reset token as used.
 func (s *Store) MarkUsed(ctx context.Context, id primitive.ObjectID) error {
 	_, err := s.c.UpdateOne(
@@ -134,3 +180,9 @@ func generateToken() (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(b), nil
 }
+
+// hashToken returns the hex SHA-256 of token, the form kept in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,12 @@
 package passwordreset
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/dalemusser/strataforge/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -156,8 +158,8 @@ func TestStore_VerifyToken(t *testing.T) {
 
 	// Invalid token
 	_, err = store.VerifyToken(ctx, "invalid-token")
-	if err == nil {
-		t.Error("VerifyToken() should fail for invalid token")
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("VerifyToken() error = %v, want ErrInvalidToken", err)
 	}
 }
 
@@ -179,8 +181,8 @@ func TestStore_VerifyToken_Expired(t *testing.T) {
 
 	// Should fail
 	_, err = store.VerifyToken(ctx, created.Token)
-	if err == nil {
-		t.Error("VerifyToken() should fail for expired token")
+	if !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("VerifyToken() error = %v, want ErrTokenExpired", err)
 	}
 }
 
@@ -203,8 +205,8 @@ func TestStore_VerifyToken_Used(t *testing.T) {
 
 	// Should fail
 	_, err = store.VerifyToken(ctx, created.Token)
-	if err == nil {
-		t.Error("VerifyToken() should fail for used token")
+	if !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("VerifyToken() error = %v, want ErrTokenUsed", err)
 	}
 }
 
@@ -245,6 +247,48 @@ func TestStore_MarkUsed_NonexistentID(t *testing.T) {
 	}
 }
 
+func TestStore_Create_StoresHashOnly(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testExpiry)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	created, err := store.Create(ctx, primitive.NewObjectID(), "hash@example.com")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var raw bson.M
+	if err := store.c.FindOne(ctx, bson.M{"_id": created.ID}).Decode(&raw); err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if _, ok := raw["token"]; ok {
+		t.Error("raw token should not be stored")
+	}
+	if raw["token_hash"] != hashToken(created.Token) {
+		t.Errorf("token_hash = %v, want hash of returned token", raw["token_hash"])
+	}
+}
+
+func TestStore_Consume(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testExpiry)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	created, err := store.Create(ctx, primitive.NewObjectID(), "consume@example.com")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := store.Consume(ctx, created.ID); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if err := store.Consume(ctx, created.ID); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("second Consume() error = %v, want ErrTokenUsed", err)
+	}
+}
+
 func TestGenerateToken(t *testing.T) {
 	tokens := make(map[string]bool)
 
@@ -301,8 +345,8 @@ func TestStore_Create_VerifyToken_EndToEnd(t *testing.T) {
 	if err == nil {
 		t.Error("Token should be invalid after use")
 	}
-	if err != nil && err.Error() != "invalid or expired token" {
-		t.Errorf("Expected 'invalid or expired token' error, got: %v", err)
+	if err != nil && !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("Expected ErrTokenUsed, got: %v", err)
 	}
 }
 
@@ -321,7 +365,7 @@ func TestStore_EnsureIndexes_UniqueToken(t *testing.T) {
 		ID:        primitive.NewObjectID(),
 		UserID:    primitive.NewObjectID(),
 		Email:     "dup1@example.com",
-		Token:     "duplicate-token",
+		TokenHash: hashToken("duplicate-token"),
 		Used:      false,
 		ExpiresAt: time.Now().Add(time.Hour),
 		CreatedAt: time.Now(),
@@ -336,7 +380,7 @@ func TestStore_EnsureIndexes_UniqueToken(t *testing.T) {
 		ID:        primitive.NewObjectID(),
 		UserID:    primitive.NewObjectID(),
 		Email:     "dup2@example.com",
-		Token:     "duplicate-token", // Same token
+		TokenHash: hashToken("duplicate-token"), // Same token
 		Used:      false,
 		ExpiresAt: time.Now().Add(time.Hour),
 		CreatedAt: time.Now(),
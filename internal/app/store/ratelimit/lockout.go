@@ -0,0 +1,103 @@
+// internal/app/store/ratelimit/lockout.go
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LockoutStore is a lockout.Store in MongoDB, shared by every instance. Each
+// key is one document; a TTL index on expires_at removes it once both its
+// count and its lock have expired.
+type LockoutStore struct {
+	c *mongo.Collection
+}
+
+// NewLockoutStore creates a LockoutStore on the login_lockouts collection.
+func NewLockoutStore(db *mongo.Database) *LockoutStore {
+	return &LockoutStore{c: db.Collection("login_lockouts")}
+}
+
+// EnsureIndexes creates the unique key index and the TTL index.
+func (s *LockoutStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.c.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_lockout_key"),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("idx_lockout_ttl"),
+		},
+	})
+	return err
+}
+
+// Incr implements lockout.Store. The count is updated with a single
+// pipeline update, so concurrent failures are all counted.
+func (s *LockoutStore) Incr(ctx context.Context, key string, window time.Duration) (int, error) {
+	now := time.Now()
+	active := bson.M{"$gt": bson.A{"$count_expires_at", now}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"count":            bson.M{"$cond": bson.A{active, bson.M{"$add": bson.A{"$count", 1}}, 1}},
+			"count_expires_at": bson.M{"$cond": bson.A{active, "$count_expires_at", now.Add(window)}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"expires_at": bson.M{"$max": bson.A{"$count_expires_at", "$locked_until"}},
+		}}},
+	}
+
+	var doc struct {
+		Count int `bson:"count"`
+	}
+	err := s.c.FindOneAndUpdate(ctx, bson.M{"key": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+	return doc.Count, nil
+}
+
+// Lock implements lockout.Store.
+func (s *LockoutStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	now := time.Now()
+	until := now.Add(d)
+	_, err := s.c.UpdateOne(ctx, bson.M{"key": key},
+		bson.M{"$set": bson.M{
+			"count":            0,
+			"count_expires_at": now,
+			"locked_until":     until,
+			"expires_at":       until,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Locked implements lockout.Store.
+func (s *LockoutStore) Locked(ctx context.Context, key string) (time.Duration, error) {
+	now := time.Now()
+	var doc struct {
+		LockedUntil time.Time `bson:"locked_until"`
+	}
+	err := s.c.FindOne(ctx, bson.M{"key": key, "locked_until": bson.M{"$gt": now}}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return doc.LockedUntil.Sub(now), nil
+}
+
+// Reset implements lockout.Store.
+func (s *LockoutStore) Reset(ctx context.Context, key string) error {
+	_, err := s.c.DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/testutil"
+)
+
+func TestLockoutStore(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := NewLockoutStore(db)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	if err := store.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes() error = %v", err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		n, err := store.Incr(ctx, "account:alice", time.Minute)
+		if err != nil {
+			t.Fatalf("Incr() error = %v", err)
+		}
+		if n != want {
+			t.Errorf("Incr() = %d, want %d", n, want)
+		}
+	}
+
+	if d, err := store.Locked(ctx, "account:alice"); err != nil || d != 0 {
+		t.Errorf("Locked() before Lock = %v, %v; want 0", d, err)
+	}
+	if err := store.Lock(ctx, "account:alice", time.Minute); err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	d, err := store.Locked(ctx, "account:alice")
+	if err != nil {
+		t.Fatalf("Locked() error = %v", err)
+	}
+	if d <= 0 || d > time.Minute {
+		t.Errorf("Locked() = %v, want within (0, 1m]", d)
+	}
+	if n, _ := store.Incr(ctx, "account:alice", time.Minute); n != 1 {
+		t.Errorf("Incr() after Lock = %d, want the count cleared", n)
+	}
+
+	if err := store.Reset(ctx, "account:alice"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if d, _ := store.Locked(ctx, "account:alice"); d != 0 {
+		t.Errorf("Locked() after Reset = %v, want 0", d)
+	}
+}
+
+func TestLockoutStore_WindowExpiry(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := NewLockoutStore(db)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	if _, err := store.Incr(ctx, "ip:198.51.100.1", time.Millisecond); err != nil {
+		t.Fatalf("Incr() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if n, _ := store.Incr(ctx, "ip:198.51.100.1", time.Minute); n != 1 {
+		t.Errorf("Incr() after the window = %d, want 1", n)
+	}
+}
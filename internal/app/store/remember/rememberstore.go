@@ -0,0 +1,255 @@
+// internal/app/store/remember/rememberstore.go
+package remember
+
+// Terminology: User Identifiers
+//   - UserID / userID / user_id: The MongoDB ObjectID (_id) that uniquely identifies a user record
+//   - LoginID / loginID / login_id: The human-readable string users type to log in
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Errors returned by Use.
+var (
+	// ErrInvalidToken is returned for a malformed, unknown, or expired token.
+	ErrInvalidToken = errors.New("invalid or expired remember-me token")
+	// ErrTokenTheft is returned when a known selector arrives with a
+	// validator that is neither the current nor the just-replaced one. The
+	// series has been deleted by the time it is returned.
+	ErrTokenTheft = errors.New("remember-me validator mismatch; series revoked")
+)
+
+// rotationGrace is how long the validator replaced by a rotation is still
+// accepted. Browsers often send several requests at once after a restart,
+// all carrying the old cookie; only the first rotates, and the rest must not
+// look like theft.
+const rotationGrace = 30 * time.Second
+
+// Token is one remember-me series: a browser that asked to stay logged in.
+//
+// The cookie holds "selector:validator". The selector finds the record and
+// never changes; the validator is replaced on every use and only its SHA-256
+// is stored. Validator holds the raw value and is set only on the Token
+// returned by Issue and Use, for writing the cookie.
+type Token struct {
+	ID                    primitive.ObjectID `bson:"_id,omitempty"`
+	Selector              string             `bson:"selector"`
+	Validator             string             `bson:"-"`
+	ValidatorHash         string             `bson:"validator_hash"`
+	PreviousValidatorHash string             `bson:"previous_validator_hash,omitempty"`
+	RotatedAt             *time.Time         `bson:"rotated_at,omitempty"`
+	UserID                primitive.ObjectID `bson:"user_id"`
+	IPAddress             string             `bson:"ip_address,omitempty"`
+	UserAgent             string             `bson:"user_agent,omitempty"`
+	LastUsedAt            *time.Time         `bson:"last_used_at,omitempty"`
+	ExpiresAt             time.Time          `bson:"expires_at"`
+	CreatedAt             time.Time          `bson:"created_at"`
+}
+
+// CookieValue returns the value for the remember-me cookie.
+func (t *Token) CookieValue() string {
+	return t.Selector + ":" + t.Validator
+}
+
+// Store provides access to the remember_tokens collection.
+type Store struct {
+	c      *mongo.Collection
+	maxAge time.Duration
+}
+
+// New creates a remember-me store whose series last maxAge from Issue.
+func New(db *mongo.Database, maxAge time.Duration) *Store {
+	return &Store{
+		c:      db.Collection("remember_tokens"),
+		maxAge: maxAge,
+	}
+}
+
+// MaxAge returns how long a series lasts.
+func (s *Store) MaxAge() time.Duration {
+	return s.maxAge
+}
+
+// EnsureIndexes creates necessary indexes for the collection.
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "selector", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("idx_remember_selector"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("idx_remember_user"),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("idx_remember_ttl"),
+		},
+	}
+	_, err := s.c.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// Issue starts a new series for userID and returns it with its validator.
+func (s *Store) Issue(ctx context.Context, userID primitive.ObjectID, ipAddress, userAgent string) (*Token, error) {
+	selector, err := randomString(18)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	t := Token{
+		ID:            primitive.NewObjectID(),
+		Selector:      selector,
+		ValidatorHash: hashValidator(validator),
+		UserID:        userID,
+		IPAddress:     ipAddress,
+		UserAgent:     userAgent,
+		ExpiresAt:     now.Add(s.maxAge),
+		CreatedAt:     now,
+	}
+	if _, err := s.c.InsertOne(ctx, t); err != nil {
+		return nil, err
+	}
+
+	t.Validator = validator
+	return &t, nil
+}
+
+// Use checks a cookie value from the browser and, if it is valid, replaces
+// the series' validator and returns the token with the new one.
+//
+// A validator replaced less than rotationGrace ago is still accepted: the
+// token is returned with an empty Validator, and the caller should restore
+// the session without writing the cookie, leaving it to the request that
+// rotated. Any other validator for a known selector means the cookie was
+// copied and used elsewhere; the series is deleted and ErrTokenTheft is
+// returned along with the token, so the caller can audit whose it was.
+//
+// The series' expiry is fixed at Issue; using it does not extend it.
+func (s *Store) Use(ctx context.Context, cookieValue string) (*Token, error) {
+	selector, validator, ok := strings.Cut(cookieValue, ":")
+	if !ok || selector == "" || validator == "" {
+		return nil, ErrInvalidToken
+	}
+
+	var t Token
+	err := s.c.FindOne(ctx, bson.M{
+		"selector":   selector,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hash := hashValidator(validator)
+	if subtle.ConstantTimeCompare([]byte(t.ValidatorHash), []byte(hash)) != 1 {
+		if t.inGrace(hash) {
+			return &t, nil
+		}
+		if err := s.Delete(ctx, selector); err != nil {
+			return nil, err
+		}
+		return &t, ErrTokenTheft
+	}
+
+	newValidator, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	// Compare-and-swap on the old hash: of several requests racing with the
+	// same cookie, exactly one rotates.
+	res, err := s.c.UpdateOne(ctx,
+		bson.M{"_id": t.ID, "validator_hash": t.ValidatorHash},
+		bson.M{"$set": bson.M{
+			"validator_hash":          hashValidator(newValidator),
+			"previous_validator_hash": t.ValidatorHash,
+			"rotated_at":              now,
+			"last_used_at":            now,
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if res.ModifiedCount == 0 {
+		// Another request rotated first; its grace window covers this one.
+		return &t, nil
+	}
+
+	t.PreviousValidatorHash = t.ValidatorHash
+	t.ValidatorHash = hashValidator(newValidator)
+	t.RotatedAt = &now
+	t.LastUsedAt = &now
+	t.Validator = newValidator
+	return &t, nil
+}
+
+// Delete removes the series identified by selector.
+func (s *Store) Delete(ctx context.Context, selector string) error {
+	_, err := s.c.DeleteOne(ctx, bson.M{"selector": selector})
+	return err
+}
+
+// DeleteByCookie removes the series a cookie value belongs to, whatever its
+// validator. Logout uses it.
+func (s *Store) DeleteByCookie(ctx context.Context, cookieValue string) error {
+	selector, _, _ := strings.Cut(cookieValue, ":")
+	if selector == "" {
+		return nil
+	}
+	return s.Delete(ctx, selector)
+}
+
+// DeleteByUser removes every series for a user, as after a password change.
+func (s *Store) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := s.c.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// inGrace reports whether hash is the validator replaced by a rotation less
+// than rotationGrace ago.
+func (t *Token) inGrace(hash string) bool {
+	if t.PreviousValidatorHash == "" || t.RotatedAt == nil || time.Since(*t.RotatedAt) >= rotationGrace {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(t.PreviousValidatorHash), []byte(hash)) == 1
+}
+
+// randomString returns n random bytes, URL-safe base64 encoded without
+// padding so the value never contains the ':' separator.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashValidator returns the hex SHA-256 of validator, the form kept in the
+// database.
+func hashValidator(validator string) string {
+	sum := sha256.Sum256([]byte(validator))
+	return hex.EncodeToString(sum[:])
+}
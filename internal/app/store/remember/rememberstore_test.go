@@ -0,0 +1,202 @@
+package remember
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testMaxAge = 30 * 24 * time.Hour
+
+func TestStore_EnsureIndexes(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testMaxAge)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	if err := store.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes() error = %v", err)
+	}
+	if err := store.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes() second call error = %v", err)
+	}
+}
+
+func TestStore_Issue(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testMaxAge)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	userID := primitive.NewObjectID()
+	tok, err := store.Issue(ctx, userID, "127.0.0.1", "test-agent")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if tok.Selector == "" || tok.Validator == "" {
+		t.Fatal("Issue() returned an empty selector or validator")
+	}
+	if tok.UserID != userID {
+		t.Errorf("UserID = %v, want %v", tok.UserID, userID)
+	}
+
+	// Only the hash is stored
+	var raw bson.M
+	if err := db.Collection("remember_tokens").FindOne(ctx, bson.M{"selector": tok.Selector}).Decode(&raw); err != nil {
+		t.Fatalf("FindOne() error = %v", err)
+	}
+	if raw["validator_hash"] == tok.Validator {
+		t.Error("validator stored in plain text")
+	}
+	if _, ok := raw["validator"]; ok {
+		t.Error("raw validator field stored")
+	}
+}
+
+func TestStore_Use_Rotates(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testMaxAge)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	issued, err := store.Issue(ctx, primitive.NewObjectID(), "", "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	used, err := store.Use(ctx, issued.CookieValue())
+	if err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if used.Selector != issued.Selector {
+		t.Errorf("Selector = %q, want %q", used.Selector, issued.Selector)
+	}
+	if used.Validator == "" || used.Validator == issued.Validator {
+		t.Errorf("Validator = %q, want a new one", used.Validator)
+	}
+	if used.UserID != issued.UserID {
+		t.Errorf("UserID = %v, want %v", used.UserID, issued.UserID)
+	}
+
+	// The new cookie works in turn
+	if _, err := store.Use(ctx, used.CookieValue()); err != nil {
+		t.Fatalf("Use() with rotated cookie error = %v", err)
+	}
+}
+
+func TestStore_Use_GraceAfterRotation(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testMaxAge)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	issued, err := store.Issue(ctx, primitive.NewObjectID(), "", "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if _, err := store.Use(ctx, issued.CookieValue()); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+
+	// A parallel request with the old cookie is accepted without rotating
+	tok, err := store.Use(ctx, issued.CookieValue())
+	if err != nil {
+		t.Fatalf("Use() with just-replaced cookie error = %v", err)
+	}
+	if tok.Validator != "" {
+		t.Errorf("Validator = %q, want empty (no rotation)", tok.Validator)
+	}
+}
+
+func TestStore_Use_TheftRevokesSeries(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testMaxAge)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	issued, err := store.Issue(ctx, primitive.NewObjectID(), "", "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	first, err := store.Use(ctx, issued.CookieValue())
+	if err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if _, err := store.Use(ctx, first.CookieValue()); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+
+	// The original validator is two rotations old: out of grace
+	tok, err := store.Use(ctx, issued.CookieValue())
+	if !errors.Is(err, ErrTokenTheft) {
+		t.Fatalf("Use() error = %v, want ErrTokenTheft", err)
+	}
+	if tok == nil || tok.UserID != issued.UserID {
+		t.Errorf("Use() token = %+v, want the stolen series", tok)
+	}
+
+	// The whole series is gone, including its current validator
+	n, err := db.Collection("remember_tokens").CountDocuments(ctx, bson.M{"selector": issued.Selector})
+	if err != nil {
+		t.Fatalf("CountDocuments() error = %v", err)
+	}
+	if n != 0 {
+		t.Errorf("series count = %d, want 0", n)
+	}
+}
+
+func TestStore_Use_Invalid(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testMaxAge)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	for _, v := range []string{"", "no-separator", ":validator", "selector:", "unknown:validator"} {
+		if _, err := store.Use(ctx, v); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Use(%q) error = %v, want ErrInvalidToken", v, err)
+		}
+	}
+}
+
+func TestStore_Use_Expired(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, -time.Minute)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	issued, err := store.Issue(ctx, primitive.NewObjectID(), "", "")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if _, err := store.Use(ctx, issued.CookieValue()); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Use() error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestStore_DeleteByUser(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	store := New(db, testMaxAge)
+	ctx, cancel := testutil.TestContext()
+	defer cancel()
+
+	userID := primitive.NewObjectID()
+	a, _ := store.Issue(ctx, userID, "", "")
+	b, _ := store.Issue(ctx, userID, "", "")
+	other, _ := store.Issue(ctx, primitive.NewObjectID(), "", "")
+
+	if err := store.DeleteByUser(ctx, userID); err != nil {
+		t.Fatalf("DeleteByUser() error = %v", err)
+	}
+	for _, tok := range []*Token{a, b} {
+		if _, err := store.Use(ctx, tok.CookieValue()); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Use() after DeleteByUser error = %v, want ErrInvalidToken", err)
+		}
+	}
+	if _, err := store.Use(ctx, other.CookieValue()); err != nil {
+		t.Errorf("other user's token: Use() error = %v", err)
+	}
+}
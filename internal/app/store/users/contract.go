@@ -0,0 +1,39 @@
+// internal/app/store/users/contract.go
+package userstore
+
+import (
+	"context"
+
+	"github.com/dalemusser/strataforge/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotFound is returned by UserStore lookups that match no user. It is
+// mongo.ErrNoDocuments, which the MongoDB Store already returns, so
+// callers can test either with errors.Is.
+var ErrNotFound = mongo.ErrNoDocuments
+
+// UserStore is the storage contract the authentication features (login,
+// password reset, remember-me) depend on. Store is the MongoDB
+// implementation; MemoryStore is for tests, and SQLStore shows how to back
+// it with a relational database. Password hashes are produced and checked
+// by an authutil.PasswordHasher (bcrypt by default), never by the store.
+//
+// Lookups return ErrNotFound when no user matches. Login IDs and emails
+// are matched case-insensitively. Create normalizes and validates u, as
+// Store.Create does, and returns ErrDuplicateLoginID for a login ID that is
+// taken.
+type UserStore interface {
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	GetByLoginID(ctx context.Context, loginID string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Create(ctx context.Context, u models.User) (models.User, error)
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error
+}
+
+var (
+	_ UserStore = (*Store)(nil)
+	_ UserStore = (*MemoryStore)(nil)
+	_ UserStore = (*SQLStore)(nil)
+)
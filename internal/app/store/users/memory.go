@@ -0,0 +1,100 @@
+// internal/app/store/users/memory.go
+package userstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/normalize"
+	"github.com/dalemusser/strataforge/internal/domain/models"
+	"github.com/dalemusser/waffle/pantry/text"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore is an in-process UserStore for tests of the features that
+// depend on one. Lookups return a copy of the stored user.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{users: make(map[primitive.ObjectID]models.User)}
+}
+
+// GetByID implements UserStore.
+func (s *MemoryStore) GetByID(_ context.Context, id primitive.ObjectID) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+// GetByLoginID implements UserStore.
+func (s *MemoryStore) GetByLoginID(_ context.Context, loginID string) (*models.User, error) {
+	folded := text.Fold(loginID)
+	return s.find(func(u models.User) bool {
+		return u.LoginIDCI != nil && *u.LoginIDCI == folded
+	})
+}
+
+// GetByEmail implements UserStore.
+func (s *MemoryStore) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	normalized := normalize.Email(email)
+	return s.find(func(u models.User) bool {
+		return u.Email != nil && *u.Email == normalized
+	})
+}
+
+// Create implements UserStore.
+func (s *MemoryStore) Create(_ context.Context, u models.User) (models.User, error) {
+	u, err := prepareNew(u)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.LoginIDCI != nil {
+		for _, existing := range s.users {
+			if existing.LoginIDCI != nil && *existing.LoginIDCI == *u.LoginIDCI {
+				return models.User{}, ErrDuplicateLoginID
+			}
+		}
+	}
+	s.users[u.ID] = u
+	return u, nil
+}
+
+// UpdatePassword implements UserStore. Like Store.UpdatePassword, it clears
+// the temporary-password flag and is a no-op for an unknown id.
+func (s *MemoryStore) UpdatePassword(_ context.Context, id primitive.ObjectID, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	temp := false
+	u.PasswordHash = &passwordHash
+	u.PasswordTemp = &temp
+	u.UpdatedAt = time.Now()
+	s.users[id] = u
+	return nil
+}
+
+func (s *MemoryStore) find(match func(models.User) bool) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if match(u) {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,109 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/system/authutil"
+	"github.com/dalemusser/strataforge/internal/domain/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryStore_CreateAndLookups(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	loginID := "Test@Example.com"
+	email := "Contact@Example.com"
+	created, err := store.Create(ctx, models.User{
+		FullName:   "Test User",
+		LoginID:    &loginID,
+		Email:      &email,
+		AuthMethod: "password",
+		Role:       "admin",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID.IsZero() || created.CreatedAt.IsZero() || created.Status != "active" {
+		t.Errorf("Create() = %+v, want an ID, timestamps, and active status", created)
+	}
+
+	for name, lookup := range map[string]func() (*models.User, error){
+		"GetByID":      func() (*models.User, error) { return store.GetByID(ctx, created.ID) },
+		"GetByLoginID": func() (*models.User, error) { return store.GetByLoginID(ctx, "TEST@example.COM") },
+		"GetByEmail":   func() (*models.User, error) { return store.GetByEmail(ctx, "contact@EXAMPLE.com") },
+	} {
+		u, err := lookup()
+		if err != nil {
+			t.Errorf("%s() error = %v", name, err)
+			continue
+		}
+		if u.ID != created.ID {
+			t.Errorf("%s() ID = %v, want %v", name, u.ID, created.ID)
+		}
+	}
+}
+
+func TestMemoryStore_NotFound(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	if _, err := store.GetByID(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID() error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.GetByLoginID(ctx, "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByLoginID() error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.GetByEmail(ctx, "nobody@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByEmail() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStore_Create_Validation(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	loginID := "dup@example.com"
+	if _, err := store.Create(ctx, models.User{LoginID: &loginID, Role: "admin"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	upper := "DUP@example.com"
+	if _, err := store.Create(ctx, models.User{LoginID: &upper, Role: "admin"}); !errors.Is(err, ErrDuplicateLoginID) {
+		t.Errorf("Create() duplicate error = %v, want ErrDuplicateLoginID", err)
+	}
+	other := "other@example.com"
+	if _, err := store.Create(ctx, models.User{LoginID: &other, Role: "nope"}); err == nil {
+		t.Error("Create() with invalid role succeeded")
+	}
+}
+
+func TestMemoryStore_UpdatePassword(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	temp := true
+	loginID := "pw@example.com"
+	created, err := store.Create(ctx, models.User{LoginID: &loginID, Role: "admin", PasswordTemp: &temp})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	hasher := authutil.BcryptHasher{Cost: 4}
+	hash, err := hasher.Hash("correct horse battery")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if err := store.UpdatePassword(ctx, created.ID, hash); err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+
+	u, _ := store.GetByID(ctx, created.ID)
+	if u.PasswordHash == nil || !hasher.Compare("correct horse battery", *u.PasswordHash) {
+		t.Error("stored hash does not verify")
+	}
+	if u.PasswordTemp == nil || *u.PasswordTemp {
+		t.Error("UpdatePassword() did not clear PasswordTemp")
+	}
+}
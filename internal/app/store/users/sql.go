@@ -0,0 +1,145 @@
+// internal/app/store/users/sql.go
+package userstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/dberrors"
+	"github.com/dalemusser/strataforge/internal/app/system/normalize"
+	"github.com/dalemusser/strataforge/internal/domain/models"
+	"github.com/dalemusser/waffle/pantry/text"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SQLSchema creates the table SQLStore uses. It is written for PostgreSQL;
+// other databases need their own column types. IDs stay ObjectID hex
+// strings so users can move between stores without changing identity.
+const SQLSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id               CHAR(24)    PRIMARY KEY,
+	full_name        TEXT        NOT NULL,
+	full_name_ci     TEXT        NOT NULL,
+	login_id         TEXT,
+	login_id_ci      TEXT        UNIQUE,
+	email            TEXT,
+	auth_method      TEXT        NOT NULL,
+	password_hash    TEXT,
+	password_temp    BOOLEAN     NOT NULL DEFAULT FALSE,
+	role             TEXT        NOT NULL,
+	status           TEXT        NOT NULL,
+	theme_preference TEXT        NOT NULL DEFAULT '',
+	created_at       TIMESTAMPTZ NOT NULL,
+	updated_at       TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS users_email ON users (email);
+`
+
+// SQLStore is an example UserStore backed by database/sql, for
+// applications that keep users in a relational database. Queries use
+// PostgreSQL's $n placeholders. The application supplies the driver (for
+// example pgx's stdlib package) and creates the table with SQLSchema.
+type SQLStore struct {
+	db          *sql.DB
+	isDuplicate func(error) bool
+}
+
+// NewSQLStore creates a SQLStore on db. isDuplicate reports whether an
+// insert failed on the unique login ID (for pgx, a *pgconn.PgError with
+// code 23505); it may be nil, in which case dberrors.IsUniqueViolation,
+// which knows the common drivers' errors, is used.
+func NewSQLStore(db *sql.DB, isDuplicate func(error) bool) *SQLStore {
+	if isDuplicate == nil {
+		isDuplicate = dberrors.IsUniqueViolation
+	}
+	return &SQLStore{db: db, isDuplicate: isDuplicate}
+}
+
+const sqlUserColumns = `id, full_name, full_name_ci, login_id, login_id_ci, email, auth_method,
+	password_hash, password_temp, role, status, theme_preference, created_at, updated_at`
+
+// GetByID implements UserStore.
+func (s *SQLStore) GetByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	return s.queryOne(ctx, `SELECT `+sqlUserColumns+` FROM users WHERE id = $1`, id.Hex())
+}
+
+// GetByLoginID implements UserStore.
+func (s *SQLStore) GetByLoginID(ctx context.Context, loginID string) (*models.User, error) {
+	return s.queryOne(ctx, `SELECT `+sqlUserColumns+` FROM users WHERE login_id_ci = $1`, text.Fold(loginID))
+}
+
+// GetByEmail implements UserStore.
+func (s *SQLStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.queryOne(ctx, `SELECT `+sqlUserColumns+` FROM users WHERE email = $1 LIMIT 1`, normalize.Email(email))
+}
+
+// Create implements UserStore.
+func (s *SQLStore) Create(ctx context.Context, u models.User) (models.User, error) {
+	u, err := prepareNew(u)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO users (`+sqlUserColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		u.ID.Hex(), u.FullName, u.FullNameCI, u.LoginID, u.LoginIDCI, u.Email, u.AuthMethod,
+		u.PasswordHash, u.PasswordTemp != nil && *u.PasswordTemp, u.Role, u.Status, u.ThemePreference,
+		u.CreatedAt, u.UpdatedAt,
+	)
+	if err != nil {
+		if s.isDuplicate(err) {
+			return models.User{}, ErrDuplicateLoginID
+		}
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// UpdatePassword implements UserStore. It clears the temporary-password
+// flag, as Store.UpdatePassword does.
+func (s *SQLStore) UpdatePassword(ctx context.Context, id primitive.ObjectID, passwordHash string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE users SET password_hash = $1, password_temp = FALSE, updated_at = $2 WHERE id = $3`,
+		passwordHash, time.Now(), id.Hex(),
+	)
+	return err
+}
+
+// queryOne runs a query for a single user row.
+func (s *SQLStore) queryOne(ctx context.Context, query string, args ...any) (*models.User, error) {
+	var (
+		u                                       models.User
+		id                                      string
+		loginID, loginIDCI, email, passwordHash sql.NullString
+		passwordTemp                            bool
+	)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(
+		&id, &u.FullName, &u.FullNameCI, &loginID, &loginIDCI, &email, &u.AuthMethod,
+		&passwordHash, &passwordTemp, &u.Role, &u.Status, &u.ThemePreference, &u.CreatedAt, &u.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if u.ID, err = primitive.ObjectIDFromHex(id); err != nil {
+		return nil, err
+	}
+	u.LoginID = nullString(loginID)
+	u.LoginIDCI = nullString(loginIDCI)
+	u.Email = nullString(email)
+	u.PasswordHash = nullString(passwordHash)
+	u.PasswordTemp = &passwordTemp
+	return &u, nil
+}
+
+func nullString(ns sql.NullString) *string {
+	if !ns.Valid {
+		return nil
+	}
+	return &ns.String
+}
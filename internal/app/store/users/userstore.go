@@ -88,6 +88,24 @@ var (
 
 // Create inserts a new user after normalizing & validating fields.
 func (s *Store) Create(ctx context.Context, u models.User) (models.User, error) {
+	u, err := prepareNew(u)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	// Insert
+	if _, err := s.c.InsertOne(ctx, u); err != nil {
+		if wafflemongo.IsDup(err) {
+			return models.User{}, ErrDuplicateLoginID
+		}
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// prepareNew assigns a new user its ID and timestamps and normalizes and
+// validates its fields. Every UserStore's Create goes through it.
+func prepareNew(u models.User) (models.User, error) {
 	// Normalize core fields
 	u.ID = primitive.NewObjectID()
 	u.FullName = normalize.Name(u.FullName)
@@ -125,14 +143,6 @@ func (s *Store) Create(ctx context.Context, u models.User) (models.User, error)
 	now := time.Now()
 	u.CreatedAt = now
 	u.UpdatedAt = now
-
-	// Insert
-	if _, err := s.c.InsertOne(ctx, u); err != nil {
-		if wafflemongo.IsDup(err) {
-			return models.User{}, ErrDuplicateLoginID
-		}
-		return models.User{}, err
-	}
 	return u, nil
 }
 
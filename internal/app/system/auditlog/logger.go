@@ -8,9 +8,13 @@ package auditlog
 import (
 	"context"
 	"net/http"
+	"net/netip"
 	"strconv"
+	"strings"
 
 	"github.com/dalemusser/strataforge/internal/app/store/audit"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"github.com/dalemusser/strataforge/internal/app/system/network"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
@@ -23,37 +27,78 @@ type Config struct {
 	// Admin controls logging for admin action events (user CRUD, settings changes).
 	// Values: "all" (MongoDB + zap), "db" (MongoDB only), "log" (zap only), "off" (disabled)
 	Admin string
+
+	// Access controls logging for access checks (role checks passed, 401 and 403 denials).
+	// Values: "all" (MongoDB + zap), "db" (MongoDB only), "log" (zap only), "off" (disabled)
+	Access string
+
+	// Sinks receive every event that is not turned off, in addition to MongoDB
+	// and zap, e.g. to forward the trail to a SIEM.
+	Sinks []Sink
+
+	// TrustedProxies are the IPs or CIDR ranges of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed when recording the
+	// client IP. From any other peer the connection's address is recorded.
+	TrustedProxies []string
+}
+
+// Sink receives audit events. Write is called synchronously for each event,
+// so slow sinks should buffer; an error is logged and does not stop other
+// destinations.
+type Sink interface {
+	Write(ctx context.Context, event audit.Event) error
+}
+
+// SinkFunc adapts a function to Sink.
+type SinkFunc func(ctx context.Context, event audit.Event) error
+
+// Write calls f(ctx, event).
+func (f SinkFunc) Write(ctx context.Context, event audit.Event) error {
+	return f(ctx, event)
 }
 
 // Logger provides convenience methods for logging audit events.
-// It logs to both MongoDB (via audit.Store) and structured logs (via zap).
+// It logs to both MongoDB (via audit.Store) and structured logs (via zap),
+// plus any configured Sinks. Pass it a zap logger dedicated to the audit
+// trail (see bootstrap) so audit lines can be kept apart from the app log.
 type Logger struct {
-	store  *audit.Store
-	zapLog *zap.Logger
-	config Config
+	store          *audit.Store
+	zapLog         *zap.Logger
+	config         Config
+	trustedProxies []netip.Prefix
 }
 
 // New creates a new audit Logger.
 func New(store *audit.Store, zapLog *zap.Logger, config Config) *Logger {
 	return &Logger{
-		store:  store,
-		zapLog: zapLog,
-		config: config,
+		store:          store,
+		zapLog:         zapLog,
+		config:         config,
+		trustedProxies: network.ParsePrefixes(config.TrustedProxies),
 	}
 }
 
-// getClientIP extracts the client IP from the request.
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (for reverse proxies)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		return xff
+// NewZapLogger returns the zap logger for the audit trail. With a path, audit
+// lines are written as JSON to that file (or "stdout"/"stderr"), apart from
+// the app log; without one, they go to appLog under the "audit" name.
+func NewZapLogger(path string, appLog *zap.Logger) (*zap.Logger, error) {
+	if path == "" {
+		return appLog.Named("audit"), nil
 	}
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	cfg := zap.NewProductionConfig()
+	cfg.OutputPaths = []string{path}
+	cfg.Sampling = nil // every audit event must be kept
+	cfg.DisableStacktrace = true
+	return cfg.Build()
+}
+
+// clientIP returns the client IP of r, believing forwarding headers only
+// from the configured trusted proxies.
+func (l *Logger) clientIP(r *http.Request) string {
+	if l == nil {
+		return network.ClientIP(r, nil)
 	}
-	// Fall back to RemoteAddr
-	return r.RemoteAddr
+	return network.ClientIP(r, l.trustedProxies)
 }
 
 // logToZap logs the event to zap with consistent structure.
@@ -101,6 +146,8 @@ func (l *Logger) Log(ctx context.Context, event audit.Event) {
 		setting = l.config.Auth
 	case audit.CategoryAdmin:
 		setting = l.config.Admin
+	case audit.CategoryAccess:
+		setting = l.config.Access
 	default:
 		setting = "all" // Default to logging everything for unknown categories
 	}
@@ -116,7 +163,7 @@ func (l *Logger) Log(ctx context.Context, event audit.Event) {
 	}
 
 	// Log to MongoDB if configured
-	if setting == "all" || setting == "db" {
+	if (setting == "all" || setting == "db") && l.store != nil {
 		if err := l.store.Log(ctx, event); err != nil {
 			l.zapLog.Error("failed to store audit event",
 				zap.Error(err),
@@ -124,6 +171,115 @@ func (l *Logger) Log(ctx context.Context, event audit.Event) {
 			)
 		}
 	}
+
+	for _, sink := range l.config.Sinks {
+		if err := sink.Write(ctx, event); err != nil {
+			l.zapLog.Error("failed to write audit event to sink",
+				zap.Error(err),
+				zap.String("event_type", event.EventType),
+			)
+		}
+	}
+}
+
+// Results for Entry.Result.
+const (
+	ResultSuccess = "success"
+	ResultDenied  = "denied"
+	ResultFailure = "failure"
+)
+
+// Entry is the short form of an audit event accepted by Record.
+type Entry struct {
+	Category string // audit.CategoryAuth, CategoryAdmin, or CategoryAccess; default CategoryAdmin
+	Actor    string // user ID (hex) of who acted; "" when anonymous
+	Action   string // event type, e.g. audit.EventSettingsUpdated
+	Target   string // what was acted on: a user ID (hex), path, or resource name
+	Result   string // ResultSuccess, ResultDenied, or ResultFailure
+	Reason   string // why it was denied or failed
+	Details  map[string]string
+
+	// Request context; use Logger.FromRequest to fill these in.
+	IP        string
+	UserAgent string
+}
+
+// FromRequest returns e with the client IP and user agent of r filled in.
+// The IP is found with network.ClientIP and the logger's TrustedProxies.
+func (l *Logger) FromRequest(e Entry, r *http.Request) Entry {
+	e.IP = l.clientIP(r)
+	e.UserAgent = r.UserAgent()
+	return e
+}
+
+// Record logs a generic audit event described by e, for actions without a
+// dedicated method. A Target that is a user ID is stored as the affected
+// user; any other target goes into the "target" detail.
+func (l *Logger) Record(ctx context.Context, e Entry) {
+	if l == nil {
+		return
+	}
+	category := e.Category
+	if category == "" {
+		category = audit.CategoryAdmin
+	}
+	details := make(map[string]string, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	event := audit.Event{
+		Category:      category,
+		EventType:     e.Action,
+		IP:            e.IP,
+		UserAgent:     e.UserAgent,
+		Success:       e.Result == "" || e.Result == ResultSuccess,
+		FailureReason: e.Reason,
+	}
+	if oid, err := primitive.ObjectIDFromHex(e.Actor); err == nil {
+		event.ActorID = &oid
+	} else if e.Actor != "" {
+		details["actor"] = e.Actor
+	}
+	if oid, err := primitive.ObjectIDFromHex(e.Target); err == nil {
+		event.UserID = &oid
+	} else if e.Target != "" {
+		details["target"] = e.Target
+	}
+	if !event.Success && event.FailureReason == "" {
+		event.FailureReason = e.Result
+	}
+	if len(details) > 0 {
+		event.Details = details
+	}
+	l.Log(ctx, event)
+}
+
+// --- Access Events ---
+
+// AccessChecked records the outcome of a session manager access check. Pass
+// it to auth.SessionManager.SetAccessHook.
+func (l *Logger) AccessChecked(r *http.Request, ev auth.AccessEvent) {
+	e := Entry{
+		Category: audit.CategoryAccess,
+		Target:   r.Method + " " + r.URL.Path,
+		Details:  map[string]string{},
+	}
+	if ev.User != nil {
+		e.Actor = ev.User.ID
+		e.Details["role"] = ev.User.Role
+	}
+	if len(ev.Roles) > 0 {
+		e.Details["allowed_roles"] = strings.Join(ev.Roles, ",")
+	}
+	switch ev.Decision {
+	case auth.AccessGranted:
+		e.Action, e.Result = audit.EventAccessGranted, ResultSuccess
+	case auth.AccessForbidden:
+		e.Action, e.Result, e.Reason = audit.EventAccessDenied, ResultDenied, "role not allowed"
+	default:
+		e.Action, e.Result, e.Reason = audit.EventAccessUnauthenticated, ResultDenied, "not signed in"
+	}
+	l.Record(r.Context(), l.FromRequest(e, r))
 }
 
 // --- Authentication Events ---
@@ -134,7 +290,7 @@ func (l *Logger) LoginSuccess(ctx context.Context, r *http.Request, userID primi
 		Category:  audit.CategoryAuth,
 		EventType: audit.EventLoginSuccess,
 		UserID:    &userID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -149,7 +305,7 @@ func (l *Logger) LoginFailedUserNotFound(ctx context.Context, r *http.Request, a
 	l.Log(ctx, audit.Event{
 		Category:      audit.CategoryAuth,
 		EventType:     audit.EventLoginFailedUserNotFound,
-		IP:            getClientIP(r),
+		IP:            l.clientIP(r),
 		UserAgent:     r.UserAgent(),
 		Success:       false,
 		FailureReason: "user not found",
@@ -165,7 +321,7 @@ func (l *Logger) LoginFailedWrongPassword(ctx context.Context, r *http.Request,
 		Category:      audit.CategoryAuth,
 		EventType:     audit.EventLoginFailedWrongPassword,
 		UserID:        &userID,
-		IP:            getClientIP(r),
+		IP:            l.clientIP(r),
 		UserAgent:     r.UserAgent(),
 		Success:       false,
 		FailureReason: "wrong password",
@@ -181,7 +337,7 @@ func (l *Logger) LoginFailedUserDisabled(ctx context.Context, r *http.Request, u
 		Category:      audit.CategoryAuth,
 		EventType:     audit.EventLoginFailedUserDisabled,
 		UserID:        &userID,
-		IP:            getClientIP(r),
+		IP:            l.clientIP(r),
 		UserAgent:     r.UserAgent(),
 		Success:       false,
 		FailureReason: "user disabled",
@@ -204,7 +360,7 @@ func (l *Logger) Logout(ctx context.Context, r *http.Request, userIDStr string)
 		Category:  audit.CategoryAuth,
 		EventType: audit.EventLogout,
 		UserID:    userID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 	})
@@ -216,7 +372,7 @@ func (l *Logger) PasswordChanged(ctx context.Context, r *http.Request, userID pr
 		Category:  audit.CategoryAuth,
 		EventType: audit.EventPasswordChanged,
 		UserID:    &userID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -231,7 +387,7 @@ func (l *Logger) VerificationCodeSent(ctx context.Context, r *http.Request, user
 		Category:  audit.CategoryAuth,
 		EventType: audit.EventVerificationCodeSent,
 		UserID:    &userID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -246,7 +402,7 @@ func (l *Logger) VerificationCodeResent(ctx context.Context, r *http.Request, us
 		Category:  audit.CategoryAuth,
 		EventType: audit.EventVerificationCodeResent,
 		UserID:    &userID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -262,7 +418,7 @@ func (l *Logger) VerificationCodeFailed(ctx context.Context, r *http.Request, us
 		Category:      audit.CategoryAuth,
 		EventType:     audit.EventVerificationCodeFailed,
 		UserID:        &userID,
-		IP:            getClientIP(r),
+		IP:            l.clientIP(r),
 		UserAgent:     r.UserAgent(),
 		Success:       false,
 		FailureReason: reason,
@@ -275,7 +431,7 @@ func (l *Logger) MagicLinkUsed(ctx context.Context, r *http.Request, userID prim
 		Category:  audit.CategoryAuth,
 		EventType: audit.EventMagicLinkUsed,
 		UserID:    &userID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -293,7 +449,7 @@ func (l *Logger) UserCreated(ctx context.Context, r *http.Request, actorID, targ
 		EventType: audit.EventUserCreated,
 		UserID:    &targetUserID,
 		ActorID:   &actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -311,7 +467,7 @@ func (l *Logger) UserUpdated(ctx context.Context, r *http.Request, actorID, targ
 		EventType: audit.EventUserUpdated,
 		UserID:    &targetUserID,
 		ActorID:   &actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -328,7 +484,7 @@ func (l *Logger) UserDisabled(ctx context.Context, r *http.Request, actorID, tar
 		EventType: audit.EventUserDisabled,
 		UserID:    &targetUserID,
 		ActorID:   &actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -344,7 +500,7 @@ func (l *Logger) UserEnabled(ctx context.Context, r *http.Request, actorID, targ
 		EventType: audit.EventUserEnabled,
 		UserID:    &targetUserID,
 		ActorID:   &actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -360,7 +516,7 @@ func (l *Logger) UserDeleted(ctx context.Context, r *http.Request, actorID, targ
 		EventType: audit.EventUserDeleted,
 		UserID:    &targetUserID,
 		ActorID:   &actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -376,7 +532,7 @@ func (l *Logger) SettingsUpdated(ctx context.Context, r *http.Request, actorID p
 		Category:  audit.CategoryAdmin,
 		EventType: audit.EventSettingsUpdated,
 		ActorID:   &actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -392,7 +548,7 @@ func (l *Logger) PageUpdated(ctx context.Context, r *http.Request, actorID primi
 		Category:  audit.CategoryAdmin,
 		EventType: audit.EventPageUpdated,
 		ActorID:   &actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details: map[string]string{
@@ -422,7 +578,7 @@ func (l *Logger) LogAuthEvent(r *http.Request, userID *primitive.ObjectID, event
 		Category:      audit.CategoryAuth,
 		EventType:     eventType,
 		UserID:        userID,
-		IP:            getClientIP(r),
+		IP:            l.clientIP(r),
 		UserAgent:     r.UserAgent(),
 		Success:       success,
 		FailureReason: failureReason,
@@ -437,7 +593,7 @@ func (l *Logger) LogAdminEvent(r *http.Request, actorID, targetUserID *primitive
 		EventType: eventType,
 		UserID:    targetUserID,
 		ActorID:   actorID,
-		IP:        getClientIP(r),
+		IP:        l.clientIP(r),
 		UserAgent: r.UserAgent(),
 		Success:   true,
 		Details:   details,
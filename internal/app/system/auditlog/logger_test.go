@@ -0,0 +1,142 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/store/audit"
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// collect returns a Sink that appends events to *events.
+func collect(events *[]audit.Event) Sink {
+	return SinkFunc(func(_ context.Context, e audit.Event) error {
+		*events = append(*events, e)
+		return nil
+	})
+}
+
+func TestRecord_MapsEntry(t *testing.T) {
+	var events []audit.Event
+	l := New(nil, zap.NewNop(), Config{Admin: "log", Sinks: []Sink{collect(&events)}})
+	actor := primitive.NewObjectID()
+
+	l.Record(context.Background(), Entry{
+		Actor:  actor.Hex(),
+		Action: "report_exported",
+		Target: "reports/2026-q3",
+		Result: ResultFailure,
+	})
+
+	if len(events) != 1 {
+		t.Fatalf("sink got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Category != audit.CategoryAdmin || e.EventType != "report_exported" {
+		t.Errorf("category, type = %q, %q", e.Category, e.EventType)
+	}
+	if e.ActorID == nil || *e.ActorID != actor {
+		t.Errorf("ActorID = %v, want %v", e.ActorID, actor)
+	}
+	if e.Success || e.FailureReason != ResultFailure {
+		t.Errorf("Success, FailureReason = %v, %q; want false, %q", e.Success, e.FailureReason, ResultFailure)
+	}
+	if e.Details["target"] != "reports/2026-q3" {
+		t.Errorf("target detail = %q", e.Details["target"])
+	}
+}
+
+func TestLog_OffSkipsSinks(t *testing.T) {
+	var events []audit.Event
+	l := New(nil, zap.NewNop(), Config{Access: "off", Sinks: []Sink{collect(&events)}})
+
+	l.Record(context.Background(), Entry{Category: audit.CategoryAccess, Action: audit.EventAccessGranted})
+
+	if len(events) != 0 {
+		t.Errorf("sink got %d events with access logging off, want 0", len(events))
+	}
+}
+
+func TestLog_SinkErrorIsLogged(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	failing := SinkFunc(func(context.Context, audit.Event) error { return errors.New("siem down") })
+	l := New(nil, zap.New(core), Config{Admin: "log", Sinks: []Sink{failing}})
+
+	l.Record(context.Background(), Entry{Action: "x"})
+
+	if logs.FilterMessage("failed to write audit event to sink").Len() != 1 {
+		t.Errorf("expected the sink failure to be logged, got %v", logs.All())
+	}
+}
+
+func TestAccessChecked(t *testing.T) {
+	var events []audit.Event
+	l := New(nil, zap.NewNop(), Config{Access: "log", Sinks: []Sink{collect(&events)}})
+	user := &auth.SessionUser{ID: primitive.NewObjectID().Hex(), Role: "member"}
+
+	req := httptest.NewRequest(http.MethodGet, "/system-users", nil)
+	l.AccessChecked(req, auth.AccessEvent{Decision: auth.AccessForbidden, User: user, Roles: []string{"admin"}})
+	l.AccessChecked(req, auth.AccessEvent{Decision: auth.AccessUnauthorized})
+	l.AccessChecked(req, auth.AccessEvent{Decision: auth.AccessGranted, User: user, Roles: []string{"member"}})
+
+	want := []struct {
+		eventType string
+		success   bool
+	}{
+		{audit.EventAccessDenied, false},
+		{audit.EventAccessUnauthenticated, false},
+		{audit.EventAccessGranted, true},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Category != audit.CategoryAccess || e.EventType != w.eventType || e.Success != w.success {
+			t.Errorf("event %d = %s/%s success=%v, want access/%s success=%v", i, e.Category, e.EventType, e.Success, w.eventType, w.success)
+		}
+		if e.Details["target"] != "GET /system-users" {
+			t.Errorf("event %d target = %q", i, e.Details["target"])
+		}
+	}
+	if events[0].Details["allowed_roles"] != "admin" || events[0].ActorID == nil {
+		t.Errorf("denied event = %+v, want actor and allowed_roles", events[0])
+	}
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		name, remote, want string
+	}{
+		{"untrusted peer", "203.0.113.7:4000", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:4000", "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []audit.Event
+			l := New(nil, zap.NewNop(), Config{Auth: "log", Access: "log", TrustedProxies: []string{"10.0.0.0/8"}, Sinks: []Sink{collect(&events)}})
+
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = tt.remote
+			req.Header.Set("X-Forwarded-For", "198.51.100.9")
+			req.Header.Set("X-Real-IP", "198.51.100.9")
+			l.LoginFailedUserNotFound(context.Background(), req, "nobody")
+			l.AccessChecked(req, auth.AccessEvent{Decision: auth.AccessUnauthorized})
+
+			if len(events) != 2 {
+				t.Fatalf("got %d events, want 2", len(events))
+			}
+			for _, e := range events {
+				if e.IP != tt.want {
+					t.Errorf("%s IP = %q, want %q", e.EventType, e.IP, tt.want)
+				}
+			}
+		})
+	}
+}
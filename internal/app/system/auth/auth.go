@@ -10,6 +10,7 @@ import (
 	"encoding/base64"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -52,10 +53,13 @@ const (
 // It provides middleware and utilities for session-based authentication.
 // Use NewSessionManager to create an instance.
 type SessionManager struct {
-	store       *sessions.CookieStore
-	logger      *zap.Logger
-	name        string
-	userFetcher UserFetcher
+	store        *sessions.CookieStore
+	logger       *zap.Logger
+	name         string
+	userFetcher  UserFetcher
+	unauthorized http.HandlerFunc
+	forbidden    http.HandlerFunc
+	accessHook   func(*http.Request, AccessEvent)
 }
 
 // NewSessionManager creates a new SessionManager with the provided configuration.
@@ -160,6 +164,54 @@ func (sm *SessionManager) SetUserFetcher(uf UserFetcher) {
 | UserFetcher interface                                                       |
 *─────────────────────────────────────────────────────────────────────────────*/
 
+// SetUnauthorizedHandler sets the handler RequireLogin uses to answer
+// non-browser requests without a signed-in user, typically the errors
+// feature's Unauthorized. Without one, a plain-text 401 is written.
+func (sm *SessionManager) SetUnauthorizedHandler(h http.HandlerFunc) {
+	sm.unauthorized = h
+}
+
+// SetForbiddenHandler sets the handler RequireRole uses to answer signed-in
+// users who lack every allowed role, typically the errors feature's
+// Forbidden. Without one, browsers are redirected to /forbidden and other
+// clients get a plain-text 403.
+func (sm *SessionManager) SetForbiddenHandler(h http.HandlerFunc) {
+	sm.forbidden = h
+}
+
+// AccessDecision is the outcome of an access check.
+type AccessDecision string
+
+const (
+	AccessGranted      AccessDecision = "granted"      // passed RequireRole
+	AccessUnauthorized AccessDecision = "unauthorized" // no signed-in user
+	AccessForbidden    AccessDecision = "forbidden"    // signed in without an allowed role
+)
+
+// AccessEvent describes one access check, for the hook set with
+// SetAccessHook.
+type AccessEvent struct {
+	Decision AccessDecision
+	User     *SessionUser // nil when unauthorized
+	Roles    []string     // roles RequireRole allows; empty for sign-in checks
+}
+
+// SetAccessHook sets a function called with the outcome of access checks,
+// typically to write an audit trail. It is called for every denial by
+// RequireSignedIn, RequireLogin, and RequireRole, and for every request
+// RequireRole lets through; passing a plain sign-in check is not reported.
+// It runs before the response is written and must not write to it.
+func (sm *SessionManager) SetAccessHook(fn func(r *http.Request, ev AccessEvent)) {
+	sm.accessHook = fn
+}
+
+// reportAccess passes ev to the access hook, if one is set.
+func (sm *SessionManager) reportAccess(r *http.Request, ev AccessEvent) {
+	if sm.accessHook != nil {
+		sm.accessHook(r, ev)
+	}
+}
+
 // UserFetcher fetches fresh user data from the database.
 // Implementations should return nil if the user is not found or is disabled.
 type UserFetcher interface {
@@ -205,7 +257,13 @@ const currentUserKey ctxKey = "currentUser"
 
 // CurrentUser returns the user & "found?" flag from the request context.
 func CurrentUser(r *http.Request) (*SessionUser, bool) {
-	u, ok := r.Context().Value(currentUserKey).(*SessionUser)
+	return UserFromContext(r.Context())
+}
+
+// UserFromContext returns the user & "found?" flag from ctx, for code that
+// has a context but not the request.
+func UserFromContext(ctx context.Context) (*SessionUser, bool) {
+	u, ok := ctx.Value(currentUserKey).(*SessionUser)
 	return u, ok
 }
 
@@ -293,6 +351,7 @@ func (sm *SessionManager) RequireSignedIn(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		sm.reportAccess(r, AccessEvent{Decision: AccessUnauthorized})
 
 		ret := url.QueryEscape(currentURI(r))
 
@@ -314,7 +373,48 @@ func (sm *SessionManager) RequireSignedIn(next http.Handler) http.Handler {
 	})
 }
 
-// RequireRole returns middleware that ensures there is a user with the required role.
+// RequireLogin returns middleware that ensures there is a user in context,
+// as loaded by LoadSessionUser; handlers read it with CurrentUser.
+//
+// Without one, requests whose Accept header prefers HTML are redirected to
+// loginPath with the original URL in a "next" query parameter, HTMX requests
+// get an HX-Redirect to the same place, and everything else (API and JSON
+// clients) gets a 401 from the handler set with SetUnauthorizedHandler.
+func (sm *SessionManager) RequireLogin(loginPath string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := CurrentUser(r); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			sm.reportAccess(r, AccessEvent{Decision: AccessUnauthorized})
+
+			target := loginPath + "?" + url.Values{"next": {currentURI(r)}}.Encode()
+
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", target)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if prefersHTML(r) {
+				http.Redirect(w, r, target, http.StatusSeeOther)
+				return
+			}
+
+			if sm.unauthorized != nil {
+				sm.unauthorized(w, r)
+				return
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		})
+	}
+}
+
+// RequireRole returns middleware that ensures there is a user with one of
+// the allowed roles. A missing user gets 401 semantics, as with
+// RequireLogin; a signed-in user without an allowed role gets 403 from the
+// handler set with SetForbiddenHandler. It can be stacked after RequireLogin.
 func (sm *SessionManager) RequireRole(allowed ...string) func(http.Handler) http.Handler {
 	set := make(map[string]struct{}, len(allowed))
 	for _, role := range allowed {
@@ -327,6 +427,7 @@ func (sm *SessionManager) RequireRole(allowed ...string) func(http.Handler) http
 
 			// 1) Not signed in → 401 semantics
 			if !ok {
+				sm.reportAccess(r, AccessEvent{Decision: AccessUnauthorized, Roles: allowed})
 				ret := url.QueryEscape(currentURI(r))
 
 				if r.Header.Get("HX-Request") == "true" {
@@ -340,6 +441,10 @@ func (sm *SessionManager) RequireRole(allowed ...string) func(http.Handler) http
 					return
 				}
 
+				if sm.unauthorized != nil {
+					sm.unauthorized(w, r)
+					return
+				}
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
@@ -347,12 +452,18 @@ func (sm *SessionManager) RequireRole(allowed ...string) func(http.Handler) http
 			// 2) Signed in but wrong role → 403 semantics
 			userRole := normalize.Role(u.Role)
 			if _, has := set[userRole]; !has {
+				sm.reportAccess(r, AccessEvent{Decision: AccessForbidden, User: u, Roles: allowed})
 				if r.Header.Get("HX-Request") == "true" {
 					w.Header().Set("HX-Redirect", "/forbidden")
 					w.WriteHeader(http.StatusForbidden)
 					return
 				}
 
+				if sm.forbidden != nil {
+					sm.forbidden(w, r)
+					return
+				}
+
 				if wantsHTML(r) {
 					http.Redirect(w, r, "/forbidden", http.StatusSeeOther)
 					return
@@ -363,6 +474,7 @@ func (sm *SessionManager) RequireRole(allowed ...string) func(http.Handler) http
 			}
 
 			// Authorized → carry on
+			sm.reportAccess(r, AccessEvent{Decision: AccessGranted, User: u, Roles: allowed})
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -397,6 +509,31 @@ func wantsHTML(r *http.Request) bool {
 	return strings.Contains(accept, "text/html")
 }
 
+// prefersHTML reports whether the Accept header ranks an HTML type at least
+// as high as application/json. A missing header or a bare wildcard, as sent
+// by curl and most HTTP client libraries, does not count as HTML.
+func prefersHTML(r *http.Request) bool {
+	var htmlQ, jsonQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		fields := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range fields[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(fields[0])) {
+		case "text/html", "application/xhtml+xml":
+			htmlQ = max(htmlQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return htmlQ > 0 && htmlQ >= jsonQ
+}
+
 func currentURI(r *http.Request) string {
 	u := *r.URL
 	return u.RequestURI()
@@ -492,6 +629,17 @@ func (sm *SessionManager) GetSessionToken(r *http.Request) string {
 	return getString(sess, sessionTokenKey)
 }
 
+// IsAuthenticated reports whether the request carries a signed-in session
+// cookie. Unlike CurrentUser it does not need LoadSessionUser to have run.
+func (sm *SessionManager) IsAuthenticated(r *http.Request) bool {
+	sess, err := sm.store.Get(r, sm.name)
+	if err != nil {
+		return false
+	}
+	isAuth, _ := sess.Values[isAuthKey].(bool)
+	return isAuth && getString(sess, userIDKey) != ""
+}
+
 // GenerateSessionToken generates a random URL-safe token for session tracking.
 func GenerateSessionToken() (string, error) {
 	b := make([]byte, 32)
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -571,6 +572,181 @@ func TestRequireAuth_Alias(t *testing.T) {
 	}
 }
 
+func TestRequireLogin(t *testing.T) {
+	logger := zap.NewNop()
+	sm, _ := NewSessionManager("this-is-a-32-character-long-key!", "", "", time.Hour, false, logger)
+	sm.SetUnauthorizedHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	var gotUser *SessionUser
+	protected := sm.RequireLogin("/signin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, _ = CurrentUser(r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		accept   string
+		wantCode int
+		wantLoc  string
+	}{
+		{"browser", "text/html,application/xhtml+xml,*/*;q=0.8", http.StatusSeeOther, "/signin?next=%2Freports%3Fyear%3D2024"},
+		{"json", "application/json", http.StatusUnauthorized, ""},
+		{"json preferred", "text/html;q=0.5, application/json", http.StatusUnauthorized, ""},
+		{"wildcard", "*/*", http.StatusUnauthorized, ""},
+		{"no accept", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/reports?year=2024", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			protected.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLoc {
+				t.Errorf("Location = %q, want %q", got, tt.wantLoc)
+			}
+			if tt.wantCode == http.StatusUnauthorized && rec.Header().Get("Content-Type") != "application/json" {
+				t.Error("401 should come from the unauthorized handler")
+			}
+		})
+	}
+
+	t.Run("authenticated", func(t *testing.T) {
+		user := &SessionUser{ID: primitive.NewObjectID().Hex(), Name: "Test", Role: "admin"}
+		req := WithTestUser(httptest.NewRequest("GET", "/reports", nil), user)
+		rec := httptest.NewRecorder()
+
+		protected.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("Status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if gotUser != user {
+			t.Error("handler should see the signed-in user via CurrentUser")
+		}
+	})
+}
+
+func TestRequireRole_WithErrorHandlers(t *testing.T) {
+	logger := zap.NewNop()
+	sm, _ := NewSessionManager("this-is-a-32-character-long-key!", "", "", time.Hour, false, logger)
+	var answered string
+	sm.SetUnauthorizedHandler(func(w http.ResponseWriter, r *http.Request) {
+		answered = "unauthorized"
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	sm.SetForbiddenHandler(func(w http.ResponseWriter, r *http.Request) {
+		answered = "forbidden"
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	protected := sm.RequireLogin("/login")(sm.RequireRole("admin")(ok))
+
+	tests := []struct {
+		name     string
+		user     *SessionUser
+		wantCode int
+		want     string
+	}{
+		{"anonymous", nil, http.StatusUnauthorized, "unauthorized"},
+		{"wrong role", &SessionUser{ID: "1", Role: "member"}, http.StatusForbidden, "forbidden"},
+		{"admin", &SessionUser{ID: "2", Role: "Admin"}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answered = ""
+			req := httptest.NewRequest("GET", "/admin", nil)
+			req.Header.Set("Accept", "application/json")
+			if tt.user != nil {
+				req = WithTestUser(req, tt.user)
+			}
+			rec := httptest.NewRecorder()
+
+			protected.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if answered != tt.want {
+				t.Errorf("answered by %q, want %q", answered, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAccessHook(t *testing.T) {
+	sm, _ := NewSessionManager("this-is-a-32-character-long-key!", "", "", time.Hour, false, zap.NewNop())
+	var events []AccessEvent
+	sm.SetAccessHook(func(r *http.Request, ev AccessEvent) {
+		events = append(events, ev)
+	})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	signedIn := sm.RequireSignedIn(ok)
+	admin := sm.RequireRole("admin")(ok)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		user    *SessionUser
+		want    AccessDecision // "" means no event
+	}{
+		{"signed in", signedIn, &SessionUser{ID: "1", Role: "member"}, ""},
+		{"sign-in required", signedIn, nil, AccessUnauthorized},
+		{"role granted", admin, &SessionUser{ID: "2", Role: "admin"}, AccessGranted},
+		{"role denied", admin, &SessionUser{ID: "1", Role: "member"}, AccessForbidden},
+		{"role needs sign-in", admin, nil, AccessUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+			req := httptest.NewRequest("GET", "/admin", nil)
+			req.Header.Set("Accept", "application/json")
+			if tt.user != nil {
+				req = WithTestUser(req, tt.user)
+			}
+			tt.handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if tt.want == "" {
+				if len(events) != 0 {
+					t.Errorf("events = %+v, want none", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Decision != tt.want {
+				t.Fatalf("events = %+v, want one %q", events, tt.want)
+			}
+			if tt.user != nil && events[0].User != tt.user {
+				t.Errorf("event user = %v, want %v", events[0].User, tt.user)
+			}
+		})
+	}
+}
+
+func TestUserFromContext(t *testing.T) {
+	if _, ok := UserFromContext(context.Background()); ok {
+		t.Error("UserFromContext() on empty context should report not found")
+	}
+
+	user := &SessionUser{ID: "1", Role: "admin"}
+	req := WithTestUser(httptest.NewRequest("GET", "/", nil), user)
+	if got, ok := UserFromContext(req.Context()); !ok || got != user {
+		t.Errorf("UserFromContext() = %v, %v; want the injected user", got, ok)
+	}
+}
+
 func TestClassifySessionError_Types(t *testing.T) {
 	// Test with various error message patterns
 	tests := []struct {
@@ -80,19 +80,47 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
-// HashPassword hashes a password using bcrypt.
-// The password should be validated with ValidatePassword first.
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+// PasswordHasher hashes and verifies passwords. Handlers that check
+// credentials accept one so tests can inject a fast fake.
+type PasswordHasher interface {
+	// Hash returns a hash of password suitable for storage.
+	Hash(password string) (string, error)
+	// Compare reports whether password matches hash.
+	Compare(password, hash string) bool
+}
+
+// BcryptHasher is the PasswordHasher used in production.
+type BcryptHasher struct {
+	Cost int // bcrypt cost; zero uses BcryptCost
+}
+
+// Hash implements PasswordHasher.
+func (b BcryptHasher) Hash(password string) (string, error) {
+	cost := b.Cost
+	if cost == 0 {
+		cost = BcryptCost
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	if err != nil {
 		return "", err
 	}
 	return string(hash), nil
 }
 
+// Compare implements PasswordHasher.
+func (BcryptHasher) Compare(password, hash string) bool {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	return err == nil
+}
+
+// HashPassword hashes a password using bcrypt.
+// The password should be validated with ValidatePassword first.
+func HashPassword(password string) (string, error) {
+	return BcryptHasher{}.Hash(password)
+}
+
 // CheckPassword compares a plain-text password with a bcrypt hash.
 // Returns true if the password matches, false otherwise.
 func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return BcryptHasher{}.Compare(password, hash)
 }
@@ -314,3 +314,27 @@ func TestErrorMessages(t *testing.T) {
 		t.Error("ErrPasswordCommon should mention 'common'")
 	}
 }
+
+func TestBcryptHasher(t *testing.T) {
+	var h PasswordHasher = BcryptHasher{Cost: 4}
+
+	hash, err := h.Hash("validpassword123")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$2a$04$") {
+		t.Errorf("Hash() = %q, want cost 04 bcrypt hash", hash)
+	}
+	if !h.Compare("validpassword123", hash) {
+		t.Error("Compare() rejected the correct password")
+	}
+	if h.Compare("wrongpassword", hash) {
+		t.Error("Compare() accepted the wrong password")
+	}
+
+	// HashPassword hashes are verifiable by any BcryptHasher regardless of cost.
+	stored, _ := HashPassword("validpassword123")
+	if !h.Compare("validpassword123", stored) {
+		t.Error("Compare() rejected a HashPassword hash")
+	}
+}
@@ -0,0 +1,191 @@
+// internal/app/system/captcha/captcha.go
+//
+// Package captcha checks that form submissions come from people, using
+// reCAPTCHA or hCaptcha.
+//
+// A form shows the provider's widget with the captchaWidget template
+// function, passing a Widget from its view model:
+//
+//	<form method="POST">
+//	  ...
+//	  {{ captchaWidget .Captcha }}
+//	</form>
+//
+// The zero Widget renders nothing, so forms work unchanged while CAPTCHA is
+// off. On submit, the handler passes the widget's response (Token) to a
+// Verifier, which answers ErrFailed for a missing or wrong response.
+// NewVerifier builds the Verifier for a provider; Noop accepts everything
+// and is used when no provider is configured and in tests.
+//
+// The widget loads a script from the provider, so the Content-Security-Policy
+// must allow it (www.google.com and www.gstatic.com for reCAPTCHA,
+// hcaptcha.com and *.hcaptcha.com for hCaptcha).
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/templatefuncs"
+)
+
+// Providers accepted by NewVerifier and Widget.
+const (
+	ProviderRecaptcha = "recaptcha"
+	ProviderHCaptcha  = "hcaptcha"
+)
+
+// ErrFailed is returned by Verify when the response is missing, invalid,
+// expired, or already used.
+var ErrFailed = errors.New("captcha: verification failed")
+
+// Verifier checks a widget response. remoteIP is optional and is passed to
+// the provider as extra signal.
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// Noop is a Verifier that accepts every response.
+type Noop struct{}
+
+// Verify implements Verifier.
+func (Noop) Verify(context.Context, string, string) error { return nil }
+
+// provider holds the endpoints and markup for one CAPTCHA service.
+type provider struct {
+	verifyURL string
+	scriptURL string
+	class     string // class of the widget element
+	field     string // form field carrying the response
+}
+
+var providers = map[string]provider{
+	ProviderRecaptcha: {
+		verifyURL: "https://www.google.com/recaptcha/api/siteverify",
+		scriptURL: "https://www.google.com/recaptcha/api.js",
+		class:     "g-recaptcha",
+		field:     "g-recaptcha-response",
+	},
+	ProviderHCaptcha: {
+		verifyURL: "https://api.hcaptcha.com/siteverify",
+		scriptURL: "https://js.hcaptcha.com/1/api.js",
+		class:     "h-captcha",
+		field:     "h-captcha-response",
+	},
+}
+
+// NewVerifier returns the Verifier for providerName ("recaptcha" or
+// "hcaptcha") using the provider's secret key. An empty providerName
+// returns Noop.
+func NewVerifier(providerName, secret string) (Verifier, error) {
+	if providerName == "" {
+		return Noop{}, nil
+	}
+	p, ok := providers[strings.ToLower(providerName)]
+	if !ok {
+		return nil, fmt.Errorf("captcha: unknown provider %q", providerName)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("captcha: %s needs a secret key", providerName)
+	}
+	return &siteVerifier{
+		endpoint: p.verifyURL,
+		secret:   secret,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// siteVerifier calls a siteverify endpoint; reCAPTCHA and hCaptcha share
+// the protocol.
+type siteVerifier struct {
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+// Verify implements Verifier.
+func (v *siteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrFailed
+	}
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha: verify request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha: verify request: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("captcha: decode verify response: %w", err)
+	}
+	if !result.Success {
+		if len(result.ErrorCodes) > 0 {
+			return fmt.Errorf("%w: %s", ErrFailed, strings.Join(result.ErrorCodes, ", "))
+		}
+		return ErrFailed
+	}
+	return nil
+}
+
+// Token returns the widget response posted with r's form, for either
+// provider.
+func Token(r *http.Request) string {
+	for _, p := range providers {
+		if token := r.PostFormValue(p.field); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// Widget is what a form needs to show the CAPTCHA. The zero value renders
+// nothing.
+type Widget struct {
+	Provider string
+	SiteKey  string
+}
+
+// Enabled reports whether w renders a widget.
+func (w Widget) Enabled() bool {
+	_, ok := providers[strings.ToLower(w.Provider)]
+	return ok && w.SiteKey != ""
+}
+
+// HTML returns the widget markup and the provider's script tag.
+func (w Widget) HTML() template.HTML {
+	if !w.Enabled() {
+		return ""
+	}
+	p := providers[strings.ToLower(w.Provider)]
+	return template.HTML(`<script src="` + p.scriptURL + `" async defer></script>` +
+		`<div class="` + p.class + `" data-sitekey="` + template.HTMLEscapeString(w.SiteKey) + `"></div>`)
+}
+
+func init() {
+	// Registered here rather than while the router is built, so feature
+	// templates using it parse in tests as well.
+	templatefuncs.MustRegister("captchaWidget", Widget.HTML)
+}
@@ -0,0 +1,122 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeSiteverify answers like a siteverify endpoint: success for the token
+// "good", a failure with error codes for anything else.
+func fakeSiteverify(t *testing.T, got *url.Values) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		*got = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("response") == "good" {
+			_, _ = w.Write([]byte(`{"success": true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSiteVerifier(t *testing.T) {
+	var got url.Values
+	srv := fakeSiteverify(t, &got)
+	v := &siteVerifier{endpoint: srv.URL, secret: "s3cret", client: srv.Client()}
+	ctx := context.Background()
+
+	if err := v.Verify(ctx, "good", "203.0.113.7"); err != nil {
+		t.Fatalf("Verify(good) = %v, want nil", err)
+	}
+	if got.Get("secret") != "s3cret" || got.Get("remoteip") != "203.0.113.7" {
+		t.Errorf("posted %v, want the secret and remote IP", got)
+	}
+
+	err := v.Verify(ctx, "bad", "")
+	if !errors.Is(err, ErrFailed) {
+		t.Fatalf("Verify(bad) = %v, want ErrFailed", err)
+	}
+	if !strings.Contains(err.Error(), "invalid-input-response") {
+		t.Errorf("Verify(bad) = %v, want the error codes in the message", err)
+	}
+	if got.Has("remoteip") {
+		t.Error("remoteip posted although none was given")
+	}
+
+	if err := v.Verify(ctx, "", ""); !errors.Is(err, ErrFailed) {
+		t.Errorf("Verify(empty) = %v, want ErrFailed", err)
+	}
+}
+
+func TestSiteVerifier_ProviderDown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	v := &siteVerifier{endpoint: srv.URL, secret: "s3cret", client: srv.Client()}
+
+	err := v.Verify(context.Background(), "good", "")
+	if err == nil || errors.Is(err, ErrFailed) {
+		t.Errorf("Verify = %v, want a non-ErrFailed error", err)
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	if v, err := NewVerifier("", ""); err != nil || v != (Noop{}) {
+		t.Errorf(`NewVerifier("") = %v, %v; want Noop`, v, err)
+	}
+	if _, err := NewVerifier("hcaptcha", "secret"); err != nil {
+		t.Errorf("NewVerifier(hcaptcha) = %v", err)
+	}
+	if _, err := NewVerifier("recaptcha", ""); err == nil {
+		t.Error("NewVerifier without a secret succeeded")
+	}
+	if _, err := NewVerifier("turnstile", "secret"); err == nil {
+		t.Error("NewVerifier with an unknown provider succeeded")
+	}
+}
+
+func TestToken(t *testing.T) {
+	for field, provider := range map[string]string{
+		"g-recaptcha-response": ProviderRecaptcha,
+		"h-captcha-response":   ProviderHCaptcha,
+	} {
+		form := url.Values{field: {"tok-" + provider}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if got := Token(r); got != "tok-"+provider {
+			t.Errorf("Token with %s = %q, want %q", field, got, "tok-"+provider)
+		}
+	}
+}
+
+func TestWidget_HTML(t *testing.T) {
+	if got := (Widget{}).HTML(); got != "" {
+		t.Errorf("zero Widget rendered %q", got)
+	}
+	if got := (Widget{Provider: "recaptcha"}).HTML(); got != "" {
+		t.Errorf("Widget without a site key rendered %q", got)
+	}
+
+	got := string(Widget{Provider: "hcaptcha", SiteKey: `key"<x>`}.HTML())
+	for _, want := range []string{
+		`<script src="https://js.hcaptcha.com/1/api.js" async defer></script>`,
+		`class="h-captcha"`,
+		`data-sitekey="key&#34;&lt;x&gt;"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML() = %s, want it to contain %s", got, want)
+		}
+	}
+}
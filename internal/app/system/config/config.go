@@ -0,0 +1,265 @@
+// internal/app/system/config/config.go
+//
+// Package config loads settings into a struct from three layers, each
+// overriding the one before it:
+//
+//  1. defaults from `default:"..."` struct tags
+//  2. an optional YAML, JSON, or TOML file (chosen by extension), with a
+//     per-environment profile deep-merged onto it (WithProfiles)
+//  3. environment variables named by `env:"..."` struct tags
+//
+// Fields tagged `required:"true"` must end up non-zero; Load reports every
+// one that is missing in a single error.
+//
+// Usage:
+//
+//	type Settings struct {
+//		Port     int           `env:"PORT" default:"8080" yaml:"port"`
+//		MongoURI string        `env:"MONGO_URI" required:"true" yaml:"mongo_uri"`
+//		Timeout  time.Duration `env:"TIMEOUT" default:"30s" yaml:"timeout"`
+//	}
+//
+//	var s Settings
+//	err := config.Load(&s, config.WithFile("config.yaml"))
+//
+// File keys are matched by the format's own tags (yaml, json, or toml).
+// With WithProfiles and APP_ENV=staging, config.staging.yaml is merged onto
+// config.yaml, so each environment's file holds only what differs.
+// The main application settings are loaded by WAFFLE in
+// bootstrap.LoadConfig, which overlays the same APP_ENV profiles through
+// ReadProfile; this package is for tools and features that keep their own
+// settings struct.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotStructPointer is returned when Load is not given a pointer to a struct.
+var ErrNotStructPointer = errors.New("config: Load requires a non-nil pointer to a struct")
+
+// MissingError lists the required fields that were not set by any layer.
+type MissingError struct {
+	Fields []string // Go field paths, with the env var in parentheses when tagged
+}
+
+func (e *MissingError) Error() string {
+	return "config: missing required fields: " + strings.Join(e.Fields, ", ")
+}
+
+// options holds the settings built up by Options.
+type options struct {
+	file      string
+	envPrefix string
+	lookupEnv func(string) (string, bool)
+	profiles  bool
+	logger    *zap.Logger
+}
+
+// Option configures Load.
+type Option func(*options)
+
+// WithFile reads path after applying defaults. The format is taken from the
+// extension: .yaml/.yml, .json, or .toml. A missing file is an error; pass
+// an empty path to skip the file layer.
+func WithFile(path string) Option {
+	return func(o *options) {
+		o.file = path
+	}
+}
+
+// WithEnvPrefix prepends prefix to every env tag, so `env:"PORT"` with
+// prefix "STRATA_" reads STRATA_PORT.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) {
+		o.envPrefix = prefix
+	}
+}
+
+// WithLookupEnv replaces os.LookupEnv, mainly for tests.
+func WithLookupEnv(fn func(string) (string, bool)) Option {
+	return func(o *options) {
+		o.lookupEnv = fn
+	}
+}
+
+// Load populates into, which must be a pointer to a struct, from defaults,
+// the configured file and profile, and the environment, in that order. Errors from
+// parsing tag or environment values name the field and the offending value.
+func Load(into any, opts ...Option) error {
+	o := options{lookupEnv: os.LookupEnv, logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	rv := reflect.ValueOf(into)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+	root := rv.Elem()
+
+	if err := walk(root, "", func(f reflect.Value, sf reflect.StructField, path string) error {
+		def, ok := sf.Tag.Lookup("default")
+		if !ok {
+			return nil
+		}
+		if err := setValue(f, def); err != nil {
+			return fmt.Errorf("config: default for %s: %w", path, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if o.file != "" {
+		if env, _ := o.lookupEnv(ProfileEnvVar); o.profiles && env != "" {
+			if err := decodeProfile(o.file, env, into, o.logger); err != nil {
+				return err
+			}
+		} else if err := decodeFile(o.file, into); err != nil {
+			return err
+		}
+	}
+
+	if err := walk(root, "", func(f reflect.Value, sf reflect.StructField, path string) error {
+		name := sf.Tag.Get("env")
+		if name == "" {
+			return nil
+		}
+		val, ok := o.lookupEnv(o.envPrefix + name)
+		if !ok {
+			return nil
+		}
+		if err := setValue(f, val); err != nil {
+			return fmt.Errorf("config: env %s%s for %s: %w", o.envPrefix, name, path, err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var missing []string
+	_ = walk(root, "", func(f reflect.Value, sf reflect.StructField, path string) error {
+		if sf.Tag.Get("required") != "true" || !f.IsZero() {
+			return nil
+		}
+		if name := sf.Tag.Get("env"); name != "" {
+			path += " (" + o.envPrefix + name + ")"
+		}
+		missing = append(missing, path)
+		return nil
+	})
+	if len(missing) > 0 {
+		return &MissingError{Fields: missing}
+	}
+	return nil
+}
+
+// walk calls fn for every settable leaf field of v, descending into nested
+// structs other than time.Time. path is the dotted Go field path.
+func walk(v reflect.Value, prefix string, fn func(reflect.Value, reflect.StructField, string) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := v.Field(i)
+		path := prefix + sf.Name
+		if f.Kind() == reflect.Struct && f.Type() != reflect.TypeOf(time.Time{}) {
+			if err := walk(f, path+".", fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(f, sf, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// durationType is checked before the integer kinds it shares.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue parses s into f according to f's type. Slices of strings are
+// comma-separated with surrounding spaces trimmed.
+func setValue(f reflect.Value, s string) error {
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", f.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.Set(reflect.ValueOf(items).Convert(f.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
+
+// decodeFile decodes path into into, keeping any field the file leaves out.
+func decodeFile(path string, into any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: read %s: %w", path, err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	if _, ok := formatTags[ext]; !ok {
+		return fmt.Errorf("config: unsupported file type %q for %s", ext, path)
+	}
+	if err := unmarshal(data, ext, into); err != nil {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
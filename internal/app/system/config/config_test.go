@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSettings struct {
+	Port     int           `env:"PORT" default:"8080" yaml:"port" json:"port" toml:"port"`
+	Host     string        `env:"HOST" default:"localhost" yaml:"host" json:"host" toml:"host"`
+	Timeout  time.Duration `env:"TIMEOUT" default:"30s" yaml:"timeout" json:"timeout" toml:"timeout"`
+	Debug    bool          `env:"DEBUG" yaml:"debug" json:"debug" toml:"debug"`
+	Origins  []string      `env:"ORIGINS" yaml:"origins" json:"origins" toml:"origins"`
+	MongoURI string        `env:"MONGO_URI" required:"true" yaml:"mongo_uri" json:"mongo_uri" toml:"mongo_uri"`
+	Mail     struct {
+		Host string `env:"MAIL_HOST" required:"true" yaml:"host" json:"host" toml:"host"`
+	} `yaml:"mail" json:"mail" toml:"mail"`
+}
+
+func env(vars map[string]string) Option {
+	return WithLookupEnv(func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	})
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	yamlPath := writeFile(t, "config.yaml", "port: 9000\nhost: file.example\nmongo_uri: mongodb://file\nmail:\n  host: smtp.file\n")
+
+	var s testSettings
+	err := Load(&s, WithFile(yamlPath), env(map[string]string{
+		"PORT":    "9100",
+		"DEBUG":   "true",
+		"ORIGINS": "https://a.example, https://b.example",
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if s.Port != 9100 {
+		t.Errorf("Port = %d, want env value 9100", s.Port)
+	}
+	if s.Host != "file.example" {
+		t.Errorf("Host = %q, want file value", s.Host)
+	}
+	if s.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, want default 30s", s.Timeout)
+	}
+	if !s.Debug {
+		t.Error("Debug = false, want true from env")
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(s.Origins, want) {
+		t.Errorf("Origins = %v, want %v", s.Origins, want)
+	}
+	if s.Mail.Host != "smtp.file" {
+		t.Errorf("Mail.Host = %q, want file value", s.Mail.Host)
+	}
+}
+
+func TestLoad_FileFormats(t *testing.T) {
+	tests := map[string]string{
+		"config.json": `{"port": 7000, "mongo_uri": "mongodb://json", "mail": {"host": "smtp"}}`,
+		"config.toml": "port = 7000\nmongo_uri = \"mongodb://toml\"\n[mail]\nhost = \"smtp\"\n",
+		"config.yml":  "port: 7000\nmongo_uri: mongodb://yml\nmail:\n  host: smtp\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			var s testSettings
+			if err := Load(&s, WithFile(writeFile(t, name, content)), env(nil)); err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if s.Port != 7000 || s.Host != "localhost" {
+				t.Errorf("Port, Host = %d, %q; want 7000, %q", s.Port, s.Host, "localhost")
+			}
+		})
+	}
+}
+
+func TestLoad_MissingRequired(t *testing.T) {
+	var s testSettings
+	err := Load(&s, WithEnvPrefix("APP_"), env(nil))
+
+	var missing *MissingError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Load() error = %v, want *MissingError", err)
+	}
+	want := []string{"MongoURI (APP_MONGO_URI)", "Mail.Host (APP_MAIL_HOST)"}
+	if !reflect.DeepEqual(missing.Fields, want) {
+		t.Errorf("Fields = %v, want %v", missing.Fields, want)
+	}
+}
+
+func TestLoad_EnvPrefix(t *testing.T) {
+	var s testSettings
+	err := Load(&s, WithEnvPrefix("APP_"), env(map[string]string{
+		"APP_MONGO_URI": "mongodb://env",
+		"APP_MAIL_HOST": "smtp.env",
+		"PORT":          "1",
+	}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Port != 8080 {
+		t.Errorf("Port = %d, want default; unprefixed PORT should be ignored", s.Port)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	var s testSettings
+	if err := Load(s); !errors.Is(err, ErrNotStructPointer) {
+		t.Errorf("Load(non-pointer) error = %v, want ErrNotStructPointer", err)
+	}
+	if err := Load(&s, env(map[string]string{"PORT": "eighty"})); err == nil {
+		t.Error("Load() with bad int = nil, want error")
+	}
+	if err := Load(&s, WithFile(writeFile(t, "config.ini", "")), env(nil)); err == nil {
+		t.Error("Load() with .ini file = nil, want error")
+	}
+	if err := Load(&s, WithFile(filepath.Join(t.TempDir(), "absent.yaml")), env(nil)); err == nil {
+		t.Error("Load() with missing file = nil, want error")
+	}
+}
@@ -0,0 +1,211 @@
+// internal/app/system/config/profile.go
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+)
+
+// ProfileEnvVar names the environment variable that selects the profile
+// overlaid by WithProfiles, such as "staging". It is read without the
+// WithEnvPrefix prefix.
+const ProfileEnvVar = "APP_ENV"
+
+// WithProfiles overlays a per-environment profile on the WithFile config.
+// When ProfileEnvVar is set, the profile is the file next to the base one
+// with the environment's name before the extension, e.g. config.staging.yaml
+// for config.yaml, and must exist. It is deep-merged onto the base: nested
+// tables merge key by key, while scalars and lists in the profile replace
+// the base's. Environment variables still override both.
+//
+// Keys in the profile that match no field are logged as warnings (see
+// WithLogger), since a misspelled override otherwise does nothing.
+func WithProfiles() Option {
+	return func(o *options) {
+		o.profiles = true
+	}
+}
+
+// WithLogger sets the logger that WithProfiles warns on. The default
+// discards.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// profilePath returns the profile file for base and env:
+// dir/config.yaml and "staging" give dir/config.staging.yaml.
+func profilePath(base, env string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + env + ext
+}
+
+// ReadProfile reads the env profile for the config file base, as
+// WithProfiles does, and returns its path and its parsed contents. It is
+// for loaders that merge the profile themselves, such as the one for the
+// main app settings in bootstrap. The error wraps fs.ErrNotExist when
+// there is no such profile.
+func ReadProfile(base, env string) (path string, values map[string]any, err error) {
+	if strings.ContainsAny(env, `/\`) || env == "." || env == ".." {
+		return "", nil, fmt.Errorf("config: invalid profile name %q in %s", env, ProfileEnvVar)
+	}
+	ext := strings.ToLower(filepath.Ext(base))
+	if _, ok := formatTags[ext]; !ok {
+		return "", nil, fmt.Errorf("config: unsupported file type %q for %s", ext, base)
+	}
+	path = profilePath(base, env)
+	values, err = readMap(path, ext)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, values, nil
+}
+
+// decodeProfile decodes the base file with the named profile merged onto
+// it into into, warning on profile keys that into does not have.
+func decodeProfile(base, env string, into any, logger *zap.Logger) error {
+	path, overlay, err := ReadProfile(base, env)
+	if err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(base))
+	merged, err := readMap(base, ext)
+	if err != nil {
+		return err
+	}
+	for _, key := range unknownKeys(overlay, reflect.TypeOf(into).Elem(), formatTags[ext], "") {
+		logger.Warn("config: unknown key in profile", zap.String("file", path), zap.String("key", key))
+	}
+	deepMerge(merged, overlay)
+
+	data, err := encodeMap(merged, ext)
+	if err != nil {
+		return fmt.Errorf("config: merge %s onto %s: %w", path, base, err)
+	}
+	if err := unmarshal(data, ext, into); err != nil {
+		return fmt.Errorf("config: parse %s with %s: %w", base, path, err)
+	}
+	return nil
+}
+
+// formatTags maps a file extension to the struct tag naming its keys.
+var formatTags = map[string]string{
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".json": "json",
+	".toml": "toml",
+}
+
+// readMap parses path as a generic table.
+func readMap(path, ext string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: read %s: %w", path, err)
+	}
+	m := map[string]any{}
+	if err := unmarshal(data, ext, &m); err != nil {
+		return nil, fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// unmarshal decodes data in the format named by ext into into.
+func unmarshal(data []byte, ext string, into any) error {
+	switch ext {
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(data, into)
+	case ".json":
+		return json.NewDecoder(bytes.NewReader(data)).Decode(into)
+	case ".toml":
+		return toml.Unmarshal(data, into)
+	}
+	return fmt.Errorf("unsupported file type %q", ext)
+}
+
+// encodeMap writes m back out in the format named by ext.
+func encodeMap(m map[string]any, ext string) ([]byte, error) {
+	switch ext {
+	case ".yaml", ".yml":
+		return yaml.Marshal(m)
+	case ".json":
+		return json.Marshal(m)
+	case ".toml":
+		return toml.Marshal(m)
+	}
+	return nil, fmt.Errorf("unsupported file type %q", ext)
+}
+
+// deepMerge copies src into dst, merging tables present in both.
+func deepMerge(dst, src map[string]any) {
+	for k, v := range src {
+		if sub, ok := v.(map[string]any); ok {
+			if existing, ok := dst[k].(map[string]any); ok {
+				deepMerge(existing, sub)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// unknownKeys returns the dotted paths of keys in m that name no field of
+// t under the given tag, sorted. Keys are matched case-insensitively, and
+// the contents of map fields are not checked.
+func unknownKeys(m map[string]any, t reflect.Type, tag, prefix string) []string {
+	fields := map[string]reflect.Type{}
+	collectFields(t, tag, fields)
+
+	var unknown []string
+	for k, v := range m {
+		ft, ok := fields[strings.ToLower(k)]
+		if !ok {
+			unknown = append(unknown, prefix+k)
+			continue
+		}
+		if sub, ok := v.(map[string]any); ok && ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) {
+			unknown = append(unknown, unknownKeys(sub, ft, tag, prefix+k+".")...)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// collectFields records the lowercased key of each field of struct type t
+// under tag, with its type. Untagged embedded structs contribute their own
+// fields, as the decoders treat them.
+func collectFields(t reflect.Type, tag string, into map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && ft.Kind() == reflect.Struct && (name == "" || strings.Contains(opts, "inline")) {
+			collectFields(ft, tag, into)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		into[strings.ToLower(name)] = ft
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type profileSettings struct {
+	Port    int      `env:"PORT" yaml:"port" json:"port" toml:"port"`
+	Origins []string `yaml:"origins" json:"origins" toml:"origins"`
+	Mail    struct {
+		Host string `yaml:"host" json:"host" toml:"host"`
+		From string `yaml:"from" json:"from" toml:"from"`
+	} `yaml:"mail" json:"mail" toml:"mail"`
+	Labels map[string]string `yaml:"labels" json:"labels" toml:"labels"`
+}
+
+// writeProfiles writes each file into one directory and returns the path
+// of the first, the base config.
+func writeProfiles(t *testing.T, files ...[2]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f[0]), []byte(f[1]), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, files[0][0])
+}
+
+func TestLoad_ProfileDeepMerge(t *testing.T) {
+	tests := map[string][][2]string{
+		"yaml": {
+			{"config.yaml", "port: 8080\norigins: [https://a.example, https://b.example]\nmail:\n  host: smtp.base\n  from: base@example.com\nlabels:\n  team: core\n"},
+			{"config.staging.yaml", "origins: [https://staging.example]\nmail:\n  host: smtp.staging\nlabels:\n  tier: staging\n"},
+		},
+		"json": {
+			{"config.json", `{"port": 8080, "origins": ["https://a.example", "https://b.example"], "mail": {"host": "smtp.base", "from": "base@example.com"}, "labels": {"team": "core"}}`},
+			{"config.staging.json", `{"origins": ["https://staging.example"], "mail": {"host": "smtp.staging"}, "labels": {"tier": "staging"}}`},
+		},
+		"toml": {
+			{"config.toml", "port = 8080\norigins = [\"https://a.example\", \"https://b.example\"]\n[mail]\nhost = \"smtp.base\"\nfrom = \"base@example.com\"\n[labels]\nteam = \"core\"\n"},
+			{"config.staging.toml", "origins = [\"https://staging.example\"]\n[mail]\nhost = \"smtp.staging\"\n[labels]\ntier = \"staging\"\n"},
+		},
+	}
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			var s profileSettings
+			err := Load(&s, WithFile(writeProfiles(t, files...)), WithProfiles(), env(map[string]string{"APP_ENV": "staging"}))
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if s.Port != 8080 {
+				t.Errorf("Port = %d, want base value 8080", s.Port)
+			}
+			if len(s.Origins) != 1 || s.Origins[0] != "https://staging.example" {
+				t.Errorf("Origins = %v, want the profile's list to replace the base's", s.Origins)
+			}
+			if s.Mail.Host != "smtp.staging" || s.Mail.From != "base@example.com" {
+				t.Errorf("Mail = %+v, want host from profile and from from base", s.Mail)
+			}
+			if s.Labels["team"] != "core" || s.Labels["tier"] != "staging" {
+				t.Errorf("Labels = %v, want both base and profile keys", s.Labels)
+			}
+		})
+	}
+}
+
+func TestLoad_ProfileEnvOverrides(t *testing.T) {
+	base := writeProfiles(t,
+		[2]string{"config.yaml", "port: 8080\n"},
+		[2]string{"config.prod.yaml", "port: 9000\n"},
+	)
+
+	var s profileSettings
+	if err := Load(&s, WithFile(base), WithProfiles(), env(map[string]string{"APP_ENV": "prod", "PORT": "9100"})); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Port != 9100 {
+		t.Errorf("Port = %d, want env value 9100 over the profile", s.Port)
+	}
+}
+
+func TestLoad_ProfileNotSelected(t *testing.T) {
+	base := writeProfiles(t,
+		[2]string{"config.yaml", "port: 8080\n"},
+		[2]string{"config.prod.yaml", "port: 9000\n"},
+	)
+
+	var s profileSettings
+	if err := Load(&s, WithFile(base), WithProfiles(), env(nil)); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Port != 8080 {
+		t.Errorf("Port = %d, want base value 8080 without APP_ENV", s.Port)
+	}
+
+	// Without WithProfiles, APP_ENV is ignored.
+	s = profileSettings{}
+	if err := Load(&s, WithFile(base), env(map[string]string{"APP_ENV": "prod"})); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s.Port != 8080 {
+		t.Errorf("Port = %d, want base value 8080 without WithProfiles", s.Port)
+	}
+}
+
+func TestLoad_ProfileUnknownKeysWarn(t *testing.T) {
+	base := writeProfiles(t,
+		[2]string{"config.yaml", "port: 8080\nmail:\n  host: smtp.base\n"},
+		[2]string{"config.dev.yaml", "prot: 9000\nmail:\n  hots: smtp.dev\nlabels:\n  anything: goes\n"},
+	)
+	core, logs := observer.New(zapcore.WarnLevel)
+
+	var s profileSettings
+	err := Load(&s, WithFile(base), WithProfiles(), WithLogger(zap.New(core)), env(map[string]string{"APP_ENV": "dev"}))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var keys []string
+	for _, entry := range logs.All() {
+		keys = append(keys, entry.ContextMap()["key"].(string))
+	}
+	if len(keys) != 2 || keys[0] != "mail.hots" || keys[1] != "prot" {
+		t.Errorf("warned keys = %v, want [mail.hots prot]", keys)
+	}
+	if s.Port != 8080 || s.Mail.Host != "smtp.base" {
+		t.Errorf("settings = %+v, want unknown keys to leave base values alone", s)
+	}
+}
+
+func TestLoad_ProfileErrors(t *testing.T) {
+	base := writeProfiles(t, [2]string{"config.yaml", "port: 8080\n"})
+
+	var s profileSettings
+	if err := Load(&s, WithFile(base), WithProfiles(), env(map[string]string{"APP_ENV": "staging"})); err == nil {
+		t.Error("Load() with missing profile file = nil, want error")
+	}
+	if err := Load(&s, WithFile(base), WithProfiles(), env(map[string]string{"APP_ENV": "../config"})); err == nil {
+		t.Error("Load() with path in profile name = nil, want error")
+	}
+}
+
+func TestReadProfile(t *testing.T) {
+	base := writeProfiles(t,
+		[2]string{"config.toml", "port = 8080\n"},
+		[2]string{"config.prod.toml", "port = 9000\n[mail]\nhost = \"smtp.prod\"\n"},
+	)
+
+	path, values, err := ReadProfile(base, "prod")
+	if err != nil {
+		t.Fatalf("ReadProfile() error = %v", err)
+	}
+	if filepath.Base(path) != "config.prod.toml" {
+		t.Errorf("path = %q, want config.prod.toml", path)
+	}
+	if values["port"] != int64(9000) || values["mail"].(map[string]any)["host"] != "smtp.prod" {
+		t.Errorf("values = %v", values)
+	}
+
+	if _, _, err := ReadProfile(base, "staging"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadProfile() for a missing profile error = %v, want fs.ErrNotExist", err)
+	}
+}
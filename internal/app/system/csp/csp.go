@@ -0,0 +1,226 @@
+// internal/app/system/csp/csp.go
+//
+// Package csp lets pages run inline scripts under a strict
+// Content-Security-Policy without 'unsafe-inline'.
+//
+// Middleware gives each request a random nonce and, when the response is
+// sent, adds 'nonce-…' to the script-src of any Content-Security-Policy (or
+// Content-Security-Policy-Report-Only) header on it, whichever middleware or
+// handler set the header. A policy without script-src gets one copied from
+// default-src, so the nonce does not loosen or tighten anything else; a
+// response with no policy is left alone.
+//
+// A directive that allows 'unsafe-inline' is not given the nonce: browsers
+// that understand nonces ignore 'unsafe-inline' next to one, which would
+// block the inline event handlers such a policy is there to allow.
+//
+// Templates mark their inline scripts with the cspNonce function (Attr) and
+// the nonce viewdata puts on BaseVM:
+//
+//	<script {{ cspNonce .CSPNonce }}>
+//	    ...
+//	</script>
+//
+// Inline event handlers (onclick="...") cannot carry a nonce; a strict
+// policy blocks them, so they have to move into a nonced script.
+package csp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"html/template"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Policy headers that Middleware adds the nonce to.
+const (
+	HeaderName           = "Content-Security-Policy"
+	ReportOnlyHeaderName = "Content-Security-Policy-Report-Only"
+)
+
+// nonceKey is the context key for the request's nonce.
+type nonceKey struct{}
+
+// WithNonce returns a copy of ctx carrying nonce. Middleware calls it; it is
+// exported for tests of handlers that render nonced templates.
+func WithNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, nonceKey{}, nonce)
+}
+
+// Nonce returns the request's nonce, or "" outside Middleware.
+func Nonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// newNonce returns 128 random bits, base64 encoded as CSP expects.
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic("csp: generate nonce: " + err.Error())
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// Middleware returns middleware that stores a fresh nonce in each request's
+// context and adds it to the response's policy headers. Place it ahead of
+// anything that may write an error page, such as panic recovery, so those
+// pages get the nonce too.
+func Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := newNonce()
+			next.ServeHTTP(&nonceWriter{ResponseWriter: w, nonce: nonce}, r.WithContext(WithNonce(r.Context(), nonce)))
+		})
+	}
+}
+
+// AddNonce returns policy with 'nonce-<nonce>' added to its script-src
+// directive, or to a script-src copied from default-src when there is none,
+// and to any script-src-elem, which takes precedence for <script> tags. A
+// policy with none of these, or an empty nonce, is returned unchanged, as
+// is a directive that already has the nonce or allows 'unsafe-inline'.
+func AddNonce(policy, nonce string) string {
+	if policy == "" || nonce == "" {
+		return policy
+	}
+	source := "'nonce-" + nonce + "'"
+
+	directives := strings.Split(policy, ";")
+	defaultSrc, scriptSrc := -1, false
+	for i, d := range directives {
+		name, value := directive(d)
+		switch name {
+		case "script-src", "script-src-elem":
+			scriptSrc = scriptSrc || name == "script-src"
+			if !slices.Contains(strings.Fields(value), source) && !allowsInline(value) {
+				directives[i] = strings.TrimRight(d, " \t") + " " + source
+			}
+		case "default-src":
+			defaultSrc = i
+		}
+	}
+	policy = strings.Join(directives, ";")
+	if scriptSrc || defaultSrc < 0 {
+		return policy
+	}
+
+	_, sources := directive(directives[defaultSrc])
+	if allowsInline(sources) {
+		return policy
+	}
+	// 'none' allows nothing, so it cannot be combined with the nonce.
+	if strings.EqualFold(strings.TrimSpace(sources), "'none'") {
+		sources = ""
+	}
+	added := strings.Join(strings.Fields("script-src "+sources+" "+source), " ")
+	return strings.TrimRight(strings.TrimSpace(policy), ";") + "; " + added
+}
+
+// RemoveNonce returns policy without the 'nonce-<nonce>' source AddNonce
+// gave it, for storing a response's policy so it can be sent again with a
+// different nonce. Directive names and other sources are kept.
+func RemoveNonce(policy, nonce string) string {
+	if policy == "" || nonce == "" {
+		return policy
+	}
+	source := "'nonce-" + nonce + "'"
+	if !strings.Contains(policy, source) {
+		return policy
+	}
+
+	directives := strings.Split(policy, ";")
+	for i, d := range directives {
+		fields := strings.Fields(d)
+		if !slices.Contains(fields, source) {
+			continue
+		}
+		fields = slices.DeleteFunc(fields, func(s string) bool { return s == source })
+		directives[i] = strings.Join(fields, " ")
+		if i > 0 {
+			directives[i] = " " + directives[i]
+		}
+	}
+	return strings.Join(directives, ";")
+}
+
+// directive splits a policy directive into its lowercased name and its
+// source list.
+func directive(d string) (name, value string) {
+	name, value, _ = strings.Cut(strings.TrimSpace(d), " ")
+	return strings.ToLower(name), value
+}
+
+// allowsInline reports whether a source list includes 'unsafe-inline'.
+func allowsInline(sources string) bool {
+	return slices.ContainsFunc(strings.Fields(sources), func(s string) bool {
+		return strings.EqualFold(s, "'unsafe-inline'")
+	})
+}
+
+// nonceWriter adds the nonce to the policy headers before the response
+// starts.
+type nonceWriter struct {
+	http.ResponseWriter
+	nonce string
+	once  sync.Once
+}
+
+func (w *nonceWriter) addNonce() {
+	w.once.Do(func() {
+		h := w.Header()
+		for _, name := range []string{HeaderName, ReportOnlyHeaderName} {
+			for i, v := range h[name] {
+				h[name][i] = AddNonce(v, w.nonce)
+			}
+		}
+	})
+}
+
+func (w *nonceWriter) WriteHeader(status int) {
+	// Informational responses do not carry the final headers.
+	if status >= 200 {
+		w.addNonce()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *nonceWriter) Write(b []byte) (int, error) {
+	w.addNonce()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the writer.
+func (w *nonceWriter) Flush() {
+	w.addNonce()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through the writer.
+func (w *nonceWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+// and httpx.Written.
+func (w *nonceWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Attr renders the nonce attribute for a script or style tag, or nothing
+// when nonce is "". The shared templates register it as cspNonce.
+func Attr(nonce string) template.HTMLAttr {
+	if nonce == "" {
+		return ""
+	}
+	return template.HTMLAttr(`nonce="` + template.HTMLEscapeString(nonce) + `"`)
+}
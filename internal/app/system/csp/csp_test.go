@@ -0,0 +1,177 @@
+package csp
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+)
+
+func TestAddNonce(t *testing.T) {
+	tests := []struct {
+		name, policy, want string
+	}{
+		{"empty policy", "", ""},
+		{"script-src", "default-src 'self'; script-src 'self'", "default-src 'self'; script-src 'self' 'nonce-abc'"},
+		{"script-src first", "script-src 'self'; img-src *", "script-src 'self' 'nonce-abc'; img-src *"},
+		{"uppercase directive", "SCRIPT-SRC 'self'", "SCRIPT-SRC 'self' 'nonce-abc'"},
+		{"already present", "script-src 'self' 'nonce-abc'", "script-src 'self' 'nonce-abc'"},
+		{"from default-src", "default-src 'self' https://cdn.example; img-src *", "default-src 'self' https://cdn.example; img-src *; script-src 'self' https://cdn.example 'nonce-abc'"},
+		{"default-src none", "default-src 'none';", "default-src 'none'; script-src 'nonce-abc'"},
+		{"script-src-elem too", "script-src 'self'; script-src-elem 'self'", "script-src 'self' 'nonce-abc'; script-src-elem 'self' 'nonce-abc'"},
+		{"no script policy", "img-src *; frame-ancestors 'none'", "img-src *; frame-ancestors 'none'"},
+		{"unsafe-inline", "script-src 'self' 'unsafe-inline'", "script-src 'self' 'unsafe-inline'"},
+		{"unsafe-inline in default-src", "default-src 'self' 'UNSAFE-INLINE'", "default-src 'self' 'UNSAFE-INLINE'"},
+		{"unsafe-inline in script-src only", "script-src 'unsafe-inline'; script-src-elem 'self'", "script-src 'unsafe-inline'; script-src-elem 'self' 'nonce-abc'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AddNonce(tt.policy, "abc"); got != tt.want {
+				t.Errorf("AddNonce(%q) = %q, want %q", tt.policy, got, tt.want)
+			}
+		})
+	}
+
+	if got := AddNonce("script-src 'self'", ""); got != "script-src 'self'" {
+		t.Errorf("AddNonce with empty nonce = %q, want policy unchanged", got)
+	}
+}
+
+func TestRemoveNonce(t *testing.T) {
+	tests := []struct {
+		name, policy, want string
+	}{
+		{"empty policy", "", ""},
+		{"no nonce", "script-src 'self'", "script-src 'self'"},
+		{"other nonce", "script-src 'self' 'nonce-xyz'", "script-src 'self' 'nonce-xyz'"},
+		{"script-src", "default-src 'self'; script-src 'self' 'nonce-abc'", "default-src 'self'; script-src 'self'"},
+		{"script-src-elem too", "script-src 'self' 'nonce-abc'; script-src-elem 'nonce-abc' 'self'", "script-src 'self'; script-src-elem 'self'"},
+		{"copied from default-src", "default-src 'none'; script-src 'nonce-abc'", "default-src 'none'; script-src"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RemoveNonce(tt.policy, "abc"); got != tt.want {
+				t.Errorf("RemoveNonce(%q) = %q, want %q", tt.policy, got, tt.want)
+			}
+		})
+	}
+
+	// A removed nonce leaves a policy that takes a new one.
+	policy := RemoveNonce(AddNonce("script-src 'self'", "abc"), "abc")
+	if got, want := AddNonce(policy, "def"), "script-src 'self' 'nonce-def'"; got != want {
+		t.Errorf("AddNonce after RemoveNonce = %q, want %q", got, want)
+	}
+}
+
+func TestMiddleware_AddsNonceToPolicy(t *testing.T) {
+	var seen string
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = Nonce(r.Context())
+		w.Header().Set(HeaderName, "default-src 'self'; script-src 'self'")
+		w.Header().Set(ReportOnlyHeaderName, "default-src 'none'")
+		w.Write([]byte("ok"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if seen == "" {
+		t.Fatal("handler saw no nonce")
+	}
+	if want := "default-src 'self'; script-src 'self' 'nonce-" + seen + "'"; rec.Header().Get(HeaderName) != want {
+		t.Errorf("%s = %q, want %q", HeaderName, rec.Header().Get(HeaderName), want)
+	}
+	if want := "default-src 'none'; script-src 'nonce-" + seen + "'"; rec.Header().Get(ReportOnlyHeaderName) != want {
+		t.Errorf("%s = %q, want %q", ReportOnlyHeaderName, rec.Header().Get(ReportOnlyHeaderName), want)
+	}
+}
+
+func TestMiddleware_PolicySetBeforeMiddleware(t *testing.T) {
+	// A policy set by outer middleware is rewritten when the handler writes.
+	var seen string
+	inner := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = Nonce(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderName, "script-src 'self'")
+		inner.ServeHTTP(w, r)
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := "script-src 'self' 'nonce-" + seen + "'"; rec.Header().Get(HeaderName) != want {
+		t.Errorf("%s = %q, want %q", HeaderName, rec.Header().Get(HeaderName), want)
+	}
+}
+
+func TestMiddleware_FreshNoncePerRequest(t *testing.T) {
+	seen := map[string]bool{}
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen[Nonce(r.Context())] = true
+	}))
+	for i := 0; i < 10; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if len(seen) != 10 {
+		t.Errorf("got %d distinct nonces over 10 requests, want 10", len(seen))
+	}
+}
+
+func TestMiddleware_NoPolicy(t *testing.T) {
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if _, ok := rec.Header()[HeaderName]; ok {
+		t.Errorf("%s set without a policy: %q", HeaderName, rec.Header().Get(HeaderName))
+	}
+}
+
+func TestMiddleware_KeepsWrittenVisible(t *testing.T) {
+	var written bool
+	h := httpx.Middleware(Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+		written = httpx.Written(w)
+	})))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !written {
+		t.Error("httpx.Written = false through the nonce writer, want true")
+	}
+}
+
+func TestNonce_OutsideMiddleware(t *testing.T) {
+	if got := Nonce(httptest.NewRequest(http.MethodGet, "/", nil).Context()); got != "" {
+		t.Errorf("Nonce() = %q, want empty", got)
+	}
+}
+
+func TestAttr(t *testing.T) {
+	tmpl := template.Must(template.New("page").Funcs(template.FuncMap{"cspNonce": Attr}).
+		Parse(`<script {{ cspNonce .Nonce }}>run()</script>`))
+
+	tests := []struct{ nonce, want string }{
+		{"abc+/=", `<script nonce="abc+/=">run()</script>`},
+		{"", `<script >run()</script>`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, struct{ Nonce string }{tt.nonce}); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("nonce %q rendered %q, want %q", tt.nonce, got, tt.want)
+		}
+	}
+	if strings.Contains(string(Attr(`x" onload="y`)), `" onload`) {
+		t.Error("Attr did not escape the nonce")
+	}
+}
@@ -0,0 +1,210 @@
+// Package dberrors recognizes database errors that handlers should report
+// to the client rather than as an internal error.
+//
+// UniqueViolation reports whether an insert or update broke a unique
+// constraint, such as a second signup with the same email, so the handler
+// can answer 409 Conflict instead of 500:
+//
+//	user, err := store.Create(ctx, input)
+//	if constraint, ok := dberrors.UniqueViolation(err); ok {
+//	    h.errors.ConflictWithDetails(w, r, map[string]string{fieldFor(constraint): "is already taken"})
+//	    return
+//	}
+//
+// It understands MongoDB's duplicate key errors and those of the common SQL
+// drivers: lib/pq and pgx (PostgreSQL), go-sql-driver/mysql, and mattn or
+// modernc sqlite. The SQL drivers are matched by the shape of their error
+// types rather than imported, so the application only links the driver it
+// uses.
+package dberrors
+
+import (
+	stderrors "errors"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Driver error codes for a unique constraint violation.
+const (
+	pgUniqueViolation      = "23505" // SQLSTATE unique_violation
+	mysqlDupEntry          = 1062    // ER_DUP_ENTRY
+	mysqlDupEntryWithKey   = 1586    // ER_DUP_ENTRY_WITH_KEY_NAME
+	sqliteConstraintUnique = 2067    // SQLITE_CONSTRAINT_UNIQUE
+	sqliteConstraintPK     = 1555    // SQLITE_CONSTRAINT_PRIMARYKEY
+)
+
+// UniqueViolation reports whether err, or any error it wraps, is a unique
+// constraint violation. constraint names the violated constraint or index
+// when the driver reports it (for MySQL, the key; for SQLite, the
+// table.column list) and is empty otherwise.
+func UniqueViolation(err error) (constraint string, ok bool) {
+	if err == nil {
+		return "", false
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return after(err.Error(), "index: ", " "), true
+	}
+	for _, e := range chain(err) {
+		if constraint, ok := uniqueViolation(e); ok {
+			return constraint, true
+		}
+	}
+	return "", false
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+// It has the func(error) bool shape stores take for duplicate detection,
+// such as userstore.NewSQLStore.
+func IsUniqueViolation(err error) bool {
+	_, ok := UniqueViolation(err)
+	return ok
+}
+
+// uniqueViolation checks a single error, without unwrapping it.
+func uniqueViolation(err error) (string, bool) {
+	// lib/pq's *pq.Error and pgx's *pgconn.PgError.
+	if s, ok := err.(interface{ SQLState() string }); ok {
+		if s.SQLState() != pgUniqueViolation {
+			return "", false
+		}
+		if name, ok := stringField(err, "ConstraintName"); ok { // pgx
+			return name, true
+		}
+		name, _ := stringField(err, "Constraint") // pq
+		return name, true
+	}
+
+	// go-sql-driver/mysql's *mysql.MySQLError; the key is only in the message:
+	// "Duplicate entry 'a@example.com' for key 'users.email'".
+	if typeName(err) == "MySQLError" {
+		if n, ok := uintField(err, "Number"); ok && (n == mysqlDupEntry || n == mysqlDupEntryWithKey) {
+			return after(err.Error(), "for key '", "'"), true
+		}
+		return "", false
+	}
+
+	// mattn/go-sqlite3's sqlite3.Error carries the extended code in a field,
+	// modernc.org/sqlite's *sqlite.Error behind a Code method. Both messages
+	// end "UNIQUE constraint failed: users.email".
+	code, ok := intField(err, "ExtendedCode")
+	if !ok {
+		if c, isCoder := err.(interface{ Code() int }); isCoder {
+			code, ok = int64(c.Code()), true
+		}
+	}
+	if ok && (code == sqliteConstraintUnique || code == sqliteConstraintPK) {
+		msg := err.Error()
+		if i := strings.LastIndex(msg, "constraint failed: "); i >= 0 {
+			return after(msg[i:], "constraint failed: ", " ("), true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// chain returns err and every error it wraps, depth first, following both
+// Unwrap() error and Unwrap() []error.
+func chain(err error) []error {
+	var out []error
+	stack := []error{err}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if e == nil {
+			continue
+		}
+		out = append(out, e)
+		switch u := e.(type) {
+		case interface{ Unwrap() []error }:
+			errs := u.Unwrap()
+			for i := len(errs) - 1; i >= 0; i-- {
+				stack = append(stack, errs[i])
+			}
+		default:
+			if next := stderrors.Unwrap(e); next != nil {
+				stack = append(stack, next)
+			}
+		}
+	}
+	return out
+}
+
+// after returns the text in s between the first occurrence of start and the
+// next occurrence of end (or the end of s), or "" if start does not occur.
+func after(s, start, end string) string {
+	i := strings.Index(s, start)
+	if i < 0 {
+		return ""
+	}
+	s = s[i+len(start):]
+	if j := strings.Index(s, end); j >= 0 {
+		s = s[:j]
+	}
+	return s
+}
+
+// structValue returns the struct err holds, dereferencing a pointer.
+func structValue(err error) (reflect.Value, bool) {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct
+}
+
+// typeName returns the name of err's type, without any pointer.
+func typeName(err error) string {
+	v, ok := structValue(err)
+	if !ok {
+		return ""
+	}
+	return v.Type().Name()
+}
+
+// field returns the exported field of err's struct with the given name.
+func field(err error, name string) (reflect.Value, bool) {
+	v, ok := structValue(err)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	f, ok := v.Type().FieldByName(name)
+	if !ok || !f.IsExported() {
+		return reflect.Value{}, false
+	}
+	return v.FieldByIndex(f.Index), true
+}
+
+func stringField(err error, name string) (string, bool) {
+	f, ok := field(err, name)
+	if !ok || f.Kind() != reflect.String {
+		return "", false
+	}
+	return f.String(), true
+}
+
+func intField(err error, name string) (int64, bool) {
+	f, ok := field(err, name)
+	if !ok {
+		return 0, false
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint()), true
+	}
+	return 0, false
+}
+
+func uintField(err error, name string) (uint64, bool) {
+	n, ok := intField(err, name)
+	if !ok || n < 0 {
+		return 0, false
+	}
+	return uint64(n), true
+}
@@ -0,0 +1,151 @@
+package dberrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The types below mirror the shape of each driver's error type, which is
+// all UniqueViolation looks at.
+
+// pqError is shaped like lib/pq's *pq.Error.
+type pqError struct {
+	Code       string
+	Message    string
+	Constraint string
+}
+
+func (e *pqError) Error() string    { return "pq: " + e.Message }
+func (e *pqError) SQLState() string { return e.Code }
+
+// PgError is shaped like pgx's *pgconn.PgError.
+type PgError struct {
+	Code           string
+	Message        string
+	ConstraintName string
+}
+
+func (e *PgError) Error() string    { return "ERROR: " + e.Message + " (SQLSTATE " + e.Code + ")" }
+func (e *PgError) SQLState() string { return e.Code }
+
+// MySQLError is shaped like go-sql-driver/mysql's *mysql.MySQLError.
+type MySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *MySQLError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+// sqlite3Error is shaped like mattn/go-sqlite3's sqlite3.Error.
+type sqlite3Error struct {
+	Code         int
+	ExtendedCode int
+	msg          string
+}
+
+func (e sqlite3Error) Error() string { return e.msg }
+
+// sqliteError is shaped like modernc.org/sqlite's *sqlite.Error.
+type sqliteError struct {
+	msg  string
+	code int
+}
+
+func (e *sqliteError) Error() string { return e.msg }
+func (e *sqliteError) Code() int     { return e.code }
+
+func TestUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantOK         bool
+		wantConstraint string
+	}{
+		{"nil", nil, false, ""},
+		{"plain error", errors.New("connection refused"), false, ""},
+		{
+			name:           "pq",
+			err:            &pqError{Code: "23505", Message: "duplicate key value violates unique constraint", Constraint: "users_email_key"},
+			wantOK:         true,
+			wantConstraint: "users_email_key",
+		},
+		{
+			name:   "pq other code",
+			err:    &pqError{Code: "23503", Message: "violates foreign key constraint", Constraint: "orders_user_fk"},
+			wantOK: false,
+		},
+		{
+			name:           "pgx",
+			err:            &PgError{Code: "23505", Message: "duplicate key value", ConstraintName: "users_login_id_ci_key"},
+			wantOK:         true,
+			wantConstraint: "users_login_id_ci_key",
+		},
+		{
+			name:           "mysql",
+			err:            &MySQLError{Number: 1062, Message: "Duplicate entry 'a@example.com' for key 'users.email'"},
+			wantOK:         true,
+			wantConstraint: "users.email",
+		},
+		{
+			name:   "mysql other number",
+			err:    &MySQLError{Number: 1452, Message: "Cannot add or update a child row"},
+			wantOK: false,
+		},
+		{
+			name:           "mattn sqlite",
+			err:            sqlite3Error{Code: 19, ExtendedCode: 2067, msg: "UNIQUE constraint failed: users.email"},
+			wantOK:         true,
+			wantConstraint: "users.email",
+		},
+		{
+			name:   "mattn sqlite not null",
+			err:    sqlite3Error{Code: 19, ExtendedCode: 1299, msg: "NOT NULL constraint failed: users.email"},
+			wantOK: false,
+		},
+		{
+			name:           "modernc sqlite",
+			err:            &sqliteError{code: 1555, msg: "constraint failed: UNIQUE constraint failed: users.id (1555)"},
+			wantOK:         true,
+			wantConstraint: "users.id",
+		},
+		{
+			name:           "wrapped",
+			err:            fmt.Errorf("create user: %w", &pqError{Code: "23505", Constraint: "users_email_key"}),
+			wantOK:         true,
+			wantConstraint: "users_email_key",
+		},
+		{
+			name:           "joined",
+			err:            errors.Join(errors.New("rollback failed"), &MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'PRIMARY'"}),
+			wantOK:         true,
+			wantConstraint: "PRIMARY",
+		},
+		{
+			name: "mongo",
+			err: mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+				Code:    11000,
+				Message: `E11000 duplicate key error collection: app.users index: email_1 dup key: { email: "a@example.com" }`,
+			}}},
+			wantOK:         true,
+			wantConstraint: "email_1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraint, ok := UniqueViolation(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("UniqueViolation() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if constraint != tt.wantConstraint {
+				t.Errorf("UniqueViolation() constraint = %q, want %q", constraint, tt.wantConstraint)
+			}
+			if got := IsUniqueViolation(tt.err); got != tt.wantOK {
+				t.Errorf("IsUniqueViolation() = %v, want %v", got, tt.wantOK)
+			}
+		})
+	}
+}
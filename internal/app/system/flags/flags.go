@@ -0,0 +1,176 @@
+// Package flags turns features on per environment or for a percentage of
+// users.
+//
+// Static flags come from configuration and are the same for everyone.
+// Rollout flags are enabled for a stable percentage of signed-in users: a
+// user is placed in a bucket by hashing the flag key with their user ID, so
+// the same user keeps the same answer across requests and raising the
+// percentage only adds users. Combine them with Any.
+//
+// Handlers are gated with Require, which answers with the given not-found
+// handler (normally the errors feature's 404 page) while the flag is off.
+// Views use the featureEnabled template function from FuncMap:
+//
+//	{{ if featureEnabled "reports_v2" .UserID }}...{{ end }}
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+)
+
+// Set reports whether a feature is enabled for the request or job that
+// ctx belongs to.
+type Set interface {
+	Enabled(ctx context.Context, key string) bool
+}
+
+// Static is a fixed set of flags, keyed by flag name.
+type Static map[string]bool
+
+// Enabled implements Set.
+func (s Static) Enabled(_ context.Context, key string) bool {
+	return s[key]
+}
+
+// ParseStatic builds a Static set from a comma-separated list of enabled
+// flag names, such as the feature_flags config value.
+func ParseStatic(list string) Static {
+	s := Static{}
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			s[key] = true
+		}
+	}
+	return s
+}
+
+// Rollout enables each flag for a percentage of users.
+type Rollout struct {
+	percents map[string]int
+}
+
+// NewRollout returns a Rollout for percents, keyed by flag name, with
+// values from 0 to 100.
+func NewRollout(percents map[string]int) *Rollout {
+	return &Rollout{percents: percents}
+}
+
+// ParseRollout builds a Rollout from a comma-separated list of
+// "flag:percent" entries, such as the feature_rollouts config value.
+func ParseRollout(list string) (*Rollout, error) {
+	percents := map[string]int{}
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		key, pct, ok := strings.Cut(entry, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(pct))
+		if !ok || err != nil || n < 0 || n > 100 || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("flags: invalid rollout %q (want flag:percent with percent 0-100)", entry)
+		}
+		percents[strings.TrimSpace(key)] = n
+	}
+	return NewRollout(percents), nil
+}
+
+// Enabled implements Set. Flags at 100 are on for everyone, including
+// signed-out visitors; below that, only users whose bucket falls under the
+// percentage see the flag.
+func (ro *Rollout) Enabled(ctx context.Context, key string) bool {
+	pct, ok := ro.percents[key]
+	switch {
+	case !ok || pct <= 0:
+		return false
+	case pct >= 100:
+		return true
+	}
+	id := userID(ctx)
+	if id == "" {
+		return false
+	}
+	return bucket(key, id) < pct
+}
+
+// bucket maps a flag and user to a stable value in [0, 100).
+func bucket(key, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(userID))
+	return int(h.Sum32() % 100)
+}
+
+// anySet is enabled when any of its sets is.
+type anySet []Set
+
+// Any returns a Set that enables a flag when any of sets enables it.
+func Any(sets ...Set) Set {
+	return anySet(sets)
+}
+
+func (a anySet) Enabled(ctx context.Context, key string) bool {
+	for _, s := range a {
+		if s != nil && s.Enabled(ctx, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// userIDKey is the context key for an explicit user ID.
+type userIDKey struct{}
+
+// WithUserID returns a copy of ctx that rollouts evaluate for userID, for
+// background jobs and templates that have a user ID but no request.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// userID returns the user ID set by WithUserID, or else the signed-in
+// session user's.
+func userID(ctx context.Context) string {
+	if id, ok := ctx.Value(userIDKey{}).(string); ok {
+		return id
+	}
+	if u, ok := auth.UserFromContext(ctx); ok {
+		return u.ID
+	}
+	return ""
+}
+
+// Require returns middleware that serves notFound while key is disabled
+// for the request, so gated routes look like they do not exist.
+func Require(set Set, key string, notFound http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !set.Enabled(r.Context(), key) {
+				notFound.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// FuncMap returns template helpers for feature flags:
+//
+//	featureEnabled KEY [USERID] - reports whether KEY is on, for USERID when given
+func FuncMap(set Set) template.FuncMap {
+	return template.FuncMap{
+		"featureEnabled": func(key string, userID ...string) bool {
+			ctx := context.Background()
+			if len(userID) > 0 {
+				ctx = WithUserID(ctx, userID[0])
+			}
+			return set.Enabled(ctx, key)
+		},
+	}
+}
@@ -0,0 +1,146 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dalemusser/strataforge/internal/app/system/auth"
+)
+
+func TestParseStatic(t *testing.T) {
+	s := ParseStatic(" new_nav, beta_reports ,,")
+	ctx := context.Background()
+	if !s.Enabled(ctx, "new_nav") || !s.Enabled(ctx, "beta_reports") {
+		t.Errorf("ParseStatic() = %v, want new_nav and beta_reports on", s)
+	}
+	if s.Enabled(ctx, "other") {
+		t.Error("unlisted flag should be off")
+	}
+}
+
+func TestParseRollout(t *testing.T) {
+	ro, err := ParseRollout("reports_v2:25, everyone:100,nobody:0")
+	if err != nil {
+		t.Fatalf("ParseRollout() error = %v", err)
+	}
+	if len(ro.percents) != 3 || ro.percents["reports_v2"] != 25 {
+		t.Errorf("ParseRollout() percents = %v", ro.percents)
+	}
+
+	for _, bad := range []string{"reports_v2", "reports_v2:abc", "reports_v2:101", ":50"} {
+		if _, err := ParseRollout(bad); err == nil {
+			t.Errorf("ParseRollout(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestRollout_Enabled(t *testing.T) {
+	ro := NewRollout(map[string]int{"half": 50, "all": 100, "none": 0})
+	ctx := context.Background()
+
+	if !ro.Enabled(ctx, "all") {
+		t.Error("100% flag should be on without a user")
+	}
+	if ro.Enabled(WithUserID(ctx, "u1"), "none") || ro.Enabled(WithUserID(ctx, "u1"), "unknown") {
+		t.Error("0% and unknown flags should be off")
+	}
+	if ro.Enabled(ctx, "half") {
+		t.Error("partial rollout should be off without a user")
+	}
+
+	on := 0
+	for i := 0; i < 1000; i++ {
+		userCtx := WithUserID(ctx, fmt.Sprintf("user-%d", i))
+		got := ro.Enabled(userCtx, "half")
+		if got != ro.Enabled(userCtx, "half") {
+			t.Fatal("rollout answer is not stable for the same user")
+		}
+		if got {
+			on++
+		}
+	}
+	if on < 400 || on > 600 {
+		t.Errorf("50%% rollout enabled %d of 1000 users, want about 500", on)
+	}
+}
+
+func TestRollout_RaisingPercentOnlyAddsUsers(t *testing.T) {
+	low := NewRollout(map[string]int{"f": 10})
+	high := NewRollout(map[string]int{"f": 30})
+	for i := 0; i < 500; i++ {
+		ctx := WithUserID(context.Background(), fmt.Sprintf("user-%d", i))
+		if low.Enabled(ctx, "f") && !high.Enabled(ctx, "f") {
+			t.Fatalf("user-%d lost the flag when the rollout grew", i)
+		}
+	}
+}
+
+func TestRollout_UsesSessionUser(t *testing.T) {
+	ro := NewRollout(map[string]int{"f": 50})
+	var id string
+	for i := 0; ; i++ {
+		id = fmt.Sprintf("user-%d", i)
+		if ro.Enabled(WithUserID(context.Background(), id), "f") {
+			break
+		}
+	}
+
+	req := auth.WithTestUser(httptest.NewRequest(http.MethodGet, "/", nil), &auth.SessionUser{ID: id})
+	if !ro.Enabled(req.Context(), "f") {
+		t.Error("rollout should use the signed-in user's ID")
+	}
+}
+
+func TestAny(t *testing.T) {
+	set := Any(Static{"a": true}, nil, NewRollout(map[string]int{"b": 100}))
+	ctx := context.Background()
+	if !set.Enabled(ctx, "a") || !set.Enabled(ctx, "b") || set.Enabled(ctx, "c") {
+		t.Error("Any() should enable a flag when any set does")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	set := Static{"on": true}
+
+	tests := map[string]int{"on": http.StatusOK, "off": http.StatusNotFound}
+	for key, want := range tests {
+		rec := httptest.NewRecorder()
+		Require(set, key, notFound)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != want {
+			t.Errorf("Require(%q) status = %d, want %d", key, rec.Code, want)
+		}
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	set := Any(Static{"new_nav": true}, NewRollout(map[string]int{"half": 50}))
+	tmpl := template.Must(template.New("t").Funcs(FuncMap(set)).Parse(
+		`{{ if featureEnabled "new_nav" }}nav{{ end }}{{ if featureEnabled "half" .UserID }} half{{ end }}`))
+
+	var enabledFor string
+	for i := 0; enabledFor == ""; i++ {
+		id := fmt.Sprintf("user-%d", i)
+		if set.Enabled(WithUserID(context.Background(), id), "half") {
+			enabledFor = id
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, struct{ UserID string }{enabledFor}); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if b.String() != "nav half" {
+		t.Errorf("rendered %q, want %q", b.String(), "nav half")
+	}
+}
@@ -0,0 +1,164 @@
+package formutil
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBytes is the body limit Parse uses when maxBytes is zero or
+// negative.
+const DefaultMaxBytes int64 = 1 << 20
+
+// DefaultMaxMemory is how much of a multipart body Parse keeps in memory;
+// file parts beyond it are written to temporary files that Form.Close
+// removes.
+const DefaultMaxMemory int64 = 8 << 20
+
+// Kinds of Parse failure. A *ParseError wraps exactly one of them, so
+// callers can pick a response with errors.Is.
+var (
+	// ErrTooLarge means the body exceeded the limit; answer with 413.
+	ErrTooLarge = errors.New("formutil: request body too large")
+	// ErrBadForm means the body could not be parsed as a form; answer
+	// with 400.
+	ErrBadForm = errors.New("formutil: invalid form body")
+)
+
+// ErrNoFile is returned by Form.File when the request has no file for
+// the field, or the file is empty.
+var ErrNoFile = errors.New("formutil: no file uploaded")
+
+// ParseError describes why Parse rejected a request body. Its message is
+// safe to show to users.
+type ParseError struct {
+	Kind    error // ErrTooLarge or ErrBadForm
+	Message string
+}
+
+func (e *ParseError) Error() string { return e.Message }
+
+// Unwrap returns the error kind.
+func (e *ParseError) Unwrap() error { return e.Kind }
+
+// Form is a parsed urlencoded or multipart form. Call Close when done with
+// it, typically with defer, to close opened files and remove temporary
+// files.
+type Form struct {
+	r     *http.Request
+	files []multipart.File
+}
+
+// Parse parses r's body as a form, limiting it to maxBytes through
+// http.MaxBytesReader. multipart/form-data bodies keep at most
+// DefaultMaxMemory (or maxBytes, if smaller) in memory. Failures are
+// returned as *ParseError.
+//
+// Usage:
+//
+//	f, err := formutil.Parse(r, 32<<20)
+//	if err != nil {
+//	    if errors.Is(err, formutil.ErrTooLarge) {
+//	        errorsHandler.Error(w, r, http.StatusRequestEntityTooLarge)
+//	        return
+//	    }
+//	    errorsHandler.BadRequest(w, r)
+//	    return
+//	}
+//	defer f.Close()
+func Parse(r *http.Request, maxBytes int64) (*Form, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	r.Body = http.MaxBytesReader(nil, r.Body, maxBytes)
+
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		err = r.ParseMultipartForm(min(maxBytes, DefaultMaxMemory))
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return nil, parseError(err, maxBytes)
+	}
+	return &Form{r: r}, nil
+}
+
+// parseError classifies an error from ParseForm or ParseMultipartForm.
+func parseError(err error, maxBytes int64) *ParseError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || errors.Is(err, multipart.ErrMessageTooLarge) {
+		return &ParseError{Kind: ErrTooLarge, Message: fmt.Sprintf("form must not be larger than %d bytes", maxBytes)}
+	}
+	return &ParseError{Kind: ErrBadForm, Message: "form data is invalid"}
+}
+
+// String returns the first value for key with surrounding whitespace
+// removed, or "" if it is absent.
+func (f *Form) String(key string) string {
+	return strings.TrimSpace(f.r.FormValue(key))
+}
+
+// Int returns the value for key as an int, or def if it is absent or
+// blank. A value that is not an integer is an error.
+func (f *Form) Int(key string, def int) (int, error) {
+	s := f.String(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def, fmt.Errorf("%s must be a whole number", key)
+	}
+	return n, nil
+}
+
+// Bool reports whether key holds a checkbox-style true value: "on",
+// "true", "1", or "yes", in any case.
+func (f *Form) Bool(key string) bool {
+	switch strings.ToLower(f.String(key)) {
+	case "on", "true", "1", "yes":
+		return true
+	}
+	return false
+}
+
+// File returns the first non-empty file uploaded for key, or ErrNoFile.
+// The file is closed by Close.
+func (f *Form) File(key string) (multipart.File, *multipart.FileHeader, error) {
+	if f.r.MultipartForm == nil {
+		return nil, nil, ErrNoFile
+	}
+	headers := f.r.MultipartForm.File[key]
+	if len(headers) == 0 || headers[0].Size == 0 {
+		return nil, nil, ErrNoFile
+	}
+	file, err := headers[0].Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	f.files = append(f.files, file)
+	return file, headers[0], nil
+}
+
+// Close closes files opened with File and removes any temporary files
+// created while parsing.
+func (f *Form) Close() error {
+	var errs []error
+	for _, file := range f.files {
+		// Callers often close the file themselves as well.
+		if err := file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	f.files = nil
+	if f.r.MultipartForm != nil {
+		errs = append(errs, f.r.MultipartForm.RemoveAll())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,168 @@
+package formutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newMultipartRequest builds a multipart POST with the given fields and one
+// file part named "file".
+func newMultipartRequest(t *testing.T, fields map[string]string, fileContent []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	if fileContent != nil {
+		fw, err := mw.CreateFormFile("file", "notes.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(fileContent)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestParse_URLEncoded(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader("name=+Ada+&count=3&notify=on&bad=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	f, err := Parse(req, 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	defer f.Close()
+
+	if got := f.String("name"); got != "Ada" {
+		t.Errorf("String(name) = %q, want %q", got, "Ada")
+	}
+	if n, err := f.Int("count", 0); err != nil || n != 3 {
+		t.Errorf("Int(count) = %d, %v; want 3, nil", n, err)
+	}
+	if n, err := f.Int("missing", 7); err != nil || n != 7 {
+		t.Errorf("Int(missing) = %d, %v; want default 7", n, err)
+	}
+	if _, err := f.Int("bad", 0); err == nil {
+		t.Error("Int(bad) should fail for a non-numeric value")
+	}
+	if !f.Bool("notify") || f.Bool("missing") {
+		t.Error("Bool() did not follow checkbox semantics")
+	}
+	if _, _, err := f.File("file"); !errors.Is(err, ErrNoFile) {
+		t.Errorf("File() on a urlencoded form error = %v, want ErrNoFile", err)
+	}
+}
+
+func TestParse_Multipart(t *testing.T) {
+	req := newMultipartRequest(t, map[string]string{"description": "Q3 notes"}, []byte("hello"))
+
+	f, err := Parse(req, 1<<20)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	defer f.Close()
+
+	if got := f.String("description"); got != "Q3 notes" {
+		t.Errorf("String(description) = %q", got)
+	}
+	file, header, err := f.File("file")
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if header.Filename != "notes.txt" {
+		t.Errorf("Filename = %q", header.Filename)
+	}
+	if b, _ := io.ReadAll(file); string(b) != "hello" {
+		t.Errorf("file content = %q", b)
+	}
+	if _, _, err := f.File("other"); !errors.Is(err, ErrNoFile) {
+		t.Errorf("File(other) error = %v, want ErrNoFile", err)
+	}
+}
+
+func TestParse_EmptyFileIsNoFile(t *testing.T) {
+	f, err := Parse(newMultipartRequest(t, nil, []byte{}), 0)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	defer f.Close()
+
+	if _, _, err := f.File("file"); !errors.Is(err, ErrNoFile) {
+		t.Errorf("File() for an empty upload error = %v, want ErrNoFile", err)
+	}
+}
+
+func TestParse_TooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *http.Request
+	}{
+		{"urlencoded", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("v="+strings.Repeat("a", 200)))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}()},
+		{"multipart", newMultipartRequest(t, nil, bytes.Repeat([]byte("a"), 200))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.req, 64)
+			if !errors.Is(err, ErrTooLarge) {
+				t.Fatalf("Parse() error = %v, want ErrTooLarge", err)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) || pe.Message == "" {
+				t.Errorf("Parse() error %v is not a *ParseError with a message", err)
+			}
+		})
+	}
+}
+
+func TestParse_BadMultipart(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not multipart"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=xyz")
+
+	if _, err := Parse(req, 0); !errors.Is(err, ErrBadForm) {
+		t.Errorf("Parse() error = %v, want ErrBadForm", err)
+	}
+}
+
+func TestForm_CloseRemovesTempFiles(t *testing.T) {
+	// A file larger than the in-memory budget is stored on disk.
+	content := bytes.Repeat([]byte("x"), int(DefaultMaxMemory)+1024)
+	f, err := Parse(newMultipartRequest(t, nil, content), 2*DefaultMaxMemory)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	file, _, err := f.File("file")
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	osFile, ok := file.(*os.File)
+	if !ok {
+		t.Fatalf("large upload is %T, want a temporary *os.File", file)
+	}
+	path := osFile.Name()
+	file.Close() // closing twice must not make Close fail
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("temporary file %s still exists after Close (stat err = %v)", path, err)
+	}
+}
@@ -0,0 +1,43 @@
+// internal/app/system/httpx/deadline.go
+package httpx
+
+import (
+	"context"
+	"time"
+)
+
+// deadlineKey is the context key for the deadline set with WithDeadline.
+type deadlineKey struct{}
+
+// WithDeadline returns a copy of ctx that reports d as the request's
+// deadline. It is for middleware that enforces a deadline by cancelling the
+// context itself (as the timeout feature does) rather than letting it
+// expire, so ctx.Deadline alone would not show it.
+func WithDeadline(ctx context.Context, d time.Time) context.Context {
+	if prev, ok := ctx.Value(deadlineKey{}).(time.Time); ok && prev.Before(d) {
+		return ctx
+	}
+	return context.WithValue(ctx, deadlineKey{}, d)
+}
+
+// Deadline returns when the request's time runs out: the earlier of the
+// deadline set with WithDeadline and ctx's own. ok is false when there is
+// neither. Handlers can compare time.Until(deadline) with the cost of
+// optional work, such as a slow summary panel, and skip it when time is
+// short.
+func Deadline(ctx context.Context) (deadline time.Time, ok bool) {
+	deadline, ok = ctx.Deadline()
+	if d, set := ctx.Value(deadlineKey{}).(time.Time); set && (!ok || d.Before(deadline)) {
+		deadline, ok = d, true
+	}
+	return deadline, ok
+}
+
+// Expired reports whether ctx is done or its Deadline has passed.
+func Expired(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return true
+	}
+	d, ok := Deadline(ctx)
+	return ok && !time.Now().Before(d)
+}
@@ -0,0 +1,62 @@
+package httpx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeadline_None(t *testing.T) {
+	if _, ok := Deadline(context.Background()); ok {
+		t.Error("Deadline() ok = true for a context without a deadline")
+	}
+	if Expired(context.Background()) {
+		t.Error("Expired() = true for a context without a deadline")
+	}
+}
+
+func TestDeadline_FromValue(t *testing.T) {
+	want := time.Now().Add(time.Minute)
+	got, ok := Deadline(WithDeadline(context.Background(), want))
+	if !ok || !got.Equal(want) {
+		t.Errorf("Deadline() = %v, %v; want %v, true", got, ok, want)
+	}
+}
+
+func TestDeadline_EarliestWins(t *testing.T) {
+	soon := time.Now().Add(time.Second)
+	later := soon.Add(time.Hour)
+
+	ctx, cancel := context.WithDeadline(context.Background(), later)
+	defer cancel()
+	if got, _ := Deadline(WithDeadline(ctx, soon)); !got.Equal(soon) {
+		t.Errorf("value earlier than context: Deadline() = %v, want %v", got, soon)
+	}
+
+	ctx2, cancel2 := context.WithDeadline(context.Background(), soon)
+	defer cancel2()
+	if got, _ := Deadline(WithDeadline(ctx2, later)); !got.Equal(soon) {
+		t.Errorf("context earlier than value: Deadline() = %v, want %v", got, soon)
+	}
+
+	// A later WithDeadline does not extend an earlier one
+	ctx3 := WithDeadline(WithDeadline(context.Background(), soon), later)
+	if got, _ := Deadline(ctx3); !got.Equal(soon) {
+		t.Errorf("nested WithDeadline: Deadline() = %v, want %v", got, soon)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	if !Expired(WithDeadline(context.Background(), time.Now().Add(-time.Second))) {
+		t.Error("Expired() = false for a past deadline")
+	}
+	if Expired(WithDeadline(context.Background(), time.Now().Add(time.Minute))) {
+		t.Error("Expired() = true for a future deadline")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if !Expired(ctx) {
+		t.Error("Expired() = false for a cancelled context")
+	}
+}
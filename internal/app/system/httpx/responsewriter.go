@@ -0,0 +1,130 @@
+// internal/app/system/httpx/responsewriter.go
+//
+// Package httpx holds small net/http helpers shared by middleware and
+// handlers.
+//
+// ResponseWriter records whether a response has started and with which
+// status, and drops a second WriteHeader instead of letting net/http log
+// "superfluous response.WriteHeader call". Code that may write an error
+// after a handler has begun its response (the errors feature's Handler, the
+// panic recovery middleware) asks Written first and leaves a started
+// response alone.
+package httpx
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// StatusWriter is implemented by response writers that know whether the
+// response has started. Written reports whether the status line has been
+// sent or committed to; Status is that status, or 0 before it.
+type StatusWriter interface {
+	Written() bool
+	Status() int
+}
+
+// Written reports whether the response behind w has started. It checks w
+// and each writer it wraps (through Unwrap) for a StatusWriter and returns
+// false if none is found.
+func Written(w http.ResponseWriter) bool {
+	for w != nil {
+		if sw, ok := w.(StatusWriter); ok {
+			return sw.Written()
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+	return false
+}
+
+// ResponseWriter wraps an http.ResponseWriter to track the status and the
+// number of body bytes written. Only the first WriteHeader call with a
+// final (non-1xx) status is passed on.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// Wrap returns w as a *ResponseWriter, wrapping it unless it already is one.
+func Wrap(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+// Middleware wraps every response in a ResponseWriter, so handlers and
+// middleware below it can rely on Written.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(Wrap(w), r)
+	})
+}
+
+// WriteHeader sends the status unless one has already been sent.
+// Informational (1xx) statuses are passed on and do not count.
+func (w *ResponseWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write sends the body, with a 200 status if none has been sent.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Written reports whether the status has been sent.
+func (w *ResponseWriter) Written() bool {
+	return w.status != 0
+}
+
+// Status returns the status sent, or 0 if none has been.
+func (w *ResponseWriter) Status() int {
+	return w.status
+}
+
+// BytesWritten returns the number of body bytes written.
+func (w *ResponseWriter) BytesWritten() int {
+	return w.bytes
+}
+
+// Flush sends any buffered data, with a 200 status if none has been sent.
+func (w *ResponseWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets WebSocket and other upgraded connections take over the
+// connection. The response counts as written afterwards.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,87 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriter_IgnoresSecondWriteHeader(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := Wrap(rec)
+
+	if w.Written() {
+		t.Fatal("Written() = true before anything was written")
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusInternalServerError)
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("recorded status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if w.Status() != http.StatusCreated {
+		t.Errorf("Status() = %d, want %d", w.Status(), http.StatusCreated)
+	}
+	if !w.Written() {
+		t.Error("Written() = false after WriteHeader")
+	}
+}
+
+func TestResponseWriter_WriteImpliesOK(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := Wrap(rec)
+
+	n, err := w.Write([]byte("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if w.Status() != http.StatusOK {
+		t.Errorf("Status() = %d, want %d", w.Status(), http.StatusOK)
+	}
+	if w.BytesWritten() != 5 {
+		t.Errorf("BytesWritten() = %d, want 5", w.BytesWritten())
+	}
+}
+
+func TestResponseWriter_InformationalDoesNotCount(t *testing.T) {
+	w := Wrap(httptest.NewRecorder())
+
+	w.WriteHeader(http.StatusEarlyHints)
+	if w.Written() {
+		t.Error("Written() = true after a 1xx status")
+	}
+	w.WriteHeader(http.StatusAccepted)
+	if w.Status() != http.StatusAccepted {
+		t.Errorf("Status() = %d, want %d", w.Status(), http.StatusAccepted)
+	}
+}
+
+func TestWrap_ReusesExisting(t *testing.T) {
+	w := Wrap(httptest.NewRecorder())
+	if Wrap(w) != w {
+		t.Error("Wrap of a *ResponseWriter returned a new wrapper")
+	}
+}
+
+// passthrough wraps a writer without tracking anything, like most middleware.
+type passthrough struct {
+	http.ResponseWriter
+}
+
+func (p passthrough) Unwrap() http.ResponseWriter { return p.ResponseWriter }
+
+func TestWritten_WalksUnwrap(t *testing.T) {
+	inner := Wrap(httptest.NewRecorder())
+	outer := passthrough{inner}
+
+	if Written(outer) {
+		t.Fatal("Written = true before anything was written")
+	}
+	outer.WriteHeader(http.StatusNoContent)
+	if !Written(outer) {
+		t.Error("Written = false after WriteHeader through a wrapper")
+	}
+	if Written(httptest.NewRecorder()) {
+		t.Error("Written = true for a writer with no StatusWriter")
+	}
+}
@@ -0,0 +1,306 @@
+// internal/app/system/i18n/i18n.go
+//
+// Package i18n loads translations and picks a locale for each request.
+//
+// Translations live in one JSON file per locale, named for the locale
+// ("en.json", "es.json", "pt-BR.json"), usually in an embed.FS. A file is an
+// object of keys to messages; nested objects are flattened with dots, so
+// {"nav": {"home": "Home"}} defines "nav.home". Messages may contain fmt
+// verbs filled from T's args.
+//
+// Middleware picks the locale from the locale cookie (Cookie, default
+// "lang"), then the Accept-Language header, then the bundle's default, and
+// stores it in the request context together with the bundle. Handlers
+// translate with T(ctx, key, args...); templates use the "t" function from
+// TemplateFuncs with the page's locale:
+//
+//	{{ t .Locale "nav.home" }}
+//
+// A key missing from the locale falls back to its base language (pt-BR ->
+// pt), then the default locale. A key missing everywhere is returned as is,
+// and a warning is logged the first time it is seen.
+//
+// The errors feature reads the locale chosen here (see Locale), so error
+// pages follow the same choice as the rest of the app.
+//
+// Usage:
+//
+//	//go:embed locales/*.json
+//	var localeFS embed.FS
+//
+//	bundle, err := i18n.Load(localeFS, "locales", "en", i18n.WithLogger(logger))
+//	templatefuncs.RegisterMap(bundle.TemplateFuncs())
+//	r.Use(bundle.Middleware)
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// DefaultCookie is the name of the cookie holding a chosen locale.
+const DefaultCookie = "lang"
+
+// Bundle holds the translations for every locale.
+type Bundle struct {
+	messages      map[string]map[string]string // locale -> key -> message
+	defaultLocale string
+	cookie        string
+	logger        *zap.Logger
+	missing       sync.Map // key -> struct{}; keys already warned about
+}
+
+// Option configures a Bundle.
+type Option func(*Bundle)
+
+// WithLogger logs missing keys as warnings, once per key.
+func WithLogger(logger *zap.Logger) Option {
+	return func(b *Bundle) {
+		b.logger = logger
+	}
+}
+
+// WithCookie sets the name of the cookie Middleware reads the locale from.
+// The default is DefaultCookie.
+func WithCookie(name string) Option {
+	return func(b *Bundle) {
+		b.cookie = name
+	}
+}
+
+// Load reads every *.json file in dir of fsys as the translations for the
+// locale it is named after. defaultLocale must be one of them.
+func Load(fsys fs.FS, dir, defaultLocale string, opts ...Option) (*Bundle, error) {
+	b := &Bundle{
+		messages:      make(map[string]map[string]string),
+		defaultLocale: normalize(defaultLocale),
+		cookie:        DefaultCookie,
+		logger:        zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var raw map[string]any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		msgs := make(map[string]string)
+		if err := flatten("", raw, msgs); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", file, err)
+		}
+		b.messages[normalize(strings.TrimSuffix(path.Base(file), ".json"))] = msgs
+	}
+
+	if _, ok := b.messages[b.defaultLocale]; !ok {
+		return nil, fmt.Errorf("i18n: no translations for default locale %q in %s", defaultLocale, dir)
+	}
+	return b, nil
+}
+
+// flatten copies the messages in m into out, joining nested keys with dots.
+func flatten(prefix string, m map[string]any, out map[string]string) error {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			out[key] = v
+		case map[string]any:
+			if err := flatten(key, v, out); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: message must be a string or an object, not %T", key, v)
+		}
+	}
+	return nil
+}
+
+// Locales returns the loaded locales, sorted.
+func (b *Bundle) Locales() []string {
+	locales := make([]string, 0, len(b.messages))
+	for l := range b.messages {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// DefaultLocale returns the locale used when no other matches.
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// Match returns the loaded locale for tag: tag itself or its base language
+// (es-MX -> es). It returns "" when neither is loaded.
+func (b *Bundle) Match(tag string) string {
+	tag = normalize(tag)
+	if tag == "" {
+		return ""
+	}
+	if _, ok := b.messages[tag]; ok {
+		return tag
+	}
+	if base, _, found := strings.Cut(tag, "-"); found {
+		if _, ok := b.messages[base]; ok {
+			return base
+		}
+	}
+	return ""
+}
+
+// Detect picks the locale for r: the locale cookie, then Accept-Language,
+// then the default locale.
+func (b *Bundle) Detect(r *http.Request) string {
+	if c, err := r.Cookie(b.cookie); err == nil {
+		if locale := b.Match(c.Value); locale != "" {
+			return locale
+		}
+	}
+	for _, tag := range AcceptedLanguages(r.Header.Get("Accept-Language")) {
+		if locale := b.Match(tag); locale != "" {
+			return locale
+		}
+	}
+	return b.defaultLocale
+}
+
+// Middleware stores the detected locale and the bundle in each request
+// context, for Locale and T.
+func (b *Bundle) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), bundleKey{}, b)
+		ctx = WithLocale(ctx, b.Detect(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Translate returns the message for key in locale, formatted with args.
+// See the package documentation for the fallbacks.
+func (b *Bundle) Translate(locale, key string, args ...any) string {
+	msg, ok := b.lookup(locale, key)
+	if !ok {
+		if _, warned := b.missing.LoadOrStore(key, struct{}{}); !warned {
+			b.logger.Warn("missing translation", zap.String("key", key), zap.String("locale", locale))
+		}
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// lookup finds key in locale, its base language, or the default locale.
+func (b *Bundle) lookup(locale, key string) (string, bool) {
+	if l := b.Match(locale); l != "" {
+		if msg, ok := b.messages[l][key]; ok {
+			return msg, true
+		}
+		if base, _, found := strings.Cut(l, "-"); found {
+			if msg, ok := b.messages[base][key]; ok {
+				return msg, true
+			}
+		}
+	}
+	msg, ok := b.messages[b.defaultLocale][key]
+	return msg, ok
+}
+
+// TemplateFuncs returns the "t" template function, called as
+// {{ t LOCALE KEY [ARGS...] }}. Register it with templatefuncs.RegisterMap.
+func (b *Bundle) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{"t": b.Translate}
+}
+
+// bundleKey and localeKey are the context keys Middleware sets.
+type (
+	bundleKey struct{}
+	localeKey struct{}
+)
+
+// WithLocale returns a copy of ctx carrying locale.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns the locale stored by Middleware or WithLocale, or "".
+func Locale(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// T translates key into the request's locale with the bundle installed by
+// Middleware. Without a bundle in ctx it returns key.
+func T(ctx context.Context, key string, args ...any) string {
+	b, ok := ctx.Value(bundleKey{}).(*Bundle)
+	if !ok {
+		return key
+	}
+	return b.Translate(Locale(ctx), key, args...)
+}
+
+// AcceptedLanguages returns the language tags in an Accept-Language header,
+// highest quality first. Wildcards and tags with q=0 are dropped.
+func AcceptedLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(name, "q") {
+				if f, err := strconv.ParseFloat(value, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{tag, q})
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// normalize lowercases a locale tag and uses "-" as the separator.
+func normalize(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
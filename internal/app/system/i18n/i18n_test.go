@@ -0,0 +1,135 @@
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var testFS = fstest.MapFS{
+	"locales/en.json":    {Data: []byte(`{"greeting": "Hello, %s!", "nav": {"home": "Home", "files": "Files"}}`)},
+	"locales/es.json":    {Data: []byte(`{"greeting": "¡Hola, %s!", "nav": {"home": "Inicio"}}`)},
+	"locales/pt-BR.json": {Data: []byte(`{"nav": {"home": "Início"}}`)},
+	"locales/pt.json":    {Data: []byte(`{"nav": {"files": "Arquivos"}}`)},
+}
+
+func mustLoad(t *testing.T, opts ...Option) *Bundle {
+	t.Helper()
+	b, err := Load(testFS, "locales", "en", opts...)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return b
+}
+
+func TestLoad(t *testing.T) {
+	b := mustLoad(t)
+	if got, want := b.Locales(), []string{"en", "es", "pt", "pt-br"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Locales() = %v, want %v", got, want)
+	}
+
+	if _, err := Load(testFS, "locales", "fr"); err == nil {
+		t.Error("Load with a missing default locale succeeded")
+	}
+	bad := fstest.MapFS{"l/en.json": {Data: []byte(`{"count": 3}`)}}
+	if _, err := Load(bad, "l", "en"); err == nil {
+		t.Error("Load accepted a non-string message")
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	b := mustLoad(t)
+	tests := []struct {
+		locale, key string
+		args        []any
+		want        string
+	}{
+		{"es", "nav.home", nil, "Inicio"},
+		{"es-MX", "nav.home", nil, "Inicio"},    // base language
+		{"es", "nav.files", nil, "Files"},       // default locale
+		{"pt-BR", "nav.files", nil, "Arquivos"}, // pt-BR -> pt
+		{"pt-br", "nav.home", nil, "Início"},
+		{"en", "greeting", []any{"Ana"}, "Hello, Ana!"},
+		{"es", "greeting", []any{"Ana"}, "¡Hola, Ana!"},
+		{"fr", "nav.home", nil, "Home"},
+		{"en", "nav.missing", nil, "nav.missing"},
+	}
+	for _, tt := range tests {
+		if got := b.Translate(tt.locale, tt.key, tt.args...); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.locale, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate_WarnsOnceForMissingKey(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	b := mustLoad(t, WithLogger(zap.New(core)))
+
+	b.Translate("en", "nope")
+	b.Translate("es", "nope")
+	b.Translate("en", "other")
+
+	if n := logs.FilterMessage("missing translation").Len(); n != 2 {
+		t.Errorf("got %d warnings, want 2 (one per key)", n)
+	}
+}
+
+func TestMiddleware_DetectsLocale(t *testing.T) {
+	b := mustLoad(t)
+
+	tests := []struct {
+		name   string
+		cookie string
+		accept string
+		want   string
+	}{
+		{"default", "", "", "en"},
+		{"accept-language", "", "fr;q=1, es-MX;q=0.8, en;q=0.5", "es"},
+		{"cookie wins", "pt-BR", "es", "pt-br"},
+		{"unknown cookie ignored", "xx", "es", "es"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotLocale, gotText string
+			h := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotLocale = Locale(r.Context())
+				gotText = T(r.Context(), "nav.home")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: DefaultCookie, Value: tt.cookie})
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept-Language", tt.accept)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if gotLocale != tt.want {
+				t.Errorf("Locale = %q, want %q", gotLocale, tt.want)
+			}
+			if want := b.Translate(tt.want, "nav.home"); gotText != want {
+				t.Errorf("T = %q, want %q", gotText, want)
+			}
+		})
+	}
+}
+
+func TestT_WithoutBundle(t *testing.T) {
+	if got := T(context.Background(), "nav.home"); got != "nav.home" {
+		t.Errorf("T without a bundle = %q, want the key", got)
+	}
+}
+
+func TestAcceptedLanguages(t *testing.T) {
+	got := AcceptedLanguages("da, en-GB;q=0.8, en;q=0.7, *;q=0.5, fr;q=0")
+	if want := []string{"da", "en-GB", "en"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AcceptedLanguages = %v, want %v", got, want)
+	}
+}
@@ -55,6 +55,12 @@ func EnsureAll(ctx context.Context, db *mongo.Database) error {
 	if err := ensureRateLimits(ctx, db); err != nil {
 		problems = append(problems, "rate_limits: "+err.Error())
 	}
+	if err := ensureLoginLockouts(ctx, db); err != nil {
+		problems = append(problems, "login_lockouts: "+err.Error())
+	}
+	if err := ensureRememberTokens(ctx, db); err != nil {
+		problems = append(problems, "remember_tokens: "+err.Error())
+	}
 	if err := ensureFileFolders(ctx, db); err != nil {
 		problems = append(problems, "file_folders: "+err.Error())
 	}
@@ -522,6 +528,53 @@ func ensureRateLimits(ctx context.Context, db *mongo.Database) error {
 	})
 }
 
+func ensureLoginLockouts(ctx context.Context, db *mongo.Database) error {
+	c := db.Collection("login_lockouts")
+	return ensureIndexSet(ctx, c, []mongo.IndexModel{
+		// Unique lockout key (account or client IP)
+		{
+			Keys: bson.D{
+				{Key: "key", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetName("idx_lockout_key"),
+		},
+		// TTL index - remove a key once its count and lock have both expired
+		{
+			Keys: bson.D{
+				{Key: "expires_at", Value: 1},
+			},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("idx_lockout_ttl"),
+		},
+	})
+}
+
+func ensureRememberTokens(ctx context.Context, db *mongo.Database) error {
+	c := db.Collection("remember_tokens")
+	return ensureIndexSet(ctx, c, []mongo.IndexModel{
+		// Unique selector - looked up from the remember-me cookie
+		{
+			Keys: bson.D{
+				{Key: "selector", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetName("idx_remember_selector"),
+		},
+		// All tokens for a user (revoked on password reset)
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().SetName("idx_remember_user"),
+		},
+		// TTL index - remove tokens once they expire
+		{
+			Keys: bson.D{
+				{Key: "expires_at", Value: 1},
+			},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("idx_remember_ttl"),
+		},
+	})
+}
+
 func ensureFileFolders(ctx context.Context, db *mongo.Database) error {
 	c := db.Collection("file_folders")
 	return ensureIndexSet(ctx, c, []mongo.IndexModel{
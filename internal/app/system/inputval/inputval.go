@@ -17,14 +17,20 @@
 //	    Email:    r.FormValue("email"),
 //	}
 //
-//	if err := inputval.Validate(input); err != nil {
+//	if err := inputval.Validate(input); err.HasErrors() {
 //	    // err.First() gives the first error message for display
 //	    renderWithError(w, r, err.First())
 //	    return
 //	}
+//
+// Validate stops at the first invalid field. For JSON APIs that report
+// every field at once, use a Validator, which also accepts errors from the
+// handler's own checks, and pass FieldErrors to BadRequestWithDetails.
+// RegisterRule adds application-specific rules to both.
 package inputval
 
 import (
+	"errors"
 	"net/mail"
 	"net/url"
 	"reflect"
@@ -74,41 +80,89 @@ func (r *Result) All() string {
 }
 
 // customValidator is a singleton validator with custom rules registered.
+// It stops at the first error, for forms that show one message at a time.
+// allValidator reports every error, for Validator.
 var (
 	customValidator *validate.Validator
+	allValidator    *validate.Validator
 	validatorOnce   sync.Once
 )
 
+// customMessages holds the message functions of rules added with
+// RegisterRule, keyed by rule name.
+var (
+	customMessagesMu sync.RWMutex
+	customMessages   = map[string]func(label, param string) string{}
+)
+
 // getValidator returns the singleton validator with custom rules.
 func getValidator() *validate.Validator {
+	initValidators()
+	return customValidator
+}
+
+// initValidators builds both singleton validators with the custom rules.
+func initValidators() {
 	validatorOnce.Do(func() {
 		customValidator = validate.New(validate.WithStopOnFirstError())
+		allValidator = validate.New()
+
+		for _, v := range []*validate.Validator{customValidator, allValidator} {
+			// authmethod: validates against AllowedAuthMethods
+			v.RegisterRuleFunc("authmethod", func(value any) bool {
+				if s, ok := value.(string); ok {
+					return IsValidAuthMethod(s)
+				}
+				return false
+			}, "authmethod")
+
+			// httpurl: validates that string is a valid http/https URL
+			v.RegisterRuleFunc("httpurl", func(value any) bool {
+				if s, ok := value.(string); ok {
+					return IsValidHTTPURL(s)
+				}
+				return false
+			}, "httpurl")
+
+			// objectid: validates that string is a valid MongoDB ObjectID hex
+			v.RegisterRuleFunc("objectid", func(value any) bool {
+				if s, ok := value.(string); ok {
+					return IsValidObjectID(s)
+				}
+				return false
+			}, "objectid")
+		}
+	})
+}
 
-		// authmethod: validates against AllowedAuthMethods
-		customValidator.RegisterRuleFunc("authmethod", func(value any) bool {
-			if s, ok := value.(string); ok {
-				return IsValidAuthMethod(s)
-			}
-			return false
-		}, "authmethod")
-
-		// httpurl: validates that string is a valid http/https URL
-		customValidator.RegisterRuleFunc("httpurl", func(value any) bool {
-			if s, ok := value.(string); ok {
-				return IsValidHTTPURL(s)
-			}
-			return false
-		}, "httpurl")
+// RegisterRule adds a validation rule usable in validate tags by both
+// Validate and Validator. valid receives the field value and the rule's
+// parameter (the part after "=", or ""); message builds the error text
+// from the field's label and the parameter. Register rules at startup,
+// before validating.
+//
+// Example:
+//
+//	inputval.RegisterRule("slug", func(value any, _ string) bool {
+//	    s, _ := value.(string)
+//	    return slugPattern.MatchString(s)
+//	}, func(label, _ string) string {
+//	    return label + " may only contain lowercase letters, digits, and hyphens."
+//	})
+func RegisterRule(name string, valid func(value any, param string) bool, message func(label, param string) string) {
+	initValidators()
+	fn := func(value any, param string, _ reflect.Value) string {
+		if valid(value, param) {
+			return ""
+		}
+		return name
+	}
+	customValidator.RegisterRule(name, fn)
+	allValidator.RegisterRule(name, fn)
 
-		// objectid: validates that string is a valid MongoDB ObjectID hex
-		customValidator.RegisterRuleFunc("objectid", func(value any) bool {
-			if s, ok := value.(string); ok {
-				return IsValidObjectID(s)
-			}
-			return false
-		}, "objectid")
-	})
-	return customValidator
+	customMessagesMu.Lock()
+	customMessages[name] = message
+	customMessagesMu.Unlock()
 }
 
 // Validate validates a struct and returns a Result with user-friendly errors.
@@ -137,34 +191,122 @@ func getValidator() *validate.Validator {
 //	    Auth   string `validate:"required,authmethod" label:"Auth method"`
 //	}
 func Validate(s any) *Result {
-	result := &Result{}
+	return &Result{Errors: fieldErrors(s, getValidator().Struct(s))}
+}
 
-	v := getValidator()
-	err := v.Struct(s)
-	if err == nil {
-		return result
+// fieldErrors converts a pantry validation error for s into FieldErrors
+// with labels and user-friendly messages.
+func fieldErrors(s any, err error) []FieldError {
+	errs, ok := err.(validate.Errors)
+	if !ok {
+		return nil
 	}
 
 	// Get field labels from struct tags
 	labels := getFieldLabels(s)
 
-	if errs, ok := err.(validate.Errors); ok {
-		for _, e := range errs {
-			label := labels[e.Field]
-			if label == "" {
-				label = e.Field
-			}
+	out := make([]FieldError, 0, len(errs))
+	for _, e := range errs {
+		label := labels[e.Field]
+		if label == "" {
+			label = e.Field
+		}
 
-			msg := formatMessage(label, e.Rule, e.Param)
-			result.Errors = append(result.Errors, FieldError{
-				Field:   e.Field,
-				Label:   label,
-				Message: msg,
-			})
+		out = append(out, FieldError{
+			Field:   e.Field,
+			Label:   label,
+			Message: formatMessage(label, e.Rule, e.Param),
+		})
+	}
+	return out
+}
+
+// Validator collects errors for every invalid field, from struct tags and
+// from checks the handler makes itself, such as uniqueness lookups or
+// comparisons between fields. Each field keeps only its first error. The
+// zero value is ready to use.
+//
+// Example:
+//
+//	var v inputval.Validator
+//	if input.Password != input.Confirm {
+//	    v.AddError("confirm", "Passwords do not match.")
+//	}
+//	if err := v.Validate(input); err != nil {
+//	    errorsHandler.BadRequestWithDetails(w, r, inputval.FieldErrors(err))
+//	    return
+//	}
+type Validator struct {
+	errs []FieldError
+}
+
+// AddError records message for field, unless field already has an error.
+func (v *Validator) AddError(field, message string) {
+	v.add(FieldError{Field: field, Label: field, Message: message})
+}
+
+// Validate checks s against its validate tags, adds an error for each
+// invalid field, and returns Err.
+func (v *Validator) Validate(s any) error {
+	initValidators()
+	for _, e := range fieldErrors(s, allValidator.Struct(s)) {
+		v.add(e)
+	}
+	return v.Err()
+}
+
+// Err returns a *ValidationError holding the recorded errors, or nil if
+// there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: append([]FieldError(nil), v.errs...)}
+}
+
+// add records e unless its field already has an error.
+func (v *Validator) add(e FieldError) {
+	for _, existing := range v.errs {
+		if existing.Field == e.Field {
+			return
 		}
 	}
+	v.errs = append(v.errs, e)
+}
+
+// ValidationError is returned by Validator when one or more fields are
+// invalid.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error joins the field messages with "; ".
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
 
-	return result
+// FieldErrors returns the messages keyed by field name, in the shape the
+// errors feature's BadRequestWithDetails expects.
+func (e *ValidationError) FieldErrors() map[string]string {
+	m := make(map[string]string, len(e.Errors))
+	for _, fe := range e.Errors {
+		m[fe.Field] = fe.Message
+	}
+	return m
+}
+
+// FieldErrors returns the per-field messages of a *ValidationError in err's
+// chain, or nil if there is none.
+func FieldErrors(err error) map[string]string {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.FieldErrors()
+	}
+	return nil
 }
 
 // getFieldLabels extracts the "label" tag from struct fields.
@@ -203,6 +345,13 @@ func getFieldLabels(s any) map[string]string {
 
 // formatMessage creates a user-friendly message for a validation rule.
 func formatMessage(label, rule, param string) string {
+	customMessagesMu.RLock()
+	custom := customMessages[rule]
+	customMessagesMu.RUnlock()
+	if custom != nil {
+		return custom(label, param)
+	}
+
 	switch rule {
 	case "required":
 		return label + " is required."
@@ -1,6 +1,9 @@
 package inputval
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -394,3 +397,88 @@ func TestValidate_NoLabel(t *testing.T) {
 		t.Errorf("Validate() error message = %q, want field name message", result.First())
 	}
 }
+
+func TestValidator_Validate(t *testing.T) {
+	type SignupInput struct {
+		Name    string `json:"name" validate:"required,max=5" label:"Name"`
+		Email   string `json:"email" validate:"required,email" label:"Email"`
+		Website string `json:"website" validate:"omitempty,httpurl" label:"Website"`
+	}
+
+	var v Validator
+	v.AddError("email", "Email is already registered.")
+	err := v.Validate(SignupInput{Name: "", Email: "bad", Website: "ftp://x"})
+	if err == nil {
+		t.Fatal("Validate() error = nil, want errors")
+	}
+
+	got := FieldErrors(err)
+	want := map[string]string{
+		"name":    "Name is required.",
+		"email":   "Email is already registered.",
+		"website": "Website must be a valid URL starting with http:// or https://.",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FieldErrors() = %v, want %v", got, want)
+	}
+	for field, msg := range want {
+		if got[field] != msg {
+			t.Errorf("FieldErrors()[%q] = %q, want %q", field, got[field], msg)
+		}
+	}
+	if !strings.Contains(err.Error(), "Name is required.") {
+		t.Errorf("Error() = %q, want field messages", err.Error())
+	}
+}
+
+func TestValidator_Valid(t *testing.T) {
+	type Input struct {
+		Name string `validate:"required"`
+	}
+	var v Validator
+	if err := v.Validate(Input{Name: "Ada"}); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	if FieldErrors(nil) != nil {
+		t.Error("FieldErrors(nil) should be nil")
+	}
+}
+
+func TestValidator_Wrapped(t *testing.T) {
+	var v Validator
+	v.AddError("title", "Title is taken.")
+	err := fmt.Errorf("create: %w", v.Err())
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatal("errors.As() should find *ValidationError")
+	}
+	if got := FieldErrors(err)["title"]; got != "Title is taken." {
+		t.Errorf("FieldErrors()[title] = %q", got)
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule("testprefix", func(value any, param string) bool {
+		s, _ := value.(string)
+		return strings.HasPrefix(s, param)
+	}, func(label, param string) string {
+		return label + " must start with " + param + "."
+	})
+
+	type Input struct {
+		Code string `json:"code" validate:"testprefix=SF-" label:"Code"`
+	}
+
+	if r := Validate(Input{Code: "SF-1"}); r.HasErrors() {
+		t.Errorf("Validate() valid code got: %s", r.First())
+	}
+	if r := Validate(Input{Code: "X-1"}); r.First() != "Code must start with SF-." {
+		t.Errorf("Validate() First() = %q", r.First())
+	}
+
+	var v Validator
+	if got := FieldErrors(v.Validate(Input{Code: "X-1"}))["code"]; got != "Code must start with SF-." {
+		t.Errorf("Validator FieldErrors()[code] = %q", got)
+	}
+}
@@ -0,0 +1,214 @@
+// internal/app/system/jobqueue/queue.go
+//
+// Package jobqueue runs short jobs in the background on an in-memory
+// worker pool, so handlers can respond first and send email or process
+// uploads afterwards.
+//
+// Jobs are lost if the process exits before they run. Work that must
+// survive a restart belongs in the persistent jobrunner instead; scheduled
+// maintenance belongs in tasks.
+//
+// Example:
+//
+//	err := queue.Enqueue(jobqueue.Job{
+//	    Name: "welcome-email",
+//	    Run: func(ctx context.Context) error {
+//	        return mailer.Send(ctx, msg)
+//	    },
+//	})
+//	if err != nil {
+//	    logger.Warn("could not queue welcome email", zap.Error(err))
+//	}
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrQueueFull is returned by Enqueue when every slot is taken.
+	ErrQueueFull = errors.New("jobqueue: queue is full")
+	// ErrStopped is returned by Enqueue once Stop has been called.
+	ErrStopped = errors.New("jobqueue: queue is stopped")
+)
+
+// Job is a unit of background work.
+type Job struct {
+	// Name identifies the job in logs.
+	Name string
+	// Timeout bounds the job's context. Zero uses Config.JobTimeout.
+	Timeout time.Duration
+	// Run does the work. It should return promptly once ctx is done.
+	Run func(ctx context.Context) error
+}
+
+// Config holds configuration for the queue.
+type Config struct {
+	// Workers is the number of jobs that run at once.
+	Workers int
+
+	// QueueSize is how many jobs may wait for a worker before Enqueue
+	// returns ErrQueueFull.
+	QueueSize int
+
+	// JobTimeout is the default deadline for each job.
+	JobTimeout time.Duration
+}
+
+// DefaultConfig returns a Config with sensible defaults.
+func DefaultConfig() Config {
+	return Config{
+		Workers:    4,
+		QueueSize:  256,
+		JobTimeout: time.Minute,
+	}
+}
+
+// Queue is an in-memory worker pool. It is safe for concurrent use.
+type Queue struct {
+	config Config
+	logger *zap.Logger
+	jobs   chan Job
+
+	// ctx is the parent of every job context; it is cancelled only when
+	// Stop gives up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// New creates a queue and starts its workers. Zero config fields take the
+// DefaultConfig values.
+func New(config Config, logger *zap.Logger) *Queue {
+	def := DefaultConfig()
+	if config.Workers <= 0 {
+		config.Workers = def.Workers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = def.QueueSize
+	}
+	if config.JobTimeout <= 0 {
+		config.JobTimeout = def.JobTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		config: config,
+		logger: logger,
+		jobs:   make(chan Job, config.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := 0; i < config.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	return q
+}
+
+// Enqueue schedules job without blocking. It returns ErrQueueFull when the
+// queue is at capacity and ErrStopped after Stop.
+func (q *Queue) Enqueue(job Job) error {
+	if job.Run == nil {
+		return fmt.Errorf("jobqueue: job %q has no Run function", job.Name)
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.stopped {
+		return ErrStopped
+	}
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Len returns the number of jobs waiting for a worker.
+func (q *Queue) Len() int {
+	return len(q.jobs)
+}
+
+// Stop stops accepting jobs and waits for queued and running jobs to
+// finish. If ctx ends first, the contexts of running jobs are cancelled,
+// jobs still waiting are dropped, and ctx.Err() is returned.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.stopped {
+		q.stopped = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		q.logger.Info("job queue drained")
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		q.logger.Warn("job queue stop timed out; cancelling running jobs",
+			zap.Int("dropped_count", len(q.jobs)))
+		return ctx.Err()
+	}
+}
+
+// worker runs jobs until the queue is closed, or until Stop gives up.
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for job := range q.jobs {
+		if q.ctx.Err() != nil {
+			continue // Stop timed out; drop what is left
+		}
+		q.run(job)
+	}
+}
+
+// run executes one job with its timeout, recovering from panics.
+func (q *Queue) run(job Job) {
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = q.config.JobTimeout
+	}
+	ctx, cancel := context.WithTimeout(q.ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			q.logger.Error("job panicked",
+				zap.String("job", job.Name),
+				zap.Any("panic", p),
+				zap.Duration("duration", time.Since(start)),
+				zap.Stack("stack"))
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		q.logger.Error("job failed",
+			zap.String("job", job.Name),
+			zap.Duration("duration", time.Since(start)),
+			zap.Error(err))
+		return
+	}
+	q.logger.Debug("job completed",
+		zap.String("job", job.Name),
+		zap.Duration("duration", time.Since(start)))
+}
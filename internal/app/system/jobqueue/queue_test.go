@@ -0,0 +1,143 @@
+package jobqueue_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/jobqueue"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestQueue_RunsJobs(t *testing.T) {
+	q := jobqueue.New(jobqueue.Config{Workers: 2}, zap.NewNop())
+
+	var ran atomic.Int32
+	for i := 0; i < 10; i++ {
+		err := q.Enqueue(jobqueue.Job{Name: "count", Run: func(ctx context.Context) error {
+			ran.Add(1)
+			return nil
+		}})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if ran.Load() != 10 {
+		t.Errorf("ran %d jobs, want 10 (Stop should drain the queue)", ran.Load())
+	}
+}
+
+func TestQueue_EnqueueErrors(t *testing.T) {
+	block := make(chan struct{})
+	q := jobqueue.New(jobqueue.Config{Workers: 1, QueueSize: 1}, zap.NewNop())
+	wait := jobqueue.Job{Name: "wait", Run: func(ctx context.Context) error {
+		<-block
+		return nil
+	}}
+
+	if err := q.Enqueue(jobqueue.Job{Name: "no-run"}); err == nil {
+		t.Error("Enqueue() of a job without Run should fail")
+	}
+
+	// One job running, one waiting; the next does not fit.
+	q.Enqueue(wait)
+	time.Sleep(20 * time.Millisecond)
+	q.Enqueue(wait)
+	if err := q.Enqueue(wait); !errors.Is(err, jobqueue.ErrQueueFull) {
+		t.Errorf("Enqueue() on a full queue error = %v, want ErrQueueFull", err)
+	}
+
+	close(block)
+	q.Stop(context.Background())
+	if err := q.Enqueue(wait); !errors.Is(err, jobqueue.ErrStopped) {
+		t.Errorf("Enqueue() after Stop error = %v, want ErrStopped", err)
+	}
+}
+
+func TestQueue_JobTimeout(t *testing.T) {
+	q := jobqueue.New(jobqueue.Config{Workers: 1, JobTimeout: time.Hour}, zap.NewNop())
+
+	got := make(chan error, 1)
+	q.Enqueue(jobqueue.Job{Name: "slow", Timeout: 20 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		got <- ctx.Err()
+		return ctx.Err()
+	}})
+
+	select {
+	case err := <-got:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("job context error = %v, want DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job context did not time out")
+	}
+	q.Stop(context.Background())
+}
+
+func TestQueue_RecoversPanics(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	q := jobqueue.New(jobqueue.Config{Workers: 1}, zap.New(core))
+
+	var after atomic.Bool
+	q.Enqueue(jobqueue.Job{Name: "boom", Run: func(ctx context.Context) error {
+		panic("boom")
+	}})
+	q.Enqueue(jobqueue.Job{Name: "after", Run: func(ctx context.Context) error {
+		after.Store(true)
+		return nil
+	}})
+	q.Stop(context.Background())
+
+	if !after.Load() {
+		t.Error("worker did not survive the panic")
+	}
+	entries := logs.FilterMessage("job panicked").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d panics, want 1", len(entries))
+	}
+	if job := entries[0].ContextMap()["job"]; job != "boom" {
+		t.Errorf("panic log job = %v, want boom", job)
+	}
+}
+
+func TestQueue_StopTimeoutCancelsJobs(t *testing.T) {
+	q := jobqueue.New(jobqueue.Config{Workers: 1}, zap.NewNop())
+
+	cancelled := make(chan struct{})
+	q.Enqueue(jobqueue.Job{Name: "stuck", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}})
+	var dropped atomic.Bool
+	q.Enqueue(jobqueue.Job{Name: "dropped", Run: func(ctx context.Context) error {
+		dropped.Store(true)
+		return nil
+	}})
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want DeadlineExceeded", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("running job was not cancelled")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if dropped.Load() {
+		t.Error("waiting job ran after Stop timed out")
+	}
+}
@@ -0,0 +1,686 @@
+// internal/app/system/jsonschema/jsonschema.go
+//
+// Package jsonschema validates JSON request bodies against a JSON Schema
+// before they reach the handler.
+//
+// Validate compiles a schema once, at setup, and returns middleware that
+// reads the body, checks it, and either answers 400 with one detail per
+// offending field (through the errors feature's BadRequestWithDetails when
+// WithErrorHandler is used) or passes the request on with the body intact,
+// so the handler decodes it as usual.
+//
+// Schemas are usually kept next to the feature and embedded:
+//
+//	//go:embed schemas/*.json
+//	var schemas embed.FS
+//
+//	r.With(jsonschema.ValidateFS(schemas, "schemas/create_user.json",
+//		jsonschema.WithErrorHandler(errorsHandler),
+//	)).Post("/api/users", h.create)
+//
+// A practical subset of JSON Schema is supported: type, enum, const,
+// properties, required, additionalProperties, items, minItems, maxItems,
+// minLength, maxLength, pattern, format ("email", "date-time", "date",
+// "uuid"), minimum, maximum, exclusiveMinimum, and exclusiveMaximum.
+// Annotations such as title and description are ignored. Other keywords,
+// such as $ref or oneOf, are rejected when the schema is compiled, so a
+// schema never appears to validate something it does not.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"mime"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dalemusser/strataforge/internal/app/system/jsonutil"
+)
+
+// DefaultMaxBytes is the body limit used unless WithMaxBytes sets another.
+const DefaultMaxBytes = jsonutil.DefaultMaxBytes
+
+// BodyField is the detail key for problems with the body as a whole, such
+// as malformed JSON or a top-level value of the wrong type.
+const BodyField = "body"
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	types         []string
+	enum          []any
+	constVal      *any
+	properties    map[string]*Schema
+	required      []string
+	additional    *Schema // nil: any extra property is allowed
+	noAdditional  bool    // additionalProperties: false
+	items         *Schema
+	minItems      *int
+	maxItems      *int
+	minLength     *int
+	maxLength     *int
+	pattern       *regexp.Regexp
+	format        string
+	minimum       *big.Rat
+	maximum       *big.Rat
+	exclusiveMin  *big.Rat
+	exclusiveMax  *big.Rat
+	patternSource string
+}
+
+// Violation is one way a document fails a schema. Path is the offending
+// field, written as dotted keys with [i] for array elements
+// ("address.zip", "tags[2]"); it is BodyField for the document itself.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+// annotations are keywords with no effect on validation.
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+	"readOnly": true, "writeOnly": true, "deprecated": true,
+}
+
+// Compile parses schema. It returns an error for invalid JSON, malformed
+// keyword values, and keywords outside the supported subset.
+func Compile(schema []byte) (*Schema, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(schema))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	s, err := compile(doc, "#")
+	if err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	return s, nil
+}
+
+// MustCompile is Compile for schemas known at startup; it panics on error.
+func MustCompile(schema []byte) *Schema {
+	s, err := Compile(schema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Load reads and compiles the schema at name in fsys, typically an
+// embed.FS.
+func Load(fsys fs.FS, name string) (*Schema, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	s, err := Compile(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w (in %s)", err, name)
+	}
+	return s, nil
+}
+
+func compile(v any, at string) (*Schema, error) {
+	if b, ok := v.(bool); ok {
+		// true accepts anything; false accepts nothing.
+		if b {
+			return &Schema{}, nil
+		}
+		return &Schema{enum: []any{}}, nil
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or boolean", at)
+	}
+
+	s := &Schema{}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		val := m[k]
+		where := at + "/" + k
+		var err error
+		switch k {
+		case "type":
+			s.types, err = compileTypes(val, where)
+		case "enum":
+			arr, ok := val.([]any)
+			if !ok {
+				err = fmt.Errorf("%s: must be an array", where)
+			}
+			s.enum = arr
+		case "const":
+			c := val
+			s.constVal = &c
+		case "properties":
+			props, ok := val.(map[string]any)
+			if !ok {
+				err = fmt.Errorf("%s: must be an object", where)
+				break
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, ps := range props {
+				if s.properties[name], err = compile(ps, where+"/"+name); err != nil {
+					break
+				}
+			}
+		case "required":
+			arr, ok := val.([]any)
+			if !ok {
+				err = fmt.Errorf("%s: must be an array of strings", where)
+				break
+			}
+			for _, item := range arr {
+				name, ok := item.(string)
+				if !ok {
+					err = fmt.Errorf("%s: must be an array of strings", where)
+					break
+				}
+				s.required = append(s.required, name)
+			}
+		case "additionalProperties":
+			if b, ok := val.(bool); ok {
+				s.noAdditional = !b
+				break
+			}
+			s.additional, err = compile(val, where)
+		case "items":
+			s.items, err = compile(val, where)
+		case "minItems":
+			s.minItems, err = compileCount(val, where)
+		case "maxItems":
+			s.maxItems, err = compileCount(val, where)
+		case "minLength":
+			s.minLength, err = compileCount(val, where)
+		case "maxLength":
+			s.maxLength, err = compileCount(val, where)
+		case "pattern":
+			src, ok := val.(string)
+			if !ok {
+				err = fmt.Errorf("%s: must be a string", where)
+				break
+			}
+			if s.pattern, err = regexp.Compile(src); err != nil {
+				err = fmt.Errorf("%s: %w", where, err)
+			}
+			s.patternSource = src
+		case "format":
+			f, ok := val.(string)
+			if !ok {
+				err = fmt.Errorf("%s: must be a string", where)
+				break
+			}
+			if _, known := formats[f]; !known {
+				err = fmt.Errorf("%s: unsupported format %q", where, f)
+			}
+			s.format = f
+		case "minimum":
+			s.minimum, err = compileNumber(val, where)
+		case "maximum":
+			s.maximum, err = compileNumber(val, where)
+		case "exclusiveMinimum":
+			s.exclusiveMin, err = compileNumber(val, where)
+		case "exclusiveMaximum":
+			s.exclusiveMax, err = compileNumber(val, where)
+		default:
+			if !annotations[k] {
+				err = fmt.Errorf("%s: unsupported keyword", where)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// jsonTypes are the type names JSON Schema defines.
+var jsonTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+func compileTypes(v any, at string) ([]string, error) {
+	var names []any
+	switch t := v.(type) {
+	case string:
+		names = []any{t}
+	case []any:
+		names = t
+	default:
+		return nil, fmt.Errorf("%s: must be a string or array of strings", at)
+	}
+	types := make([]string, 0, len(names))
+	for _, n := range names {
+		name, ok := n.(string)
+		if !ok || !jsonTypes[name] {
+			return nil, fmt.Errorf("%s: unknown type %v", at, n)
+		}
+		types = append(types, name)
+	}
+	return types, nil
+}
+
+func compileCount(v any, at string) (*int, error) {
+	n, ok := v.(json.Number)
+	if ok {
+		if i, err := strconv.Atoi(n.String()); err == nil && i >= 0 {
+			return &i, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+}
+
+func compileNumber(v any, at string) (*big.Rat, error) {
+	if n, ok := v.(json.Number); ok {
+		if r, ok := new(big.Rat).SetString(n.String()); ok {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: must be a number", at)
+}
+
+// formats checks the supported "format" values.
+var formats = map[string]func(string) bool{
+	"email": func(s string) bool {
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	},
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	},
+	"uuid": regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+}
+
+// ValidateBytes checks the JSON document doc against s. Malformed JSON is
+// reported as a single BodyField violation.
+func (s *Schema) ValidateBytes(doc []byte) []Violation {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []Violation{{Path: BodyField, Message: "body must be valid JSON"}}
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return []Violation{{Path: BodyField, Message: "body must contain a single JSON value"}}
+	}
+	return s.Validate(v)
+}
+
+// Validate checks v, a value decoded with json.Decoder.UseNumber, against s
+// and returns every violation found, in a stable order.
+func (s *Schema) Validate(v any) []Violation {
+	var out []Violation
+	s.validate(v, "", &out)
+	return out
+}
+
+func (s *Schema) validate(v any, path string, out *[]Violation) {
+	add := func(format string, args ...any) {
+		p := path
+		if p == "" {
+			p = BodyField
+		}
+		*out = append(*out, Violation{Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !matchesType(v, s.types) {
+		add("must be %s", typeList(s.types))
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, v) {
+		if len(s.enum) == 0 {
+			add("is not allowed")
+		} else {
+			add("must be one of: %s", valueList(s.enum))
+		}
+	}
+	if s.constVal != nil && !equalValues(*s.constVal, v) {
+		add("must be %s", valueList([]any{*s.constVal}))
+	}
+
+	switch x := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(x)
+		if s.minLength != nil && n < *s.minLength {
+			add("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			add("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(x) {
+			add("must match pattern %s", s.patternSource)
+		}
+		if s.format != "" && !formats[s.format](x) {
+			add("must be a valid %s", s.format)
+		}
+	case json.Number:
+		r, ok := new(big.Rat).SetString(x.String())
+		if !ok {
+			break
+		}
+		if s.minimum != nil && r.Cmp(s.minimum) < 0 {
+			add("must be at least %s", s.minimum.RatString())
+		}
+		if s.maximum != nil && r.Cmp(s.maximum) > 0 {
+			add("must be at most %s", s.maximum.RatString())
+		}
+		if s.exclusiveMin != nil && r.Cmp(s.exclusiveMin) <= 0 {
+			add("must be greater than %s", s.exclusiveMin.RatString())
+		}
+		if s.exclusiveMax != nil && r.Cmp(s.exclusiveMax) >= 0 {
+			add("must be less than %s", s.exclusiveMax.RatString())
+		}
+	case []any:
+		if s.minItems != nil && len(x) < *s.minItems {
+			add("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(x) > *s.maxItems {
+			add("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range x {
+				s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), out)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := x[name]; !ok {
+				*out = append(*out, Violation{Path: join(path, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(x))
+		for name := range x {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if ps, ok := s.properties[name]; ok {
+				ps.validate(x[name], join(path, name), out)
+				continue
+			}
+			switch {
+			case s.noAdditional:
+				*out = append(*out, Violation{Path: join(path, name), Message: "is not an allowed field"})
+			case s.additional != nil:
+				s.additional.validate(x[name], join(path, name), out)
+			}
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func matchesType(v any, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]any); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]any); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(json.Number); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := v.(json.Number); ok {
+				if r, ok := new(big.Rat).SetString(n.String()); ok && r.IsInt() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// typeList names types for a message: "a string", "a string or null".
+func typeList(types []string) string {
+	parts := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "null":
+			parts[i] = "null"
+		case "array", "integer", "object":
+			parts[i] = "an " + t
+		default:
+			parts[i] = "a " + t
+		}
+	}
+	return strings.Join(parts, " or ")
+}
+
+func valueList(vals []any) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		b, _ := json.Marshal(v)
+		parts[i] = string(b)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func containsValue(vals []any, v any) bool {
+	for _, candidate := range vals {
+		if equalValues(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares decoded JSON values, numbers by value (1 == 1.0).
+func equalValues(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		rx, okx := new(big.Rat).SetString(x.String())
+		ry, oky := new(big.Rat).SetString(y.String())
+		return okx && oky && rx.Cmp(ry) == 0
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalValues(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, xv := range x {
+			yv, ok := y[k]
+			if !ok || !equalValues(xv, yv) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+/*─────────────────────────────────────────────────────────────────────────────*
+| Middleware                                                                  |
+*─────────────────────────────────────────────────────────────────────────────*/
+
+// ErrorHandler renders validation failures. The errors feature's Handler
+// satisfies it.
+type ErrorHandler interface {
+	Error(w http.ResponseWriter, r *http.Request, status int)
+	BadRequestWithDetails(w http.ResponseWriter, r *http.Request, details map[string]string)
+}
+
+// config holds the settings built up by Options.
+type config struct {
+	errors   ErrorHandler
+	maxBytes int64
+}
+
+// Option configures the validation middleware.
+type Option func(*config)
+
+// WithErrorHandler renders failures with h: violations through
+// BadRequestWithDetails, an oversized body as 413, and a non-JSON
+// Content-Type as 415. Without it, failures are written with jsonutil.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(c *config) {
+		c.errors = h
+	}
+}
+
+// WithMaxBytes limits the body to n bytes (default DefaultMaxBytes).
+func WithMaxBytes(n int64) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxBytes = n
+		}
+	}
+}
+
+// Validate returns middleware that validates request bodies against
+// schema. It panics if schema does not compile, so a bad schema fails
+// startup rather than the first request.
+func Validate(schema []byte, opts ...Option) func(http.Handler) http.Handler {
+	return MustCompile(schema).Middleware(opts...)
+}
+
+// ValidateFS is Validate for the schema at name in fsys, typically an
+// embed.FS. It panics if the file cannot be read or does not compile.
+func ValidateFS(fsys fs.FS, name string, opts ...Option) func(http.Handler) http.Handler {
+	s, err := Load(fsys, name)
+	if err != nil {
+		panic(err)
+	}
+	return s.Middleware(opts...)
+}
+
+// Middleware returns middleware that validates request bodies against s.
+// Requests that pass reach next with the body unread.
+func (s *Schema) Middleware(opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{maxBytes: DefaultMaxBytes}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isJSON(r.Header.Get("Content-Type")) {
+				cfg.fail(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.maxBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					cfg.fail(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("body must not be larger than %d bytes", cfg.maxBytes))
+					return
+				}
+				cfg.fail(w, r, http.StatusBadRequest, "body could not be read")
+				return
+			}
+
+			if violations := s.ValidateBytes(body); len(violations) > 0 {
+				cfg.invalid(w, r, Details(violations))
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Details turns violations into the field-to-message map that
+// BadRequestWithDetails takes. Several violations of one field are joined
+// with "; ".
+func Details(violations []Violation) map[string]string {
+	details := make(map[string]string, len(violations))
+	for _, v := range violations {
+		if prev, ok := details[v.Path]; ok {
+			details[v.Path] = prev + "; " + v.Message
+			continue
+		}
+		details[v.Path] = v.Message
+	}
+	return details
+}
+
+func (c *config) invalid(w http.ResponseWriter, r *http.Request, details map[string]string) {
+	if c.errors != nil {
+		c.errors.BadRequestWithDetails(w, r, details)
+		return
+	}
+	jsonutil.ValidationError(w, details)
+}
+
+func (c *config) fail(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if status == http.StatusBadRequest {
+		c.invalid(w, r, map[string]string{BodyField: msg})
+		return
+	}
+	if c.errors != nil {
+		c.errors.Error(w, r, status)
+		return
+	}
+	jsonutil.Error(w, status, msg)
+}
+
+// isJSON reports whether ct is application/json or a structured +json
+// type, with any parameters.
+func isJSON(ct string) bool {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,205 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	errorsfeature "github.com/dalemusser/strataforge/internal/app/features/errors"
+)
+
+var _ ErrorHandler = (*errorsfeature.Handler)(nil)
+
+func loadUserSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, err := Load(os.DirFS("testdata"), "user.json")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return s
+}
+
+func TestValidateBytes(t *testing.T) {
+	s := loadUserSchema(t)
+
+	tests := []struct {
+		name string
+		doc  string
+		want map[string]string
+	}{
+		{"valid", `{"email":"a@example.com","role":"admin","age":30,"tags":["go"]}`, nil},
+		{"missing required", `{"name":"Ann"}`, map[string]string{
+			"email": "is required",
+			"role":  "is required",
+		}},
+		{"wrong types", `{"email":5,"role":"admin","age":1.5}`, map[string]string{
+			"email": "must be a string",
+			"age":   "must be an integer",
+		}},
+		{"enum", `{"email":"a@example.com","role":"owner"}`, map[string]string{
+			"role": `must be one of: "admin", "member"`,
+		}},
+		{"format and length", `{"email":"nope","role":"member","name":""}`, map[string]string{
+			"email": "must be a valid email",
+			"name":  "must be at least 1 characters",
+		}},
+		{"bounds", `{"email":"a@example.com","role":"member","age":150}`, map[string]string{
+			"age": "must be less than 150",
+		}},
+		{"array items", `{"email":"a@example.com","role":"member","tags":["ok","Bad",3]}`, map[string]string{
+			"tags[1]": "must match pattern ^[a-z]+$",
+			"tags[2]": "must be a string",
+		}},
+		{"unknown field", `{"email":"a@example.com","role":"member","admin":true}`, map[string]string{
+			"admin": "is not an allowed field",
+		}},
+		{"not an object", `[1,2]`, map[string]string{BodyField: "must be an object"}},
+		{"malformed", `{"email":`, map[string]string{BodyField: "body must be valid JSON"}},
+		{"trailing data", `{} {}`, map[string]string{BodyField: "body must contain a single JSON value"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Details(s.ValidateBytes([]byte(tt.doc)))
+			if len(got) != len(tt.want) {
+				t.Fatalf("details = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("details[%q] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestDetails_JoinsSamePath(t *testing.T) {
+	got := Details([]Violation{
+		{Path: "name", Message: "must be at least 3 characters"},
+		{Path: "name", Message: "must match pattern ^x"},
+	})
+	if want := "must be at least 3 characters; must match pattern ^x"; got["name"] != want {
+		t.Errorf("details[name] = %q, want %q", got["name"], want)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, schema := range []string{
+		`not json`,
+		`"string"`,
+		`{"type":"str"}`,
+		`{"$ref":"#/defs/x"}`,
+		`{"oneOf":[{"type":"string"}]}`,
+		`{"minLength":-1}`,
+		`{"pattern":"("}`,
+		`{"format":"hostname"}`,
+		`{"properties":{"a":{"type":7}}}`,
+	} {
+		if _, err := Compile([]byte(schema)); err == nil {
+			t.Errorf("Compile(%s) error = nil, want an error", schema)
+		}
+	}
+}
+
+func TestValidate_PanicsOnBadSchema(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Validate() did not panic on a bad schema")
+		}
+	}()
+	Validate([]byte(`{"type":"nope"}`))
+}
+
+func TestMiddleware(t *testing.T) {
+	schema := []byte(`{"type":"object","required":["name"],"properties":{"name":{"type":"string"}}}`)
+
+	var gotBody string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := Validate(schema, WithMaxBytes(64))(next)
+
+	tests := []struct {
+		name       string
+		ct         string
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{"valid", "application/json", `{"name":"Ann"}`, http.StatusNoContent, ""},
+		{"valid with charset", "application/json; charset=utf-8", `{"name":"Ann"}`, http.StatusNoContent, ""},
+		{"invalid", "application/json", `{}`, http.StatusBadRequest, "name"},
+		{"malformed", "application/json", `{`, http.StatusBadRequest, BodyField},
+		{"wrong content type", "text/plain", `{"name":"Ann"}`, http.StatusUnsupportedMediaType, ""},
+		{"too large", "application/json", `{"name":"` + strings.Repeat("a", 100) + `"}`, http.StatusRequestEntityTooLarge, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody = ""
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.ct)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusNoContent && gotBody != tt.body {
+				t.Errorf("handler body = %q, want %q", gotBody, tt.body)
+			}
+			if tt.wantField != "" {
+				var resp struct {
+					Fields map[string]string `json:"fields"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if resp.Fields[tt.wantField] == "" {
+					t.Errorf("fields = %v, want an entry for %q", resp.Fields, tt.wantField)
+				}
+			}
+		})
+	}
+}
+
+type recordingErrors struct {
+	status  int
+	details map[string]string
+}
+
+func (e *recordingErrors) Error(w http.ResponseWriter, r *http.Request, status int) {
+	e.status = status
+	w.WriteHeader(status)
+}
+
+func (e *recordingErrors) BadRequestWithDetails(w http.ResponseWriter, r *http.Request, details map[string]string) {
+	e.status = http.StatusBadRequest
+	e.details = details
+	w.WriteHeader(http.StatusBadRequest)
+}
+
+func TestMiddleware_WithErrorHandler(t *testing.T) {
+	eh := &recordingErrors{}
+	h := ValidateFS(os.DirFS("testdata"), "user.json", WithErrorHandler(eh))(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email":"a@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if eh.status != http.StatusBadRequest || eh.details["role"] != "is required" {
+		t.Errorf("status = %d, details = %v; want 400 with role required", eh.status, eh.details)
+	}
+
+	eh = &recordingErrors{}
+	h = ValidateFS(os.DirFS("testdata"), "user.json", WithErrorHandler(eh))(http.NotFoundHandler())
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if eh.status != http.StatusUnsupportedMediaType {
+		t.Errorf("status = %d, want 415 for a missing Content-Type", eh.status)
+	}
+}
@@ -0,0 +1,125 @@
+package jsonutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBytes is the body limit DecodeJSON uses when maxBytes is zero
+// or negative.
+const DefaultMaxBytes int64 = 1 << 20
+
+// Kinds of DecodeJSON failure. A *DecodeError wraps exactly one of them,
+// so callers can pick a response with errors.Is.
+var (
+	// ErrContentType means the request did not declare a JSON body; answer
+	// with 415 Unsupported Media Type or 400.
+	ErrContentType = errors.New("jsonutil: content type is not application/json")
+	// ErrTooLarge means the body exceeded the limit; answer with 413.
+	ErrTooLarge = errors.New("jsonutil: request body too large")
+	// ErrBadJSON means the body was empty, malformed, had unknown fields or
+	// wrongly typed values, or held more than one value; answer with 400.
+	ErrBadJSON = errors.New("jsonutil: invalid JSON body")
+)
+
+// DecodeError describes why DecodeJSON rejected a request body. Its message
+// is safe to show to API clients.
+type DecodeError struct {
+	Kind    error  // ErrContentType, ErrTooLarge, or ErrBadJSON
+	Field   string // offending field, when known
+	Message string
+}
+
+func (e *DecodeError) Error() string { return e.Message }
+
+// Unwrap returns the error kind.
+func (e *DecodeError) Unwrap() error { return e.Kind }
+
+// DecodeJSON decodes a single JSON value from r's body into into. The body
+// must be declared as JSON (application/json or a +json type), is limited
+// to maxBytes through http.MaxBytesReader, and may not contain fields that
+// into does not have. Failures are returned as *DecodeError; passing a
+// non-pointer into is a programming error and panics.
+//
+// Usage:
+//
+//	var input CreateLogInput
+//	if err := jsonutil.DecodeJSON(w, r, &input, 64<<10); err != nil {
+//	    if errors.Is(err, jsonutil.ErrTooLarge) {
+//	        errorsHandler.Error(w, r, http.StatusRequestEntityTooLarge)
+//	        return
+//	    }
+//	    var de *jsonutil.DecodeError
+//	    errors.As(err, &de)
+//	    errorsHandler.BadRequestWithDetails(w, r, map[string]string{"body": de.Message})
+//	    return
+//	}
+func DecodeJSON(w http.ResponseWriter, r *http.Request, into any, maxBytes int64) error {
+	if !isJSONContentType(r.Header.Get("Content-Type")) {
+		return &DecodeError{Kind: ErrContentType, Message: "Content-Type must be application/json"}
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(into); err != nil {
+		return decodeError(err, maxBytes)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return decodeError(err, maxBytes)
+		}
+		return &DecodeError{Kind: ErrBadJSON, Message: "body must contain a single JSON value"}
+	}
+	return nil
+}
+
+// decodeError classifies an error from json.Decoder.Decode.
+func decodeError(err error, maxBytes int64) *DecodeError {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		tooLargeErr *http.MaxBytesError
+		invalidErr  *json.InvalidUnmarshalError
+	)
+	switch {
+	case errors.As(err, &tooLargeErr):
+		return &DecodeError{Kind: ErrTooLarge, Message: fmt.Sprintf("body must not be larger than %d bytes", maxBytes)}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{Kind: ErrBadJSON, Message: "body must not be empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Kind: ErrBadJSON, Message: "body contains malformed JSON"}
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{Kind: ErrBadJSON, Message: fmt.Sprintf("body contains malformed JSON at position %d", syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		return &DecodeError{Kind: ErrBadJSON, Field: typeErr.Field, Message: fmt.Sprintf("field %q must be of type %s", typeErr.Field, typeErr.Type)}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for DisallowUnknownFields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &DecodeError{Kind: ErrBadJSON, Field: field, Message: fmt.Sprintf("unknown field %q", field)}
+	case errors.As(err, &invalidErr):
+		panic(err)
+	default:
+		return &DecodeError{Kind: ErrBadJSON, Message: "body contains invalid JSON"}
+	}
+}
+
+// isJSONContentType reports whether ct is application/json or a
+// structured +json type, with any parameters.
+func isJSONContentType(ct string) bool {
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
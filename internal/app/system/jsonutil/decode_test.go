@@ -0,0 +1,110 @@
+package jsonutil
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type createInput struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func newJSONRequest(body, contentType string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/items", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func TestDecodeJSON(t *testing.T) {
+	var got createInput
+	req := newJSONRequest(`{"name":"Ada","age":36}`, "application/json; charset=utf-8")
+	if err := DecodeJSON(httptest.NewRecorder(), req, &got, 0); err != nil {
+		t.Fatalf("DecodeJSON() error = %v", err)
+	}
+	if got != (createInput{Name: "Ada", Age: 36}) {
+		t.Errorf("DecodeJSON() = %+v", got)
+	}
+
+	req = newJSONRequest(`{"name":"Ada"}`, "application/merge-patch+json")
+	if err := DecodeJSON(httptest.NewRecorder(), req, &got, 0); err != nil {
+		t.Errorf("DecodeJSON() with +json type error = %v", err)
+	}
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		maxBytes    int64
+		wantKind    error
+		wantField   string
+	}{
+		{"missing content type", `{}`, "", 0, ErrContentType, ""},
+		{"form content type", `{}`, "application/x-www-form-urlencoded", 0, ErrContentType, ""},
+		{"too large", `{"name":"` + strings.Repeat("a", 100) + `"}`, "application/json", 32, ErrTooLarge, ""},
+		{"trailing data too large", `{"name":"a"}` + strings.Repeat(" ", 100) + `{}`, "application/json", 32, ErrTooLarge, ""},
+		{"empty", ``, "application/json", 0, ErrBadJSON, ""},
+		{"syntax", `{"name":}`, "application/json", 0, ErrBadJSON, ""},
+		{"truncated", `{"name":"Ada"`, "application/json", 0, ErrBadJSON, ""},
+		{"wrong type", `{"age":"old"}`, "application/json", 0, ErrBadJSON, "age"},
+		{"unknown field", `{"name":"Ada","admin":true}`, "application/json", 0, ErrBadJSON, "admin"},
+		{"two values", `{"name":"Ada"}{"name":"Bob"}`, "application/json", 0, ErrBadJSON, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got createInput
+			err := DecodeJSON(httptest.NewRecorder(), newJSONRequest(tt.body, tt.contentType), &got, tt.maxBytes)
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("DecodeJSON() error = %v, want kind %v", err, tt.wantKind)
+			}
+			var de *DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("DecodeJSON() error %T is not *DecodeError", err)
+			}
+			if de.Field != tt.wantField {
+				t.Errorf("Field = %q, want %q", de.Field, tt.wantField)
+			}
+			if de.Message == "" {
+				t.Error("Message should not be empty")
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := WriteJSON(rec, http.StatusCreated, map[string]int{"id": 7}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if got := rec.Body.String(); got != "{\"id\":7}\n" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestWriteJSON_EncodeError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := WriteJSON(rec, http.StatusOK, map[string]float64{"bad": math.Inf(1)})
+	if err == nil {
+		t.Fatal("WriteJSON() with unencodable value returned nil error")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if got := rec.Body.String(); got != "{\"error\":\"internal error\"}\n" {
+		t.Errorf("body = %q, want a clean error body", got)
+	}
+}
@@ -18,11 +18,33 @@ import (
 //	    "data": result,
 //	})
 func JSON(w http.ResponseWriter, status int, data any) {
+	_ = WriteJSON(w, status, data)
+}
+
+// WriteJSON is JSON for callers that want to know about failures. The body
+// is encoded before anything is written, so a value that cannot be encoded
+// produces a clean 500 {"error": "internal error"} instead of a truncated
+// response, and the encoding error is returned for logging. Errors writing
+// to the client are returned as well.
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	var body []byte
+	if v != nil {
+		var err error
+		if body, err = json.Marshal(v); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":"internal error"}` + "\n"))
+			return err
+		}
+		body = append(body, '\n')
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if data != nil {
-		_ = json.NewEncoder(w).Encode(data)
+	if body == nil {
+		return nil
 	}
+	_, err := w.Write(body)
+	return err
 }
 
 // OK writes a 200 OK JSON response.
@@ -0,0 +1,180 @@
+// Package lifecycle runs start and stop hooks in a defined order, so
+// features that open connections, warm caches, or run workers have one
+// place to set them up and tear them down.
+//
+// Hooks are registered with OnStart and OnStop. Start runs the start hooks
+// in registration order and Stop runs the stop hooks in reverse, so what
+// started first stops last. A stop hook runs only if every start hook
+// registered before it succeeded, which lets a feature register its start
+// and stop hooks together:
+//
+//	lc := lifecycle.New(lifecycle.WithLogger(logger))
+//	lc.OnStart("report cache", cache.Warm)
+//	lc.OnStop("report cache", cache.Flush)
+//	...
+//	if err := lc.Start(ctx); err != nil { ... }
+//	defer lc.Stop(shutdownCtx)
+//
+// Run does both around waiting for a context, and server.WithLifecycle ties
+// them to a server's graceful shutdown. In the main app the hooks are
+// started at the end of bootstrap.BuildHandler and stopped by
+// bootstrap.Shutdown.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultStopTimeout is how long Run gives the stop hooks, matching the
+// server package's default shutdown timeout.
+const DefaultStopTimeout = 15 * time.Second
+
+// Hook is a start or stop function. It should honor ctx's deadline.
+type Hook func(ctx context.Context) error
+
+// hook is a registered Hook with its place in registration order.
+type hook struct {
+	name string
+	fn   Hook
+	seq  int
+}
+
+// Lifecycle holds registered hooks. Create it with New.
+type Lifecycle struct {
+	logger      *zap.Logger
+	stopTimeout time.Duration
+
+	mu      sync.Mutex
+	starts  []hook
+	stops   []hook
+	seq     int
+	failed  int  // seq of the start hook that failed; 0 if none has
+	stopped bool // Stop has run
+}
+
+// Option configures a Lifecycle.
+type Option func(*Lifecycle)
+
+// WithLogger logs each phase and hook as it runs, and any failure.
+func WithLogger(logger *zap.Logger) Option {
+	return func(l *Lifecycle) {
+		l.logger = logger
+	}
+}
+
+// WithStopTimeout sets how long Run gives the stop hooks once its context
+// is done. The default is DefaultStopTimeout.
+func WithStopTimeout(d time.Duration) Option {
+	return func(l *Lifecycle) {
+		l.stopTimeout = d
+	}
+}
+
+// New creates an empty Lifecycle.
+func New(opts ...Option) *Lifecycle {
+	l := &Lifecycle{logger: zap.NewNop(), stopTimeout: DefaultStopTimeout}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// OnStart registers fn to run, under name in logs and errors, when Start
+// is called. Hooks registered after Start has run are not started.
+func (l *Lifecycle) OnStart(name string, fn Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	l.starts = append(l.starts, hook{name: name, fn: fn, seq: l.seq})
+}
+
+// OnStop registers fn to run, under name in logs and errors, when Stop is
+// called.
+func (l *Lifecycle) OnStop(name string, fn Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	l.stops = append(l.stops, hook{name: name, fn: fn, seq: l.seq})
+}
+
+// Start runs the start hooks in registration order. At the first failure
+// it runs no more and returns that error; call Stop to undo the hooks that
+// did start.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	starts := l.starts
+	l.mu.Unlock()
+
+	l.logger.Info("lifecycle starting", zap.Int("hooks", len(starts)))
+	for _, h := range starts {
+		begin := time.Now()
+		if err := h.fn(ctx); err != nil {
+			l.mu.Lock()
+			l.failed = h.seq
+			l.mu.Unlock()
+			l.logger.Error("lifecycle start hook failed", zap.String("hook", h.name), zap.Error(err))
+			return fmt.Errorf("start %s: %w", h.name, err)
+		}
+		l.logger.Info("lifecycle hook started", zap.String("hook", h.name), zap.Duration("duration", time.Since(begin)))
+	}
+	l.logger.Info("lifecycle started")
+	return nil
+}
+
+// Stop runs the stop hooks in reverse registration order, skipping any
+// registered after a start hook that failed. Every eligible hook runs even
+// if an earlier one fails; the failures are returned joined. Stop runs the
+// hooks only once; later calls return nil.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return nil
+	}
+	l.stopped = true
+	stops := l.stops
+	failed := l.failed
+	l.mu.Unlock()
+
+	l.logger.Info("lifecycle stopping", zap.Int("hooks", len(stops)))
+	var errs []error
+	for i := len(stops) - 1; i >= 0; i-- {
+		h := stops[i]
+		if failed != 0 && h.seq > failed {
+			continue
+		}
+		begin := time.Now()
+		if err := h.fn(ctx); err != nil {
+			l.logger.Warn("lifecycle stop hook failed", zap.String("hook", h.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("stop %s: %w", h.name, err))
+			continue
+		}
+		l.logger.Info("lifecycle hook stopped", zap.String("hook", h.name), zap.Duration("duration", time.Since(begin)))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	l.logger.Info("lifecycle stopped")
+	return nil
+}
+
+// Run starts the hooks, waits for ctx to be done, and stops them with
+// the stop timeout (see WithStopTimeout). If a start hook fails, Run stops
+// what had started and returns the start error joined with any stop errors.
+func (l *Lifecycle) Run(ctx context.Context) error {
+	startErr := l.Start(ctx)
+	if startErr == nil {
+		<-ctx.Done()
+	}
+
+	// ctx is done or failed to start; give the stop hooks their own deadline.
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.stopTimeout)
+	defer cancel()
+	return errors.Join(startErr, l.Stop(stopCtx))
+}
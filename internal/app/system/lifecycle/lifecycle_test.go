@@ -0,0 +1,153 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recording returns a hook that appends name to calls and returns err.
+func recording(calls *[]string, name string, err error) Hook {
+	return func(context.Context) error {
+		*calls = append(*calls, name)
+		return err
+	}
+}
+
+func TestStartStop_Order(t *testing.T) {
+	var calls []string
+	l := New()
+	l.OnStart("db", recording(&calls, "start db", nil))
+	l.OnStop("db", recording(&calls, "stop db", nil))
+	l.OnStart("cache", recording(&calls, "start cache", nil))
+	l.OnStop("cache", recording(&calls, "stop cache", nil))
+
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := l.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	want := []string{"start db", "start cache", "stop cache", "stop db"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestStart_FailureSkipsLaterHooks(t *testing.T) {
+	var calls []string
+	boom := errors.New("boom")
+	l := New()
+	l.OnStart("db", recording(&calls, "start db", nil))
+	l.OnStop("db", recording(&calls, "stop db", nil))
+	l.OnStart("cache", recording(&calls, "start cache", boom))
+	l.OnStop("cache", recording(&calls, "stop cache", nil))
+	l.OnStart("workers", recording(&calls, "start workers", nil))
+
+	err := l.Start(context.Background())
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "cache") {
+		t.Fatalf("Start() error = %v, want boom naming the cache hook", err)
+	}
+	if err := l.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	want := []string{"start db", "start cache", "stop db"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestStop_RunsAllAndJoinsErrors(t *testing.T) {
+	var calls []string
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	l := New()
+	l.OnStop("a", recording(&calls, "stop a", errA))
+	l.OnStop("b", recording(&calls, "stop b", errB))
+	l.OnStop("c", recording(&calls, "stop c", nil))
+
+	err := l.Stop(context.Background())
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Stop() error = %v, want both failures", err)
+	}
+	if want := []string{"stop c", "stop b", "stop a"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	// A second Stop does nothing.
+	if err := l.Stop(context.Background()); err != nil || len(calls) != 3 {
+		t.Errorf("second Stop() = %v after %d calls, want nil and no more hooks", err, len(calls))
+	}
+}
+
+func TestRun_StopsWhenContextDone(t *testing.T) {
+	var stopped, live, hasDeadline bool
+	l := New(WithStopTimeout(time.Second))
+	l.OnStop("db", func(ctx context.Context) error {
+		stopped, live = true, ctx.Err() == nil
+		_, hasDeadline = ctx.Deadline()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Run(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after its context was canceled")
+	}
+	if !stopped {
+		t.Fatal("stop hook did not run")
+	}
+	if !live || !hasDeadline {
+		t.Errorf("stop hook context live = %v, has deadline = %v; want its own, with the stop timeout", live, hasDeadline)
+	}
+}
+
+func TestRun_StartFailure(t *testing.T) {
+	var calls []string
+	boom := errors.New("boom")
+	l := New()
+	l.OnStop("db", recording(&calls, "stop db", nil))
+	l.OnStart("cache", recording(&calls, "start cache", boom))
+
+	// Run returns without waiting on the context.
+	if err := l.Run(context.Background()); !errors.Is(err, boom) {
+		t.Fatalf("Run() error = %v, want boom", err)
+	}
+	if want := []string{"start cache", "stop db"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestLogsPhases(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	l := New(WithLogger(zap.New(core)))
+	l.OnStart("db", func(context.Context) error { return nil })
+	l.OnStop("db", func(context.Context) error { return errors.New("disconnect failed") })
+
+	_ = l.Start(context.Background())
+	_ = l.Stop(context.Background())
+
+	for _, msg := range []string{"lifecycle starting", "lifecycle hook started", "lifecycle started", "lifecycle stopping", "lifecycle stop hook failed"} {
+		if logs.FilterMessage(msg).Len() != 1 {
+			t.Errorf("expected one %q log line", msg)
+		}
+	}
+	if logs.FilterMessage("lifecycle stopped").Len() != 0 {
+		t.Error("logged a clean stop after a stop hook failed")
+	}
+}
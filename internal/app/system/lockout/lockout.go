@@ -0,0 +1,146 @@
+// internal/app/system/lockout/lockout.go
+//
+// Package lockout slows brute-force login attempts by locking an account,
+// or a client IP, after repeated failures.
+//
+// Failures are counted per account and per IP in a fixed window starting at
+// the first failure. When a count reaches its threshold the account or IP is
+// locked for the lockout duration and its count starts over. Locks and
+// counts expire on their own; a successful login clears the account's count
+// and lock. The IP count is left alone, so one valid account cannot be used
+// to keep guessing at others from the same address.
+//
+// State lives in a Store: MemoryStore for a single instance, RedisStore
+// (build tag "redis") for several, or the MongoDB store in store/ratelimit.
+//
+// Usage:
+//
+//	l := lockout.New(lockout.NewMemoryStore(), lockout.Config{
+//		MaxAttempts:   5,
+//		IPMaxAttempts: 50,
+//		Window:        15 * time.Minute,
+//		Duration:      15 * time.Minute,
+//	})
+//	if wait, _ := l.Check(ctx, loginID, ip); wait > 0 {
+//		errorsHandler.TooManyRequests(w, r, wait)
+//		return
+//	}
+package lockout
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Store counts failures and holds locks by key. Counts and locks must expire
+// without further calls, and implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Incr adds one to key's count and returns the new count. A count that
+	// does not exist yet, or has expired, starts at 1 and expires after
+	// window.
+	Incr(ctx context.Context, key string, window time.Duration) (int, error)
+	// Lock locks key for d and clears its count.
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// Locked returns how long key stays locked, or 0 if it is not.
+	Locked(ctx context.Context, key string) (time.Duration, error)
+	// Reset clears key's count and lock.
+	Reset(ctx context.Context, key string) error
+}
+
+// Config holds the thresholds. A non-positive MaxAttempts or IPMaxAttempts
+// turns that check off.
+type Config struct {
+	MaxAttempts   int           // failures per account before it is locked
+	IPMaxAttempts int           // failures per client IP before it is locked
+	Window        time.Duration // how long failures are counted
+	Duration      time.Duration // how long a lock lasts
+}
+
+// Lockout applies Config to the accounts and IPs recorded in a Store.
+type Lockout struct {
+	store Store
+	cfg   Config
+}
+
+// New creates a Lockout keeping its state in store.
+func New(store Store, cfg Config) *Lockout {
+	return &Lockout{store: store, cfg: cfg}
+}
+
+// Check returns how long login attempts for account from ip are refused, or
+// 0 when they may go ahead. Either key may be empty to skip it.
+func (l *Lockout) Check(ctx context.Context, account, ip string) (time.Duration, error) {
+	var wait time.Duration
+	var errs []error
+	for _, key := range l.keys(account, ip) {
+		d, err := l.store.Locked(ctx, key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		wait = max(wait, d)
+	}
+	return wait, errors.Join(errs...)
+}
+
+// Fail records a failed attempt for account from ip. If that reaches a
+// threshold, the account or IP is locked and Fail returns the lock
+// duration; otherwise it returns 0.
+func (l *Lockout) Fail(ctx context.Context, account, ip string) (time.Duration, error) {
+	var locked time.Duration
+	var errs []error
+	for _, key := range l.keys(account, ip) {
+		limit := l.cfg.MaxAttempts
+		if strings.HasPrefix(key, ipPrefix) {
+			limit = l.cfg.IPMaxAttempts
+		}
+		n, err := l.store.Incr(ctx, key, l.cfg.Window)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if n < limit {
+			continue
+		}
+		if err := l.store.Lock(ctx, key, l.cfg.Duration); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		locked = l.cfg.Duration
+	}
+	return locked, errors.Join(errs...)
+}
+
+// Succeed clears account's failures and lock after a successful login.
+func (l *Lockout) Succeed(ctx context.Context, account string) error {
+	if l.cfg.MaxAttempts <= 0 || account == "" {
+		return nil
+	}
+	return l.store.Reset(ctx, accountKey(account))
+}
+
+// Key prefixes keep accounts and IPs apart in the store.
+const (
+	accountPrefix = "account:"
+	ipPrefix      = "ip:"
+)
+
+// keys returns the store keys checked for account and ip.
+func (l *Lockout) keys(account, ip string) []string {
+	keys := make([]string, 0, 2)
+	if l.cfg.MaxAttempts > 0 && account != "" {
+		keys = append(keys, accountKey(account))
+	}
+	if l.cfg.IPMaxAttempts > 0 && ip != "" {
+		keys = append(keys, ipPrefix+ip)
+	}
+	return keys
+}
+
+// accountKey normalizes account so "Alice" and "alice " share a count.
+func accountKey(account string) string {
+	return accountPrefix + strings.ToLower(strings.TrimSpace(account))
+}
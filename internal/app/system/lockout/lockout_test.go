@@ -0,0 +1,152 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLockout returns a Lockout on a MemoryStore with a settable clock.
+func newTestLockout(cfg Config) (*Lockout, *MemoryStore, *time.Time) {
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	return New(s, cfg), s, &now
+}
+
+func TestLockout_LocksAccountAfterMaxAttempts(t *testing.T) {
+	l, _, now := newTestLockout(Config{MaxAttempts: 3, Window: time.Minute, Duration: 10 * time.Minute})
+	ctx := context.Background()
+
+	for i := 1; i < 3; i++ {
+		if locked, _ := l.Fail(ctx, "alice", "198.51.100.1"); locked != 0 {
+			t.Fatalf("failure %d locked for %v, want no lock yet", i, locked)
+		}
+	}
+	if locked, _ := l.Fail(ctx, "alice", "198.51.100.1"); locked != 10*time.Minute {
+		t.Fatalf("third failure locked for %v, want %v", locked, 10*time.Minute)
+	}
+
+	if wait, _ := l.Check(ctx, "ALICE ", "203.0.113.9"); wait != 10*time.Minute {
+		t.Errorf("Check = %v, want the account locked from any IP", wait)
+	}
+	if wait, _ := l.Check(ctx, "bob", "198.51.100.1"); wait != 0 {
+		t.Errorf("Check(bob) = %v, want other accounts unaffected", wait)
+	}
+
+	*now = now.Add(4 * time.Minute)
+	if wait, _ := l.Check(ctx, "alice", ""); wait != 6*time.Minute {
+		t.Errorf("Check after 4m = %v, want %v", wait, 6*time.Minute)
+	}
+
+	*now = now.Add(6 * time.Minute)
+	if wait, _ := l.Check(ctx, "alice", ""); wait != 0 {
+		t.Errorf("Check after the lockout = %v, want 0", wait)
+	}
+	if locked, _ := l.Fail(ctx, "alice", ""); locked != 0 {
+		t.Error("first failure after the lockout locked again, want a fresh count")
+	}
+}
+
+func TestLockout_WindowExpires(t *testing.T) {
+	l, _, now := newTestLockout(Config{MaxAttempts: 2, Window: time.Minute, Duration: time.Hour})
+	ctx := context.Background()
+
+	l.Fail(ctx, "alice", "")
+	*now = now.Add(2 * time.Minute)
+	if locked, _ := l.Fail(ctx, "alice", ""); locked != 0 {
+		t.Error("failures in different windows locked the account")
+	}
+}
+
+func TestLockout_IPLimit(t *testing.T) {
+	l, _, _ := newTestLockout(Config{MaxAttempts: 10, IPMaxAttempts: 3, Window: time.Minute, Duration: time.Minute})
+	ctx := context.Background()
+
+	for _, account := range []string{"a", "b", "c"} {
+		l.Fail(ctx, account, "198.51.100.1")
+	}
+	if wait, _ := l.Check(ctx, "d", "198.51.100.1"); wait != time.Minute {
+		t.Errorf("Check from the IP = %v, want it locked across accounts", wait)
+	}
+	if wait, _ := l.Check(ctx, "d", "198.51.100.2"); wait != 0 {
+		t.Errorf("Check from another IP = %v, want 0", wait)
+	}
+
+	// A success clears the account, not the IP.
+	if err := l.Succeed(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := l.Check(ctx, "a", "198.51.100.1"); wait != time.Minute {
+		t.Errorf("Check after success = %v, want the IP still locked", wait)
+	}
+}
+
+func TestLockout_SucceedResetsAccount(t *testing.T) {
+	l, s, _ := newTestLockout(Config{MaxAttempts: 2, Window: time.Minute, Duration: time.Minute})
+	ctx := context.Background()
+
+	l.Fail(ctx, "alice", "")
+	if err := l.Succeed(ctx, "Alice"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 0 {
+		t.Errorf("store holds %d keys after success, want 0", s.Len())
+	}
+	if locked, _ := l.Fail(ctx, "alice", ""); locked != 0 {
+		t.Error("failure after a success locked the account, want the count reset")
+	}
+}
+
+func TestLockout_DisabledChecks(t *testing.T) {
+	l, s, _ := newTestLockout(Config{Window: time.Minute, Duration: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if locked, _ := l.Fail(ctx, "alice", "198.51.100.1"); locked != 0 {
+			t.Fatal("failure locked with both limits off")
+		}
+	}
+	if s.Len() != 0 {
+		t.Errorf("store holds %d keys with both limits off, want 0", s.Len())
+	}
+}
+
+// failingStore is a Store whose every call fails.
+type failingStore struct{}
+
+var errStore = errors.New("store down")
+
+func (failingStore) Incr(context.Context, string, time.Duration) (int, error) { return 0, errStore }
+func (failingStore) Lock(context.Context, string, time.Duration) error        { return errStore }
+func (failingStore) Locked(context.Context, string) (time.Duration, error)    { return 0, errStore }
+func (failingStore) Reset(context.Context, string) error                      { return errStore }
+
+func TestLockout_StoreErrors(t *testing.T) {
+	l := New(failingStore{}, Config{MaxAttempts: 1, IPMaxAttempts: 1, Window: time.Minute, Duration: time.Minute})
+	ctx := context.Background()
+
+	if wait, err := l.Check(ctx, "alice", "198.51.100.1"); wait != 0 || !errors.Is(err, errStore) {
+		t.Errorf("Check = %v, %v; want 0 and the store error", wait, err)
+	}
+	if locked, err := l.Fail(ctx, "alice", "198.51.100.1"); locked != 0 || !errors.Is(err, errStore) {
+		t.Errorf("Fail = %v, %v; want 0 and the store error", locked, err)
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s.Incr(ctx, "counted", time.Minute)
+	s.Lock(ctx, "locked", time.Hour)
+
+	now = now.Add(2 * sweepInterval)
+	s.Incr(ctx, "fresh", time.Minute)
+	if s.Len() != 2 {
+		t.Errorf("Len = %d after sweep, want the expired count dropped and the lock kept", s.Len())
+	}
+}
@@ -0,0 +1,116 @@
+// internal/app/system/lockout/memory.go
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops expired entries.
+const sweepInterval = time.Minute
+
+// MemoryStore is an in-process Store, fine for a single instance. Expired
+// entries are swept lazily, so it needs no background goroutine.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// entry is the state of one key.
+type entry struct {
+	count        int
+	countExpires time.Time
+	lockedUntil  time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*entry), now: time.Now}
+}
+
+// Incr implements Store.
+func (s *MemoryStore) Incr(_ context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	e := s.entry(key)
+	if !now.Before(e.countExpires) {
+		e.count = 0
+		e.countExpires = now.Add(window)
+	}
+	e.count++
+	return e.count, nil
+}
+
+// Lock implements Store.
+func (s *MemoryStore) Lock(_ context.Context, key string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entry(key)
+	e.count = 0
+	e.countExpires = time.Time{}
+	e.lockedUntil = s.now().Add(d)
+	return nil
+}
+
+// Locked implements Store.
+func (s *MemoryStore) Locked(_ context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	if d := e.lockedUntil.Sub(s.now()); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
+// Reset implements Store.
+func (s *MemoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// Len returns the number of keys currently tracked.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// entry returns key's entry, creating it. The caller holds s.mu.
+func (s *MemoryStore) entry(key string) *entry {
+	e, ok := s.entries[key]
+	if !ok {
+		e = &entry{}
+		s.entries[key] = e
+	}
+	return e
+}
+
+// sweep drops entries whose count and lock have both expired, at most once
+// per sweepInterval. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.countExpires) && !now.Before(e.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
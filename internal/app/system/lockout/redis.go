@@ -0,0 +1,72 @@
+//go:build redis
+
+// internal/app/system/lockout/redis.go
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps counts and locks in Redis, shared by every instance.
+// Counts live under "lockout:count:<key>" and locks under
+// "lockout:lock:<key>", both with Redis expiries. Build with -tags redis to
+// include it.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore returns a RedisStore using client.
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client, prefix: "lockout:"}
+}
+
+// Incr implements Store.
+func (s *RedisStore) Incr(ctx context.Context, key string, window time.Duration) (int, error) {
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incr = p.Incr(ctx, s.countKey(key))
+		// NX keeps the expiry set by the first failure in the window.
+		p.ExpireNX(ctx, s.countKey(key), window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+// Lock implements Store.
+func (s *RedisStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, s.lockKey(key), 1, d)
+		p.Del(ctx, s.countKey(key))
+		return nil
+	})
+	return err
+}
+
+// Locked implements Store.
+func (s *RedisStore) Locked(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, s.lockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL answers negative values for missing keys and keys without an
+	// expiry.
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// Reset implements Store.
+func (s *RedisStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.countKey(key), s.lockKey(key)).Err()
+}
+
+func (s *RedisStore) countKey(key string) string { return s.prefix + "count:" + key }
+func (s *RedisStore) lockKey(key string) string  { return s.prefix + "lock:" + key }
@@ -0,0 +1,189 @@
+// internal/app/system/logging/body.go
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// redactedValue replaces the value of any redacted key, matching the
+// errors feature's ErrorLogger.
+const redactedValue = "[REDACTED]"
+
+// WithBodies records up to maxBytes of each request and response body on
+// the access log line, as request_body and response_body, for debugging
+// client integrations. It is meant to be switched on briefly; bodies are
+// large and may hold personal data.
+//
+// Values under any key containing one of redactKeys (case-insensitive), so
+// "password" also covers new_password and "token" csrf_token, are replaced
+// with "[REDACTED]" in JSON and form bodies; a JSON or form body that cannot
+// be parsed, including one cut off at maxBytes, is dropped rather than risk
+// logging a secret. HTML bodies, which carry CSRF tokens in their forms, and
+// binary ones are not logged; other text bodies are logged as they are.
+//
+// The request body is captured as the handler reads it, so nothing is read
+// ahead of the handler and what it sees is unchanged.
+func WithBodies(maxBytes int, redactKeys ...string) Option {
+	return func(c *config) {
+		if maxBytes <= 0 {
+			return
+		}
+		c.bodyLimit = maxBytes
+		for _, k := range redactKeys {
+			c.redactKeys = append(c.redactKeys, strings.ToLower(k))
+		}
+	}
+}
+
+// capture keeps the first limit bytes written to it and counts the rest.
+type capture struct {
+	buf   bytes.Buffer
+	limit int
+	total int
+}
+
+func (c *capture) keep(p []byte) {
+	c.total += len(p)
+	if room := c.limit - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(room, len(p))])
+	}
+}
+
+func (c *capture) truncated() bool { return c.total > c.buf.Len() }
+
+// captureBody records what the handler reads from a request body.
+type captureBody struct {
+	io.ReadCloser
+	capture
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.keep(p[:n])
+	return n, err
+}
+
+// captureWriter records what the handler writes to the response.
+type captureWriter struct {
+	http.ResponseWriter
+	capture
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.keep(p[:n])
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the capture.
+func (w *captureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket upgrades through the capture.
+func (w *captureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bodyFields returns the access log fields for a body captured with the
+// given headers.
+func (c config) bodyFields(name string, h http.Header, body *capture) []zap.Field {
+	if body.total == 0 {
+		return nil
+	}
+	value := "[" + h.Get("Content-Encoding") + " body not logged]"
+	if h.Get("Content-Encoding") == "" {
+		value = c.redactBody(h.Get("Content-Type"), body.buf.Bytes())
+	}
+	fields := []zap.Field{zap.String(name, value)}
+	if body.truncated() {
+		fields = append(fields, zap.Bool(name+"_truncated", true))
+	}
+	return fields
+}
+
+// redactBody renders a captured body for the log according to its type.
+func (c config) redactBody(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	// An untyped body is treated as JSON, so it is only logged if it parses.
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "":
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return redactedValue
+		}
+		out, err := json.Marshal(c.redactJSON(v))
+		if err != nil {
+			return redactedValue
+		}
+		return string(out)
+	case mediaType == "application/x-www-form-urlencoded":
+		if len(c.redactKeys) == 0 {
+			return string(body)
+		}
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return redactedValue
+		}
+		for key := range values {
+			if c.isRedacted(key) {
+				values[key] = []string{redactedValue}
+			}
+		}
+		return values.Encode()
+	case mediaType == "text/html":
+		return "[" + mediaType + " body not logged]"
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/xml":
+		return string(body)
+	default:
+		return "[" + mediaType + " body not logged]"
+	}
+}
+
+// redactJSON replaces the values of redacted keys anywhere in v.
+func (c config) redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if c.isRedacted(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = c.redactJSON(val)
+			}
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = c.redactJSON(val)
+		}
+	}
+	return v
+}
+
+// isRedacted reports whether key should have its value redacted: whether it
+// contains any of the redact keys.
+func (c config) isRedacted(key string) bool {
+	key = strings.ToLower(key)
+	return slices.ContainsFunc(c.redactKeys, func(k string) bool {
+		return strings.Contains(key, k)
+	})
+}
@@ -0,0 +1,125 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// ctxKey is the context key for the request-scoped logger.
+type ctxKey struct{}
+
+// scoped is the request-scoped logger together with the fields it adds, so
+// other loggers (such as the errors feature's ErrorLogger) can adopt them.
+type scoped struct {
+	base   *zap.Logger // logger before fields, so a field can be replaced
+	logger *zap.Logger
+	fields []zap.Field
+}
+
+// NewContext returns a copy of ctx carrying logger with fields added.
+// Handlers retrieve it with FromContext.
+func NewContext(ctx context.Context, logger *zap.Logger, fields ...zap.Field) context.Context {
+	if prev, ok := ctx.Value(ctxKey{}).(scoped); ok {
+		fields = append(append([]zap.Field(nil), prev.fields...), fields...)
+	}
+	return context.WithValue(ctx, ctxKey{}, scoped{base: logger, logger: logger.With(fields...), fields: fields})
+}
+
+// WithUserID returns a copy of ctx whose context logger carries a user_id
+// field of id, replacing any user_id it had, so every later log line in the
+// request, including the ErrorLogger's, names the user. The access log line
+// (FieldUserID) gets it too. Without a context logger only the access log
+// line is tagged. An empty id, as for anonymous requests, changes nothing.
+func WithUserID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	if note, ok := ctx.Value(accessKey{}).(*accessNote); ok {
+		note.userID.Store(&id)
+	}
+	s, ok := ctx.Value(ctxKey{}).(scoped)
+	if !ok {
+		return ctx
+	}
+	fields := make([]zap.Field, 0, len(s.fields)+1)
+	for _, f := range s.fields {
+		if f.Key == string(FieldUserID) {
+			if f.String == id {
+				return ctx
+			}
+			continue
+		}
+		fields = append(fields, f)
+	}
+	fields = append(fields, zap.String(string(FieldUserID), id))
+	return context.WithValue(ctx, ctxKey{}, scoped{base: s.base, logger: s.base.With(fields...), fields: fields})
+}
+
+// FromContext returns the request-scoped logger installed by
+// ContextMiddleware, with the matched chi route added once routing has
+// happened. Without one it returns zap.L(), which is a no-op logger unless
+// the application has replaced it.
+func FromContext(ctx context.Context) *zap.Logger {
+	s, ok := ctx.Value(ctxKey{}).(scoped)
+	if !ok {
+		return zap.L()
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return s.logger.With(zap.String(string(FieldRoute), pattern))
+		}
+	}
+	return s.logger
+}
+
+// ContextFields returns the request-scoped fields carried by ctx, or nil
+// when it has no context logger. The route is not included.
+func ContextFields(ctx context.Context) []zap.Field {
+	s, ok := ctx.Value(ctxKey{}).(scoped)
+	if !ok {
+		return nil
+	}
+	return s.fields
+}
+
+// ContextMiddleware stores a logger derived from logger in each request
+// context, tagged with the request ID and, when userID returns a non-empty
+// value, a user_id field (see WithUserID). Install it after the request ID
+// and session user middleware so both are known. userID may be nil.
+func ContextMiddleware(logger *zap.Logger, userID func(*http.Request) string) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var fields []zap.Field
+			if id := requestID(r); id != "" {
+				fields = append(fields, zap.String(string(FieldRequestID), id))
+			}
+			ctx := NewContext(r.Context(), logger, fields...)
+			if userID != nil {
+				ctx = WithUserID(ctx, userID(r))
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// UserMiddleware tags the context logger and the access log line with the
+// user ID that userID returns (see WithUserID), for routes authenticated
+// after ContextMiddleware runs, such as an API group that checks bearer
+// tokens. Install it after that authentication. Requests for which userID
+// returns "" are left untagged.
+func UserMiddleware(userID func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := userID(r); id != "" {
+				r = r.WithContext(WithUserID(r.Context(), id))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestContextMiddleware_EnrichesLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	userID := func(*http.Request) string { return "user-7" }
+
+	r := chi.NewRouter()
+	r.Use(ContextMiddleware(zap.New(core), userID))
+	r.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("loading item")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("loading item").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != "req-1" {
+		t.Errorf("request_id = %v, want %q", fields["request_id"], "req-1")
+	}
+	if fields["user_id"] != "user-7" {
+		t.Errorf("user_id = %v, want %q", fields["user_id"], "user-7")
+	}
+	if fields["route"] != "/items/{id}" {
+		t.Errorf("route = %v, want %q", fields["route"], "/items/{id}")
+	}
+}
+
+func TestContextMiddleware_OmitsEmptyUser(t *testing.T) {
+	var got []string
+	h := ContextMiddleware(zap.NewNop(), func(*http.Request) string { return "" })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, f := range ContextFields(r.Context()) {
+				got = append(got, f.Key)
+			}
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(got) != 1 || got[0] != "request_id" {
+		t.Errorf("context fields = %v, want [request_id]", got)
+	}
+}
+
+func TestFromContext_FallsBackToGlobal(t *testing.T) {
+	if FromContext(context.Background()) != zap.L() {
+		t.Error("FromContext without a context logger should return zap.L()")
+	}
+	if ContextFields(context.Background()) != nil {
+		t.Error("ContextFields without a context logger should be nil")
+	}
+}
+
+func TestNewContext_AccumulatesFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	base := zap.New(core)
+
+	ctx := NewContext(context.Background(), base, zap.String("request_id", "req-1"))
+	ctx = NewContext(ctx, base, zap.String("job", "export"))
+	FromContext(ctx).Info("step")
+
+	fields := logs.All()[0].ContextMap()
+	if fields["request_id"] != "req-1" || fields["job"] != "export" {
+		t.Errorf("fields = %v, want request_id and job", fields)
+	}
+	if n := len(ContextFields(ctx)); n != 2 {
+		t.Errorf("len(ContextFields) = %d, want 2", n)
+	}
+}
+
+func TestWithUserID_ReplacesUser(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := NewContext(context.Background(), zap.New(core), zap.String("request_id", "req-1"), zap.String("user_id", "session-user"))
+
+	ctx = WithUserID(ctx, "api-user")
+	FromContext(ctx).Info("handled")
+
+	entry := logs.All()[0]
+	var users []string
+	for _, f := range entry.Context {
+		if f.Key == "user_id" {
+			users = append(users, f.String)
+		}
+	}
+	if len(users) != 1 || users[0] != "api-user" {
+		t.Errorf("user_id fields = %v, want only [api-user]", users)
+	}
+	if entry.ContextMap()["request_id"] != "req-1" {
+		t.Error("request_id lost when replacing user_id")
+	}
+	if got := WithUserID(ctx, ""); got != ctx {
+		t.Error("WithUserID with an empty id should leave ctx as is")
+	}
+}
+
+func TestUserMiddleware_TagsAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handled")
+	})
+	h = UserMiddleware(func(r *http.Request) string { return r.Header.Get("X-Test-User") })(h)
+	h = ContextMiddleware(logger, nil)(h)
+	h = Middleware(logger)(h)
+
+	tests := []struct {
+		name string
+		user string
+	}{
+		{"authenticated", "user-7"},
+		{"anonymous", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Test-User", tt.user)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			for _, msg := range []string{"handled", "http_request"} {
+				entries := logs.FilterMessage(msg).All()
+				if len(entries) != 1 {
+					t.Fatalf("expected one %q line, got %d", msg, len(entries))
+				}
+				got, ok := entries[0].ContextMap()["user_id"]
+				if tt.user == "" && ok {
+					t.Errorf("%s user_id = %v, want it absent", msg, got)
+				}
+				if tt.user != "" && got != tt.user {
+					t.Errorf("%s user_id = %v, want %q", msg, got, tt.user)
+				}
+			}
+		})
+	}
+}
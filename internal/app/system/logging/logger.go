@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log output formats accepted by Options.Format.
+const (
+	FormatJSON    = "json"    // one JSON object per line, for log shippers
+	FormatConsole = "console" // human-readable, for local development
+)
+
+// Options configures the logger built by New.
+type Options struct {
+	// Format is FormatJSON or FormatConsole. Empty means FormatJSON.
+	Format string
+	// Level is the minimum level: "debug", "info", "warn", "error",
+	// "dpanic", "panic", or "fatal". Empty means "info".
+	Level string
+}
+
+// New builds a zap logger writing to stderr, for use with the middleware in
+// this package, the errors feature, and anything else that takes a
+// *zap.Logger. An unrecognized level or format falls back to info or JSON,
+// and the returned logger logs a warning saying so.
+func New(opts Options) (*zap.Logger, error) {
+	var warnings []string
+
+	level := zapcore.InfoLevel
+	if s := strings.TrimSpace(opts.Level); s != "" {
+		if err := level.UnmarshalText([]byte(strings.ToLower(s))); err != nil {
+			level = zapcore.InfoLevel
+			warnings = append(warnings, "invalid log level "+strconv.Quote(opts.Level)+"; using info")
+		}
+	}
+
+	var cfg zap.Config
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", FormatJSON:
+		cfg = zap.NewProductionConfig()
+	case FormatConsole:
+		cfg = zap.NewDevelopmentConfig()
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		cfg = zap.NewProductionConfig()
+		warnings = append(warnings, "invalid log format "+strconv.Quote(opts.Format)+"; using json")
+	}
+	cfg.Level = zap.NewAtomicLevelAt(level)
+
+	logger, err := cfg.Build()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		logger.Warn(w)
+	}
+	return logger, nil
+}
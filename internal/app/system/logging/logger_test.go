@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew_Level(t *testing.T) {
+	tests := []struct {
+		level       string
+		wantEnabled zapcore.Level
+		wantBelow   zapcore.Level // not enabled
+	}{
+		{"", zapcore.InfoLevel, zapcore.DebugLevel},
+		{"debug", zapcore.DebugLevel, zapcore.DebugLevel - 1},
+		{"WARN", zapcore.WarnLevel, zapcore.InfoLevel},
+		{"nonsense", zapcore.InfoLevel, zapcore.DebugLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			logger, err := New(Options{Level: tt.level})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if !logger.Core().Enabled(tt.wantEnabled) {
+				t.Errorf("level %v not enabled", tt.wantEnabled)
+			}
+			if logger.Core().Enabled(tt.wantBelow) {
+				t.Errorf("level %v enabled", tt.wantBelow)
+			}
+		})
+	}
+}
+
+func TestNew_Format(t *testing.T) {
+	for _, format := range []string{"", FormatJSON, FormatConsole, "xml"} {
+		logger, err := New(Options{Format: format})
+		if err != nil {
+			t.Errorf("New(Format: %q): %v", format, err)
+			continue
+		}
+		if logger == nil {
+			t.Errorf("New(Format: %q) returned a nil logger", format)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Package logging provides an access log middleware that writes one
+// structured zap line per HTTP request.
+//
+// Access log lines carry the same request_id as error log lines written by
+// the errors feature, so a request can be followed across both.
+//
+// Successful requests (2xx and 3xx) can be sampled with
+// WithSuccessSampleRate and WithPathSampleRate to trim healthy traffic such
+// as static assets; 4xx and 5xx requests are always logged. The decision is
+// derived from the request ID, so every instance makes the same choice for
+// a request.
+//
+// WithBodies adds request and response bodies, with secrets redacted, for
+// debugging client integrations.
+//
+// ContextMiddleware also stores a request-scoped logger in each request
+// context; handlers get it with FromContext so their own lines carry the
+// request ID, user ID, and route without repeating them. The user ID is
+// added once authentication has run (WithUserID, UserMiddleware), and also
+// lands on the access log line, which is written last.
+//
+// New builds a zap logger from a format ("json" or "console") and a level
+// name, for programs and tools that do not get one from the framework.
+package logging
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/dalemusser/strataforge/internal/app/system/network"
+	"github.com/dalemusser/waffle/pantry/requestid"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// Field names a value that can be recorded on each access log line.
+type Field string
+
+const (
+	FieldMethod    Field = "method"
+	FieldPath      Field = "path"
+	FieldRoute     Field = "route" // matched chi route pattern, e.g. /users/{id}
+	FieldQuery     Field = "query"
+	FieldStatus    Field = "status"
+	FieldBytes     Field = "bytes"
+	FieldDuration  Field = "duration"
+	FieldRequestID Field = "request_id"
+	FieldUserID    Field = "user_id" // set by WithUserID; absent for anonymous requests
+	FieldRemoteIP  Field = "remote_ip"
+	FieldUserAgent Field = "user_agent"
+	FieldReferer   Field = "referer"
+)
+
+// DefaultFields are recorded when no WithFields option is given.
+var DefaultFields = []Field{
+	FieldMethod,
+	FieldPath,
+	FieldStatus,
+	FieldBytes,
+	FieldDuration,
+	FieldRequestID,
+	FieldUserID,
+}
+
+// config holds the settings built up by Options.
+type config struct {
+	fields         []Field
+	skip           map[string]struct{}
+	trustedProxies []netip.Prefix
+	sampleRate     float64    // fraction of successful requests logged
+	pathRates      []pathRate // per-prefix overrides, longest prefix first
+	bodyLimit      int        // bytes of each body logged; 0 logs none
+	redactKeys     []string   // lowercased substrings of body keys to redact
+}
+
+// accessKey is the context key for the *accessNote of the request being
+// access logged.
+type accessKey struct{}
+
+// accessNote carries values learned after the access log middleware has
+// handed the request on, such as the user ID once authentication has run.
+type accessNote struct {
+	userID atomic.Pointer[string]
+}
+
+// pathRate is a sample rate for requests under a path prefix.
+type pathRate struct {
+	prefix string
+	rate   float64
+}
+
+// Option configures the access log middleware.
+type Option func(*config)
+
+// WithFields replaces the default set of fields recorded on each line.
+func WithFields(fields ...Field) Option {
+	return func(c *config) {
+		c.fields = fields
+	}
+}
+
+// WithSkipPaths disables logging for requests to the given exact paths,
+// such as health checks polled by a load balancer.
+func WithSkipPaths(paths ...string) Option {
+	return func(c *config) {
+		for _, p := range paths {
+			c.skip[p] = struct{}{}
+		}
+	}
+}
+
+// WithTrustedProxies sets the reverse proxies (IPs or CIDR ranges) whose
+// forwarding headers are believed for the remote_ip field. Without it,
+// remote_ip is the connecting peer's address.
+func WithTrustedProxies(entries ...string) Option {
+	return func(c *config) {
+		c.trustedProxies = network.ParsePrefixes(entries)
+	}
+}
+
+// WithSuccessSampleRate logs only the given fraction (0 to 1) of requests
+// that end with a 2xx or 3xx status; 4xx and 5xx are always logged. The
+// default, 1, logs everything. Sampled lines carry a sample_rate field so
+// counts can be scaled back up.
+func WithSuccessSampleRate(rate float64) Option {
+	return func(c *config) {
+		c.sampleRate = clampRate(rate)
+	}
+}
+
+// WithPathSampleRate overrides the success sample rate for paths starting
+// with prefix, e.g. WithPathSampleRate("/assets/", 0.01). The longest
+// matching prefix wins.
+func WithPathSampleRate(prefix string, rate float64) Option {
+	return func(c *config) {
+		c.pathRates = append(c.pathRates, pathRate{prefix: prefix, rate: clampRate(rate)})
+		sort.SliceStable(c.pathRates, func(i, j int) bool {
+			return len(c.pathRates[i].prefix) > len(c.pathRates[j].prefix)
+		})
+	}
+}
+
+// clampRate limits rate to [0, 1].
+func clampRate(rate float64) float64 {
+	return math.Max(0, math.Min(1, rate))
+}
+
+// Middleware returns middleware that logs each request as a single
+// "http_request" line at Info level once the response has been written.
+// Requests that never call WriteHeader are logged with status 200.
+func Middleware(logger *zap.Logger, opts ...Option) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	cfg := config{fields: DefaultFields, skip: make(map[string]struct{}), sampleRate: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := cfg.skip[r.URL.Path]; ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+
+			if cfg.logsUser() {
+				r = r.WithContext(context.WithValue(r.Context(), accessKey{}, &accessNote{}))
+			}
+
+			var reqBody *captureBody
+			var respBody *captureWriter
+			if cfg.bodyLimit > 0 {
+				if r.Body != nil && r.Body != http.NoBody {
+					reqBody = &captureBody{ReadCloser: r.Body, capture: capture{limit: cfg.bodyLimit}}
+					r.Body = reqBody
+				}
+				respBody = &captureWriter{ResponseWriter: w, capture: capture{limit: cfg.bodyLimit}}
+				w = respBody
+			}
+			ww := httpx.Wrap(w)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			fields := cfg.zapFields(r, status, ww.BytesWritten(), time.Since(start))
+			if status < 400 {
+				rate := cfg.rateFor(r.URL.Path)
+				if !sampled(r, rate) {
+					return
+				}
+				if rate < 1 {
+					fields = append(fields, zap.Float64("sample_rate", rate))
+				}
+			}
+			if reqBody != nil {
+				fields = append(fields, cfg.bodyFields("request_body", r.Header, &reqBody.capture)...)
+			}
+			if respBody != nil {
+				fields = append(fields, cfg.bodyFields("response_body", ww.Header(), &respBody.capture)...)
+			}
+			logger.Info("http_request", fields...)
+		})
+	}
+}
+
+// logsUser reports whether FieldUserID is recorded.
+func (c config) logsUser() bool {
+	for _, f := range c.fields {
+		if f == FieldUserID {
+			return true
+		}
+	}
+	return false
+}
+
+// rateFor returns the success sample rate for path.
+func (c config) rateFor(path string) float64 {
+	for _, pr := range c.pathRates {
+		if strings.HasPrefix(path, pr.prefix) {
+			return pr.rate
+		}
+	}
+	return c.sampleRate
+}
+
+// sampled reports whether a successful request is logged at rate. The
+// choice is a hash of the request ID, so it is the same wherever the
+// request is seen; requests without an ID are always logged.
+func sampled(r *http.Request, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	id := requestID(r)
+	if id == "" {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return float64(mix(h.Sum64())) < rate*math.MaxUint64
+}
+
+// mix spreads the bits of an FNV hash (the splitmix64 finalizer), which on
+// its own varies little for short, similar IDs such as sequential ones.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// zapFields builds the configured fields for a finished request.
+func (c config) zapFields(r *http.Request, status, bytes int, elapsed time.Duration) []zap.Field {
+	fields := make([]zap.Field, 0, len(c.fields))
+	for _, f := range c.fields {
+		switch f {
+		case FieldMethod:
+			fields = append(fields, zap.String(string(f), r.Method))
+		case FieldPath:
+			fields = append(fields, zap.String(string(f), r.URL.Path))
+		case FieldRoute:
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				fields = append(fields, zap.String(string(f), rctx.RoutePattern()))
+			}
+		case FieldQuery:
+			fields = append(fields, zap.String(string(f), r.URL.RawQuery))
+		case FieldStatus:
+			fields = append(fields, zap.Int(string(f), status))
+		case FieldBytes:
+			fields = append(fields, zap.Int(string(f), bytes))
+		case FieldDuration:
+			fields = append(fields, zap.Duration(string(f), elapsed))
+		case FieldRequestID:
+			fields = append(fields, zap.String(string(f), requestID(r)))
+		case FieldUserID:
+			if note, ok := r.Context().Value(accessKey{}).(*accessNote); ok {
+				if id := note.userID.Load(); id != nil {
+					fields = append(fields, zap.String(string(f), *id))
+				}
+			}
+		case FieldRemoteIP:
+			fields = append(fields, zap.String(string(f), network.ClientIP(r, c.trustedProxies)))
+		case FieldUserAgent:
+			fields = append(fields, zap.String(string(f), r.UserAgent()))
+		case FieldReferer:
+			fields = append(fields, zap.String(string(f), r.Referer()))
+		}
+	}
+	return fields
+}
+
+// requestID follows the errors feature's convention: the ID stored by the
+// request ID middleware, falling back to the incoming X-Request-ID header.
+func requestID(r *http.Request) string {
+	if id := requestid.FromRequest(r); id != "" {
+		return id
+	}
+	return r.Header.Get(requestid.DefaultHeader)
+}
@@ -0,0 +1,341 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMiddleware_LogsDefaultFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/items", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.FilterMessage("http_request").All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 access log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["method"] != "POST" || fields["path"] != "/items" {
+		t.Errorf("method/path = %v %v, want POST /items", fields["method"], fields["path"])
+	}
+	if fields["status"] != int64(http.StatusCreated) {
+		t.Errorf("status = %v, want %d", fields["status"], http.StatusCreated)
+	}
+	if fields["bytes"] != int64(5) {
+		t.Errorf("bytes = %v, want 5", fields["bytes"])
+	}
+	if fields["request_id"] != "req-1" {
+		t.Errorf("request_id = %v, want %q", fields["request_id"], "req-1")
+	}
+	if _, ok := fields["duration"]; !ok {
+		t.Error("expected duration field")
+	}
+	if _, ok := fields["user_agent"]; ok {
+		t.Error("expected user_agent to be off by default")
+	}
+}
+
+func TestMiddleware_ImplicitOKStatus(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := logs.All()[0].ContextMap()["status"]; got != int64(http.StatusOK) {
+		t.Errorf("status = %v, want %d", got, http.StatusOK)
+	}
+}
+
+func TestMiddleware_WithFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	r := chi.NewRouter()
+	r.Use(Middleware(zap.New(core), WithFields(FieldRoute, FieldStatus)))
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	fields := logs.All()[0].ContextMap()
+	if len(fields) != 2 {
+		t.Errorf("fields = %v, want only route and status", fields)
+	}
+	if fields["route"] != "/users/{id}" {
+		t.Errorf("route = %v, want %q", fields["route"], "/users/{id}")
+	}
+}
+
+func TestMiddleware_SkipPaths(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core), WithSkipPaths("/health"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if logs.Len() != 0 {
+		t.Errorf("expected no log entries, got %d", logs.Len())
+	}
+}
+
+func TestMiddleware_RemoteIPTrustedProxies(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core),
+		WithFields(FieldRemoteIP),
+		WithTrustedProxies("127.0.0.1"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, remoteAddr := range []string{"127.0.0.1:5000", "198.51.100.1:5000"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["remote_ip"]; got != "203.0.113.7" {
+		t.Errorf("remote_ip via trusted proxy = %v, want %q", got, "203.0.113.7")
+	}
+	if got := entries[1].ContextMap()["remote_ip"]; got != "198.51.100.1" {
+		t.Errorf("remote_ip from untrusted peer = %v, want %q", got, "198.51.100.1")
+	}
+}
+
+func TestMiddleware_SampleRate(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	handler := Middleware(zap.New(core), WithSuccessSampleRate(0.25))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	const n = 2000
+	serve := func(path, id string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-ID", id)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for i := 0; i < n; i++ {
+		serve("/ok", fmt.Sprintf("req-%d", i))
+		serve("/fail", fmt.Sprintf("req-%d", i))
+	}
+
+	var ok, failed int
+	for _, e := range logs.All() {
+		if e.ContextMap()["status"] == int64(http.StatusOK) {
+			ok++
+			if e.ContextMap()["sample_rate"] != 0.25 {
+				t.Fatalf("sampled line sample_rate = %v, want 0.25", e.ContextMap()["sample_rate"])
+			}
+		} else {
+			failed++
+		}
+	}
+	if failed != n {
+		t.Errorf("logged %d of %d failed requests, want all", failed, n)
+	}
+	if ok < n/5 || ok > n*3/10 {
+		t.Errorf("logged %d of %d successful requests, want about %d", ok, n, n/4)
+	}
+}
+
+func TestMiddleware_SampleDecisionIsDeterministic(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	ok := Middleware(zap.New(core), WithSuccessSampleRate(0.5))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 3; j++ {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Request-ID", fmt.Sprintf("req-%d", i))
+			ok.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, e := range logs.All() {
+		counts[e.ContextMap()["request_id"].(string)]++
+	}
+	for id, c := range counts {
+		if c != 3 {
+			t.Errorf("request %s logged %d of 3 times, want all or none", id, c)
+		}
+	}
+}
+
+func TestMiddleware_PathSampleRate(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	handler := Middleware(zap.New(core),
+		WithSuccessSampleRate(1),
+		WithPathSampleRate("/assets/", 0),
+		WithPathSampleRate("/assets/important/", 1),
+	)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, path := range []string{"/page", "/assets/app.css", "/assets/important/x.js"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var paths []string
+	for _, e := range logs.All() {
+		paths = append(paths, e.ContextMap()["path"].(string))
+	}
+	if len(paths) != 2 || paths[0] != "/page" || paths[1] != "/assets/important/x.js" {
+		t.Errorf("logged paths = %v, want [/page /assets/important/x.js]", paths)
+	}
+}
+
+func TestMiddleware_BodiesOffByDefault(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`)))
+
+	fields := logs.All()[0].ContextMap()
+	if _, ok := fields["request_body"]; ok {
+		t.Error("request_body logged without WithBodies")
+	}
+	if _, ok := fields["response_body"]; ok {
+		t.Error("response_body logged without WithBodies")
+	}
+}
+
+func TestMiddleware_BodiesRedacted(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	var seen string
+	h := Middleware(zap.New(core), WithBodies(1024, "password", "token"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user":{"id":7,"Token":"abc"}}`))
+	}))
+
+	body := `{"login":"ada","password":"hunter2"}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if seen != body {
+		t.Errorf("handler read %q, want %q", seen, body)
+	}
+	fields := logs.All()[0].ContextMap()
+	if got := fields["request_body"]; got != `{"login":"ada","password":"[REDACTED]"}` {
+		t.Errorf("request_body = %v", got)
+	}
+	if got := fields["response_body"]; got != `{"user":{"Token":"[REDACTED]","id":7}}` {
+		t.Errorf("response_body = %v", got)
+	}
+}
+
+func TestMiddleware_BodyFormAndTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"form", "application/x-www-form-urlencoded", "login=ada&password=hunter2", "login=ada&password=%5BREDACTED%5D"},
+		{"text", "text/plain; charset=utf-8", "hello", "hello"},
+		{"html", "text/html; charset=utf-8", `<input name="csrf_token" value="x">`, "[text/html body not logged]"},
+		{"binary", "image/png", "\x89PNG", "[image/png body not logged]"},
+		{"invalid json", "application/json", `{"password":`, "[REDACTED]"},
+		{"untyped json", "", `{"password":"x"}`, `{"password":"[REDACTED]"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zap.InfoLevel)
+			h := Middleware(zap.New(core), WithBodies(1024, "password"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got := logs.All()[0].ContextMap()["request_body"]; got != tt.want {
+				t.Errorf("request_body = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware_BodyPasswordChangeForm(t *testing.T) {
+	// The fields of the profile page's password change form, redacted with
+	// the keys the app passes to WithBodies.
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core), WithBodies(1024, "authorization", "cookie", "password", "token", "secret", "api_key"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+	}))
+
+	form := url.Values{
+		"csrf_token":       {"tok-123"},
+		"current_password": {"old-secret"},
+		"new_password":     {"new-secret"},
+		"confirm_password": {"new-secret"},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/profile/password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	got, _ := logs.All()[0].ContextMap()["request_body"].(string)
+	for _, secret := range []string{"tok-123", "old-secret", "new-secret"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("request_body = %q, logs %q", got, secret)
+		}
+	}
+	logged, err := url.ParseQuery(got)
+	if err != nil {
+		t.Fatalf("request_body %q is not a form: %v", got, err)
+	}
+	for key := range form {
+		if logged.Get(key) != "[REDACTED]" {
+			t.Errorf("%s = %q, want [REDACTED]", key, logged.Get(key))
+		}
+	}
+}
+
+func TestMiddleware_BodyTruncated(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core), WithBodies(4))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello world"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Body.String() != "hello world" {
+		t.Errorf("client got %q, want the full body", rec.Body.String())
+	}
+	fields := logs.All()[0].ContextMap()
+	if fields["response_body"] != "hell" || fields["response_body_truncated"] != true {
+		t.Errorf("response_body = %v, truncated = %v; want \"hell\", true", fields["response_body"], fields["response_body_truncated"])
+	}
+}
+
+func TestMiddleware_BodyUnreadNotLogged(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	h := Middleware(zap.New(core), WithBodies(1024))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader("unread")))
+
+	if _, ok := logs.All()[0].ContextMap()["request_body"]; ok {
+		t.Error("request_body logged for a body the handler never read")
+	}
+}
@@ -0,0 +1,42 @@
+// internal/app/system/mailer/compose.go
+package mailer
+
+import (
+	"html"
+
+	"github.com/dalemusser/strataforge/internal/app/system/render"
+)
+
+// Compose builds an Email whose bodies are rendered with rd from the shared
+// template engine: htmlTemplate becomes the HTML body and, when not empty,
+// textTemplate the plain-text body. Register email templates like any other
+// feature templates.
+//
+// The engine escapes for HTML, so the escaping is undone for the text body;
+// text templates can use values such as "Tom & Jerry" as they are.
+//
+// Example:
+//
+//	email, err := mailer.Compose(rd, user.Email, "Your report is ready",
+//	    "reports/email_ready", "reports/email_ready_text", vm)
+//	if err == nil {
+//	    err = m.Send(ctx, email)
+//	}
+func Compose(rd *render.Renderer, to, subject, htmlTemplate, textTemplate string, data any) (Email, error) {
+	email := Email{To: to, Subject: subject}
+
+	htmlBody, err := rd.Execute(htmlTemplate, data)
+	if err != nil {
+		return Email{}, err
+	}
+	email.HTMLBody = htmlBody
+
+	if textTemplate != "" {
+		textBody, err := rd.Execute(textTemplate, data)
+		if err != nil {
+			return Email{}, err
+		}
+		email.TextBody = html.UnescapeString(textBody)
+	}
+	return email, nil
+}
@@ -2,28 +2,43 @@
 package mailer
 
 import (
-	"bytes"
-	"crypto/rand"
-	"encoding/hex"
+	"context"
 	"fmt"
-	"net/smtp"
 
 	"go.uber.org/zap"
 )
 
-// Mailer sends emails via SMTP.
+// Backend names accepted by Config.Backend.
+const (
+	BackendSMTP = "smtp"
+	BackendLog  = "log"
+)
+
+// Sender delivers a fully addressed message. SMTPSender sends it to a mail
+// server; LogSender only logs it, for development without one.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Message is an Email with its From header filled in by the Mailer.
+type Message struct {
+	From string // "Name <address>" or a bare address
+	Email
+}
+
+// Mailer sends emails from the configured sender address through a Sender.
 type Mailer struct {
-	host     string
-	port     int
-	user     string
-	pass     string
+	sender   Sender
 	from     string
 	fromName string
 	log      *zap.Logger
 }
 
-// Config holds the configuration for creating a Mailer.
+// Config holds the configuration for creating a Mailer. Credentials come
+// from the config loader (mail_smtp_user, mail_smtp_pass) and are only
+// handed to the SMTP backend; they are never logged.
 type Config struct {
+	Backend  string // BackendSMTP (default) or BackendLog
 	Host     string
 	Port     int
 	User     string
@@ -34,13 +49,22 @@ type Config struct {
 
 // New creates a new Mailer with the given configuration.
 func New(cfg Config, log *zap.Logger) *Mailer {
+	var sender Sender
+	if cfg.Backend == BackendLog {
+		sender = NewLogSender(log)
+	} else {
+		sender = NewSMTPSender(SMTPConfig{Host: cfg.Host, Port: cfg.Port, User: cfg.User, Pass: cfg.Pass})
+	}
+	return NewWithSender(sender, cfg.From, cfg.FromName, log)
+}
+
+// NewWithSender creates a Mailer that delivers through sender, for tests and
+// custom backends.
+func NewWithSender(sender Sender, from, fromName string, log *zap.Logger) *Mailer {
 	return &Mailer{
-		host:     cfg.Host,
-		port:     cfg.Port,
-		user:     cfg.User,
-		pass:     cfg.Pass,
-		from:     cfg.From,
-		fromName: cfg.FromName,
+		sender:   sender,
+		from:     from,
+		fromName: fromName,
 		log:      log,
 	}
 }
@@ -60,58 +84,14 @@ type Email struct {
 }
 
 // Send sends an email. If HTMLBody is provided, sends a multipart email with both
-// plain text and HTML versions.
-func (m *Mailer) Send(email Email) error {
+// plain text and HTML versions. Sending stops when ctx is done.
+func (m *Mailer) Send(ctx context.Context, email Email) error {
 	from := m.from
 	if m.fromName != "" {
 		from = fmt.Sprintf("%s <%s>", m.fromName, m.from)
 	}
 
-	var msg bytes.Buffer
-
-	// Headers
-	msg.WriteString(fmt.Sprintf("From: %s\r\n", from))
-	msg.WriteString(fmt.Sprintf("To: %s\r\n", email.To))
-	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", email.Subject))
-	msg.WriteString("MIME-Version: 1.0\r\n")
-
-	if email.HTMLBody != "" {
-		// Multipart email with both text and HTML
-		boundary := randomBoundary()
-		msg.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
-		msg.WriteString("\r\n")
-
-		// Plain text part
-		msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-		msg.WriteString("\r\n")
-		msg.WriteString(email.TextBody)
-		msg.WriteString("\r\n")
-
-		// HTML part
-		msg.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
-		msg.WriteString("\r\n")
-		msg.WriteString(email.HTMLBody)
-		msg.WriteString("\r\n")
-
-		// End boundary
-		msg.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
-	} else {
-		// Plain text only
-		msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-		msg.WriteString("\r\n")
-		msg.WriteString(email.TextBody)
-	}
-
-	addr := fmt.Sprintf("%s:%d", m.host, m.port)
-
-	var auth smtp.Auth
-	if m.user != "" && m.pass != "" {
-		auth = smtp.PlainAuth("", m.user, m.pass, m.host)
-	}
-
-	err := smtp.SendMail(addr, auth, m.from, []string{email.To}, msg.Bytes())
+	err := m.sender.Send(ctx, Message{From: from, Email: email})
 	if err != nil {
 		m.log.Error("failed to send email",
 			zap.String("to", email.To),
@@ -126,12 +106,3 @@ func (m *Mailer) Send(email Email) error {
 
 	return nil
 }
-
-// randomBoundary generates a random boundary string for multipart emails.
-func randomBoundary() string {
-	b := make([]byte, 16)
-	if _, err := rand.Read(b); err != nil {
-		panic("crypto/rand.Read failed: " + err.Error())
-	}
-	return "----=_Part_" + hex.EncodeToString(b)
-}
@@ -0,0 +1,172 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/resources"
+	"github.com/dalemusser/strataforge/internal/app/system/render"
+	"github.com/dalemusser/waffle/pantry/templates"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recordingSender keeps the messages it is asked to send.
+type recordingSender struct {
+	msgs []Message
+	err  error
+}
+
+func (s *recordingSender) Send(_ context.Context, msg Message) error {
+	s.msgs = append(s.msgs, msg)
+	return s.err
+}
+
+func TestMailer_Send(t *testing.T) {
+	rs := &recordingSender{}
+	m := NewWithSender(rs, "noreply@example.com", "Strata", zap.NewNop())
+
+	err := m.Send(context.Background(), Email{To: "ada@example.com", Subject: "Hi", TextBody: "hello"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(rs.msgs) != 1 {
+		t.Fatalf("sender got %d messages, want 1", len(rs.msgs))
+	}
+	if got := rs.msgs[0].From; got != "Strata <noreply@example.com>" {
+		t.Errorf("From = %q", got)
+	}
+	if rs.msgs[0].To != "ada@example.com" {
+		t.Errorf("To = %q", rs.msgs[0].To)
+	}
+
+	rs.err = errors.New("relay down")
+	if err := m.Send(context.Background(), Email{To: "ada@example.com"}); !errors.Is(err, rs.err) {
+		t.Errorf("Send() error = %v, want wrapped sender error", err)
+	}
+}
+
+func TestNew_LogBackend(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	m := New(Config{Backend: BackendLog, From: "noreply@example.com", Pass: "secret"}, zap.New(core))
+
+	err := m.Send(context.Background(), Email{To: "ada@example.com", Subject: "Code", TextBody: "Your code is 123456"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	entries := logs.FilterMessage("email (log backend, not sent)").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d emails, want 1", len(entries))
+	}
+	if body := entries[0].ContextMap()["text_body"]; body != "Your code is 123456" {
+		t.Errorf("logged text_body = %v", body)
+	}
+	for _, e := range logs.All() {
+		for _, v := range e.ContextMap() {
+			if s, ok := v.(string); ok && strings.Contains(s, "secret") {
+				t.Errorf("log entry %q contains the SMTP password", e.Message)
+			}
+		}
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	msg := Message{From: "Strata <noreply@example.com>", Email: Email{
+		To:       "ada@example.com\r\nBcc: eve@example.com",
+		Subject:  "Café",
+		TextBody: "plain",
+		HTMLBody: "<p>html</p>",
+	}}
+	raw := string(buildMessage(msg))
+
+	if strings.Contains(raw, "\r\nBcc:") {
+		t.Error("header value injected a header")
+	}
+	if !strings.Contains(raw, "Subject: =?UTF-8?q?Caf=C3=A9?=\r\n") {
+		t.Errorf("subject not encoded:\n%s", raw)
+	}
+	for _, want := range []string{"multipart/alternative", "text/plain; charset=UTF-8\r\n\r\nplain", "text/html; charset=UTF-8\r\n\r\n<p>html</p>"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("message missing %q:\n%s", want, raw)
+		}
+	}
+
+	plain := string(buildMessage(Message{From: "a@example.com", Email: Email{To: "b@example.com", TextBody: "only text"}}))
+	if strings.Contains(plain, "multipart") || !strings.HasSuffix(plain, "\r\n\r\nonly text") {
+		t.Errorf("plain message:\n%s", plain)
+	}
+}
+
+func TestSMTPSender_ContextCancel(t *testing.T) {
+	// A server that accepts connections but never greets.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	s := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: addr.Port})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = s.Send(ctx, Message{From: "a@example.com", Email: Email{To: "b@example.com"}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send() error = %v, want DeadlineExceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Send() did not stop when the context ended")
+	}
+}
+
+func TestCompose(t *testing.T) {
+	resources.LoadSharedTemplates()
+	templates.Register(templates.Set{
+		Name: "mailer_test",
+		FS: fstest.MapFS{
+			"templates/email.gohtml": {Data: []byte(`{{ define "mailer_test/email" }}<p>Hi {{ .Name }}</p>{{ end }}` +
+				`{{ define "mailer_test/email_text" }}Hi {{ .Name }}{{ end }}`)},
+		},
+		Patterns: []string{"templates/*.gohtml"},
+	})
+	eng := templates.New(false)
+	if err := eng.Boot(zap.NewNop()); err != nil {
+		t.Fatalf("boot templates: %v", err)
+	}
+	rd := render.New(eng)
+
+	data := struct{ Name string }{"Tom & Jerry"}
+	email, err := Compose(rd, "ada@example.com", "Hello", "mailer_test/email", "mailer_test/email_text", data)
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+	if email.HTMLBody != "<p>Hi Tom &amp; Jerry</p>" {
+		t.Errorf("HTMLBody = %q", email.HTMLBody)
+	}
+	if email.TextBody != "Hi Tom & Jerry" {
+		t.Errorf("TextBody = %q", email.TextBody)
+	}
+	if email.To != "ada@example.com" || email.Subject != "Hello" {
+		t.Errorf("Compose() = %+v", email)
+	}
+
+	if _, err := Compose(rd, "ada@example.com", "Hello", "mailer_test/missing", "", data); err == nil {
+		t.Error("Compose() with an unknown template should fail")
+	}
+}
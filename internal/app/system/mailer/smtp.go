@@ -0,0 +1,165 @@
+// internal/app/system/mailer/smtp.go
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// SMTPConfig holds the SMTP server address and credentials.
+type SMTPConfig struct {
+	Host string
+	Port int
+	User string
+	Pass string
+}
+
+// SMTPSender sends messages to an SMTP server, upgrading to TLS with
+// STARTTLS when the server offers it.
+type SMTPSender struct {
+	host string
+	port int
+	auth smtp.Auth
+}
+
+// NewSMTPSender returns an SMTPSender for cfg. PLAIN authentication is used
+// when both User and Pass are set; net/smtp only sends it over TLS or to
+// localhost.
+func NewSMTPSender(cfg SMTPConfig) *SMTPSender {
+	s := &SMTPSender{host: cfg.Host, port: cfg.Port}
+	if cfg.User != "" && cfg.Pass != "" {
+		s.auth = smtp.PlainAuth("", cfg.User, cfg.Pass, cfg.Host)
+	}
+	return s
+}
+
+// Send implements Sender. The connection is closed when ctx is done, which
+// aborts a slow or stuck server.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	envelopeFrom := msg.From
+	if addr, err := mail.ParseAddress(msg.From); err == nil {
+		envelopeFrom = addr.Address
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return withContext(ctx, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return withContext(ctx, err)
+		}
+	}
+	if s.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server does not support AUTH")
+		}
+		if err := c.Auth(s.auth); err != nil {
+			return withContext(ctx, err)
+		}
+	}
+	if err := c.Mail(envelopeFrom); err != nil {
+		return withContext(ctx, err)
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return withContext(ctx, err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return withContext(ctx, err)
+	}
+	if _, err := w.Write(buildMessage(msg)); err != nil {
+		return withContext(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return withContext(ctx, err)
+	}
+	return withContext(ctx, c.Quit())
+}
+
+// withContext reports ctx's error in place of the network error caused by
+// closing the connection.
+func withContext(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %v", ctx.Err(), err)
+	}
+	return err
+}
+
+// buildMessage renders msg as an RFC 5322 message. With an HTMLBody it is
+// multipart/alternative with both plain text and HTML versions.
+func buildMessage(msg Message) []byte {
+	var b bytes.Buffer
+
+	// Headers
+	b.WriteString(fmt.Sprintf("From: %s\r\n", headerValue(msg.From)))
+	b.WriteString(fmt.Sprintf("To: %s\r\n", headerValue(msg.To)))
+	b.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", headerValue(msg.Subject))))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTMLBody != "" {
+		// Multipart email with both text and HTML
+		boundary := randomBoundary()
+		b.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
+		b.WriteString("\r\n")
+
+		// Plain text part
+		b.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(msg.TextBody)
+		b.WriteString("\r\n")
+
+		// HTML part
+		b.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+		b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(msg.HTMLBody)
+		b.WriteString("\r\n")
+
+		// End boundary
+		b.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
+	} else {
+		// Plain text only
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(msg.TextBody)
+	}
+	return b.Bytes()
+}
+
+// headerValue drops line breaks so a value cannot add headers of its own.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// randomBoundary generates a random boundary string for multipart emails.
+func randomBoundary() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("crypto/rand.Read failed: " + err.Error())
+	}
+	return "----=_Part_" + hex.EncodeToString(b)
+}
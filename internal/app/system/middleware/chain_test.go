@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// tag returns middleware that records name before and after next runs.
+func tag(order *[]string, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*order = append(*order, name)
+			next.ServeHTTP(w, r)
+			*order = append(*order, "/"+name)
+		})
+	}
+}
+
+func serve(h http.Handler) {
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	final := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	})
+
+	c := New(tag(&order, "a"), tag(&order, "b")).Append(tag(&order, "c"))
+	serve(c.Then(final))
+
+	want := []string{"a", "b", "c", "handler", "/c", "/b", "/a"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestChain_AppendDoesNotShare(t *testing.T) {
+	var order []string
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	// Spare capacity in base must not let one variant overwrite another.
+	base := New(tag(&order, "base")).Append(tag(&order, "x")).Append()
+	left := base.Append(tag(&order, "left"))
+	right := base.Append(tag(&order, "right"))
+
+	serve(left.Then(noop))
+	if want := []string{"base", "x", "left", "/left", "/x", "/base"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("left order = %v, want %v", order, want)
+	}
+	order = nil
+	serve(right.Then(noop))
+	if want := []string{"base", "x", "right", "/right", "/x", "/base"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("right order = %v, want %v", order, want)
+	}
+	if base.Len() != 2 {
+		t.Errorf("base.Len() = %d, want 2", base.Len())
+	}
+}
+
+func TestChain_Extend(t *testing.T) {
+	var order []string
+	a := New(tag(&order, "a"))
+	b := New(tag(&order, "b"), tag(&order, "c"))
+
+	serve(a.Extend(b).ThenFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	want := []string{"a", "b", "c", "/c", "/b", "/a"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if a.Len() != 1 || b.Len() != 2 {
+		t.Errorf("Extend changed its inputs: a.Len() = %d, b.Len() = %d", a.Len(), b.Len())
+	}
+}
+
+func TestChain_EmptyAndHandler(t *testing.T) {
+	called := false
+	h := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })
+
+	var zero Chain
+	serve(zero.Then(h))
+	if !called {
+		t.Error("empty chain did not call the handler")
+	}
+
+	var order []string
+	mw := New(tag(&order, "a"), tag(&order, "b")).Handler
+	serve(mw(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	if want := []string{"a", "b", "/b", "/a"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
@@ -0,0 +1,274 @@
+// internal/app/system/migrate/migrate.go
+//
+// Package migrate applies versioned SQL migrations to a database/sql database.
+//
+// Migrations are .sql files in an fs.FS (usually an embed.FS), named
+// <version>_<name>.up.sql with an optional matching .down.sql:
+//
+//	migrations/0001_create_reports.up.sql
+//	migrations/0001_create_reports.down.sql
+//	migrations/0002_add_report_index.up.sql
+//
+// Applied versions are recorded in a schema_migrations table. Each
+// migration runs in its own transaction together with its bookkeeping row,
+// so a failure leaves the database at the last good version.
+//
+// The application's own data lives in MongoDB, whose indexes are managed by
+// system/indexes; this package is for features backed by a SQL database.
+//
+// Usage:
+//
+//	//go:embed migrations/*.sql
+//	var migrationFS embed.FS
+//
+//	m, err := migrate.New(migrationFS, "migrations", migrate.WithPlaceholder(migrate.Dollar))
+//	err = m.Up(ctx, db)
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table is the name of the table that records applied versions.
+const Table = "schema_migrations"
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string // empty when there is no .down.sql file
+}
+
+// MigrationStatus reports whether a migration has been applied.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time // zero when pending
+}
+
+// Placeholder returns the bind parameter for the nth argument (1-based).
+type Placeholder func(n int) string
+
+// Question is the "?" placeholder used by MySQL and SQLite. It is the default.
+func Question(int) string { return "?" }
+
+// Dollar is the "$n" placeholder used by PostgreSQL.
+func Dollar(n int) string { return "$" + strconv.Itoa(n) }
+
+// Migrator applies a fixed set of migrations.
+type Migrator struct {
+	migrations  []Migration
+	placeholder Placeholder
+}
+
+// Option configures a Migrator.
+type Option func(*Migrator)
+
+// WithPlaceholder sets the bind parameter style for the driver in use.
+func WithPlaceholder(p Placeholder) Option {
+	return func(m *Migrator) {
+		m.placeholder = p
+	}
+}
+
+// New reads the migrations in dir of fsys. Files that do not end in .sql are
+// ignored. It fails on a malformed name, a duplicate version, or a .down.sql
+// file without a matching .up.sql.
+func New(fsys fs.FS, dir string, opts ...Option) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		version, name, direction, err := parseFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+		} else if mig.Name != name {
+			return nil, fmt.Errorf("migration version %d used by both %q and %q", version, mig.Name, name)
+		}
+		if direction == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	m := &Migrator{placeholder: Question}
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no .up.sql file", mig.Version, mig.Name)
+		}
+		m.migrations = append(m.migrations, *mig)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// parseFilename splits "0001_create_reports.up.sql" into its parts.
+func parseFilename(filename string) (version int64, name, direction string, err error) {
+	base := strings.TrimSuffix(filename, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", filename)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	num, name, _ := strings.Cut(base, "_")
+	version, err = strconv.ParseInt(num, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("migration %s: name must start with a positive version number", filename)
+	}
+	return version, name, direction, nil
+}
+
+// Migrations returns the migrations in version order.
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Up applies every pending migration in version order, stopping at the
+// first failure.
+func (m *Migrator) Up(ctx context.Context, db *sql.DB) error {
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		insert := fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s)",
+			Table, m.placeholder(1), m.placeholder(2), m.placeholder(3))
+		if err := m.run(ctx, db, mig, "up", mig.Up, insert, mig.Version, mig.Name, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down rolls back the most recently applied steps migrations, newest first.
+// It stops at the first failure, including a migration with no .down.sql.
+func (m *Migrator) Down(ctx context.Context, db *sql.DB, steps int) error {
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return err
+	}
+	for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return fmt.Errorf("migration %d_%s has no .down.sql file", mig.Version, mig.Name)
+		}
+		del := fmt.Sprintf("DELETE FROM %s WHERE version = %s", Table, m.placeholder(1))
+		if err := m.run(ctx, db, mig, "down", mig.Down, del, mig.Version); err != nil {
+			return err
+		}
+		steps--
+	}
+	return nil
+}
+
+// Status reports every known migration in version order and whether it has
+// been applied.
+func (m *Migrator) Status(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		out = append(out, MigrationStatus{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+// run executes one migration and its bookkeeping statement in a transaction.
+func (m *Migrator) run(ctx context.Context, db *sql.DB, mig Migration, direction, body, record string, args ...any) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s %s: begin: %w", mig.Version, mig.Name, direction, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+	if _, err = tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("migration %d_%s %s: record version: %w", mig.Version, mig.Name, direction, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("migration %d_%s %s: commit: %w", mig.Version, mig.Name, direction, err)
+	}
+	return nil
+}
+
+// applied creates the schema_migrations table if needed and returns the
+// applied versions with their timestamps.
+func (m *Migrator) applied(ctx context.Context, db *sql.DB) (map[int64]time.Time, error) {
+	create := "CREATE TABLE IF NOT EXISTS " + Table +
+		" (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)"
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("create %s: %w", Table, err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM "+Table)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", Table, err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("read %s: %w", Table, err)
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", Table, err)
+	}
+	return applied, nil
+}
@@ -0,0 +1,252 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeDB is a minimal database/sql driver that understands the bookkeeping
+// statements issued by Migrator and records every other statement. Any
+// statement containing "FAIL" returns an error.
+type fakeDB struct {
+	versions map[int64]time.Time
+	executed []string
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = &fakeTx{conn: c}
+	return c.tx, nil
+}
+
+type fakeTx struct {
+	conn    *fakeConn
+	pending []func()
+}
+
+func (t *fakeTx) Commit() error {
+	for _, op := range t.pending {
+		op()
+	}
+	t.conn.tx = nil
+	return nil
+}
+
+func (t *fakeTx) Rollback() error {
+	t.conn.tx = nil
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	var op func()
+	switch {
+	case strings.Contains(s.query, "FAIL"):
+		return nil, errors.New("syntax error")
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS "+Table):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(s.query, "INSERT INTO "+Table):
+		op = func() { db.versions[args[0].(int64)] = args[2].(time.Time) }
+	case strings.HasPrefix(s.query, "DELETE FROM "+Table):
+		op = func() { delete(db.versions, args[0].(int64)) }
+	default:
+		op = func() { db.executed = append(db.executed, s.query) }
+	}
+	if s.conn.tx != nil {
+		s.conn.tx.pending = append(s.conn.tx.pending, op)
+	} else {
+		op()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{}
+	for v, at := range s.conn.db.versions {
+		rows.data = append(rows.data, []driver.Value{v, at})
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	data [][]driver.Value
+	i    int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"version", "applied_at"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.data) {
+		return io.EOF
+	}
+	copy(dest, r.data[r.i])
+	r.i++
+	return nil
+}
+
+func openFake(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+	state := &fakeDB{versions: make(map[int64]time.Time)}
+	db := sql.OpenDB(fakeConnector{db: state})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, state
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/0001_create_reports.up.sql":   {Data: []byte("CREATE TABLE reports")},
+		"migrations/0001_create_reports.down.sql": {Data: []byte("DROP TABLE reports")},
+		"migrations/0002_add_index.up.sql":        {Data: []byte("CREATE INDEX reports_idx")},
+		"migrations/0002_add_index.down.sql":      {Data: []byte("DROP INDEX reports_idx")},
+		"migrations/0010_add_column.up.sql":       {Data: []byte("ALTER TABLE reports ADD title")},
+		"migrations/README.md":                    {Data: []byte("ignored")},
+	}
+}
+
+func TestNew_OrdersAndPairsFiles(t *testing.T) {
+	m, err := New(testFS(), "migrations")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	migs := m.Migrations()
+	if len(migs) != 3 {
+		t.Fatalf("len(Migrations()) = %d, want 3", len(migs))
+	}
+	if migs[0].Version != 1 || migs[2].Version != 10 {
+		t.Errorf("versions = %d..%d, want 1..10", migs[0].Version, migs[2].Version)
+	}
+	if migs[0].Down != "DROP TABLE reports" || migs[2].Down != "" {
+		t.Errorf("down bodies = %q, %q", migs[0].Down, migs[2].Down)
+	}
+}
+
+func TestNew_RejectsBadNames(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no direction": {"m/0001_x.sql": {}},
+		"no version":   {"m/abc_x.up.sql": {}},
+		"orphan down":  {"m/0001_x.down.sql": {Data: []byte("DROP")}},
+		"duplicate":    {"m/0001_a.up.sql": {Data: []byte("A")}, "m/0001_b.up.sql": {Data: []byte("B")}},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(fsys, "m"); err == nil {
+				t.Error("New() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db, state := openFake(t)
+	m, err := New(testFS(), "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Up(ctx, db); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(state.versions) != 3 || len(state.executed) != 3 {
+		t.Fatalf("after Up: %d versions, %d statements; want 3, 3", len(state.versions), len(state.executed))
+	}
+
+	if err := m.Up(ctx, db); err != nil {
+		t.Fatalf("second Up() error = %v", err)
+	}
+	if len(state.executed) != 3 {
+		t.Errorf("second Up re-ran migrations: %v", state.executed)
+	}
+
+	if err := m.Down(ctx, db, 1); err == nil {
+		t.Error("Down() of migration without .down.sql = nil, want error")
+	}
+
+	delete(state.versions, 10)
+	if err := m.Down(ctx, db, 1); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if got := state.executed[len(state.executed)-1]; got != "DROP INDEX reports_idx" {
+		t.Errorf("last statement = %q, want the 0002 down migration", got)
+	}
+
+	status, err := m.Status(ctx, db)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	want := []bool{true, false, false}
+	for i, s := range status {
+		if s.Applied != want[i] {
+			t.Errorf("status[%d] (version %d) Applied = %v, want %v", i, s.Version, s.Applied, want[i])
+		}
+	}
+	if status[0].AppliedAt.IsZero() {
+		t.Error("applied migration has zero AppliedAt")
+	}
+}
+
+func TestUp_StopsOnFailure(t *testing.T) {
+	ctx := context.Background()
+	db, state := openFake(t)
+	fsys := fstest.MapFS{
+		"m/0001_ok.up.sql":     {Data: []byte("CREATE TABLE a")},
+		"m/0002_broken.up.sql": {Data: []byte("FAIL")},
+		"m/0003_later.up.sql":  {Data: []byte("CREATE TABLE c")},
+	}
+	m, err := New(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Up(ctx, db)
+	if err == nil || !strings.Contains(err.Error(), "migration 2_broken up") {
+		t.Fatalf("Up() error = %v, want it to name migration 2_broken", err)
+	}
+	if _, ok := state.versions[2]; ok {
+		t.Error("failed migration was recorded as applied")
+	}
+	if _, ok := state.versions[3]; ok {
+		t.Error("migration after the failure was applied")
+	}
+	if _, ok := state.versions[1]; !ok {
+		t.Error("migration before the failure was not applied")
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	if got := Question(2); got != "?" {
+		t.Errorf("Question(2) = %q", got)
+	}
+	if got := Dollar(2); got != "$2" {
+		t.Errorf("Dollar(2) = %q", got)
+	}
+}
@@ -2,13 +2,18 @@
 package network
 
 import (
+	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 )
 
 // GetClientIP extracts the client IP address from the request.
 // It checks X-Forwarded-For and X-Real-IP headers for reverse proxy setups,
 // and falls back to RemoteAddr if neither is present.
+//
+// The headers are trusted from any peer, so a client can choose the result.
+// Use ClientIP where the address matters for access or throttling.
 func GetClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header first (for reverse proxies)
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
@@ -30,3 +35,86 @@ func GetClientIP(r *http.Request) string {
 	}
 	return r.RemoteAddr
 }
+
+// ClientIP returns the client IP address for r without port or brackets.
+//
+// Unlike GetClientIP, forwarding headers are only believed when the
+// immediate peer (RemoteAddr) is within trustedProxies. X-Forwarded-For is
+// then read right to left, skipping entries that are themselves trusted
+// proxies, and the first untrusted address is the client. Entries a client
+// prepends to the header are never reached, so they cannot spoof the result.
+// X-Real-IP is used when a trusted peer sends no X-Forwarded-For.
+//
+// With no trusted proxies the peer address is always returned.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	peer := remoteIP(r.RemoteAddr)
+	peerAddr, err := netip.ParseAddr(peer)
+	if err != nil || !isTrusted(peerAddr.Unmap(), trustedProxies) {
+		return peer
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.Trim(strings.TrimSpace(hops[i]), "[]")
+			addr, err := netip.ParseAddr(hop)
+			if err != nil {
+				break
+			}
+			client = addr.Unmap().String()
+			if !isTrusted(addr.Unmap(), trustedProxies) {
+				return client
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+
+	if xri := strings.Trim(strings.TrimSpace(r.Header.Get("X-Real-IP")), "[]"); xri != "" {
+		if addr, err := netip.ParseAddr(xri); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+	return peer
+}
+
+// ParsePrefixes parses IPs and CIDR ranges such as "10.0.0.5" or
+// "192.168.0.0/16". A bare IP becomes a single-address prefix. Blank and
+// unparseable entries are skipped.
+func ParsePrefixes(entries []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+// remoteIP strips the port and any IPv6 brackets from a RemoteAddr.
+func remoteIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return strings.Trim(remoteAddr, "[]")
+}
+
+func isTrusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -107,3 +107,114 @@ func TestGetClientIP_ProxyChain(t *testing.T) {
 		t.Errorf("GetClientIP() = %q, want %q", ip, "203.0.113.195")
 	}
 }
+
+func TestClientIP(t *testing.T) {
+	trusted := ParsePrefixes([]string{"10.0.0.0/8", "::1", "bogus"})
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		xRealIP       string
+		expectedIP    string
+	}{
+		{
+			name:          "untrusted peer spoofing X-Forwarded-For",
+			remoteAddr:    "198.51.100.9:5555",
+			xForwardedFor: "1.2.3.4",
+			expectedIP:    "198.51.100.9",
+		},
+		{
+			name:       "untrusted peer spoofing X-Real-IP",
+			remoteAddr: "198.51.100.9:5555",
+			xRealIP:    "1.2.3.4",
+			expectedIP: "198.51.100.9",
+		},
+		{
+			name:          "trusted proxy forwards client",
+			remoteAddr:    "10.0.0.1:5555",
+			xForwardedFor: "203.0.113.7",
+			expectedIP:    "203.0.113.7",
+		},
+		{
+			name:          "client prepends spoofed entry through trusted proxy",
+			remoteAddr:    "10.0.0.1:5555",
+			xForwardedFor: "1.2.3.4, 203.0.113.7",
+			expectedIP:    "203.0.113.7",
+		},
+		{
+			name:          "chain of trusted proxies is skipped",
+			remoteAddr:    "10.0.0.1:5555",
+			xForwardedFor: "203.0.113.7, 10.1.1.1, 10.2.2.2",
+			expectedIP:    "203.0.113.7",
+		},
+		{
+			name:          "all hops trusted returns leftmost",
+			remoteAddr:    "10.0.0.1:5555",
+			xForwardedFor: "10.3.3.3, 10.2.2.2",
+			expectedIP:    "10.3.3.3",
+		},
+		{
+			name:       "trusted proxy with X-Real-IP only",
+			remoteAddr: "10.0.0.1:5555",
+			xRealIP:    "203.0.113.8",
+			expectedIP: "203.0.113.8",
+		},
+		{
+			name:          "trusted IPv6 peer",
+			remoteAddr:    "[::1]:5555",
+			xForwardedFor: "2001:db8::5",
+			expectedIP:    "2001:db8::5",
+		},
+		{
+			name:       "IPv6 peer without headers loses brackets",
+			remoteAddr: "[2001:db8::9]:5555",
+			expectedIP: "2001:db8::9",
+		},
+		{
+			name:       "RemoteAddr without port",
+			remoteAddr: "198.51.100.9",
+			expectedIP: "198.51.100.9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xForwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.xForwardedFor)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+
+			if got := ClientIP(req, trusted); got != tt.expectedIP {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.expectedIP)
+			}
+		})
+	}
+}
+
+func TestClientIP_NoTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:8080"
+	req.Header.Set("X-Forwarded-For", "203.0.113.195")
+
+	if got := ClientIP(req, nil); got != "127.0.0.1" {
+		t.Errorf("ClientIP() = %q, want %q", got, "127.0.0.1")
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	got := ParsePrefixes([]string{" 10.0.0.5 ", "192.168.1.7/16", "", "nope", "::ffff:10.0.0.6"})
+	want := []string{"10.0.0.5/32", "192.168.0.0/16", "10.0.0.6/32"}
+	if len(got) != len(want) {
+		t.Fatalf("ParsePrefixes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("prefix %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package pagination standardizes how list pages read paging parameters and
+// render pagers.
+//
+// Handlers call Parse to read ?page= and ?per_page= with sane bounds, pass
+// Offset and Limit to the store, and wrap the results with New for the
+// template:
+//
+//	p := pagination.Parse(r, 50)
+//	items, total, err := store.List(ctx, filter, p.Offset(), p.Limit())
+//	data.Pager = pagination.New(r, p, items, total)
+//
+// In the template, PageURL keeps the current filters and only changes the
+// page number:
+//
+//	{{if .Pager.HasPrev}}<a href="{{.Pager.PageURL .Pager.PrevPage}}">Previous</a>{{end}}
+//	{{if .Pager.HasNext}}<a href="{{.Pager.PageURL .Pager.NextPage}}">Next</a>{{end}}
+package pagination
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// MaxPerPage caps per_page so a client cannot request unbounded pages.
+	MaxPerPage = 200
+	// MaxPage caps page so offsets cannot overflow.
+	MaxPage = 100000
+)
+
+// Params are the validated paging parameters of a request.
+type Params struct {
+	Page    int // 1-based
+	PerPage int
+}
+
+// Parse reads page and per_page from r's query string. Missing, invalid,
+// or out-of-range values are clamped: page to [1, MaxPage] and per_page to
+// [1, MaxPerPage], with defaultPerPage used when per_page is absent.
+func Parse(r *http.Request, defaultPerPage int) Params {
+	q := r.URL.Query()
+	p := Params{
+		Page:    atoiOr(q.Get("page"), 1),
+		PerPage: atoiOr(q.Get("per_page"), defaultPerPage),
+	}
+	p.Page = clamp(p.Page, 1, MaxPage)
+	p.PerPage = clamp(p.PerPage, 1, MaxPerPage)
+	return p
+}
+
+// Offset returns the number of items before the current page.
+func (p Params) Offset() int64 {
+	return int64(p.Page-1) * int64(p.PerPage)
+}
+
+// Limit returns the page size as an int64 for store queries.
+func (p Params) Limit() int64 {
+	return int64(p.PerPage)
+}
+
+// Page is one page of results plus what a template needs to render a pager.
+type Page[T any] struct {
+	Items      []T
+	Total      int64
+	Page       int
+	PerPage    int
+	TotalPages int // at least 1, so "page 1 of 1" renders for empty lists
+	HasNext    bool
+	HasPrev    bool
+
+	base url.URL
+}
+
+// New builds a Page for items out of total matching results. r supplies the
+// path and query that PageURL reuses.
+func New[T any](r *http.Request, p Params, items []T, total int64) Page[T] {
+	totalPages := 1
+	if total > 0 {
+		totalPages = int((total + int64(p.PerPage) - 1) / int64(p.PerPage))
+	}
+	return Page[T]{
+		Items:      items,
+		Total:      total,
+		Page:       p.Page,
+		PerPage:    p.PerPage,
+		TotalPages: totalPages,
+		HasNext:    p.Page < totalPages,
+		HasPrev:    p.Page > 1,
+		base:       url.URL{Path: r.URL.Path, RawQuery: r.URL.RawQuery},
+	}
+}
+
+// PrevPage returns the previous page number, or 1 on the first page.
+func (pg Page[T]) PrevPage() int {
+	return max(pg.Page-1, 1)
+}
+
+// NextPage returns the next page number, or the last page when there is
+// no next page.
+func (pg Page[T]) NextPage() int {
+	return min(pg.Page+1, pg.TotalPages)
+}
+
+// PageURL returns the current URL with page set to n, clamped to
+// [1, TotalPages]. Other query parameters, such as filters and per_page,
+// are preserved.
+func (pg Page[T]) PageURL(n int) string {
+	n = clamp(n, 1, pg.TotalPages)
+	q := pg.base.Query()
+	if n == 1 {
+		q.Del("page")
+	} else {
+		q.Set("page", strconv.Itoa(n))
+	}
+	u := pg.base
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func atoiOr(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+func clamp(n, lo, hi int) int {
+	return max(lo, min(n, hi))
+}
@@ -0,0 +1,94 @@
+package pagination
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Params
+	}{
+		{"", Params{Page: 1, PerPage: 25}},
+		{"page=3&per_page=10", Params{Page: 3, PerPage: 10}},
+		{"page=0", Params{Page: 1, PerPage: 25}},
+		{"page=-4&per_page=0", Params{Page: 1, PerPage: 1}},
+		{"page=abc&per_page=xyz", Params{Page: 1, PerPage: 25}},
+		{"per_page=100000", Params{Page: 1, PerPage: MaxPerPage}},
+		{"page=99999999999", Params{Page: MaxPage, PerPage: 25}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/items?"+tt.query, nil)
+			if got := Parse(r, 25); got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParams_OffsetLimit(t *testing.T) {
+	p := Params{Page: 3, PerPage: 20}
+	if got := p.Offset(); got != 40 {
+		t.Errorf("Offset() = %d, want 40", got)
+	}
+	if got := p.Limit(); got != 20 {
+		t.Errorf("Limit() = %d, want 20", got)
+	}
+	if got := (Params{Page: 1, PerPage: 20}).Offset(); got != 0 {
+		t.Errorf("first page Offset() = %d, want 0", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	r := httptest.NewRequest("GET", "/items", nil)
+	tests := []struct {
+		name      string
+		page      int
+		total     int64
+		wantPages int
+		wantPrev  bool
+		wantNext  bool
+	}{
+		{"empty", 1, 0, 1, false, false},
+		{"exact fit", 1, 10, 1, false, false},
+		{"first of two", 1, 11, 2, false, true},
+		{"last of two", 2, 11, 2, true, false},
+		{"middle", 2, 30, 3, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg := New(r, Params{Page: tt.page, PerPage: 10}, []int{}, tt.total)
+			if pg.TotalPages != tt.wantPages {
+				t.Errorf("TotalPages = %d, want %d", pg.TotalPages, tt.wantPages)
+			}
+			if pg.HasPrev != tt.wantPrev || pg.HasNext != tt.wantNext {
+				t.Errorf("HasPrev, HasNext = %v, %v; want %v, %v", pg.HasPrev, pg.HasNext, tt.wantPrev, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestPage_PageURL(t *testing.T) {
+	r := httptest.NewRequest("GET", "/jobs/list?status=failed&page=2&per_page=10", nil)
+	pg := New(r, Parse(r, 50), []string{"a"}, 45)
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{3, "/jobs/list?page=3&per_page=10&status=failed"},
+		{1, "/jobs/list?per_page=10&status=failed"},
+		{0, "/jobs/list?per_page=10&status=failed"},
+		{99, "/jobs/list?page=5&per_page=10&status=failed"},
+	}
+	for _, tt := range tests {
+		if got := pg.PageURL(tt.n); got != tt.want {
+			t.Errorf("PageURL(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+	if pg.PrevPage() != 1 || pg.NextPage() != 3 {
+		t.Errorf("PrevPage, NextPage = %d, %d; want 1, 3", pg.PrevPage(), pg.NextPage())
+	}
+}
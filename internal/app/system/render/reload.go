@@ -0,0 +1,51 @@
+// internal/app/system/render/reload.go
+package render
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dalemusser/waffle/pantry/templates"
+)
+
+// diskFS marks a template set already switched to its directory on disk.
+type diskFS struct{ fs.FS }
+
+var diskMu sync.Mutex
+
+// useDiskSources re-registers every template set that has a source
+// directory under root to read from that directory instead of its
+// embedded files.
+func useDiskSources(root string) {
+	diskMu.Lock()
+	defer diskMu.Unlock()
+
+	sets := templates.All()
+	templates.Reset()
+	for _, s := range sets {
+		if _, ok := s.FS.(diskFS); !ok {
+			if dir := sourceDir(root, s); dir != "" {
+				s.FS = diskFS{os.DirFS(dir)}
+			}
+		}
+		templates.Register(s)
+	}
+}
+
+// sourceDir returns the directory under root holding s's templates, or ""
+// if there is none, as for sets registered by tests.
+func sourceDir(root string, s templates.Set) string {
+	dir := filepath.Join(root, "internal", "app", "features", s.Name)
+	if s.Name == "shared" {
+		dir = filepath.Join(root, "internal", "app", "resources")
+	}
+	disk := os.DirFS(dir)
+	for _, pat := range s.Patterns {
+		if matches, _ := fs.Glob(disk, pat); len(matches) > 0 {
+			return dir
+		}
+	}
+	return ""
+}
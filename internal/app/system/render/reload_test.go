@@ -0,0 +1,108 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dalemusser/waffle/pantry/templates"
+)
+
+// registerReloadSet registers a set whose embedded page differs from the
+// copy written under root, and unregisters it when the test ends.
+func registerReloadSet(t *testing.T, root, page string) string {
+	t.Helper()
+	newTestRenderer(t) // boot the shared engine before the registry changes
+
+	dir := filepath.Join(root, "internal", "app", "features", "render_reload", "templates")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "page.gohtml")
+	writeFile(t, path, page)
+
+	templates.Register(templates.Set{
+		Name:     "render_reload",
+		FS:       fstest.MapFS{"templates/page.gohtml": {Data: []byte(`{{ define "render_reload/page" }}embedded{{ end }}`)}},
+		Patterns: []string{"templates/*.gohtml"},
+	})
+	t.Cleanup(func() {
+		sets := templates.All()
+		templates.Reset()
+		for _, s := range sets {
+			if s.Name != "render_reload" {
+				templates.Register(s)
+			}
+		}
+	})
+	return path
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithReload_ReadsEditsFromDisk(t *testing.T) {
+	root := t.TempDir()
+	path := registerReloadSet(t, root, `{{ define "render_reload/page" }}first {{ . }}{{ end }}`)
+	rd := New(testEng, WithReload(true), WithSourceRoot(root))
+
+	render := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		if err := rd.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), "render_reload/page", "edit"); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		return rec.Body.String()
+	}
+
+	if got := render(); got != "first edit" {
+		t.Errorf("body = %q, want the page from disk", got)
+	}
+	writeFile(t, path, `{{ define "render_reload/page" }}second {{ . }}{{ end }}`)
+	if got := render(); got != "second edit" {
+		t.Errorf("body = %q, want the edited page", got)
+	}
+	if got, err := rd.Execute("render_test/hello", "embedded"); err != nil || got != "Hello embedded" {
+		t.Errorf("Execute() = %q, %v; want sets without a source directory to keep their embedded files", got, err)
+	}
+}
+
+func TestWithReload_ParseErrorPage(t *testing.T) {
+	root := t.TempDir()
+	registerReloadSet(t, root, `{{ define "render_reload/page" }}{{ if }}{{ end }}`)
+	rd := New(testEng, WithReload(true), WithSourceRoot(root))
+	rec := httptest.NewRecorder()
+
+	err := rd.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), "render_reload/page", nil)
+	if !errors.Is(err, ErrReload) {
+		t.Fatalf("Render() error = %v, want ErrReload", err)
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "Template error") || !strings.Contains(body, "page.gohtml") {
+		t.Errorf("body = %q, want a page naming the broken template", body)
+	}
+	if _, err := rd.Execute("render_test/hello", nil); !errors.Is(err, ErrReload) {
+		t.Errorf("Execute() error = %v, want ErrReload", err)
+	}
+}
+
+func TestRender_WithoutReloadKeepsParsedTemplates(t *testing.T) {
+	root := t.TempDir()
+	registerReloadSet(t, root, `{{ define "render_reload/page" }}disk{{ end }}`)
+	rd := New(testEng, WithSourceRoot(root))
+
+	if err := rd.Render(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), "render_reload/page", nil); err == nil {
+		t.Error("Render() of a set registered after boot should fail without WithReload")
+	}
+}
@@ -0,0 +1,180 @@
+// Package render writes HTML pages from the shared template engine.
+//
+// Templates are registered by each feature with templates.Register and
+// compiled once at startup against the shared layout and partials, with the
+// helpers collected by templatefuncs; see resources.LoadSharedTemplates.
+// Renderer adds what the package-level
+// templates.Render helper does not: it buffers the page so a failure part way
+// through never sends a half-written response, sets the Content-Type and
+// status, and returns the error to the caller instead of only logging it.
+//
+// A page whose request deadline (see httpx.Deadline) passes before it is
+// written is answered with 503 Service Unavailable instead, so a slow render
+// under load degrades to a short error rather than running into the timeout.
+// Error pages, which are often written because the deadline passed, turn
+// the check off with WithDeadlineCheck(false).
+//
+// In development, WithReload re-parses the templates from disk on every
+// render, so template edits show on the next refresh without a restart.
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/dalemusser/waffle/pantry/templates"
+	"go.uber.org/zap"
+)
+
+// ErrDeadlineExceeded is returned by RenderStatus when the request's
+// deadline passed before the page could be written. A 503 has been written
+// in its place; the caller should not write anything else.
+var ErrDeadlineExceeded = errors.New("render: request deadline exceeded")
+
+// ErrReload is returned, wrapping the parse error, when WithReload is on
+// and the templates on disk fail to parse. RenderStatus has written a 500
+// page showing the error in its place; the caller should not write
+// anything else.
+var ErrReload = errors.New("render: reload templates")
+
+// contentType is the Content-Type written for rendered pages.
+const contentType = "text/html; charset=utf-8"
+
+// Renderer renders named templates from a booted engine.
+type Renderer struct {
+	eng          *templates.Engine
+	reload       bool   // re-parse the templates on every render
+	root         string // directory source template paths are relative to
+	skipDeadline bool   // render even when the request's deadline has passed
+}
+
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// WithReload, when on, re-parses every registered template set on each
+// render instead of using eng's parse-once templates. It is for
+// development only: each render pays for parsing all templates.
+//
+// Sets whose source directory exists under the source root,
+// internal/app/resources for the shared set and internal/app/features/NAME
+// for the others, are read from disk from then on; the rest keep their
+// embedded files. A parse error shows as a 500 page with the error rather
+// than failing the process.
+func WithReload(on bool) Option {
+	return func(rd *Renderer) {
+		rd.reload = on
+	}
+}
+
+// WithSourceRoot sets the directory WithReload finds template sources
+// under. The default, ".", suits running from the repository root.
+func WithSourceRoot(dir string) Option {
+	return func(rd *Renderer) {
+		rd.root = dir
+	}
+}
+
+// WithDeadlineCheck controls whether RenderStatus answers 503 instead of
+// the page once the request's deadline has passed. It is on by default; the
+// errors feature turns it off so a 504 from the timeout middleware, or any
+// error page written after the request was cancelled, is still rendered.
+func WithDeadlineCheck(on bool) Option {
+	return func(rd *Renderer) {
+		rd.skipDeadline = !on
+	}
+}
+
+// New returns a Renderer for eng, which must already be booted.
+func New(eng *templates.Engine, opts ...Option) *Renderer {
+	rd := &Renderer{eng: eng, root: "."}
+	for _, opt := range opts {
+		opt(rd)
+	}
+	if rd.reload {
+		useDiskSources(rd.root)
+	}
+	return rd
+}
+
+// engine returns the engine to render with: eng, or with WithReload a
+// fresh one parsed from the current sources.
+func (rd *Renderer) engine() (*templates.Engine, error) {
+	if !rd.reload {
+		return rd.eng, nil
+	}
+	eng := templates.New(true)
+	if err := eng.Boot(zap.NewNop()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReload, err)
+	}
+	return eng, nil
+}
+
+// Render renders the named template with data as a 200 OK page.
+func (rd *Renderer) Render(w http.ResponseWriter, r *http.Request, name string, data any) error {
+	return rd.RenderStatus(w, r, http.StatusOK, name, data)
+}
+
+// RenderStatus renders the named template with data and writes it with the
+// given status. If rendering fails, nothing is written to w and the error is
+// returned so the caller can respond another way. If the request's deadline
+// has passed, before or during rendering, a 503 is written instead and
+// ErrDeadlineExceeded returned; if reloaded templates fail to parse, a 500
+// page with the error is written and ErrReload returned. Errors writing the
+// finished page to the client are not reported.
+func (rd *Renderer) RenderStatus(w http.ResponseWriter, r *http.Request, status int, name string, data any) error {
+	if rd.deadlineExceeded(w, r) {
+		return ErrDeadlineExceeded
+	}
+	eng, err := rd.engine()
+	if err != nil {
+		writeReloadError(w, err)
+		return err
+	}
+	var buf bytes.Buffer
+	if err := eng.Render(&buf, r, name, data); err != nil {
+		return err
+	}
+	if rd.deadlineExceeded(w, r) {
+		return ErrDeadlineExceeded
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+	return nil
+}
+
+// deadlineExceeded writes a 503 and reports true if r's deadline has passed
+// and the check is on.
+func (rd *Renderer) deadlineExceeded(w http.ResponseWriter, r *http.Request) bool {
+	if rd.skipDeadline || r == nil || !httpx.Expired(r.Context()) {
+		return false
+	}
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	return true
+}
+
+// writeReloadError writes a 500 page showing a template parse error.
+func writeReloadError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<title>Template error</title>\n<h1>Template error</h1>\n<pre>%s</pre>\n", html.EscapeString(err.Error()))
+}
+
+// Execute renders the named template with data and returns the output, for
+// content that is not an HTTP response, such as email bodies. The engine's
+// html/template escaping applies as it does for pages.
+func (rd *Renderer) Execute(name string, data any) (string, error) {
+	eng, err := rd.engine()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := eng.Render(&buf, nil, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
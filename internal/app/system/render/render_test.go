@@ -0,0 +1,164 @@
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/resources"
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/dalemusser/waffle/pantry/templates"
+	"go.uber.org/zap"
+)
+
+var (
+	bootOnce sync.Once
+	testEng  *templates.Engine
+	bootErr  error
+)
+
+// newTestRenderer boots an engine with the shared templates plus a small
+// test set, once per test binary.
+func newTestRenderer(t *testing.T) *Renderer {
+	t.Helper()
+	bootOnce.Do(func() {
+		resources.LoadSharedTemplates()
+		templates.Register(templates.Set{
+			Name: "render_test",
+			FS: fstest.MapFS{
+				"templates/hello.gohtml":  {Data: []byte(`{{ define "render_test/hello" }}Hello {{ . }}{{ end }}`)},
+				"templates/broken.gohtml": {Data: []byte(`{{ define "render_test/broken" }}partial {{ .Missing }}{{ end }}`)},
+				"templates/funcs.gohtml":  {Data: []byte(`{{ define "render_test/funcs" }}{{ . | truncate 5 }}{{ end }}`)},
+			},
+			Patterns: []string{"templates/*.gohtml"},
+		})
+		testEng = templates.New(false)
+		bootErr = testEng.Boot(zap.NewNop())
+	})
+	if bootErr != nil {
+		t.Fatalf("boot templates: %v", bootErr)
+	}
+	return New(testEng)
+}
+
+func TestExecute_SharedFuncs(t *testing.T) {
+	rd := newTestRenderer(t)
+
+	got, err := rd.Execute("render_test/funcs", "templates")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got != "temp…" {
+		t.Errorf("Execute() = %q, want the templatefuncs truncate helper applied", got)
+	}
+}
+
+func TestRender_WritesPage(t *testing.T) {
+	rd := newTestRenderer(t)
+	rec := httptest.NewRecorder()
+
+	if err := rd.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), "render_test/hello", "world"); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("Content-Type = %q, want %q", got, contentType)
+	}
+	if got := rec.Body.String(); got != "Hello world" {
+		t.Errorf("body = %q, want %q", got, "Hello world")
+	}
+}
+
+func TestRenderStatus_SetsStatus(t *testing.T) {
+	rd := newTestRenderer(t)
+	rec := httptest.NewRecorder()
+
+	if err := rd.RenderStatus(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusTeapot, "render_test/hello", "tea"); err != nil {
+		t.Fatalf("RenderStatus() error = %v", err)
+	}
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTeapot)
+	}
+}
+
+func TestRenderStatus_DeadlineExceeded(t *testing.T) {
+	rd := newTestRenderer(t)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(httpx.WithDeadline(req.Context(), time.Now().Add(-time.Second)))
+
+	err := rd.Render(rec, req, "render_test/hello", "late")
+	if !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("Render() error = %v, want ErrDeadlineExceeded", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if strings.Contains(rec.Body.String(), "Hello") {
+		t.Errorf("body = %q, want no page content", rec.Body.String())
+	}
+}
+
+func TestRenderStatus_DeadlineCheckOff(t *testing.T) {
+	newTestRenderer(t)
+	rd := New(testEng, WithDeadlineCheck(false))
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(httpx.WithDeadline(req.Context(), time.Now().Add(-time.Second)))
+
+	if err := rd.RenderStatus(rec, req, http.StatusGatewayTimeout, "render_test/hello", "late"); err != nil {
+		t.Fatalf("RenderStatus() error = %v", err)
+	}
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	if got := rec.Body.String(); got != "Hello late" {
+		t.Errorf("body = %q, want %q", got, "Hello late")
+	}
+}
+
+func TestRender_ErrorWritesNothing(t *testing.T) {
+	rd := newTestRenderer(t)
+
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unknown template", "render_test/missing"},
+		{"execution error", "render_test/broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := rd.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), tt.template, "data"); err == nil {
+				t.Fatal("Render() error = nil, want error")
+			}
+			if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+				t.Errorf("expected nothing written, got body %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	rd := newTestRenderer(t)
+
+	got, err := rd.Execute("render_test/hello", "<b>mail</b>")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "Hello &lt;b&gt;mail&lt;/b&gt;"; got != want {
+		t.Errorf("Execute() = %q, want %q", got, want)
+	}
+	if _, err := rd.Execute("render_test/missing", nil); err == nil {
+		t.Error("Execute() of an unknown template should fail")
+	}
+}
@@ -0,0 +1,153 @@
+// internal/app/system/scaffold/scaffold.go
+//
+// Package scaffold generates the skeleton of a new feature package laid
+// out like the existing ones: a handler with NewHandler, a view model,
+// Routes, and an Index page; a test file; and templates.go, which embeds
+// the feature's templates and registers them when the package is imported.
+//
+// It backs the `strataforge new feature <name>` command.
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrExists is returned when a file the scaffold would write already
+// exists. Nothing is written in that case.
+var ErrExists = errors.New("scaffold: file already exists")
+
+// validName is what a feature name may look like: a short, lowercase Go
+// package name.
+var validName = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// File is one generated file, relative to the repository root.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// data is what the file templates see.
+type data struct {
+	Module string // module path from go.mod
+	Name   string // package name, e.g. "reports"
+	Title  string // display name, e.g. "Reports"
+}
+
+// Feature generates internal/app/features/<name> under root, the
+// repository root (the directory holding go.mod). It refuses names that
+// are not lowercase Go identifiers and, without writing anything, any
+// feature whose files already exist. Go files are gofmt-formatted. It
+// returns the files written.
+func Feature(root, name string) ([]File, error) {
+	files, err := Render(root, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(root, f.Path)); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrExists, f.Path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		path := filepath.Join(root, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		// O_EXCL keeps a file created since the check above.
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return nil, fmt.Errorf("%w: %s", ErrExists, f.Path)
+			}
+			return nil, err
+		}
+		_, werr := out.Write(f.Content)
+		if cerr := out.Close(); werr == nil {
+			werr = cerr
+		}
+		if werr != nil {
+			return nil, werr
+		}
+	}
+	return files, nil
+}
+
+// Render returns the files Feature would write, without touching disk.
+func Render(root, name string) ([]File, error) {
+	if !validName.MatchString(name) || token.IsKeyword(name) {
+		return nil, fmt.Errorf("scaffold: invalid feature name %q: use lowercase letters and digits, starting with a letter", name)
+	}
+	module, err := modulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	d := data{
+		Module: module,
+		Name:   name,
+		Title:  strings.ToUpper(name[:1]) + name[1:],
+	}
+
+	dir := filepath.Join("internal", "app", "features", name)
+	var files []File
+	for _, t := range fileTemplates {
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, d); err != nil {
+			return nil, err
+		}
+		content := buf.Bytes()
+		if strings.HasSuffix(t.path, ".go") {
+			if content, err = format.Source(content); err != nil {
+				return nil, fmt.Errorf("scaffold: format %s: %w", t.path, err)
+			}
+		}
+		files = append(files, File{
+			Path:    filepath.Join(dir, strings.ReplaceAll(t.path, "NAME", name)),
+			Content: content,
+		})
+	}
+	return files, nil
+}
+
+// Wiring returns the lines to add to bootstrap/routes.go to mount the
+// feature; the scaffold does not edit routes.go itself.
+func Wiring(root, name string) (string, error) {
+	module, err := modulePath(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`import %sfeature "%s/internal/app/features/%s"
+
+%sHandler := %sfeature.NewHandler(deps.MongoDatabase, logger)
+r.Mount("/%s", %sfeature.Routes(%sHandler))
+`, name, module, name, name, name, name, name, name), nil
+}
+
+// modulePath reads the module path from a go.mod file.
+func modulePath(gomod string) (string, error) {
+	f, err := os.Open(gomod)
+	if err != nil {
+		return "", fmt.Errorf("scaffold: %w (run from the repository root)", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("scaffold: no module line in %s", gomod)
+}
@@ -0,0 +1,113 @@
+package scaffold
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.24\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestFeature_WritesFiles(t *testing.T) {
+	root := newRoot(t)
+
+	files, err := Feature(root, "reports")
+	if err != nil {
+		t.Fatalf("Feature() error = %v", err)
+	}
+
+	want := []string{"reports.go", "reports_test.go", "templates.go", "templates/index.gohtml"}
+	if len(files) != len(want) {
+		t.Fatalf("Feature() wrote %d files, want %d", len(files), len(want))
+	}
+	for i, name := range want {
+		path := filepath.Join("internal", "app", "features", "reports", name)
+		if files[i].Path != path {
+			t.Errorf("files[%d].Path = %q, want %q", i, files[i].Path, path)
+		}
+		if _, err := os.Stat(filepath.Join(root, path)); err != nil {
+			t.Errorf("%s not written: %v", path, err)
+		}
+	}
+
+	handler := string(files[0].Content)
+	for _, s := range []string{
+		"package reports",
+		`"example.com/app/internal/app/system/viewdata"`,
+		`vm.Title = "Reports"`,
+		`templates.Render(w, r, "reports/index", vm)`,
+	} {
+		if !strings.Contains(handler, s) {
+			t.Errorf("handler missing %q", s)
+		}
+	}
+	if !strings.Contains(string(files[2].Content), `Name:     "reports",`) {
+		t.Error("templates.go does not register the template set")
+	}
+	if !strings.Contains(string(files[3].Content), `{{ define "reports/index" }}`) {
+		t.Error("index.gohtml does not define reports/index")
+	}
+}
+
+func TestFeature_RefusesToOverwrite(t *testing.T) {
+	root := newRoot(t)
+	dir := filepath.Join(root, "internal", "app", "features", "reports")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(dir, "templates.go")
+	if err := os.WriteFile(existing, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Feature(root, "reports"); !errors.Is(err, ErrExists) {
+		t.Fatalf("Feature() error = %v, want ErrExists", err)
+	}
+
+	if b, _ := os.ReadFile(existing); string(b) != "keep" {
+		t.Errorf("existing file overwritten: %q", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "reports.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Feature() wrote files despite a conflict")
+	}
+}
+
+func TestRender_InvalidName(t *testing.T) {
+	root := newRoot(t)
+	for _, name := range []string{"", "Reports", "my-feature", "1st", "func"} {
+		if _, err := Render(root, name); err == nil {
+			t.Errorf("Render(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestRender_MissingGoMod(t *testing.T) {
+	if _, err := Render(t.TempDir(), "reports"); err == nil {
+		t.Error("Render() without go.mod succeeded, want error")
+	}
+}
+
+func TestWiring(t *testing.T) {
+	root := newRoot(t)
+	got, err := Wiring(root, "reports")
+	if err != nil {
+		t.Fatalf("Wiring() error = %v", err)
+	}
+	for _, s := range []string{
+		`reportsfeature "example.com/app/internal/app/features/reports"`,
+		`r.Mount("/reports", reportsfeature.Routes(reportsHandler))`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("Wiring() missing %q", s)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// internal/app/system/scaffold/templates.go
+package scaffold
+
+import "text/template"
+
+// fileTemplate is one generated file. NAME in path is replaced with the
+// feature name. Templates use [[ ]] delimiters so the generated
+// .gohtml can keep its own {{ }} actions.
+type fileTemplate struct {
+	path string
+	tmpl *template.Template
+}
+
+func mustParse(name, text string) *template.Template {
+	return template.Must(template.New(name).Delims("[[", "]]").Parse(text))
+}
+
+var fileTemplates = []fileTemplate{
+	{path: "NAME.go", tmpl: mustParse("handler", handlerTmpl)},
+	{path: "NAME_test.go", tmpl: mustParse("test", testTmpl)},
+	{path: "templates.go", tmpl: mustParse("register", registerTmpl)},
+	{path: "templates/index.gohtml", tmpl: mustParse("index", indexTmpl)},
+}
+
+const handlerTmpl = `// internal/app/features/[[.Name]]/[[.Name]].go
+package [[.Name]]
+
+import (
+	"net/http"
+
+	"[[.Module]]/internal/app/system/viewdata"
+	"github.com/dalemusser/waffle/pantry/templates"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// Handler provides [[.Name]] handlers.
+type Handler struct {
+	db     *mongo.Database
+	logger *zap.Logger
+}
+
+// NewHandler creates a new [[.Name]] Handler.
+func NewHandler(db *mongo.Database, logger *zap.Logger) *Handler {
+	return &Handler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// IndexVM is the view model for the [[.Name]] index page.
+type IndexVM struct {
+	viewdata.BaseVM
+}
+
+// Routes returns a chi.Router with [[.Name]] routes mounted.
+func Routes(h *Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", h.Index)
+	return r
+}
+
+// Index renders the [[.Name]] index page.
+func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
+	vm := IndexVM{
+		BaseVM: viewdata.New(r),
+	}
+	vm.Title = "[[.Title]]"
+
+	templates.Render(w, r, "[[.Name]]/index", vm)
+}
+`
+
+const testTmpl = `package [[.Name]]
+
+import (
+	"testing"
+
+	"[[.Module]]/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func TestNewHandler(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	logger := zap.NewNop()
+
+	h := NewHandler(db, logger)
+	if h == nil {
+		t.Fatal("NewHandler() returned nil")
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	logger := zap.NewNop()
+
+	h := NewHandler(db, logger)
+	router := Routes(h)
+
+	if router == nil {
+		t.Fatal("Routes() returned nil")
+	}
+}
+`
+
+const registerTmpl = `// internal/app/features/[[.Name]]/templates.go
+package [[.Name]]
+
+import (
+	"embed"
+
+	"github.com/dalemusser/waffle/pantry/templates"
+)
+
+//go:embed templates/*.gohtml
+var FS embed.FS
+
+func init() {
+	templates.Register(templates.Set{
+		Name:     "[[.Name]]",
+		FS:       FS,
+		Patterns: []string{"templates/*.gohtml"},
+	})
+}
+`
+
+const indexTmpl = `{{ define "[[.Name]]/index" }}
+  {{ template "layout" . }}
+{{ end }}
+
+{{ define "content" }}
+<div class="flex flex-col h-full">
+  <div class="mb-4">
+    <h1 class="text-2xl font-bold text-gray-900 dark:text-gray-100">{{ .Title }}</h1>
+  </div>
+</div>
+{{ end }}
+`
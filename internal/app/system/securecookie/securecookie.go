@@ -0,0 +1,192 @@
+// internal/app/system/securecookie/securecookie.go
+//
+// Package securecookie encodes values into tamper-proof cookie strings.
+//
+// Every value is JSON-encoded together with its expiry time and signed with
+// HMAC-SHA256, so a client cannot alter it without detection. When a key
+// has a block key, the payload is also encrypted with AES-GCM so the client
+// cannot read it either. The cookie name is bound into the signature, so a
+// value issued for one cookie is rejected under another name.
+//
+// Keys can be rotated: pass the new key first and older keys after it.
+// Encode always uses the first key; Decode accepts a value made with any of
+// them, so existing cookies stay valid until they expire.
+//
+// Usage:
+//
+//	codec, err := securecookie.New([]securecookie.Key{{Hash: hashKey}},
+//		securecookie.WithMaxAge(24*time.Hour))
+//	encoded, err := codec.Encode("prefs", prefs)
+//	err = codec.Decode("prefs", cookie.Value, &prefs)
+package securecookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxLength is the longest encoded value Encode will produce. Browsers
+// ignore cookies larger than about 4KB.
+const MaxLength = 4096
+
+var (
+	// ErrNoKeys is returned by New when no keys are given.
+	ErrNoKeys = errors.New("securecookie: at least one key is required")
+	// ErrInvalid is returned by Decode for a value that is malformed or was
+	// not signed by any of the codec's keys.
+	ErrInvalid = errors.New("securecookie: invalid value")
+	// ErrExpired is returned by Decode for a value past its embedded expiry.
+	ErrExpired = errors.New("securecookie: value expired")
+	// ErrTooLong is returned by Encode when the result exceeds MaxLength.
+	ErrTooLong = errors.New("securecookie: encoded value too long")
+)
+
+// Key is one signing key with an optional encryption key.
+type Key struct {
+	Hash  []byte // HMAC-SHA256 key; 32 or 64 random bytes recommended
+	Block []byte // AES key of 16, 24, or 32 bytes; nil disables encryption
+}
+
+// key is a Key ready for use.
+type key struct {
+	hash []byte
+	aead cipher.AEAD // nil when Block is unset
+}
+
+// Codec encodes and decodes cookie values. It is safe for concurrent use.
+type Codec struct {
+	keys   []key
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// Option configures a Codec.
+type Option func(*Codec)
+
+// WithMaxAge embeds an expiry of now+d in every encoded value. Zero, the
+// default, issues values that never expire.
+func WithMaxAge(d time.Duration) Option {
+	return func(c *Codec) {
+		c.maxAge = d
+	}
+}
+
+// New returns a Codec that signs with keys[0] and accepts any of keys.
+// It fails if keys is empty, a hash key is empty, or a block key is not a
+// valid AES key size.
+func New(keys []Key, opts ...Option) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	c := &Codec{now: time.Now}
+	for i, k := range keys {
+		if len(k.Hash) == 0 {
+			return nil, fmt.Errorf("securecookie: key %d has an empty hash key", i)
+		}
+		ready := key{hash: k.Hash}
+		if k.Block != nil {
+			block, err := aes.NewCipher(k.Block)
+			if err != nil {
+				return nil, fmt.Errorf("securecookie: key %d: %w", i, err)
+			}
+			if ready.aead, err = cipher.NewGCM(block); err != nil {
+				return nil, fmt.Errorf("securecookie: key %d: %w", i, err)
+			}
+		}
+		c.keys = append(c.keys, ready)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Encode returns value, JSON-encoded, stamped with its expiry, signed, and
+// encrypted if the newest key has a block key, as a URL-safe string.
+func (c *Codec) Encode(name string, value any) (string, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("securecookie: encode %s: %w", name, err)
+	}
+
+	var expires int64
+	if c.maxAge > 0 {
+		expires = c.now().Add(c.maxAge).Unix()
+	}
+	body := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(payload)), uint64(expires))
+	body = append(body, payload...)
+
+	k := c.keys[0]
+	if k.aead != nil {
+		nonce := make([]byte, k.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", fmt.Errorf("securecookie: encode %s: %w", name, err)
+		}
+		body = k.aead.Seal(nonce, nonce, body, []byte(name))
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(append(body, sign(k.hash, name, body)...))
+	if len(encoded) > MaxLength {
+		return "", ErrTooLong
+	}
+	return encoded, nil
+}
+
+// Decode verifies encoded against every key, newest first, and unmarshals
+// the payload into into. It returns ErrInvalid for values that fail
+// verification and ErrExpired for values past their expiry.
+func (c *Codec) Decode(name, encoded string, into any) error {
+	if len(encoded) > MaxLength {
+		return ErrInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(raw) < sha256.Size {
+		return ErrInvalid
+	}
+	body, mac := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+
+	for _, k := range c.keys {
+		if !hmac.Equal(mac, sign(k.hash, name, body)) {
+			continue
+		}
+		plain := body
+		if k.aead != nil {
+			n := k.aead.NonceSize()
+			if len(body) < n {
+				return ErrInvalid
+			}
+			if plain, err = k.aead.Open(nil, body[:n], body[n:], []byte(name)); err != nil {
+				return ErrInvalid
+			}
+		}
+		if len(plain) < 8 {
+			return ErrInvalid
+		}
+		if expires := int64(binary.BigEndian.Uint64(plain[:8])); expires != 0 && c.now().Unix() > expires {
+			return ErrExpired
+		}
+		if err := json.Unmarshal(plain[8:], into); err != nil {
+			return fmt.Errorf("securecookie: decode %s: %w", name, err)
+		}
+		return nil
+	}
+	return ErrInvalid
+}
+
+// sign returns the HMAC of the cookie name and body.
+func sign(hashKey []byte, name string, body []byte) []byte {
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write(body)
+	return mac.Sum(nil)
+}
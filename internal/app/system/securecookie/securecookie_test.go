@@ -0,0 +1,150 @@
+package securecookie
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	hashA  = []byte("0123456789abcdef0123456789abcdef")
+	hashB  = []byte("fedcba9876543210fedcba9876543210")
+	blockA = []byte("0123456789abcdef")
+)
+
+type prefs struct {
+	Theme string `json:"theme"`
+	Count int    `json:"count"`
+}
+
+func mustNew(t *testing.T, keys []Key, opts ...Option) *Codec {
+	t.Helper()
+	c, err := New(keys, opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := map[string][]Key{
+		"signed":    {{Hash: hashA}},
+		"encrypted": {{Hash: hashA, Block: blockA}},
+	}
+	for name, keys := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := mustNew(t, keys)
+			encoded, err := c.Encode("prefs", prefs{Theme: "dark", Count: 3})
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			var got prefs
+			if err := c.Decode("prefs", encoded, &got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got != (prefs{Theme: "dark", Count: 3}) {
+				t.Errorf("Decode() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestEncryptedValueIsOpaque(t *testing.T) {
+	c := mustNew(t, []Key{{Hash: hashA, Block: blockA}})
+	encoded, _ := c.Encode("prefs", prefs{Theme: "secret-theme"})
+	signedOnly := mustNew(t, []Key{{Hash: hashA}})
+	plain, _ := signedOnly.Encode("prefs", prefs{Theme: "secret-theme"})
+
+	if plain == encoded {
+		t.Fatal("encrypted and signed-only values match")
+	}
+	var got prefs
+	if err := signedOnly.Decode("prefs", encoded, &got); err == nil {
+		t.Error("signed-only codec decoded an encrypted value")
+	}
+}
+
+func TestDecode_RejectsTampering(t *testing.T) {
+	c := mustNew(t, []Key{{Hash: hashA}})
+	encoded, _ := c.Encode("prefs", prefs{Theme: "dark"})
+
+	flipped := []byte(encoded)
+	flipped[len(flipped)/2] ^= 1
+	cases := map[string]struct{ name, value string }{
+		"modified body": {"prefs", string(flipped)},
+		"other cookie":  {"session", encoded},
+		"not base64":    {"prefs", "!!!"},
+		"too short":     {"prefs", "abcd"},
+		"too long":      {"prefs", strings.Repeat("a", MaxLength+1)},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got prefs
+			if err := c.Decode(tc.name, tc.value, &got); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Decode() error = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := mustNew(t, []Key{{Hash: hashA, Block: blockA}})
+	issued, _ := old.Encode("prefs", prefs{Theme: "old"})
+
+	rotated := mustNew(t, []Key{{Hash: hashB}, {Hash: hashA, Block: blockA}})
+	var got prefs
+	if err := rotated.Decode("prefs", issued, &got); err != nil || got.Theme != "old" {
+		t.Fatalf("rotated Decode(old value) = %+v, %v", got, err)
+	}
+
+	fresh, _ := rotated.Encode("prefs", prefs{Theme: "new"})
+	if err := old.Decode("prefs", fresh, &got); !errors.Is(err, ErrInvalid) {
+		t.Errorf("old codec Decode(new value) error = %v, want ErrInvalid (signed with newest key)", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	c := mustNew(t, []Key{{Hash: hashA}}, WithMaxAge(time.Hour))
+	c.now = func() time.Time { return now }
+
+	encoded, _ := c.Encode("prefs", prefs{Theme: "dark"})
+	var got prefs
+
+	now = now.Add(59 * time.Minute)
+	if err := c.Decode("prefs", encoded, &got); err != nil {
+		t.Errorf("Decode() before expiry error = %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := c.Decode("prefs", encoded, &got); !errors.Is(err, ErrExpired) {
+		t.Errorf("Decode() after expiry error = %v, want ErrExpired", err)
+	}
+
+	// Expiry is embedded in the value, so a codec without a max age still enforces it.
+	lenient := mustNew(t, []Key{{Hash: hashA}})
+	lenient.now = c.now
+	if err := lenient.Decode("prefs", encoded, &got); !errors.Is(err, ErrExpired) {
+		t.Errorf("lenient Decode() error = %v, want ErrExpired", err)
+	}
+}
+
+func TestEncode_TooLong(t *testing.T) {
+	c := mustNew(t, []Key{{Hash: hashA}})
+	if _, err := c.Encode("big", strings.Repeat("x", MaxLength)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode() error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	if _, err := New(nil); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("New(nil) error = %v, want ErrNoKeys", err)
+	}
+	if _, err := New([]Key{{}}); err == nil {
+		t.Error("New() with empty hash key = nil error")
+	}
+	if _, err := New([]Key{{Hash: hashA, Block: []byte("short")}}); err == nil {
+		t.Error("New() with bad block key = nil error")
+	}
+}
@@ -0,0 +1,184 @@
+// Package server runs standalone HTTP servers with graceful shutdown.
+//
+// The main application server is started and drained by WAFFLE (see
+// cmd/strataforge and the shutdown_timeout setting). Use this package for any
+// additional http.Server the app runs on its own, such as an internal admin
+// or metrics listener, so it drains in-flight requests the same way.
+//
+// With WithDrain, shutdown first tells a Drainer (such as the health
+// handler) to fail the readiness probe, then keeps serving for a grace
+// period so load balancers notice and stop sending traffic, and only then
+// stops accepting connections. WAFFLE stops the main server as soon as the
+// signal arrives, so there the same effect needs a preStop delay in the
+// orchestrator.
+//
+// With WithLifecycle, a Lifecycle's start hooks run before the server
+// accepts connections and its stop hooks once it has shut down.
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	waffleserver "github.com/dalemusser/waffle/server"
+	"go.uber.org/zap"
+)
+
+// DefaultShutdownTimeout matches WAFFLE's default shutdown_timeout.
+const DefaultShutdownTimeout = 15 * time.Second
+
+// config holds the settings built up by Options.
+type config struct {
+	logger          *zap.Logger
+	shutdownTimeout time.Duration
+	drainer         Drainer
+	drainGrace      time.Duration
+	lifecycle       Lifecycle
+}
+
+// Drainer is told when shutdown begins, before the server stops accepting
+// connections. The health feature's Handler is one.
+type Drainer interface {
+	Drain()
+}
+
+// Lifecycle is started before the server accepts connections and stopped
+// after it has shut down. The lifecycle package's Lifecycle is one.
+type Lifecycle interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Option configures Run and Serve.
+type Option func(*config)
+
+// WithLogger logs server start, shutdown progress, and errors to logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithShutdownTimeout sets how long in-flight requests may take to finish
+// once shutdown begins. The default is DefaultShutdownTimeout.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.shutdownTimeout = d
+	}
+}
+
+// WithDrain calls d.Drain when shutdown begins and keeps serving for grace
+// before shutting down, so a failing readiness probe takes the instance out
+// of the load balancer before connections are refused. grace should exceed
+// the probe's period times its failure threshold.
+func WithDrain(d Drainer, grace time.Duration) Option {
+	return func(c *config) {
+		c.drainer = d
+		c.drainGrace = grace
+	}
+}
+
+// WithLifecycle runs l's start hooks before serving and its stop hooks after
+// shutdown, given their own shutdown timeout. If a start hook fails, the
+// server is not started; l is stopped and the error returned.
+func WithLifecycle(l Lifecycle) Option {
+	return func(c *config) {
+		c.lifecycle = l
+	}
+}
+
+// Run listens on srv.Addr and serves until ctx is canceled or the process
+// receives SIGINT or SIGTERM, then shuts srv down gracefully (draining
+// first, with WithDrain, and stopping the lifecycle after, with
+// WithLifecycle). It returns once in-flight requests have drained or the
+// shutdown timeout has elapsed; in the latter case remaining connections
+// are closed and the shutdown error is returned.
+func Run(ctx context.Context, srv *http.Server, opts ...Option) error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, srv, ln, opts...)
+}
+
+// Serve is like Run but accepts connections on ln.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, opts ...Option) error {
+	cfg := config{logger: zap.NewNop(), shutdownTimeout: DefaultShutdownTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	logger := cfg.logger.With(zap.String("addr", ln.Addr().String()))
+
+	if cfg.lifecycle == nil {
+		return serve(ctx, srv, ln, cfg, logger)
+	}
+	err := cfg.lifecycle.Start(ctx)
+	if err != nil {
+		_ = ln.Close()
+	} else {
+		err = serve(ctx, srv, ln, cfg, logger)
+	}
+	stopCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+	return errors.Join(err, cfg.lifecycle.Stop(stopCtx))
+}
+
+// serve runs srv on ln until ctx is done or a shutdown signal arrives, then
+// drains and shuts it down.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, cfg config, logger *zap.Logger) error {
+	ctx, stop := waffleserver.WithShutdownSignals(ctx, logger)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("http server listening")
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		logger.Error("http server failed", zap.Error(err))
+		return err
+	case <-ctx.Done():
+	}
+
+	if cfg.drainer != nil {
+		logger.Info("http server draining", zap.Duration("grace", cfg.drainGrace))
+		cfg.drainer.Drain()
+		timer := time.NewTimer(cfg.drainGrace)
+		select {
+		case err := <-errCh:
+			timer.Stop()
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			logger.Error("http server failed", zap.Error(err))
+			return err
+		case <-timer.C:
+		}
+	}
+
+	logger.Info("http server shutting down", zap.Duration("timeout", cfg.shutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("http server shutdown timed out; closing remaining connections", zap.Error(err))
+		_ = srv.Close()
+		<-errCh
+		return err
+	}
+	<-errCh
+	logger.Info("http server stopped")
+	return nil
+}
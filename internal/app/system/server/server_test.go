@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- Serve(ctx, srv, ln, WithShutdownTimeout(time.Second)) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			body <- "error: " + err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	cancel()
+
+	if got := <-body; got != "done" {
+		t.Errorf("in-flight response = %q, want %q", got, "done")
+	}
+	if err := <-result; err != nil {
+		t.Errorf("Serve() error = %v, want nil", err)
+	}
+}
+
+func TestServe_TimeoutClosesConnections(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- Serve(ctx, srv, ln, WithShutdownTimeout(50*time.Millisecond)) }()
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Error("Serve() error = nil, want shutdown timeout error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve() did not return after the shutdown timeout")
+	}
+}
+
+type fakeDrainer struct {
+	drained atomic.Bool
+}
+
+func (d *fakeDrainer) Drain() { d.drained.Store(true) }
+
+func TestServe_DrainsBeforeShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})}
+
+	d := &fakeDrainer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- Serve(ctx, srv, ln, WithDrain(d, 200*time.Millisecond), WithShutdownTimeout(time.Second))
+	}()
+
+	// Wait until the server answers, then begin shutdown.
+	url := "http://" + ln.Addr().String()
+	for i := 0; ; i++ {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 50 {
+			t.Fatalf("server never answered: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for !d.drained.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("Drain() was not called")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Still serving during the grace period
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("request during grace period failed: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("Serve() error = %v, want nil", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve() did not return after the grace period")
+	}
+}
+
+// fakeLifecycle records Start and Stop calls.
+type fakeLifecycle struct {
+	startErr error
+	events   chan string
+}
+
+func (l *fakeLifecycle) Start(ctx context.Context) error {
+	l.events <- "start"
+	return l.startErr
+}
+
+func (l *fakeLifecycle) Stop(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok || ctx.Err() != nil {
+		l.events <- "stop without its own deadline"
+		return nil
+	}
+	l.events <- "stop"
+	return nil
+}
+
+func TestServe_Lifecycle(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	lc := &fakeLifecycle{events: make(chan string, 3)}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lc.events <- "request"
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- Serve(ctx, srv, ln, WithLifecycle(lc), WithShutdownTimeout(time.Second)) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	_ = resp.Body.Close()
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	for _, want := range []string{"start", "request", "stop"} {
+		if got := <-lc.events; got != want {
+			t.Errorf("event = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestServe_LifecycleStartFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	boom := errors.New("boom")
+	lc := &fakeLifecycle{startErr: boom, events: make(chan string, 2)}
+
+	err = Serve(context.Background(), &http.Server{}, ln, WithLifecycle(lc))
+	if !errors.Is(err, boom) {
+		t.Fatalf("Serve() error = %v, want the start error", err)
+	}
+	if got := []string{<-lc.events, <-lc.events}; got[0] != "start" || got[1] != "stop" {
+		t.Errorf("events = %v, want start then stop", got)
+	}
+	if _, err := http.Get("http://" + ln.Addr().String()); err == nil {
+		t.Error("server accepted a connection after its lifecycle failed to start")
+	}
+}
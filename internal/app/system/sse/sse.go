@@ -0,0 +1,121 @@
+// Package sse streams Server-Sent Events to browsers.
+//
+// Stream writes each Event from a channel in the text/event-stream format
+// and flushes it straight away, so events reach the client as they happen.
+// It is a simpler alternative to WebSockets for pages that only need
+// server-to-client push; the browser's EventSource reconnects on its own
+// and sends the last event ID it saw in the Last-Event-ID header.
+//
+// Example:
+//
+//	func (h *Handler) serveEvents(w http.ResponseWriter, r *http.Request) {
+//	    events := make(chan sse.Event)
+//	    go h.publish(r.Context(), sse.LastEventID(r), events) // closes events when done
+//	    if err := sse.Stream(w, r, events); err != nil {
+//	        h.logger.Debug("event stream ended", zap.Error(err))
+//	    }
+//	}
+//
+// The compression middleware passes text/event-stream responses through
+// uncompressed and the ETag middleware stops buffering at the first flush.
+// The request timeout middleware would end the stream after its deadline,
+// so exempt the route with timeout.WithExemptPaths.
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is one server-sent event. Only Data is required.
+type Event struct {
+	// ID is sent back by the browser as Last-Event-ID when it reconnects.
+	ID string
+	// Event names the event type; EventSource listeners registered with
+	// addEventListener(Event, ...) receive it. Empty means "message".
+	Event string
+	// Data is the payload. Multi-line data is sent as several data lines
+	// and arrives in the browser joined with newlines.
+	Data string
+	// Retry, when positive, tells the browser how long to wait before
+	// reconnecting.
+	Retry time.Duration
+}
+
+// Stream sends events to the client until events is closed or the client
+// goes away, flushing after each one. It sets the event-stream headers
+// itself, so the handler must not write anything first.
+//
+// Stream returns nil when events is closed or the request's context is
+// canceled, and an error if the response cannot be flushed or a write
+// fails. Any write deadline set by the server is cleared for the stream.
+func Stream(w http.ResponseWriter, r *http.Request, events <-chan Event) error {
+	rc := http.NewResponseController(w)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // nginx: do not buffer the stream
+	h.Del("Content-Length")
+
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return err
+	}
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if _, err := w.Write(ev.encode()); err != nil {
+				return err
+			}
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// LastEventID returns the ID of the last event the browser received
+// before reconnecting, or "" on the first connection.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+// encode renders e in the text/event-stream format, ending with the blank
+// line that dispatches it.
+func (e Event) encode() []byte {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + singleLine(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + singleLine(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	data := strings.ReplaceAll(strings.ReplaceAll(e.Data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return []byte(b.String())
+}
+
+// singleLine drops line breaks, which would end the field early.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,113 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEvent_Encode(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   Event
+		want string
+	}{
+		{"data only", Event{Data: "hello"}, "data: hello\n\n"},
+		{"all fields", Event{ID: "7", Event: "stats", Data: `{"n":1}`, Retry: 3 * time.Second},
+			"id: 7\nevent: stats\nretry: 3000\ndata: {\"n\":1}\n\n"},
+		{"multi-line data", Event{Data: "a\r\nb\nc"}, "data: a\ndata: b\ndata: c\n\n"},
+		{"empty data", Event{Event: "ping"}, "event: ping\ndata: \n\n"},
+		{"newlines in fields", Event{ID: "1\n2", Event: "x\ry", Data: "d"}, "id: 12\nevent: xy\ndata: d\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.ev.encode()); got != tt.want {
+				t.Errorf("encode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStream_ClosedChannel(t *testing.T) {
+	events := make(chan Event, 2)
+	events <- Event{ID: "1", Data: "one"}
+	events <- Event{ID: "2", Data: "two"}
+	close(events)
+
+	rec := httptest.NewRecorder()
+	if err := Stream(rec, httptest.NewRequest(http.MethodGet, "/events", nil), events); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", cc)
+	}
+	if got, want := rec.Body.String(), "id: 1\ndata: one\n\nid: 2\ndata: two\n\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+	if !rec.Flushed {
+		t.Error("Stream() did not flush")
+	}
+}
+
+func TestStream_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+
+	done := make(chan error, 1)
+	go func() { done <- Stream(httptest.NewRecorder(), req, make(chan Event)) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Stream() error = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stream() did not return after the context was canceled")
+	}
+}
+
+func TestStream_DeliversEachEvent(t *testing.T) {
+	events := make(chan Event)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Stream(w, r, events)
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+
+	// Each event must arrive before the next one is sent.
+	for _, data := range []string{"first", "second"} {
+		events <- Event{Data: data}
+		select {
+		case line := <-lines:
+			if line != "data: "+data {
+				t.Errorf("line = %q, want %q", line, "data: "+data)
+			}
+			<-lines // blank line ending the event
+		case <-time.After(time.Second):
+			t.Fatalf("event %q was buffered", data)
+		}
+	}
+	close(events)
+}
@@ -0,0 +1,132 @@
+// internal/app/system/templatefuncs/templatefuncs.go
+//
+// Package templatefuncs collects the template helpers shared by every
+// feature into a single template.FuncMap.
+//
+// Features add their helpers with Register or RegisterMap, typically from
+// init() or while the router is built; a name can only be registered once,
+// so two features cannot silently replace each other's helper. Install then
+// hands the merged map to the template engine before it boots, which makes
+// the helpers available to every page, including those rendered through
+// render.Renderer and the errors handler.
+//
+// The package provides these helpers itself:
+//
+//	formatDate TIME [LAYOUT] - TIME in LAYOUT (default "Jan 2, 2006"); "" for the zero time
+//	truncate N STRING        - STRING cut to N characters, ending in "…" when shortened
+package templatefuncs
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dalemusser/waffle/pantry/templates"
+)
+
+// DefaultDateLayout is the layout formatDate uses when none is given.
+const DefaultDateLayout = "Jan 2, 2006"
+
+var (
+	mu       sync.RWMutex
+	registry = template.FuncMap{
+		"formatDate": formatDate,
+		"truncate":   truncate,
+	}
+)
+
+// Register adds fn as the template function name. It returns an error if
+// name is already taken, by another registration or by one of the engine's
+// built-in helpers, or if fn is not a function.
+func Register(name string, fn any) error {
+	if name == "" {
+		return errors.New("templatefuncs: empty function name")
+	}
+	if fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func {
+		return fmt.Errorf("templatefuncs: %q is a %T, not a function", name, fn)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := templates.Funcs()[name]; ok {
+		return fmt.Errorf("templatefuncs: %q is a built-in template function", name)
+	}
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("templatefuncs: %q is already registered", name)
+	}
+	registry[name] = fn
+	return nil
+}
+
+// MustRegister is Register for use in init(); it panics on error.
+func MustRegister(name string, fn any) {
+	if err := Register(name, fn); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterMap registers every function in m, in name order, and returns the
+// errors for any that could not be registered.
+func RegisterMap(m template.FuncMap) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, Register(name, m[name]))
+	}
+	return errors.Join(errs...)
+}
+
+// FuncMap returns a copy of the merged map: the engine's built-in helpers
+// plus everything registered here.
+func FuncMap() template.FuncMap {
+	m := templates.Funcs()
+	mu.RLock()
+	defer mu.RUnlock()
+	for name, fn := range registry {
+		m[name] = fn
+	}
+	return m
+}
+
+// Install passes the registered functions to the template engine. It must
+// run before the engine boots and is safe to call more than once.
+func Install() {
+	mu.RLock()
+	defer mu.RUnlock()
+	for name, fn := range registry {
+		templates.RegisterFunc(name, fn)
+	}
+}
+
+// formatDate formats t with layout, or DefaultDateLayout.
+func formatDate(t time.Time, layout ...string) string {
+	if t.IsZero() {
+		return ""
+	}
+	if len(layout) > 0 && layout[0] != "" {
+		return t.Format(layout[0])
+	}
+	return t.Format(DefaultDateLayout)
+}
+
+// truncate shortens s to at most n characters, counting the trailing
+// ellipsis. The argument order suits pipelines: {{ .Bio | truncate 80 }}.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	return string(r[:n-1]) + "…"
+}
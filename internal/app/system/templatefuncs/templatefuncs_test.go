@@ -0,0 +1,90 @@
+package templatefuncs
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegister_Duplicates(t *testing.T) {
+	if err := Register("testShout", strings.ToUpper); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	err := Register("testShout", strings.ToLower)
+	if err == nil || !strings.Contains(err.Error(), `"testShout" is already registered`) {
+		t.Errorf("duplicate Register() error = %v", err)
+	}
+	if err := Register("truncate", strings.ToLower); err == nil {
+		t.Error("Register() should refuse a name this package provides")
+	}
+	if err := Register("lower", strings.ToLower); err == nil {
+		t.Error("Register() should refuse an engine built-in")
+	}
+	if err := Register("testNotFunc", "value"); err == nil {
+		t.Error("Register() should refuse a non-function")
+	}
+}
+
+func TestRegisterMap(t *testing.T) {
+	err := RegisterMap(template.FuncMap{
+		"testA": func() string { return "a" },
+		"testB": func() string { return "b" },
+	})
+	if err != nil {
+		t.Fatalf("RegisterMap() error = %v", err)
+	}
+
+	err = RegisterMap(template.FuncMap{
+		"testA": func() string { return "again" },
+		"testC": func() string { return "c" },
+	})
+	if err == nil || !strings.Contains(err.Error(), "testA") {
+		t.Errorf("RegisterMap() with a duplicate error = %v", err)
+	}
+	if _, ok := FuncMap()["testC"]; !ok {
+		t.Error("RegisterMap() should still register the names that do not clash")
+	}
+}
+
+func TestFuncMap_Templates(t *testing.T) {
+	MustRegister("testGreet", func(name string) string { return "hi " + name })
+	tmpl := template.Must(template.New("t").Funcs(FuncMap()).Parse(
+		`{{ testGreet "ada" }}|{{ lower "X" }}|{{ .When | formatDate }}|{{ formatDate .When "2006-01-02" }}|{{ .Bio | truncate 6 }}`))
+
+	var b strings.Builder
+	err := tmpl.Execute(&b, map[string]any{
+		"When": time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		"Bio":  "Mathematician",
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "hi ada|x|Mar 9, 2026|2026-03-09|Mathe…"; b.String() != want {
+		t.Errorf("output = %q, want %q", b.String(), want)
+	}
+}
+
+func TestFormatDate_Zero(t *testing.T) {
+	if got := formatDate(time.Time{}); got != "" {
+		t.Errorf("formatDate(zero) = %q, want empty", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n    int
+		in   string
+		want string
+	}{
+		{10, "short", "short"},
+		{5, "exact", "exact"},
+		{4, "héllo", "hél…"},
+		{0, "gone", ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.in); got != tt.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.in, got, tt.want)
+		}
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"context"
 	"html/template"
 	"net/http"
+	"time"
 
 	settingsstore "github.com/dalemusser/strataforge/internal/app/store/settings"
 	"github.com/dalemusser/strataforge/internal/app/system/auth"
 	"github.com/dalemusser/strataforge/internal/app/system/authz"
+	"github.com/dalemusser/strataforge/internal/app/system/csp"
 	"github.com/dalemusser/strataforge/internal/app/system/htmlsanitize"
+	"github.com/dalemusser/strataforge/internal/app/system/httpx"
+	"github.com/dalemusser/strataforge/internal/app/system/i18n"
 	"github.com/dalemusser/strataforge/internal/app/system/timeouts"
 	"github.com/dalemusser/strataforge/internal/domain/models"
 	"github.com/dalemusser/waffle/pantry/httpnav"
@@ -58,14 +62,17 @@ type BaseVM struct {
 	Role            string
 	UserName        string
 	ThemePreference string // light, dark, system (empty = system)
+	Locale          string // chosen by the i18n middleware; pass to the "t" template function
 
 	// Page context
 	Title       string
 	BackURL     string
 	CurrentPath string
+	Deadline    time.Time // when the request times out; zero if it does not (see httpx.Deadline)
 
 	// Security
 	CSRFToken string // CSRF token for forms (use in hidden input field)
+	CSPNonce  string // nonce for inline scripts: <script {{ cspNonce .CSPNonce }}>
 
 	// Announcements for banner display
 	Announcements []AnnouncementVM
@@ -114,11 +121,14 @@ func NewBaseVM(r *http.Request, db *mongo.Database, title, backDefault string) B
 		Role:            role,
 		UserName:        name,
 		ThemePreference: authz.ThemePreference(r),
+		Locale:          i18n.Locale(r.Context()),
 		Title:           title,
 		BackURL:         httpnav.ResolveBackURL(r, backDefault),
 		CurrentPath:     httpnav.CurrentPath(r),
 		CSRFToken:       csrf.Token(r),
+		CSPNonce:        csp.Nonce(r.Context()),
 	}
+	vm.Deadline, _ = httpx.Deadline(r.Context())
 
 	// Get LoginID from session if logged in
 	if signedIn {
@@ -194,9 +204,12 @@ func New(r *http.Request) BaseVM {
 		Role:            role,
 		UserName:        name,
 		ThemePreference: authz.ThemePreference(r),
+		Locale:          i18n.Locale(r.Context()),
 		CurrentPath:     httpnav.CurrentPath(r),
 		CSRFToken:       csrf.Token(r),
+		CSPNonce:        csp.Nonce(r.Context()),
 	}
+	vm.Deadline, _ = httpx.Deadline(r.Context())
 
 	// Get LoginID from session if logged in
 	if signedIn {
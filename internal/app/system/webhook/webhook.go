@@ -0,0 +1,284 @@
+// internal/app/system/webhook/webhook.go
+//
+// Package webhook verifies HMAC-signed webhooks from third parties.
+//
+// Signatures use the Stripe-style header format
+//
+//	Webhook-Signature: t=1767225600,v1=5257a869e7ec...
+//
+// where each v1 value is the hex HMAC-SHA256 of "<t>.<raw body>" under the
+// shared secret. Several v1 values may be sent while a sender rotates
+// secrets; any one matching is enough. The timestamp is signed too, so a
+// captured request cannot be replayed once it falls outside the tolerance.
+//
+// Verification needs the exact bytes that were signed, so Verify reads the
+// raw body itself, bounded by a size limit, and puts it back on the
+// request: handlers may decode r.Body as usual afterwards.
+//
+//	r.With(webhook.Middleware(secret, "Stripe-Signature", errorsHandler)).
+//		Post("/webhooks/stripe", h.stripe)
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dalemusser/strataforge/internal/app/system/jsonutil"
+)
+
+// DefaultHeader is a conventional name for the signature header. Senders
+// usually pick their own (e.g. "Stripe-Signature"); pass that to Verify.
+const DefaultHeader = "Webhook-Signature"
+
+// DefaultTolerance is how far a signed timestamp may be from now, in
+// either direction, unless WithTolerance sets another.
+const DefaultTolerance = 5 * time.Minute
+
+// DefaultMaxBytes is the body limit used unless WithMaxBytes sets another.
+const DefaultMaxBytes = jsonutil.DefaultMaxBytes
+
+// Verification errors. Status maps each to the response it deserves.
+var (
+	ErrMissingSignature   = errors.New("webhook: missing signature header")
+	ErrMalformedSignature = errors.New("webhook: malformed signature header")
+	ErrTooLarge           = errors.New("webhook: body too large")
+	ErrInvalidSignature   = errors.New("webhook: signature mismatch")
+	ErrExpired            = errors.New("webhook: timestamp outside tolerance")
+)
+
+// ErrNoSecret is returned by Verify when the secret is empty: anyone could
+// compute an HMAC under an empty key, so nothing would really be verified.
+// It is a configuration mistake, which Status maps to 500.
+var ErrNoSecret = errors.New("webhook: empty secret")
+
+// config holds the settings built up by Options.
+type config struct {
+	tolerance time.Duration
+	maxBytes  int64
+	now       func() time.Time
+}
+
+// Option configures verification.
+type Option func(*config)
+
+// WithTolerance sets how old (or how far in the future) a signed timestamp
+// may be (default DefaultTolerance).
+func WithTolerance(d time.Duration) Option {
+	return func(c *config) {
+		if d > 0 {
+			c.tolerance = d
+		}
+	}
+}
+
+// WithMaxBytes limits the body to n bytes (default DefaultMaxBytes).
+func WithMaxBytes(n int64) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.maxBytes = n
+		}
+	}
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		tolerance: DefaultTolerance,
+		maxBytes:  DefaultMaxBytes,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Verify checks the signature in the named header against r's raw body and
+// returns the verified payload. The body is read at most once and is
+// restored on r, whatever the outcome, so the handler can still read it;
+// the returned slice holds the same bytes.
+//
+// Errors are ErrNoSecret, ErrMissingSignature, ErrMalformedSignature,
+// ErrTooLarge, ErrInvalidSignature, ErrExpired, or the error from reading
+// the body.
+func Verify(r *http.Request, secret []byte, header string, opts ...Option) ([]byte, error) {
+	if len(secret) == 0 {
+		return nil, ErrNoSecret
+	}
+	cfg := newConfig(opts)
+
+	value := r.Header.Get(header)
+	if value == "" {
+		return nil, ErrMissingSignature
+	}
+	ts, sigs, err := parseHeader(value)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := readBody(r, cfg.maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := mac(secret, ts, body)
+	matched := false
+	for _, sig := range sigs {
+		if hmac.Equal(sig, expected) {
+			matched = true
+		}
+	}
+	if !matched {
+		return nil, ErrInvalidSignature
+	}
+
+	skew := cfg.now().Sub(time.Unix(ts, 0))
+	if skew > cfg.tolerance || skew < -cfg.tolerance {
+		return nil, ErrExpired
+	}
+	return body, nil
+}
+
+// Sign returns the header value that signs payload at time t, for sending
+// webhooks or for tests of handlers that receive them.
+func Sign(secret []byte, t time.Time, payload []byte) string {
+	ts := t.Unix()
+	return "t=" + strconv.FormatInt(ts, 10) + ",v1=" + hex.EncodeToString(mac(secret, ts, payload))
+}
+
+// Status returns the HTTP status for a Verify error: 400 for a request
+// that is not a well-formed signed webhook, 413 for a body over the limit,
+// 401 for one whose signature or timestamp does not verify, and 500 for
+// anything else.
+func Status(err error) int {
+	switch {
+	case errors.Is(err, ErrMissingSignature), errors.Is(err, ErrMalformedSignature):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrExpired):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ErrorHandler renders verification failures. The errors feature's Handler
+// satisfies it.
+type ErrorHandler interface {
+	BadRequest(w http.ResponseWriter, r *http.Request)
+	RequestEntityTooLarge(w http.ResponseWriter, r *http.Request)
+	Unauthorized(w http.ResponseWriter, r *http.Request)
+	Error(w http.ResponseWriter, r *http.Request, status int)
+}
+
+// Middleware verifies every request with Verify and passes verified ones
+// on with the body intact. Failures are rendered by eh: BadRequest,
+// RequestEntityTooLarge, or Unauthorized as Status decides, and Error for a
+// failed body read. With a nil eh they are written with jsonutil.
+//
+// Middleware panics if secret is empty, so a missing secret in the config
+// fails at startup rather than on every request.
+func Middleware(secret []byte, header string, eh ErrorHandler, opts ...Option) func(http.Handler) http.Handler {
+	if len(secret) == 0 {
+		panic(ErrNoSecret)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := Verify(r, secret, header, opts...); err != nil {
+				fail(w, r, eh, Status(err))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func fail(w http.ResponseWriter, r *http.Request, eh ErrorHandler, status int) {
+	if eh == nil {
+		jsonutil.Error(w, status, http.StatusText(status))
+		return
+	}
+	switch status {
+	case http.StatusBadRequest:
+		eh.BadRequest(w, r)
+	case http.StatusRequestEntityTooLarge:
+		eh.RequestEntityTooLarge(w, r)
+	case http.StatusUnauthorized:
+		eh.Unauthorized(w, r)
+	default:
+		eh.Error(w, r, status)
+	}
+}
+
+// parseHeader splits "t=<unix>,v1=<hex>[,v1=<hex>...]". Unknown keys, such
+// as other signature schemes, are ignored.
+func parseHeader(value string) (int64, [][]byte, error) {
+	var (
+		ts    int64
+		hasTS bool
+		sigs  [][]byte
+	)
+	for _, part := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return 0, nil, ErrMalformedSignature
+		}
+		switch k {
+		case "t":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return 0, nil, ErrMalformedSignature
+			}
+			ts, hasTS = n, true
+		case "v1":
+			sig, err := hex.DecodeString(v)
+			if err != nil {
+				return 0, nil, ErrMalformedSignature
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+	if !hasTS || len(sigs) == 0 {
+		return 0, nil, ErrMalformedSignature
+	}
+	return ts, sigs, nil
+}
+
+// readBody reads up to limit bytes of r.Body and replaces r.Body with a
+// reader that yields the same bytes followed by anything left unread, so
+// the body is intact for the next reader even when it was too large.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return []byte{}, nil
+	}
+	orig := r.Body
+	body, err := io.ReadAll(io.LimitReader(orig, limit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), orig), orig}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrTooLarge
+	}
+	return body, nil
+}
+
+// mac computes HMAC-SHA256 of "<ts>.<payload>".
+func mac(secret []byte, ts int64, payload []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(strconv.FormatInt(ts, 10)))
+	m.Write([]byte{'.'})
+	m.Write(payload)
+	return m.Sum(nil)
+}
@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("whsec_test")
+
+func signedRequest(body, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	if signature != "" {
+		r.Header.Set(DefaultHeader, signature)
+	}
+	return r
+}
+
+func TestVerify_ValidSignature(t *testing.T) {
+	body := `{"type":"invoice.paid"}`
+	r := signedRequest(body, Sign(secret, time.Now(), []byte(body)))
+
+	payload, err := Verify(r, secret, DefaultHeader)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if string(payload) != body {
+		t.Errorf("payload = %q, want %q", payload, body)
+	}
+
+	rest, _ := io.ReadAll(r.Body)
+	if string(rest) != body {
+		t.Errorf("body after Verify = %q, want it restored", rest)
+	}
+}
+
+func TestVerify_RotatedSecret(t *testing.T) {
+	body := `{}`
+	now := time.Now()
+	old := Sign([]byte("old"), now, []byte(body))
+	current := Sign(secret, now, []byte(body))
+	_, v1, _ := strings.Cut(current, ",")
+	r := signedRequest(body, old+","+v1)
+
+	if _, err := Verify(r, secret, DefaultHeader); err != nil {
+		t.Errorf("Verify() error = %v, want any matching v1 accepted", err)
+	}
+}
+
+func TestVerify_Errors(t *testing.T) {
+	body := `{"amount":100}`
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		body       string
+		signature  string
+		wantErr    error
+		wantStatus int
+	}{
+		{"missing header", body, "", ErrMissingSignature, http.StatusBadRequest},
+		{"no timestamp", body, "v1=abcd", ErrMalformedSignature, http.StatusBadRequest},
+		{"no signature", body, "t=123", ErrMalformedSignature, http.StatusBadRequest},
+		{"bad hex", body, "t=123,v1=zz", ErrMalformedSignature, http.StatusBadRequest},
+		{"tampered body", `{"amount":999}`, Sign(secret, now, []byte(body)), ErrInvalidSignature, http.StatusUnauthorized},
+		{"wrong secret", body, Sign([]byte("other"), now, []byte(body)), ErrInvalidSignature, http.StatusUnauthorized},
+		{"stale", body, Sign(secret, now.Add(-time.Hour), []byte(body)), ErrExpired, http.StatusUnauthorized},
+		{"future", body, Sign(secret, now.Add(time.Hour), []byte(body)), ErrExpired, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(signedRequest(tt.body, tt.signature), secret, DefaultHeader)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := Status(err); got != tt.wantStatus {
+				t.Errorf("Status() = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestVerify_EmptySecret(t *testing.T) {
+	body := `{"id":"evt_1"}`
+	for _, empty := range [][]byte{nil, {}} {
+		// Signed with the same empty key, so only the secret check can refuse it.
+		r := signedRequest(body, Sign(empty, time.Now(), []byte(body)))
+		_, err := Verify(r, empty, DefaultHeader)
+		if !errors.Is(err, ErrNoSecret) {
+			t.Errorf("Verify(secret=%#v) error = %v, want ErrNoSecret", empty, err)
+		}
+		if got := Status(err); got != http.StatusInternalServerError {
+			t.Errorf("Status() = %d, want %d", got, http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestMiddleware_EmptySecretPanics(t *testing.T) {
+	defer func() {
+		if rec := recover(); rec != ErrNoSecret {
+			t.Errorf("recovered %v, want ErrNoSecret", rec)
+		}
+	}()
+	Middleware(nil, DefaultHeader, nil)
+}
+
+func TestVerify_TooLargeKeepsBody(t *testing.T) {
+	body := strings.Repeat("x", 32)
+	r := signedRequest(body, Sign(secret, time.Now(), []byte(body)))
+
+	_, err := Verify(r, secret, DefaultHeader, WithMaxBytes(16))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Verify() error = %v, want ErrTooLarge", err)
+	}
+	if got := Status(err); got != http.StatusRequestEntityTooLarge {
+		t.Errorf("Status() = %d, want %d", got, http.StatusRequestEntityTooLarge)
+	}
+	rest, _ := io.ReadAll(r.Body)
+	if string(rest) != body {
+		t.Errorf("body after Verify = %q, want the full body", rest)
+	}
+}
+
+func TestVerify_Tolerance(t *testing.T) {
+	body := `{}`
+	signedAt := time.Unix(1767225600, 0)
+	at := func(now time.Time) Option {
+		return func(c *config) { c.now = func() time.Time { return now } }
+	}
+
+	r := signedRequest(body, Sign(secret, signedAt, []byte(body)))
+	if _, err := Verify(r, secret, DefaultHeader, at(signedAt.Add(9*time.Minute)), WithTolerance(10*time.Minute)); err != nil {
+		t.Errorf("Verify() within tolerance error = %v", err)
+	}
+
+	r = signedRequest(body, Sign(secret, signedAt, []byte(body)))
+	if _, err := Verify(r, secret, DefaultHeader, at(signedAt.Add(11*time.Minute)), WithTolerance(10*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Verify() past tolerance error = %v, want ErrExpired", err)
+	}
+}
+
+type recordingErrors struct{ called string }
+
+func (e *recordingErrors) BadRequest(w http.ResponseWriter, r *http.Request) {
+	e.called = "BadRequest"
+	w.WriteHeader(http.StatusBadRequest)
+}
+
+func (e *recordingErrors) RequestEntityTooLarge(w http.ResponseWriter, r *http.Request) {
+	e.called = "RequestEntityTooLarge"
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+}
+
+func (e *recordingErrors) Unauthorized(w http.ResponseWriter, r *http.Request) {
+	e.called = "Unauthorized"
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func (e *recordingErrors) Error(w http.ResponseWriter, r *http.Request, status int) {
+	e.called = "Error"
+	w.WriteHeader(status)
+}
+
+func TestMiddleware(t *testing.T) {
+	body := `{"id":"evt_1"}`
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	})
+
+	t.Run("verified request reaches handler with body", func(t *testing.T) {
+		eh := &recordingErrors{}
+		rec := httptest.NewRecorder()
+		Middleware(secret, DefaultHeader, eh)(next).ServeHTTP(rec, signedRequest(body, Sign(secret, time.Now(), []byte(body))))
+
+		if rec.Code != http.StatusOK || eh.called != "" {
+			t.Fatalf("status = %d, error handler %q; want 200 and none", rec.Code, eh.called)
+		}
+		if got != body {
+			t.Errorf("handler read %q, want %q", got, body)
+		}
+	})
+
+	t.Run("missing signature is a bad request", func(t *testing.T) {
+		eh := &recordingErrors{}
+		Middleware(secret, DefaultHeader, eh)(next).ServeHTTP(httptest.NewRecorder(), signedRequest(body, ""))
+		if eh.called != "BadRequest" {
+			t.Errorf("error handler = %q, want BadRequest", eh.called)
+		}
+	})
+
+	t.Run("bad signature is unauthorized", func(t *testing.T) {
+		eh := &recordingErrors{}
+		Middleware(secret, DefaultHeader, eh)(next).ServeHTTP(httptest.NewRecorder(), signedRequest(body, Sign([]byte("x"), time.Now(), []byte(body))))
+		if eh.called != "Unauthorized" {
+			t.Errorf("error handler = %q, want Unauthorized", eh.called)
+		}
+	})
+
+	t.Run("oversized body is too large", func(t *testing.T) {
+		eh := &recordingErrors{}
+		rec := httptest.NewRecorder()
+		Middleware(secret, DefaultHeader, eh, WithMaxBytes(4))(next).ServeHTTP(rec, signedRequest(body, Sign(secret, time.Now(), []byte(body))))
+		if eh.called != "RequestEntityTooLarge" || rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("error handler = %q, status %d; want RequestEntityTooLarge and 413", eh.called, rec.Code)
+		}
+	})
+
+	t.Run("nil error handler writes JSON", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		Middleware(secret, DefaultHeader, nil)(next).ServeHTTP(rec, signedRequest(body, ""))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", rec.Code)
+		}
+	})
+}